@@ -0,0 +1,94 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"reflect"
+	"slices"
+)
+
+// Synchronized is implemented by the concurrent containers, which expose
+// their lock so that Atomically can lock several of them together.
+type Synchronized interface {
+	Locker() RWLocker
+}
+
+// Atomically locks all the structures for writing, runs fn and unlocks them,
+// returning the error returned by fn. The locks are acquired in a canonical
+// order, so concurrent calls on overlapping sets of structures can't
+// deadlock, whatever the order of the arguments.
+//
+// While fn runs the locks are already held, so fn must not call the methods
+// of the structures (they would try to lock them again): it must work on
+// their Unsafe() views instead:
+//
+//	err := gods.Atomically(func() error {
+//		v, err := from.Unsafe().Pop()
+//		if err != nil {
+//			return err
+//		}
+//		to.Unsafe().Append(*v)
+//		return nil
+//	}, from, to)
+func Atomically(fn func() error, structures ...Synchronized) error {
+	lockers := lockOrder(structures)
+	for _, l := range lockers {
+		l.Lock()
+	}
+	defer func() {
+		for i := len(lockers) - 1; i >= 0; i-- {
+			lockers[i].Unlock()
+		}
+	}()
+	return fn()
+}
+
+// AtomicallyRead is like Atomically, but it locks the structures for reading,
+// so fn sees a consistent state of all of them but must not modify them.
+func AtomicallyRead(fn func() error, structures ...Synchronized) error {
+	lockers := lockOrder(structures)
+	for _, l := range lockers {
+		l.RLock()
+	}
+	defer func() {
+		for i := len(lockers) - 1; i >= 0; i-- {
+			lockers[i].RUnlock()
+		}
+	}()
+	return fn()
+}
+
+// lockOrder returns the (distinct) lockers of the structures in the order in
+// which they must be acquired: by address, which never changes for heap
+// allocated values
+func lockOrder(structures []Synchronized) []RWLocker {
+	lockers := make([]RWLocker, 0, len(structures))
+	for _, s := range structures {
+		if s != nil {
+			lockers = append(lockers, s.Locker())
+		}
+	}
+	slices.SortFunc(lockers, func(a, b RWLocker) int {
+		pa, pb := reflect.ValueOf(a).Pointer(), reflect.ValueOf(b).Pointer()
+		switch {
+		case pa < pb:
+			return -1
+		case pa > pb:
+			return 1
+		}
+		return 0
+	})
+	return slices.CompactFunc(lockers, func(a, b RWLocker) bool { return a == b })
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	cslinkList "github.com/pzaino/gods/pkg/cslinkList"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+)
+
+func TestAtomicallyTransfer(t *testing.T) {
+	s := csstack.NewFromSlice([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	l := cslinkList.New[int](gods.WithSharding(4))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		// stack -> list, with the arguments in one order
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				_ = gods.Atomically(func() error {
+					v, err := s.Unsafe().Pop()
+					if err != nil {
+						return err
+					}
+					l.Unsafe().Prepend(*v)
+					return nil
+				}, s, l)
+			}
+		}()
+		// list -> stack, with the arguments in the opposite order
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				_ = gods.Atomically(func() error {
+					first := l.Unsafe().GetFirst()
+					if first == nil {
+						return nil
+					}
+					s.Unsafe().Push(first.Value)
+					return l.Unsafe().DeleteAt(0)
+				}, l, s)
+			}
+		}()
+	}
+	// readers always see all the elements
+	for i := 0; i < 100; i++ {
+		_ = gods.AtomicallyRead(func() error {
+			if n := s.Unsafe().Size() + l.Unsafe().Size(); n != 10 {
+				t.Errorf(errExpectedX, 10, n)
+			}
+			return nil
+		}, s, l)
+	}
+	wg.Wait()
+	if n := s.Size() + l.Size(); n != 10 {
+		t.Errorf(errExpectedX, 10, n)
+	}
+}
+
+func TestAtomicallyError(t *testing.T) {
+	s := csstack.New[int]()
+	errFailed := errors.New("failed")
+	// the same structure twice is locked only once
+	err := gods.Atomically(func() error { return errFailed }, s, s, nil)
+	if !errors.Is(err, errFailed) {
+		t.Errorf(errExpectedX, errFailed, err)
+	}
+	// the locks have been released
+	s.Push(1)
+	if s.Size() != 1 {
+		t.Errorf(errExpectedX, 1, s.Size())
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ErrCheckpointerStarted = "checkpointer already started"
+)
+
+// CheckpointOption configures a Checkpointer.
+type CheckpointOption func(*Checkpointer)
+
+// WithInterval makes the Checkpointer snapshot its containers every d.
+func WithInterval(d time.Duration) CheckpointOption {
+	return func(cp *Checkpointer) {
+		cp.interval = d
+	}
+}
+
+// WithJitter adds a random delay in [0, d) to every interval, so many
+// processes started together don't write their snapshots at the same time.
+func WithJitter(d time.Duration) CheckpointOption {
+	return func(cp *Checkpointer) {
+		cp.jitter = d
+	}
+}
+
+// WithMutationThreshold makes the Checkpointer snapshot its containers as
+// soon as n mutations have been reported by the containers created with its
+// MutationHook (without waiting for the interval).
+func WithMutationThreshold(n uint64) CheckpointOption {
+	return func(cp *Checkpointer) {
+		cp.threshold = n
+	}
+}
+
+// WithFailureHandler sets a function called with the path and the error of
+// every snapshot that fails in the background.
+func WithFailureHandler(fn func(path string, err error)) CheckpointOption {
+	return func(cp *Checkpointer) {
+		cp.onFailure = fn
+	}
+}
+
+// WithCheckpointSnapshotOptions sets the options passed to Snapshot.
+func WithCheckpointSnapshotOptions(opts ...SnapshotOption) CheckpointOption {
+	return func(cp *Checkpointer) {
+		cp.snapshotOpts = opts
+	}
+}
+
+// Checkpointer snapshots a set of containers in the background, on an
+// interval and/or after a number of mutations:
+//
+//	cp := gods.NewCheckpointer(gods.WithInterval(time.Minute), gods.WithMutationThreshold(10000))
+//	q := csstack.New[int](cp.MutationHook())
+//	cp.Register(q, "/var/lib/app/stack.snap")
+//	cp.Start()
+//	defer cp.Stop()
+//
+// Every checkpoint snapshots all the registered containers with Snapshot, so
+// each file is replaced atomically.
+type Checkpointer struct {
+	interval     time.Duration
+	jitter       time.Duration
+	threshold    uint64
+	onFailure    func(path string, err error)
+	snapshotOpts []SnapshotOption
+
+	mu        sync.Mutex // serializes the checkpoints and protects targets
+	targets   []checkpointTarget
+	mutations atomic.Uint64
+	kick      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// checkpointTarget is a registered container
+type checkpointTarget struct {
+	c    Encodable
+	path string
+}
+
+// NewCheckpointer creates a Checkpointer. It does nothing until Start is
+// called.
+func NewCheckpointer(opts ...CheckpointOption) *Checkpointer {
+	cp := &Checkpointer{kick: make(chan struct{}, 1)}
+	for _, opt := range opts {
+		opt(cp)
+	}
+	return cp
+}
+
+// Register adds c to the containers snapshotted to path at every checkpoint.
+func (cp *Checkpointer) Register(c Encodable, path string) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.targets = append(cp.targets, checkpointTarget{c: c, path: path})
+}
+
+// MutationHook returns the option that makes a concurrent container report
+// its mutations to the Checkpointer.
+func (cp *Checkpointer) MutationHook() Option {
+	return WithMutationHook(cp.Mutated)
+}
+
+// Mutated reports a mutation. It is called by the containers created with
+// MutationHook, but it can be called directly for the other containers.
+func (cp *Checkpointer) Mutated() {
+	if cp.threshold > 0 && cp.mutations.Add(1) == cp.threshold {
+		select {
+		case cp.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start starts the background goroutine.
+func (cp *Checkpointer) Start() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.stop != nil {
+		return errors.New(ErrCheckpointerStarted)
+	}
+	cp.stop = make(chan struct{})
+	cp.done = make(chan struct{})
+	go cp.run(cp.stop, cp.done)
+	return nil
+}
+
+// Stop stops the background goroutine and takes a last checkpoint, so the
+// mutations made since the previous one are not lost. It returns the errors
+// of the last checkpoint.
+func (cp *Checkpointer) Stop() error {
+	cp.mu.Lock()
+	stop, done := cp.stop, cp.done
+	cp.stop = nil
+	cp.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	return cp.Checkpoint()
+}
+
+// Checkpoint snapshots all the registered containers now. The failures are
+// passed to the failure handler too, and returned joined together.
+func (cp *Checkpointer) Checkpoint() (err error) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	_, end := Trace(context.Background(), "gods.Checkpoint", slog.Int("containers", len(cp.targets)))
+	defer func() { end(err) }()
+	cp.mutations.Store(0)
+	var errs []error
+	for _, t := range cp.targets {
+		if err := Snapshot(t.c, t.path, cp.snapshotOpts...); err != nil {
+			if cp.onFailure != nil {
+				cp.onFailure(t.path, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run takes a checkpoint every interval or when the mutation threshold is
+// reached, until Stop is called
+func (cp *Checkpointer) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	var tick <-chan time.Time
+	var timer *time.Timer
+	if cp.interval > 0 {
+		timer = time.NewTimer(cp.nextDelay())
+		defer timer.Stop()
+		tick = timer.C
+	}
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick:
+		case <-cp.kick:
+		}
+		_ = cp.Checkpoint()
+		if timer != nil {
+			timer.Reset(cp.nextDelay())
+		}
+	}
+}
+
+// nextDelay returns the interval plus the jitter
+func (cp *Checkpointer) nextDelay() time.Duration {
+	if cp.jitter <= 0 {
+		return cp.interval
+	}
+	return cp.interval + rand.N(cp.jitter)
+}
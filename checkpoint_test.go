@@ -0,0 +1,109 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gods "github.com/pzaino/gods"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+)
+
+// waitFor polls cond until it is true or a second has passed
+func waitFor(cond func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if cond() {
+			return true
+		}
+	}
+	return cond()
+}
+
+func restoredStack(path string) []int {
+	s := csstack.New[int]()
+	if err := gods.Restore(path, s); err != nil {
+		return nil
+	}
+	return s.ToSlice()
+}
+
+func TestMutationHook(t *testing.T) {
+	var n atomic.Int64
+	s := csstack.New[int](gods.WithMutationHook(func() { n.Add(1) }), gods.WithMutationHook(func() { n.Add(10) }))
+	s.Push(1)
+	s.Push(2)
+	_, _ = s.Pop()
+	_ = s.ToSlice()
+	if n.Load() != 33 {
+		t.Errorf(errExpectedX, 33, n.Load())
+	}
+}
+
+func TestCheckpointerMutationThreshold(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stack.snap")
+	cp := gods.NewCheckpointer(gods.WithMutationThreshold(3))
+	s := csstack.New[int](cp.MutationHook())
+	cp.Register(s, path)
+	if err := cp.Start(); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if err := cp.Start(); err == nil || err.Error() != gods.ErrCheckpointerStarted {
+		t.Errorf(errExpectedX, gods.ErrCheckpointerStarted, err)
+	}
+	s.Push(1)
+	s.Push(2)
+	s.Push(3)
+	if !waitFor(func() bool { return slices.Equal(restoredStack(path), []int{3, 2, 1}) }) {
+		t.Errorf(errExpectedX, []int{3, 2, 1}, restoredStack(path))
+	}
+
+	// Stop takes a last checkpoint
+	s.Push(4)
+	if err := cp.Stop(); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if got := restoredStack(path); !slices.Equal(got, []int{4, 3, 2, 1}) {
+		t.Errorf(errExpectedX, []int{4, 3, 2, 1}, got)
+	}
+}
+
+func TestCheckpointerInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stack.snap")
+	var failures atomic.Int64
+	cp := gods.NewCheckpointer(
+		gods.WithInterval(5*time.Millisecond),
+		gods.WithJitter(time.Millisecond),
+		gods.WithFailureHandler(func(string, error) { failures.Add(1) }),
+	)
+	s := csstack.NewFromSlice([]int{1})
+	cp.Register(s, path)
+	cp.Register(s, filepath.Join(t.TempDir(), "missing", "stack.snap"))
+	if err := cp.Start(); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if !waitFor(func() bool { return failures.Load() >= 2 }) {
+		t.Errorf("Expected the failure handler to be called on every interval")
+	}
+	if got := restoredStack(path); !slices.Equal(got, []int{1}) {
+		t.Errorf(errExpectedX, []int{1}, got)
+	}
+	if err := cp.Stop(); err == nil {
+		t.Errorf("Expected the error of the last checkpoint")
+	}
+}
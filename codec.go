@@ -0,0 +1,185 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+)
+
+const (
+	ErrCodecIsNil        = "codec is nil"
+	ErrInvalidDecodeDest = "decode destination must be a non-nil pointer"
+)
+
+// Codec encodes and decodes values to and from a stream. Every container in
+// the library implements Encode(w, codec) and Decode(r, codec) on top of a
+// Codec, so applications can persist different kind of collections in the
+// same way.
+type Codec interface {
+	// Encode writes v to w
+	Encode(w io.Writer, v any) error
+	// Decode reads from r into v (which must be a pointer)
+	Decode(r io.Reader, v any) error
+}
+
+// Serializable is implemented by all the containers in the library.
+type Serializable interface {
+	// Encode writes the content of the container to w using the given codec
+	Encode(w io.Writer, codec Codec) error
+	// Decode replaces the content of the container with the data read from r
+	Decode(r io.Reader, codec Codec) error
+}
+
+// Encodable is the encoding half of Serializable. Immutable containers (like
+// phashmap) implement only this one, as they are decoded into a new value.
+type Encodable interface {
+	// Encode writes the content of the container to w using the given codec
+	Encode(w io.Writer, codec Codec) error
+}
+
+// JSONCodec encodes values as JSON.
+type JSONCodec struct{}
+
+// Encode writes v to w as JSON
+func (JSONCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// Decode reads a JSON value from r into v
+func (JSONCodec) Decode(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// GobCodec encodes values using encoding/gob.
+type GobCodec struct{}
+
+// Encode writes v to w using gob
+func (GobCodec) Encode(w io.Writer, v any) error {
+	return gob.NewEncoder(w).Encode(v)
+}
+
+// Decode reads a gob value from r into v
+func (GobCodec) Decode(r io.Reader, v any) error {
+	return gob.NewDecoder(r).Decode(v)
+}
+
+// BinaryCodec encodes values using encoding/binary (little endian).
+// It only supports fixed-size values (for example int64, float32 or structs
+// of fixed-size fields) and slices of them. Slices are prefixed with their
+// length, so they can be decoded without knowing their size in advance.
+// Note that int and uint are not fixed-size types.
+type BinaryCodec struct{}
+
+// Encode writes v to w in binary form
+func (BinaryCodec) Encode(w io.Writer, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice {
+		if err := binary.Write(w, binary.LittleEndian, uint64(rv.Len())); err != nil {
+			return err
+		}
+	}
+	return binary.Write(w, binary.LittleEndian, v)
+}
+
+// Decode reads a binary value from r into v
+func (BinaryCodec) Decode(r io.Reader, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New(ErrInvalidDecodeDest)
+	}
+	if rv.Elem().Kind() != reflect.Slice {
+		return binary.Read(r, binary.LittleEndian, v)
+	}
+
+	var length uint64
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return err
+	}
+	items := reflect.MakeSlice(rv.Elem().Type(), 0, 0)
+	// Read the elements one by one, so a corrupted length can't make us
+	// allocate a huge slice upfront
+	elem := reflect.New(rv.Elem().Type().Elem())
+	for i := uint64(0); i < length; i++ {
+		if err := binary.Read(r, binary.LittleEndian, elem.Interface()); err != nil {
+			return err
+		}
+		items = reflect.Append(items, elem.Elem())
+	}
+	rv.Elem().Set(items)
+	return nil
+}
+
+// EncodeSlice writes items to w using the given codec. It is the helper used
+// by the containers to implement Serializable.
+func EncodeSlice[T any](w io.Writer, codec Codec, items []T) error {
+	if codec == nil {
+		return errors.New(ErrCodecIsNil)
+	}
+	if items == nil {
+		// some codecs (e.g. gob) can't encode nil values
+		items = []T{}
+	}
+	return codec.Encode(w, items)
+}
+
+// DecodeSlice reads a slice of items from r using the given codec.
+func DecodeSlice[T any](r io.Reader, codec Codec) ([]T, error) {
+	if codec == nil {
+		return nil, errors.New(ErrCodecIsNil)
+	}
+	var items []T
+	if err := codec.Decode(r, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// MarshalText returns the content of c as a JSON array (in the order used by
+// its Encode method). The containers use it to implement
+// encoding.TextMarshaler and json.Marshaler.
+func MarshalText(c Encodable) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, JSONCodec{}); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// UnmarshalText replaces the content of c with the JSON array in data.
+func UnmarshalText(c Serializable, data []byte) error {
+	return c.Decode(bytes.NewReader(data), JSONCodec{})
+}
+
+// MarshalBinary returns the content of c encoded with GobCodec. The
+// containers use it to implement encoding.BinaryMarshaler.
+func MarshalBinary(c Encodable) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, GobCodec{}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the content of c with the data written by
+// MarshalBinary.
+func UnmarshalBinary(c Serializable, data []byte) error {
+	return c.Decode(bytes.NewReader(data), GobCodec{})
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	abBuffer "github.com/pzaino/gods/pkg/abBuffer"
+	buffer "github.com/pzaino/gods/pkg/buffer"
+	circularLinkList "github.com/pzaino/gods/pkg/circularLinkList"
+	csBuffer "github.com/pzaino/gods/pkg/csBuffer"
+	csdlinkList "github.com/pzaino/gods/pkg/csdlinkList"
+	cslinkList "github.com/pzaino/gods/pkg/cslinkList"
+	csmap "github.com/pzaino/gods/pkg/csmap"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+	dlinkList "github.com/pzaino/gods/pkg/dlinkList"
+	linkList "github.com/pzaino/gods/pkg/linkList"
+	phashmap "github.com/pzaino/gods/pkg/phashmap"
+	pqueue "github.com/pzaino/gods/pkg/pqueue"
+	queue "github.com/pzaino/gods/pkg/queue"
+	ringBuffer "github.com/pzaino/gods/pkg/ringBuffer"
+	stack "github.com/pzaino/gods/pkg/stack"
+)
+
+// serializableCollection is what the round trip test needs from a container
+type serializableCollection interface {
+	gods.Collection[int64]
+	gods.Serializable
+}
+
+// Compile-time checks: every container must implement gods.Serializable
+var (
+	_ gods.Serializable = (*stack.Stack[int])(nil)
+	_ gods.Serializable = (*csstack.CSStack[int])(nil)
+	_ gods.Serializable = (*queue.Queue[int])(nil)
+	_ gods.Serializable = (*pqueue.PriorityQueue[int])(nil)
+	_ gods.Serializable = (*linkList.LinkList[int])(nil)
+	_ gods.Serializable = (*cslinkList.CSLinkList[int])(nil)
+	_ gods.Serializable = (*dlinkList.DLinkList[int])(nil)
+	_ gods.Serializable = (*csdlinkList.CSDLinkList[int])(nil)
+	_ gods.Serializable = (*circularLinkList.CircularLinkList[int])(nil)
+	_ gods.Serializable = (*buffer.Buffer[int])(nil)
+	_ gods.Serializable = (*csBuffer.ConcurrentBuffer[int])(nil)
+	_ gods.Serializable = (*ringBuffer.CircularBuffer[int])(nil)
+	_ gods.Serializable = (*abBuffer.ABBuffer[int])(nil)
+	_ gods.Serializable = (*csmap.CSMap[int, int])(nil)
+)
+
+func TestCodecsRoundTrip(t *testing.T) {
+	codecs := map[string]gods.Codec{
+		"json":   gods.JSONCodec{},
+		"gob":    gods.GobCodec{},
+		"binary": gods.BinaryCodec{},
+	}
+	for name, codec := range codecs {
+		for _, items := range [][]int64{nil, {1}, {1, -2, 3}} {
+			var buf bytes.Buffer
+			if err := gods.EncodeSlice(&buf, codec, items); err != nil {
+				t.Fatalf("%s: unexpected error %v", name, err)
+			}
+			got, err := gods.DecodeSlice[int64](&buf, codec)
+			if err != nil {
+				t.Fatalf("%s: unexpected error %v", name, err)
+			}
+			if !slices.Equal(got, items) {
+				t.Errorf("%s: "+errExpectedX, name, items, got)
+			}
+		}
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gods.EncodeSlice(&buf, nil, []int{1}); err == nil || err.Error() != gods.ErrCodecIsNil {
+		t.Errorf(errExpectedX, gods.ErrCodecIsNil, err)
+	}
+	if _, err := gods.DecodeSlice[int](&buf, nil); err == nil || err.Error() != gods.ErrCodecIsNil {
+		t.Errorf(errExpectedX, gods.ErrCodecIsNil, err)
+	}
+	if err := (gods.BinaryCodec{}).Decode(&buf, []int64{}); err == nil || err.Error() != gods.ErrInvalidDecodeDest {
+		t.Errorf(errExpectedX, gods.ErrInvalidDecodeDest, err)
+	}
+	// truncated input
+	if err := (gods.BinaryCodec{}).Encode(&buf, []int64{1, 2, 3}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	buf.Truncate(buf.Len() - 4)
+	if _, err := gods.DecodeSlice[int64](&buf, gods.BinaryCodec{}); err == nil {
+		t.Errorf("Expected an error decoding truncated data")
+	}
+}
+
+// containers creates an empty instance of every container
+var containers = map[string]func() serializableCollection{
+	"stack":            func() serializableCollection { return stack.New[int64]() },
+	"csstack":          func() serializableCollection { return csstack.New[int64]() },
+	"queue":            func() serializableCollection { return queue.New[int64]() },
+	"linkList":         func() serializableCollection { return linkList.New[int64]() },
+	"cslinkList":       func() serializableCollection { return cslinkList.New[int64]() },
+	"dlinkList":        func() serializableCollection { return dlinkList.New[int64]() },
+	"csdlinkList":      func() serializableCollection { return csdlinkList.New[int64]() },
+	"circularLinkList": func() serializableCollection { return circularLinkList.New[int64]() },
+	"buffer":           func() serializableCollection { return buffer.New[int64]() },
+	"csBuffer":         func() serializableCollection { return csBuffer.New[int64]() },
+	"ringBuffer":       func() serializableCollection { return ringBuffer.New[int64](10) },
+	"abBuffer":         func() serializableCollection { return abBuffer.New[int64](10) },
+}
+
+func TestContainersRoundTrip(t *testing.T) {
+	src := []int64{1, 2, 3, 4}
+	for name, newContainer := range containers {
+		for _, codec := range []gods.Codec{gods.JSONCodec{}, gods.GobCodec{}, gods.BinaryCodec{}} {
+			// fill the source container by decoding a plain slice
+			var buf bytes.Buffer
+			_ = gods.EncodeSlice(&buf, codec, src)
+			c := newContainer()
+			if err := c.Decode(&buf, codec); err != nil {
+				t.Fatalf("%s: unexpected error %v", name, err)
+			}
+			want := c.ToSlice()
+
+			buf.Reset()
+			if err := c.Encode(&buf, codec); err != nil {
+				t.Fatalf("%s: unexpected error %v", name, err)
+			}
+			d := newContainer()
+			if err := d.Decode(&buf, codec); err != nil {
+				t.Fatalf("%s: unexpected error %v", name, err)
+			}
+			if got := d.ToSlice(); !slices.Equal(got, want) {
+				t.Errorf("%s: "+errExpectedX, name, want, got)
+			}
+		}
+	}
+}
+
+// standardMarshaler is what the standard encoding packages look for
+type standardMarshaler interface {
+	serializableCollection
+	encoding.TextMarshaler
+	encoding.TextUnmarshaler
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+	json.Marshaler
+	json.Unmarshaler
+}
+
+func TestStandardMarshalers(t *testing.T) {
+	for name, newContainer := range containers {
+		c, ok := newContainer().(standardMarshaler)
+		if !ok {
+			t.Fatalf("%s doesn't implement the standard marshalers", name)
+		}
+		if err := c.UnmarshalText([]byte("[1,2,3,4]")); err != nil {
+			t.Fatalf("%s: unexpected error %v", name, err)
+		}
+		want := c.ToSlice()
+		if text, _ := c.MarshalText(); string(text) != "[1,2,3,4]" {
+			t.Errorf("%s: "+errExpectedX, name, "[1,2,3,4]", string(text))
+		}
+
+		// the encoding packages use the containers as they are
+		data, err := c.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", name, err)
+		}
+		d := newContainer().(standardMarshaler)
+		if err := d.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: unexpected error %v", name, err)
+		}
+		if got := d.ToSlice(); !slices.Equal(got, want) {
+			t.Errorf("%s: "+errExpectedX, name, want, got)
+		}
+		data, err = json.Marshal(map[string]any{"items": c})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", name, err)
+		}
+		var decoded struct{ Items standardMarshaler }
+		decoded.Items = newContainer().(standardMarshaler)
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%s: unexpected error %v", name, err)
+		}
+		if got := decoded.Items.ToSlice(); !slices.Equal(got, want) {
+			t.Errorf("%s: "+errExpectedX, name, want, got)
+		}
+	}
+}
+
+func TestZeroConcurrentContainersUnmarshal(t *testing.T) {
+	var config struct {
+		Stack *csstack.CSStack[int]
+		List  *cslinkList.CSLinkList[int]
+		DList *csdlinkList.CSDLinkList[int]
+		Buf   *csBuffer.ConcurrentBuffer[int]
+	}
+	data := `{"Stack": [2, 1], "List": [1, 2], "DList": [1, 2], "Buf": [1, 2]}`
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if got := config.Stack.ToSlice(); !slices.Equal(got, []int{2, 1}) {
+		t.Errorf(errExpectedX, []int{2, 1}, got)
+	}
+	for _, got := range [][]int{config.List.ToSlice(), config.DList.ToSlice(), config.Buf.ToSlice()} {
+		if !slices.Equal(got, []int{1, 2}) {
+			t.Errorf(errExpectedX, []int{1, 2}, got)
+		}
+	}
+	config.Stack.Push(3)
+	if config.Stack.Size() != 3 {
+		t.Errorf(errExpectedX, 3, config.Stack.Size())
+	}
+}
+
+func TestMarshalImmutableMap(t *testing.T) {
+	m := phashmap.New[string, int]().Assoc("a", 1)
+	text, err := m.MarshalText()
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	decoded, err := phashmap.Decode[string, int](bytes.NewReader(text), gods.JSONCodec{})
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if v, err := decoded.Get("a"); err != nil || v != 1 {
+		t.Errorf(errExpectedX, 1, v)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ContentionStats are the lock statistics collected by a concurrent container
+// created with the WithContentionStats option.
+type ContentionStats struct {
+	// Acquisitions is the number of times the lock was acquired for writing
+	Acquisitions uint64
+	// ReadAcquisitions is the number of times the lock was acquired for reading
+	ReadAcquisitions uint64
+	// WaitTime is the total time spent waiting to acquire the lock for writing
+	WaitTime time.Duration
+	// ReadWaitTime is the total time spent waiting to acquire the lock for reading
+	ReadWaitTime time.Duration
+	// MaxWaitTime is the longest wait for a single acquisition (of any kind)
+	MaxWaitTime time.Duration
+	// HoldTime is the total time the lock was held for writing
+	HoldTime time.Duration
+	// ReadHoldTime is the total time the lock was held by at least one reader
+	ReadHoldTime time.Duration
+}
+
+// AvgWaitTime returns the average wait per acquisition (of any kind).
+func (s ContentionStats) AvgWaitTime() time.Duration {
+	n := s.Acquisitions + s.ReadAcquisitions
+	if n == 0 {
+		return 0
+	}
+	return (s.WaitTime + s.ReadWaitTime) / time.Duration(n)
+}
+
+// WithContentionStats enables the collection of lock statistics, returned by
+// the ContentionStats method of the concurrent containers. It can be combined
+// with any lock strategy. Collecting the statistics has a cost, so it is meant
+// for profiling rather than production use.
+func WithContentionStats() Option {
+	return func(o *Options) {
+		o.ContentionStats = true
+	}
+}
+
+// LockContentionStats returns the statistics collected by a locker created by
+// NewLocker with the WithContentionStats option. For any other locker it
+// returns zero statistics.
+func LockContentionStats(l RWLocker) ContentionStats {
+	for {
+		if p, ok := l.(*profiledLocker); ok {
+			return p.stats()
+		}
+		u, ok := l.(interface{ unwrap() RWLocker })
+		if !ok {
+			return ContentionStats{}
+		}
+		l = u.unwrap()
+	}
+}
+
+// profiledLocker wraps an RWLocker and records its statistics
+type profiledLocker struct {
+	locker RWLocker
+
+	acquisitions     atomic.Uint64
+	readAcquisitions atomic.Uint64
+	waitTime         atomic.Int64
+	readWaitTime     atomic.Int64
+	maxWaitTime      atomic.Int64
+	holdTime         atomic.Int64
+	lockedAt         time.Time // protected by the write lock
+
+	// readers and readLockedAt track the periods in which at least one
+	// reader holds the lock
+	readMu       sync.Mutex
+	readers      int
+	readLockedAt time.Time
+	readHoldTime time.Duration
+}
+
+// unwrap returns the profiled locker
+func (p *profiledLocker) unwrap() RWLocker {
+	return p.locker
+}
+
+func (p *profiledLocker) recordWait(wait time.Duration) {
+	for {
+		max := p.maxWaitTime.Load()
+		if int64(wait) <= max || p.maxWaitTime.CompareAndSwap(max, int64(wait)) {
+			return
+		}
+	}
+}
+
+// Lock acquires the lock for writing
+func (p *profiledLocker) Lock() {
+	start := time.Now()
+	p.locker.Lock()
+	p.lockedAt = time.Now()
+	wait := p.lockedAt.Sub(start)
+	p.acquisitions.Add(1)
+	p.waitTime.Add(int64(wait))
+	p.recordWait(wait)
+}
+
+// Unlock releases the write lock
+func (p *profiledLocker) Unlock() {
+	p.holdTime.Add(int64(time.Since(p.lockedAt)))
+	p.locker.Unlock()
+}
+
+// RLock acquires the lock for reading
+func (p *profiledLocker) RLock() {
+	start := time.Now()
+	p.locker.RLock()
+	now := time.Now()
+	wait := now.Sub(start)
+	p.readAcquisitions.Add(1)
+	p.readWaitTime.Add(int64(wait))
+	p.recordWait(wait)
+
+	p.readMu.Lock()
+	if p.readers == 0 {
+		p.readLockedAt = now
+	}
+	p.readers++
+	p.readMu.Unlock()
+}
+
+// RUnlock releases a read lock
+func (p *profiledLocker) RUnlock() {
+	p.readMu.Lock()
+	p.readers--
+	if p.readers == 0 {
+		p.readHoldTime += time.Since(p.readLockedAt)
+	}
+	p.readMu.Unlock()
+	p.locker.RUnlock()
+}
+
+func (p *profiledLocker) stats() ContentionStats {
+	p.readMu.Lock()
+	readHold := p.readHoldTime
+	if p.readers > 0 {
+		readHold += time.Since(p.readLockedAt)
+	}
+	p.readMu.Unlock()
+	return ContentionStats{
+		Acquisitions:     p.acquisitions.Load(),
+		ReadAcquisitions: p.readAcquisitions.Load(),
+		WaitTime:         time.Duration(p.waitTime.Load()),
+		ReadWaitTime:     time.Duration(p.readWaitTime.Load()),
+		MaxWaitTime:      time.Duration(p.maxWaitTime.Load()),
+		HoldTime:         time.Duration(p.holdTime.Load()),
+		ReadHoldTime:     readHold,
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	gods "github.com/pzaino/gods"
+)
+
+func TestContentionStats(t *testing.T) {
+	for name, opt := range map[string]gods.Option{
+		"rwmutex": gods.WithRWMutex(),
+		"mutex":   gods.WithMutex(),
+		"sharded": gods.WithSharding(4),
+	} {
+		t.Run(name, func(t *testing.T) {
+			l := gods.NewLocker(opt, gods.WithContentionStats())
+			var wg sync.WaitGroup
+			for g := 0; g < 4; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 10; i++ {
+						l.Lock()
+						time.Sleep(100 * time.Microsecond)
+						l.Unlock()
+						l.RLock()
+						l.RUnlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			stats := gods.LockContentionStats(l)
+			if stats.Acquisitions != 40 || stats.ReadAcquisitions != 40 {
+				t.Errorf(errExpectedX, 40, stats.Acquisitions)
+			}
+			if stats.HoldTime < 40*100*time.Microsecond {
+				t.Errorf("Expected a hold time of at least 4ms, got %v", stats.HoldTime)
+			}
+			if stats.WaitTime == 0 || stats.MaxWaitTime == 0 || stats.AvgWaitTime() == 0 {
+				t.Errorf("Expected some wait time, got %+v", stats)
+			}
+		})
+	}
+}
+
+func TestContentionStatsDisabled(t *testing.T) {
+	l := gods.NewLocker()
+	l.Lock()
+	l.Unlock()
+	if stats := gods.LockContentionStats(l); stats != (gods.ContentionStats{}) {
+		t.Errorf("Expected no statistics, got %+v", stats)
+	}
+	if avg := (gods.ContentionStats{}).AvgWaitTime(); avg != 0 {
+		t.Errorf(errExpectedX, 0, avg)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Copier is implemented by the containers that can be deep copied (like
+// *stack.Stack[T], *buffer.Buffer[T] or *linkList.LinkList[T]).
+type Copier[C any] interface {
+	Copy() C
+}
+
+// COW is a copy-on-write wrapper around a container, for read-mostly
+// workloads. Readers get an immutable snapshot of the container with a single
+// atomic load and never block; writers copy the current snapshot, modify the
+// copy and publish it atomically. Writers are serialized, and every write
+// costs a full copy of the container.
+type COW[C Copier[C]] struct {
+	current atomic.Pointer[C]
+	writer  sync.Mutex
+}
+
+// CopyOnWrite wraps a container in a copy-on-write wrapper. The container
+// must not be used directly any more:
+//
+//	s := gods.CopyOnWrite(stack.New[int]())
+//	_ = s.Update(func(st *stack.Stack[int]) error { st.Push(1); return nil })
+//	top, _ := s.Load().Peek()
+func CopyOnWrite[C Copier[C]](container C) *COW[C] {
+	c := &COW[C]{}
+	c.current.Store(&container)
+	return c
+}
+
+// Load returns the current snapshot of the container. The snapshot is shared
+// by all the readers, so it must not be modified; it is not affected by the
+// updates that happen after Load returns.
+func (c *COW[C]) Load() C {
+	return *c.current.Load()
+}
+
+// Update applies fn to a copy of the container and publishes the copy. If fn
+// returns an error the copy is discarded (so the update is all or nothing)
+// and the error is returned.
+func (c *COW[C]) Update(fn func(C) error) error {
+	c.writer.Lock()
+	defer c.writer.Unlock()
+	next := (*c.current.Load()).Copy()
+	if err := fn(next); err != nil {
+		return err
+	}
+	c.current.Store(&next)
+	return nil
+}
+
+// Store replaces the container with another one (which must not be used
+// directly any more).
+func (c *COW[C]) Store(container C) {
+	c.writer.Lock()
+	defer c.writer.Unlock()
+	c.current.Store(&container)
+}
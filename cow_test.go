@@ -0,0 +1,88 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	buffer "github.com/pzaino/gods/pkg/buffer"
+	stack "github.com/pzaino/gods/pkg/stack"
+)
+
+func TestCopyOnWrite(t *testing.T) {
+	s := gods.CopyOnWrite(stack.New[int]())
+	snapshot := s.Load()
+
+	err := s.Update(func(st *stack.Stack[int]) error {
+		st.Push(1)
+		return nil
+	})
+	if err != nil {
+		t.Errorf(errUnexpectedErr, err)
+	}
+	if !snapshot.IsEmpty() {
+		t.Errorf("Expected the old snapshot to be unchanged")
+	}
+	if top, err := s.Load().Peek(); err != nil || *top != 1 {
+		t.Errorf(errExpectedX, 1, top)
+	}
+
+	// a failed update is discarded
+	errFailed := errors.New("failed")
+	err = s.Update(func(st *stack.Stack[int]) error {
+		st.Push(2)
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) || s.Load().Size() != 1 {
+		t.Errorf(errExpectedX, 1, s.Load().Size())
+	}
+
+	s.Store(stack.New[int]())
+	if !s.Load().IsEmpty() {
+		t.Errorf("Expected an empty stack")
+	}
+}
+
+func TestCopyOnWriteConcurrent(t *testing.T) {
+	b := gods.CopyOnWrite(buffer.New[int]())
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				_ = b.Update(func(buf *buffer.Buffer[int]) error {
+					return buf.Append(i)
+				})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				snapshot := b.Load()
+				if uint64(len(snapshot.ToSlice())) != snapshot.Size() {
+					t.Errorf("Expected a consistent snapshot")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if b.Load().Size() != 200 {
+		t.Errorf(errExpectedX, 200, b.Load().Size())
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// ToCSV writes the elements of seq (for example c.Iter() of any Collection)
+// to w as CSV records, one per element, built by row. If header is not nil,
+// the record it returns is written first.
+//
+//	err := gods.ToCSV(w, q.Iter(), func() []string { return []string{"id", "name"} },
+//		func(u User) []string { return []string{strconv.Itoa(u.ID), u.Name} })
+func ToCSV[T any](w io.Writer, seq iter.Seq[T], header func() []string, row func(T) []string) error {
+	cw := csv.NewWriter(w)
+	if header != nil {
+		if err := cw.Write(header()); err != nil {
+			return err
+		}
+	}
+	for v := range seq {
+		if err := cw.Write(row(v)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// MapToCSV is like ToCSV for the key/value pairs of a map (for example the
+// All() iterator of a csmap or phashmap).
+func MapToCSV[K, V any](w io.Writer, seq iter.Seq2[K, V], header func() []string, row func(K, V) []string) error {
+	return ToCSV(w, func(yield func(pair[K, V]) bool) {
+		for k, v := range seq {
+			if !yield(pair[K, V]{k, v}) {
+				return
+			}
+		}
+	}, header, func(p pair[K, V]) []string { return row(p.key, p.value) })
+}
+
+// pair is a key/value pair
+type pair[K, V any] struct {
+	key   K
+	value V
+}
+
+// FromCSV reads the CSV records of r, converting each one into an element with
+// parse, and returns the elements in order (ready for a NewFromSlice
+// constructor). If skipHeader is true the first record is ignored. The
+// errors returned by parse are wrapped with the line of the record.
+func FromCSV[T any](r io.Reader, skipHeader bool, parse func(record []string) (T, error)) ([]T, error) {
+	cr := csv.NewReader(r)
+	var items []T
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if skipHeader {
+			skipHeader = false
+			continue
+		}
+		v, err := parse(rec)
+		if err != nil {
+			line, _ := cr.FieldPos(0)
+			return nil, fmt.Errorf("csv line %d: %w", line, err)
+		}
+		items = append(items, v)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	csmap "github.com/pzaino/gods/pkg/csmap"
+	linkList "github.com/pzaino/gods/pkg/linkList"
+)
+
+type user struct {
+	ID   int
+	Name string
+}
+
+func parseUser(rec []string) (user, error) {
+	if len(rec) != 2 {
+		return user{}, errors.New("expected 2 fields")
+	}
+	id, err := strconv.Atoi(rec[0])
+	return user{ID: id, Name: rec[1]}, err
+}
+
+func TestCSV(t *testing.T) {
+	l := linkList.NewFromSlice([]user{{1, "Ann"}, {2, "Bob, Jr."}})
+	var buf bytes.Buffer
+	err := gods.ToCSV(&buf, l.Iter(), func() []string { return []string{"id", "name"} },
+		func(u user) []string { return []string{strconv.Itoa(u.ID), u.Name} })
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if want := "id,name\n1,Ann\n2,\"Bob, Jr.\"\n"; buf.String() != want {
+		t.Errorf(errExpectedX, want, buf.String())
+	}
+
+	users, err := gods.FromCSV(&buf, true, parseUser)
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if restored := linkList.NewFromSlice(users); !slices.Equal(restored.ToSlice(), l.ToSlice()) {
+		t.Errorf(errExpectedX, l.ToSlice(), restored.ToSlice())
+	}
+
+	_, err = gods.FromCSV(strings.NewReader("1,Ann\nx,Bob\n"), false, parseUser)
+	if err == nil || !strings.HasPrefix(err.Error(), "csv line 2:") {
+		t.Errorf(errExpectedX, "an error on line 2", err)
+	}
+}
+
+func TestMapToCSV(t *testing.T) {
+	m := csmap.NewFromMap(map[string]int{"a": 1, "b": 2})
+	var buf bytes.Buffer
+	err := gods.MapToCSV(&buf, m.All(), nil, func(k string, v int) []string { return []string{k, strconv.Itoa(v)} })
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	type entry struct {
+		k string
+		v int
+	}
+	entries, err := gods.FromCSV(&buf, false, func(rec []string) (entry, error) {
+		v, err := strconv.Atoi(rec[1])
+		return entry{rec[0], v}, err
+	})
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	restored := map[string]int{}
+	for _, e := range entries {
+		restored[e.k] = e.v
+	}
+	if !maps.Equal(restored, m.ToMap()) {
+		t.Errorf(errExpectedX, m.ToMap(), restored)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Dumper writes the lines of the Dump methods of the containers, which render
+// their internal structure (the chain of a list, the layout of a ring, the
+// shape of a heap or of a trie) for debugging. It keeps the first write error,
+// so the Dump methods can write all their lines and return Err at the end.
+type Dumper struct {
+	w   io.Writer
+	err error
+}
+
+// NewDumper returns a Dumper writing to w.
+func NewDumper(w io.Writer) *Dumper {
+	return &Dumper{w: w}
+}
+
+// Line writes a line formatted like fmt.Printf, indented by depth levels.
+func (d *Dumper) Line(depth int, format string, args ...any) {
+	if d.err != nil {
+		return
+	}
+	_, d.err = fmt.Fprintf(d.w, "%s"+format+"\n", append([]any{strings.Repeat("  ", depth)}, args...)...)
+}
+
+// Err returns the first error returned by the writer.
+func (d *Dumper) Err() error {
+	return d.err
+}
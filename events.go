@@ -0,0 +1,209 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// EventKind is the kind of change described by an Event.
+type EventKind int
+
+const (
+	// EventAdded is published when an element (or a key) is added
+	EventAdded EventKind = iota + 1
+	// EventUpdated is published when the value of a key is replaced
+	EventUpdated
+	// EventRemoved is published when an element is removed by the user
+	EventRemoved
+	// EventEvicted is published when an element is removed by the
+	// container (for example to make room in a bounded container)
+	EventEvicted
+	// EventCleared is published when all the elements are removed at once
+	EventCleared
+)
+
+// String returns the name of the kind
+func (k EventKind) String() string {
+	switch k {
+	case EventAdded:
+		return "added"
+	case EventUpdated:
+		return "updated"
+	case EventRemoved:
+		return "removed"
+	case EventEvicted:
+		return "evicted"
+	case EventCleared:
+		return "cleared"
+	}
+	return "unknown"
+}
+
+// Event is a change of a container, published on its Bus. Key is the key of
+// the entry (or the zero value for the containers without keys) and Value its
+// value (the new one for EventAdded and EventUpdated, the old one for
+// EventRemoved and EventEvicted). Key and Value are not set for EventCleared.
+type Event[K, V any] struct {
+	Kind  EventKind
+	Key   K
+	Value V
+}
+
+// OverflowPolicy selects what Publish does when the channel of a subscriber
+// is full.
+type OverflowPolicy int
+
+const (
+	// DropNewest drops the event being published (the default)
+	DropNewest OverflowPolicy = iota
+	// DropOldest drops the oldest event in the channel to make room
+	DropOldest
+	// Block waits until the subscriber receives an event (or closes the
+	// subscription), stalling the container meanwhile
+	Block
+)
+
+// Bus delivers the events of a container to its subscribers over buffered
+// channels. Its zero value is ready to use: the containers embed it, publish
+// their changes while they hold the write lock (so the events are delivered
+// in the order of the changes) and expose its Subscribe method. Publishing
+// with no subscribers costs an atomic load.
+type Bus[K, V any] struct {
+	mu   sync.Mutex // serializes Subscribe and Close
+	subs atomic.Pointer[[]*Subscription[K, V]]
+}
+
+// Subscription receives the events of a Bus on the channel returned by C.
+type Subscription[K, V any] struct {
+	bus     *Bus[K, V]
+	ch      chan Event[K, V]
+	policy  OverflowPolicy
+	dropped atomic.Uint64
+
+	mu     sync.Mutex // held while sending, so Close doesn't close ch under a sender
+	closed bool
+	done   chan struct{} // closed by Close, to unblock a Block send
+	once   sync.Once
+}
+
+// Subscribe returns a subscription receiving the events published from now
+// on, with a channel buffering up to buffer events (at least 1). policy
+// selects what happens when the channel is full.
+func (b *Bus[K, V]) Subscribe(buffer int, policy OverflowPolicy) *Subscription[K, V] {
+	s := &Subscription[K, V]{
+		bus:    b,
+		ch:     make(chan Event[K, V], max(buffer, 1)),
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var subs []*Subscription[K, V]
+	if p := b.subs.Load(); p != nil {
+		subs = *p
+	}
+	subs = append(subs[:len(subs):len(subs)], s)
+	b.subs.Store(&subs)
+	return s
+}
+
+// Publish delivers e to the subscribers.
+func (b *Bus[K, V]) Publish(e Event[K, V]) {
+	p := b.subs.Load()
+	if p == nil {
+		return
+	}
+	for _, s := range *p {
+		s.send(e)
+	}
+}
+
+// Active returns true if the bus has subscribers, so the publishers can skip
+// building the events nobody receives.
+func (b *Bus[K, V]) Active() bool {
+	p := b.subs.Load()
+	return p != nil && len(*p) > 0
+}
+
+// C returns the channel of the subscription. It is closed by Close.
+func (s *Subscription[K, V]) C() <-chan Event[K, V] {
+	return s.ch
+}
+
+// Dropped returns the number of events dropped because the channel was full.
+func (s *Subscription[K, V]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close removes the subscription from its bus and closes its channel.
+func (s *Subscription[K, V]) Close() {
+	b := s.bus
+	b.mu.Lock()
+	if p := b.subs.Load(); p != nil {
+		subs := make([]*Subscription[K, V], 0, len(*p))
+		for _, o := range *p {
+			if o != s {
+				subs = append(subs, o)
+			}
+		}
+		b.subs.Store(&subs)
+	}
+	b.mu.Unlock()
+
+	s.once.Do(func() { close(s.done) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// send delivers e to the subscriber following its overflow policy
+func (s *Subscription[K, V]) send(e Event[K, V]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	switch s.policy {
+	case Block:
+		select {
+		case s.ch <- e:
+		case <-s.done:
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.ch <- e:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
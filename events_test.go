@@ -0,0 +1,86 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"testing"
+	"time"
+
+	gods "github.com/pzaino/gods"
+)
+
+func TestBusOverflowPolicies(t *testing.T) {
+	var bus gods.Bus[string, int]
+	if bus.Active() {
+		t.Errorf("Expected a bus without subscribers to be inactive")
+	}
+	newest := bus.Subscribe(2, gods.DropNewest)
+	oldest := bus.Subscribe(2, gods.DropOldest)
+	for i := 1; i <= 3; i++ {
+		bus.Publish(gods.Event[string, int]{Kind: gods.EventAdded, Key: "k", Value: i})
+	}
+	for _, tc := range []struct {
+		sub  *gods.Subscription[string, int]
+		want []int
+	}{{newest, []int{1, 2}}, {oldest, []int{2, 3}}} {
+		if tc.sub.Dropped() != 1 {
+			t.Errorf(errExpectedX, 1, tc.sub.Dropped())
+		}
+		tc.sub.Close()
+		var got []int
+		for e := range tc.sub.C() {
+			got = append(got, e.Value)
+		}
+		if len(got) != 2 || got[0] != tc.want[0] || got[1] != tc.want[1] {
+			t.Errorf(errExpectedX, tc.want, got)
+		}
+	}
+	if bus.Active() {
+		t.Errorf("Expected the bus to be inactive once the subscriptions are closed")
+	}
+	// publishing without subscribers is a no-op
+	bus.Publish(gods.Event[string, int]{Kind: gods.EventCleared})
+}
+
+func TestBusBlock(t *testing.T) {
+	var bus gods.Bus[int, int]
+	sub := bus.Subscribe(1, gods.Block)
+	bus.Publish(gods.Event[int, int]{Kind: gods.EventAdded, Value: 1})
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(gods.Event[int, int]{Kind: gods.EventAdded, Value: 2})
+		close(published)
+	}()
+	select {
+	case <-published:
+		t.Fatalf("Expected Publish to block while the channel is full")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if e := <-sub.C(); e.Value != 1 {
+		t.Errorf(errExpectedX, 1, e.Value)
+	}
+	<-published
+
+	// Close unblocks a blocked publisher
+	go bus.Publish(gods.Event[int, int]{Kind: gods.EventAdded, Value: 3})
+	time.Sleep(5 * time.Millisecond)
+	sub.Close()
+	if sub.Dropped() != 0 {
+		t.Errorf(errExpectedX, 0, sub.Dropped())
+	}
+	if gods.EventEvicted.String() != "evicted" {
+		t.Errorf(errExpectedX, "evicted", gods.EventEvicted.String())
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import "expvar"
+
+// Sizer is implemented by every container.
+type Sizer interface {
+	// Size returns the number of elements in the container
+	Size() uint64
+}
+
+// Metrics are the metrics of a container, as read by ReadMetrics.
+type Metrics struct {
+	// Size is the number of elements
+	Size uint64 `json:"size"`
+	// Capacity is the capacity of the containers with a Capacity method (like
+	// the buffers), nil for the others
+	Capacity *uint64 `json:"capacity,omitempty"`
+	// Lock are the lock statistics of the concurrent containers (collected
+	// only if they were created with WithContentionStats), nil for the others
+	Lock *ContentionStats `json:"lock,omitempty"`
+}
+
+// ReadMetrics returns the current metrics of c.
+func ReadMetrics(c Sizer) Metrics {
+	m := Metrics{Size: c.Size()}
+	if cc, ok := c.(interface{ Capacity() uint64 }); ok {
+		capacity := cc.Capacity()
+		m.Capacity = &capacity
+	}
+	if cs, ok := c.(interface{ ContentionStats() ContentionStats }); ok {
+		stats := cs.ContentionStats()
+		m.Lock = &stats
+	}
+	return m
+}
+
+// PublishExpvar publishes the metrics of c (see ReadMetrics) in expvar under
+// name, so they are served by /debug/vars. The metrics are read every time
+// the variable is read; the number of write and read acquisitions of the lock
+// is the number of operations.
+//
+// As with expvar.Publish, it panics if name is already in use.
+func PublishExpvar(name string, c Sizer) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return ReadMetrics(c)
+	}))
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	buffer "github.com/pzaino/gods/pkg/buffer"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+)
+
+// expvarRuns makes the published names unique across runs (go test -count=N),
+// since expvar panics when a name is published twice.
+var expvarRuns atomic.Uint64
+
+func TestPublishExpvar(t *testing.T) {
+	run := expvarRuns.Add(1)
+	stackName := fmt.Sprintf("%s.stack.%d", t.Name(), run)
+	bufferName := fmt.Sprintf("%s.buffer.%d", t.Name(), run)
+
+	s := csstack.New[int](gods.WithContentionStats())
+	gods.PublishExpvar(stackName, s)
+	s.Push(1)
+	s.Push(2)
+	_ = s.ToSlice()
+
+	var m struct {
+		Size     uint64
+		Capacity *uint64
+		Lock     *gods.ContentionStats
+	}
+	if err := json.Unmarshal([]byte(expvar.Get(stackName).String()), &m); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if m.Size != 2 || m.Capacity != nil || m.Lock == nil || m.Lock.Acquisitions != 2 || m.Lock.ReadAcquisitions != 1 {
+		t.Errorf(errExpectedX, "size 2, 2 writes and 1 read", expvar.Get(stackName).String())
+	}
+
+	b := buffer.New[int]()
+	b.SetCapacity(10)
+	gods.PublishExpvar(bufferName, b)
+	m.Capacity, m.Lock = nil, nil
+	if err := json.Unmarshal([]byte(expvar.Get(bufferName).String()), &m); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if m.Size != 0 || m.Capacity == nil || *m.Capacity != 10 || m.Lock != nil {
+		t.Errorf(errExpectedX, "capacity 10", expvar.Get(bufferName).String())
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"errors"
+	"iter"
+	"slices"
+)
+
+const (
+	ErrFrozenIndex = "index out of bounds"
+)
+
+// Frozen is an immutable view of the content of a container, returned by
+// the Freeze methods: it has no mutating method at all, so it can be handed
+// to code that must not change the collection (plugins, callbacks, other
+// goroutines) with the compiler enforcing it. It is a copy taken when
+// Freeze is called, so it's not affected by the later changes to the
+// container, and it can be read concurrently without locking.
+//
+// The elements are in the order of the ToSlice method of the container.
+type Frozen[T comparable] struct {
+	items []T
+}
+
+// NewFrozen returns a Frozen view of items, which must not be changed any
+// more (the containers pass a copy of their content).
+func NewFrozen[T comparable](items []T) *Frozen[T] {
+	return &Frozen[T]{items: items}
+}
+
+// Size returns the number of elements
+func (f *Frozen[T]) Size() uint64 {
+	return uint64(len(f.items))
+}
+
+// IsEmpty returns true if there are no elements
+func (f *Frozen[T]) IsEmpty() bool {
+	return len(f.items) == 0
+}
+
+// Get returns the element at the given index
+func (f *Frozen[T]) Get(index uint64) (T, error) {
+	if index >= uint64(len(f.items)) {
+		var zero T
+		return zero, errors.New(ErrFrozenIndex)
+	}
+	return f.items[index], nil
+}
+
+// Contains returns true if value is one of the elements
+func (f *Frozen[T]) Contains(value T) bool {
+	return slices.Contains(f.items, value)
+}
+
+// IndexOf returns the index of the first element equal to value, or -1
+func (f *Frozen[T]) IndexOf(value T) int {
+	return slices.Index(f.items, value)
+}
+
+// ToSlice returns a copy of the elements
+func (f *Frozen[T]) ToSlice() []T {
+	return slices.Clone(f.items)
+}
+
+// Iter returns an iterator over the elements
+func (f *Frozen[T]) Iter() iter.Seq[T] {
+	return slices.Values(f.items)
+}
+
+// Enumerate returns an iterator over the indexes and the elements
+func (f *Frozen[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		for i, v := range f.items {
+			if !yield(uint64(i), v) {
+				return
+			}
+		}
+	}
+}
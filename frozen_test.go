@@ -0,0 +1,100 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"slices"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	buffer "github.com/pzaino/gods/pkg/buffer"
+	csBuffer "github.com/pzaino/gods/pkg/csBuffer"
+	csdlinkList "github.com/pzaino/gods/pkg/csdlinkList"
+	cslinkList "github.com/pzaino/gods/pkg/cslinkList"
+	csqueue "github.com/pzaino/gods/pkg/csqueue"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+	dlinkList "github.com/pzaino/gods/pkg/dlinkList"
+	linkList "github.com/pzaino/gods/pkg/linkList"
+	queue "github.com/pzaino/gods/pkg/queue"
+	stack "github.com/pzaino/gods/pkg/stack"
+)
+
+type freezer interface {
+	Freeze() *gods.Frozen[int]
+}
+
+func TestFreeze(t *testing.T) {
+	s, cs := stack.New[int](), csstack.New[int]()
+	q, cq := queue.New[int](), csqueue.New[int]()
+	b, cb := buffer.New[int](), csBuffer.New[int]()
+	l, cl := linkList.New[int](), cslinkList.New[int]()
+	d, cd := dlinkList.New[int](), csdlinkList.New[int]()
+	for _, v := range []int{1, 2, 3} {
+		s.Push(v)
+		cs.Push(v)
+		q.Enqueue(v)
+		_ = cq.Enqueue(v)
+		_ = b.Append(v)
+		_ = cb.Append(v)
+		l.Append(v)
+		cl.Append(v)
+		d.Append(v)
+		cd.Append(v)
+	}
+	for _, tc := range []struct {
+		name   string
+		c      freezer
+		want   []int
+		change func()
+	}{
+		{"stack", s, []int{3, 2, 1}, func() { s.Push(4) }},
+		{"csstack", cs, []int{3, 2, 1}, func() { cs.Push(4) }},
+		{"queue", q, []int{1, 2, 3}, func() { q.Dequeue() }},
+		{"csqueue", cq, []int{1, 2, 3}, func() { cq.Dequeue() }},
+		{"buffer", b, []int{1, 2, 3}, func() { _ = b.Set(0, 9) }},
+		{"csBuffer", cb, []int{1, 2, 3}, func() { _ = cb.Append(9) }},
+		{"linkList", l, []int{1, 2, 3}, func() { l.Reverse() }},
+		{"cslinkList", cl, []int{1, 2, 3}, func() { cl.Reverse() }},
+		{"dlinkList", d, []int{1, 2, 3}, func() { d.DeleteFirst() }},
+		{"csdlinkList", cd, []int{1, 2, 3}, func() { cd.DeleteFirst() }},
+	} {
+		f := tc.c.Freeze()
+		// the view is a copy: changing the container doesn't change it
+		tc.change()
+		if got := f.ToSlice(); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: "+errExpectedX, tc.name, tc.want, got)
+		}
+	}
+
+	f := gods.NewFrozen([]int{5, 6, 7})
+	if f.Size() != 3 || f.IsEmpty() || !f.Contains(6) || f.Contains(8) || f.IndexOf(7) != 2 {
+		t.Errorf(errExpectedX, []int{5, 6, 7}, f.ToSlice())
+	}
+	if v, err := f.Get(1); err != nil || v != 6 {
+		t.Errorf(errExpectedX, 6, v)
+	}
+	if _, err := f.Get(3); err == nil || err.Error() != gods.ErrFrozenIndex {
+		t.Errorf(errExpectedX, gods.ErrFrozenIndex, err)
+	}
+	f.ToSlice()[0] = 0
+	if got := slices.Collect(f.Iter()); !slices.Equal(got, []int{5, 6, 7}) {
+		t.Errorf(errExpectedX, []int{5, 6, 7}, got)
+	}
+	for i, v := range f.Enumerate() {
+		if v != int(i)+5 {
+			t.Errorf(errExpectedX, int(i)+5, v)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gods provides the interfaces and the generic helpers shared by all
+// the data structures in the pkg directory.
+//
+// This package does not import any of the data structures, so every data
+// structure package can import it without creating import cycles.
+package gods
+
+import (
+	"context"
+	"iter"
+	"log/slog"
+)
+
+// Collection is the set of methods implemented by every container in the
+// library. It allows writing generic code (for example "drain any collection
+// into another") without using reflection.
+//
+// The order in which Iter and ToSlice return the elements is the natural
+// order of each container (for example from the top for a stack and from
+// the front for a queue) and it is the same for both methods.
+type Collection[T any] interface {
+	// Size returns the number of elements in the collection
+	Size() uint64
+	// IsEmpty returns true if the collection has no elements
+	IsEmpty() bool
+	// Clear removes all the elements from the collection
+	Clear()
+	// ToSlice returns the elements of the collection as a slice
+	ToSlice() []T
+	// Any returns true if at least one element matches the predicate
+	Any(predicate func(T) bool) bool
+	// Iter returns an iterator over the elements of the collection
+	Iter() iter.Seq[T]
+}
+
+// Contains returns true if the collection has at least one element equal to item.
+func Contains[T comparable](c Collection[T], item T) bool {
+	return c.Any(func(v T) bool { return v == item })
+}
+
+// Count returns the number of elements in the collection that match the predicate.
+func Count[T any](c Collection[T], predicate func(T) bool) uint64 {
+	var n uint64
+	for v := range c.Iter() {
+		if predicate(v) {
+			n++
+		}
+	}
+	return n
+}
+
+// CopyTo adds every element of src to a destination collection using the
+// given add function (for example a Push, Enqueue or Append method value).
+// The elements are added in the iteration order of src and src is not
+// modified.
+func CopyTo[T any](src Collection[T], add func(T)) {
+	for v := range src.Iter() {
+		add(v)
+	}
+}
+
+// Drain moves every element of src to a destination collection using the
+// given add function and then clears src.
+//
+// For example, to move all the items of a stack into a queue:
+//
+//	gods.Drain[int](s, q.Enqueue)
+func Drain[T any](src Collection[T], add func(T)) {
+	_, end := Trace(context.Background(), "gods.Drain", slog.Uint64("size", src.Size()))
+	defer end(nil)
+	items := src.ToSlice()
+	src.Clear()
+	for _, v := range items {
+		add(v)
+	}
+}
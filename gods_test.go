@@ -0,0 +1,139 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gods provides the interfaces shared by all the data structures.
+package gods_test
+
+import (
+	"reflect"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	abBuffer "github.com/pzaino/gods/pkg/abBuffer"
+	buffer "github.com/pzaino/gods/pkg/buffer"
+	circularLinkList "github.com/pzaino/gods/pkg/circularLinkList"
+	csBuffer "github.com/pzaino/gods/pkg/csBuffer"
+	csdlinkList "github.com/pzaino/gods/pkg/csdlinkList"
+	cslinkList "github.com/pzaino/gods/pkg/cslinkList"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+	dlinkList "github.com/pzaino/gods/pkg/dlinkList"
+	linkList "github.com/pzaino/gods/pkg/linkList"
+	pqueue "github.com/pzaino/gods/pkg/pqueue"
+	queue "github.com/pzaino/gods/pkg/queue"
+	ringBuffer "github.com/pzaino/gods/pkg/ringBuffer"
+	stack "github.com/pzaino/gods/pkg/stack"
+)
+
+const (
+	errExpectedX     = "Expected %v, but got %v"
+	errUnexpectedErr = "Unexpected error: %v"
+)
+
+// Compile-time checks: every container must implement gods.Collection
+var (
+	_ gods.Collection[int] = (*stack.Stack[int])(nil)
+	_ gods.Collection[int] = (*csstack.CSStack[int])(nil)
+	_ gods.Collection[int] = (*queue.Queue[int])(nil)
+	_ gods.Collection[int] = (*pqueue.PriorityQueue[int])(nil)
+	_ gods.Collection[int] = (*linkList.LinkList[int])(nil)
+	_ gods.Collection[int] = (*cslinkList.CSLinkList[int])(nil)
+	_ gods.Collection[int] = (*dlinkList.DLinkList[int])(nil)
+	_ gods.Collection[int] = (*csdlinkList.CSDLinkList[int])(nil)
+	_ gods.Collection[int] = (*circularLinkList.CircularLinkList[int])(nil)
+	_ gods.Collection[int] = (*buffer.Buffer[int])(nil)
+	_ gods.Collection[int] = (*csBuffer.ConcurrentBuffer[int])(nil)
+	_ gods.Collection[int] = (*ringBuffer.CircularBuffer[int])(nil)
+	_ gods.Collection[int] = (*abBuffer.ABBuffer[int])(nil)
+)
+
+func TestContains(t *testing.T) {
+	s := stack.NewFromSlice([]int{1, 2, 3})
+	if !gods.Contains[int](s, 2) {
+		t.Errorf(errExpectedX, true, false)
+	}
+	if gods.Contains[int](s, 4) {
+		t.Errorf(errExpectedX, false, true)
+	}
+}
+
+func TestCount(t *testing.T) {
+	l := linkList.NewFromSlice([]int{1, 2, 3, 4, 5})
+	n := gods.Count[int](l, func(v int) bool { return v%2 == 1 })
+	if n != 3 {
+		t.Errorf(errExpectedX, 3, n)
+	}
+}
+
+func TestCopyTo(t *testing.T) {
+	s := stack.NewFromSlice([]int{1, 2, 3})
+	q := queue.New[int]()
+	gods.CopyTo[int](s, q.Enqueue)
+
+	if s.Size() != 3 {
+		t.Errorf(errExpectedX, 3, s.Size())
+	}
+	// the stack is iterated from the top
+	if !reflect.DeepEqual(q.ToSlice(), []int{3, 2, 1}) {
+		t.Errorf(errExpectedX, []int{3, 2, 1}, q.ToSlice())
+	}
+}
+
+func TestDrain(t *testing.T) {
+	q := queue.New[int]()
+	q.Enqueue(1)
+	q.Enqueue(2)
+	q.Enqueue(3)
+
+	l := dlinkList.New[int]()
+	gods.Drain[int](q, l.Append)
+
+	if !q.IsEmpty() {
+		t.Error("Expected the source collection to be empty")
+	}
+	if !reflect.DeepEqual(l.ToSlice(), []int{1, 2, 3}) {
+		t.Errorf(errExpectedX, []int{1, 2, 3}, l.ToSlice())
+	}
+}
+
+func TestIterMatchesToSlice(t *testing.T) {
+	items := []int{5, 1, 4, 2, 3}
+	rb := ringBuffer.New[int](8)
+	b := buffer.New[int]()
+	cb := csBuffer.New[int]()
+	pq := pqueue.New[int]()
+	for _, v := range items {
+		rb.Append(v)
+		_ = b.Append(v)
+		_ = cb.Append(v)
+		pq.Enqueue(v, v)
+	}
+
+	collections := []gods.Collection[int]{
+		stack.NewFromSlice(items),
+		csstack.NewFromSlice(items),
+		linkList.NewFromSlice(items),
+		cslinkList.NewFromSlice(items),
+		circularLinkList.NewFromSlice(items),
+		rb, b, cb, pq,
+	}
+	for _, c := range collections {
+		var got []int
+		for v := range c.Iter() {
+			got = append(got, v)
+		}
+		if !reflect.DeepEqual(got, c.ToSlice()) {
+			t.Errorf("%T: "+errExpectedX, c, c.ToSlice(), got)
+		}
+	}
+}
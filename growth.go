@@ -0,0 +1,76 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import "unsafe"
+
+// Stats are the statistics of a container, returned by its Stats method.
+type Stats struct {
+	// Growth reports how the storage of the containers backed by a slice
+	// (or by chunks) grew (it is empty for the other containers)
+	Growth GrowthStats
+	// Latency are the latency histograms of the operations of a concurrent
+	// container created with WithLatencyHistograms, by operation
+	Latency map[string]LatencyHistogram
+}
+
+// GrowthStats reports how the backing array of a container grew over its
+// lifetime, to choose the initial capacity of the containers created later
+// for the same workload.
+type GrowthStats struct {
+	PeakSize     uint64 // the highest number of elements held
+	PeakCapacity uint64 // the highest capacity of the backing array
+	Resizes      uint64 // the number of times the backing array was reallocated to grow
+	Allocated    uint64 // the total capacity of the arrays allocated by the resizes
+}
+
+// Growth records the GrowthStats of a container. Its zero value is ready to
+// use; the containers embed it and grow their backing array with Append.
+type Growth struct {
+	stats GrowthStats
+}
+
+// Append is like the append builtin, but it records in g the length of the
+// returned slice and whether its backing array was reallocated.
+func Append[T any](g *Growth, s []T, items ...T) []T {
+	before := unsafe.SliceData(s)
+	s = append(s, items...)
+	if unsafe.SliceData(s) != before {
+		g.stats.Resizes++
+		g.stats.Allocated += uint64(cap(s))
+	}
+	g.stats.PeakSize = max(g.stats.PeakSize, uint64(len(s)))
+	g.stats.PeakCapacity = max(g.stats.PeakCapacity, uint64(cap(s)))
+	return s
+}
+
+// Stats returns the statistics recorded so far.
+func (g *Growth) Stats() GrowthStats {
+	return g.stats
+}
+
+// Alloc records in g that a container whose storage isn't a single slice
+// (like a list of chunks) allocated a block of n elements to grow.
+func (g *Growth) Alloc(n uint64) {
+	g.stats.Resizes++
+	g.stats.Allocated += n
+}
+
+// Track records in g the size and the capacity of a container whose storage
+// isn't a single slice, after it grew.
+func (g *Growth) Track(size, capacity uint64) {
+	g.stats.PeakSize = max(g.stats.PeakSize, size)
+	g.stats.PeakCapacity = max(g.stats.PeakCapacity, capacity)
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"bytes"
+	"hash/maphash"
+	"slices"
+)
+
+// Hasher computes the hash of a value. Values that are equal (according to
+// the Equaler used together with the Hasher) must have the same hash.
+type Hasher[T any] interface {
+	Hash(value T) uint64
+}
+
+// Equaler reports whether two values are equal. It allows to use types that
+// are not comparable (like []byte or structs with slices) as keys.
+type Equaler[T any] interface {
+	Equal(a, b T) bool
+}
+
+// HasherFunc adapts a function to the Hasher interface.
+type HasherFunc[T any] func(value T) uint64
+
+// Hash calls f(value)
+func (f HasherFunc[T]) Hash(value T) uint64 {
+	return f(value)
+}
+
+// EqualerFunc adapts a function to the Equaler interface.
+type EqualerFunc[T any] func(a, b T) bool
+
+// Equal calls f(a, b)
+func (f EqualerFunc[T]) Equal(a, b T) bool {
+	return f(a, b)
+}
+
+// ComparableEqualer is the Equaler for comparable types (it uses ==).
+type ComparableEqualer[T comparable] struct{}
+
+// Equal returns a == b
+func (ComparableEqualer[T]) Equal(a, b T) bool {
+	return a == b
+}
+
+// SliceEqualer is the Equaler for slices of comparable elements.
+type SliceEqualer[E comparable] struct{}
+
+// Equal returns true if the two slices have the same length and the same elements
+func (SliceEqualer[E]) Equal(a, b []E) bool {
+	return slices.Equal(a, b)
+}
+
+// BytesHasher hashes byte slices (and can be used as their Equaler too).
+// The zero value is ready to use. The hash values are randomized per process,
+// so they must not be persisted.
+type BytesHasher struct{}
+
+var bytesSeed = maphash.MakeSeed()
+
+// Hash returns the hash of the content of value
+func (BytesHasher) Hash(value []byte) uint64 {
+	return maphash.Bytes(bytesSeed, value)
+}
+
+// Equal returns true if a and b have the same content
+func (BytesHasher) Equal(a, b []byte) bool {
+	return bytes.Equal(a, b)
+}
+
+// ContainsWith returns true if the collection has at least one element equal
+// to item according to eq.
+func ContainsWith[T any](c Collection[T], item T, eq Equaler[T]) bool {
+	return c.Any(func(v T) bool { return eq.Equal(v, item) })
+}
+
+// EqualWith returns true if the two collections have the same elements, in
+// the same iteration order, according to eq.
+func EqualWith[T any](a, b Collection[T], eq Equaler[T]) bool {
+	if a.Size() != b.Size() {
+		return false
+	}
+	return slices.EqualFunc(a.ToSlice(), b.ToSlice(), eq.Equal)
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"strings"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	linkList "github.com/pzaino/gods/pkg/linkList"
+	queue "github.com/pzaino/gods/pkg/queue"
+	stack "github.com/pzaino/gods/pkg/stack"
+)
+
+func TestBytesHasher(t *testing.T) {
+	h := gods.BytesHasher{}
+	if h.Hash([]byte("abc")) != h.Hash([]byte("abc")) {
+		t.Errorf("Expected equal slices to have the same hash")
+	}
+	if h.Hash([]byte("abc")) == h.Hash([]byte("abd")) {
+		t.Errorf("Expected different slices to (very likely) have different hashes")
+	}
+	if !h.Equal([]byte("abc"), []byte("abc")) || h.Equal([]byte("abc"), nil) {
+		t.Errorf("Unexpected Equal result")
+	}
+}
+
+func TestFuncAdapters(t *testing.T) {
+	var h gods.Hasher[string] = gods.HasherFunc[string](func(s string) uint64 { return uint64(len(s)) })
+	if h.Hash("abc") != 3 {
+		t.Errorf(errExpectedX, 3, h.Hash("abc"))
+	}
+	var eq gods.Equaler[string] = gods.EqualerFunc[string](strings.EqualFold)
+	if !eq.Equal("ABC", "abc") {
+		t.Errorf(errExpectedX, true, false)
+	}
+	if !(gods.SliceEqualer[int]{}).Equal([]int{1, 2}, []int{1, 2}) {
+		t.Errorf(errExpectedX, true, false)
+	}
+}
+
+func TestContainsWith(t *testing.T) {
+	l := linkList.NewFromSlice([]string{"Apple", "Banana"})
+	eq := gods.EqualerFunc[string](strings.EqualFold)
+	if !gods.ContainsWith[string](l, "banana", eq) {
+		t.Errorf(errExpectedX, true, false)
+	}
+	if gods.ContainsWith[string](l, "cherry", eq) {
+		t.Errorf(errExpectedX, false, true)
+	}
+}
+
+func TestEqualWith(t *testing.T) {
+	s := stack.NewFromSlice([]int{1, 2, 3})
+	q := queue.New[int]()
+	for _, v := range []int{3, 2, 1} {
+		q.Enqueue(v)
+	}
+	if !gods.EqualWith[int](s, q, gods.ComparableEqualer[int]{}) {
+		t.Errorf(errExpectedX, true, false)
+	}
+	q.Enqueue(0)
+	if gods.EqualWith[int](s, q, gods.ComparableEqualer[int]{}) {
+		t.Errorf(errExpectedX, false, true)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"math/bits"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets is the number of buckets of a LatencyHistogram
+const LatencyBuckets = 40
+
+// LatencyHistogram is a log-bucketed histogram of the latencies of an
+// operation: Counts[0] counts the operations that took less than 1ns and
+// Counts[i] the ones that took from 2^(i-1) to 2^i ns, except for the last
+// bucket which counts all the longer ones (from about 4.6 minutes).
+type LatencyHistogram struct {
+	Counts [LatencyBuckets]uint64
+	Count  uint64        // the number of operations
+	Sum    time.Duration // the total latency
+	Max    time.Duration // the highest latency
+}
+
+// Mean returns the average latency.
+func (h LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns an upper bound of the q quantile (like 0.99) of the
+// latencies: the upper limit of the bucket holding it, capped to Max.
+func (h LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(h.Count) + 0.5)
+	rank = min(max(rank, 1), h.Count)
+	var seen uint64
+	for i, c := range h.Counts {
+		if seen += c; seen >= rank {
+			return min(time.Duration(1)<<i, h.Max)
+		}
+	}
+	return h.Max
+}
+
+// WithLatencyHistograms makes a concurrent container record a
+// LatencyHistogram for each of its operations (like "csstack.Push"),
+// returned in the Latency field of its Stats and cleared by its
+// ResetLatency method. The latency of a write operation is the time spent
+// waiting for the lock plus the time it was held; for a read operation it is
+// the time spent waiting for the lock (the readers release the lock without
+// telling which one they are). Finding the operation costs a look at the
+// call stack, so the histograms are meant for profiling under load.
+func WithLatencyHistograms() Option {
+	return func(o *Options) {
+		o.LatencyHistograms = true
+	}
+}
+
+// LockLatency returns the histograms recorded by a locker created by
+// NewLocker with the WithLatencyHistograms option, by operation. For any
+// other locker it returns nil.
+func LockLatency(l RWLocker) map[string]LatencyHistogram {
+	if ll := findLatencyLocker(l); ll != nil {
+		return ll.histograms()
+	}
+	return nil
+}
+
+// ResetLockLatency clears the histograms recorded by a locker created by
+// NewLocker with the WithLatencyHistograms option.
+func ResetLockLatency(l RWLocker) {
+	if ll := findLatencyLocker(l); ll != nil {
+		ll.ops.Range(func(_, h any) bool {
+			h.(*latencyRecorder).reset()
+			return true
+		})
+	}
+}
+
+// findLatencyLocker returns the latencyLocker wrapped by l, or nil
+func findLatencyLocker(l RWLocker) *latencyLocker {
+	for {
+		if ll, ok := l.(*latencyLocker); ok {
+			return ll
+		}
+		u, ok := l.(interface{ unwrap() RWLocker })
+		if !ok {
+			return nil
+		}
+		l = u.unwrap()
+	}
+}
+
+// latencyRecorder records a LatencyHistogram with atomic counters
+type latencyRecorder struct {
+	counts [LatencyBuckets]atomic.Uint64
+	count  atomic.Uint64
+	sum    atomic.Int64
+	max    atomic.Int64
+}
+
+// record adds a latency to the histogram
+func (r *latencyRecorder) record(d time.Duration) {
+	i := min(bits.Len64(uint64(max(d, 0))), LatencyBuckets-1)
+	r.counts[i].Add(1)
+	r.count.Add(1)
+	r.sum.Add(int64(d))
+	for {
+		m := r.max.Load()
+		if int64(d) <= m || r.max.CompareAndSwap(m, int64(d)) {
+			return
+		}
+	}
+}
+
+// histogram returns a copy of the histogram
+func (r *latencyRecorder) histogram() LatencyHistogram {
+	h := LatencyHistogram{Count: r.count.Load(), Sum: time.Duration(r.sum.Load()), Max: time.Duration(r.max.Load())}
+	for i := range r.counts {
+		h.Counts[i] = r.counts[i].Load()
+	}
+	return h
+}
+
+// reset clears the histogram
+func (r *latencyRecorder) reset() {
+	for i := range r.counts {
+		r.counts[i].Store(0)
+	}
+	r.count.Store(0)
+	r.sum.Store(0)
+	r.max.Store(0)
+}
+
+// latencyLocker records the latency of the operations locking it
+type latencyLocker struct {
+	locker RWLocker
+	ops    sync.Map // operation name -> *latencyRecorder
+	pcs    sync.Map // program counter -> *latencyRecorder (nil for the frames of this package)
+
+	// protected by the write lock
+	start time.Time
+	op    *latencyRecorder
+}
+
+// unwrap returns the measured locker
+func (l *latencyLocker) unwrap() RWLocker {
+	return l.locker
+}
+
+// Lock acquires the write lock, starting the measure of the operation
+func (l *latencyLocker) Lock() {
+	start := time.Now()
+	op := l.caller()
+	l.locker.Lock()
+	l.start, l.op = start, op
+}
+
+// Unlock records the latency of the operation and releases the write lock
+func (l *latencyLocker) Unlock() {
+	l.op.record(time.Since(l.start))
+	l.locker.Unlock()
+}
+
+// RLock acquires a read lock, recording the time spent waiting for it
+func (l *latencyLocker) RLock() {
+	start := time.Now()
+	op := l.caller()
+	l.locker.RLock()
+	op.record(time.Since(start))
+}
+
+// RUnlock releases a read lock
+func (l *latencyLocker) RUnlock() {
+	l.locker.RUnlock()
+}
+
+// caller returns the recorder of the operation calling the lock: the first
+// function outside of this package in the call stack. The functions are
+// looked up by program counter, so the names are resolved once.
+func (l *latencyLocker) caller() *latencyRecorder {
+	var pcs [16]uintptr
+	for _, pc := range pcs[:runtime.Callers(3, pcs[:])] {
+		r, ok := l.pcs.Load(pc)
+		if !ok {
+			r = l.resolve(pc)
+		}
+		if r := r.(*latencyRecorder); r != nil {
+			return r
+		}
+	}
+	r, _ := l.ops.LoadOrStore("unknown", &latencyRecorder{})
+	return r.(*latencyRecorder)
+}
+
+// resolve finds the recorder of the function at pc, caching it
+func (l *latencyLocker) resolve(pc uintptr) any {
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	var r *latencyRecorder
+	if f.Function != "" && !strings.HasPrefix(f.Function, godsPrefix) && !strings.HasPrefix(f.Function, "runtime.") {
+		op, _ := l.ops.LoadOrStore(shortFuncName(f.Function), &latencyRecorder{})
+		r = op.(*latencyRecorder)
+	}
+	l.pcs.Store(pc, r)
+	return r
+}
+
+// histograms returns a copy of the histograms, by operation
+func (l *latencyLocker) histograms() map[string]LatencyHistogram {
+	hs := make(map[string]LatencyHistogram)
+	l.ops.Range(func(op, r any) bool {
+		hs[op.(string)] = r.(*latencyRecorder).histogram()
+		return true
+	})
+	return hs
+}
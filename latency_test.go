@@ -0,0 +1,71 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"testing"
+	"time"
+
+	gods "github.com/pzaino/gods"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+)
+
+func TestLatencyHistograms(t *testing.T) {
+	s := csstack.New[int](gods.WithLatencyHistograms())
+	for i := 0; i < 100; i++ {
+		s.Push(i)
+	}
+	for i := 0; i < 50; i++ {
+		_, _ = s.Pop()
+	}
+	lat := s.Stats().Latency
+	if lat["csstack.Push"].Count != 100 {
+		t.Errorf(errExpectedX, 100, lat["csstack.Push"].Count)
+	}
+	if lat["csstack.Pop"].Count != 50 {
+		t.Errorf(errExpectedX, 50, lat["csstack.Pop"].Count)
+	}
+	push := lat["csstack.Push"]
+	if push.Max <= 0 || push.Quantile(0.99) > push.Max || push.Mean() > push.Max {
+		t.Errorf("Expected the quantiles and the mean to be bounded by the max %v, but got %v and %v", push.Max, push.Quantile(0.99), push.Mean())
+	}
+
+	s.ResetLatency()
+	if n := s.Stats().Latency["csstack.Push"].Count; n != 0 {
+		t.Errorf(errExpectedX, 0, n)
+	}
+
+	// without the option there are no histograms
+	if lat := csstack.New[int]().Stats().Latency; lat != nil {
+		t.Errorf(errExpectedX, nil, lat)
+	}
+}
+
+func TestLatencyHistogramQuantile(t *testing.T) {
+	h := gods.LatencyHistogram{Count: 100, Max: 900 * time.Nanosecond, Sum: 10 * time.Microsecond}
+	h.Counts[4] = 90  // 8ns to 16ns
+	h.Counts[10] = 10 // 512ns to 1.024µs
+	for q, want := range map[float64]time.Duration{0.5: 16, 0.9: 16, 0.95: 900, 1: 900} {
+		if got := h.Quantile(q); got != want {
+			t.Errorf(errExpectedX, want, got)
+		}
+	}
+	if h.Mean() != 100*time.Nanosecond {
+		t.Errorf(errExpectedX, 100*time.Nanosecond, h.Mean())
+	}
+	if (gods.LatencyHistogram{}).Quantile(0.5) != 0 {
+		t.Errorf("Expected the quantile of an empty histogram to be 0")
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RWLocker is the synchronization backend used by the concurrent containers.
+// *sync.RWMutex implements it.
+type RWLocker interface {
+	Lock()
+	Unlock()
+	RLock()
+	RUnlock()
+}
+
+// LockStrategy selects the RWLocker implementation used by a concurrent container.
+type LockStrategy int
+
+const (
+	// RWMutexLock uses a sync.RWMutex (the default). Readers run in parallel.
+	RWMutexLock LockStrategy = iota
+	// MutexLock uses a plain sync.Mutex, readers are serialized too. It is
+	// cheaper than RWMutexLock when there are mostly writers.
+	MutexLock
+	// ShardedLock uses a sharded reader/writer lock: readers only touch one
+	// of the shards, so they don't contend on the same cache line, while
+	// writers have to wait for all the shards. Best for read-mostly workloads
+	// on many cores.
+	ShardedLock
+)
+
+// NewLocker returns the RWLocker selected by opts.
+func NewLocker(opts ...Option) RWLocker {
+	o := NewOptions(opts...)
+	var l RWLocker
+	switch o.Locking {
+	case MutexLock:
+		l = &mutexLocker{}
+	case ShardedLock:
+		l = newShardedLocker(o.Shards, o.AutoShards)
+	default:
+		l = &sync.RWMutex{}
+	}
+	if o.ContentionStats {
+		l = &profiledLocker{locker: l}
+	}
+	if hooks := o.MutationHooks; len(hooks) > 0 {
+		l = OnUnlock(l, func() {
+			for _, fn := range hooks {
+				fn()
+			}
+		})
+	}
+	for _, obs := range o.MutationObservers {
+		l = &observedLocker{locker: l, observer: MutationObserver{SampleEvery: max(obs.SampleEvery, 1), Fn: obs.Fn}}
+	}
+	if o.LatencyHistograms {
+		l = &latencyLocker{locker: l}
+	}
+	return debugWrap(l)
+}
+
+// OnUnlock returns a locker that calls fn every time the write lock of l is
+// released, right before releasing it (so fn still runs under the lock).
+// Concurrent containers use it to publish the state that is read without
+// locking, like their size.
+func OnUnlock(l RWLocker, fn func()) RWLocker {
+	return &hookLocker{locker: l, onUnlock: fn}
+}
+
+// hookLocker is the RWLocker returned by OnUnlock
+type hookLocker struct {
+	locker   RWLocker
+	onUnlock func()
+}
+
+// unwrap returns the hooked locker
+func (h *hookLocker) unwrap() RWLocker {
+	return h.locker
+}
+
+// Lock acquires the lock for writing
+func (h *hookLocker) Lock() {
+	h.locker.Lock()
+}
+
+// Unlock calls the hook and releases the write lock
+func (h *hookLocker) Unlock() {
+	h.onUnlock()
+	h.locker.Unlock()
+}
+
+// RLock acquires the lock for reading
+func (h *hookLocker) RLock() {
+	h.locker.RLock()
+}
+
+// RUnlock releases a read lock
+func (h *hookLocker) RUnlock() {
+	h.locker.RUnlock()
+}
+
+// mutexLocker is an RWLocker where readers take the exclusive lock too
+type mutexLocker struct {
+	sync.Mutex
+}
+
+// RLock locks the mutex
+func (m *mutexLocker) RLock() {
+	m.Lock()
+}
+
+// RUnlock unlocks the mutex
+func (m *mutexLocker) RUnlock() {
+	m.Unlock()
+}
+
+// readerShard is a reader counter padded to 64 bytes: whatever the alignment
+// of the slice, two counters are never in the same cache line
+type readerShard struct {
+	readers atomic.Int64
+	_       [56]byte
+}
+
+// shardedLocker is a "big reader" lock. Readers increment the counter of a
+// random shard, writers wait until the sum of all the counters is zero.
+//
+// A reader may release the lock on a different shard from the one it used to
+// acquire it (RUnlock doesn't know which shard RLock picked), so a single
+// counter can become negative, but the sum of the counters is always the
+// number of active readers, which is all the writers need.
+//
+// The shards can be replaced (resharding) while the write lock is held: at
+// that point the sum of the old counters is zero, and readers that raced
+// with the replacement notice it and retry on the new shards.
+type shardedLocker struct {
+	writer  sync.Mutex  // serializes the writers
+	writing atomic.Bool // set while a writer holds (or is acquiring) the lock
+	shards  atomic.Pointer[[]readerShard]
+	auto    bool // follow GOMAXPROCS (WithAutoShards)
+}
+
+func newShardedLocker(shards int, auto bool) *shardedLocker {
+	l := &shardedLocker{auto: auto}
+	l.setShards(shards)
+	return l
+}
+
+// setShards replaces the shards; the write lock must be held (or l must not
+// be shared yet)
+func (l *shardedLocker) setShards(n int) {
+	shards := make([]readerShard, n)
+	l.shards.Store(&shards)
+}
+
+// RLock acquires the lock for reading
+func (l *shardedLocker) RLock() {
+	for {
+		shards := l.shards.Load()
+		shard := &(*shards)[rand.IntN(len(*shards))]
+		shard.readers.Add(1)
+		if !l.writing.Load() && l.shards.Load() == shards {
+			return
+		}
+		// a writer is active (or the shards have been replaced): back off
+		// and wait for it to finish
+		shard.readers.Add(-1)
+		for l.writing.Load() {
+			runtime.Gosched()
+		}
+	}
+}
+
+// RUnlock releases a read lock
+func (l *shardedLocker) RUnlock() {
+	shards := *l.shards.Load()
+	shards[rand.IntN(len(shards))].readers.Add(-1)
+}
+
+// Lock acquires the lock for writing
+func (l *shardedLocker) Lock() {
+	l.writer.Lock()
+	l.writing.Store(true)
+	for l.activeReaders() > 0 {
+		runtime.Gosched()
+	}
+	if l.auto {
+		if procs := runtime.GOMAXPROCS(0); procs > l.shardCount() {
+			l.setShards(procs)
+		}
+	}
+}
+
+// Unlock releases the write lock
+func (l *shardedLocker) Unlock() {
+	l.writing.Store(false)
+	l.writer.Unlock()
+}
+
+func (l *shardedLocker) activeReaders() int64 {
+	var n int64
+	shards := *l.shards.Load()
+	for i := range shards {
+		n += shards[i].readers.Load()
+	}
+	return n
+}
+
+func (l *shardedLocker) shardCount() int {
+	return len(*l.shards.Load())
+}
+
+// unwrapLocker returns the locker wrapped by the instrumentation layers
+// (contention statistics, debug checks), if any
+func unwrapLocker(l RWLocker) RWLocker {
+	for {
+		u, ok := l.(interface{ unwrap() RWLocker })
+		if !ok {
+			return l
+		}
+		l = u.unwrap()
+	}
+}
+
+// LockShards returns the number of shards of a locker created with the
+// ShardedLock strategy, or 0 for any other locker.
+func LockShards(l RWLocker) int {
+	if s, ok := unwrapLocker(l).(*shardedLocker); ok {
+		return s.shardCount()
+	}
+	return 0
+}
+
+// Reshard changes the number of shards of a locker created with the
+// ShardedLock strategy. It acquires the write lock, so it must not be called
+// while l is held. It returns false (and does nothing) if l is not sharded
+// or shards is less than 1.
+func Reshard(l RWLocker, shards int) bool {
+	if shards < 1 {
+		return false
+	}
+	if _, ok := unwrapLocker(l).(*shardedLocker); !ok {
+		return false
+	}
+	l.Lock()
+	defer l.Unlock()
+	// the write lock has been acquired through the wrappers, so they stay
+	// consistent; only the sharded locker itself is modified
+	unwrapLocker(l).(*shardedLocker).setShards(shards)
+	return true
+}
+
+// ReshardOnContention doubles the number of shards of a sharded locker
+// created with WithContentionStats if the average wait per acquisition
+// (since its creation) exceeds maxAvgWait. It returns true if the locker has
+// been resharded. It is meant to be called periodically, e.g. by a
+// monitoring goroutine.
+func ReshardOnContention(l RWLocker, maxAvgWait time.Duration) bool {
+	shards := LockShards(l)
+	if shards == 0 || LockContentionStats(l).AvgWaitTime() <= maxAvgWait {
+		return false
+	}
+	return Reshard(l, 2*shards)
+}
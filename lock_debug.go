@@ -0,0 +1,185 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build godsdebug
+
+package gods
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// DebugLocking is true when the library is built with the godsdebug tag.
+const DebugLocking = true
+
+// debugWrap wraps l with the misuse checks of the godsdebug build
+func debugWrap(l RWLocker) RWLocker {
+	return &debugLocker{locker: l, id: debugLockerID.Add(1)}
+}
+
+var debugLockerID atomic.Uint64
+
+// heldLock is a lock held by a goroutine
+type heldLock struct {
+	locker *debugLocker
+	write  bool
+	stack  []byte // where the lock was acquired
+}
+
+// lockRegistry tracks the locks held by every goroutine and the order in
+// which pairs of locks have been acquired so far
+type lockRegistry struct {
+	sync.Mutex
+	held  map[uint64][]heldLock // goroutine id -> held locks
+	order map[[2]uint64][]byte  // {first, second} -> where it was first seen
+}
+
+var debugRegistry = lockRegistry{
+	held:  make(map[uint64][]heldLock),
+	order: make(map[[2]uint64][]byte),
+}
+
+// debugLocker detects the misuses of a lock that would otherwise result in
+// a silent hang, and panics with a diagnostic trace instead:
+//
+//   - re-entrant locking, i.e. a goroutine locking a structure it already
+//     holds (for example a ForEach or Map callback calling back into the
+//     same structure);
+//   - lock ordering violations, i.e. two structures locked in the opposite
+//     order of a previous acquisition (for example a.Merge(b) and
+//     b.Merge(a) without a canonical order).
+type debugLocker struct {
+	locker RWLocker
+	id     uint64
+}
+
+// unwrap returns the checked locker
+func (l *debugLocker) unwrap() RWLocker {
+	return l.locker
+}
+
+// Lock acquires the lock for writing
+func (l *debugLocker) Lock() {
+	l.check(true)
+	l.locker.Lock()
+}
+
+// Unlock releases the write lock
+func (l *debugLocker) Unlock() {
+	l.release()
+	l.locker.Unlock()
+}
+
+// RLock acquires the lock for reading
+func (l *debugLocker) RLock() {
+	l.check(false)
+	l.locker.RLock()
+}
+
+// RUnlock releases a read lock
+func (l *debugLocker) RUnlock() {
+	l.release()
+	l.locker.RUnlock()
+}
+
+// check verifies that the current goroutine can acquire the lock and records it
+// as held (before blocking on it, so the checks of the other goroutines see it)
+func (l *debugLocker) check(write bool) {
+	g := goroutineID()
+	stack := callers()
+	r := &debugRegistry
+	r.Lock()
+	defer r.Unlock()
+	for _, h := range r.held[g] {
+		if h.locker == l {
+			panic(fmt.Sprintf("gods: re-entrant locking of structure #%d (a callback calling back "+
+				"into the same structure?)\nalready locked at:\n%s\nlocked again at:\n%s", l.id, h.stack, stack))
+		}
+		if first, ok := r.order[[2]uint64{l.id, h.locker.id}]; ok {
+			panic(fmt.Sprintf("gods: lock ordering violation: structure #%d locked while holding #%d, "+
+				"but #%d was locked while holding #%d at:\n%s\nlocked at:\n%s",
+				l.id, h.locker.id, h.locker.id, l.id, first, stack))
+		}
+		edge := [2]uint64{h.locker.id, l.id}
+		if _, ok := r.order[edge]; !ok {
+			r.order[edge] = stack
+		}
+	}
+	r.held[g] = append(r.held[g], heldLock{locker: l, write: write, stack: stack})
+}
+
+// release removes the lock from the locks held by the current goroutine (or,
+// if it is unlocked by a different goroutine, from the goroutine holding it)
+func (l *debugLocker) release() {
+	g := goroutineID()
+	r := &debugRegistry
+	r.Lock()
+	defer r.Unlock()
+	if r.remove(g, l) {
+		return
+	}
+	for other := range r.held {
+		if r.remove(other, l) {
+			return
+		}
+	}
+	panic(fmt.Sprintf("gods: unlock of structure #%d, which is not locked\n%s", l.id, callers()))
+}
+
+// remove removes l from the locks held by goroutine g
+func (r *lockRegistry) remove(g uint64, l *debugLocker) bool {
+	held := r.held[g]
+	for i := len(held) - 1; i >= 0; i-- {
+		if held[i].locker == l {
+			held = append(held[:i], held[i+1:]...)
+			if len(held) == 0 {
+				delete(r.held, g)
+			} else {
+				r.held[g] = held
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// goroutineID returns the id of the current goroutine, parsed from the
+// header of its stack trace ("goroutine 123 [running]:")
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// callers returns the stack trace of the current goroutine
+func callers() []byte {
+	buf := make([]byte, 4096)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
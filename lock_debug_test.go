@@ -0,0 +1,86 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build godsdebug
+
+package gods_test
+
+import (
+	"strings"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+)
+
+// expectPanic runs fn and checks that it panics with a message containing msg
+func expectPanic(t *testing.T, msg string, fn func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		r := recover()
+		if s, ok := r.(string); !ok || !strings.Contains(s, msg) {
+			t.Errorf("Expected a panic containing %q, got %v", msg, r)
+		}
+	}()
+	fn()
+}
+
+func TestDebugReentrantLock(t *testing.T) {
+	l := gods.NewLocker()
+	l.Lock()
+	expectPanic(t, "re-entrant locking", l.RLock)
+	expectPanic(t, "re-entrant locking", l.Lock)
+	l.Unlock()
+
+	// the lock is usable again
+	l.RLock()
+	l.RUnlock()
+}
+
+func TestDebugReentrantCallback(t *testing.T) {
+	cs := csstack.New[int]()
+	cs.Push(1)
+	expectPanic(t, "re-entrant locking", func() {
+		_ = cs.ForEach(func(*int) error {
+			_ = cs.ToSlice()
+			return nil
+		})
+	})
+	if cs.Size() != 1 {
+		t.Errorf(errExpectedX, 1, cs.Size())
+	}
+}
+
+func TestDebugLockOrdering(t *testing.T) {
+	a, b := gods.NewLocker(), gods.NewLocker(gods.WithSharding(2))
+	a.Lock()
+	b.RLock()
+	b.RUnlock()
+	a.Unlock()
+
+	b.Lock()
+	expectPanic(t, "lock ordering violation", a.Lock)
+	b.Unlock()
+
+	// the same order is fine
+	a.Lock()
+	b.Lock()
+	b.Unlock()
+	a.Unlock()
+}
+
+func TestDebugUnlockNotLocked(t *testing.T) {
+	expectPanic(t, "not locked", gods.NewLocker().Unlock)
+}
@@ -0,0 +1,26 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !godsdebug
+
+package gods
+
+// DebugLocking is true when the library is built with the godsdebug tag.
+const DebugLocking = false
+
+// debugWrap returns l unchanged: the misuse checks are only compiled with the
+// godsdebug build tag.
+func debugWrap(l RWLocker) RWLocker {
+	return l
+}
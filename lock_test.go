@@ -0,0 +1,181 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gods "github.com/pzaino/gods"
+)
+
+func TestNewOptions(t *testing.T) {
+	o := gods.NewOptions()
+	if o.Locking != gods.RWMutexLock || o.Shards != runtime.GOMAXPROCS(0) {
+		t.Errorf(errExpectedX, gods.RWMutexLock, o.Locking)
+	}
+	o = gods.NewOptions(gods.WithSharding(16))
+	if o.Locking != gods.ShardedLock || o.Shards != 16 {
+		t.Errorf(errExpectedX, 16, o.Shards)
+	}
+	// the last option wins
+	o = gods.NewOptions(gods.WithSharding(4), gods.WithMutex(), nil)
+	if o.Locking != gods.MutexLock {
+		t.Errorf(errExpectedX, gods.MutexLock, o.Locking)
+	}
+	if _, ok := gods.NewLocker(gods.WithRWMutex()).(*sync.RWMutex); !ok && !gods.DebugLocking {
+		t.Errorf("Expected a *sync.RWMutex")
+	}
+}
+
+// checkLocker verifies that writers are exclusive and that readers never
+// observe a writer in its critical section
+func checkLocker(t *testing.T, l gods.RWLocker) {
+	var writers, readers atomic.Int32
+	var counter int // protected by l
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				l.Lock()
+				if writers.Add(1) != 1 || readers.Load() != 0 {
+					t.Errorf("Expected the writer to be alone")
+				}
+				counter++
+				writers.Add(-1)
+				l.Unlock()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				l.RLock()
+				readers.Add(1)
+				if writers.Load() != 0 {
+					t.Errorf("Expected no writers while reading")
+				}
+				_ = counter
+				readers.Add(-1)
+				l.RUnlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if counter != 8*200 {
+		t.Errorf(errExpectedX, 8*200, counter)
+	}
+}
+
+func TestLockers(t *testing.T) {
+	for name, opt := range map[string]gods.Option{
+		"rwmutex": gods.WithRWMutex(),
+		"mutex":   gods.WithMutex(),
+		"sharded": gods.WithSharding(4),
+		"single":  gods.WithSharding(1),
+	} {
+		t.Run(name, func(t *testing.T) {
+			checkLocker(t, gods.NewLocker(opt))
+		})
+	}
+}
+
+func TestShardedLockerParallelReaders(t *testing.T) {
+	l := gods.NewLocker(gods.WithSharding(8))
+	l.RLock()
+	done := make(chan struct{})
+	go func() {
+		// a second reader must not be blocked by the first one
+		l.RLock()
+		l.RUnlock()
+		close(done)
+	}()
+	<-done
+	l.RUnlock()
+}
+
+func TestAutoShards(t *testing.T) {
+	procs := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(procs)
+
+	l := gods.NewLocker(gods.WithAutoShards())
+	if gods.LockShards(l) != procs {
+		t.Errorf(errExpectedX, procs, gods.LockShards(l))
+	}
+	runtime.GOMAXPROCS(procs + 2)
+	l.Lock()
+	l.Unlock()
+	if gods.LockShards(l) != procs+2 {
+		t.Errorf(errExpectedX, procs+2, gods.LockShards(l))
+	}
+
+	// fixed shards don't follow GOMAXPROCS
+	l = gods.NewLocker(gods.WithAutoShards(), gods.WithSharding(2))
+	l.Lock()
+	l.Unlock()
+	if gods.LockShards(l) != 2 {
+		t.Errorf(errExpectedX, 2, gods.LockShards(l))
+	}
+}
+
+func TestReshard(t *testing.T) {
+	if gods.Reshard(gods.NewLocker(), 4) || gods.LockShards(gods.NewLocker()) != 0 {
+		t.Errorf("Expected only sharded lockers to be resharded")
+	}
+
+	l := gods.NewLocker(gods.WithSharding(2), gods.WithContentionStats())
+	if gods.Reshard(l, 0) {
+		t.Errorf("Expected an invalid number of shards to be rejected")
+	}
+	// resharding while the lock is in use
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 50; i++ {
+			if !gods.Reshard(l, i) {
+				t.Errorf("Expected the locker to be resharded")
+			}
+		}
+	}()
+	checkLocker(t, l)
+	<-done
+	if gods.LockShards(l) != 50 {
+		t.Errorf(errExpectedX, 50, gods.LockShards(l))
+	}
+}
+
+func TestReshardOnContention(t *testing.T) {
+	l := gods.NewLocker(gods.WithSharding(2), gods.WithContentionStats())
+	if gods.ReshardOnContention(l, time.Hour) {
+		t.Errorf("Expected no resharding without contention")
+	}
+	locked := make(chan struct{})
+	go func() {
+		l.Lock()
+		close(locked)
+		time.Sleep(time.Millisecond)
+		l.Unlock()
+	}()
+	<-locked
+	l.RLock()
+	l.RUnlock()
+	if !gods.ReshardOnContention(l, 0) || gods.LockShards(l) != 4 {
+		t.Errorf(errExpectedX, 4, gods.LockShards(l))
+	}
+}
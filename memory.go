@@ -0,0 +1,78 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"iter"
+	"slices"
+	"unsafe"
+)
+
+// The MemoryUsage methods of the containers return an estimate of the bytes
+// they hold: the container itself, its backing arrays (by capacity, not by
+// length) or its nodes, computed with unsafe.Sizeof. The memory referenced by
+// the elements (like the bytes of a string or of a slice) is not known to the
+// containers, so it can be added by passing a function returning it for an
+// element, for example:
+//
+//	bytes := s.MemoryUsage(func(v string) uint64 { return uint64(len(v)) })
+//
+// The allocator rounds the allocations to its size classes, so the real
+// usage is a bit higher.
+
+// SliceMemory returns the memory held by the backing array of items (its
+// capacity times the size of T) plus the ExtraMemory of its elements. It is
+// the helper used by the containers backed by a slice.
+func SliceMemory[T any](items []T, extra ...func(T) uint64) uint64 {
+	var zero T
+	return uint64(cap(items))*uint64(unsafe.Sizeof(zero)) + ExtraMemory(slices.Values(items), extra...)
+}
+
+// NodesMemory returns the memory held by count nodes of type N. It is the
+// helper used by the linked containers.
+func NodesMemory[N any](count uint64) uint64 {
+	var node N
+	return count * uint64(unsafe.Sizeof(node))
+}
+
+// ExtraMemory returns the sum of the memory returned by the extra functions
+// for each element of seq.
+func ExtraMemory[T any](seq iter.Seq[T], extra ...func(T) uint64) uint64 {
+	var n uint64
+	for _, fn := range extra {
+		for v := range seq {
+			n += fn(v)
+		}
+	}
+	return n
+}
+
+// MapMemory returns an estimate of the memory held by the table of m (a slot
+// with a key, a value and a control byte for every entry, with the table at
+// most 7/8 full) plus the memory returned by the extra functions for each
+// entry.
+func MapMemory[K comparable, V any](m map[K]V, extra ...func(K, V) uint64) uint64 {
+	var (
+		k K
+		v V
+	)
+	n := uint64(len(m)) * (uint64(unsafe.Sizeof(k)) + uint64(unsafe.Sizeof(v)) + 1) * 8 / 7
+	for _, fn := range extra {
+		for key, val := range m {
+			n += fn(key, val)
+		}
+	}
+	return n
+}
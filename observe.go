@@ -0,0 +1,171 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// MutationEvent describes a mutation of a concurrent container, as received
+// by the observers installed with WithMutationObserver.
+type MutationEvent struct {
+	// Op is the operation, like "csstack.Push" (or the function calling the
+	// lock, for the operations done through Atomically)
+	Op string
+	// SizeBefore and SizeAfter are the sizes of the container before and
+	// after the operation
+	SizeBefore, SizeAfter uint64
+	// Duration is the time the write lock was held
+	Duration time.Duration
+}
+
+// MutationObserver is an observer installed with WithMutationObserver.
+type MutationObserver struct {
+	// SampleEvery is the sampling period (1 observes every operation)
+	SampleEvery uint64
+	// Fn is called with the sampled operations
+	Fn func(MutationEvent)
+}
+
+// WithMutationObserver makes a concurrent container call fn after one write
+// operation every sampleEvery (after every one if sampleEvery is less than 2).
+// Like the mutation hooks, fn runs while the lock is still held, so it must
+// not use the container. The operations that are not sampled cost a counter
+// increment; the sampled ones have to find the name of the operation in the
+// call stack. The option can be given more than once.
+func WithMutationObserver(sampleEvery uint64, fn func(MutationEvent)) Option {
+	return func(o *Options) {
+		if fn != nil {
+			o.MutationObservers = append(o.MutationObservers, MutationObserver{SampleEvery: max(sampleEvery, 1), Fn: fn})
+		}
+	}
+}
+
+// WithMutationLogger makes a concurrent container log one write operation
+// every sampleEvery to logger, at the given level, with the attributes op,
+// size_before, size_after and duration.
+//
+//	s := csstack.New[int](gods.WithMutationLogger(slog.Default(), slog.LevelDebug, 100))
+func WithMutationLogger(logger *slog.Logger, level slog.Level, sampleEvery uint64) Option {
+	return WithMutationObserver(sampleEvery, func(e MutationEvent) {
+		logger.LogAttrs(context.Background(), level, "gods mutation",
+			slog.String("op", e.Op),
+			slog.Uint64("size_before", e.SizeBefore),
+			slog.Uint64("size_after", e.SizeAfter),
+			slog.Duration("duration", e.Duration))
+	})
+}
+
+// ObserveSize sets the function used by the mutation observers of l to read
+// the size of the container. The concurrent containers call it in their
+// constructors; it does nothing if l has no observers.
+func ObserveSize(l RWLocker, size func() uint64) {
+	for o := l; o != nil; {
+		if ol, ok := o.(*observedLocker); ok {
+			ol.size = size
+		}
+		u, ok := o.(interface{ unwrap() RWLocker })
+		if !ok {
+			break
+		}
+		o = u.unwrap()
+	}
+}
+
+// observedLocker reports the sampled write operations to an observer
+type observedLocker struct {
+	locker   RWLocker
+	observer MutationObserver
+	size     func() uint64
+
+	// protected by the write lock
+	count    uint64
+	sampled  bool
+	lockedAt time.Time
+	before   uint64
+}
+
+// unwrap returns the observed locker
+func (l *observedLocker) unwrap() RWLocker {
+	return l.locker
+}
+
+// Lock acquires the write lock, starting the measure if the operation is
+// sampled
+func (l *observedLocker) Lock() {
+	l.locker.Lock()
+	l.count++
+	l.sampled = l.count%l.observer.SampleEvery == 0
+	if l.sampled {
+		l.lockedAt = time.Now()
+		if l.size != nil {
+			l.before = l.size()
+		}
+	}
+}
+
+// Unlock reports the sampled operations and releases the write lock
+func (l *observedLocker) Unlock() {
+	if l.sampled {
+		e := MutationEvent{Op: callerOp(), SizeBefore: l.before, Duration: time.Since(l.lockedAt)}
+		if l.size != nil {
+			e.SizeAfter = l.size()
+		}
+		l.observer.Fn(e)
+	}
+	l.locker.Unlock()
+}
+
+// RLock acquires the lock for reading
+func (l *observedLocker) RLock() {
+	l.locker.RLock()
+}
+
+// RUnlock releases a read lock
+func (l *observedLocker) RUnlock() {
+	l.locker.RUnlock()
+}
+
+// godsPrefix is the prefix of the functions of this package
+const godsPrefix = "github.com/pzaino/gods."
+
+// callerOp returns the name of the first function outside of this package
+// (and of the runtime) in the call stack, without its import path, receiver and type parameters (for
+// example "csstack.Push")
+func callerOp() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		f, more := frames.Next()
+		if f.Function != "" && !strings.HasPrefix(f.Function, godsPrefix) && !strings.HasPrefix(f.Function, "runtime.") {
+			return shortFuncName(f.Function)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// shortFuncName turns "github.com/pzaino/gods/pkg/csstack.(*CSStack[...]).Push"
+// into "csstack.Push"
+func shortFuncName(name string) string {
+	name = name[strings.LastIndex(name, "/")+1:]
+	pkg, rest, _ := strings.Cut(name, ".")
+	return pkg + "." + rest[strings.LastIndex(rest, ".")+1:]
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	csmap "github.com/pzaino/gods/pkg/csmap"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+)
+
+func TestMutationObserver(t *testing.T) {
+	var events []gods.MutationEvent
+	s := csstack.New[int](gods.WithMutationObserver(1, func(e gods.MutationEvent) { events = append(events, e) }))
+	s.Push(1)
+	s.Push(2)
+	_, _ = s.Pop()
+	_ = s.ToSlice()
+	want := []gods.MutationEvent{
+		{Op: "csstack.Push", SizeBefore: 0, SizeAfter: 1},
+		{Op: "csstack.Push", SizeBefore: 1, SizeAfter: 2},
+		{Op: "csstack.Pop", SizeBefore: 2, SizeAfter: 1},
+	}
+	if len(events) != len(want) {
+		t.Fatalf(errExpectedX, want, events)
+	}
+	for i, e := range events {
+		e.Duration = 0
+		if e != want[i] {
+			t.Errorf(errExpectedX, want[i], e)
+		}
+	}
+
+	// sampling
+	n := 0
+	m := csmap.New[int, int](gods.WithMutationObserver(10, func(gods.MutationEvent) { n++ }))
+	for i := range 100 {
+		m.Set(i, i)
+	}
+	if n != 10 {
+		t.Errorf(errExpectedX, 10, n)
+	}
+}
+
+func TestMutationLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	m := csmap.New[string, int](gods.WithMutationLogger(logger, slog.LevelDebug, 2))
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Clear()
+	out := buf.String()
+	if strings.Count(out, "msg=\"gods mutation\"") != 1 || !strings.Contains(out, "op=csmap.Set size_before=1 size_after=2") {
+		t.Errorf(errExpectedX, "a single Set logged", out)
+	}
+}
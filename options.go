@@ -0,0 +1,128 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import "runtime"
+
+// Options are the settings shared by the constructors of the containers.
+type Options struct {
+	// Locking is the lock strategy
+	Locking LockStrategy
+	// Shards is the number of shards used by ShardedLock
+	Shards int
+	// ContentionStats enables the collection of lock statistics
+	ContentionStats bool
+	// AutoShards makes ShardedLock follow GOMAXPROCS
+	AutoShards bool
+	// NodePool enables the reuse of the nodes of linked structures
+	NodePool bool
+	// MutationHooks are called every time the write lock is released
+	MutationHooks []func()
+	// MutationObservers receive the sampled write operations
+	MutationObservers []MutationObserver
+	// ChangeTracking records the mutations for the incremental snapshots
+	ChangeTracking bool
+	// LatencyHistograms enables the latency histograms of the operations
+	LatencyHistograms bool
+}
+
+// Option is a functional option for the constructors of the containers:
+//
+//	s := csstack.New[int](gods.WithSharding(16))
+type Option func(*Options)
+
+// WithRWMutex selects the RWMutexLock strategy.
+func WithRWMutex() Option {
+	return func(o *Options) {
+		o.Locking = RWMutexLock
+	}
+}
+
+// WithMutex selects the MutexLock strategy.
+func WithMutex() Option {
+	return func(o *Options) {
+		o.Locking = MutexLock
+	}
+}
+
+// WithSharding selects the ShardedLock strategy with the given number of
+// shards (if shards is less than 1, GOMAXPROCS shards are used).
+func WithSharding(shards int) Option {
+	return func(o *Options) {
+		o.Locking = ShardedLock
+		o.Shards = shards
+		o.AutoShards = false
+	}
+}
+
+// WithAutoShards selects the ShardedLock strategy with GOMAXPROCS shards,
+// growing the number of shards if GOMAXPROCS grows later (the check is done
+// by the writers, while they hold the lock).
+func WithAutoShards() Option {
+	return func(o *Options) {
+		o.Locking = ShardedLock
+		o.Shards = 0
+		o.AutoShards = true
+	}
+}
+
+// WithNodePool makes linked structures (like linkList and dlinkList) reuse
+// the nodes they remove, through a sync.Pool, instead of leaving them to the
+// garbage collector. It reduces the GC pressure of workloads with a heavy
+// churn, but the nodes returned by methods like Find or GetAt must not be used
+// after they have been removed from the structure, since they can be reused.
+func WithNodePool() Option {
+	return func(o *Options) {
+		o.NodePool = true
+	}
+}
+
+// WithMutationHook makes a concurrent container call fn every time a writer
+// releases its lock (so after every Push, Pop, Clear and so on). fn runs while
+// the lock is still held, so it must be quick and must not use the container:
+// it is meant to count mutations or to wake up another goroutine (see
+// Checkpointer). The option can be given more than once.
+func WithMutationHook(fn func()) Option {
+	return func(o *Options) {
+		if fn != nil {
+			o.MutationHooks = append(o.MutationHooks, fn)
+		}
+	}
+}
+
+// WithChangeTracking makes the containers that support it (like csmap) record
+// which elements they change, numbering their mutations, so they can encode
+// only the changes since a given mutation (see gods.ChangeTracker and
+// gods.Incremental). It costs an entry per element changed since the last full
+// snapshot.
+func WithChangeTracking() Option {
+	return func(o *Options) {
+		o.ChangeTracking = true
+	}
+}
+
+// NewOptions returns the Options resulting from applying opts to the defaults.
+func NewOptions(opts ...Option) Options {
+	o := Options{Locking: RWMutexLock}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	if o.Shards < 1 {
+		o.Shards = runtime.GOMAXPROCS(0)
+	}
+	return o
+}
@@ -18,8 +18,13 @@ package abBuffer
 import (
 	"errors"
 	"fmt"
+	"io"
+	"iter"
+	"unsafe"
 
+	gods "github.com/pzaino/gods"
 	"github.com/pzaino/gods/pkg/buffer"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 const (
@@ -126,6 +131,49 @@ func (b *ABBuffer[T]) Size() uint64 {
 	return b.active.Size()
 }
 
+// MemoryUsage returns an estimate of the bytes held by both the buffers (see
+// the gods package), adding the memory returned by extra for every item.
+func (b *ABBuffer[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
+	// A and B are part of the ABBuffer, so they are counted only once
+	buffers := 2 * uint64(unsafe.Sizeof(b.A))
+	return uint64(unsafe.Sizeof(*b)) - buffers + b.A.MemoryUsage(extra...) + b.B.MemoryUsage(extra...)
+}
+
+// Validate checks the invariants of the A/B buffer and of its two buffers (see gods.Validator).
+func (b *ABBuffer[T]) Validate() error {
+	if b.active != &b.A && b.active != &b.B {
+		return gods.Invariantf("the active buffer is neither A nor B")
+	}
+	for i, buf := range []*buffer.Buffer[T]{&b.A, &b.B} {
+		name := string(rune('A' + i))
+		if err := buf.Validate(); err != nil {
+			return fmt.Errorf("buffer %s: %w", name, err)
+		}
+		if b.capacity != 0 && buf.Size() > b.capacity {
+			return gods.Invariantf("buffer %s holds %d elements, more than the capacity %d", name, buf.Size(), b.capacity)
+		}
+	}
+	return nil
+}
+
+// Dump writes a rendering of the A/B buffer and of its two buffers to w, for
+// debugging.
+func (b *ABBuffer[T]) Dump(w io.Writer) error {
+	active := "A"
+	if b.active == &b.B {
+		active = "B"
+	}
+	d := gods.NewDumper(w)
+	d.Line(0, "ABBuffer capacity=%d active=%s", b.capacity, active)
+	if err := d.Err(); err != nil {
+		return err
+	}
+	if err := b.A.Dump(w); err != nil {
+		return err
+	}
+	return b.B.Dump(w)
+}
+
 // Capacity returns the capacity of the buffer
 func (b *ABBuffer[T]) Capacity() uint64 {
 	return b.capacity
@@ -169,9 +217,15 @@ func (b *ABBuffer[T]) Remove(index uint64) error {
 	return b.active.Remove(index)
 }
 
-// InsertAt inserts a new element at the given index in the active buffer
-func (b *ABBuffer[T]) InsertAt(index uint64, value T) error {
-	return b.active.InsertAt(index, value)
+// InsertAt inserts new elements at the given index in the active buffer
+func (b *ABBuffer[T]) InsertAt(index uint64, values ...T) error {
+	return b.active.InsertAt(index, values...)
+}
+
+// DeleteRange removes the elements from the index from up to (excluding) the
+// index to from the active buffer
+func (b *ABBuffer[T]) DeleteRange(from, to uint64) error {
+	return b.active.DeleteRange(from, to)
 }
 
 // ForEach applies the function to all elements in the active buffer
@@ -323,3 +377,83 @@ func (b *ABBuffer[T]) Merge(other *ABBuffer[T]) {
 func (b *ABBuffer[T]) Blit(other *ABBuffer[T], f func(T, T) T) error {
 	return b.active.Blit(other.active, f)
 }
+
+// Iter returns an iterator over the elements of the active buffer
+func (b *ABBuffer[T]) Iter() iter.Seq[T] {
+	return b.active.Iter()
+}
+
+// Iterator returns an iterator over the elements of the active buffer
+func (b *ABBuffer[T]) Iterator() iterator.BidirectionalIterator[T] {
+	return b.active.Iterator()
+}
+
+// Enumerate returns an iterator over the index and the value of the elements of the active buffer
+func (b *ABBuffer[T]) Enumerate() iter.Seq2[uint64, T] {
+	return b.active.Enumerate()
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (b *ABBuffer[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(b.Enumerate())
+}
+
+// Values returns an iterator over the items, in the same order as Enumerate
+// (it is Iter, under the name used by the standard library).
+func (b *ABBuffer[T]) Values() iter.Seq[T] {
+	return b.Iter()
+}
+
+// Encode writes the content of the active buffer to w using the given codec
+func (b *ABBuffer[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return b.active.Encode(w, codec)
+}
+
+// Decode replaces the content of the active buffer with the elements read from r using the given codec
+func (b *ABBuffer[T]) Decode(r io.Reader, codec gods.Codec) error {
+	return b.active.Decode(r, codec)
+}
+
+// EncodeStream writes the content of the active buffer to w incrementally
+// (see gods.EncodeStream)
+func (b *ABBuffer[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	return b.active.EncodeStream(w, codec)
+}
+
+// DecodeStream replaces the content of the active buffer with the elements of
+// a stream written by EncodeStream
+func (b *ABBuffer[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	return b.active.DecodeStream(r, codec)
+}
+
+// MarshalText implements encoding.TextMarshaler: the buffer is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (b *ABBuffer[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(b)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (b *ABBuffer[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(b, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (b *ABBuffer[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(b)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (b *ABBuffer[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(b, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (b *ABBuffer[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(b)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (b *ABBuffer[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(b, data)
+}
@@ -1,9 +1,11 @@
 package abBuffer_test
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/pzaino/gods/pkg/abBuffer"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 const (
@@ -635,3 +637,52 @@ func TestCopyActive(t *testing.T) {
 		t.Errorf(errExpectedXGotY, buf.GetActive(), newBuf.GetActive())
 	}
 }
+
+func TestIter(t *testing.T) {
+	ab := abBuffer.New[int](10)
+	_ = ab.Append(1)
+	_ = ab.Append(2)
+	ab.Swap()
+	_ = ab.Append(3)
+
+	if got := slices.Collect(ab.Iter()); !slices.Equal(got, []int{3}) {
+		t.Errorf("Expected %v, got %v", []int{3}, got)
+	}
+	ab.Swap()
+	if got := slices.Collect(ab.Iter()); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2}, got)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	ab := abBuffer.New[int](10)
+	_ = ab.Append(1)
+	_ = ab.Append(2)
+	if got := iterator.ToSlice[int](ab.Iterator()); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2}, got)
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	ab := abBuffer.New[int](10)
+	_ = ab.Append(1)
+	_ = ab.Append(2)
+	var indexes []uint64
+	var values []int
+	for i, v := range ab.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0, 1}) {
+		t.Errorf("Expected %v, got %v", []uint64{0, 1}, indexes)
+	}
+	if !slices.Equal(values, []int{1, 2}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2}, values)
+	}
+	if got := slices.Collect(ab.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+	if got := slices.Collect(ab.Values()); !slices.Equal(got, values) {
+		t.Errorf("Expected %v, got %v", values, got)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package arena provides a non-concurrent-safe chunk allocator.
+//
+// An Arena allocates values of the same type from large slabs (chunks)
+// instead of one by one, so building a structure with millions of nodes
+// results in a few thousands allocations, and all of them are released at
+// once by Free. Containers like linkList and dlinkList can be constructed
+// with an arena (see their NewWithArena functions):
+//
+//	a := arena.New[linkList.Node[int]](4096)
+//	l := linkList.NewWithArena[int](a)
+//	// ... build and use l ...
+//	a.Free() // l (and any other container using a) must not be used any more
+package arena
+
+// DefaultChunkSize is the number of values per chunk used when New is called
+// with a chunk size of 0.
+const DefaultChunkSize = 1024
+
+// Arena allocates values of type T from chunks of chunkSize values.
+type Arena[T any] struct {
+	chunks    [][]T
+	next      int // index of the next free value in the last chunk
+	chunkSize int
+}
+
+// New creates a new Arena with chunks of chunkSize values (DefaultChunkSize
+// if chunkSize is 0). No memory is allocated until the first Alloc.
+func New[T any](chunkSize uint64) *Arena[T] {
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	return &Arena[T]{chunkSize: int(chunkSize)}
+}
+
+// Alloc returns a pointer to a new zero value of type T.
+func (a *Arena[T]) Alloc() *T {
+	if len(a.chunks) == 0 || a.next == a.chunkSize {
+		a.chunks = append(a.chunks, make([]T, a.chunkSize))
+		a.next = 0
+	}
+	v := &a.chunks[len(a.chunks)-1][a.next]
+	a.next++
+	return v
+}
+
+// Free releases all the chunks of the arena at once. The pointers returned
+// by Alloc (and the containers built with the arena) must not be used after
+// Free. The arena itself can be used again.
+func (a *Arena[T]) Free() {
+	a.chunks = nil
+	a.next = 0
+}
+
+// Reset makes all the memory of the arena available again without releasing
+// it: the first chunk is zeroed and reused by the next allocations, the
+// others are released. Like with Free, the pointers returned by Alloc must
+// not be used after Reset.
+func (a *Arena[T]) Reset() {
+	if len(a.chunks) == 0 {
+		return
+	}
+	first := a.chunks[0]
+	clear(first)
+	a.chunks = append(a.chunks[:0], first)
+	clear(a.chunks[1:cap(a.chunks)])
+	a.next = 0
+}
+
+// Len returns the number of values allocated since the last Free or Reset.
+func (a *Arena[T]) Len() uint64 {
+	if len(a.chunks) == 0 {
+		return 0
+	}
+	return uint64((len(a.chunks)-1)*a.chunkSize + a.next)
+}
+
+// Chunks returns the number of chunks currently held by the arena.
+func (a *Arena[T]) Chunks() uint64 {
+	return uint64(len(a.chunks))
+}
+
+// ChunkSize returns the number of values per chunk.
+func (a *Arena[T]) ChunkSize() uint64 {
+	return uint64(a.chunkSize)
+}
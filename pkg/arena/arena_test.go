@@ -0,0 +1,95 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package arena_test
+
+import (
+	"testing"
+
+	arena "github.com/pzaino/gods/pkg/arena"
+)
+
+const (
+	errExpectedX = "Expected %v, but got %v"
+)
+
+type node struct {
+	value int
+	next  *node
+}
+
+func TestAlloc(t *testing.T) {
+	a := arena.New[node](4)
+	if a.Len() != 0 || a.Chunks() != 0 || a.ChunkSize() != 4 {
+		t.Errorf(errExpectedX, "an empty arena", a.Len())
+	}
+
+	var head *node
+	for i := 0; i < 10; i++ {
+		n := a.Alloc()
+		if n.value != 0 || n.next != nil {
+			t.Errorf(errExpectedX, "a zero value", *n)
+		}
+		n.value = i
+		n.next = head
+		head = n
+	}
+	if a.Len() != 10 || a.Chunks() != 3 {
+		t.Errorf(errExpectedX, 3, a.Chunks())
+	}
+	// the values are independent
+	expected := 9
+	for n := head; n != nil; n = n.next {
+		if n.value != expected {
+			t.Errorf(errExpectedX, expected, n.value)
+		}
+		expected--
+	}
+}
+
+func TestDefaultChunkSize(t *testing.T) {
+	a := arena.New[int](0)
+	if a.ChunkSize() != arena.DefaultChunkSize {
+		t.Errorf(errExpectedX, arena.DefaultChunkSize, a.ChunkSize())
+	}
+}
+
+func TestFreeAndReset(t *testing.T) {
+	a := arena.New[int](2)
+	for i := 0; i < 5; i++ {
+		*a.Alloc() = i + 1
+	}
+
+	a.Reset()
+	if a.Len() != 0 || a.Chunks() != 1 {
+		t.Errorf(errExpectedX, 1, a.Chunks())
+	}
+	if v := a.Alloc(); *v != 0 {
+		t.Errorf(errExpectedX, 0, *v)
+	}
+
+	a.Free()
+	if a.Len() != 0 || a.Chunks() != 0 {
+		t.Errorf(errExpectedX, 0, a.Chunks())
+	}
+	// the arena can be used again
+	*a.Alloc() = 1
+	if a.Len() != 1 {
+		t.Errorf(errExpectedX, 1, a.Len())
+	}
+	a.Reset()
+	a.Reset()
+	a.Free()
+	a.Reset()
+}
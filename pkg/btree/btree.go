@@ -0,0 +1,462 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package btree provides ordered maps implemented as B-trees: BTree keeps
+// its nodes in memory, File keeps them in the fixed-size pages of a file
+// mapped in memory, so the data can be larger than the RAM (the operating
+// system loads only the pages that are visited). Both are queried through the
+// same Ascend/Descend API (see Ordered).
+package btree
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+)
+
+const (
+	ErrBadOrder = "b-tree keys out of order"
+	ErrBadNode  = "b-tree node with a wrong number of entries"
+	ErrBadDepth = "b-tree leaves at different depths"
+	ErrBadSize  = "b-tree size mismatch"
+)
+
+// DefaultDegree is the degree of a BTree created with degree 0
+const DefaultDegree = 32
+
+// Ordered is the API shared by BTree and File to visit the entries in the
+// order of the keys.
+type Ordered[K, V any] interface {
+	// Ascend returns an iterator over all the entries, in ascending order
+	Ascend() iter.Seq2[K, V]
+	// AscendFrom returns an iterator over the entries with a key not less
+	// than from, in ascending order
+	AscendFrom(from K) iter.Seq2[K, V]
+	// AscendRange returns an iterator over the entries with a key in
+	// [from, to), in ascending order
+	AscendRange(from, to K) iter.Seq2[K, V]
+	// Descend returns an iterator over all the entries, in descending order
+	Descend() iter.Seq2[K, V]
+	// DescendFrom returns an iterator over the entries with a key not
+	// greater than from, in descending order
+	DescendFrom(from K) iter.Seq2[K, V]
+}
+
+var (
+	_ Ordered[int, int]    = (*BTree[int, int])(nil)
+	_ Ordered[string, int] = (*File[string, int])(nil)
+)
+
+type entry[K, V any] struct {
+	key   K
+	value V
+}
+
+// node holds between degree-1 and 2*degree-1 entries (the root can hold
+// fewer), and one child more than its entries unless it's a leaf
+type node[K, V any] struct {
+	entries  []entry[K, V]
+	children []*node[K, V]
+}
+
+func (n *node[K, V]) leaf() bool {
+	return len(n.children) == 0
+}
+
+// BTree is an ordered map of keys to values, ordered by a less function.
+// Two keys are the same if neither is less than the other. It is not
+// concurrency-safe, and it must not be changed while it's iterated.
+type BTree[K, V any] struct {
+	root   *node[K, V]
+	degree int
+	size   uint64
+	less   cmpx.Less[K]
+}
+
+// New creates a new empty B-tree ordered by less. Every node holds up to
+// 2*degree-1 entries; a degree lower than 2 selects DefaultDegree.
+func New[K, V any](degree int, less cmpx.Less[K]) *BTree[K, V] {
+	if degree < 2 {
+		degree = DefaultDegree
+	}
+	return &BTree[K, V]{root: &node[K, V]{}, degree: degree, less: less}
+}
+
+// Size returns the number of entries
+func (t *BTree[K, V]) Size() uint64 {
+	return t.size
+}
+
+// IsEmpty returns true if the B-tree has no entries
+func (t *BTree[K, V]) IsEmpty() bool {
+	return t.size == 0
+}
+
+// Clear removes all the entries
+func (t *BTree[K, V]) Clear() {
+	t.root, t.size = &node[K, V]{}, 0
+}
+
+// find returns the index of the first entry of n with a key not less than
+// key, and whether it is the same key
+func (t *BTree[K, V]) find(n *node[K, V], key K) (int, bool) {
+	i, _ := slices.BinarySearchFunc(n.entries, key, func(e entry[K, V], key K) int {
+		if t.less(e.key, key) {
+			return -1
+		}
+		return 1
+	})
+	return i, i < len(n.entries) && !t.less(key, n.entries[i].key)
+}
+
+// Get returns the value of key
+func (t *BTree[K, V]) Get(key K) (V, bool) {
+	for n := t.root; ; {
+		i, found := t.find(n, key)
+		if found {
+			return n.entries[i].value, true
+		}
+		if n.leaf() {
+			var zero V
+			return zero, false
+		}
+		n = n.children[i]
+	}
+}
+
+// Contains returns true if the B-tree holds key
+func (t *BTree[K, V]) Contains(key K) bool {
+	_, ok := t.Get(key)
+	return ok
+}
+
+// Put sets the value of key in O(log n), and returns true if key is new.
+func (t *BTree[K, V]) Put(key K, value V) bool {
+	if len(t.root.entries) == 2*t.degree-1 {
+		t.root = &node[K, V]{children: []*node[K, V]{t.root}}
+		t.split(t.root, 0)
+	}
+	for n := t.root; ; {
+		i, found := t.find(n, key)
+		if found {
+			n.entries[i].value = value
+			return false
+		}
+		if n.leaf() {
+			n.entries = slices.Insert(n.entries, i, entry[K, V]{key, value})
+			t.size++
+			return true
+		}
+		// full nodes are split on the way down, so the parent of a split
+		// always has room for the middle entry
+		if len(n.children[i].entries) == 2*t.degree-1 {
+			t.split(n, i)
+			if t.less(n.entries[i].key, key) {
+				i++
+			} else if !t.less(key, n.entries[i].key) {
+				n.entries[i].value = value
+				return false
+			}
+		}
+		n = n.children[i]
+	}
+}
+
+// split moves the second half of the full child i of n to a new child, and
+// its middle entry to n
+func (t *BTree[K, V]) split(n *node[K, V], i int) {
+	child, mid := n.children[i], t.degree-1
+	right := &node[K, V]{entries: slices.Clone(child.entries[mid+1:])}
+	if !child.leaf() {
+		right.children = slices.Clone(child.children[mid+1:])
+		clear(child.children[mid+1:])
+		child.children = child.children[:mid+1]
+	}
+	n.entries = slices.Insert(n.entries, i, child.entries[mid])
+	n.children = slices.Insert(n.children, i+1, right)
+	clear(child.entries[mid:])
+	child.entries = child.entries[:mid]
+}
+
+// Delete removes key in O(log n), and returns its value and false if there
+// was no such key.
+func (t *BTree[K, V]) Delete(key K) (V, bool) {
+	value, ok := t.delete(t.root, key)
+	if len(t.root.entries) == 0 && !t.root.leaf() {
+		t.root = t.root.children[0]
+	}
+	if ok {
+		t.size--
+	}
+	return value, ok
+}
+
+// delete removes key from the subtree of n, which has at least degree
+// entries unless it's the root
+func (t *BTree[K, V]) delete(n *node[K, V], key K) (V, bool) {
+	i, found := t.find(n, key)
+	if n.leaf() {
+		if !found {
+			var zero V
+			return zero, false
+		}
+		value := n.entries[i].value
+		n.entries = slices.Delete(n.entries, i, i+1)
+		return value, true
+	}
+	if found {
+		value := n.entries[i].value
+		switch {
+		case len(n.children[i].entries) >= t.degree:
+			// replace the entry with its predecessor
+			n.entries[i] = t.last(n.children[i])
+			t.delete(n.children[i], n.entries[i].key)
+		case len(n.children[i+1].entries) >= t.degree:
+			// or with its successor
+			n.entries[i] = t.first(n.children[i+1])
+			t.delete(n.children[i+1], n.entries[i].key)
+		default:
+			t.merge(n, i)
+			t.delete(n.children[i], key)
+		}
+		return value, true
+	}
+	// make room in the child before going down, so that it can lose an
+	// entry
+	if len(n.children[i].entries) < t.degree {
+		i = t.fill(n, i)
+	}
+	return t.delete(n.children[i], key)
+}
+
+// fill adds an entry to the child i of n, taken from a sibling or by merging
+// it with a sibling, and returns the new index of the child
+func (t *BTree[K, V]) fill(n *node[K, V], i int) int {
+	child := n.children[i]
+	switch {
+	case i > 0 && len(n.children[i-1].entries) >= t.degree:
+		left := n.children[i-1]
+		child.entries = slices.Insert(child.entries, 0, n.entries[i-1])
+		n.entries[i-1] = left.entries[len(left.entries)-1]
+		left.entries = left.entries[:len(left.entries)-1]
+		if !left.leaf() {
+			child.children = slices.Insert(child.children, 0, left.children[len(left.children)-1])
+			left.children[len(left.children)-1] = nil
+			left.children = left.children[:len(left.children)-1]
+		}
+	case i < len(n.entries) && len(n.children[i+1].entries) >= t.degree:
+		right := n.children[i+1]
+		child.entries = append(child.entries, n.entries[i])
+		n.entries[i] = right.entries[0]
+		right.entries = slices.Delete(right.entries, 0, 1)
+		if !right.leaf() {
+			child.children = append(child.children, right.children[0])
+			right.children = slices.Delete(right.children, 0, 1)
+		}
+	case i < len(n.entries):
+		t.merge(n, i)
+	default:
+		t.merge(n, i-1)
+		i--
+	}
+	return i
+}
+
+// merge joins the child i of n, its entry i and its child i+1
+func (t *BTree[K, V]) merge(n *node[K, V], i int) {
+	left, right := n.children[i], n.children[i+1]
+	left.entries = append(left.entries, n.entries[i])
+	left.entries = append(left.entries, right.entries...)
+	left.children = append(left.children, right.children...)
+	n.entries = slices.Delete(n.entries, i, i+1)
+	n.children = slices.Delete(n.children, i+1, i+2)
+}
+
+// first returns the first entry of the subtree of n
+func (t *BTree[K, V]) first(n *node[K, V]) entry[K, V] {
+	for !n.leaf() {
+		n = n.children[0]
+	}
+	return n.entries[0]
+}
+
+// last returns the last entry of the subtree of n
+func (t *BTree[K, V]) last(n *node[K, V]) entry[K, V] {
+	for !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+	return n.entries[len(n.entries)-1]
+}
+
+// Min returns the smallest key and its value, and false if the B-tree is
+// empty
+func (t *BTree[K, V]) Min() (K, V, bool) {
+	if t.size == 0 {
+		var zero entry[K, V]
+		return zero.key, zero.value, false
+	}
+	e := t.first(t.root)
+	return e.key, e.value, true
+}
+
+// Max returns the largest key and its value, and false if the B-tree is
+// empty
+func (t *BTree[K, V]) Max() (K, V, bool) {
+	if t.size == 0 {
+		var zero entry[K, V]
+		return zero.key, zero.value, false
+	}
+	e := t.last(t.root)
+	return e.key, e.value, true
+}
+
+// ascend visits the entries of the subtree of n with a key in [from, to)
+// (nil for no bound), and returns false if the visit must stop
+func (t *BTree[K, V]) ascend(n *node[K, V], from, to *K, yield func(K, V) bool) bool {
+	i := 0
+	if from != nil {
+		i, _ = t.find(n, *from)
+	}
+	for ; i <= len(n.entries); i++ {
+		if !n.leaf() && !t.ascend(n.children[i], from, to, yield) {
+			return false
+		}
+		// only the first child visited can hold keys less than from
+		from = nil
+		if i == len(n.entries) {
+			break
+		}
+		e := n.entries[i]
+		if to != nil && !t.less(e.key, *to) {
+			return false
+		}
+		if !yield(e.key, e.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// descend visits the entries of the subtree of n with a key not greater than
+// from (nil for no bound), and returns false if the visit must stop
+func (t *BTree[K, V]) descend(n *node[K, V], from *K, yield func(K, V) bool) bool {
+	end, found := len(n.entries), false
+	if from != nil {
+		end, found = t.find(n, *from)
+		if found {
+			end++
+		}
+	}
+	// the child after the last entry visited holds greater keys, unless
+	// that entry is the first one greater than from
+	if !n.leaf() && !found && !t.descend(n.children[end], from, yield) {
+		return false
+	}
+	for i := end - 1; i >= 0; i-- {
+		if !yield(n.entries[i].key, n.entries[i].value) {
+			return false
+		}
+		if !n.leaf() && !t.descend(n.children[i], nil, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// Ascend returns an iterator over all the entries, in ascending order
+func (t *BTree[K, V]) Ascend() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		t.ascend(t.root, nil, nil, yield)
+	}
+}
+
+// AscendFrom returns an iterator over the entries with a key not less than
+// from, in ascending order, starting in O(log n)
+func (t *BTree[K, V]) AscendFrom(from K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		t.ascend(t.root, &from, nil, yield)
+	}
+}
+
+// AscendRange returns an iterator over the entries with a key in [from, to),
+// in ascending order, starting in O(log n)
+func (t *BTree[K, V]) AscendRange(from, to K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		t.ascend(t.root, &from, &to, yield)
+	}
+}
+
+// Descend returns an iterator over all the entries, in descending order
+func (t *BTree[K, V]) Descend() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		t.descend(t.root, nil, yield)
+	}
+}
+
+// DescendFrom returns an iterator over the entries with a key not greater
+// than from, in descending order, starting in O(log n)
+func (t *BTree[K, V]) DescendFrom(from K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		t.descend(t.root, &from, yield)
+	}
+}
+
+// Validate checks the structure of the B-tree: the keys are in order, every
+// node but the root has between degree-1 and 2*degree-1 entries, and all
+// the leaves are at the same depth. It returns the first problem found.
+func (t *BTree[K, V]) Validate() error {
+	var (
+		count uint64
+		depth = -1
+		prev  *K
+	)
+	var walk func(n *node[K, V], level int) error
+	walk = func(n *node[K, V], level int) error {
+		if len(n.entries) > 2*t.degree-1 || (n != t.root && len(n.entries) < t.degree-1) ||
+			(!n.leaf() && len(n.children) != len(n.entries)+1) {
+			return fmt.Errorf("%s: %d entries at depth %d", ErrBadNode, len(n.entries), level)
+		}
+		if n.leaf() {
+			if depth >= 0 && depth != level {
+				return fmt.Errorf("%s: %d and %d", ErrBadDepth, depth, level)
+			}
+			depth = level
+		}
+		for i := 0; i <= len(n.entries); i++ {
+			if !n.leaf() {
+				if err := walk(n.children[i], level+1); err != nil {
+					return err
+				}
+			}
+			if i == len(n.entries) {
+				break
+			}
+			if prev != nil && !t.less(*prev, n.entries[i].key) {
+				return fmt.Errorf("%s: at entry %d", ErrBadOrder, count)
+			}
+			prev = &n.entries[i].key
+			count++
+		}
+		return nil
+	}
+	if err := walk(t.root, 0); err != nil {
+		return err
+	}
+	if count != t.size {
+		return fmt.Errorf("%s: %d entries, size %d", ErrBadSize, count, t.size)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btree_test
+
+import (
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	btree "github.com/pzaino/gods/pkg/btree"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+func less(a, b int) bool { return a < b }
+
+func keys[V any](seq iter.Seq2[int, V]) []int {
+	var keys []int
+	for k := range seq {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// checkOrder compares the iterators of t with the sorted keys in ref
+func checkOrder[V any](t *testing.T, tree btree.Ordered[int, V], ref []int) {
+	t.Helper()
+	if got := keys(tree.Ascend()); !slices.Equal(got, ref) {
+		t.Fatalf(errExpectedX, ref, got)
+	}
+	reversed := slices.Clone(ref)
+	slices.Reverse(reversed)
+	if got := keys(tree.Descend()); !slices.Equal(got, reversed) {
+		t.Fatalf(errExpectedX, reversed, got)
+	}
+	for _, pivot := range []int{-1, 0, 17, 250, 499, 1000} {
+		i, found := slices.BinarySearch(ref, pivot)
+		if got := keys(tree.AscendFrom(pivot)); !slices.Equal(got, ref[i:]) {
+			t.Fatalf("AscendFrom(%d): "+errExpectedX, pivot, ref[i:], got)
+		}
+		j, _ := slices.BinarySearch(ref, pivot+100)
+		if got := keys(tree.AscendRange(pivot, pivot+100)); !slices.Equal(got, ref[i:j]) {
+			t.Fatalf("AscendRange(%d): "+errExpectedX, pivot, ref[i:j], got)
+		}
+		if found {
+			i++
+		}
+		want := slices.Clone(ref[:i])
+		slices.Reverse(want)
+		if got := keys(tree.DescendFrom(pivot)); !slices.Equal(got, want) {
+			t.Fatalf("DescendFrom(%d): "+errExpectedX, pivot, want, got)
+		}
+	}
+	// stopping the loop
+	for range tree.Ascend() {
+		break
+	}
+	for range tree.Descend() {
+		break
+	}
+}
+
+func TestBTreeAgainstSlice(t *testing.T) {
+	for _, degree := range []int{2, 3, 0} {
+		tree := btree.New[int, int](degree, less)
+		var ref []int
+		r := rand.New(rand.NewPCG(1, uint64(degree)))
+		for i := 0; i < 5000; i++ {
+			k := r.IntN(500)
+			pos, found := slices.BinarySearch(ref, k)
+			if r.IntN(3) == 0 {
+				if v, ok := tree.Delete(k); ok != found || (ok && v != -k) {
+					t.Fatalf(errExpectedX, found, ok)
+				}
+				if found {
+					ref = slices.Delete(ref, pos, pos+1)
+				}
+			} else {
+				if tree.Put(k, -k) == found {
+					t.Fatalf(errExpectedX, !found, found)
+				}
+				if !found {
+					ref = slices.Insert(ref, pos, k)
+				}
+			}
+			if i%100 == 0 {
+				if err := tree.Validate(); err != nil {
+					t.Fatalf(errExpectedNoError, err)
+				}
+			}
+		}
+		if err := tree.Validate(); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+		if tree.Size() != uint64(len(ref)) {
+			t.Fatalf(errExpectedX, len(ref), tree.Size())
+		}
+		for _, k := range ref {
+			if v, ok := tree.Get(k); !ok || v != -k {
+				t.Fatalf(errExpectedX, -k, v)
+			}
+		}
+		if tree.Contains(-1) {
+			t.Errorf("expected -1 not to be in the tree")
+		}
+		if k, _, ok := tree.Min(); !ok || k != ref[0] {
+			t.Errorf(errExpectedX, ref[0], k)
+		}
+		if k, v, ok := tree.Max(); !ok || k != ref[len(ref)-1] || v != -k {
+			t.Errorf(errExpectedX, ref[len(ref)-1], k)
+		}
+		checkOrder(t, tree, ref)
+
+		tree.Clear()
+		if !tree.IsEmpty() || keys(tree.Ascend()) != nil {
+			t.Errorf(errExpectedX, 0, tree.Size())
+		}
+		if _, _, ok := tree.Min(); ok {
+			t.Errorf("expected no minimum")
+		}
+	}
+}
+
+func TestBTreeReplace(t *testing.T) {
+	tree := btree.New[int, string](2, less)
+	for i := range 100 {
+		tree.Put(i, "a")
+	}
+	for i := range 100 {
+		if tree.Put(i, "b") {
+			t.Fatalf(errExpectedX, false, true)
+		}
+	}
+	for _, v := range tree.Ascend() {
+		if v != "b" {
+			t.Fatalf(errExpectedX, "b", v)
+		}
+	}
+	if tree.Size() != 100 {
+		t.Errorf(errExpectedX, 100, tree.Size())
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btree
+
+import (
+	"bytes"
+	"errors"
+	"iter"
+
+	gods "github.com/pzaino/gods"
+)
+
+// Option configures a File.
+type Option func(*config)
+
+type config struct {
+	pageSize int
+	codec    gods.Codec
+	noSync   bool
+}
+
+// WithPageSize sets the page size of a new file, a power of 2 between 1024
+// and 65536 (the default is DefaultPageSize). An existing file keeps the
+// page size it was created with. An entry (key and value encoded) can take
+// up to a quarter of a page.
+func WithPageSize(n int) Option {
+	return func(cfg *config) {
+		cfg.pageSize = n
+	}
+}
+
+// WithCodec selects the codec used to encode the values (the default is
+// gods.JSONCodec).
+func WithCodec(codec gods.Codec) Option {
+	return func(cfg *config) {
+		cfg.codec = codec
+	}
+}
+
+// WithNoSync leaves the sync of the commits to the operating system: the
+// changes survive a crash of the process, but a crash of the machine can
+// corrupt the file. Loading a lot of entries is much faster, followed by a
+// Sync.
+func WithNoSync() Option {
+	return func(cfg *config) {
+		cfg.noSync = true
+	}
+}
+
+// File is an ordered map of keys to values stored in a file as a B+tree of
+// fixed-size pages, mapped in memory: the data can be larger than the RAM,
+// as only the pages visited by a lookup or an iteration are loaded by the
+// operating system (on the systems without mmap the file is read in memory).
+//
+// The keys are encoded by a KeyCodec that preserves their order, the values
+// by a gods.Codec. Every Put and Delete is a transaction: the pages it
+// changes are copied on write and the new tree is published atomically by a
+// meta page, synced to disk before it returns (see WithNoSync). A crash
+// leaves the file as it was after the last completed change. The pages
+// released by a change are kept in a free list, stored in the file too, and
+// reused by the following ones.
+//
+// A File is not concurrency-safe. The iterators stop at the first error (a
+// corrupted page, or a key or value that can't be decoded), reported by Err.
+// Changing the file in the loop of an iteration ends it too, after the
+// current entry, with ErrModified.
+type File[K, V any] struct {
+	s     *store
+	keys  KeyCodec[K]
+	codec gods.Codec
+	key   []byte
+	buf   bytes.Buffer
+	err   error
+}
+
+// Open opens the B-tree stored in the file at path, creating it if it
+// doesn't exist.
+func Open[K, V any](path string, keys KeyCodec[K], opts ...Option) (*File[K, V], error) {
+	cfg := &config{pageSize: DefaultPageSize, codec: gods.JSONCodec{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if err := validPageSize(cfg.pageSize); err != nil {
+		return nil, err
+	}
+	s, err := openStore(path, cfg.pageSize, cfg.noSync)
+	if err != nil {
+		return nil, err
+	}
+	return &File[K, V]{s: s, keys: keys, codec: cfg.codec}, nil
+}
+
+// Size returns the number of entries
+func (f *File[K, V]) Size() uint64 {
+	if f.s == nil {
+		return 0
+	}
+	return f.s.meta.count
+}
+
+// IsEmpty returns true if the file has no entries
+func (f *File[K, V]) IsEmpty() bool {
+	return f.Size() == 0
+}
+
+// PageSize returns the size of the pages of the file
+func (f *File[K, V]) PageSize() int {
+	if f.s == nil {
+		return 0
+	}
+	return f.s.pageSize
+}
+
+// Pages returns the number of pages of the file in use and the number of
+// free pages among them.
+func (f *File[K, V]) Pages() (pages, free uint64) {
+	if f.s == nil {
+		return 0, 0
+	}
+	return f.s.meta.pages, uint64(len(f.s.free))
+}
+
+// encodeKey returns the encoding of key, valid until the next call
+func (f *File[K, V]) encodeKey(key K) []byte {
+	f.key = f.keys.AppendKey(f.key[:0], key)
+	return f.key
+}
+
+func (f *File[K, V]) decode(key, value []byte) (K, V, error) {
+	var v V
+	k, err := f.keys.DecodeKey(key)
+	if err == nil {
+		err = f.codec.Decode(bytes.NewReader(value), &v)
+	}
+	return k, v, err
+}
+
+// Get returns the value of key
+func (f *File[K, V]) Get(key K) (V, bool, error) {
+	var zero V
+	if f.s == nil {
+		return zero, false, errors.New(ErrClosed)
+	}
+	value, ok, err := f.s.get(f.encodeKey(key))
+	if !ok || err != nil {
+		return zero, false, err
+	}
+	if err := f.codec.Decode(bytes.NewReader(value), &zero); err != nil {
+		return zero, false, err
+	}
+	return zero, true, nil
+}
+
+// Contains returns true if the file holds key
+func (f *File[K, V]) Contains(key K) (bool, error) {
+	if f.s == nil {
+		return false, errors.New(ErrClosed)
+	}
+	_, ok, err := f.s.get(f.encodeKey(key))
+	return ok, err
+}
+
+// Put sets the value of key, and returns true if key is new. It returns
+// ErrEntryTooLarge if the encoded key and value take more than a quarter of
+// a page.
+func (f *File[K, V]) Put(key K, value V) (bool, error) {
+	if f.s == nil {
+		return false, errors.New(ErrClosed)
+	}
+	f.buf.Reset()
+	if err := f.codec.Encode(&f.buf, value); err != nil {
+		return false, err
+	}
+	k := f.encodeKey(key)
+	limit := (f.s.pageSize - nodeHeader) / 4
+	if leafEntrySize(k, f.buf.Bytes()) > limit || branchEntrySize(k) > limit {
+		return false, errors.New(ErrEntryTooLarge)
+	}
+	return f.s.put(k, f.buf.Bytes())
+}
+
+// Delete removes key, and returns false if there was no such key.
+func (f *File[K, V]) Delete(key K) (bool, error) {
+	if f.s == nil {
+		return false, errors.New(ErrClosed)
+	}
+	return f.s.delete(f.encodeKey(key))
+}
+
+// iterate runs a visit of the store, decoding the entries for yield
+func (f *File[K, V]) iterate(visit func(yield func(key, value []byte) bool) (bool, error), yield func(K, V) bool) {
+	if f.err = nil; f.s == nil {
+		f.err = errors.New(ErrClosed)
+		return
+	}
+	s := f.s
+	gen := s.gen
+	_, err := visit(func(key, value []byte) bool {
+		k, v, err := f.decode(key, value)
+		if err != nil {
+			f.err = err
+			return false
+		}
+		if !yield(k, v) {
+			return false
+		}
+		// a change in the loop can have unmapped the pages being visited:
+		// the visit must stop before reading them again
+		if s.gen != gen {
+			f.err = errors.New(ErrModified)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		f.err = err
+	}
+}
+
+// Ascend returns an iterator over all the entries, in ascending order
+func (f *File[K, V]) Ascend() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		f.iterate(func(visit func(key, value []byte) bool) (bool, error) {
+			return f.s.ascend(f.s.meta.root, nil, nil, visit)
+		}, yield)
+	}
+}
+
+// AscendFrom returns an iterator over the entries with a key not less than
+// from, in ascending order
+func (f *File[K, V]) AscendFrom(from K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		f.iterate(func(visit func(key, value []byte) bool) (bool, error) {
+			return f.s.ascend(f.s.meta.root, f.keys.AppendKey(nil, from), nil, visit)
+		}, yield)
+	}
+}
+
+// AscendRange returns an iterator over the entries with a key in [from, to),
+// in ascending order
+func (f *File[K, V]) AscendRange(from, to K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		f.iterate(func(visit func(key, value []byte) bool) (bool, error) {
+			return f.s.ascend(f.s.meta.root, f.keys.AppendKey(nil, from), f.keys.AppendKey(nil, to), visit)
+		}, yield)
+	}
+}
+
+// Descend returns an iterator over all the entries, in descending order
+func (f *File[K, V]) Descend() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		f.iterate(func(visit func(key, value []byte) bool) (bool, error) {
+			return f.s.descend(f.s.meta.root, nil, visit)
+		}, yield)
+	}
+}
+
+// DescendFrom returns an iterator over the entries with a key not greater
+// than from, in descending order
+func (f *File[K, V]) DescendFrom(from K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		f.iterate(func(visit func(key, value []byte) bool) (bool, error) {
+			return f.s.descend(f.s.meta.root, f.keys.AppendKey(nil, from), visit)
+		}, yield)
+	}
+}
+
+// Err returns the error that stopped the last iteration, or nil if it
+// visited all the entries it had to (or was stopped by the loop).
+func (f *File[K, V]) Err() error {
+	return f.err
+}
+
+// Validate checks the structure of the file: the keys are in order, the
+// leaves are at the same depth, the number of entries is right, and every
+// page is either in use or in the free list, once. It returns the first
+// problem found.
+func (f *File[K, V]) Validate() error {
+	if f.s == nil {
+		return errors.New(ErrClosed)
+	}
+	return f.s.validate()
+}
+
+// Sync flushes the file to stable storage, for the changes made with
+// WithNoSync.
+func (f *File[K, V]) Sync() error {
+	if f.s == nil {
+		return errors.New(ErrClosed)
+	}
+	return f.s.sync()
+}
+
+// Close syncs and closes the file. It is a no-op after the first call.
+func (f *File[K, V]) Close() error {
+	if f.s == nil {
+		return nil
+	}
+	err := f.s.sync()
+	if cerr := f.s.close(); err == nil {
+		err = cerr
+	}
+	f.s = nil
+	return err
+}
@@ -0,0 +1,312 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btree_test
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	btree "github.com/pzaino/gods/pkg/btree"
+)
+
+// intKeys stores int keys with Int64Keys
+type intKeys struct{ btree.Int64Keys }
+
+func (k intKeys) AppendKey(b []byte, key int) []byte {
+	return k.Int64Keys.AppendKey(b, int64(key))
+}
+
+func (k intKeys) DecodeKey(b []byte) (int, error) {
+	key, err := k.Int64Keys.DecodeKey(b)
+	return int(key), err
+}
+
+func openFile(t *testing.T, path string, opts ...btree.Option) *btree.File[int, string] {
+	t.Helper()
+	f, err := btree.Open[int, string](path, intKeys{}, opts...)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	return f
+}
+
+// sameEntries compares the entries of f with the ones of tree
+func sameEntries(t *testing.T, f *btree.File[int, string], tree *btree.BTree[int, string]) {
+	t.Helper()
+	var want, got []string
+	for k, v := range tree.Ascend() {
+		want = append(want, fmt.Sprint(k, "=", v))
+	}
+	for k, v := range f.Ascend() {
+		got = append(got, fmt.Sprint(k, "=", v))
+	}
+	if err := f.Err(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if !slices.Equal(got, want) || f.Size() != tree.Size() {
+		t.Fatalf(errExpectedX, want, got)
+	}
+}
+
+func TestFileAgainstBTree(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree")
+	f := openFile(t, path, btree.WithPageSize(1024), btree.WithNoSync())
+	tree := btree.New[int, string](0, less)
+	r := rand.New(rand.NewPCG(3, 4))
+	for i := 0; i < 3000; i++ {
+		k := r.IntN(1000) - 200
+		if r.IntN(3) == 0 {
+			_, want := tree.Delete(k)
+			if ok, err := f.Delete(k); err != nil || ok != want {
+				t.Fatalf(errExpectedX, want, ok)
+			}
+		} else {
+			// values of different sizes make nodes of different sizes
+			v := strings.Repeat("v", r.IntN(150))
+			want := tree.Put(k, v)
+			if added, err := f.Put(k, v); err != nil || added != want {
+				t.Fatalf(errExpectedX, want, added)
+			}
+		}
+		if i%250 == 0 {
+			if err := f.Validate(); err != nil {
+				t.Fatalf(errExpectedNoError, err)
+			}
+		}
+	}
+	if err := f.Validate(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	sameEntries(t, f, tree)
+	checkOrder(t, f, keys(tree.Ascend()))
+	for k, v := range tree.Ascend() {
+		if got, ok, err := f.Get(k); err != nil || !ok || got != v {
+			t.Fatalf(errExpectedX, v, got)
+		}
+	}
+	if ok, err := f.Contains(5000); err != nil || ok {
+		t.Errorf(errExpectedX, false, ok)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+
+	f = openFile(t, path)
+	defer f.Close()
+	if f.PageSize() != 1024 {
+		t.Errorf(errExpectedX, 1024, f.PageSize())
+	}
+	if err := f.Validate(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	sameEntries(t, f, tree)
+}
+
+func TestFileReusesFreePages(t *testing.T) {
+	f := openFile(t, filepath.Join(t.TempDir(), "tree"), btree.WithPageSize(1024), btree.WithNoSync())
+	defer f.Close()
+	value := strings.Repeat("v", 100)
+	fill := func() {
+		for k := range 500 {
+			if _, err := f.Put(k, value); err != nil {
+				t.Fatalf(errExpectedNoError, err)
+			}
+		}
+	}
+	fill()
+	pages, _ := f.Pages()
+	for k := range 500 {
+		if ok, err := f.Delete(k); err != nil || !ok {
+			t.Fatalf(errExpectedX, true, ok)
+		}
+	}
+	if err := f.Validate(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if _, free := f.Pages(); !f.IsEmpty() || free < pages/2 {
+		t.Errorf("expected the pages of the deleted entries to be free, got %d free of %d", free, pages)
+	}
+	fill()
+	if err := f.Validate(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if again, _ := f.Pages(); again > pages+pages/10 {
+		t.Errorf("expected the free pages to be reused, the file grew from %d to %d pages", pages, again)
+	}
+}
+
+func TestFileRecoversFromTornMeta(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree")
+	f := openFile(t, path)
+	// a new file is at transaction 1: these are 2 (meta page 0) and 3
+	// (meta page 1)
+	_, _ = f.Put(1, "one")
+	_, _ = f.Put(2, "two")
+	_ = f.Close()
+
+	tear := func(page int64) {
+		t.Helper()
+		file, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+		defer file.Close()
+		if _, err := file.WriteAt([]byte("torn"), page*btree.DefaultPageSize+20); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+	}
+	tear(1)
+	f = openFile(t, path)
+	if err := f.Validate(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if ok, _ := f.Contains(2); ok || f.Size() != 1 {
+		t.Errorf("expected the last change to be lost, got %d entries", f.Size())
+	}
+	if v, ok, err := f.Get(1); err != nil || !ok || v != "one" {
+		t.Errorf(errExpectedX, "one", v)
+	}
+	// the next change is written over the torn meta page
+	if _, err := f.Put(3, "three"); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	_ = f.Close()
+
+	tear(0)
+	f = openFile(t, path)
+	if got := keys(f.Ascend()); !slices.Equal(got, []int{1, 3}) {
+		t.Errorf(errExpectedX, []int{1, 3}, got)
+	}
+	_ = f.Close()
+
+	tear(1)
+	if _, err := btree.Open[int, string](path, intKeys{}); err == nil || !strings.HasPrefix(err.Error(), btree.ErrCorrupted) {
+		t.Errorf(errExpectedX, btree.ErrCorrupted, err)
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := btree.Open[int, string](filepath.Join(dir, "bad"), intKeys{}, btree.WithPageSize(1000)); err == nil || err.Error() != btree.ErrPageSize {
+		t.Errorf(errExpectedX, btree.ErrPageSize, err)
+	}
+
+	path := filepath.Join(dir, "tree")
+	f := openFile(t, path, btree.WithPageSize(1024))
+	if _, err := f.Put(1, strings.Repeat("v", 300)); err == nil || err.Error() != btree.ErrEntryTooLarge {
+		t.Errorf(errExpectedX, btree.ErrEntryTooLarge, err)
+	}
+	_, _ = f.Put(1, "one")
+	_ = f.Close()
+	if err := f.Close(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+	if _, err := f.Put(2, "two"); err == nil || err.Error() != btree.ErrClosed {
+		t.Errorf(errExpectedX, btree.ErrClosed, err)
+	}
+
+	// the values are strings, not numbers
+	g, err := btree.Open[int, int](path, intKeys{})
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	defer g.Close()
+	for range g.Ascend() {
+		t.Errorf("expected the value not to be decoded")
+	}
+	if g.Err() == nil {
+		t.Errorf("expected an error decoding the value")
+	}
+}
+
+func TestFileChangedDuringIteration(t *testing.T) {
+	f := openFile(t, filepath.Join(t.TempDir(), "tree"), btree.WithPageSize(1024), btree.WithNoSync())
+	defer f.Close()
+	value := strings.Repeat("v", 100)
+	for k := range 100 {
+		_, _ = f.Put(k, value)
+	}
+	visited := 0
+	for k := range f.Ascend() {
+		visited++
+		// enough pages to grow (and map again) the file
+		for i := range 200 {
+			if _, err := f.Put(1000+k*200+i, value); err != nil {
+				t.Fatalf(errExpectedNoError, err)
+			}
+		}
+	}
+	if err := f.Err(); visited != 1 || err == nil || err.Error() != btree.ErrModified {
+		t.Errorf(errExpectedX, btree.ErrModified, err)
+	}
+	visited = 0
+	for range f.Descend() {
+		if visited++; visited == 1 {
+			_, _ = f.Delete(0)
+		}
+	}
+	if err := f.Err(); visited != 1 || err == nil || err.Error() != btree.ErrModified {
+		t.Errorf(errExpectedX, btree.ErrModified, err)
+	}
+	for range f.Ascend() {
+	}
+	if err := f.Err(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+	if err := f.Validate(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+}
+
+func TestKeyCodecsKeepTheOrder(t *testing.T) {
+	dir := t.TempDir()
+	ints, err := btree.Open[int64, bool](filepath.Join(dir, "ints"), btree.Int64Keys{}, btree.WithNoSync())
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	defer ints.Close()
+	want := []int64{-1 << 62, -100, -1, 0, 1, 100, 1 << 62}
+	for _, i := range []int{3, 6, 0, 2, 5, 1, 4} {
+		_, _ = ints.Put(want[i], true)
+	}
+	var got []int64
+	for k := range ints.Ascend() {
+		got = append(got, k)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf(errExpectedX, want, got)
+	}
+
+	words, err := btree.Open[string, int](filepath.Join(dir, "words"), btree.StringKeys{}, btree.WithNoSync())
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	defer words.Close()
+	for i, w := range []string{"b", "", "ab", "a", "ba"} {
+		_, _ = words.Put(w, i)
+	}
+	var all []string
+	for w := range words.DescendFrom("b") {
+		all = append(all, w)
+	}
+	if want := []string{"b", "ab", "a", ""}; !slices.Equal(all, want) {
+		t.Errorf(errExpectedX, want, all)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btree
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+)
+
+const ErrBadKey = "b-tree key of the wrong length"
+
+// KeyCodec encodes the keys of a File. The encoded keys are compared as
+// bytes (see bytes.Compare), so the encoding must preserve the order of the
+// keys.
+type KeyCodec[K any] interface {
+	// AppendKey appends the encoding of key to b
+	AppendKey(b []byte, key K) []byte
+	// DecodeKey returns the key encoded in b
+	DecodeKey(b []byte) (K, error)
+}
+
+// StringKeys stores string keys as their bytes, ordered like the strings.
+type StringKeys struct{}
+
+func (StringKeys) AppendKey(b []byte, key string) []byte {
+	return append(b, key...)
+}
+
+func (StringKeys) DecodeKey(b []byte) (string, error) {
+	return string(b), nil
+}
+
+// BytesKeys stores []byte keys as they are, ordered by bytes.Compare.
+type BytesKeys struct{}
+
+func (BytesKeys) AppendKey(b []byte, key []byte) []byte {
+	return append(b, key...)
+}
+
+func (BytesKeys) DecodeKey(b []byte) ([]byte, error) {
+	return slices.Clone(b), nil
+}
+
+// Uint64Keys stores uint64 keys in big endian order.
+type Uint64Keys struct{}
+
+func (Uint64Keys) AppendKey(b []byte, key uint64) []byte {
+	return binary.BigEndian.AppendUint64(b, key)
+}
+
+func (Uint64Keys) DecodeKey(b []byte) (uint64, error) {
+	if len(b) != 8 {
+		return 0, errors.New(ErrBadKey)
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// Int64Keys stores int64 keys in big endian order, with the sign bit flipped
+// so that the negative keys come first.
+type Int64Keys struct{}
+
+func (Int64Keys) AppendKey(b []byte, key int64) []byte {
+	return binary.BigEndian.AppendUint64(b, uint64(key)^1<<63)
+}
+
+func (Int64Keys) DecodeKey(b []byte) (int64, error) {
+	if len(b) != 8 {
+		return 0, errors.New(ErrBadKey)
+	}
+	return int64(binary.BigEndian.Uint64(b) ^ 1<<63), nil
+}
@@ -0,0 +1,534 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"slices"
+	"sort"
+)
+
+// A file is a sequence of pages of the same size. The pages 0 and 1 hold two
+// copies of the meta data, written alternately by the commits: the one with
+// the highest transaction id and a valid checksum is the current one. The
+// meta data is:
+//
+//	magic    uint32
+//	version  uint32
+//	pageSize uint32
+//	txid     uint64
+//	root     uint64 the root page of the tree
+//	freelist uint64 the first page of the free list (0 if there's none)
+//	pages    uint64 the number of pages in use, free ones included
+//	count    uint64 the number of entries
+//	checksum uint32 CRC-32C of the fields above
+//
+// The other pages are the nodes of a B+tree (the values are in the leaves,
+// the branches hold the smallest key of each child), or the pages of the
+// free list. A node is its kind (1 byte), a reserved byte, the number n of
+// entries (uint16), the offsets of the entries in the page (n uint16) and
+// then the entries: uvarint key length, uvarint value length, key and value
+// in a leaf; child page (uint64), uvarint key length and key in a branch. A
+// page of the free list is its kind, 3 reserved bytes, the number n of page
+// ids (uint32), the next page of the list (uint64) and the n page ids
+// (uint64). All the integers are little endian.
+//
+// The pages are copied on write: a change writes the nodes it modifies to
+// free pages, the nodes they replace are freed, and the new root is
+// published by the next meta page. A crash before the meta page is written
+// leaves the file as it was at the previous commit. The pages freed by a
+// commit become reusable only after it, since the previous meta page still
+// refers to them.
+
+const (
+	ErrCorrupted     = "corrupted b-tree file"
+	ErrPageSize      = "b-tree page size must be a power of 2 between 1024 and 65536"
+	ErrEntryTooLarge = "b-tree entry larger than a quarter of a page"
+	ErrClosed        = "b-tree file is closed"
+	ErrModified      = "b-tree file changed during the iteration"
+)
+
+const (
+	// DefaultPageSize is the page size of a new File
+	DefaultPageSize = 4096
+	minPageSize     = 1024
+	maxPageSize     = 65536
+)
+
+const (
+	metaMagic   = 0x62746764 // "dgtb"
+	metaVersion = 1
+	metaSize    = 60
+	// the pages of a new file: the two meta pages and an empty root leaf
+	initialPages = 3
+)
+
+// page kinds
+const (
+	kindLeaf     byte = 1
+	kindBranch   byte = 2
+	kindFreelist byte = 3
+)
+
+const (
+	nodeHeader     = 4
+	freelistHeader = 16
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type meta struct {
+	txid     uint64
+	root     uint64
+	freelist uint64
+	pages    uint64
+	count    uint64
+}
+
+func (m *meta) encode(b []byte, pageSize int) {
+	binary.LittleEndian.PutUint32(b[0:], metaMagic)
+	binary.LittleEndian.PutUint32(b[4:], metaVersion)
+	binary.LittleEndian.PutUint32(b[8:], uint32(pageSize))
+	binary.LittleEndian.PutUint64(b[16:], m.txid)
+	binary.LittleEndian.PutUint64(b[24:], m.root)
+	binary.LittleEndian.PutUint64(b[32:], m.freelist)
+	binary.LittleEndian.PutUint64(b[40:], m.pages)
+	binary.LittleEndian.PutUint64(b[48:], m.count)
+	binary.LittleEndian.PutUint32(b[56:], crc32.Checksum(b[:56], crcTable))
+}
+
+// decodeMeta returns the meta data in b and its page size, and false if b
+// doesn't hold valid meta data
+func decodeMeta(b []byte) (meta, int, bool) {
+	if len(b) < metaSize || binary.LittleEndian.Uint32(b[0:]) != metaMagic ||
+		binary.LittleEndian.Uint32(b[4:]) != metaVersion ||
+		binary.LittleEndian.Uint32(b[56:]) != crc32.Checksum(b[:56], crcTable) {
+		return meta{}, 0, false
+	}
+	pageSize := int(binary.LittleEndian.Uint32(b[8:]))
+	m := meta{
+		txid:     binary.LittleEndian.Uint64(b[16:]),
+		root:     binary.LittleEndian.Uint64(b[24:]),
+		freelist: binary.LittleEndian.Uint64(b[32:]),
+		pages:    binary.LittleEndian.Uint64(b[40:]),
+		count:    binary.LittleEndian.Uint64(b[48:]),
+	}
+	if validPageSize(pageSize) != nil || m.pages < initialPages || m.root < 2 || m.root >= m.pages || m.freelist >= m.pages {
+		return meta{}, 0, false
+	}
+	return m, pageSize, true
+}
+
+func validPageSize(n int) error {
+	if n < minPageSize || n > maxPageSize || n&(n-1) != 0 {
+		return errors.New(ErrPageSize)
+	}
+	return nil
+}
+
+// store is the page store of a File. Its methods work on the encoded keys
+// and values.
+type store struct {
+	f        *os.File
+	data     []byte // the file, mapped in memory
+	pageSize int
+	meta     meta     // of the last commit
+	free     []uint64 // the free pages, in decreasing order
+	freelist []uint64 // the pages of the free list
+	noSync   bool
+	buf      []byte
+	// failed is the error of a commit that may have been written, after
+	// which the store refuses the changes
+	failed error
+	// gen changes with every commit and every new mapping of the file, so
+	// that an iteration can tell that its pages are gone
+	gen uint64
+}
+
+// openStore opens the page store in path, creating it with the given page
+// size if it doesn't exist
+func openStore(path string, pageSize int, noSync bool) (*store, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	s := &store{f: f, noSync: noSync}
+	if err := s.open(pageSize); err != nil {
+		if s.data != nil {
+			_ = unmapFile(s.data)
+		}
+		_ = f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *store) open(pageSize int) error {
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		if err := s.create(pageSize); err != nil {
+			return err
+		}
+	} else if err := s.readMeta(info.Size()); err != nil {
+		return err
+	}
+	if info, err = s.f.Stat(); err != nil {
+		return err
+	}
+	if s.data, err = mapFile(s.f, int(info.Size())); err != nil {
+		return err
+	}
+	s.buf = make([]byte, s.pageSize)
+	return s.readFreelist()
+}
+
+// create initializes an empty file
+func (s *store) create(pageSize int) error {
+	s.pageSize = pageSize
+	b := make([]byte, initialPages*pageSize)
+	b[2*pageSize] = kindLeaf
+	s.meta = meta{txid: 1, root: 2, pages: initialPages}
+	(&meta{root: 2, pages: initialPages}).encode(b, pageSize)
+	s.meta.encode(b[pageSize:], pageSize)
+	if _, err := s.f.WriteAt(b, 0); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// readMeta loads the current meta data of the file
+func (s *store) readMeta(size int64) error {
+	b := make([]byte, metaSize)
+	found := false
+	try := func(off int64, pageSize int) {
+		if _, err := s.f.ReadAt(b, off); err != nil {
+			return
+		}
+		m, ps, ok := decodeMeta(b)
+		// the first meta page tells the page size; if it's damaged, the
+		// second one is looked for at every possible page size
+		if !ok || (off > 0 && ps != pageSize) || int64(m.pages)*int64(ps) > size {
+			return
+		}
+		if !found || m.txid > s.meta.txid {
+			s.meta, s.pageSize, found = m, ps, true
+		}
+	}
+	try(0, 0)
+	if found {
+		try(int64(s.pageSize), s.pageSize)
+	} else {
+		for ps := minPageSize; ps <= maxPageSize; ps *= 2 {
+			try(int64(ps), ps)
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s: no valid meta page", ErrCorrupted)
+	}
+	return nil
+}
+
+// readFreelist loads the free list of the current meta data
+func (s *store) readFreelist() error {
+	s.free, s.freelist = nil, nil
+	for id := s.meta.freelist; id != 0; {
+		if len(s.freelist) >= int(s.meta.pages) {
+			return fmt.Errorf("%s: free list loop", ErrCorrupted)
+		}
+		p, err := s.page(id)
+		if err != nil {
+			return err
+		}
+		n := int(binary.LittleEndian.Uint32(p[4:]))
+		if p[0] != kindFreelist || n > (s.pageSize-freelistHeader)/8 {
+			return fmt.Errorf("%s: bad free list page %d", ErrCorrupted, id)
+		}
+		s.freelist = append(s.freelist, id)
+		for i := 0; i < n; i++ {
+			free := binary.LittleEndian.Uint64(p[freelistHeader+8*i:])
+			if free < 2 || free >= s.meta.pages {
+				return fmt.Errorf("%s: bad free page %d", ErrCorrupted, free)
+			}
+			s.free = append(s.free, free)
+		}
+		id = binary.LittleEndian.Uint64(p[8:])
+	}
+	slices.SortFunc(s.free, func(a, b uint64) int { return -cmpUint64(a, b) })
+	return nil
+}
+
+func cmpUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// page returns the page id, as mapped in memory
+func (s *store) page(id uint64) ([]byte, error) {
+	off := int(id) * s.pageSize
+	if id < 2 || off+s.pageSize > len(s.data) {
+		return nil, fmt.Errorf("%s: page %d out of the file", ErrCorrupted, id)
+	}
+	return s.data[off : off+s.pageSize : off+s.pageSize], nil
+}
+
+// writePage writes b to the page id
+func (s *store) writePage(id uint64, b []byte) error {
+	off := int(id) * s.pageSize
+	if _, err := s.f.WriteAt(b, int64(off)); err != nil {
+		return err
+	}
+	if !mapped {
+		copy(s.data[off:], b)
+	}
+	return nil
+}
+
+// grow makes the file (and its mapping) large enough for pages pages,
+// doubling its size up to 1 GiB and then growing by 1 GiB
+func (s *store) grow(pages uint64) error {
+	need := int(pages) * s.pageSize
+	if need <= len(s.data) {
+		return nil
+	}
+	size := len(s.data)
+	for size < need {
+		size += min(size, 1<<30)
+	}
+	if err := s.f.Truncate(int64(size)); err != nil {
+		return err
+	}
+	data, err := mapFile(s.f, size)
+	if err != nil {
+		return err
+	}
+	_ = unmapFile(s.data)
+	s.data = data
+	s.gen++
+	return nil
+}
+
+func (s *store) sync() error {
+	return s.f.Sync()
+}
+
+func (s *store) close() error {
+	err := unmapFile(s.data)
+	s.data = nil
+	s.gen++
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// header returns the kind and the number of entries of a node page
+func header(p []byte) (byte, int, error) {
+	n := int(binary.LittleEndian.Uint16(p[2:]))
+	if (p[0] != kindLeaf && p[0] != kindBranch) || nodeHeader+2*n > len(p) || (p[0] == kindBranch && n == 0) {
+		return 0, 0, fmt.Errorf("%s: bad node page", ErrCorrupted)
+	}
+	return p[0], n, nil
+}
+
+// entryAt returns the key of the entry i of a node page, and the value of a
+// leaf entry or the child of a branch entry
+func entryAt(p []byte, i int) (key, value []byte, child uint64, err error) {
+	b := p[min(int(binary.LittleEndian.Uint16(p[nodeHeader+2*i:])), len(p)):]
+	if p[0] == kindBranch {
+		if len(b) < 8 {
+			return nil, nil, 0, fmt.Errorf("%s: bad branch entry", ErrCorrupted)
+		}
+		child, b = binary.LittleEndian.Uint64(b), b[8:]
+	}
+	klen, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, nil, 0, fmt.Errorf("%s: bad entry", ErrCorrupted)
+	}
+	b = b[n:]
+	var vlen uint64
+	if p[0] == kindLeaf {
+		if vlen, n = binary.Uvarint(b); n <= 0 {
+			return nil, nil, 0, fmt.Errorf("%s: bad entry", ErrCorrupted)
+		}
+		b = b[n:]
+	}
+	if klen+vlen > uint64(len(b)) {
+		return nil, nil, 0, fmt.Errorf("%s: bad entry", ErrCorrupted)
+	}
+	return b[:klen:klen], b[klen : klen+vlen : klen+vlen], child, nil
+}
+
+// search returns the index of the first entry of a node page with a key not
+// less than key, and whether it is the same key
+func search(p []byte, n int, key []byte) (int, bool, error) {
+	var err error
+	i := sort.Search(n, func(i int) bool {
+		k, _, _, e := entryAt(p, i)
+		if e != nil {
+			err = e
+			return true
+		}
+		return bytes.Compare(k, key) >= 0
+	})
+	if err != nil || i == n {
+		return i, false, err
+	}
+	k, _, _, err := entryAt(p, i)
+	return i, err == nil && bytes.Equal(k, key), err
+}
+
+// childIndex returns the index of the child of a branch page whose keys
+// include key: the last child with a first key not greater than key, or
+// the first child
+func childIndex(p []byte, n int, key []byte) (int, error) {
+	i, found, err := search(p, n, key)
+	if !found && i > 0 {
+		i--
+	}
+	return i, err
+}
+
+// get returns the value of key
+func (s *store) get(key []byte) ([]byte, bool, error) {
+	for id := s.meta.root; ; {
+		p, err := s.page(id)
+		if err != nil {
+			return nil, false, err
+		}
+		kind, n, err := header(p)
+		if err != nil {
+			return nil, false, err
+		}
+		if kind == kindLeaf {
+			i, found, err := search(p, n, key)
+			if !found || err != nil {
+				return nil, false, err
+			}
+			_, value, _, err := entryAt(p, i)
+			return value, err == nil, err
+		}
+		i, err := childIndex(p, n, key)
+		if err != nil {
+			return nil, false, err
+		}
+		if _, _, id, err = entryAt(p, i); err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+// ascend visits the entries of the subtree of the page id with a key in
+// [from, to) (nil for no bound), and returns false if the visit must stop
+func (s *store) ascend(id uint64, from, to []byte, yield func(key, value []byte) bool) (bool, error) {
+	p, err := s.page(id)
+	if err != nil {
+		return false, err
+	}
+	kind, n, err := header(p)
+	if err != nil {
+		return false, err
+	}
+	i := 0
+	if from != nil {
+		if kind == kindLeaf {
+			i, _, err = search(p, n, from)
+		} else {
+			i, err = childIndex(p, n, from)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	for ; i < n; i++ {
+		key, value, child, err := entryAt(p, i)
+		if err != nil {
+			return false, err
+		}
+		if to != nil && bytes.Compare(key, to) >= 0 {
+			return false, nil
+		}
+		if kind == kindLeaf {
+			if !yield(key, value) {
+				return false, nil
+			}
+			continue
+		}
+		if more, err := s.ascend(child, from, to, yield); !more || err != nil {
+			return false, err
+		}
+		// only the first child visited can hold keys less than from
+		from = nil
+	}
+	return true, nil
+}
+
+// descend visits the entries of the subtree of the page id with a key not
+// greater than from (nil for no bound), and returns false if the visit must
+// stop
+func (s *store) descend(id uint64, from []byte, yield func(key, value []byte) bool) (bool, error) {
+	p, err := s.page(id)
+	if err != nil {
+		return false, err
+	}
+	kind, n, err := header(p)
+	if err != nil {
+		return false, err
+	}
+	i := n - 1
+	if from != nil {
+		if kind == kindLeaf {
+			var found bool
+			if i, found, err = search(p, n, from); !found {
+				i--
+			}
+		} else {
+			i, err = childIndex(p, n, from)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	for ; i >= 0; i-- {
+		key, value, child, err := entryAt(p, i)
+		if err != nil {
+			return false, err
+		}
+		if kind == kindLeaf {
+			if !yield(key, value) {
+				return false, nil
+			}
+			continue
+		}
+		if more, err := s.descend(child, from, yield); !more || err != nil {
+			return false, err
+		}
+		// only the first child visited can hold keys greater than from
+		from = nil
+	}
+	return true, nil
+}
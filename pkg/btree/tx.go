@@ -0,0 +1,526 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// pageNode is a node page decoded in memory, to be changed and written to new
+// pages
+type pageNode struct {
+	leaf     bool
+	keys     [][]byte
+	values   [][]byte // of a leaf
+	children []uint64 // of a branch
+}
+
+// node decodes the page id
+func (s *store) node(id uint64) (*pageNode, error) {
+	p, err := s.page(id)
+	if err != nil {
+		return nil, err
+	}
+	kind, count, err := header(p)
+	if err != nil {
+		return nil, err
+	}
+	n := &pageNode{leaf: kind == kindLeaf}
+	for i := 0; i < count; i++ {
+		key, value, child, err := entryAt(p, i)
+		if err != nil {
+			return nil, err
+		}
+		// the page can be unmapped by the growth of the file
+		n.keys = append(n.keys, slices.Clone(key))
+		if n.leaf {
+			n.values = append(n.values, slices.Clone(value))
+		} else {
+			n.children = append(n.children, child)
+		}
+	}
+	return n, nil
+}
+
+func leafEntrySize(key, value []byte) int {
+	return 2 + uvarintLen(len(key)) + uvarintLen(len(value)) + len(key) + len(value)
+}
+
+func branchEntrySize(key []byte) int {
+	return 2 + 8 + uvarintLen(len(key)) + len(key)
+}
+
+func uvarintLen(n int) int {
+	l := 1
+	for ; n >= 0x80; n >>= 7 {
+		l++
+	}
+	return l
+}
+
+// size returns the size of the entry i in the page (its offset included)
+func (n *pageNode) size(i int) int {
+	if n.leaf {
+		return leafEntrySize(n.keys[i], n.values[i])
+	}
+	return branchEntrySize(n.keys[i])
+}
+
+// encode writes the entries [from, to) to the page p
+func (n *pageNode) encode(p []byte, from, to int) {
+	clear(p)
+	p[0] = kindBranch
+	if n.leaf {
+		p[0] = kindLeaf
+	}
+	binary.LittleEndian.PutUint16(p[2:], uint16(to-from))
+	off := nodeHeader + 2*(to-from)
+	for i := from; i < to; i++ {
+		binary.LittleEndian.PutUint16(p[nodeHeader+2*(i-from):], uint16(off))
+		if !n.leaf {
+			binary.LittleEndian.PutUint64(p[off:], n.children[i])
+			off += 8
+		}
+		off += binary.PutUvarint(p[off:], uint64(len(n.keys[i])))
+		if n.leaf {
+			off += binary.PutUvarint(p[off:], uint64(len(n.values[i])))
+		}
+		off += copy(p[off:], n.keys[i])
+		if n.leaf {
+			off += copy(p[off:], n.values[i])
+		}
+	}
+}
+
+// search returns the index of the first key not less than key, and whether
+// it is the same key
+func (n *pageNode) search(key []byte) (int, bool) {
+	return slices.BinarySearchFunc(n.keys, key, bytes.Compare)
+}
+
+// childIndex returns the index of the child whose keys include key
+func (n *pageNode) childIndex(key []byte) int {
+	i, found := n.search(key)
+	if !found && i > 0 {
+		i--
+	}
+	return i
+}
+
+// splice replaces the child i of a branch with the nodes written in its
+// place
+func (n *pageNode) splice(i int, refs []ref) {
+	keys := make([][]byte, len(refs))
+	children := make([]uint64, len(refs))
+	for j, r := range refs {
+		keys[j], children[j] = r.key, r.id
+	}
+	n.keys = slices.Replace(n.keys, i, i+1, keys...)
+	n.children = slices.Replace(n.children, i, i+1, children...)
+}
+
+// ref is a node written to a page: its first key, its page and its size
+type ref struct {
+	key  []byte
+	id   uint64
+	size int
+}
+
+// tx is a change of the tree. The pages it writes are not visible until it's
+// committed, and dropping it leaves the store unchanged.
+type tx struct {
+	s       *store
+	root    uint64
+	pages   uint64
+	count   uint64
+	taken   int             // pages taken from the end of s.free
+	reused  []uint64        // pages written and then freed by the tx
+	pending []uint64        // pages of the last commit freed by the tx
+	fresh   map[uint64]bool // pages written by the tx
+}
+
+func (s *store) begin() *tx {
+	return &tx{s: s, root: s.meta.root, pages: s.meta.pages, count: s.meta.count, fresh: make(map[uint64]bool)}
+}
+
+// alloc returns a free page, growing the file if there's none
+func (t *tx) alloc() (uint64, error) {
+	var id uint64
+	switch {
+	case len(t.reused) > 0:
+		id, t.reused = t.reused[len(t.reused)-1], t.reused[:len(t.reused)-1]
+	case t.taken < len(t.s.free):
+		t.taken++
+		id = t.s.free[len(t.s.free)-t.taken]
+	default:
+		if err := t.s.grow(t.pages + 1); err != nil {
+			return 0, err
+		}
+		id = t.pages
+		t.pages++
+	}
+	t.fresh[id] = true
+	return id, nil
+}
+
+// release frees the page id: at once if the tx wrote it, after the commit
+// otherwise
+func (t *tx) release(id uint64) {
+	if t.fresh[id] {
+		delete(t.fresh, id)
+		t.reused = append(t.reused, id)
+		return
+	}
+	t.pending = append(t.pending, id)
+}
+
+// writeNode writes the entries [from, to) of n to a new page
+func (t *tx) writeNode(n *pageNode, from, to, size int) (ref, error) {
+	id, err := t.alloc()
+	if err != nil {
+		return ref{}, err
+	}
+	n.encode(t.s.buf, from, to)
+	if err := t.s.writePage(id, t.s.buf); err != nil {
+		return ref{}, err
+	}
+	r := ref{id: id, size: nodeHeader + size}
+	if from < to {
+		r.key = n.keys[from]
+	}
+	return r, nil
+}
+
+// write writes n to new pages, splitting it in nodes of about the same size
+// if it doesn't fit in one. An empty node isn't written.
+func (t *tx) write(n *pageNode) ([]ref, error) {
+	usable := t.s.pageSize - nodeHeader
+	total := 0
+	for i := range n.keys {
+		total += n.size(i)
+	}
+	if total == 0 {
+		return nil, nil
+	}
+	pieces := (total + usable - 1) / usable
+	target := (total + pieces - 1) / pieces
+	var refs []ref
+	for from := 0; from < len(n.keys); {
+		to, size := from, 0
+		for to < len(n.keys) && size < target && size+n.size(to) <= usable {
+			size += n.size(to)
+			to++
+		}
+		r, err := t.writeNode(n, from, to, size)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, r)
+		from = to
+	}
+	return refs, nil
+}
+
+// put sets key to value in the subtree of the page id, and returns the
+// nodes written in its place and whether key is new
+func (t *tx) put(id uint64, key, value []byte) ([]ref, bool, error) {
+	n, err := t.s.node(id)
+	if err != nil {
+		return nil, false, err
+	}
+	added := false
+	if n.leaf {
+		i, found := n.search(key)
+		if found {
+			n.values[i] = value
+		} else {
+			n.keys = slices.Insert(n.keys, i, key)
+			n.values = slices.Insert(n.values, i, value)
+			added = true
+		}
+	} else {
+		i := n.childIndex(key)
+		refs, a, err := t.put(n.children[i], key, value)
+		if err != nil {
+			return nil, false, err
+		}
+		n.splice(i, refs)
+		added = a
+	}
+	t.release(id)
+	refs, err := t.write(n)
+	return refs, added, err
+}
+
+// delete removes key from the subtree of the page id, and returns the nodes
+// written in its place and whether key was found (the subtree is unchanged
+// otherwise)
+func (t *tx) delete(id uint64, key []byte) ([]ref, bool, error) {
+	n, err := t.s.node(id)
+	if err != nil {
+		return nil, false, err
+	}
+	if n.leaf {
+		i, found := n.search(key)
+		if !found {
+			return nil, false, nil
+		}
+		n.keys = slices.Delete(n.keys, i, i+1)
+		n.values = slices.Delete(n.values, i, i+1)
+	} else {
+		i := n.childIndex(key)
+		refs, found, err := t.delete(n.children[i], key)
+		if !found || err != nil {
+			return nil, found, err
+		}
+		n.splice(i, refs)
+		if len(refs) == 1 && refs[0].size < t.s.pageSize/4 && len(n.keys) > 1 {
+			if err := t.merge(n, max(i-1, 0)); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	t.release(id)
+	refs, err := t.write(n)
+	return refs, true, err
+}
+
+// merge joins the children i and i+1 of a branch, splitting them again
+// (evenly) if they don't fit in a page
+func (t *tx) merge(n *pageNode, i int) error {
+	left, err := t.s.node(n.children[i])
+	if err != nil {
+		return err
+	}
+	right, err := t.s.node(n.children[i+1])
+	if err != nil {
+		return err
+	}
+	left.keys = append(left.keys, right.keys...)
+	left.values = append(left.values, right.values...)
+	left.children = append(left.children, right.children...)
+	t.release(n.children[i])
+	t.release(n.children[i+1])
+	refs, err := t.write(left)
+	if err != nil {
+		return err
+	}
+	n.keys = slices.Delete(n.keys, i, i+1)
+	n.children = slices.Delete(n.children, i, i+1)
+	n.splice(i, refs)
+	return nil
+}
+
+// setRoot makes the nodes written in place of the root the new root, adding
+// a level if they are more than one and removing the levels with a single
+// child
+func (t *tx) setRoot(refs []ref) error {
+	for len(refs) > 1 {
+		n := &pageNode{}
+		for _, r := range refs {
+			n.keys = append(n.keys, r.key)
+			n.children = append(n.children, r.id)
+		}
+		var err error
+		if refs, err = t.write(n); err != nil {
+			return err
+		}
+	}
+	if len(refs) == 0 {
+		r, err := t.writeNode(&pageNode{leaf: true}, 0, 0, 0)
+		if err != nil {
+			return err
+		}
+		refs = []ref{r}
+	}
+	t.root = refs[0].id
+	for {
+		n, err := t.s.node(t.root)
+		if err != nil {
+			return err
+		}
+		if n.leaf || len(n.children) > 1 {
+			return nil
+		}
+		t.release(t.root)
+		t.root = n.children[0]
+	}
+}
+
+// commit writes the free list and then the meta page, publishing the changes
+func (t *tx) commit() error {
+	// the free list is rewritten as a whole
+	for _, id := range t.s.freelist {
+		t.release(id)
+	}
+	perPage := (t.s.pageSize - freelistHeader) / 8
+	var listPages []uint64
+	for perPage*len(listPages) < len(t.reused)+len(t.s.free)-t.taken+len(t.pending) {
+		id, err := t.alloc()
+		if err != nil {
+			return err
+		}
+		listPages = append(listPages, id)
+	}
+	free := slices.Concat(t.s.free[:len(t.s.free)-t.taken], t.reused, t.pending)
+	slices.SortFunc(free, func(a, b uint64) int { return -cmpUint64(a, b) })
+	for i, id := range listPages {
+		ids := free[min(i*perPage, len(free)):min((i+1)*perPage, len(free))]
+		p := t.s.buf
+		clear(p)
+		p[0] = kindFreelist
+		binary.LittleEndian.PutUint32(p[4:], uint32(len(ids)))
+		if i+1 < len(listPages) {
+			binary.LittleEndian.PutUint64(p[8:], listPages[i+1])
+		}
+		for j, free := range ids {
+			binary.LittleEndian.PutUint64(p[freelistHeader+8*j:], free)
+		}
+		if err := t.s.writePage(id, p); err != nil {
+			return err
+		}
+	}
+
+	m := meta{txid: t.s.meta.txid + 1, root: t.root, pages: t.pages, count: t.count}
+	if len(listPages) > 0 {
+		m.freelist = listPages[0]
+	}
+	if !t.s.noSync {
+		if err := t.s.sync(); err != nil {
+			return err
+		}
+	}
+	b := make([]byte, metaSize)
+	m.encode(b, t.s.pageSize)
+	_, err := t.s.f.WriteAt(b, int64(m.txid%2)*int64(t.s.pageSize))
+	if err == nil && !t.s.noSync {
+		err = t.s.sync()
+	}
+	if err != nil {
+		// the meta page may be on disk or not: the pages of both commits
+		// must be left alone
+		t.s.failed = err
+		return err
+	}
+	t.s.meta, t.s.free, t.s.freelist = m, free, listPages
+	t.s.gen++
+	return nil
+}
+
+// put sets key to value, and returns true if key is new
+func (s *store) put(key, value []byte) (bool, error) {
+	if s.failed != nil {
+		return false, s.failed
+	}
+	t := s.begin()
+	refs, added, err := t.put(t.root, key, value)
+	if err != nil {
+		return false, err
+	}
+	if err := t.setRoot(refs); err != nil {
+		return false, err
+	}
+	if added {
+		t.count++
+	}
+	return added, t.commit()
+}
+
+// delete removes key, and returns false if there was no such key
+func (s *store) delete(key []byte) (bool, error) {
+	if s.failed != nil {
+		return false, s.failed
+	}
+	t := s.begin()
+	refs, found, err := t.delete(t.root, key)
+	if !found || err != nil {
+		return false, err
+	}
+	if err := t.setRoot(refs); err != nil {
+		return false, err
+	}
+	t.count--
+	return true, t.commit()
+}
+
+// validate checks the tree and the free list: the keys are in order, the
+// first key of every child is its key in the parent, the leaves are at the
+// same depth, and every page is either in use or free, once.
+func (s *store) validate() error {
+	used := map[uint64]bool{0: true, 1: true}
+	mark := func(id uint64) error {
+		if id >= s.meta.pages || used[id] {
+			return fmt.Errorf("%s: page %d used twice or out of the file", ErrCorrupted, id)
+		}
+		used[id] = true
+		return nil
+	}
+	var (
+		count uint64
+		depth = -1
+		prev  []byte
+	)
+	var walk func(id uint64, first []byte, level int) error
+	walk = func(id uint64, first []byte, level int) error {
+		if err := mark(id); err != nil {
+			return err
+		}
+		n, err := s.node(id)
+		if err != nil {
+			return err
+		}
+		if first != nil && (len(n.keys) == 0 || !bytes.Equal(n.keys[0], first)) {
+			return fmt.Errorf("%s: the key of page %d is not its first key", ErrCorrupted, id)
+		}
+		if !n.leaf {
+			for i, child := range n.children {
+				if err := walk(child, n.keys[i], level+1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if depth >= 0 && depth != level {
+			return fmt.Errorf("%s: leaves at depth %d and %d", ErrCorrupted, depth, level)
+		}
+		depth = level
+		for _, key := range n.keys {
+			if prev != nil && bytes.Compare(prev, key) >= 0 {
+				return fmt.Errorf("%s: keys out of order in page %d", ErrCorrupted, id)
+			}
+			prev = key
+			count++
+		}
+		return nil
+	}
+	if err := walk(s.meta.root, nil, 0); err != nil {
+		return err
+	}
+	if count != s.meta.count {
+		return fmt.Errorf("%s: %d entries, count %d", ErrCorrupted, count, s.meta.count)
+	}
+	for _, id := range slices.Concat(s.freelist, s.free) {
+		if err := mark(id); err != nil {
+			return err
+		}
+	}
+	if uint64(len(used)) != s.meta.pages {
+		return fmt.Errorf("%s: %d pages leaked", ErrCorrupted, s.meta.pages-uint64(len(used)))
+	}
+	return nil
+}
@@ -16,10 +16,18 @@
 package buffer
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"iter"
 	"runtime"
+	"slices"
 	"sync"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 const (
@@ -35,6 +43,7 @@ type Buffer[T comparable] struct {
 	data     []T
 	size     uint64
 	capacity uint64
+	growth   gods.Growth
 }
 
 // New creates a new Buffer
@@ -116,23 +125,27 @@ func (b *Buffer[T]) Append(elem T) error {
 	if b.IsFull() {
 		return errors.New(ErrBufferOverflow)
 	}
-	b.data = append(b.data, elem)
+	b.data = gods.Append(&b.growth, b.data, elem)
 	b.size++
 	return nil
 }
 
-// InsertAt adds an element at the given index
-func (b *Buffer[T]) InsertAt(index uint64, elem T) error {
+// InsertAt inserts the elements at the given index, moving the following
+// elements once (and without allocating, unless the buffer has to grow).
+func (b *Buffer[T]) InsertAt(index uint64, elems ...T) error {
 	if b.IsEmpty() && index != 0 {
 		return errors.New(ErrBufferEmpty)
 	}
-	if index > b.size || b.IsFull() {
+	n := uint64(len(elems))
+	if index > b.size || (b.capacity != 0 && b.size+n > b.capacity) {
 		return errors.New(ErrBufferOverflow)
 	}
 
-	// Insert the element at the given index
-	b.data = append(b.data[:index], append([]T{elem}, b.data[index:]...)...)
-	b.size++
+	// grow by n, then shift the tail up and copy the elements into the gap
+	b.data = gods.Append(&b.growth, b.data, elems...)
+	copy(b.data[index+n:], b.data[index:b.size])
+	copy(b.data[index:], elems)
+	b.size += n
 
 	return nil
 }
@@ -183,6 +196,19 @@ func (b *Buffer[T]) Remove(index uint64) error {
 	return nil
 }
 
+// DeleteRange removes the elements from the index from up to (excluding) the
+// index to, moving the following elements once.
+func (b *Buffer[T]) DeleteRange(from, to uint64) error {
+	if from > to || to > b.size {
+		return errors.New(ErrIndexOutOfBounds)
+	}
+	copy(b.data[from:], b.data[to:])
+	clear(b.data[b.size-(to-from):])
+	b.data = b.data[:b.size-(to-from)]
+	b.size -= to - from
+	return nil
+}
+
 // Clear removes all elements from the buffer
 func (b *Buffer[T]) Clear() {
 	b.data = []T{}
@@ -209,6 +235,39 @@ func (b *Buffer[T]) Size() uint64 {
 	return b.size
 }
 
+// MemoryUsage returns an estimate of the bytes held by the buffer (see the
+// gods package), adding the memory returned by extra for every item.
+func (b *Buffer[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
+	return uint64(unsafe.Sizeof(*b)) + gods.SliceMemory(b.data, extra...)
+}
+
+// Stats returns the statistics of the buffer: its peak size and how many
+// times its backing array was reallocated to grow.
+func (b *Buffer[T]) Stats() gods.Stats {
+	return gods.Stats{Growth: b.growth.Stats()}
+}
+
+// Validate checks the invariants of the buffer (see gods.Validator).
+func (b *Buffer[T]) Validate() error {
+	if b.size != uint64(len(b.data)) {
+		return gods.Invariantf("size is %d but the buffer holds %d elements", b.size, len(b.data))
+	}
+	if b.capacity != 0 && b.size > b.capacity {
+		return gods.Invariantf("size %d exceeds the capacity %d", b.size, b.capacity)
+	}
+	return nil
+}
+
+// Dump writes a rendering of the buffer to w, for debugging.
+func (b *Buffer[T]) Dump(w io.Writer) error {
+	d := gods.NewDumper(w)
+	d.Line(0, "Buffer size=%d capacity=%d cap=%d", b.size, b.capacity, cap(b.data))
+	for i, v := range b.data {
+		d.Line(1, "[%d] %v", i, v)
+	}
+	return d.Err()
+}
+
 // Capacity returns the capacity of the buffer
 func (b *Buffer[T]) Capacity() uint64 {
 	return b.capacity
@@ -250,6 +309,12 @@ func (b *Buffer[T]) ToSlice() []T {
 	return b.data
 }
 
+// Freeze returns an immutable copy of the buffer, which can be
+// shared without risking changes to it (see gods.Frozen).
+func (b *Buffer[T]) Freeze() *gods.Frozen[T] {
+	return gods.NewFrozen(slices.Clone(b.ToSlice()))
+}
+
 // Reverse reverses the buffer
 func (b *Buffer[T]) Reverse() {
 	if b.IsEmpty() {
@@ -310,7 +375,7 @@ func (b *Buffer[T]) Merge(other *Buffer[T]) {
 		return
 	}
 
-	b.data = append(b.data, other.data...)
+	b.data = gods.Append(&b.growth, b.data, other.data...)
 	b.size += other.size
 
 	// Clear the other buffer
@@ -340,7 +405,7 @@ func (b *Buffer[T]) PushN(items ...T) error {
 	if b.size+uint64(len(items)) > b.capacity && b.capacity != 0 {
 		return errors.New(ErrBufferOverflow)
 	}
-	b.data = append(b.data, items...)
+	b.data = gods.Append(&b.growth, b.data, items...)
 	b.size += uint64(len(items))
 	return nil
 }
@@ -780,3 +845,159 @@ func (b *Buffer[T]) BlitRange(start, end uint64, other *Buffer[T], f func(T, T)
 
 	return nil
 }
+
+// Iter returns an iterator over the elements in the buffer
+func (b *Buffer[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if b.IsEmpty() {
+			return
+		}
+		for i := uint64(0); i < b.size; i++ {
+			if !yield(b.data[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Iterator returns a bidirectional iterator over the elements in the buffer
+// The buffer must not be modified while iterating.
+func (b *Buffer[T]) Iterator() iterator.BidirectionalIterator[T] {
+	if b.IsEmpty() {
+		return iterator.FromSlice[T](nil)
+	}
+	return iterator.FromSlice(b.data[:b.size])
+}
+
+// Enumerate returns an iterator over the index and the value of the elements in the buffer
+func (b *Buffer[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		if b.IsEmpty() {
+			return
+		}
+		for i := uint64(0); i < b.size; i++ {
+			if !yield(i, b.data[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (b *Buffer[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(b.Enumerate())
+}
+
+// Encode writes the buffer to w using the given codec
+func (b *Buffer[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeSlice(w, codec, b.ToSlice())
+}
+
+// Decode replaces the content of the buffer with the elements read from r using the given codec
+func (b *Buffer[T]) Decode(r io.Reader, codec gods.Codec) error {
+	items, err := gods.DecodeSlice[T](r, codec)
+	if err != nil {
+		return err
+	}
+	b.Clear()
+	for _, item := range items {
+		if err := b.Append(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeStream writes the buffer to w incrementally, in chunks encoded with the
+// given codec (see gods.EncodeStream), without copying its elements first.
+func (b *Buffer[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeStream(w, codec, b.Iter())
+}
+
+// DecodeStream replaces the content of the buffer with the elements of a
+// stream written by EncodeStream, adding them as they are read.
+func (b *Buffer[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	b.Clear()
+	return gods.DecodeStream(r, codec, func(item T) error {
+		return b.Append(item)
+	})
+}
+
+// MarshalText implements encoding.TextMarshaler: the buffer is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (b *Buffer[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(b)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (b *Buffer[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(b, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (b *Buffer[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(b)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (b *Buffer[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(b, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (b *Buffer[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(b)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (b *Buffer[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(b, data)
+}
+
+// FilterCtx is like Filter but it stops as soon as the context is done,
+// returning ctx.Err(). In that case the buffer is left unchanged.
+func (b *Buffer[T]) FilterCtx(ctx context.Context, predicate func(T) bool) error {
+	var newData []T
+	for i := uint64(0); i < b.size; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if predicate(b.data[i]) {
+			newData = append(newData, b.data[i])
+		}
+	}
+	b.data = newData
+	b.size = uint64(len(newData))
+	return nil
+}
+
+// MapCtx is like Map but it stops as soon as the context is done, returning ctx.Err().
+func (b *Buffer[T]) MapCtx(ctx context.Context, fn func(T) T) (*Buffer[T], error) {
+	newBuffer := NewWithCapacity[T](b.capacity)
+	for i := uint64(0); i < b.size; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		newBuffer.data = append(newBuffer.data, fn(b.data[i]))
+	}
+	newBuffer.size = b.size
+	return newBuffer, nil
+}
+
+// ForEachCtx is like ForEach but it stops as soon as the context is done,
+// returning ctx.Err().
+func (b *Buffer[T]) ForEachCtx(ctx context.Context, fn func(*T) error) error {
+	if b.IsEmpty() {
+		return errors.New(ErrBufferEmpty)
+	}
+	for i := uint64(0); i < b.size; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&b.data[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
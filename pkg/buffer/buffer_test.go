@@ -16,12 +16,17 @@
 package buffer_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"reflect"
+	"slices"
 	"sync"
 	"testing"
 
 	buffer "github.com/pzaino/gods/pkg/buffer"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 const (
@@ -1015,6 +1020,13 @@ func TestInsertAt(t *testing.T) {
 	if elem != 5 {
 		t.Errorf("Expected element 2, got %v", elem)
 	}
+	err = b2.InsertAt(2, 6, 7)
+	if err != nil {
+		t.Errorf(errUnexpectedErr, err)
+	}
+	if !slices.Equal(b2.Values(), []int{1, 5, 6, 7, 2, 3}) {
+		t.Errorf(errExpectedValue, []int{1, 5, 6, 7, 2, 3}, b2.Values())
+	}
 	b.Clear()
 	err = b.InsertAt(1, 1)
 	if err == nil {
@@ -1167,3 +1179,280 @@ func TestNewWithSizeAndCapacity(t *testing.T) {
 		t.Errorf("Expected capacity 10, got %v", b.Capacity())
 	}
 }
+
+func TestIter(t *testing.T) {
+	b := buffer.New[int]()
+	for i := 1; i <= 3; i++ {
+		_ = b.Append(i)
+	}
+	var items []int
+	for v := range b.Iter() {
+		items = append(items, v)
+	}
+	if !reflect.DeepEqual(items, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, items)
+	}
+	for range buffer.New[int]().Iter() {
+		t.Error("Expected empty iteration")
+	}
+}
+
+func TestIterator(t *testing.T) {
+	b := buffer.New[int]()
+	for i := 1; i <= 3; i++ {
+		_ = b.Append(i)
+	}
+	it := b.Iterator()
+	if got := iterator.ToSlice[int](it); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, got)
+	}
+	if !it.Prev() || it.Value() != 3 {
+		t.Errorf("Expected %v, got %v", 3, it.Value())
+	}
+	if buffer.New[int]().Iterator().Next() {
+		t.Error("Expected empty iteration")
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	b := buffer.New[int]()
+	for i := 1; i <= 3; i++ {
+		_ = b.Append(i)
+	}
+	var indexes []uint64
+	var values []int
+	for i, v := range b.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0, 1, 2}) {
+		t.Errorf("Expected %v, got %v", []uint64{0, 1, 2}, indexes)
+	}
+	if !slices.Equal(values, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, values)
+	}
+	if got := slices.Collect(b.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+}
+
+func TestCtxVariants(t *testing.T) {
+	b := buffer.New[int]()
+	_ = b.PushN(1, 2, 3, 4)
+
+	if err := b.FilterCtx(context.Background(), func(v int) bool { return v > 2 }); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(b.ToSlice(), []int{3, 4}) {
+		t.Errorf("Expected %v, got %v", []int{3, 4}, b.ToSlice())
+	}
+	mapped, err := b.MapCtx(context.Background(), func(v int) int { return -v })
+	if err != nil || !reflect.DeepEqual(mapped.ToSlice(), []int{-3, -4}) {
+		t.Errorf("Expected %v, got %v", []int{-3, -4}, mapped)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.FilterCtx(ctx, func(int) bool { return false }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	if b.Size() != 2 {
+		t.Errorf("Expected size %d, got %d", 2, b.Size())
+	}
+	if _, err := b.MapCtx(ctx, func(v int) int { return v }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	if err := b.ForEachCtx(ctx, func(*int) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	b := createBufferWithElements(t, []int{1, 2, 1, 2, 1, 2, 3, 1, 2}, 0)
+	if i, err := b.IndexOf(2); err != nil || i != 1 {
+		t.Errorf(errExpectedValue, 1, i)
+	}
+	if _, err := buffer.New[int]().IndexOf(2); err == nil || err.Error() != buffer.ErrBufferEmpty {
+		t.Errorf(errExpectedErr, buffer.ErrBufferEmpty, err)
+	}
+	for _, tc := range []struct {
+		sub         []int
+		first, last uint64
+		found       bool
+	}{
+		{[]int{1, 2, 1, 2, 3}, 2, 2, true},
+		{[]int{1, 2}, 0, 7, true},
+		{[]int{}, 0, 9, true},
+		{[]int{2, 3, 1, 2}, 5, 5, true},
+		{[]int{3, 3}, 0, 0, false},
+	} {
+		first, err := b.Index(tc.sub)
+		last, lerr := b.LastIndex(tc.sub)
+		if !tc.found {
+			if err == nil || err.Error() != buffer.ErrValueNotFound || lerr == nil {
+				t.Errorf(errExpectedErr, buffer.ErrValueNotFound, err)
+			}
+			continue
+		}
+		if err != nil || lerr != nil || first != tc.first || last != tc.last {
+			t.Errorf(errExpectedValue, []uint64{tc.first, tc.last}, []uint64{first, last})
+		}
+	}
+
+	bb := buffer.New[byte]()
+	_ = bb.PushN([]byte("abcabcab")...)
+	if i, err := buffer.IndexBytes(bb, []byte("cab")); err != nil || i != 2 {
+		t.Errorf(errExpectedValue, 2, i)
+	}
+	if i, err := buffer.LastIndexBytes(bb, []byte("cab")); err != nil || i != 5 {
+		t.Errorf(errExpectedValue, 5, i)
+	}
+	if _, err := buffer.IndexBytes(bb, []byte("x")); err == nil {
+		t.Errorf(errExpectedErr, buffer.ErrValueNotFound, err)
+	}
+}
+
+// lcs returns the length of the longest common subsequence of x and y
+func lcs(x, y []int) int {
+	prev, cur := make([]int, len(y)+1), make([]int, len(y)+1)
+	for i := range x {
+		for j := range y {
+			if x[i] == y[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(cur[j], prev[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(y)]
+}
+
+func TestDiffAndApplyPatch(t *testing.T) {
+	a := createBufferWithElements(t, []int{1, 2, 3, 4, 5, 6}, 0)
+	b := createBufferWithElements(t, []int{1, 3, 4, 7, 6, 8}, 0)
+	edits := a.Diff(b)
+	want := []buffer.Edit[int]{
+		{Index: 1, Delete: 1},
+		{Index: 3, Delete: 1, Insert: []int{7}},
+		{Index: 5, Insert: []int{8}},
+	}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf(errExpectedValue, want, edits)
+	}
+	if err := a.ApplyPatch(edits); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if !slices.Equal(a.ToSlice(), b.ToSlice()) {
+		t.Errorf(errExpectedValue, b.ToSlice(), a.ToSlice())
+	}
+	if edits := a.Diff(b); len(edits) != 0 {
+		t.Errorf(errExpectedValue, "no edits", edits)
+	}
+
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for range 200 {
+		x := make([]int, rnd.IntN(30))
+		for i := range x {
+			x[i] = rnd.IntN(4)
+		}
+		y := make([]int, rnd.IntN(30))
+		for i := range y {
+			y[i] = rnd.IntN(4)
+		}
+		a, b := createBufferWithElements(t, x, 0), createBufferWithElements(t, y, 0)
+		edits := a.Diff(b)
+		changed := 0
+		for _, e := range edits {
+			changed += int(e.Delete) + len(e.Insert)
+		}
+		if want := len(x) + len(y) - 2*lcs(x, y); changed != want {
+			t.Errorf(errExpectedValue, want, changed)
+		}
+		if err := a.ApplyPatch(edits); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		if !slices.Equal(a.ToSlice(), y) {
+			t.Fatalf(errExpectedValue, y, a.ToSlice())
+		}
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	b := createBufferWithElements(t, []int{1, 2, 3}, 4)
+	err := b.ApplyPatch([]buffer.Edit[int]{{Index: 2, Delete: 2}})
+	if err == nil || err.Error() != buffer.ErrIndexOutOfBounds {
+		t.Errorf(errExpectedErr, buffer.ErrIndexOutOfBounds, err)
+	}
+	err = b.ApplyPatch([]buffer.Edit[int]{{Index: 0, Insert: []int{4, 5}}})
+	if err == nil || err.Error() != buffer.ErrBufferOverflow {
+		t.Errorf(errExpectedErr, buffer.ErrBufferOverflow, err)
+	}
+	if !slices.Equal(b.ToSlice(), []int{1, 2, 3}) {
+		t.Errorf(errExpectedValue, []int{1, 2, 3}, b.ToSlice())
+	}
+}
+
+func TestLines(t *testing.T) {
+	b := buffer.New[byte]()
+	for _, line := range []string{"first", "second\r", ""} {
+		if err := buffer.AppendLine(b, []byte(line)); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+	}
+	if err := b.PushN([]byte("partial")...); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+
+	var lines []string
+	for line := range buffer.Lines(b) {
+		lines = append(lines, string(line))
+	}
+	if want := []string{"first", "second", "", "partial"}; !slices.Equal(lines, want) {
+		t.Errorf(errExpectedValue, want, lines)
+	}
+
+	for _, want := range []string{"first", "second", ""} {
+		line, err := buffer.ReadLine(b)
+		if err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		if string(line) != want {
+			t.Errorf(errExpectedValue, want, string(line))
+		}
+	}
+	if _, err := buffer.ReadLine(b); err == nil || err.Error() != buffer.ErrNoLine {
+		t.Errorf(errExpectedErr, buffer.ErrNoLine, err)
+	}
+	if string(b.ToSlice()) != "partial" {
+		t.Errorf(errExpectedValue, "partial", string(b.ToSlice()))
+	}
+
+	small := buffer.NewWithCapacity[byte](4)
+	if err := buffer.AppendLine(small, []byte("four")); err == nil || err.Error() != buffer.ErrBufferOverflow {
+		t.Errorf(errExpectedErr, buffer.ErrBufferOverflow, err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	b := buffer.New[int]()
+	for i := 0; i < 100; i++ {
+		if err := b.Append(i); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+	}
+	// the peak size survives a Clear
+	b.Clear()
+	_ = b.Append(1)
+	growth := b.Stats().Growth
+	if growth.PeakSize != 100 {
+		t.Errorf(errExpectedValue, 100, growth.PeakSize)
+	}
+	// the backing array doubles while it is small
+	if growth.Resizes < 7 || growth.Resizes > 10 {
+		t.Errorf(errExpectedValue, "7 to 10 resizes", growth.Resizes)
+	}
+	if growth.PeakCapacity < 100 || growth.Allocated < growth.PeakCapacity {
+		t.Errorf(errExpectedValue, ">= 100", growth.PeakCapacity)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffer
+
+import (
+	"errors"
+	"slices"
+)
+
+// Edit is a change of a buffer computed by Diff: the Delete elements at Index
+// are replaced by the Insert elements. Index is a position in the buffer being
+// patched after the previous edits have been applied.
+type Edit[T any] struct {
+	Index  uint64
+	Delete uint64
+	Insert []T
+}
+
+// Diff returns the edits turning b into other, with the fewest elements
+// deleted and inserted (Myers' algorithm). The edits can be applied to a copy
+// of b with ApplyPatch. Diff runs in O((N+M)D) time and O(D²) memory, where D
+// is the number of elements deleted and inserted, after skipping the common
+// prefix and suffix of the buffers.
+func (b *Buffer[T]) Diff(other *Buffer[T]) []Edit[T] {
+	x, y := b.data[:b.size], other.data[:other.size]
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	x, y = x[prefix:], y[prefix:]
+	suffix := 0
+	for suffix < len(x) && suffix < len(y) && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	x, y = x[:len(x)-suffix], y[:len(y)-suffix]
+
+	var edits []Edit[T]
+	offset := int64(prefix) // from a position in x to a position in the patched buffer
+	for _, op := range myers(x, y) {
+		if n := len(edits); n > 0 {
+			last := &edits[n-1]
+			if uint64(int64(op.x)+offset) == last.Index+last.Delete {
+				// extend the last edit
+				if op.insert {
+					last.Insert = append(last.Insert, y[op.y])
+				} else {
+					last.Delete++
+				}
+				continue
+			}
+			offset += int64(len(last.Insert)) - int64(last.Delete)
+		}
+		e := Edit[T]{Index: uint64(int64(op.x) + offset)}
+		if op.insert {
+			e.Insert = []T{y[op.y]}
+		} else {
+			e.Delete = 1
+		}
+		edits = append(edits, e)
+	}
+	return edits
+}
+
+// ApplyPatch applies the edits returned by Diff, rebuilding the buffer in a
+// single pass. If an edit is out of range, or the patched buffer would exceed
+// the capacity, an error is returned and the buffer is not modified.
+func (b *Buffer[T]) ApplyPatch(edits []Edit[T]) error {
+	size := b.size
+	var prev uint64 // the end of the previous edit in the patched buffer
+	for _, e := range edits {
+		if e.Index < prev || e.Index+e.Delete > size {
+			return errors.New(ErrIndexOutOfBounds)
+		}
+		size = size - e.Delete + uint64(len(e.Insert))
+		prev = e.Index + uint64(len(e.Insert))
+	}
+	if b.capacity != 0 && size > b.capacity {
+		return errors.New(ErrBufferOverflow)
+	}
+
+	data := make([]T, 0, size)
+	var pos uint64 // the next element of b to copy
+	offset := int64(0)
+	for _, e := range edits {
+		from := uint64(int64(e.Index) - offset)
+		data = append(data, b.data[pos:from]...)
+		data = append(data, e.Insert...)
+		pos = from + e.Delete
+		offset += int64(len(e.Insert)) - int64(e.Delete)
+	}
+	b.data = append(data, b.data[pos:b.size]...)
+	b.size = size
+	return nil
+}
+
+// diffOp is a single deletion of x[x] or insertion of y[y] before x[x]
+type diffOp struct {
+	x, y   int
+	insert bool
+}
+
+// myers returns the shortest sequence of deletions and insertions turning x
+// into y, in order
+func myers[T comparable](x, y []T) []diffOp {
+	n, m := len(x), len(y)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	// v[k+max] is the furthest x reached on diagonal k = x - y; trace[d] keeps
+	// the diagonals -d..d reached with d edits, to walk the path back
+	max := n + m
+	v := make([]int, 2*max+2)
+	var trace [][]int
+	d := 0
+search:
+	for ; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && v[k-1+max] < v[k+1+max]) {
+				i = v[k+1+max] // insertion, from diagonal k+1
+			} else {
+				i = v[k-1+max] + 1 // deletion, from diagonal k-1
+			}
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i, j = i+1, j+1
+			}
+			v[k+max] = i
+			if i >= n && j >= m {
+				trace = append(trace, slices.Clone(v[max-d:max+d+1]))
+				break search
+			}
+		}
+		trace = append(trace, slices.Clone(v[max-d:max+d+1]))
+	}
+
+	ops := make([]diffOp, d)
+	i, j := n, m
+	for ; d > 0; d-- {
+		prev := trace[d-1] // prev[k+d-1] is diagonal k
+		k := i - j
+		var op diffOp
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			pi := prev[k+1+d-1]
+			op = diffOp{x: pi, y: pi - (k + 1), insert: true}
+			i, j = pi, pi-(k+1)
+		} else {
+			pi := prev[k-1+d-1]
+			op = diffOp{x: pi, y: pi - (k - 1)}
+			i, j = pi, pi-(k-1)
+		}
+		ops[d-1] = op
+	}
+	return ops
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"iter"
+)
+
+const (
+	ErrNoLine = "no complete line in the buffer"
+)
+
+// ReadLine removes the first line from a byte buffer and returns it without
+// its "\n" or "\r\n" terminator. If the buffer doesn't contain a complete
+// line yet, it returns an ErrNoLine error and leaves the buffer unchanged, so
+// the rest of the line can be appended later. The returned slice shares the
+// memory of the buffer, but it's not modified by the following operations.
+func ReadLine(b *Buffer[byte]) ([]byte, error) {
+	i := bytes.IndexByte(b.data, '\n')
+	if i < 0 {
+		return nil, errors.New(ErrNoLine)
+	}
+	line := b.data[:i:i]
+	b.data = b.data[i+1:]
+	b.size -= uint64(i + 1)
+	return dropCR(line), nil
+}
+
+// Lines returns an iterator over the lines of a byte buffer, without their
+// "\n" or "\r\n" terminator. The last line is returned even if it isn't
+// terminated, like bufio.Scanner does. The buffer is not modified, and must
+// not be modified during the iteration.
+func Lines(b *Buffer[byte]) iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		data := b.data
+		for len(data) > 0 {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				yield(dropCR(data[:len(data):len(data)]))
+				return
+			}
+			if !yield(dropCR(data[:i:i])) {
+				return
+			}
+			data = data[i+1:]
+		}
+	}
+}
+
+// AppendLine appends line and a "\n" terminator to a byte buffer, checking
+// the capacity once for both.
+func AppendLine(b *Buffer[byte], line []byte) error {
+	if b.capacity != 0 && b.size+uint64(len(line))+1 > b.capacity {
+		return errors.New(ErrBufferOverflow)
+	}
+	_ = b.PushN(line...)
+	return b.PushN('\n')
+}
+
+// dropCR drops the "\r" at the end of line
+func dropCR(line []byte) []byte {
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		return line[: n-1 : n-1]
+	}
+	return line
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffer
+
+import (
+	"bytes"
+	"errors"
+)
+
+// IndexOf returns the index of the first element with the given value. It is
+// Find, named after LastIndexOf.
+func (b *Buffer[T]) IndexOf(value T) (uint64, error) {
+	return b.Find(value)
+}
+
+// Index returns the index of the first occurrence of sub in the buffer (0 if
+// sub is empty). It runs in linear time (Knuth-Morris-Pratt).
+func (b *Buffer[T]) Index(sub []T) (uint64, error) {
+	if i := indexKMP(b.data, sub); i >= 0 {
+		return uint64(i), nil
+	}
+	return 0, errors.New(ErrValueNotFound)
+}
+
+// LastIndex returns the index of the last occurrence of sub in the buffer
+// (the size of the buffer if sub is empty). It runs in linear time.
+func (b *Buffer[T]) LastIndex(sub []T) (uint64, error) {
+	if i := lastIndexKMP(b.data, sub); i >= 0 {
+		return uint64(i), nil
+	}
+	return 0, errors.New(ErrValueNotFound)
+}
+
+// IndexBytes is Index for a byte buffer, using the optimized search of the
+// bytes package.
+func IndexBytes(b *Buffer[byte], sub []byte) (uint64, error) {
+	if i := bytes.Index(b.data, sub); i >= 0 {
+		return uint64(i), nil
+	}
+	return 0, errors.New(ErrValueNotFound)
+}
+
+// LastIndexBytes is LastIndex for a byte buffer, using the optimized search
+// of the bytes package.
+func LastIndexBytes(b *Buffer[byte], sub []byte) (uint64, error) {
+	if i := bytes.LastIndex(b.data, sub); i >= 0 {
+		return uint64(i), nil
+	}
+	return 0, errors.New(ErrValueNotFound)
+}
+
+// indexKMP returns the index of the first occurrence of sub in data, or -1
+func indexKMP[T comparable](data, sub []T) int {
+	if len(sub) == 0 {
+		return 0
+	}
+	// fail[i] is the length of the longest proper border of sub[:i+1]
+	fail := make([]int, len(sub))
+	for i, k := 1, 0; i < len(sub); i++ {
+		for k > 0 && sub[i] != sub[k] {
+			k = fail[k-1]
+		}
+		if sub[i] == sub[k] {
+			k++
+		}
+		fail[i] = k
+	}
+	for i, k := 0, 0; i < len(data); i++ {
+		for k > 0 && data[i] != sub[k] {
+			k = fail[k-1]
+		}
+		if data[i] == sub[k] {
+			k++
+		}
+		if k == len(sub) {
+			return i - k + 1
+		}
+	}
+	return -1
+}
+
+// lastIndexKMP returns the index of the last occurrence of sub in data, or
+// -1. It is indexKMP run from the end of data and sub.
+func lastIndexKMP[T comparable](data, sub []T) int {
+	n, m := len(data), len(sub)
+	if m == 0 {
+		return n
+	}
+	rsub := func(i int) T { return sub[m-1-i] }
+	fail := make([]int, m)
+	for i, k := 1, 0; i < m; i++ {
+		for k > 0 && rsub(i) != rsub(k) {
+			k = fail[k-1]
+		}
+		if rsub(i) == rsub(k) {
+			k++
+		}
+		fail[i] = k
+	}
+	for i, k := n-1, 0; i >= 0; i-- {
+		for k > 0 && data[i] != rsub(k) {
+			k = fail[k-1]
+		}
+		if data[i] == rsub(k) {
+			k++
+		}
+		if k == m {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,268 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache provides a bounded key/value cache with a choice of
+// eviction policies:
+//
+//   - LRU (the default): the least recently used entry is evicted.
+//   - SLRU (WithSLRU): the entries used at least twice move from a probation
+//     segment to a protected one (80% of the cache), so a scan of new keys
+//     evicts only the probation entries.
+//   - W-TinyLFU (WithTinyLFU): new entries go to a small LRU window (1% of
+//     the cache); the ones leaving it are admitted in the SLRU main area only
+//     if a frequency sketch (a count-min sketch with a doorkeeper, aged
+//     periodically) says they are used more often than the entry they would
+//     evict. The one-hit wonders are rejected, giving hit ratios close to the
+//     optimum on skewed (Zipfian) workloads.
+package cache
+
+import (
+	"errors"
+	"iter"
+
+	gods "github.com/pzaino/gods"
+	indexedlist "github.com/pzaino/gods/pkg/indexedlist"
+)
+
+const (
+	ErrInvalidCapacity = "the capacity must be positive"
+)
+
+// Option configures a Cache created with New.
+type Option func(*config)
+
+type config struct {
+	slru    bool
+	tinyLFU bool
+}
+
+// WithSLRU selects the segmented LRU policy.
+func WithSLRU() Option {
+	return func(cfg *config) {
+		cfg.slru = true
+	}
+}
+
+// WithTinyLFU selects the W-TinyLFU policy (a window LRU and an SLRU main
+// area with TinyLFU admission).
+func WithTinyLFU() Option {
+	return func(cfg *config) {
+		cfg.slru, cfg.tinyLFU = true, true
+	}
+}
+
+// Stats are the counters of a Cache.
+type Stats struct {
+	Hits       uint64
+	Misses     uint64
+	Evictions  uint64 // the entries evicted to make room for new ones
+	Rejections uint64 // the new entries refused by the admission filter
+}
+
+// Cache is a bounded key/value cache. It is not concurrency-safe.
+type Cache[K comparable, V any] struct {
+	capacity     int
+	window       *indexedlist.List[K, V] // W-TinyLFU only
+	windowCap    int
+	probation    *indexedlist.List[K, V] // the whole main area for LRU
+	protected    *indexedlist.List[K, V] // SLRU and W-TinyLFU only
+	protectedCap int
+	mainCap      int
+	sketch       *sketch[K] // W-TinyLFU only
+	stats        Stats
+}
+
+// New creates a new Cache holding up to capacity entries, with the LRU
+// policy unless an Option selects another one.
+func New[K comparable, V any](capacity int, opts ...Option) (*Cache[K, V], error) {
+	if capacity <= 0 {
+		return nil, errors.New(ErrInvalidCapacity)
+	}
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	c := &Cache[K, V]{
+		capacity:  capacity,
+		window:    indexedlist.New[K, V](),
+		probation: indexedlist.New[K, V](),
+		protected: indexedlist.New[K, V](),
+		mainCap:   capacity,
+	}
+	if cfg.tinyLFU {
+		c.sketch = newSketch[K](capacity)
+		if capacity > 1 {
+			c.windowCap = max(capacity/100, 1)
+			c.mainCap = capacity - c.windowCap
+		}
+	}
+	if cfg.slru {
+		c.protectedCap = c.mainCap * 8 / 10
+	}
+	return c, nil
+}
+
+// Capacity returns the maximum number of entries
+func (c *Cache[K, V]) Capacity() int {
+	return c.capacity
+}
+
+// Size returns the number of entries
+func (c *Cache[K, V]) Size() int {
+	return int(c.window.Size() + c.probation.Size() + c.protected.Size())
+}
+
+// Stats returns the counters of the cache
+func (c *Cache[K, V]) Stats() Stats {
+	return c.stats
+}
+
+// Contains returns true if key is in the cache, without counting it as a use
+func (c *Cache[K, V]) Contains(key K) bool {
+	return c.segment(key) != nil
+}
+
+// segment returns the segment holding key, or nil
+func (c *Cache[K, V]) segment(key K) *indexedlist.List[K, V] {
+	for _, seg := range []*indexedlist.List[K, V]{c.window, c.probation, c.protected} {
+		if seg.Contains(key) {
+			return seg
+		}
+	}
+	return nil
+}
+
+// touch records a use of key, which is in seg, and returns the segment
+// holding it now
+func (c *Cache[K, V]) touch(key K, seg *indexedlist.List[K, V]) *indexedlist.List[K, V] {
+	if seg != c.probation || c.protectedCap == 0 {
+		seg.MoveToFront(key)
+		return seg
+	}
+	// second use: promote to the protected segment, demoting its least
+	// recently used entry to probation if it's full
+	value, _ := c.probation.Remove(key)
+	c.protected.PushFront(key, value)
+	if c.protected.Size() > uint64(c.protectedCap) {
+		k, v, _ := c.protected.PopBack()
+		c.probation.PushFront(k, v)
+	}
+	return c.protected
+}
+
+// Get returns the value of key, and false if it's not in the cache
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	if c.sketch != nil {
+		c.sketch.increment(key)
+	}
+	seg := c.segment(key)
+	if seg == nil {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	value, _ := c.touch(key, seg).Get(key)
+	return value, true
+}
+
+// Put adds or updates the entry of key. A new entry may evict another one,
+// or be rejected by the TinyLFU admission filter: then Put returns false
+// (with WithTinyLFU the new entries enter the window, so this happens to
+// the entries leaving it, and Put only reports it for a capacity of 1).
+func (c *Cache[K, V]) Put(key K, value V) bool {
+	if c.sketch != nil {
+		c.sketch.increment(key)
+	}
+	if seg := c.segment(key); seg != nil {
+		c.touch(key, seg).PushFront(key, value)
+		return true
+	}
+	if c.windowCap == 0 {
+		return c.admit(key, value)
+	}
+	c.window.PushFront(key, value)
+	if c.window.Size() <= uint64(c.windowCap) {
+		return true
+	}
+	// the entry leaving the window competes for the main area
+	k, v, _ := c.window.PopBack()
+	c.admit(k, v)
+	return true
+}
+
+// admit moves a new entry (or one leaving the window) to the main area,
+// evicting its victim if the area is full. With TinyLFU, the entry is
+// rejected instead unless it's used more often than the victim.
+func (c *Cache[K, V]) admit(key K, value V) bool {
+	if c.probation.Size()+c.protected.Size() < uint64(c.mainCap) {
+		c.probation.PushFront(key, value)
+		return true
+	}
+	victims := c.probation
+	if victims.IsEmpty() {
+		victims = c.protected
+	}
+	victim, _, _ := victims.Back()
+	if c.sketch != nil && c.sketch.estimate(key) <= c.sketch.estimate(victim) {
+		c.stats.Rejections++
+		return false
+	}
+	victims.PopBack()
+	c.stats.Evictions++
+	c.probation.PushFront(key, value)
+	return true
+}
+
+// Remove removes key from the cache and returns its value
+func (c *Cache[K, V]) Remove(key K) (V, bool) {
+	if seg := c.segment(key); seg != nil {
+		return seg.Remove(key)
+	}
+	var zero V
+	return zero, false
+}
+
+// Clear removes all the entries (the counters and the frequency sketch are
+// kept)
+func (c *Cache[K, V]) Clear() {
+	c.window.Clear()
+	c.probation.Clear()
+	c.protected.Clear()
+}
+
+// All returns an iterator over the entries, from the most protected ones
+// to the first candidates for eviction. The cache must not be changed during
+// the iteration.
+func (c *Cache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, seg := range []*indexedlist.List[K, V]{c.protected, c.window, c.probation} {
+			for k, v := range seg.All() {
+				if !yield(k, v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys (in the order of All).
+func (c *Cache[K, V]) Keys() iter.Seq[K] {
+	return gods.Keys(c.All())
+}
+
+// Values returns an iterator over the values (in the order of All).
+func (c *Cache[K, V]) Values() iter.Seq[V] {
+	return gods.Values(c.All())
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache_test
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+
+	cache "github.com/pzaino/gods/pkg/cache"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+func TestLRU(t *testing.T) {
+	c, err := cache.New[string, int](2)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Get("a")
+	c.Put("c", 3) // evicts b, the least recently used
+	if c.Contains("b") || !c.Contains("a") || !c.Contains("c") {
+		t.Errorf(errExpectedX, "a and c", c.Size())
+	}
+	c.Put("a", 10)
+	if v, ok := c.Get("a"); !ok || v != 10 {
+		t.Errorf(errExpectedX, 10, v)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("expected b to be evicted")
+	}
+	if st := c.Stats(); st.Hits != 2 || st.Misses != 1 || st.Evictions != 1 {
+		t.Errorf(errExpectedX, "2 hits, 1 miss, 1 eviction", st)
+	}
+	if v, ok := c.Remove("a"); !ok || v != 10 || c.Size() != 1 {
+		t.Errorf(errExpectedX, 10, v)
+	}
+	c.Clear()
+	if c.Size() != 0 || c.Capacity() != 2 {
+		t.Errorf(errExpectedX, 0, c.Size())
+	}
+	if _, err := cache.New[int, int](0); err == nil || err.Error() != cache.ErrInvalidCapacity {
+		t.Errorf(errExpectedX, cache.ErrInvalidCapacity, err)
+	}
+}
+
+func TestSLRUScanResistance(t *testing.T) {
+	c, _ := cache.New[int, int](10, cache.WithSLRU())
+	// a working set used twice is protected...
+	for round := 0; round < 2; round++ {
+		for k := 0; k < 5; k++ {
+			if round == 0 {
+				c.Put(k, k)
+			} else {
+				c.Get(k)
+			}
+		}
+	}
+	// ...from a scan of keys used once
+	for k := 100; k < 200; k++ {
+		c.Put(k, k)
+	}
+	for k := 0; k < 5; k++ {
+		if !c.Contains(k) {
+			t.Errorf("expected %d to survive the scan", k)
+		}
+	}
+	if c.Size() != 10 {
+		t.Errorf(errExpectedX, 10, c.Size())
+	}
+	n := 0
+	for range c.All() {
+		n++
+	}
+	if n != 10 {
+		t.Errorf(errExpectedX, 10, n)
+	}
+	keys, values := slices.Collect(c.Keys()), slices.Collect(c.Values())
+	if len(keys) != 10 || len(values) != 10 {
+		t.Errorf(errExpectedX, 10, len(keys))
+	}
+	for i, k := range keys {
+		if values[i] != k {
+			t.Errorf(errExpectedX, k, values[i])
+		}
+	}
+}
+
+func TestTinyLFURejectsOneHitWonders(t *testing.T) {
+	c, _ := cache.New[string, int](100, cache.WithTinyLFU())
+	for round := 0; round < 5; round++ {
+		for k := 0; k < 100; k++ {
+			key := fmt.Sprint("hot", k)
+			if _, ok := c.Get(key); !ok {
+				c.Put(key, k)
+			}
+		}
+	}
+	for k := 0; k < 1000; k++ {
+		c.Put(fmt.Sprint("cold", k), k)
+	}
+	hot := 0
+	for k := 0; k < 100; k++ {
+		if c.Contains(fmt.Sprint("hot", k)) {
+			hot++
+		}
+	}
+	// an LRU cache would keep none of them; the sketch is approximate, so
+	// allow for a few collisions
+	if hot < 95 {
+		t.Errorf(errExpectedX, "at least 95 hot keys", hot)
+	}
+	if st := c.Stats(); st.Rejections == 0 || c.Size() != 100 {
+		t.Errorf(errExpectedX, "rejections", st)
+	}
+}
+
+// hitRatio runs a Zipfian workload on a cache of the given policy
+func hitRatio(t *testing.T, opts ...cache.Option) float64 {
+	c, err := cache.New[uint64, uint64](500, opts...)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	zipf := rand.NewZipf(rand.New(rand.NewSource(42)), 1.01, 1, 100000)
+	for i := 0; i < 200000; i++ {
+		k := zipf.Uint64()
+		if _, ok := c.Get(k); !ok {
+			c.Put(k, k)
+		}
+	}
+	st := c.Stats()
+	return float64(st.Hits) / float64(st.Hits+st.Misses)
+}
+
+func TestTinyLFUZipf(t *testing.T) {
+	lru, tiny := hitRatio(t), hitRatio(t, cache.WithTinyLFU())
+	t.Logf("hit ratio: LRU %.3f, W-TinyLFU %.3f", lru, tiny)
+	if tiny <= lru {
+		t.Errorf("expected W-TinyLFU (%.3f) to beat LRU (%.3f)", tiny, lru)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math/bits"
+)
+
+const (
+	sketchDepth = 4
+	maxCount    = 15 // 4-bit counters, as in the TinyLFU paper
+)
+
+// sketch estimates the access frequency of the keys: a count-min sketch of
+// small saturating counters, fronted by a doorkeeper (a bloom filter that
+// absorbs the first access of every key, so the one-hit wonders don't
+// pollute the counters). All the counters are halved every sampleSize
+// accesses, so the old popularity fades.
+type sketch[K comparable] struct {
+	counters   [sketchDepth][]uint8
+	doorkeeper []uint64 // bitset
+	mask       uint64
+	additions  int
+	sampleSize int
+	seed       maphash.Seed
+}
+
+// newSketch returns a sketch sized for a cache of the given capacity: 4
+// counters per entry in each row, and about 8 doorkeeper bits for each of
+// the accesses in a sample, so neither saturates before the reset
+func newSketch[K comparable](capacity int) *sketch[K] {
+	width := pow2(max(4*capacity, 64))
+	sampleSize := 10 * capacity
+	s := &sketch[K]{
+		doorkeeper: make([]uint64, pow2(8*sampleSize)/64+1),
+		mask:       width - 1,
+		sampleSize: sampleSize,
+		seed:       maphash.MakeSeed(),
+	}
+	for i := range s.counters {
+		s.counters[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// pow2 returns the smallest power of 2 not less than n
+func pow2(n int) uint64 {
+	return uint64(1) << bits.Len64(uint64(n)-1)
+}
+
+// hash returns the hash of key
+func (s *sketch[K]) hash(key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return maphash.String(s.seed, k)
+	case int:
+		return mix(uint64(k))
+	case int64:
+		return mix(uint64(k))
+	case int32:
+		return mix(uint64(k))
+	case uint:
+		return mix(uint64(k))
+	case uint64:
+		return mix(k)
+	case uint32:
+		return mix(uint64(k))
+	default:
+		return maphash.String(s.seed, fmt.Sprintf("%#v", key))
+	}
+}
+
+// mix scrambles the bits of an integer key (the splitmix64 finalizer)
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// index returns the position of hash h in row i (double hashing)
+func (s *sketch[K]) index(h uint64, i int) uint64 {
+	return (h + uint64(i)*(h>>32|1)) & s.mask
+}
+
+// doorkeeperBits returns the two doorkeeper bits of hash h
+func (s *sketch[K]) doorkeeperBits(h uint64) [2]uint64 {
+	n := uint64(len(s.doorkeeper)) * 64
+	return [2]uint64{h % n, (h >> 32) % n}
+}
+
+// increment records an access to key
+func (s *sketch[K]) increment(key K) {
+	h := s.hash(key)
+	seen := true
+	for _, b := range s.doorkeeperBits(h) {
+		if s.doorkeeper[b/64]&(1<<(b%64)) == 0 {
+			seen = false
+			s.doorkeeper[b/64] |= 1 << (b % 64)
+		}
+	}
+	if seen {
+		// conservative update: only the smallest counters grow
+		least := s.count(h)
+		for i := range s.counters {
+			if c := &s.counters[i][s.index(h, i)]; *c == least && *c < maxCount {
+				*c++
+			}
+		}
+	}
+	if s.additions++; s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+// count returns the smallest counter of hash h
+func (s *sketch[K]) count(h uint64) uint8 {
+	least := uint8(maxCount)
+	for i := range s.counters {
+		least = min(least, s.counters[i][s.index(h, i)])
+	}
+	return least
+}
+
+// estimate returns the estimated number of recent accesses to key
+func (s *sketch[K]) estimate(key K) int {
+	h := s.hash(key)
+	n := int(s.count(h))
+	in := true
+	for _, b := range s.doorkeeperBits(h) {
+		in = in && s.doorkeeper[b/64]&(1<<(b%64)) != 0
+	}
+	if in {
+		n++
+	}
+	return n
+}
+
+// reset halves all the counters and clears the doorkeeper
+func (s *sketch[K]) reset() {
+	for i := range s.counters {
+		for j := range s.counters[i] {
+			s.counters[i][j] /= 2
+		}
+	}
+	clear(s.doorkeeper)
+	s.additions /= 2
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package calendar provides a booking calendar: a set of non-overlapping
+// reserved time intervals, with the queries scheduling services need (can
+// this interval be booked, what is booked in this period, which free slots
+// of at least this length are there). The intervals are half-open, [From,
+// To), so a booking can start exactly when another one ends.
+//
+// The bookings are kept in a skip list ordered by start time, so all the
+// operations take O(log n) (plus the size of their result).
+package calendar
+
+import (
+	"errors"
+	"iter"
+	"time"
+
+	skiplist "github.com/pzaino/gods/pkg/skiplist"
+)
+
+const (
+	ErrInvalidInterval = "the interval must end after it starts"
+	ErrOverlap         = "the interval overlaps a booking"
+)
+
+// Booking is a reserved interval with a value (who or what booked it)
+type Booking[T any] struct {
+	From, To time.Time
+	Value    T
+}
+
+// Slot is a free interval
+type Slot struct {
+	From, To time.Time
+}
+
+// Duration returns the length of the slot
+func (s Slot) Duration() time.Duration {
+	return s.To.Sub(s.From)
+}
+
+// Calendar holds non-overlapping bookings. It is not concurrency-safe.
+type Calendar[T any] struct {
+	bookings *skiplist.SkipList[Booking[T]]
+}
+
+// New creates a new empty Calendar.
+func New[T any]() *Calendar[T] {
+	return &Calendar[T]{bookings: skiplist.New(func(a, b Booking[T]) bool {
+		return a.From.Before(b.From)
+	})}
+}
+
+// key returns the element to look up the bookings by start time
+func key[T any](at time.Time) Booking[T] {
+	return Booking[T]{From: at}
+}
+
+// Size returns the number of bookings
+func (c *Calendar[T]) Size() uint64 {
+	return c.bookings.Size()
+}
+
+// IsEmpty returns true if there are no bookings
+func (c *Calendar[T]) IsEmpty() bool {
+	return c.bookings.IsEmpty()
+}
+
+// CanBook returns true if [from, to) is a valid interval that doesn't
+// overlap any booking
+func (c *Calendar[T]) CanBook(from, to time.Time) bool {
+	if !from.Before(to) {
+		return false
+	}
+	// the last booking starting at or before from must end by from, and the
+	// first one starting at or after from must start at or after to
+	if prev, ok := c.bookings.Floor(key[T](from)); ok && prev.To.After(from) {
+		return false
+	}
+	if next, ok := c.bookings.Ceiling(key[T](from)); ok && next.From.Before(to) {
+		return false
+	}
+	return true
+}
+
+// Book reserves [from, to) for value. It returns ErrInvalidInterval if to
+// isn't after from, and ErrOverlap if the interval overlaps a booking.
+func (c *Calendar[T]) Book(from, to time.Time, value T) error {
+	if !from.Before(to) {
+		return errors.New(ErrInvalidInterval)
+	}
+	if !c.CanBook(from, to) {
+		return errors.New(ErrOverlap)
+	}
+	c.bookings.Insert(Booking[T]{From: from, To: to, Value: value})
+	return nil
+}
+
+// Cancel removes the booking starting at from and returns it, or false if
+// there's no such booking
+func (c *Calendar[T]) Cancel(from time.Time) (Booking[T], bool) {
+	b, ok := c.bookings.Get(key[T](from))
+	if ok {
+		c.bookings.Delete(b)
+	}
+	return b, ok
+}
+
+// At returns the booking covering the instant at, and false if it's free
+func (c *Calendar[T]) At(at time.Time) (Booking[T], bool) {
+	if b, ok := c.bookings.Floor(key[T](at)); ok && b.To.After(at) {
+		return b, true
+	}
+	return Booking[T]{}, false
+}
+
+// Bookings returns the bookings overlapping [from, to), in order
+func (c *Calendar[T]) Bookings(from, to time.Time) []Booking[T] {
+	var result []Booking[T]
+	c.overlapping(from, to, func(b Booking[T]) {
+		result = append(result, b)
+	})
+	return result
+}
+
+// overlapping calls f with the bookings overlapping [from, to), in order
+func (c *Calendar[T]) overlapping(from, to time.Time, f func(Booking[T])) {
+	if !from.Before(to) {
+		return
+	}
+	// a booking starting before from can still cover it
+	if prev, ok := c.bookings.Floor(key[T](from)); ok && prev.From.Before(from) && prev.To.After(from) {
+		f(prev)
+	}
+	for b := range c.bookings.Seek(key[T](from)) {
+		if !b.From.Before(to) {
+			break
+		}
+		f(b)
+	}
+}
+
+// FreeSlots returns the free intervals within [from, to) lasting at least
+// minDuration (or any free interval if minDuration <= 0), in order
+func (c *Calendar[T]) FreeSlots(from, to time.Time, minDuration time.Duration) []Slot {
+	var result []Slot
+	add := func(s Slot) {
+		if s.Duration() > 0 && s.Duration() >= minDuration {
+			result = append(result, s)
+		}
+	}
+	cursor := from
+	c.overlapping(from, to, func(b Booking[T]) {
+		if b.From.After(cursor) {
+			add(Slot{From: cursor, To: b.From})
+		}
+		if b.To.After(cursor) {
+			cursor = b.To
+		}
+	})
+	if to.After(cursor) {
+		add(Slot{From: cursor, To: to})
+	}
+	return result
+}
+
+// All returns an iterator over the bookings, in order
+func (c *Calendar[T]) All() iter.Seq[Booking[T]] {
+	return c.bookings.All()
+}
+
+// ToSlice returns the bookings, in order
+func (c *Calendar[T]) ToSlice() []Booking[T] {
+	return c.bookings.ToSlice()
+}
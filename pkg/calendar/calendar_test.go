@@ -0,0 +1,115 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calendar_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	calendar "github.com/pzaino/gods/pkg/calendar"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+var day = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+// h returns the time at the given hour of day
+func h(hour float64) time.Time {
+	return day.Add(time.Duration(hour * float64(time.Hour)))
+}
+
+func TestCalendarBook(t *testing.T) {
+	c := calendar.New[string]()
+	if err := c.Book(h(9), h(10), "standup"); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if err := c.Book(h(13), h(15), "review"); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	for _, tc := range []struct {
+		from, to float64
+		ok       bool
+	}{
+		{8, 9, true},      // ends when a booking starts
+		{10, 13, true},    // between two bookings
+		{8, 9.5, false},   // overlaps the start of a booking
+		{9.5, 11, false},  // overlaps the end of a booking
+		{9, 10, false},    // same interval
+		{9.2, 9.8, false}, // inside a booking
+		{8, 16, false},    // covers bookings
+		{11, 11, false},   // empty
+		{12, 11, false},   // reversed
+	} {
+		if got := c.CanBook(h(tc.from), h(tc.to)); got != tc.ok {
+			t.Errorf("[%v, %v): "+errExpectedX, tc.from, tc.to, tc.ok, got)
+		}
+	}
+	if err := c.Book(h(9.5), h(11), "x"); err == nil || err.Error() != calendar.ErrOverlap {
+		t.Errorf(errExpectedX, calendar.ErrOverlap, err)
+	}
+	if err := c.Book(h(11), h(11), "x"); err == nil || err.Error() != calendar.ErrInvalidInterval {
+		t.Errorf(errExpectedX, calendar.ErrInvalidInterval, err)
+	}
+	if b, ok := c.At(h(14)); !ok || b.Value != "review" {
+		t.Errorf(errExpectedX, "review", b.Value)
+	}
+	if _, ok := c.At(h(15)); ok {
+		t.Errorf("expected 15:00 to be free")
+	}
+	if b, ok := c.Cancel(h(9)); !ok || b.Value != "standup" || c.Size() != 1 {
+		t.Errorf(errExpectedX, "standup", b.Value)
+	}
+	if _, ok := c.Cancel(h(9)); ok {
+		t.Errorf("expected the booking to be already cancelled")
+	}
+	if !c.CanBook(h(9), h(10)) {
+		t.Errorf("expected the cancelled interval to be free")
+	}
+}
+
+func TestCalendarFreeSlots(t *testing.T) {
+	c := calendar.New[int]()
+	for i, b := range [][2]float64{{9, 10}, {10, 10.5}, {11, 12}, {14, 17}} {
+		if err := c.Book(h(b[0]), h(b[1]), i); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+	}
+	slots := c.FreeSlots(h(9.5), h(16), 0)
+	want := []calendar.Slot{{From: h(10.5), To: h(11)}, {From: h(12), To: h(14)}}
+	if !slices.Equal(slots, want) {
+		t.Errorf(errExpectedX, want, slots)
+	}
+	slots = c.FreeSlots(h(8), h(18), time.Hour)
+	want = []calendar.Slot{{From: h(8), To: h(9)}, {From: h(12), To: h(14)}, {From: h(17), To: h(18)}}
+	if !slices.Equal(slots, want) {
+		t.Errorf(errExpectedX, want, slots)
+	}
+	if got := c.Bookings(h(10.2), h(11.5)); len(got) != 2 || got[0].Value != 1 || got[1].Value != 2 {
+		t.Errorf(errExpectedX, "bookings 1 and 2", got)
+	}
+	if got := len(c.ToSlice()); got != 4 {
+		t.Errorf(errExpectedX, 4, got)
+	}
+	if got := slices.Collect(c.All()); !slices.Equal(got, c.ToSlice()) {
+		t.Errorf(errExpectedX, c.ToSlice(), got)
+	}
+	if slots := calendar.New[int]().FreeSlots(h(1), h(2), 0); len(slots) != 1 || slots[0].Duration() != time.Hour {
+		t.Errorf(errExpectedX, "one hour", slots)
+	}
+}
@@ -16,7 +16,16 @@
 package circularLinkList
 
 import (
+	"database/sql/driver"
 	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 const (
@@ -195,6 +204,63 @@ func (l *CircularLinkList[T]) Size() uint64 {
 	return l.size
 }
 
+// MemoryUsage returns an estimate of the bytes held by the list and its nodes
+// (see the gods package), adding the memory returned by extra for every item.
+func (l *CircularLinkList[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
+	return uint64(unsafe.Sizeof(*l)) + gods.NodesMemory[Node[T]](l.size) + gods.ExtraMemory(l.Iter(), extra...)
+}
+
+// Validate checks the invariants of the list: size nodes from the head to the
+// tail, which links back to the head (see gods.Validator).
+func (l *CircularLinkList[T]) Validate() error {
+	if l.Head == nil || l.Tail == nil {
+		if l.Head != l.Tail || l.size != 0 {
+			return gods.Invariantf("head and tail must both be nil in an empty list (size %d)", l.size)
+		}
+		return nil
+	}
+	n := l.Head
+	for i := uint64(1); i < l.size; i++ {
+		if n == l.Tail || n.Next == nil {
+			return gods.Invariantf("size is %d but the tail is node %d", l.size, i)
+		}
+		n = n.Next
+	}
+	if n != l.Tail {
+		return gods.Invariantf("size is %d but node %d is not the tail", l.size, l.size)
+	}
+	if l.Tail.Next != l.Head {
+		return gods.Invariantf("the tail does not link back to the head")
+	}
+	return nil
+}
+
+// Dump writes the ring of the list to w, from the head, for debugging.
+func (l *CircularLinkList[T]) Dump(w io.Writer) error {
+	d := gods.NewDumper(w)
+	d.Line(0, "CircularLinkList size=%d", l.size)
+	if l.Head == nil {
+		d.Line(1, "head -> nil")
+		return d.Err()
+	}
+	var chain []string
+	n := l.Head
+	for i := uint64(0); n != nil && i < l.size; i++ {
+		v := fmt.Sprint(n.Value)
+		if n == l.Tail {
+			v += " (tail)"
+		}
+		chain = append(chain, v)
+		n = n.Next
+	}
+	end := "(head)"
+	if n != l.Head {
+		end = "(not the head!)"
+	}
+	d.Line(1, "head -> %s", strings.Join(append(chain, end), " -> "))
+	return d.Err()
+}
+
 // CheckSize recalculate the size of the list
 func (l *CircularLinkList[T]) CheckSize() {
 	size := uint64(0)
@@ -694,3 +760,184 @@ func (l *CircularLinkList[T]) ReduceRange(start, end uint64, f func(T, T) T) (T,
 
 	return result, nil
 }
+
+// Any checks if any node in the list matches the predicate
+func (l *CircularLinkList[T]) Any(f func(T) bool) bool {
+	for value := range l.Iter() {
+		if f(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Iter returns an iterator over the values in the list, from the head to the tail
+// (each node is visited only once)
+func (l *CircularLinkList[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if l.Head == nil {
+			return
+		}
+
+		current := l.Head
+		for {
+			if !yield(current.Value) {
+				return
+			}
+			current = current.Next
+			if current == l.Head {
+				break
+			}
+		}
+	}
+}
+
+// Iterator returns an iterator over the values in the list, from the head to the tail
+// (each node is visited only once)
+func (l *CircularLinkList[T]) Iterator() iterator.Iterator[T] {
+	current := l.Head
+	return iterator.FromFunc(func() (T, bool) {
+		if current == nil {
+			var zero T
+			return zero, false
+		}
+		value := current.Value
+		current = current.Next
+		if current == l.Head {
+			current = nil
+		}
+		return value, true
+	})
+}
+
+// Enumerate returns an iterator over the index and the value of the nodes in the list
+// (each node is visited only once)
+func (l *CircularLinkList[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		i := uint64(0)
+		for value := range l.Iter() {
+			if !yield(i, value) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (l *CircularLinkList[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(l.Enumerate())
+}
+
+// Values returns an iterator over the items, in the same order as Enumerate
+// (it is Iter, under the name used by the standard library).
+func (l *CircularLinkList[T]) Values() iter.Seq[T] {
+	return l.Iter()
+}
+
+// Encode writes the list to w using the given codec
+func (l *CircularLinkList[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeSlice(w, codec, l.ToSlice())
+}
+
+// Decode replaces the content of the list with the elements read from r using the given codec
+func (l *CircularLinkList[T]) Decode(r io.Reader, codec gods.Codec) error {
+	items, err := gods.DecodeSlice[T](r, codec)
+	if err != nil {
+		return err
+	}
+	l.Clear()
+	for _, item := range items {
+		l.Append(item)
+	}
+	return nil
+}
+
+// EncodeStream writes the list to w incrementally, in chunks encoded with the
+// given codec (see gods.EncodeStream), without copying its elements first.
+func (l *CircularLinkList[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeStream(w, codec, l.Iter())
+}
+
+// DecodeStream replaces the content of the list with the elements of a
+// stream written by EncodeStream, adding them as they are read.
+func (l *CircularLinkList[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	l.Clear()
+	return gods.DecodeStream(r, codec, func(item T) error {
+		l.Append(item)
+		return nil
+	})
+}
+
+// MarshalText implements encoding.TextMarshaler: the list is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (l *CircularLinkList[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(l)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (l *CircularLinkList[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(l, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (l *CircularLinkList[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(l)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (l *CircularLinkList[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(l, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (l *CircularLinkList[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(l)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (l *CircularLinkList[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(l, data)
+}
+
+// MarshalYAML implements the Marshaler interface of the YAML packages
+// (gopkg.in/yaml.v2 and v3): the list is encoded as a sequence, in order.
+func (l *CircularLinkList[T]) MarshalYAML() (any, error) {
+	return l.ToSlice(), nil
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of gopkg.in/yaml.v2
+// (also honored by v3): the content of the list is replaced with the elements
+// of a YAML sequence.
+func (l *CircularLinkList[T]) UnmarshalYAML(unmarshal func(any) error) error {
+	var items []T
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+	l.Clear()
+	for _, item := range items {
+		l.Append(item)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer: the list is stored as a JSON array, so it
+// can be saved in a JSON (or JSONB) column.
+func (l *CircularLinkList[T]) Value() (driver.Value, error) {
+	return gods.ValueSlice(l.ToSlice())
+}
+
+// Scan implements sql.Scanner: the content of the list is replaced with the
+// elements of the JSON array read from the database (NULL is an empty list).
+func (l *CircularLinkList[T]) Scan(src any) error {
+	items, err := gods.ScanSlice[T](src)
+	if err != nil {
+		return err
+	}
+	l.Clear()
+	for _, item := range items {
+		l.Append(item)
+	}
+	return nil
+}
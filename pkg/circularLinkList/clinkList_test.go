@@ -16,10 +16,13 @@
 package circularLinkList_test
 
 import (
+	"encoding/json"
 	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/pzaino/gods/pkg/circularLinkList" // Adjust the import path as necessary
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 const (
@@ -686,3 +689,76 @@ func TestCheckSize(t *testing.T) {
 		t.Fatalf(errExpectedLength, expectedSize, actualSize)
 	}
 }
+
+func TestIterAndAny(t *testing.T) {
+	list := circularLinkList.NewFromSlice([]int{1, 2, 3})
+	if got := slices.Collect(list.Iter()); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, got)
+	}
+	if !list.Any(func(v int) bool { return v == 3 }) {
+		t.Error("Expected Any to return true")
+	}
+	if list.Any(func(v int) bool { return v == 4 }) {
+		t.Error("Expected Any to return false")
+	}
+	if circularLinkList.New[int]().Any(func(int) bool { return true }) {
+		t.Error("Expected Any to return false on an empty list")
+	}
+}
+
+func TestIterator(t *testing.T) {
+	list := circularLinkList.NewFromSlice([]int{1, 2, 3})
+	if got := iterator.ToSlice(list.Iterator()); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, got)
+	}
+	if circularLinkList.New[int]().Iterator().Next() {
+		t.Error("Expected empty iteration")
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	list := circularLinkList.NewFromSlice([]int{1, 2, 3})
+	var indexes []uint64
+	var values []int
+	for i, v := range list.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0, 1, 2}) {
+		t.Errorf("Expected %v, got %v", []uint64{0, 1, 2}, indexes)
+	}
+	if !slices.Equal(values, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, values)
+	}
+	if got := slices.Collect(list.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+	if got := slices.Collect(list.Values()); !slices.Equal(got, values) {
+		t.Errorf("Expected %v, got %v", values, got)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	list := circularLinkList.New[int]()
+	list.Append(3)
+	list.Append(1)
+	v, err := list.MarshalYAML()
+	if err != nil {
+		t.Fatalf(errExpectedNoErr, err)
+	}
+	if items, ok := v.([]int); !ok || !slices.Equal(items, []int{3, 1}) {
+		t.Errorf("expected %v, got %v", []int{3, 1}, v)
+	}
+
+	// the YAML packages call UnmarshalYAML with a function decoding the
+	// node into the given value
+	unmarshal := func(v any) error {
+		return json.Unmarshal([]byte("[1, 2, 3]"), v)
+	}
+	if err := list.UnmarshalYAML(unmarshal); err != nil {
+		t.Fatalf(errExpectedNoErr, err)
+	}
+	if got := list.ToSlice(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("expected %v, got %v", []int{1, 2, 3}, got)
+	}
+}
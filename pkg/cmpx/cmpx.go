@@ -0,0 +1,129 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmpx provides reusable and composable ordering functions.
+//
+// A Less can be passed wherever the library expects a "less" function (for
+// example the Sort methods of the lists), so comparators can be written
+// once and composed:
+//
+//	byAge := cmpx.ByKey(func(p Person) int { return p.Age })
+//	byName := cmpx.ByKey(func(p Person) string { return p.Name })
+//	list.Sort(byAge.Reverse().Then(byName))
+package cmpx
+
+import "cmp"
+
+// Less reports whether a must be ordered before b.
+type Less[T any] func(a, b T) bool
+
+// NaturalOrder returns a Less that orders the values using the < operator.
+// Like cmp.Less, NaN values are ordered before any other float value.
+func NaturalOrder[T cmp.Ordered]() Less[T] {
+	return cmp.Less[T]
+}
+
+// ByKey returns a Less that orders the values by the natural order of the key
+// extracted by the key function.
+func ByKey[T any, K cmp.Ordered](key func(T) K) Less[T] {
+	return func(a, b T) bool {
+		return cmp.Less(key(a), key(b))
+	}
+}
+
+// ByKeyWith returns a Less that orders the values by the key extracted by the
+// key function, using less to compare the keys.
+func ByKeyWith[T any, K any](key func(T) K, less Less[K]) Less[T] {
+	return func(a, b T) bool {
+		return less(key(a), key(b))
+	}
+}
+
+// FromCompare returns a Less from a three-way comparison function (like the
+// ones used by slices.SortFunc).
+func FromCompare[T any](compare func(a, b T) int) Less[T] {
+	return func(a, b T) bool {
+		return compare(a, b) < 0
+	}
+}
+
+// Chain returns a Less that compares the values lexicographically: the first
+// Less decides, unless it considers the two values equal, in that case the
+// next one is used and so on. Chain with no arguments considers all the
+// values equal.
+func Chain[T any](less ...Less[T]) Less[T] {
+	return func(a, b T) bool {
+		for _, l := range less {
+			if l(a, b) {
+				return true
+			}
+			if l(b, a) {
+				return false
+			}
+		}
+		return false
+	}
+}
+
+// Reverse returns a Less with the opposite order.
+func (l Less[T]) Reverse() Less[T] {
+	return func(a, b T) bool {
+		return l(b, a)
+	}
+}
+
+// Then returns a Less that uses next to order the values that l considers equal.
+func (l Less[T]) Then(next Less[T]) Less[T] {
+	return Chain(l, next)
+}
+
+// Compare is a three-way comparison based on l: it returns -1 if a is ordered
+// before b, +1 if b is ordered before a and 0 otherwise. The method value
+// (e.g. less.Compare) can be passed to slices.SortFunc.
+func (l Less[T]) Compare(a, b T) int {
+	if l(a, b) {
+		return -1
+	}
+	if l(b, a) {
+		return 1
+	}
+	return 0
+}
+
+// Equal reports whether a and b are equivalent for l (neither is ordered before the other).
+func (l Less[T]) Equal(a, b T) bool {
+	return !l(a, b) && !l(b, a)
+}
+
+// Min returns the smallest of the given values according to l.
+// It returns the zero value and false if no values are given.
+func (l Less[T]) Min(values ...T) (T, bool) {
+	var result T
+	if len(values) == 0 {
+		return result, false
+	}
+	result = values[0]
+	for _, v := range values[1:] {
+		if l(v, result) {
+			result = v
+		}
+	}
+	return result, true
+}
+
+// Max returns the largest of the given values according to l.
+// It returns the zero value and false if no values are given.
+func (l Less[T]) Max(values ...T) (T, bool) {
+	return l.Reverse().Min(values...)
+}
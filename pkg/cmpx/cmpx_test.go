@@ -0,0 +1,111 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmpx provides reusable and composable ordering functions.
+package cmpx_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+	dlinkList "github.com/pzaino/gods/pkg/dlinkList"
+)
+
+const (
+	errExpectedX = "Expected %v, but got %v"
+)
+
+type person struct {
+	Name string
+	Age  int
+}
+
+var (
+	byAge  = cmpx.ByKey(func(p person) int { return p.Age })
+	byName = cmpx.ByKey(func(p person) string { return p.Name })
+)
+
+func TestNaturalOrderAndReverse(t *testing.T) {
+	less := cmpx.NaturalOrder[int]()
+	if !less(1, 2) || less(2, 1) || less(2, 2) {
+		t.Errorf(errExpectedX, "1 < 2", "a different order")
+	}
+	rev := less.Reverse()
+	if rev(1, 2) || !rev(2, 1) {
+		t.Errorf(errExpectedX, "2 before 1", "a different order")
+	}
+}
+
+func TestByKeyWith(t *testing.T) {
+	caseInsensitive := cmpx.ByKeyWith(strings.ToLower, cmpx.NaturalOrder[string]())
+	if !caseInsensitive("apple", "Banana") {
+		t.Errorf(errExpectedX, true, false)
+	}
+	if !caseInsensitive.Equal("ABC", "abc") {
+		t.Errorf(errExpectedX, true, false)
+	}
+}
+
+func TestChainAndThen(t *testing.T) {
+	people := []person{{"Bob", 30}, {"Alice", 30}, {"Carl", 25}}
+
+	slices.SortFunc(people, byAge.Reverse().Then(byName).Compare)
+	expected := []person{{"Alice", 30}, {"Bob", 30}, {"Carl", 25}}
+	if !slices.Equal(people, expected) {
+		t.Errorf(errExpectedX, expected, people)
+	}
+
+	if cmpx.Chain[person]()(people[0], people[1]) {
+		t.Errorf("Expected an empty chain to consider all values equal")
+	}
+}
+
+func TestFromCompare(t *testing.T) {
+	less := cmpx.FromCompare(strings.Compare)
+	if !less("a", "b") || less("b", "a") {
+		t.Errorf(errExpectedX, "a < b", "a different order")
+	}
+	if less.Compare("b", "a") != 1 || less.Compare("a", "a") != 0 {
+		t.Errorf("Unexpected Compare result")
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	less := cmpx.NaturalOrder[int]()
+	if v, ok := less.Min(3, 1, 2); !ok || v != 1 {
+		t.Errorf(errExpectedX, 1, v)
+	}
+	if v, ok := less.Max(3, 1, 2); !ok || v != 3 {
+		t.Errorf(errExpectedX, 3, v)
+	}
+	if _, ok := less.Min(); ok {
+		t.Errorf(errExpectedX, false, ok)
+	}
+}
+
+func TestWithListSort(t *testing.T) {
+	list := dlinkList.New[person]()
+	list.Append(person{"Bob", 30})
+	list.Append(person{"Carl", 25})
+	list.Append(person{"Alice", 30})
+
+	list.Sort(byAge.Then(byName))
+	got := slices.Collect(list.Iter())
+	expected := []person{{"Carl", 25}, {"Alice", 30}, {"Bob", 30}}
+	if !slices.Equal(got, expected) {
+		t.Errorf(errExpectedX, expected, got)
+	}
+}
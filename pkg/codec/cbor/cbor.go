@@ -0,0 +1,791 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cbor provides a gods.Codec using CBOR (RFC 8949), the binary format
+// used by many IoT protocols and by COSE. Containers are encoded as arrays, in
+// the order used by their Encode method:
+//
+//	var buf bytes.Buffer
+//	err := myQueue.Encode(&buf, cbor.Codec{Deterministic: true})
+//
+// Integers always use the shortest form. Structs are encoded as maps keyed by
+// field name (or by the name in the `cbor:"name"` tag; fields tagged with "-"
+// and unexported fields are skipped).
+//
+// In deterministic mode the encoding follows the core deterministic encoding
+// requirements of RFC 8949 (section 4.2.1): map keys are sorted by the bytes
+// of their encoding and floats use the shortest form that preserves their
+// value, so equal values always produce the same bytes (as needed, for
+// example, to sign them).
+//
+// The decoder also accepts indefinite-length items and ignores tags (the
+// tagged content is decoded as if it wasn't tagged).
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+
+	gods "github.com/pzaino/gods"
+)
+
+const (
+	ErrUnsupportedType = "cbor: unsupported type"
+	ErrInvalidData     = "cbor: invalid data"
+	ErrTypeMismatch    = "cbor: value doesn't match the destination type"
+)
+
+// major types
+const (
+	majorUint   byte = 0
+	majorNegInt byte = 1
+	majorBytes  byte = 2
+	majorText   byte = 3
+	majorArray  byte = 4
+	majorMap    byte = 5
+	majorTag    byte = 6
+	majorSimple byte = 7
+)
+
+// simple values and special additional information
+const (
+	simpleFalse byte = 20
+	simpleTrue  byte = 21
+	simpleNull  byte = 22
+	simpleUndef byte = 23
+	infoFloat16 byte = 25
+	infoFloat32 byte = 26
+	infoFloat64 byte = 27
+	infoIndef   byte = 31
+	breakCode   byte = 0xff
+)
+
+// Codec is a gods.Codec using the CBOR format.
+type Codec struct {
+	// Deterministic enables the deterministic encoding
+	Deterministic bool
+}
+
+// Encode writes v to w in CBOR format
+func (c Codec) Encode(w io.Writer, v any) error {
+	b, err := (&encoder{deterministic: c.Deterministic}).appendValue(nil, reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// Decode reads a CBOR data item from r into v (a pointer). It reads only the
+// bytes of one item.
+func (Codec) Decode(r io.Reader, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New(gods.ErrInvalidDecodeDest)
+	}
+	d := &decoder{r: r}
+	if br, ok := r.(io.ByteReader); ok {
+		d.br = br
+	}
+	return d.decode(rv.Elem())
+}
+
+// Marshal returns the CBOR encoding of v.
+func Marshal(v any) ([]byte, error) {
+	return (&encoder{}).appendValue(nil, reflect.ValueOf(v))
+}
+
+// MarshalDeterministic returns the deterministic CBOR encoding of v.
+func MarshalDeterministic(v any) ([]byte, error) {
+	return (&encoder{deterministic: true}).appendValue(nil, reflect.ValueOf(v))
+}
+
+// Unmarshal decodes the CBOR data item in data into v (a pointer).
+func Unmarshal(data []byte, v any) error {
+	return Codec{}.Decode(bytes.NewReader(data), v)
+}
+
+// encoding
+
+type encoder struct {
+	deterministic bool
+}
+
+// appendHead appends the head of a data item: its major type and argument
+// n, in the shortest form
+func appendHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), n)
+}
+
+func appendInt(b []byte, n int64) []byte {
+	if n >= 0 {
+		return appendHead(b, majorUint, uint64(n))
+	}
+	return appendHead(b, majorNegInt, uint64(-1-n))
+}
+
+func appendText(b []byte, s string) []byte {
+	return append(appendHead(b, majorText, uint64(len(s))), s...)
+}
+
+// appendFloat appends f as a float64, or in the shortest form that
+// preserves its value if shortest is set
+func appendFloat(b []byte, f float64, shortest bool) []byte {
+	simple := majorSimple << 5
+	if shortest {
+		if math.IsNaN(f) {
+			// canonical NaN
+			return append(b, simple|infoFloat16, 0x7e, 0x00)
+		}
+		if h, ok := toFloat16(f); ok {
+			return binary.BigEndian.AppendUint16(append(b, simple|infoFloat16), h)
+		}
+		if float64(float32(f)) == f {
+			return binary.BigEndian.AppendUint32(append(b, simple|infoFloat32), math.Float32bits(float32(f)))
+		}
+	}
+	return binary.BigEndian.AppendUint64(append(b, simple|infoFloat64), math.Float64bits(f))
+}
+
+// toFloat16 returns the half precision representation of f, if f can be
+// represented exactly
+func toFloat16(f float64) (uint16, bool) {
+	if float64(float32(f)) != f {
+		return 0, false
+	}
+	bits := math.Float32bits(float32(f))
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23&0xff) - 127
+	mant := bits & 0x7fffff
+	var h uint16
+	switch {
+	case f == 0:
+		h = sign
+	case math.IsInf(f, 0):
+		h = sign | 0x7c00
+	case exp >= -14 && exp <= 15:
+		if mant&0x1fff != 0 {
+			return 0, false
+		}
+		h = sign | uint16(exp+15)<<10 | uint16(mant>>13)
+	case exp >= -24 && exp < -14:
+		// subnormal: f = m * 2^-24
+		shift := uint(-(exp + 1))
+		full := mant | 0x800000
+		if full&(1<<shift-1) != 0 {
+			return 0, false
+		}
+		h = sign | uint16(full>>shift)
+	default:
+		return 0, false
+	}
+	return h, fromFloat16(h) == f
+}
+
+// fromFloat16 converts a half precision float
+func fromFloat16(h uint16) float64 {
+	exp := int(h >> 10 & 0x1f)
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant != 0 {
+			return math.NaN()
+		}
+		f = math.Inf(1)
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
+
+// fieldName returns the name of a struct field in the encoding (or "" if
+// the field must be skipped)
+func fieldName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	tag := f.Tag.Get("cbor")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return f.Name
+}
+
+// appendMap appends a map header and its (already encoded) entries, sorted
+// by the bytes of their keys in deterministic mode
+func (e *encoder) appendMap(b []byte, keys, values [][]byte) []byte {
+	b = appendHead(b, majorMap, uint64(len(keys)))
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	if e.deterministic {
+		slices.SortFunc(order, func(i, j int) int { return bytes.Compare(keys[i], keys[j]) })
+	}
+	for _, i := range order {
+		b = append(append(b, keys[i]...), values[i]...)
+	}
+	return b
+}
+
+func (e *encoder) appendValue(b []byte, rv reflect.Value) ([]byte, error) {
+	null := majorSimple<<5 | simpleNull
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return append(b, null), nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return append(b, majorSimple<<5|simpleTrue), nil
+		}
+		return append(b, majorSimple<<5|simpleFalse), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendInt(b, rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendHead(b, majorUint, rv.Uint()), nil
+	case reflect.Float32:
+		if !e.deterministic {
+			return binary.BigEndian.AppendUint32(append(b, majorSimple<<5|infoFloat32), math.Float32bits(float32(rv.Float()))), nil
+		}
+		return appendFloat(b, rv.Float(), true), nil
+	case reflect.Float64:
+		return appendFloat(b, rv.Float(), e.deterministic), nil
+	case reflect.String:
+		return appendText(b, rv.String()), nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return append(b, null), nil
+		}
+		return e.appendValue(b, rv.Elem())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return append(b, null), nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(data), rv)
+			return append(appendHead(b, majorBytes, uint64(len(data))), data...), nil
+		}
+		b = appendHead(b, majorArray, uint64(rv.Len()))
+		for i := 0; i < rv.Len(); i++ {
+			var err error
+			if b, err = e.appendValue(b, rv.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return append(b, null), nil
+		}
+		var keys, values [][]byte
+		iter := rv.MapRange()
+		for iter.Next() {
+			k, err := e.appendValue(nil, iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			v, err := e.appendValue(nil, iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			keys, values = append(keys, k), append(values, v)
+		}
+		return e.appendMap(b, keys, values), nil
+	case reflect.Struct:
+		var keys, values [][]byte
+		for i := 0; i < rv.NumField(); i++ {
+			name := fieldName(rv.Type().Field(i))
+			if name == "" {
+				continue
+			}
+			v, err := e.appendValue(nil, rv.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			keys, values = append(keys, appendText(nil, name)), append(values, v)
+		}
+		return e.appendMap(b, keys, values), nil
+	}
+	return nil, fmt.Errorf("%s: %s", ErrUnsupportedType, rv.Type())
+}
+
+// decoding
+
+// decoder reads exactly the bytes of one data item from r
+type decoder struct {
+	r   io.Reader
+	br  io.ByteReader // r, if it implements io.ByteReader
+	buf [8]byte
+}
+
+func (d *decoder) readByte() (byte, error) {
+	if d.br != nil {
+		return d.br.ReadByte()
+	}
+	_, err := io.ReadFull(d.r, d.buf[:1])
+	return d.buf[0], err
+}
+
+// readUint reads a big endian unsigned integer of size bytes
+func (d *decoder) readUint(size int) (uint64, error) {
+	if _, err := io.ReadFull(d.r, d.buf[:size]); err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range d.buf[:size] {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+func (d *decoder) readBytes(n uint64) ([]byte, error) {
+	// read in chunks, so a corrupted length can't make us allocate a huge
+	// buffer upfront
+	data := []byte{}
+	for n > 0 {
+		chunk := min(n, 64*1024)
+		start := len(data)
+		data = append(data, make([]byte, chunk)...)
+		if _, err := io.ReadFull(d.r, data[start:]); err != nil {
+			return nil, err
+		}
+		n -= chunk
+	}
+	return data, nil
+}
+
+// kind of a decoded head
+type kind int
+
+const (
+	kindNil kind = iota
+	kindBool
+	kindInt
+	kindUint
+	kindFloat
+	kindBytes
+	kindText
+	kindArray
+	kindMap
+	kindBreak
+)
+
+// head is a decoded data item head: scalars are complete, strings, arrays
+// and maps carry their length (unless they are indefinite)
+type head struct {
+	kind  kind
+	i     int64
+	u     uint64
+	f     float64
+	n     uint64 // length
+	indef bool   // indefinite length
+}
+
+// argSizes are the sizes of the arguments following the initial byte
+var argSizes = map[byte]int{24: 1, 25: 2, 26: 4, 27: 8}
+
+func (d *decoder) readHead() (head, error) {
+	for {
+		c, err := d.readByte()
+		if err != nil {
+			return head{}, err
+		}
+		if c == breakCode {
+			return head{kind: kindBreak}, nil
+		}
+		major, info := c>>5, c&0x1f
+		var arg uint64
+		indef := false
+		switch {
+		case info < 24:
+			arg = uint64(info)
+		case info == infoIndef:
+			if major < majorBytes || major > majorMap {
+				return head{}, fmt.Errorf("%s: unexpected indefinite length", ErrInvalidData)
+			}
+			indef = true
+		case argSizes[info] != 0:
+			if arg, err = d.readUint(argSizes[info]); err != nil {
+				return head{}, err
+			}
+		default:
+			return head{}, fmt.Errorf("%s: reserved additional information %d", ErrInvalidData, info)
+		}
+		switch major {
+		case majorUint:
+			return head{kind: kindUint, u: arg}, nil
+		case majorNegInt:
+			if arg > math.MaxInt64 {
+				return head{}, fmt.Errorf("%s: negative integer overflows int64", ErrUnsupportedType)
+			}
+			return head{kind: kindInt, i: -1 - int64(arg)}, nil
+		case majorBytes:
+			return head{kind: kindBytes, n: arg, indef: indef}, nil
+		case majorText:
+			return head{kind: kindText, n: arg, indef: indef}, nil
+		case majorArray:
+			return head{kind: kindArray, n: arg, indef: indef}, nil
+		case majorMap:
+			return head{kind: kindMap, n: arg, indef: indef}, nil
+		case majorTag:
+			// tags are ignored: decode the tagged item
+			continue
+		}
+		switch {
+		case info == simpleFalse || info == simpleTrue:
+			return head{kind: kindBool, u: uint64(info - simpleFalse)}, nil
+		case info == simpleNull || info == simpleUndef:
+			return head{kind: kindNil}, nil
+		case info == infoFloat16:
+			return head{kind: kindFloat, f: fromFloat16(uint16(arg))}, nil
+		case info == infoFloat32:
+			return head{kind: kindFloat, f: float64(math.Float32frombits(uint32(arg)))}, nil
+		case info == infoFloat64:
+			return head{kind: kindFloat, f: math.Float64frombits(arg)}, nil
+		}
+		return head{}, fmt.Errorf("%s: unsupported simple value %d", ErrUnsupportedType, arg)
+	}
+}
+
+// more reports whether there is another element in an array or map with
+// head h; i is the index of the element. For indefinite lengths it reads the
+// head of the next element, that is returned in next.
+func (d *decoder) more(h head, i uint64) (ok bool, next *head, err error) {
+	if !h.indef {
+		return i < h.n, nil, nil
+	}
+	nh, err := d.readHead()
+	if err != nil || nh.kind == kindBreak {
+		return false, nil, err
+	}
+	return true, &nh, nil
+}
+
+// decodeItem decodes the next item, using h as its head if it is not nil
+func (d *decoder) decodeItem(h *head, rv reflect.Value) error {
+	if h == nil {
+		return d.decode(rv)
+	}
+	return d.decodeWithHead(*h, rv)
+}
+
+// readString reads the content of a byte or text string with head h
+func (d *decoder) readString(h head) ([]byte, error) {
+	if !h.indef {
+		return d.readBytes(h.n)
+	}
+	// an indefinite string is a sequence of definite strings of the same type
+	data := []byte{}
+	for {
+		chunk, err := d.readHead()
+		if err != nil {
+			return nil, err
+		}
+		if chunk.kind == kindBreak {
+			return data, nil
+		}
+		if chunk.kind != h.kind || chunk.indef {
+			return nil, fmt.Errorf("%s: invalid indefinite string chunk", ErrInvalidData)
+		}
+		part, err := d.readBytes(chunk.n)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, part...)
+	}
+}
+
+func (d *decoder) decode(rv reflect.Value) error {
+	h, err := d.readHead()
+	if err != nil {
+		return err
+	}
+	return d.decodeWithHead(h, rv)
+}
+
+func mismatch(h head, rv reflect.Value) error {
+	return fmt.Errorf("%s: kind %d into %s", ErrTypeMismatch, h.kind, rv.Type())
+}
+
+func (d *decoder) decodeWithHead(h head, rv reflect.Value) error {
+	switch h.kind {
+	case kindNil:
+		rv.SetZero()
+		return nil
+	case kindBreak:
+		return fmt.Errorf("%s: unexpected break", ErrInvalidData)
+	}
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return d.decodeWithHead(h, rv.Elem())
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return mismatch(h, rv)
+		}
+		v, err := d.decodeAny(h)
+		if err != nil {
+			return err
+		}
+		if v != nil {
+			rv.Set(reflect.ValueOf(v))
+		}
+		return nil
+	case reflect.Bool:
+		if h.kind != kindBool {
+			return mismatch(h, rv)
+		}
+		rv.SetBool(h.u != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch {
+		case h.kind == kindInt:
+			n = h.i
+		case h.kind == kindUint && h.u <= math.MaxInt64:
+			n = int64(h.u)
+		default:
+			return mismatch(h, rv)
+		}
+		if rv.OverflowInt(n) {
+			return mismatch(h, rv)
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if h.kind != kindUint || rv.OverflowUint(h.u) {
+			return mismatch(h, rv)
+		}
+		rv.SetUint(h.u)
+	case reflect.Float32, reflect.Float64:
+		switch h.kind {
+		case kindFloat:
+			rv.SetFloat(h.f)
+		case kindInt:
+			rv.SetFloat(float64(h.i))
+		case kindUint:
+			rv.SetFloat(float64(h.u))
+		default:
+			return mismatch(h, rv)
+		}
+	case reflect.String:
+		if h.kind != kindText && h.kind != kindBytes {
+			return mismatch(h, rv)
+		}
+		data, err := d.readString(h)
+		if err != nil {
+			return err
+		}
+		rv.SetString(string(data))
+	case reflect.Slice, reflect.Array:
+		return d.decodeList(h, rv)
+	case reflect.Map:
+		if h.kind != kindMap {
+			return mismatch(h, rv)
+		}
+		m := reflect.MakeMap(rv.Type())
+		for i := uint64(0); ; i++ {
+			ok, next, err := d.more(h, i)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				break
+			}
+			k, v := reflect.New(rv.Type().Key()).Elem(), reflect.New(rv.Type().Elem()).Elem()
+			if err := d.decodeItem(next, k); err != nil {
+				return err
+			}
+			if err := d.decode(v); err != nil {
+				return err
+			}
+			m.SetMapIndex(k, v)
+		}
+		rv.Set(m)
+	case reflect.Struct:
+		if h.kind != kindMap {
+			return mismatch(h, rv)
+		}
+		fields := make(map[string]int)
+		for i := 0; i < rv.NumField(); i++ {
+			if name := fieldName(rv.Type().Field(i)); name != "" {
+				fields[name] = i
+			}
+		}
+		for i := uint64(0); ; i++ {
+			ok, next, err := d.more(h, i)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				break
+			}
+			var name string
+			if err := d.decodeItem(next, reflect.ValueOf(&name).Elem()); err != nil {
+				return err
+			}
+			if idx, ok := fields[name]; ok {
+				if err := d.decode(rv.Field(idx)); err != nil {
+					return err
+				}
+			} else if _, err := d.decodeNext(); err != nil {
+				// unknown fields are skipped
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%s: %s", ErrUnsupportedType, rv.Type())
+	}
+	return nil
+}
+
+// decodeList decodes an array (or a byte string, for byte slices) into a
+// slice or an array
+func (d *decoder) decodeList(h head, rv reflect.Value) error {
+	isBytes := rv.Type().Elem().Kind() == reflect.Uint8
+	if h.kind == kindBytes && isBytes {
+		data, err := d.readString(h)
+		if err != nil {
+			return err
+		}
+		if rv.Kind() == reflect.Slice {
+			rv.Set(reflect.MakeSlice(rv.Type(), len(data), len(data)))
+		}
+		reflect.Copy(rv, reflect.ValueOf(data))
+		return nil
+	}
+	if h.kind != kindArray {
+		return mismatch(h, rv)
+	}
+	if rv.Kind() == reflect.Slice {
+		rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+	}
+	elem := reflect.New(rv.Type().Elem()).Elem()
+	for i := uint64(0); ; i++ {
+		ok, next, err := d.more(h, i)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		elem.SetZero()
+		if err := d.decodeItem(next, elem); err != nil {
+			return err
+		}
+		if rv.Kind() == reflect.Slice {
+			rv.Set(reflect.Append(rv, elem))
+		} else if i < uint64(rv.Len()) {
+			rv.Index(int(i)).Set(elem)
+		}
+	}
+}
+
+// decodeNext decodes the next item into a generic value
+func (d *decoder) decodeNext() (any, error) {
+	h, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+	return d.decodeAny(h)
+}
+
+// decodeAny decodes an item into a generic value: nil, bool, int64, uint64,
+// float64, string, []byte, []any or map[any]any
+func (d *decoder) decodeAny(h head) (any, error) {
+	switch h.kind {
+	case kindNil:
+		return nil, nil
+	case kindBreak:
+		return nil, fmt.Errorf("%s: unexpected break", ErrInvalidData)
+	case kindBool:
+		return h.u != 0, nil
+	case kindInt:
+		return h.i, nil
+	case kindUint:
+		return h.u, nil
+	case kindFloat:
+		return h.f, nil
+	case kindText:
+		data, err := d.readString(h)
+		return string(data), err
+	case kindBytes:
+		return d.readString(h)
+	case kindArray:
+		items := []any{}
+		for i := uint64(0); ; i++ {
+			ok, next, err := d.more(h, i)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return items, nil
+			}
+			var v any
+			if err := d.decodeItem(next, reflect.ValueOf(&v).Elem()); err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+	}
+	m := make(map[any]any)
+	for i := uint64(0); ; i++ {
+		ok, next, err := d.more(h, i)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return m, nil
+		}
+		var k any
+		if err := d.decodeItem(next, reflect.ValueOf(&k).Elem()); err != nil {
+			return nil, err
+		}
+		v, err := d.decodeNext()
+		if err != nil {
+			return nil, err
+		}
+		if k != nil && !reflect.TypeOf(k).Comparable() {
+			return nil, fmt.Errorf("%s: map key of type %T", ErrUnsupportedType, k)
+		}
+		m[k] = v
+	}
+}
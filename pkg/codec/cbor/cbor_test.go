@@ -0,0 +1,211 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cbor_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	cbor "github.com/pzaino/gods/pkg/codec/cbor"
+	queue "github.com/pzaino/gods/pkg/queue"
+	stack "github.com/pzaino/gods/pkg/stack"
+)
+
+const (
+	errExpectedX     = "Expected %v, but got %v"
+	errUnexpectedErr = "Unexpected error: %v"
+)
+
+type record struct {
+	ID      uint32 `cbor:"id"`
+	Name    string
+	Score   float64
+	Tags    []string
+	Meta    map[string]int
+	Parent  *record
+	Skipped string `cbor:"-"`
+}
+
+// examples from RFC 8949, appendix A
+func TestDeterministicEncoding(t *testing.T) {
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{1000000, "1a000f4240"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{-1, "20"},
+		{-1000, "3903e7"},
+		{0.0, "f90000"},
+		{math.Copysign(0, -1), "f98000"},
+		{1.5, "f93e00"},
+		{65504.0, "f97bff"},
+		{100000.0, "fa47c35000"},
+		{1.1, "fb3ff199999999999a"},
+		{5.960464477539063e-8, "f90001"},
+		{math.Inf(1), "f97c00"},
+		{math.NaN(), "f97e00"},
+		{false, "f4"},
+		{nil, "f6"},
+		{"IETF", "6449455446"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{[]int{1, 2, 3}, "83010203"},
+		{map[int]int{3: 4, 1: 2}, "a201020304"},
+		// keys sorted by their encoding: shorter first
+		{map[string]int{"aa": 1, "b": 2}, "a2616202626161" + "01"},
+	}
+	for _, test := range tests {
+		data, err := cbor.MarshalDeterministic(test.value)
+		if err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		if got := hex.EncodeToString(data); got != test.expected {
+			t.Errorf(errExpectedX, test.expected, got)
+		}
+	}
+}
+
+func TestDeterministicStructs(t *testing.T) {
+	r := record{ID: 1, Name: "n", Meta: map[string]int{"x": 1, "y": 2, "z": 3}}
+	first, err := cbor.MarshalDeterministic(r)
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	for i := 0; i < 10; i++ {
+		data, _ := cbor.MarshalDeterministic(r)
+		if !bytes.Equal(data, first) {
+			t.Fatalf(errExpectedX, first, data)
+		}
+	}
+}
+
+func TestDecodeRFCExamples(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected any
+	}{
+		{"c11a514b67b0", uint64(1363896240)}, // tagged epoch time
+		{"f97c00", math.Inf(1)},
+		{"f93c00", 1.0},
+		{"fa47c35000", 100000.0},
+		{"f7", nil},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9f018202039f0405ffff", []any{uint64(1), []any{uint64(2), uint64(3)}, []any{uint64(4), uint64(5)}}},
+		{"bf61610161629f0203ffff", map[any]any{"a": uint64(1), "b": []any{uint64(2), uint64(3)}}},
+		{"3903e7", int64(-1000)},
+	}
+	for _, test := range tests {
+		data, _ := hex.DecodeString(test.data)
+		var v any
+		if err := cbor.Unmarshal(data, &v); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		if !reflect.DeepEqual(v, test.expected) {
+			t.Errorf(errExpectedX, test.expected, v)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	values := []any{
+		int8(-100), int32(math.MinInt32), int64(math.MinInt64), uint64(math.MaxUint64),
+		float32(1.25), 3.14159, "", string(make([]byte, 300)), make([]byte, 70000),
+		[]string{"a", "b"}, [3]int{1, 2, 3}, map[int]string{1: "one", -2: "minus two"},
+		record{ID: 7, Name: "x", Score: 0.5, Tags: []string{"t"}, Meta: map[string]int{"k": 1},
+			Parent: &record{Name: "parent"}},
+	}
+	for _, deterministic := range []bool{false, true} {
+		codec := cbor.Codec{Deterministic: deterministic}
+		for _, v := range values {
+			var buf bytes.Buffer
+			if err := codec.Encode(&buf, v); err != nil {
+				t.Fatalf(errUnexpectedErr, err)
+			}
+			decoded := reflect.New(reflect.TypeOf(v))
+			if err := codec.Decode(&buf, decoded.Interface()); err != nil {
+				t.Fatalf(errUnexpectedErr, err)
+			}
+			if !reflect.DeepEqual(decoded.Elem().Interface(), v) {
+				t.Errorf(errExpectedX, v, decoded.Elem().Interface())
+			}
+		}
+	}
+}
+
+func TestContainers(t *testing.T) {
+	q := queue.New[string]()
+	q.Enqueue("first")
+	q.Enqueue("second")
+	s := stack.New[int]()
+	s.Push(1)
+	s.Push(2)
+
+	// several items on the same stream
+	codec := cbor.Codec{Deterministic: true}
+	var buf bytes.Buffer
+	for _, c := range []gods.Serializable{q, s} {
+		if err := c.Encode(&buf, codec); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+	}
+	dq, ds := queue.New[string](), stack.New[int]()
+	for _, c := range []gods.Serializable{dq, ds} {
+		if err := c.Decode(&buf, codec); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+	}
+	if !slices.Equal(dq.ToSlice(), q.ToSlice()) {
+		t.Errorf(errExpectedX, q.ToSlice(), dq.ToSlice())
+	}
+	if !slices.Equal(ds.ToSlice(), s.ToSlice()) {
+		t.Errorf(errExpectedX, s.ToSlice(), ds.ToSlice())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := cbor.Marshal(make(chan int)); err == nil {
+		t.Errorf("Expected an error encoding a channel")
+	}
+	var i8 int8
+	if err := cbor.Unmarshal([]byte{0x18, 0xc8}, &i8); err == nil {
+		t.Errorf("Expected an overflow error")
+	}
+	var u uint
+	if err := cbor.Unmarshal([]byte{0x20}, &u); err == nil {
+		t.Errorf("Expected an error decoding a negative value into an uint")
+	}
+	var s string
+	if err := cbor.Unmarshal([]byte{0x63, 'a'}, &s); err == nil {
+		t.Errorf("Expected an error decoding truncated data")
+	}
+	if err := cbor.Unmarshal([]byte{0x1c}, &s); err == nil {
+		t.Errorf("Expected an error decoding reserved additional information")
+	}
+	if err := cbor.Unmarshal([]byte{0x5f, 0x61, 'a', 0xff}, &s); err == nil {
+		t.Errorf("Expected an error decoding a text chunk in a byte string")
+	}
+	if err := cbor.Unmarshal([]byte{0xf6}, s); err == nil {
+		t.Errorf("Expected an error decoding into a non-pointer")
+	}
+}
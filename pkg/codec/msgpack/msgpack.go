@@ -0,0 +1,593 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package msgpack provides a gods.Codec using the MessagePack format, a
+// compact binary alternative to JSON. Containers are encoded as arrays, in the
+// order used by their Encode method, so the encoding preserves their order:
+//
+//	var buf bytes.Buffer
+//	err := myQueue.Encode(&buf, msgpack.Codec{})
+//
+// Integers use the smallest representation, structs are encoded as maps
+// keyed by field name (or by the name in the `msgpack:"name"` tag; fields
+// tagged with "-" and unexported fields are skipped). Messages are
+// self-delimiting, so several of them can be written to the same stream.
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+
+	gods "github.com/pzaino/gods"
+)
+
+const (
+	ErrUnsupportedType = "msgpack: unsupported type"
+	ErrInvalidData     = "msgpack: invalid data"
+	ErrTypeMismatch    = "msgpack: value doesn't match the destination type"
+)
+
+// Codec is a gods.Codec using the MessagePack format.
+type Codec struct{}
+
+// Encode writes v to w in MessagePack format
+func (Codec) Encode(w io.Writer, v any) error {
+	b, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// Decode reads a MessagePack value from r into v (a pointer). It reads only
+// the bytes of one value.
+func (Codec) Decode(r io.Reader, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New(gods.ErrInvalidDecodeDest)
+	}
+	d := &decoder{r: r}
+	if br, ok := r.(io.ByteReader); ok {
+		d.br = br
+	}
+	return d.decode(rv.Elem())
+}
+
+// Marshal returns the MessagePack encoding of v.
+func Marshal(v any) ([]byte, error) {
+	return appendValue(nil, reflect.ValueOf(v))
+}
+
+// Unmarshal decodes the MessagePack value in data into v (a pointer).
+func Unmarshal(data []byte, v any) error {
+	return Codec{}.Decode(bytes.NewReader(data), v)
+}
+
+// encoding
+
+func appendUint(b []byte, n uint64) []byte {
+	switch {
+	case n <= 0x7f:
+		return append(b, byte(n))
+	case n <= math.MaxUint8:
+		return append(b, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xcf), n)
+}
+
+func appendInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0:
+		return appendUint(b, uint64(n))
+	case n >= -32:
+		return append(b, byte(n))
+	case n >= math.MinInt8:
+		return append(b, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+}
+
+// appendHeader appends the header of a str, bin, array or map of length n.
+// fix is the fix-format prefix (0 if there isn't one) and fixMax its maximum
+// length, codes are the 8 (0 if there isn't one), 16 and 32 bit formats.
+func appendHeader(b []byte, n int, fix byte, fixMax int, codes [3]byte) []byte {
+	switch {
+	case fix != 0 && n <= fixMax:
+		return append(b, fix|byte(n))
+	case codes[0] != 0 && n <= math.MaxUint8:
+		return append(b, codes[0], byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, codes[1]), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, codes[2]), uint32(n))
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendHeader(b, len(s), 0xa0, 31, [3]byte{0xd9, 0xda, 0xdb})
+	return append(b, s...)
+}
+
+func appendArrayHeader(b []byte, n int) []byte {
+	return appendHeader(b, n, 0x90, 15, [3]byte{0, 0xdc, 0xdd})
+}
+
+func appendMapHeader(b []byte, n int) []byte {
+	return appendHeader(b, n, 0x80, 15, [3]byte{0, 0xde, 0xdf})
+}
+
+// fieldName returns the name of a struct field in the encoding (or "" if
+// the field must be skipped)
+func fieldName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	tag := f.Tag.Get("msgpack")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return f.Name
+}
+
+func appendValue(b []byte, rv reflect.Value) ([]byte, error) {
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return append(b, 0xc0), nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendInt(b, rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendUint(b, rv.Uint()), nil
+	case reflect.Float32:
+		return binary.BigEndian.AppendUint32(append(b, 0xca), math.Float32bits(float32(rv.Float()))), nil
+	case reflect.Float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(rv.Float())), nil
+	case reflect.String:
+		return appendString(b, rv.String()), nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		return appendValue(b, rv.Elem())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(data), rv)
+			b = appendHeader(b, len(data), 0, 0, [3]byte{0xc4, 0xc5, 0xc6})
+			return append(b, data...), nil
+		}
+		b = appendArrayHeader(b, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			var err error
+			if b, err = appendValue(b, rv.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		b = appendMapHeader(b, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			var err error
+			if b, err = appendValue(b, iter.Key()); err != nil {
+				return nil, err
+			}
+			if b, err = appendValue(b, iter.Value()); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Struct:
+		t := rv.Type()
+		n := 0
+		for i := 0; i < t.NumField(); i++ {
+			if fieldName(t.Field(i)) != "" {
+				n++
+			}
+		}
+		b = appendMapHeader(b, n)
+		for i := 0; i < t.NumField(); i++ {
+			name := fieldName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			b = appendString(b, name)
+			var err error
+			if b, err = appendValue(b, rv.Field(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("%s: %s", ErrUnsupportedType, rv.Type())
+}
+
+// decoding
+
+// decoder reads exactly the bytes of one value from r
+type decoder struct {
+	r   io.Reader
+	br  io.ByteReader // r, if it implements io.ByteReader
+	buf [8]byte
+}
+
+func (d *decoder) readByte() (byte, error) {
+	if d.br != nil {
+		return d.br.ReadByte()
+	}
+	_, err := io.ReadFull(d.r, d.buf[:1])
+	return d.buf[0], err
+}
+
+// readUint reads a big endian unsigned integer of size bytes
+func (d *decoder) readUint(size int) (uint64, error) {
+	if _, err := io.ReadFull(d.r, d.buf[:size]); err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range d.buf[:size] {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+func (d *decoder) readBytes(n uint64) ([]byte, error) {
+	// read in chunks, so a corrupted length can't make us allocate a huge
+	// buffer upfront
+	var data []byte
+	for n > 0 {
+		chunk := min(n, 64*1024)
+		start := len(data)
+		data = append(data, make([]byte, chunk)...)
+		if _, err := io.ReadFull(d.r, data[start:]); err != nil {
+			return nil, err
+		}
+		n -= chunk
+	}
+	return data, nil
+}
+
+// kind of a decoded header
+type kind int
+
+const (
+	kindNil kind = iota
+	kindBool
+	kindInt
+	kindUint
+	kindFloat
+	kindString
+	kindBinary
+	kindArray
+	kindMap
+)
+
+// header is a decoded value header: scalars are complete, strings, binaries,
+// arrays and maps carry their length
+type header struct {
+	kind kind
+	i    int64
+	u    uint64
+	f    float64
+	n    uint64 // length
+}
+
+// formatSizes are the sizes of the payload (or length) of the 8/16/32/64
+// bit formats
+var formatSizes = map[byte]int{
+	0xcc: 1, 0xcd: 2, 0xce: 4, 0xcf: 8,
+	0xd0: 1, 0xd1: 2, 0xd2: 4, 0xd3: 8,
+	0xca: 4, 0xcb: 8,
+	0xd9: 1, 0xda: 2, 0xdb: 4,
+	0xc4: 1, 0xc5: 2, 0xc6: 4,
+	0xdc: 2, 0xdd: 4, 0xde: 2, 0xdf: 4,
+}
+
+func (d *decoder) readHeader() (header, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return header{}, err
+	}
+	switch {
+	case c <= 0x7f:
+		return header{kind: kindUint, u: uint64(c)}, nil
+	case c >= 0xe0:
+		return header{kind: kindInt, i: int64(int8(c))}, nil
+	case c&0xf0 == 0x80:
+		return header{kind: kindMap, n: uint64(c & 0x0f)}, nil
+	case c&0xf0 == 0x90:
+		return header{kind: kindArray, n: uint64(c & 0x0f)}, nil
+	case c&0xe0 == 0xa0:
+		return header{kind: kindString, n: uint64(c & 0x1f)}, nil
+	}
+	switch c {
+	case 0xc0:
+		return header{kind: kindNil}, nil
+	case 0xc2, 0xc3:
+		return header{kind: kindBool, u: uint64(c - 0xc2)}, nil
+	}
+	size, ok := formatSizes[c]
+	if !ok {
+		return header{}, fmt.Errorf("%s: unsupported format 0x%02x", ErrInvalidData, c)
+	}
+	n, err := d.readUint(size)
+	if err != nil {
+		return header{}, err
+	}
+	switch c {
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return header{kind: kindUint, u: n}, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		// sign extend
+		shift := 64 - 8*size
+		return header{kind: kindInt, i: int64(n<<shift) >> shift}, nil
+	case 0xca:
+		return header{kind: kindFloat, f: float64(math.Float32frombits(uint32(n)))}, nil
+	case 0xcb:
+		return header{kind: kindFloat, f: math.Float64frombits(n)}, nil
+	case 0xd9, 0xda, 0xdb:
+		return header{kind: kindString, n: n}, nil
+	case 0xc4, 0xc5, 0xc6:
+		return header{kind: kindBinary, n: n}, nil
+	case 0xdc, 0xdd:
+		return header{kind: kindArray, n: n}, nil
+	}
+	return header{kind: kindMap, n: n}, nil
+}
+
+func (d *decoder) decode(rv reflect.Value) error {
+	h, err := d.readHeader()
+	if err != nil {
+		return err
+	}
+	return d.decodeWithHeader(h, rv)
+}
+
+func mismatch(h header, rv reflect.Value) error {
+	return fmt.Errorf("%s: kind %d into %s", ErrTypeMismatch, h.kind, rv.Type())
+}
+
+func (d *decoder) decodeWithHeader(h header, rv reflect.Value) error {
+	if h.kind == kindNil {
+		rv.SetZero()
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return d.decodeWithHeader(h, rv.Elem())
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return mismatch(h, rv)
+		}
+		v, err := d.decodeAny(h)
+		if err != nil {
+			return err
+		}
+		if v != nil {
+			rv.Set(reflect.ValueOf(v))
+		}
+		return nil
+	case reflect.Bool:
+		if h.kind != kindBool {
+			return mismatch(h, rv)
+		}
+		rv.SetBool(h.u != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch {
+		case h.kind == kindInt:
+			n = h.i
+		case h.kind == kindUint && h.u <= math.MaxInt64:
+			n = int64(h.u)
+		default:
+			return mismatch(h, rv)
+		}
+		if rv.OverflowInt(n) {
+			return mismatch(h, rv)
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if h.kind != kindUint || rv.OverflowUint(h.u) {
+			return mismatch(h, rv)
+		}
+		rv.SetUint(h.u)
+	case reflect.Float32, reflect.Float64:
+		switch h.kind {
+		case kindFloat:
+			rv.SetFloat(h.f)
+		case kindInt:
+			rv.SetFloat(float64(h.i))
+		case kindUint:
+			rv.SetFloat(float64(h.u))
+		default:
+			return mismatch(h, rv)
+		}
+	case reflect.String:
+		if h.kind != kindString && h.kind != kindBinary {
+			return mismatch(h, rv)
+		}
+		data, err := d.readBytes(h.n)
+		if err != nil {
+			return err
+		}
+		rv.SetString(string(data))
+	case reflect.Slice, reflect.Array:
+		return d.decodeList(h, rv)
+	case reflect.Map:
+		if h.kind != kindMap {
+			return mismatch(h, rv)
+		}
+		m := reflect.MakeMap(rv.Type())
+		for i := uint64(0); i < h.n; i++ {
+			k, v := reflect.New(rv.Type().Key()).Elem(), reflect.New(rv.Type().Elem()).Elem()
+			if err := d.decode(k); err != nil {
+				return err
+			}
+			if err := d.decode(v); err != nil {
+				return err
+			}
+			m.SetMapIndex(k, v)
+		}
+		rv.Set(m)
+	case reflect.Struct:
+		if h.kind != kindMap {
+			return mismatch(h, rv)
+		}
+		fields := make(map[string]int)
+		for i := 0; i < rv.NumField(); i++ {
+			if name := fieldName(rv.Type().Field(i)); name != "" {
+				fields[name] = i
+			}
+		}
+		for i := uint64(0); i < h.n; i++ {
+			var name string
+			if err := d.decode(reflect.ValueOf(&name).Elem()); err != nil {
+				return err
+			}
+			if idx, ok := fields[name]; ok {
+				if err := d.decode(rv.Field(idx)); err != nil {
+					return err
+				}
+			} else if _, err := d.decodeNext(); err != nil {
+				// unknown fields are skipped
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%s: %s", ErrUnsupportedType, rv.Type())
+	}
+	return nil
+}
+
+// decodeList decodes an array (or a binary, for byte slices) into a slice or an array
+func (d *decoder) decodeList(h header, rv reflect.Value) error {
+	isBytes := rv.Type().Elem().Kind() == reflect.Uint8
+	if h.kind == kindBinary && isBytes {
+		data, err := d.readBytes(h.n)
+		if err != nil {
+			return err
+		}
+		if rv.Kind() == reflect.Slice {
+			rv.Set(reflect.MakeSlice(rv.Type(), len(data), len(data)))
+		}
+		reflect.Copy(rv, reflect.ValueOf(data))
+		return nil
+	}
+	if h.kind != kindArray {
+		return mismatch(h, rv)
+	}
+	if rv.Kind() == reflect.Slice {
+		rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+	}
+	elem := reflect.New(rv.Type().Elem()).Elem()
+	for i := uint64(0); i < h.n; i++ {
+		elem.SetZero()
+		if err := d.decode(elem); err != nil {
+			return err
+		}
+		if rv.Kind() == reflect.Slice {
+			rv.Set(reflect.Append(rv, elem))
+		} else if i < uint64(rv.Len()) {
+			rv.Index(int(i)).Set(elem)
+		}
+	}
+	return nil
+}
+
+// decodeNext decodes the next value into a generic value
+func (d *decoder) decodeNext() (any, error) {
+	h, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	return d.decodeAny(h)
+}
+
+// decodeAny decodes a value into a generic value: nil, bool, int64, uint64,
+// float64, string, []byte, []any or map[any]any
+func (d *decoder) decodeAny(h header) (any, error) {
+	switch h.kind {
+	case kindNil:
+		return nil, nil
+	case kindBool:
+		return h.u != 0, nil
+	case kindInt:
+		return h.i, nil
+	case kindUint:
+		return h.u, nil
+	case kindFloat:
+		return h.f, nil
+	case kindString:
+		data, err := d.readBytes(h.n)
+		return string(data), err
+	case kindBinary:
+		return d.readBytes(h.n)
+	case kindArray:
+		var items []any
+		for i := uint64(0); i < h.n; i++ {
+			v, err := d.decodeNext()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	}
+	m := make(map[any]any)
+	for i := uint64(0); i < h.n; i++ {
+		k, err := d.decodeNext()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decodeNext()
+		if err != nil {
+			return nil, err
+		}
+		if k != nil && !reflect.TypeOf(k).Comparable() {
+			return nil, fmt.Errorf("%s: map key of type %T", ErrUnsupportedType, k)
+		}
+		m[k] = v
+	}
+	return m, nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package msgpack_test
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	msgpack "github.com/pzaino/gods/pkg/codec/msgpack"
+	pqueue "github.com/pzaino/gods/pkg/pqueue"
+	queue "github.com/pzaino/gods/pkg/queue"
+)
+
+const (
+	errExpectedX     = "Expected %v, but got %v"
+	errUnexpectedErr = "Unexpected error: %v"
+)
+
+type record struct {
+	ID      uint32 `msgpack:"id"`
+	Name    string
+	Score   float64
+	Tags    []string
+	Meta    map[string]int
+	Parent  *record
+	Skipped string `msgpack:"-"`
+	private int
+}
+
+func TestWireFormat(t *testing.T) {
+	tests := []struct {
+		value    any
+		expected []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{5, []byte{0x05}},
+		{-1, []byte{0xff}},
+		{200, []byte{0xcc, 0xc8}},
+		{-200, []byte{0xd1, 0xff, 0x38}},
+		{70000, []byte{0xce, 0x00, 0x01, 0x11, 0x70}},
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"abc", []byte{0xa3, 'a', 'b', 'c'}},
+		{[]byte{1, 2}, []byte{0xc4, 0x02, 0x01, 0x02}},
+		{[]int{1, 2, 3}, []byte{0x93, 0x01, 0x02, 0x03}},
+		{map[string]bool{"a": false}, []byte{0x81, 0xa1, 'a', 0xc2}},
+	}
+	for _, test := range tests {
+		data, err := msgpack.Marshal(test.value)
+		if err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		if !bytes.Equal(data, test.expected) {
+			t.Errorf(errExpectedX, test.expected, data)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	values := []any{
+		int8(-100), int16(-30000), int32(math.MinInt32), int64(math.MinInt64), uint64(math.MaxUint64),
+		float32(1.25), math.Inf(-1), "", string(make([]byte, 300)), make([]byte, 70000),
+		[]string{"a", "b"}, [3]int{1, 2, 3}, map[int]string{1: "one", -2: "minus two"},
+		record{ID: 7, Name: "x", Score: 0.5, Tags: []string{"t"}, Meta: map[string]int{"k": 1},
+			Parent: &record{Name: "parent"}},
+	}
+	for _, v := range values {
+		data, err := msgpack.Marshal(v)
+		if err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		decoded := reflect.New(reflect.TypeOf(v))
+		if err := msgpack.Unmarshal(data, decoded.Interface()); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		if !reflect.DeepEqual(decoded.Elem().Interface(), v) {
+			t.Errorf(errExpectedX, v, decoded.Elem().Interface())
+		}
+	}
+}
+
+func TestStructFields(t *testing.T) {
+	data, err := msgpack.Marshal(record{ID: 1, Skipped: "s", private: 2})
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	var generic map[any]any
+	if err := msgpack.Unmarshal(data, &generic); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if generic["id"] != uint64(1) || len(generic) != 6 {
+		t.Errorf(errExpectedX, "6 fields with the tagged name", generic)
+	}
+	// unknown fields are skipped
+	data, _ = msgpack.Marshal(map[string]any{"Name": "n", "Unknown": []any{1, "x"}})
+	var r record
+	if err := msgpack.Unmarshal(data, &r); err != nil || r.Name != "n" {
+		t.Errorf(errExpectedX, "n", r.Name)
+	}
+}
+
+func TestContainers(t *testing.T) {
+	q := queue.New[string]()
+	q.Enqueue("first")
+	q.Enqueue("second")
+	pq := pqueue.New[int]()
+	pq.Enqueue(1, 1)
+	pq.Enqueue(2, 2)
+
+	// several messages on the same stream
+	var buf bytes.Buffer
+	for _, c := range []gods.Serializable{q, pq} {
+		if err := c.Encode(&buf, msgpack.Codec{}); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+	}
+	dq, dpq := queue.New[string](), pqueue.New[int]()
+	for _, c := range []gods.Serializable{dq, dpq} {
+		if err := c.Decode(&buf, msgpack.Codec{}); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+	}
+	if !slices.Equal(dq.ToSlice(), q.ToSlice()) {
+		t.Errorf(errExpectedX, q.ToSlice(), dq.ToSlice())
+	}
+	if v, _ := dpq.Dequeue(); v != 2 {
+		t.Errorf(errExpectedX, 2, v)
+	}
+
+	// more compact than JSON
+	var js bytes.Buffer
+	_ = q.Encode(&js, gods.JSONCodec{})
+	data, _ := msgpack.Marshal(q.ToSlice())
+	if len(data) >= js.Len() {
+		t.Errorf(errExpectedX, "a smaller encoding than JSON", len(data))
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := msgpack.Marshal(make(chan int)); err == nil {
+		t.Errorf("Expected an error encoding a channel")
+	}
+	var i8 int8
+	if err := msgpack.Unmarshal([]byte{0xcc, 0xc8}, &i8); err == nil {
+		t.Errorf("Expected an overflow error")
+	}
+	var u uint
+	if err := msgpack.Unmarshal([]byte{0xff}, &u); err == nil {
+		t.Errorf("Expected an error decoding a negative value into an uint")
+	}
+	var s string
+	if err := msgpack.Unmarshal([]byte{0xa3, 'a'}, &s); err == nil {
+		t.Errorf("Expected an error decoding truncated data")
+	}
+	if err := msgpack.Unmarshal([]byte{0xc1}, &s); err == nil {
+		t.Errorf("Expected an error decoding an invalid format")
+	}
+	if err := msgpack.Unmarshal([]byte{0xc0}, s); err == nil {
+		t.Errorf("Expected an error decoding into a non-pointer")
+	}
+}
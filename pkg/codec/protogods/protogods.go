@@ -0,0 +1,379 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protogods provides a gods.Codec producing Protocol Buffers
+// messages, so the containers can be sent over gRPC without flattening them
+// by hand. The messages are defined in gods.proto (Collection, Value and
+// Fields): services can import it and use the generated Collection type, or a
+// bytes field, to carry the snapshots.
+//
+// The package implements the wire format directly, so it has no
+// dependencies:
+//
+//	data, err := protogods.Marshal(myStack)
+//	// ... send data ...
+//	s := stack.New[int]()
+//	err = protogods.Unmarshal(data, s)
+package protogods
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+
+	gods "github.com/pzaino/gods"
+)
+
+const (
+	ErrNotASlice       = "protogods: the top level value must be a slice"
+	ErrUnsupportedType = "protogods: unsupported type"
+	ErrInvalidMessage  = "protogods: invalid message"
+	ErrKindMismatch    = "protogods: value kind doesn't match the destination type"
+)
+
+// field numbers of the Value message (see gods.proto)
+const (
+	fieldInt    = 1
+	fieldUint   = 2
+	fieldFloat  = 3
+	fieldString = 4
+	fieldBytes  = 5
+	fieldBool   = 6
+	fieldList   = 7
+	fieldFields = 8
+)
+
+// wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Codec is a gods.Codec encoding slices as Collection messages. A message is
+// not self-delimiting, so Decode reads r until EOF: every stream must carry a
+// single message.
+type Codec struct{}
+
+// Encode writes v (a slice) to w as a Collection message
+func (Codec) Encode(w io.Writer, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return errors.New(ErrNotASlice)
+	}
+	data, err := appendCollection(nil, rv)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Decode reads a Collection message from r into v (a pointer to a slice)
+func (Codec) Decode(r io.Reader, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New(gods.ErrInvalidDecodeDest)
+	}
+	if rv.Elem().Kind() != reflect.Slice {
+		return errors.New(ErrNotASlice)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return decodeCollection(data, rv.Elem())
+}
+
+// Marshal returns the Collection message of a container.
+func Marshal(c gods.Serializable) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, Codec{}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal replaces the content of a container with the elements of a
+// Collection message.
+func Unmarshal(data []byte, c gods.Serializable) error {
+	return c.Decode(bytes.NewReader(data), Codec{})
+}
+
+// encoding
+
+func appendKey(b []byte, field, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wire))
+}
+
+func appendLengthDelimited(b []byte, field int, data []byte) []byte {
+	b = appendKey(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// appendCollection appends the fields of a Collection with the elements of rv
+func appendCollection(b []byte, rv reflect.Value) ([]byte, error) {
+	if rv.Kind() == reflect.Map {
+		iter := rv.MapRange()
+		for iter.Next() {
+			fields, err := appendValue(nil, iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			if fields, err = appendValue(fields, iter.Value()); err != nil {
+				return nil, err
+			}
+			b = appendLengthDelimited(b, 1, appendLengthDelimited(nil, fieldFields, fields))
+		}
+		return b, nil
+	}
+	for i := 0; i < rv.Len(); i++ {
+		item, err := appendValueMessage(nil, rv.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		b = appendLengthDelimited(b, 1, item)
+	}
+	return b, nil
+}
+
+// appendValue appends rv as an element (field 1) of a Fields message
+func appendValue(b []byte, rv reflect.Value) ([]byte, error) {
+	item, err := appendValueMessage(nil, rv)
+	if err != nil {
+		return nil, err
+	}
+	return appendLengthDelimited(b, 1, item), nil
+}
+
+// appendValueMessage appends the Value message of rv
+func appendValueMessage(b []byte, rv reflect.Value) ([]byte, error) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := rv.Int()
+		b = appendKey(b, fieldInt, wireVarint)
+		return binary.AppendUvarint(b, uint64(n<<1)^uint64(n>>63)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		b = appendKey(b, fieldUint, wireVarint)
+		return binary.AppendUvarint(b, rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		b = appendKey(b, fieldFloat, wireFixed64)
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(rv.Float())), nil
+	case reflect.String:
+		return appendLengthDelimited(b, fieldString, []byte(rv.String())), nil
+	case reflect.Bool:
+		b = appendKey(b, fieldBool, wireVarint)
+		if rv.Bool() {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return appendLengthDelimited(b, fieldBytes, rv.Bytes()), nil
+		}
+		list, err := appendCollection(nil, rv)
+		if err != nil {
+			return nil, err
+		}
+		return appendLengthDelimited(b, fieldList, list), nil
+	case reflect.Struct:
+		var fields []byte
+		for i := 0; i < rv.NumField(); i++ {
+			if !rv.Type().Field(i).IsExported() {
+				continue
+			}
+			var err error
+			if fields, err = appendValue(fields, rv.Field(i)); err != nil {
+				return nil, err
+			}
+		}
+		return appendLengthDelimited(b, fieldFields, fields), nil
+	}
+	return nil, fmt.Errorf("%s: %s", ErrUnsupportedType, rv.Type())
+}
+
+// decoding
+
+// field is a decoded field of a message
+type field struct {
+	num   int
+	wire  int
+	value uint64 // varint and fixed values
+	data  []byte // length-delimited values
+}
+
+// parseMessage splits a message into its fields
+func parseMessage(b []byte) ([]field, error) {
+	var fields []field
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errors.New(ErrInvalidMessage)
+		}
+		b = b[n:]
+		f := field{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			if f.value, n = binary.Uvarint(b); n <= 0 {
+				return nil, errors.New(ErrInvalidMessage)
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, errors.New(ErrInvalidMessage)
+			}
+			f.value, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, errors.New(ErrInvalidMessage)
+			}
+			f.value, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || length > uint64(len(b)-n) {
+				return nil, errors.New(ErrInvalidMessage)
+			}
+			f.data, b = b[n:n+int(length)], b[n+int(length):]
+		default:
+			return nil, errors.New(ErrInvalidMessage)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// repeated returns the data of the occurrences of field 1 (the repeated field
+// of Collection and Fields)
+func repeated(b []byte) ([][]byte, error) {
+	fields, err := parseMessage(b)
+	if err != nil {
+		return nil, err
+	}
+	var items [][]byte
+	for _, f := range fields {
+		if f.num == 1 && f.wire == wireBytes {
+			items = append(items, f.data)
+		}
+	}
+	return items, nil
+}
+
+// decodeCollection decodes a Collection into rv (a slice, array or map)
+func decodeCollection(b []byte, rv reflect.Value) error {
+	items, err := repeated(b)
+	if err != nil {
+		return err
+	}
+	switch rv.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(rv.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(item, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+	case reflect.Array:
+		for i := 0; i < rv.Len() && i < len(items); i++ {
+			if err := decodeValue(items[i], rv.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		m := reflect.MakeMapWithSize(rv.Type(), len(items))
+		for _, item := range items {
+			fields, err := parseMessage(item)
+			if err != nil {
+				return err
+			}
+			if len(fields) != 1 || fields[0].num != fieldFields {
+				return errors.New(ErrKindMismatch)
+			}
+			kv, err := repeated(fields[0].data)
+			if err != nil {
+				return err
+			}
+			if len(kv) != 2 {
+				return errors.New(ErrInvalidMessage)
+			}
+			k, v := reflect.New(rv.Type().Key()).Elem(), reflect.New(rv.Type().Elem()).Elem()
+			if err := decodeValue(kv[0], k); err != nil {
+				return err
+			}
+			if err := decodeValue(kv[1], v); err != nil {
+				return err
+			}
+			m.SetMapIndex(k, v)
+		}
+		rv.Set(m)
+	default:
+		return fmt.Errorf("%s: %s", ErrUnsupportedType, rv.Type())
+	}
+	return nil
+}
+
+// decodeValue decodes a Value message into rv
+func decodeValue(b []byte, rv reflect.Value) error {
+	fields, err := parseMessage(b)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		// an unset oneof: leave the zero value
+		return nil
+	}
+	// with a oneof, the last field wins
+	f := fields[len(fields)-1]
+	kind := rv.Kind()
+	switch {
+	case f.num == fieldInt && kind >= reflect.Int && kind <= reflect.Int64:
+		rv.SetInt(int64(f.value>>1) ^ -int64(f.value&1))
+	case f.num == fieldUint && kind >= reflect.Uint && kind <= reflect.Uintptr:
+		rv.SetUint(f.value)
+	case f.num == fieldFloat && (kind == reflect.Float32 || kind == reflect.Float64):
+		rv.SetFloat(math.Float64frombits(f.value))
+	case f.num == fieldString && kind == reflect.String:
+		rv.SetString(string(f.data))
+	case f.num == fieldBytes && kind == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		rv.SetBytes(bytes.Clone(f.data))
+	case f.num == fieldBool && kind == reflect.Bool:
+		rv.SetBool(f.value != 0)
+	case f.num == fieldList && (kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map):
+		return decodeCollection(f.data, rv)
+	case f.num == fieldFields && kind == reflect.Struct:
+		values, err := repeated(f.data)
+		if err != nil {
+			return err
+		}
+		i := 0
+		for j := 0; j < rv.NumField() && i < len(values); j++ {
+			if !rv.Type().Field(j).IsExported() {
+				continue
+			}
+			if err := decodeValue(values[i], rv.Field(j)); err != nil {
+				return err
+			}
+			i++
+		}
+	default:
+		return fmt.Errorf("%s: field %d into %s", ErrKindMismatch, f.num, rv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protogods_test
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	protogods "github.com/pzaino/gods/pkg/codec/protogods"
+	phashmap "github.com/pzaino/gods/pkg/phashmap"
+	pqueue "github.com/pzaino/gods/pkg/pqueue"
+	queue "github.com/pzaino/gods/pkg/queue"
+	stack "github.com/pzaino/gods/pkg/stack"
+)
+
+const (
+	errExpectedX     = "Expected %v, but got %v"
+	errUnexpectedErr = "Unexpected error: %v"
+)
+
+type point struct {
+	X, Y    float64
+	Label   string
+	Tags    []string
+	Data    []byte
+	Visible bool
+	hidden  int
+}
+
+func TestWireFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (protogods.Codec{}).Encode(&buf, []int{1, -1}); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	// Collection{items: [Value{int: 1}, Value{int: -1}]}, ints are zigzag encoded
+	expected := []byte{0x0a, 0x02, 0x08, 0x02, 0x0a, 0x02, 0x08, 0x01}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf(errExpectedX, expected, buf.Bytes())
+	}
+}
+
+func TestStackAndQueue(t *testing.T) {
+	s := stack.New[int]()
+	for i := -2; i < 3; i++ {
+		s.Push(i * 1000)
+	}
+	data, err := protogods.Marshal(s)
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	decoded := stack.New[int]()
+	if err := protogods.Unmarshal(data, decoded); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if !slices.Equal(decoded.ToSlice(), s.ToSlice()) {
+		t.Errorf(errExpectedX, s.ToSlice(), decoded.ToSlice())
+	}
+
+	q := queue.New[string]()
+	q.Enqueue("a")
+	q.Enqueue("")
+	q.Enqueue("c")
+	if data, err = protogods.Marshal(q); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	dq := queue.New[string]()
+	if err := protogods.Unmarshal(data, dq); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if !slices.Equal(dq.ToSlice(), q.ToSlice()) {
+		t.Errorf(errExpectedX, q.ToSlice(), dq.ToSlice())
+	}
+}
+
+func TestStructElements(t *testing.T) {
+	points := []point{
+		{X: 1.5, Y: -2, Label: "a", Tags: []string{"x", "y"}, Data: []byte{0, 1}, Visible: true, hidden: 7},
+		{},
+	}
+	var buf bytes.Buffer
+	if err := (protogods.Codec{}).Encode(&buf, points); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	var decoded []point
+	if err := (protogods.Codec{}).Decode(&buf, &decoded); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	points[0].hidden = 0 // unexported fields are not encoded
+	if len(decoded) != 2 || decoded[0].Label != "a" || decoded[0].Y != -2 ||
+		!slices.Equal(decoded[0].Tags, points[0].Tags) || !bytes.Equal(decoded[0].Data, points[0].Data) ||
+		!decoded[0].Visible || decoded[0].hidden != 0 || decoded[1].Label != "" {
+		t.Errorf(errExpectedX, points, decoded)
+	}
+}
+
+func TestPriorityQueueAndMap(t *testing.T) {
+	pq := pqueue.New[string]()
+	pq.Enqueue("low", 1)
+	pq.Enqueue("high", 10)
+	data, err := protogods.Marshal(pq)
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	decoded := pqueue.New[string]()
+	if err := protogods.Unmarshal(data, decoded); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if v, _ := decoded.Dequeue(); v != "high" {
+		t.Errorf(errExpectedX, "high", v)
+	}
+
+	m := phashmap.New[string, uint8]().Assoc("a", 1).Assoc("b", 255)
+	var buf bytes.Buffer
+	if err := m.Encode(&buf, protogods.Codec{}); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	dm, err := phashmap.Decode[string, uint8](&buf, protogods.Codec{})
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if v, _ := dm.Get("b"); dm.Size() != 2 || v != 255 {
+		t.Errorf(errExpectedX, 255, v)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (protogods.Codec{}).Encode(&buf, 1); err == nil {
+		t.Errorf("Expected an error encoding a non-slice value")
+	}
+	if err := (protogods.Codec{}).Encode(&buf, []chan int{nil}); err == nil {
+		t.Errorf("Expected an error encoding an unsupported type")
+	}
+	var ints []int
+	if err := (protogods.Codec{}).Decode(bytes.NewReader([]byte{0x0a, 0x05}), &ints); err == nil {
+		t.Errorf("Expected an error decoding a truncated message")
+	}
+	var strs []string
+	if err := (protogods.Codec{}).Decode(bytes.NewReader([]byte{0x0a, 0x02, 0x08, 0x02}), &strs); err == nil {
+		t.Errorf("Expected an error decoding an int into a string")
+	}
+	if err := (protogods.Codec{}).Decode(bytes.NewReader(nil), ints); err == nil {
+		t.Errorf("Expected an error decoding into a non-pointer")
+	}
+}
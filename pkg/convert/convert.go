@@ -0,0 +1,160 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package convert provides typed conversions between the data structures of
+// the library.
+//
+// All the conversions preserve the "extraction order" of the source: the
+// first element you would get out of the source (the top of a stack, the
+// front of a queue, the head of a list) is the first element you get out of
+// the result. The source is never modified.
+package convert
+
+import (
+	"context"
+
+	gods "github.com/pzaino/gods"
+	buffer "github.com/pzaino/gods/pkg/buffer"
+	dlinkList "github.com/pzaino/gods/pkg/dlinkList"
+	linkList "github.com/pzaino/gods/pkg/linkList"
+	pqueue "github.com/pzaino/gods/pkg/pqueue"
+	queue "github.com/pzaino/gods/pkg/queue"
+	ringBuffer "github.com/pzaino/gods/pkg/ringBuffer"
+	stack "github.com/pzaino/gods/pkg/stack"
+)
+
+// StackToQueue returns a queue with the items of the stack, where the top of
+// the stack is the front of the queue (so Dequeue returns the items in the
+// same order Pop would). O(n)
+func StackToQueue[T comparable](s *stack.Stack[T]) *queue.Queue[T] {
+	q := queue.New[T]()
+	for item := range s.Iter() {
+		q.Enqueue(item)
+	}
+	return q
+}
+
+// QueueToStack returns a stack with the elements of the queue, where the front
+// of the queue is the top of the stack (so Pop returns the elements in the
+// same order Dequeue would). O(n)
+func QueueToStack[T comparable](q *queue.Queue[T]) *stack.Stack[T] {
+	items := q.ToSlice()
+	s := stack.New[T]()
+	for i := len(items) - 1; i >= 0; i-- {
+		s.Push(items[i])
+	}
+	return s
+}
+
+// ListToQueue returns a queue with the values of the list, where the head of
+// the list is the front of the queue. O(n)
+func ListToQueue[T comparable](l *linkList.LinkList[T]) *queue.Queue[T] {
+	q := queue.New[T]()
+	for value := range l.Iter() {
+		q.Enqueue(value)
+	}
+	return q
+}
+
+// QueueToList returns a linked list with the elements of the queue, where the
+// front of the queue is the head of the list. O(n)
+func QueueToList[T comparable](q *queue.Queue[T]) *linkList.LinkList[T] {
+	return linkList.NewFromSlice(q.ToSlice())
+}
+
+// ListToDList returns a doubly linked list with the values of the list. O(n)
+func ListToDList[T comparable](l *linkList.LinkList[T]) *dlinkList.DLinkList[T] {
+	d := dlinkList.New[T]()
+	for value := range l.Iter() {
+		d.Append(value)
+	}
+	return d
+}
+
+// DListToList returns a (singly) linked list with the values of the doubly linked list. O(n)
+func DListToList[T comparable](d *dlinkList.DLinkList[T]) *linkList.LinkList[T] {
+	l := linkList.New[T]()
+	for value := range d.Iter() {
+		l.Append(value)
+	}
+	return l
+}
+
+// ToBuffer returns a buffer with the elements of any collection, in its iteration order. O(n)
+func ToBuffer[T comparable](c gods.Collection[T]) *buffer.Buffer[T] {
+	b := buffer.New[T]()
+	for item := range c.Iter() {
+		_ = b.Append(item) // a buffer with no capacity never fails
+	}
+	return b
+}
+
+// ToRingBuffer returns a ring buffer with the given capacity with the elements
+// of any collection, in its iteration order. If the collection has more
+// elements than capacity, only the last capacity elements are kept. O(n)
+func ToRingBuffer[T comparable](c gods.Collection[T], capacity uint64) *ringBuffer.CircularBuffer[T] {
+	rb := ringBuffer.New[T](capacity)
+	for item := range c.Iter() {
+		rb.Append(item)
+	}
+	return rb
+}
+
+// ToPriorityQueue returns a priority queue with the elements of any collection,
+// using the priority function to assign a priority to each element. O(n log n)
+func ToPriorityQueue[T comparable](c gods.Collection[T], priority func(T) int) *pqueue.PriorityQueue[T] {
+	pq := pqueue.New[T]()
+	for item := range c.Iter() {
+		pq.Enqueue(item, priority(item))
+	}
+	return pq
+}
+
+// QueueToChannel returns a channel that receives the elements of the queue
+// from the front to the back. The elements are copied when QueueToChannel is
+// called, so the queue can be modified afterwards. The channel is closed after
+// the last element or when the context is done. O(n)
+func QueueToChannel[T comparable](ctx context.Context, q *queue.Queue[T]) <-chan T {
+	items := q.ToSlice()
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		for _, item := range items {
+			select {
+			case ch <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// ChannelToQueue reads from the channel until it is closed or the context is
+// done and returns a queue with the received elements, in the order they were
+// received. O(n)
+func ChannelToQueue[T comparable](ctx context.Context, ch <-chan T) *queue.Queue[T] {
+	q := queue.New[T]()
+	for {
+		select {
+		case item, ok := <-ch:
+			if !ok {
+				return q
+			}
+			q.Enqueue(item)
+		case <-ctx.Done():
+			return q
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package convert provides typed conversions between the data structures.
+package convert_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	convert "github.com/pzaino/gods/pkg/convert"
+	dlinkList "github.com/pzaino/gods/pkg/dlinkList"
+	linkList "github.com/pzaino/gods/pkg/linkList"
+	queue "github.com/pzaino/gods/pkg/queue"
+	stack "github.com/pzaino/gods/pkg/stack"
+)
+
+const (
+	errExpectedX = "Expected %v, but got %v"
+)
+
+func newQueue(items ...int) *queue.Queue[int] {
+	q := queue.New[int]()
+	for _, item := range items {
+		q.Enqueue(item)
+	}
+	return q
+}
+
+func TestStackToQueue(t *testing.T) {
+	s := stack.NewFromSlice([]int{1, 2, 3}) // 3 is on top
+	q := convert.StackToQueue(s)
+	if got := q.ToSlice(); !slices.Equal(got, []int{3, 2, 1}) {
+		t.Errorf(errExpectedX, []int{3, 2, 1}, got)
+	}
+	if s.Size() != 3 {
+		t.Errorf(errExpectedX, 3, s.Size())
+	}
+}
+
+func TestQueueToStack(t *testing.T) {
+	q := newQueue(1, 2, 3)
+	s := convert.QueueToStack(q)
+	for _, expected := range []int{1, 2, 3} {
+		item, err := s.Pop()
+		if err != nil || *item != expected {
+			t.Errorf(errExpectedX, expected, item)
+		}
+	}
+	if q.Size() != 3 {
+		t.Errorf(errExpectedX, 3, q.Size())
+	}
+	if !convert.QueueToStack(queue.New[int]()).IsEmpty() {
+		t.Errorf(errExpectedX, "empty stack", "non empty stack")
+	}
+}
+
+func TestListConversions(t *testing.T) {
+	l := linkList.NewFromSlice([]int{1, 2, 3})
+
+	if got := convert.ListToQueue(l).ToSlice(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf(errExpectedX, []int{1, 2, 3}, got)
+	}
+	if got := convert.QueueToList(newQueue(1, 2, 3)).ToSlice(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf(errExpectedX, []int{1, 2, 3}, got)
+	}
+
+	d := convert.ListToDList(l)
+	if got := slices.Collect(d.Backward()); !slices.Equal(got, []int{3, 2, 1}) {
+		t.Errorf(errExpectedX, []int{3, 2, 1}, got)
+	}
+	if got := convert.DListToList(dlinkList.New[int]()).ToSlice(); len(got) != 0 {
+		t.Errorf(errExpectedX, "[]", got)
+	}
+}
+
+func TestCollectionConversions(t *testing.T) {
+	s := stack.NewFromSlice([]int{1, 2, 3, 4})
+
+	if got := convert.ToBuffer[int](s).ToSlice(); !slices.Equal(got, []int{4, 3, 2, 1}) {
+		t.Errorf(errExpectedX, []int{4, 3, 2, 1}, got)
+	}
+
+	// only the last elements fit in the ring buffer
+	if got := convert.ToRingBuffer[int](s, 2).ToSlice(); !slices.Equal(got, []int{2, 1}) {
+		t.Errorf(errExpectedX, []int{2, 1}, got)
+	}
+
+	pq := convert.ToPriorityQueue[int](s, func(v int) int { return -v })
+	if v, _ := pq.Dequeue(); v != 1 {
+		t.Errorf(errExpectedX, 1, v)
+	}
+}
+
+func TestQueueChannel(t *testing.T) {
+	q := newQueue(1, 2, 3)
+	ch := convert.QueueToChannel(context.Background(), q)
+	q.Clear() // elements were copied
+	got := convert.ChannelToQueue(context.Background(), ch).ToSlice()
+	if !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf(errExpectedX, []int{1, 2, 3}, got)
+	}
+
+	// a cancelled context stops both sides
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ch = convert.QueueToChannel(ctx, newQueue(1, 2, 3))
+	for range ch {
+		// the producer may still deliver an element before seeing ctx.Done()
+	}
+	if got := convert.ChannelToQueue(ctx, make(chan int)); !got.IsEmpty() {
+		t.Errorf(errExpectedX, "empty queue", got.ToSlice())
+	}
+}
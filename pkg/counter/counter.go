@@ -0,0 +1,304 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package counter provides a concurrency-safe map of int64 counters, for
+// metric aggregation and the other increment-heavy workloads.
+//
+// The keys are spread over shards, and every counter is an atomic integer:
+// incrementing an existing counter only takes the read lock of its shard, so
+// the goroutines incrementing counters don't serialize on a lock. The write
+// lock is taken to add a new key.
+package counter
+
+import (
+	"cmp"
+	"fmt"
+	"hash/maphash"
+	"iter"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	gods "github.com/pzaino/gods"
+)
+
+// Option configures a Counter created with New.
+type Option func(*config)
+
+type config struct {
+	shards int
+}
+
+// WithShards sets the number of shards (the default is 32). More shards mean
+// less contention when new keys are added.
+func WithShards(n int) Option {
+	return func(cfg *config) {
+		cfg.shards = max(n, 1)
+	}
+}
+
+// Entry is a key with its count.
+type Entry[K comparable] struct {
+	Key   K
+	Count int64
+}
+
+// shard is a part of the counters, with its own lock
+type shard[K comparable] struct {
+	mu       sync.RWMutex
+	counters map[K]*atomic.Int64
+	_        [64]byte // keep the locks of the shards on different cache lines
+}
+
+// Counter is a concurrency-safe map from keys to int64 counters. A missing
+// key counts 0.
+type Counter[K comparable] struct {
+	shards []shard[K]
+	seed   maphash.Seed
+}
+
+// New creates a new empty Counter.
+func New[K comparable](opts ...Option) *Counter[K] {
+	cfg := config{shards: 32}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	c := &Counter[K]{shards: make([]shard[K], cfg.shards), seed: maphash.MakeSeed()}
+	for i := range c.shards {
+		c.shards[i].counters = make(map[K]*atomic.Int64)
+	}
+	return c
+}
+
+// shard returns the shard of key
+func (c *Counter[K]) shard(key K) *shard[K] {
+	var h uint64
+	switch k := any(key).(type) {
+	case string:
+		h = maphash.String(c.seed, k)
+	case int:
+		h = mix(uint64(k))
+	case int64:
+		h = mix(uint64(k))
+	case int32:
+		h = mix(uint64(k))
+	case uint:
+		h = mix(uint64(k))
+	case uint64:
+		h = mix(k)
+	case uint32:
+		h = mix(uint64(k))
+	default:
+		h = maphash.String(c.seed, fmt.Sprintf("%#v", key))
+	}
+	return &c.shards[h%uint64(len(c.shards))]
+}
+
+// mix scrambles the bits of an integer key (the splitmix64 finalizer)
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// Inc adds 1 to the counter of key and returns its new value.
+func (c *Counter[K]) Inc(key K) int64 {
+	return c.Add(key, 1)
+}
+
+// Add adds delta to the counter of key and returns its new value.
+func (c *Counter[K]) Add(key K, delta int64) int64 {
+	sh := c.shard(key)
+	// the counter is updated while the lock is held, so Reset doesn't lose
+	// the updates made to the counters it takes away
+	sh.mu.RLock()
+	if n, ok := sh.counters[key]; ok {
+		v := n.Add(delta)
+		sh.mu.RUnlock()
+		return v
+	}
+	sh.mu.RUnlock()
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	n, ok := sh.counters[key]
+	if !ok {
+		n = new(atomic.Int64)
+		sh.counters[key] = n
+	}
+	return n.Add(delta)
+}
+
+// Get returns the counter of key.
+func (c *Counter[K]) Get(key K) int64 {
+	sh := c.shard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	if n, ok := sh.counters[key]; ok {
+		return n.Load()
+	}
+	return 0
+}
+
+// Delete removes key and returns its counter (and whether it existed).
+func (c *Counter[K]) Delete(key K) (int64, bool) {
+	sh := c.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	n, ok := sh.counters[key]
+	if !ok {
+		return 0, false
+	}
+	delete(sh.counters, key)
+	return n.Load(), true
+}
+
+// Len returns the number of keys.
+func (c *Counter[K]) Len() int {
+	size := 0
+	for i := range c.shards {
+		sh := &c.shards[i]
+		sh.mu.RLock()
+		size += len(sh.counters)
+		sh.mu.RUnlock()
+	}
+	return size
+}
+
+// Total returns the sum of the counters.
+func (c *Counter[K]) Total() int64 {
+	var total int64
+	for _, n := range c.All() {
+		total += n
+	}
+	return total
+}
+
+// All returns an iterator over the keys and their counter, shard by shard.
+// The read lock of a shard is held while iterating over it, so the loop
+// body can increment the existing counters but not add keys.
+func (c *Counter[K]) All() iter.Seq2[K, int64] {
+	return func(yield func(K, int64) bool) {
+		for i := range c.shards {
+			sh := &c.shards[i]
+			sh.mu.RLock()
+			for k, n := range sh.counters {
+				if !yield(k, n.Load()) {
+					sh.mu.RUnlock()
+					return
+				}
+			}
+			sh.mu.RUnlock()
+		}
+	}
+}
+
+// Keys returns an iterator over the keys (in the order of All).
+func (c *Counter[K]) Keys() iter.Seq[K] {
+	return gods.Keys(c.All())
+}
+
+// Values returns an iterator over the counts (in the order of All).
+func (c *Counter[K]) Values() iter.Seq[int64] {
+	return gods.Values(c.All())
+}
+
+// Snapshot returns a copy of the counters. The shards are copied one at a
+// time, so the snapshot isn't atomic across them.
+func (c *Counter[K]) Snapshot() map[K]int64 {
+	return maps.Collect(c.All())
+}
+
+// Reset removes all the keys and returns their counters, without losing
+// the concurrent updates: each one is either in the result or in the
+// counters left. It's the usual way to flush metrics periodically.
+func (c *Counter[K]) Reset() map[K]int64 {
+	snapshot := make(map[K]int64)
+	for i := range c.shards {
+		sh := &c.shards[i]
+		sh.mu.Lock()
+		counters := sh.counters
+		sh.counters = make(map[K]*atomic.Int64, len(counters))
+		sh.mu.Unlock()
+		for k, n := range counters {
+			snapshot[k] = n.Load()
+		}
+	}
+	return snapshot
+}
+
+// TopN returns the n keys with the highest counters, highest first (the
+// keys with the same count are in no particular order).
+func (c *Counter[K]) TopN(n int) []Entry[K] {
+	if n <= 0 {
+		return nil
+	}
+	// top is a min-heap of the n highest counters seen so far
+	top := make([]Entry[K], 0, n)
+	for k, count := range c.All() {
+		switch {
+		case len(top) < n:
+			top = append(top, Entry[K]{k, count})
+			up(top, len(top)-1)
+		case count > top[0].Count:
+			top[0] = Entry[K]{k, count}
+			down(top, 0)
+		}
+	}
+	slices.SortFunc(top, func(a, b Entry[K]) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	return top
+}
+
+func up[K comparable](h []Entry[K], i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if h[parent].Count <= h[i].Count {
+			return
+		}
+		h[i], h[parent] = h[parent], h[i]
+		i = parent
+	}
+}
+
+func down[K comparable](h []Entry[K], i int) {
+	for {
+		least, left := i, 2*i+1
+		if left < len(h) && h[left].Count < h[least].Count {
+			least = left
+		}
+		if right := left + 1; right < len(h) && h[right].Count < h[least].Count {
+			least = right
+		}
+		if least == i {
+			return
+		}
+		h[i], h[least] = h[least], h[i]
+		i = least
+	}
+}
+
+// Clear removes all the keys.
+func (c *Counter[K]) Clear() {
+	for i := range c.shards {
+		sh := &c.shards[i]
+		sh.mu.Lock()
+		clear(sh.counters)
+		sh.mu.Unlock()
+	}
+}
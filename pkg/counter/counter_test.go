@@ -0,0 +1,116 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package counter_test
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+
+	counter "github.com/pzaino/gods/pkg/counter"
+)
+
+const (
+	errExpectedX = "expected %v, got %v"
+)
+
+func TestCounter(t *testing.T) {
+	c := counter.New[string](counter.WithShards(4))
+	c.Inc("a")
+	c.Add("b", 5)
+	if v := c.Inc("a"); v != 2 {
+		t.Errorf(errExpectedX, 2, v)
+	}
+	if v := c.Add("c", -3); v != -3 {
+		t.Errorf(errExpectedX, -3, v)
+	}
+	if v := c.Get("missing"); v != 0 {
+		t.Errorf(errExpectedX, 0, v)
+	}
+	if c.Len() != 3 || c.Total() != 4 {
+		t.Errorf(errExpectedX, 4, c.Total())
+	}
+	want := map[string]int64{"a": 2, "b": 5, "c": -3}
+	if got := c.Snapshot(); !maps.Equal(got, want) {
+		t.Errorf(errExpectedX, want, got)
+	}
+	if got := slices.Sorted(c.Keys()); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf(errExpectedX, []string{"a", "b", "c"}, got)
+	}
+	if got := slices.Sorted(c.Values()); !slices.Equal(got, []int64{-3, 2, 5}) {
+		t.Errorf(errExpectedX, []int64{-3, 2, 5}, got)
+	}
+	if v, ok := c.Delete("c"); !ok || v != -3 {
+		t.Errorf(errExpectedX, -3, v)
+	}
+	if _, ok := c.Delete("c"); ok {
+		t.Errorf(errExpectedX, false, ok)
+	}
+	c.Clear()
+	if c.Len() != 0 {
+		t.Errorf(errExpectedX, 0, c.Len())
+	}
+}
+
+func TestTopN(t *testing.T) {
+	c := counter.New[int]()
+	for k := range 100 {
+		c.Add(k, int64(k%50)*10+int64(k/50)) // all distinct
+	}
+	top := c.TopN(3)
+	want := []counter.Entry[int]{{Key: 99, Count: 491}, {Key: 49, Count: 490}, {Key: 98, Count: 481}}
+	if len(top) != len(want) {
+		t.Fatalf(errExpectedX, want, top)
+	}
+	for i := range want {
+		if top[i] != want[i] {
+			t.Errorf(errExpectedX, want, top)
+		}
+	}
+	if n := len(c.TopN(1000)); n != 100 {
+		t.Errorf(errExpectedX, 100, n)
+	}
+	if top := c.TopN(0); top != nil {
+		t.Errorf(errExpectedX, nil, top)
+	}
+}
+
+func TestConcurrentAddAndReset(t *testing.T) {
+	c := counter.New[string]()
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 10_000 {
+				c.Inc(fmt.Sprintf("key-%d", (g+i)%20))
+			}
+		}()
+	}
+
+	// the flushed counters and the ones left add up to all the increments
+	var flushed int64
+	for range 50 {
+		for _, n := range c.Reset() {
+			flushed += n
+		}
+	}
+	wg.Wait()
+	if total := flushed + c.Total(); total != 80_000 {
+		t.Errorf(errExpectedX, 80_000, total)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cowslice provides a copy-on-write slice, for the rarely updated and
+// constantly read lists shared across goroutines (configuration, routing
+// tables, listeners, ...).
+//
+// Reading is a single atomic load and never blocks: the readers get an
+// immutable snapshot of the slice. The writers are serialized and publish a
+// modified copy atomically; Append reuses the spare capacity of the current
+// snapshot (the readers never see past their length), the other mutations
+// copy the whole slice. For the other containers, see gods.CopyOnWrite.
+package cowslice
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	gods "github.com/pzaino/gods"
+)
+
+const (
+	ErrIndexOutOfRange = "index out of range"
+)
+
+// Slice is a copy-on-write slice of items of type T. The zero value is an
+// empty slice ready to use.
+type Slice[T any] struct {
+	current atomic.Pointer[[]T]
+	writer  sync.Mutex
+}
+
+// New creates a new Slice with a copy of items.
+func New[T any](items ...T) *Slice[T] {
+	s := &Slice[T]{}
+	s.Store(items)
+	return s
+}
+
+// load returns the current slice, with its spare capacity
+func (s *Slice[T]) load() []T {
+	if p := s.current.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// Load returns the current snapshot of the slice. It's shared by all the
+// readers, so it must not be modified; it is not affected by the updates
+// that happen after Load returns. Appending to it allocates a new array.
+func (s *Slice[T]) Load() []T {
+	items := s.load()
+	return items[:len(items):len(items)]
+}
+
+// Len returns the number of items.
+func (s *Slice[T]) Len() int {
+	return len(s.load())
+}
+
+// IsEmpty returns true if the slice is empty.
+func (s *Slice[T]) IsEmpty() bool {
+	return len(s.load()) == 0
+}
+
+// Get returns the item at index i.
+func (s *Slice[T]) Get(i int) (T, error) {
+	items := s.load()
+	if i < 0 || i >= len(items) {
+		var zero T
+		return zero, errors.New(ErrIndexOutOfRange)
+	}
+	return items[i], nil
+}
+
+// All returns an iterator over a snapshot of the items.
+func (s *Slice[T]) All() iter.Seq[T] {
+	return slices.Values(s.Load())
+}
+
+// Enumerate returns an iterator over a snapshot of the items and their index.
+func (s *Slice[T]) Enumerate() iter.Seq2[int, T] {
+	return slices.All(s.Load())
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (s *Slice[T]) Keys() iter.Seq[int] {
+	return gods.Keys(s.Enumerate())
+}
+
+// Values returns an iterator over the items, in the same order as Enumerate
+// (it is All, under the name used by the standard library).
+func (s *Slice[T]) Values() iter.Seq[T] {
+	return s.All()
+}
+
+// ToSlice returns a copy of the items, that can be modified.
+func (s *Slice[T]) ToSlice() []T {
+	return slices.Clone(s.load())
+}
+
+// publish makes items the current slice. It must be called by a writer.
+func (s *Slice[T]) publish(items []T) {
+	s.current.Store(&items)
+}
+
+// Store replaces the items with a copy of items.
+func (s *Slice[T]) Store(items []T) {
+	s.writer.Lock()
+	defer s.writer.Unlock()
+	s.publish(slices.Clone(items))
+}
+
+// Append adds items to the end of the slice, in amortized O(len(items)).
+func (s *Slice[T]) Append(items ...T) {
+	s.writer.Lock()
+	defer s.writer.Unlock()
+	// the readers of the current snapshot don't see its spare capacity
+	s.publish(append(s.load(), items...))
+}
+
+// Set replaces the item at index i.
+func (s *Slice[T]) Set(i int, item T) error {
+	s.writer.Lock()
+	defer s.writer.Unlock()
+	items := s.load()
+	if i < 0 || i >= len(items) {
+		return errors.New(ErrIndexOutOfRange)
+	}
+	items = slices.Clone(items)
+	items[i] = item
+	s.publish(items)
+	return nil
+}
+
+// Insert inserts items at index i (0 to Len).
+func (s *Slice[T]) Insert(i int, items ...T) error {
+	s.writer.Lock()
+	defer s.writer.Unlock()
+	current := s.load()
+	if i < 0 || i > len(current) {
+		return errors.New(ErrIndexOutOfRange)
+	}
+	next := make([]T, 0, len(current)+len(items))
+	next = append(append(append(next, current[:i]...), items...), current[i:]...)
+	s.publish(next)
+	return nil
+}
+
+// Remove removes and returns the item at index i.
+func (s *Slice[T]) Remove(i int) (T, error) {
+	s.writer.Lock()
+	defer s.writer.Unlock()
+	current := s.load()
+	if i < 0 || i >= len(current) {
+		var zero T
+		return zero, errors.New(ErrIndexOutOfRange)
+	}
+	s.publish(slices.Concat(current[:i], current[i+1:]))
+	return current[i], nil
+}
+
+// RemoveFunc removes the items for which f returns true and returns how
+// many were removed. Nothing is published if none is.
+func (s *Slice[T]) RemoveFunc(f func(T) bool) int {
+	s.writer.Lock()
+	defer s.writer.Unlock()
+	current := s.load()
+	next := make([]T, 0, len(current))
+	for _, item := range current {
+		if !f(item) {
+			next = append(next, item)
+		}
+	}
+	if removed := len(current) - len(next); removed > 0 {
+		s.publish(next)
+		return removed
+	}
+	return 0
+}
+
+// Update applies fn to a copy of the items and publishes the slice it
+// returns. If fn returns an error nothing is published (so the update is all
+// or nothing) and the error is returned.
+func (s *Slice[T]) Update(fn func([]T) ([]T, error)) error {
+	s.writer.Lock()
+	defer s.writer.Unlock()
+	next, err := fn(slices.Clone(s.load()))
+	if err != nil {
+		return err
+	}
+	s.publish(next)
+	return nil
+}
+
+// Clear removes all the items.
+func (s *Slice[T]) Clear() {
+	s.writer.Lock()
+	defer s.writer.Unlock()
+	s.publish(nil)
+}
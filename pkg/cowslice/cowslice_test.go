@@ -0,0 +1,135 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cowslice_test
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	cowslice "github.com/pzaino/gods/pkg/cowslice"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+func TestMutations(t *testing.T) {
+	s := cowslice.New(1, 2, 3)
+	snapshot := s.Load()
+
+	s.Append(4, 5)
+	if err := s.Set(0, 10); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if err := s.Insert(1, 20, 30); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if v, err := s.Remove(3); err != nil || v != 2 {
+		t.Errorf(errExpectedX, 2, v)
+	}
+	if n := s.RemoveFunc(func(v int) bool { return v == 5 }); n != 1 {
+		t.Errorf(errExpectedX, 1, n)
+	}
+	if want := []int{10, 20, 30, 3, 4}; !slices.Equal(s.Load(), want) {
+		t.Errorf(errExpectedX, want, s.Load())
+	}
+	if !slices.Equal(snapshot, []int{1, 2, 3}) {
+		t.Errorf(errExpectedX, []int{1, 2, 3}, snapshot)
+	}
+
+	for _, err := range []error{s.Set(5, 0), s.Insert(6), func() error { _, err := s.Remove(-1); return err }()} {
+		if err == nil || err.Error() != cowslice.ErrIndexOutOfRange {
+			t.Errorf(errExpectedX, cowslice.ErrIndexOutOfRange, err)
+		}
+	}
+	if v, err := s.Get(4); err != nil || v != 4 {
+		t.Errorf(errExpectedX, 4, v)
+	}
+
+	boom := errors.New("boom")
+	if err := s.Update(func(items []int) ([]int, error) { return items[:0], boom }); err != boom {
+		t.Errorf(errExpectedX, boom, err)
+	}
+	_ = s.Update(func(items []int) ([]int, error) { return slices.Sorted(slices.Values(items)), nil })
+	if want := []int{3, 4, 10, 20, 30}; !slices.Equal(slices.Collect(s.All()), want) {
+		t.Errorf(errExpectedX, want, s.Load())
+	}
+	if want := []int{0, 1, 2, 3, 4}; !slices.Equal(slices.Collect(s.Keys()), want) {
+		t.Errorf(errExpectedX, want, slices.Collect(s.Keys()))
+	}
+	if !slices.Equal(slices.Collect(s.Values()), s.Load()) {
+		t.Errorf(errExpectedX, s.Load(), slices.Collect(s.Values()))
+	}
+	s.Clear()
+	if !s.IsEmpty() || s.Len() != 0 {
+		t.Errorf(errExpectedX, 0, s.Len())
+	}
+}
+
+func TestSnapshotsAreIsolated(t *testing.T) {
+	var s cowslice.Slice[int]
+	s.Append(1)
+	a := s.Load()
+	s.Append(2) // may reuse the spare capacity of a
+	b := s.Load()
+	s.Append(3)
+
+	// appending to a snapshot must not overwrite the published items
+	_ = append(a, 100)
+	_ = append(b, 200)
+	if want := []int{1, 2, 3}; !slices.Equal(s.Load(), want) {
+		t.Errorf(errExpectedX, want, s.Load())
+	}
+	if !slices.Equal(a, []int{1}) || !slices.Equal(b, []int{1, 2}) {
+		t.Errorf(errExpectedX, []int{1, 2}, b)
+	}
+}
+
+func TestConcurrentReaders(t *testing.T) {
+	s := cowslice.New[int]()
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// every snapshot holds 0..n-1
+				for i, v := range s.Enumerate() {
+					if v != i {
+						t.Errorf(errExpectedX, i, v)
+						return
+					}
+				}
+			}
+		}()
+	}
+	for i := range 1000 {
+		s.Append(i)
+	}
+	close(stop)
+	wg.Wait()
+	if s.Len() != 1000 {
+		t.Errorf(errExpectedX, 1000, s.Len())
+	}
+}
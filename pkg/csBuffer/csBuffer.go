@@ -16,35 +16,62 @@
 package csBuffer
 
 import (
-	"sync"
-
+	"context"
+	"io"
+	"iter"
+	"log/slog"
+	"sync/atomic"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
 	buffer "github.com/pzaino/gods/pkg/buffer"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 // ConcurrentBuffer is a thread-safe wrapper around the Buffer type.
 type ConcurrentBuffer[T comparable] struct {
-	b  *buffer.Buffer[T]
-	mu sync.RWMutex
+	b    *buffer.Buffer[T]
+	mu   gods.RWLocker
+	opts []gods.Option
+	size atomic.Uint64 // published on every write unlock, read without locking
 }
 
 // New creates a new ConcurrentBuffer.
-func New[T comparable]() *ConcurrentBuffer[T] {
-	return &ConcurrentBuffer[T]{b: buffer.New[T]()}
+// The options select the locking strategy (the default is a sync.RWMutex).
+func New[T comparable](opts ...gods.Option) *ConcurrentBuffer[T] {
+	return newConcurrentBuffer(buffer.New[T](), opts)
 }
 
 // NewWithCapacity creates a new ConcurrentBuffer with the given capacity.
-func NewWithCapacity[T comparable](capacity uint64) *ConcurrentBuffer[T] {
-	return &ConcurrentBuffer[T]{b: buffer.NewWithCapacity[T](capacity)}
+func NewWithCapacity[T comparable](capacity uint64, opts ...gods.Option) *ConcurrentBuffer[T] {
+	return newConcurrentBuffer(buffer.NewWithCapacity[T](capacity), opts)
 }
 
 // NewWithSize creates a new ConcurrentBuffer with the given size.
-func NewWithSize[T comparable](size uint64) *ConcurrentBuffer[T] {
-	return &ConcurrentBuffer[T]{b: buffer.NewWithSize[T](size)}
+func NewWithSize[T comparable](size uint64, opts ...gods.Option) *ConcurrentBuffer[T] {
+	return newConcurrentBuffer(buffer.NewWithSize[T](size), opts)
 }
 
 // NewWithSizeAndCapacity creates a new ConcurrentBuffer with the given size and capacity.
-func NewWithSizeAndCapacity[T comparable](size, capacity uint64) *ConcurrentBuffer[T] {
-	return &ConcurrentBuffer[T]{b: buffer.NewWithSizeAndCapacity[T](size, capacity)}
+func NewWithSizeAndCapacity[T comparable](size, capacity uint64, opts ...gods.Option) *ConcurrentBuffer[T] {
+	return newConcurrentBuffer(buffer.NewWithSizeAndCapacity[T](size, capacity), opts)
+}
+
+// newConcurrentBuffer wraps b in a ConcurrentBuffer using the locking strategy selected by opts
+func newConcurrentBuffer[T comparable](b *buffer.Buffer[T], opts []gods.Option) *ConcurrentBuffer[T] {
+	cb := &ConcurrentBuffer[T]{}
+	cb.init(b, opts)
+	return cb
+}
+
+// init sets up cb to wrap b
+func (cb *ConcurrentBuffer[T]) init(b *buffer.Buffer[T], opts []gods.Option) {
+	cb.b, cb.opts = b, opts
+	cb.size.Store(b.Size())
+	cb.mu = gods.OnUnlock(gods.NewLocker(opts...), func() {
+		cb.size.Store(cb.b.Size())
+	})
+	gods.ObserveSize(cb.mu, cb.size.Load)
 }
 
 // Append adds an element to the end of the buffer.
@@ -54,11 +81,19 @@ func (cb *ConcurrentBuffer[T]) Append(elem T) error {
 	return cb.b.Append(elem)
 }
 
-// InsertAt adds an element at the given index.
-func (cb *ConcurrentBuffer[T]) InsertAt(index uint64, elem T) error {
+// InsertAt inserts the elements at the given index.
+func (cb *ConcurrentBuffer[T]) InsertAt(index uint64, elems ...T) error {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
-	return cb.b.InsertAt(index, elem)
+	return cb.b.InsertAt(index, elems...)
+}
+
+// DeleteRange removes the elements from the index from up to (excluding) the
+// index to.
+func (cb *ConcurrentBuffer[T]) DeleteRange(from, to uint64) error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.b.DeleteRange(from, to)
 }
 
 // Put replaces the element at the given index.
@@ -105,9 +140,53 @@ func (cb *ConcurrentBuffer[T]) Values() []T {
 
 // Size returns the number of elements in the buffer.
 func (cb *ConcurrentBuffer[T]) Size() uint64 {
+	return cb.size.Load()
+}
+
+// MemoryUsage returns an estimate of the bytes held by the buffer (see the
+// gods package), adding the memory returned by extra for every item.
+func (cb *ConcurrentBuffer[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return uint64(unsafe.Sizeof(*cb)) + cb.b.MemoryUsage(extra...)
+}
+
+// Validate checks the invariants of the buffer and its published size (see
+// gods.Validator).
+func (cb *ConcurrentBuffer[T]) Validate() error {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	if err := cb.b.Validate(); err != nil {
+		return err
+	}
+	if size := cb.size.Load(); size != cb.b.Size() {
+		return gods.Invariantf("published size is %d but the buffer holds %d items", size, cb.b.Size())
+	}
+	return nil
+}
+
+// Dump writes a rendering of the wrapped structure to w, holding the read
+// lock, for debugging.
+func (cb *ConcurrentBuffer[T]) Dump(w io.Writer) error {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
-	return cb.b.Size()
+	return cb.b.Dump(w)
+}
+
+// Stats returns the statistics of the buffer: its peak size, how many times
+// its backing array was reallocated to grow and, if it was created with
+// gods.WithLatencyHistograms, the latency histograms of its operations.
+func (cb *ConcurrentBuffer[T]) Stats() gods.Stats {
+	cb.mu.RLock()
+	stats := cb.b.Stats()
+	cb.mu.RUnlock()
+	stats.Latency = gods.LockLatency(cb.mu)
+	return stats
+}
+
+// ResetLatency clears the latency histograms returned by Stats.
+func (cb *ConcurrentBuffer[T]) ResetLatency() {
+	gods.ResetLockLatency(cb.mu)
 }
 
 // Capacity returns the capacity of the buffer.
@@ -133,9 +212,7 @@ func (cb *ConcurrentBuffer[T]) Contains(value T) bool {
 
 // IsEmpty returns true if the buffer is empty.
 func (cb *ConcurrentBuffer[T]) IsEmpty() bool {
-	cb.mu.RLock()
-	defer cb.mu.RUnlock()
-	return cb.b.IsEmpty()
+	return cb.size.Load() == 0
 }
 
 // IsFull returns true if the buffer is full.
@@ -161,11 +238,15 @@ func (cb *ConcurrentBuffer[T]) Reverse() {
 
 // Equals returns true if the buffer is equal to another buffer.
 func (cb *ConcurrentBuffer[T]) Equals(other *ConcurrentBuffer[T]) bool {
-	cb.mu.RLock()
-	defer cb.mu.RUnlock()
-	other.mu.RLock()
-	defer other.mu.RUnlock()
-	return cb.b.Equals(other.b)
+	if cb == other {
+		return true
+	}
+	var equal bool
+	_ = gods.AtomicallyRead(func() error {
+		equal = cb.b.Equals(other.b)
+		return nil
+	}, cb, other)
+	return equal
 }
 
 // Copy returns a new buffer with copied elements.
@@ -173,16 +254,20 @@ func (cb *ConcurrentBuffer[T]) Copy() *ConcurrentBuffer[T] {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
 	newBuffer := cb.b.Copy()
-	return &ConcurrentBuffer[T]{b: newBuffer}
+	return newConcurrentBuffer(newBuffer, cb.opts)
 }
 
 // Merge appends all elements from another buffer.
+// Both buffers are locked for writing (other is cleared), in a canonical
+// order, so a.Merge(b) and b.Merge(a) can run concurrently without deadlocks.
 func (cb *ConcurrentBuffer[T]) Merge(other *ConcurrentBuffer[T]) {
-	cb.mu.Lock()
-	defer cb.mu.Unlock()
-	other.mu.RLock()
-	defer other.mu.RUnlock()
-	cb.b.Merge(other.b)
+	if cb == other {
+		return
+	}
+	_ = gods.Atomically(func() error {
+		cb.b.Merge(other.b)
+		return nil
+	}, cb, other)
 }
 
 // PopN removes and returns the last n elements.
@@ -242,7 +327,7 @@ func (cb *ConcurrentBuffer[T]) Map(fn func(T) T) (*ConcurrentBuffer[T], error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ConcurrentBuffer[T]{b: mappedBuffer}, nil
+	return newConcurrentBuffer(mappedBuffer, cb.opts), nil
 }
 
 // Reduce reduces the buffer to a single value.
@@ -301,11 +386,30 @@ func (cb *ConcurrentBuffer[T]) FindIndex(predicate func(T) bool) (uint64, error)
 	return cb.b.FindIndex(predicate)
 }
 
-// FindLast returns the last element that matches the predicate.
+// FindLast returns a pointer to a copy of the last element that matches the
+// predicate (a pointer into the buffer would be written by other goroutines
+// once the lock is released).
+//
+// Deprecated: use FindLastValue, which doesn't allocate the copy.
 func (cb *ConcurrentBuffer[T]) FindLast(predicate func(T) bool) (*T, error) {
+	v, err := cb.FindLastValue(predicate)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// FindLastValue returns a copy of the last element that matches the
+// predicate.
+func (cb *ConcurrentBuffer[T]) FindLastValue(predicate func(T) bool) (T, error) {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
-	return cb.b.FindLast(predicate)
+	p, err := cb.b.FindLast(predicate)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return *p, nil
 }
 
 // FindLastIndex returns the index of the last element that matches the predicate.
@@ -320,7 +424,7 @@ func (cb *ConcurrentBuffer[T]) FindAll(predicate func(T) bool) *ConcurrentBuffer
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
 	newBuffer := cb.b.FindAll(predicate)
-	return &ConcurrentBuffer[T]{b: newBuffer}
+	return newConcurrentBuffer(newBuffer, cb.opts)
 }
 
 // FindIndices returns the indices of all elements that match the predicate.
@@ -337,6 +441,92 @@ func (cb *ConcurrentBuffer[T]) LastIndexOf(value T) (uint64, error) {
 	return cb.b.LastIndexOf(value)
 }
 
+// IndexOf returns the index of the first element with the given value.
+func (cb *ConcurrentBuffer[T]) IndexOf(value T) (uint64, error) {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.b.IndexOf(value)
+}
+
+// Index returns the index of the first occurrence of sub in the buffer.
+func (cb *ConcurrentBuffer[T]) Index(sub []T) (uint64, error) {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.b.Index(sub)
+}
+
+// LastIndex returns the index of the last occurrence of sub in the buffer.
+func (cb *ConcurrentBuffer[T]) LastIndex(sub []T) (uint64, error) {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.b.LastIndex(sub)
+}
+
+// IndexBytes is Index for a byte buffer (see buffer.IndexBytes).
+func IndexBytes(cb *ConcurrentBuffer[byte], sub []byte) (uint64, error) {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return buffer.IndexBytes(cb.b, sub)
+}
+
+// LastIndexBytes is LastIndex for a byte buffer (see buffer.LastIndexBytes).
+func LastIndexBytes(cb *ConcurrentBuffer[byte], sub []byte) (uint64, error) {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return buffer.LastIndexBytes(cb.b, sub)
+}
+
+// AppendAll appends all the items to the end of the buffer, under a single
+// lock and with a single capacity check: if the items don't fit, none of them
+// is appended.
+func (cb *ConcurrentBuffer[T]) AppendAll(items []T) error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.b.PushN(items...)
+}
+
+// WriteFrom reads r until EOF and appends the data read to a byte buffer as
+// a single batch (see AppendAll). The reader is drained before taking the
+// lock, so a slow reader doesn't block the other users of the buffer. It
+// returns the number of bytes appended; if r fails the bytes read before the
+// error are still appended.
+func WriteFrom(cb *ConcurrentBuffer[byte], r io.Reader) (int64, error) {
+	data, rerr := io.ReadAll(r)
+	if len(data) > 0 {
+		if err := cb.AppendAll(data); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(data)), rerr
+}
+
+// ReadLine removes and returns the first line of a byte buffer (see
+// buffer.ReadLine).
+func ReadLine(cb *ConcurrentBuffer[byte]) ([]byte, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return buffer.ReadLine(cb.b)
+}
+
+// Lines returns an iterator over the lines of a snapshot of a byte buffer
+// (see buffer.Lines).
+func Lines(cb *ConcurrentBuffer[byte]) iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		cb.mu.RLock()
+		b := cb.b.Copy()
+		cb.mu.RUnlock()
+		buffer.Lines(b)(yield)
+	}
+}
+
+// AppendLine appends line and a "\n" terminator to a byte buffer (see
+// buffer.AppendLine).
+func AppendLine(cb *ConcurrentBuffer[byte], line []byte) error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return buffer.AppendLine(cb.b, line)
+}
+
 // Blit combines/overwrites the values in the buffer with the values of another buffer using a function.
 func (cb *ConcurrentBuffer[T]) Blit(other *ConcurrentBuffer[T], f func(T, T) T) error {
 	cb.mu.Lock()
@@ -345,3 +535,209 @@ func (cb *ConcurrentBuffer[T]) Blit(other *ConcurrentBuffer[T], f func(T, T) T)
 	defer other.mu.RUnlock()
 	return cb.b.Blit(other.b, f)
 }
+
+// Diff returns the edits turning the buffer into other (see buffer.Diff).
+// Both buffers are read-locked while the edits are computed.
+func (cb *ConcurrentBuffer[T]) Diff(other *ConcurrentBuffer[T]) []buffer.Edit[T] {
+	if cb == other {
+		return nil
+	}
+	var edits []buffer.Edit[T]
+	_ = gods.AtomicallyRead(func() error {
+		edits = cb.b.Diff(other.b)
+		return nil
+	}, cb, other)
+	return edits
+}
+
+// ApplyPatch applies the edits returned by Diff (see buffer.ApplyPatch).
+func (cb *ConcurrentBuffer[T]) ApplyPatch(edits []buffer.Edit[T]) error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.b.ApplyPatch(edits)
+}
+
+// ToSlice returns a copy of the elements in the buffer.
+func (cb *ConcurrentBuffer[T]) ToSlice() []T {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	if cb.b.IsEmpty() {
+		return nil
+	}
+	items := make([]T, cb.b.Size())
+	copy(items, cb.b.ToSlice())
+	return items
+}
+
+// Freeze returns an immutable copy of the buffer, taken under the read lock:
+// it can be read without locking (see gods.Frozen).
+func (cb *ConcurrentBuffer[T]) Freeze() *gods.Frozen[T] {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.b.Freeze()
+}
+
+// Iter returns an iterator over the elements in the buffer.
+// The iterator works on a snapshot of the buffer taken when the iteration starts,
+// so the buffer can be safely modified (even from within the loop) while iterating.
+func (cb *ConcurrentBuffer[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, elem := range cb.ToSlice() {
+			if !yield(elem) {
+				return
+			}
+		}
+	}
+}
+
+// Iterator returns a bidirectional iterator over a snapshot of the buffer.
+func (cb *ConcurrentBuffer[T]) Iterator() iterator.BidirectionalIterator[T] {
+	return iterator.FromSlice(cb.ToSlice())
+}
+
+// Enumerate returns an iterator over the index and the value of the elements in the buffer.
+// Like Iter, it works on a snapshot of the buffer taken when the iteration starts.
+func (cb *ConcurrentBuffer[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		for i, elem := range cb.ToSlice() {
+			if !yield(uint64(i), elem) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (cb *ConcurrentBuffer[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(cb.Enumerate())
+}
+
+// Encode writes a snapshot of the buffer to w using the given codec
+func (cb *ConcurrentBuffer[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeSlice(w, codec, cb.ToSlice())
+}
+
+// Decode replaces the content of the buffer with the elements read from r using the given codec.
+// The data is decoded before acquiring the lock, so readers are blocked only while the buffer is rebuilt.
+// A zero value (as allocated by the decoders for pointer fields) is
+// initialized with the default options.
+func (cb *ConcurrentBuffer[T]) Decode(r io.Reader, codec gods.Codec) error {
+	items, err := gods.DecodeSlice[T](r, codec)
+	if err != nil {
+		return err
+	}
+	if cb.mu == nil {
+		cb.init(buffer.New[T](), nil)
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.b.Clear()
+	for _, item := range items {
+		if err := cb.b.Append(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeStream writes the buffer to w incrementally, in chunks encoded with the
+// given codec (see gods.EncodeStream), without copying its elements first.
+// Writers are blocked until the whole buffer has been written.
+func (cb *ConcurrentBuffer[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.b.EncodeStream(w, codec)
+}
+
+// DecodeStream replaces the content of the buffer with the elements of a
+// stream written by EncodeStream. Unlike Decode, the elements are added as
+// they are read, so the lock is held until the whole stream has been read.
+func (cb *ConcurrentBuffer[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	if cb.mu == nil {
+		cb.init(buffer.New[T](), nil)
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.b.DecodeStream(r, codec)
+}
+
+// MarshalText implements encoding.TextMarshaler: the buffer is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (cb *ConcurrentBuffer[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(cb)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (cb *ConcurrentBuffer[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(cb, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (cb *ConcurrentBuffer[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(cb)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (cb *ConcurrentBuffer[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(cb, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (cb *ConcurrentBuffer[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(cb)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (cb *ConcurrentBuffer[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(cb, data)
+}
+
+// FilterCtx is like Filter but it can be aborted through the context: it
+// returns ctx.Err() as soon as the context is done, leaving the buffer unchanged.
+func (cb *ConcurrentBuffer[T]) FilterCtx(ctx context.Context, predicate func(T) bool) (err error) {
+	ctx, end := gods.Trace(ctx, "csBuffer.FilterCtx", slog.Uint64("size", cb.Size()))
+	defer func() { end(err) }()
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.b.FilterCtx(ctx, predicate)
+}
+
+// MapCtx is like Map but it returns ctx.Err() as soon as the context is done.
+func (cb *ConcurrentBuffer[T]) MapCtx(ctx context.Context, fn func(T) T) (_ *ConcurrentBuffer[T], err error) {
+	ctx, end := gods.Trace(ctx, "csBuffer.MapCtx", slog.Uint64("size", cb.Size()))
+	defer func() { end(err) }()
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	mappedBuffer, err := cb.b.MapCtx(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	return newConcurrentBuffer(mappedBuffer, cb.opts), nil
+}
+
+// ForEachCtx is like ForEach but it returns ctx.Err() as soon as the context is done.
+func (cb *ConcurrentBuffer[T]) ForEachCtx(ctx context.Context, fn func(*T) error) (err error) {
+	ctx, end := gods.Trace(ctx, "csBuffer.ForEachCtx", slog.Uint64("size", cb.Size()))
+	defer func() { end(err) }()
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.b.ForEachCtx(ctx, fn)
+}
+
+// ContentionStats returns the lock statistics of the ConcurrentBuffer. They are
+// collected only if it was created with the gods.WithContentionStats option.
+func (cb *ConcurrentBuffer[T]) ContentionStats() gods.ContentionStats {
+	return gods.LockContentionStats(cb.mu)
+}
+
+// Locker returns the lock of the ConcurrentBuffer (see gods.Atomically).
+func (cb *ConcurrentBuffer[T]) Locker() gods.RWLocker {
+	return cb.mu
+}
+
+// Unsafe returns the underlying (non concurrency-safe) buffer. It must only
+// be used while the lock is held, typically inside gods.Atomically.
+func (cb *ConcurrentBuffer[T]) Unsafe() *buffer.Buffer[T] {
+	return cb.b
+}
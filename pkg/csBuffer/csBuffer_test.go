@@ -2,10 +2,16 @@
 package csBuffer_test
 
 import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 
+	gods "github.com/pzaino/gods"
 	buffer "github.com/pzaino/gods/pkg/csBuffer"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 const (
@@ -1350,3 +1356,268 @@ func TestConcurrentWidthSizeAndCapacity(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestConcurrentBufferToSliceAndIter(t *testing.T) {
+	cb := buffer.New[int]()
+	if cb.ToSlice() != nil {
+		t.Errorf("Expected nil slice, got %v", cb.ToSlice())
+	}
+	for i := 1; i <= 3; i++ {
+		_ = cb.Append(i)
+	}
+
+	items := cb.ToSlice()
+	items[0] = 100
+	if v, _ := cb.Get(0); v != 1 {
+		t.Errorf("Expected ToSlice to return a copy, got %v", v)
+	}
+
+	var got []int
+	for v := range cb.Iter() {
+		// modifying the buffer while iterating must not deadlock
+		_ = cb.Append(v * 10)
+		got = append(got, v)
+	}
+	if !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, got)
+	}
+}
+
+func TestConcurrentBufferIterator(t *testing.T) {
+	cb := buffer.New[int]()
+	for i := 1; i <= 3; i++ {
+		_ = cb.Append(i)
+	}
+	it := cb.Iterator()
+	_ = cb.Append(4) // not part of the snapshot
+	if got := iterator.ToSlice[int](it); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, got)
+	}
+}
+
+func TestConcurrentBufferEnumerate(t *testing.T) {
+	cb := buffer.New[int]()
+	for i := 1; i <= 3; i++ {
+		_ = cb.Append(i)
+	}
+	var indexes []uint64
+	var values []int
+	for i, v := range cb.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0, 1, 2}) {
+		t.Errorf("Expected %v, got %v", []uint64{0, 1, 2}, indexes)
+	}
+	if !slices.Equal(values, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, values)
+	}
+	if got := slices.Collect(cb.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+}
+
+func TestConcurrentBufferCtxVariants(t *testing.T) {
+	cb := buffer.New[int]()
+	for i := 1; i <= 4; i++ {
+		_ = cb.Append(i)
+	}
+	if err := cb.FilterCtx(context.Background(), func(v int) bool { return v%2 == 0 }); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := cb.ToSlice(); !slices.Equal(got, []int{2, 4}) {
+		t.Errorf("Expected %v, got %v", []int{2, 4}, got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cb.MapCtx(ctx, func(v int) int { return v }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	if err := cb.ForEachCtx(ctx, func(*int) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestConcurrentBufferLockStrategies(t *testing.T) {
+	for _, opt := range []gods.Option{gods.WithRWMutex(), gods.WithMutex(), gods.WithSharding(4)} {
+		cb := buffer.New[int](opt)
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					_ = cb.Append(i)
+					_ = cb.Size()
+					_ = cb.ToSlice()
+				}
+			}()
+		}
+		wg.Wait()
+		if cb.Size() != 400 {
+			t.Errorf("Expected size %d, got %d", 400, cb.Size())
+		}
+		if c := cb.Copy(); c.Size() != 400 {
+			t.Errorf("Expected size %d, got %d", 400, c.Size())
+		}
+	}
+}
+
+func TestConcurrentBufferContentionStats(t *testing.T) {
+	cs := buffer.New[int](gods.WithContentionStats())
+	_ = cs.Append(1)
+	_ = cs.ToSlice()
+	stats := cs.ContentionStats()
+	if stats.Acquisitions != 1 || stats.ReadAcquisitions != 1 {
+		t.Errorf("Expected 1 write and 1 read, got %+v", stats)
+	}
+}
+
+func TestIndexBytes(t *testing.T) {
+	cb := buffer.New[byte]()
+	for _, c := range []byte("hello, hello") {
+		if err := cb.Append(c); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+	}
+	if i, err := buffer.IndexBytes(cb, []byte("llo")); err != nil || i != 2 {
+		t.Errorf(errExpectedVal, 2, i)
+	}
+	if i, err := buffer.LastIndexBytes(cb, []byte("llo")); err != nil || i != 9 {
+		t.Errorf(errExpectedVal, 9, i)
+	}
+	if i, err := cb.Index([]byte(", h")); err != nil || i != 5 {
+		t.Errorf(errExpectedVal, 5, i)
+	}
+	if i, err := cb.IndexOf('o'); err != nil || i != 4 {
+		t.Errorf(errExpectedVal, 4, i)
+	}
+}
+
+func TestAppendAll(t *testing.T) {
+	cb := buffer.NewWithCapacity[int](4)
+	if err := cb.AppendAll([]int{1, 2, 3}); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	// the batch doesn't fit, so nothing is appended
+	if err := cb.AppendAll([]int{4, 5}); err == nil {
+		t.Errorf("expected an overflow error")
+	}
+	if cb.Size() != 3 {
+		t.Errorf(errExpectedSize, 3, cb.Size())
+	}
+}
+
+func TestWriteFrom(t *testing.T) {
+	cb := buffer.New[byte]()
+	n, err := buffer.WriteFrom(cb, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if n != 5 || cb.Size() != 5 {
+		t.Errorf(errExpectedSize, 5, cb.Size())
+	}
+	if i, err := buffer.IndexBytes(cb, []byte("llo")); err != nil || i != 2 {
+		t.Errorf(errExpectedVal, 2, i)
+	}
+
+	small := buffer.NewWithCapacity[byte](3)
+	if n, err := buffer.WriteFrom(small, strings.NewReader("hello")); err == nil || n != 0 {
+		t.Errorf("expected an overflow error, got %d bytes appended", n)
+	}
+}
+
+func TestDiffAndApplyPatch(t *testing.T) {
+	a, b := buffer.New[int](), buffer.New[int]()
+	if err := a.AppendAll([]int{1, 2, 3, 4}); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if err := b.AppendAll([]int{2, 3, 5, 4, 6}); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if err := a.ApplyPatch(a.Diff(b)); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if !a.Equals(b) {
+		t.Errorf("expected %v, got %v", b.ToSlice(), a.ToSlice())
+	}
+	if edits := a.Diff(a); edits != nil {
+		t.Errorf("expected no edits, got %v", edits)
+	}
+}
+
+func TestLines(t *testing.T) {
+	cb := buffer.New[byte]()
+	if err := buffer.AppendLine(cb, []byte("one")); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if _, err := buffer.WriteFrom(cb, strings.NewReader("two\r\nthree")); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	var lines []string
+	for line := range buffer.Lines(cb) {
+		lines = append(lines, string(line))
+	}
+	if !slices.Equal(lines, []string{"one", "two", "three"}) {
+		t.Errorf("expected [one two three], got %v", lines)
+	}
+	if line, err := buffer.ReadLine(cb); err != nil || string(line) != "one" {
+		t.Errorf("expected one, got %q (%v)", line, err)
+	}
+	if cb.Size() != 10 {
+		t.Errorf(errExpectedSize, 10, cb.Size())
+	}
+}
+
+// TestFindLastValue tests that FindLast and FindLastValue return copies.
+func TestFindLastValue(t *testing.T) {
+	cb := buffer.New[int]()
+	for i := range 5 {
+		_ = cb.Append(i)
+	}
+	v, err := cb.FindLastValue(func(v int) bool { return v < 3 })
+	if err != nil || v != 2 {
+		t.Errorf(errExpectedVal, 2, v)
+	}
+	p, err := cb.FindLast(func(v int) bool { return v < 3 })
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	*p = 100
+	if got, _ := cb.Get(2); got != 2 {
+		t.Errorf(errExpectedVal, 2, got)
+	}
+	if _, err := cb.FindLastValue(func(v int) bool { return v > 10 }); err == nil {
+		t.Errorf(errUnexpectedErr, err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	cb := buffer.New[int]()
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				if err := cb.Append(i); err != nil {
+					t.Errorf(errUnexpectedErr, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	cb.Clear()
+	growth := cb.Stats().Growth
+	if growth.PeakSize != 100 {
+		t.Errorf(errExpectedVal, 100, growth.PeakSize)
+	}
+	// the backing array doubles while it is small
+	if growth.Resizes < 7 || growth.Resizes > 10 {
+		t.Errorf("expected 7 to 10 resizes, got %d", growth.Resizes)
+	}
+	if growth.PeakCapacity < 100 || growth.Allocated < growth.PeakCapacity {
+		t.Errorf("expected a peak capacity >= 100, got %d", growth.PeakCapacity)
+	}
+}
@@ -16,20 +16,47 @@
 package csdlinkList
 
 import (
-	"sync"
-
+	"database/sql/driver"
+	"io"
+	"iter"
+	"sync/atomic"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
 	dlinkList "github.com/pzaino/gods/pkg/dlinkList"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 // CSDLinkList is a concurrency-safe doubly linked list.
 type CSDLinkList[T comparable] struct {
-	mu sync.RWMutex
-	l  *dlinkList.DLinkList[T]
+	mu   gods.RWLocker
+	l    *dlinkList.DLinkList[T]
+	opts []gods.Option
+	size atomic.Uint64 // published on every write unlock, read without locking
 }
 
 // New creates a new concurrency-safe doubly linked list.
-func New[T comparable]() *CSDLinkList[T] {
-	return &CSDLinkList[T]{l: dlinkList.New[T]()}
+// The options select the locking strategy (the default is a sync.RWMutex).
+func New[T comparable](opts ...gods.Option) *CSDLinkList[T] {
+	return newCSDLinkList(dlinkList.New[T](opts...), opts)
+}
+
+// newCSDLinkList wraps l in a CSDLinkList using the locking strategy selected by opts
+func newCSDLinkList[T comparable](l *dlinkList.DLinkList[T], opts []gods.Option) *CSDLinkList[T] {
+	cs := &CSDLinkList[T]{}
+	cs.init(l, opts)
+	return cs
+}
+
+// init sets up cs to wrap l
+func (cs *CSDLinkList[T]) init(l *dlinkList.DLinkList[T], opts []gods.Option) {
+	cs.l, cs.opts = l, opts
+	cs.size.Store(l.Size())
+	cs.mu = gods.OnUnlock(gods.NewLocker(opts...), func() {
+		cs.size.Store(cs.l.Size())
+	})
+	gods.ObserveSize(cs.mu, cs.size.Load)
 }
 
 // Append adds a new node to the end of the doubly linked list.
@@ -126,6 +153,14 @@ func (cs *CSDLinkList[T]) ToSlice() []T {
 	return cs.l.ToSlice()
 }
 
+// Freeze returns an immutable copy of the list, taken under the read lock:
+// it can be read without locking (see gods.Frozen).
+func (cs *CSDLinkList[T]) Freeze() *gods.Frozen[T] {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.l.Freeze()
+}
+
 // ToSliceReverse converts the doubly linked list to a slice in reverse order.
 func (cs *CSDLinkList[T]) ToSliceReverse() []T {
 	cs.mu.RLock()
@@ -154,46 +189,145 @@ func (cs *CSDLinkList[T]) Reverse() {
 	cs.l.Reverse()
 }
 
+// Rotate rotates the doubly linked list left by n positions (see
+// dlinkList.Rotate).
+func (cs *CSDLinkList[T]) Rotate(n int) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.l.Rotate(n)
+}
+
 // Find returns the first node with the given value.
+// The node belongs to the list, so its links can be followed, but reading it
+// once the call returns races with the writers of the list.
+//
+// Deprecated: use FindValue, which copies the item under the lock.
 func (cs *CSDLinkList[T]) Find(value T) (*dlinkList.Node[T], error) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.l.Find(value)
 }
 
-// IsEmpty returns true if the doubly linked list is empty.
-func (cs *CSDLinkList[T]) IsEmpty() bool {
+// FindValue returns a copy of the first item equal to value.
+func (cs *CSDLinkList[T]) FindValue(value T) (T, error) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return cs.l.IsEmpty()
+	return valueOf(cs.l.Find(value))
+}
+
+// IsEmpty returns true if the doubly linked list is empty.
+func (cs *CSDLinkList[T]) IsEmpty() bool {
+	return cs.size.Load() == 0
 }
 
 // GetAt returns the node at the given index.
+// The node belongs to the list, so its links can be followed, but reading it
+// once the call returns races with the writers of the list.
+//
+// Deprecated: use GetAtValue, which copies the item under the lock.
 func (cs *CSDLinkList[T]) GetAt(index uint64) (*dlinkList.Node[T], error) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.l.GetAt(index)
 }
 
-// GetLast returns the last node in the doubly linked list.
+// GetAtValue returns a copy of the item at the given index.
+func (cs *CSDLinkList[T]) GetAtValue(index uint64) (T, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return valueOf(cs.l.GetAt(index))
+}
+
+// GetLast returns the last node in the doubly linked list, or nil if the list is empty.
+// The node belongs to the list, so its links can be followed, but reading it
+// once the call returns races with the writers of the list.
+//
+// Deprecated: use GetLastValue, which copies the item under the lock.
 func (cs *CSDLinkList[T]) GetLast() *dlinkList.Node[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.l.GetLast()
 }
 
-// GetFirst returns the first node in the doubly linked list.
+// GetLastValue returns a copy of the last item in the doubly linked list, or false if
+// the list is empty.
+func (cs *CSDLinkList[T]) GetLastValue() (T, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if n := cs.l.GetLast(); n != nil {
+		return n.Value, true
+	}
+	var zero T
+	return zero, false
+}
+
+// GetFirst returns the first node in the doubly linked list, or nil if the list is empty.
+// The node belongs to the list, so its links can be followed, but reading it
+// once the call returns races with the writers of the list.
+//
+// Deprecated: use GetFirstValue, which copies the item under the lock.
 func (cs *CSDLinkList[T]) GetFirst() *dlinkList.Node[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.l.GetFirst()
 }
 
+// GetFirstValue returns a copy of the first item in the doubly linked list, or false if
+// the list is empty.
+func (cs *CSDLinkList[T]) GetFirstValue() (T, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if n := cs.l.GetFirst(); n != nil {
+		return n.Value, true
+	}
+	var zero T
+	return zero, false
+}
+
 // Size returns the number of nodes in the doubly linked list.
 func (cs *CSDLinkList[T]) Size() uint64 {
+	return cs.size.Load()
+}
+
+// MemoryUsage returns an estimate of the bytes held by the list (see the
+// gods package), adding the memory returned by extra for every item.
+func (cs *CSDLinkList[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return cs.l.Size()
+	return uint64(unsafe.Sizeof(*cs)) + cs.l.MemoryUsage(extra...)
+}
+
+// Stats returns the statistics of the list: if it was created with
+// gods.WithLatencyHistograms, the latency histograms of its operations.
+func (cs *CSDLinkList[T]) Stats() gods.Stats {
+	return gods.Stats{Latency: gods.LockLatency(cs.mu)}
+}
+
+// ResetLatency clears the latency histograms returned by Stats.
+func (cs *CSDLinkList[T]) ResetLatency() {
+	gods.ResetLockLatency(cs.mu)
+}
+
+// Validate checks the invariants of the list and its published size (see
+// gods.Validator).
+func (cs *CSDLinkList[T]) Validate() error {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if err := cs.l.Validate(); err != nil {
+		return err
+	}
+	if size := cs.size.Load(); size != cs.l.Size() {
+		return gods.Invariantf("published size is %d but the list holds %d items", size, cs.l.Size())
+	}
+	return nil
+}
+
+// Dump writes a rendering of the wrapped structure to w, holding the read
+// lock, for debugging.
+func (cs *CSDLinkList[T]) Dump(w io.Writer) error {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.l.Dump(w)
 }
 
 // Clear removes all nodes from the doubly linked list.
@@ -287,25 +421,34 @@ func (cs *CSDLinkList[T]) Filter(f func(T) bool) {
 	cs.l.Filter(f)
 }
 
+// RemoveIf removes all the nodes whose value satisfies pred, in a single pass
+// under one lock acquisition, and returns how many were removed. pred must
+// not call back into the list.
+func (cs *CSDLinkList[T]) RemoveIf(pred func(T) bool) int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.l.RemoveIf(pred)
+}
+
 // Map returns a new doubly linked list containing the result of applying the given function to each node.
 func (cs *CSDLinkList[T]) Map(f func(T) T) *CSDLinkList[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return &CSDLinkList[T]{l: cs.l.Map(f)}
+	return newCSDLinkList(cs.l.Map(f), cs.opts)
 }
 
 // MapFrom returns a new doubly linked list containing the result of applying the given function to each node starting from the given index.
 func (cs *CSDLinkList[T]) MapFrom(index uint64, f func(T) T) *CSDLinkList[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return &CSDLinkList[T]{l: cs.l.MapFrom(index, f)}
+	return newCSDLinkList(cs.l.MapFrom(index, f), cs.opts)
 }
 
 // MapRange returns a new doubly linked list containing the result of applying the given function to each node in the given range.
 func (cs *CSDLinkList[T]) MapRange(start, end uint64, f func(T) T) *CSDLinkList[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return &CSDLinkList[T]{l: cs.l.MapRange(start, end, f)}
+	return newCSDLinkList(cs.l.MapRange(start, end, f), cs.opts)
 }
 
 // Reduce reduces the doubly linked list to a single value using the given function.
@@ -319,7 +462,7 @@ func (cs *CSDLinkList[T]) Reduce(f func(T, T) T) T {
 func (cs *CSDLinkList[T]) Copy() *CSDLinkList[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return &CSDLinkList[T]{l: cs.l.Copy()}
+	return newCSDLinkList(cs.l.Copy(), cs.opts)
 }
 
 // Merge appends the nodes of the given doubly linked list to the original doubly linked list.
@@ -331,11 +474,25 @@ func (cs *CSDLinkList[T]) Merge(list *CSDLinkList[T]) {
 	cs.l.Merge(list.l)
 }
 
+// MergeSorted merges other into the list, both being sorted by less (see
+// dlinkList.MergeSorted). Both lists are locked for writing, in a canonical
+// order, so a.MergeSorted(b) and b.MergeSorted(a) can run concurrently
+// without deadlocks.
+func (cs *CSDLinkList[T]) MergeSorted(other *CSDLinkList[T], less cmpx.Less[T]) {
+	if cs == other {
+		return
+	}
+	_ = gods.Atomically(func() error {
+		cs.l.MergeSorted(other.l, less)
+		return nil
+	}, cs, other)
+}
+
 // ReverseCopy returns a new doubly linked list with the nodes of the original doubly linked list in reverse order.
 func (cs *CSDLinkList[T]) ReverseCopy() *CSDLinkList[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return &CSDLinkList[T]{l: cs.l.ReverseCopy()}
+	return newCSDLinkList(cs.l.ReverseCopy(), cs.opts)
 }
 
 // ReverseMerge appends the nodes of the given doubly linked list to the original doubly linked list in reverse order.
@@ -374,16 +531,27 @@ func (cs *CSDLinkList[T]) Sort(f func(T, T) bool) {
 func (cs *CSDLinkList[T]) FindAll(f func(T) bool) *CSDLinkList[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return &CSDLinkList[T]{l: cs.l.FindAll(f)}
+	return newCSDLinkList(cs.l.FindAll(f), cs.opts)
 }
 
 // FindLast returns the last node that satisfies the given function.
+// The node belongs to the list, so its links can be followed, but reading it
+// once the call returns races with the writers of the list.
+//
+// Deprecated: use FindLastValue, which copies the item under the lock.
 func (cs *CSDLinkList[T]) FindLast(f func(T) bool) (*dlinkList.Node[T], error) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.l.FindLast(f)
 }
 
+// FindLastValue returns a copy of the last item that satisfies the given function.
+func (cs *CSDLinkList[T]) FindLastValue(f func(T) bool) (T, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return valueOf(cs.l.FindLast(f))
+}
+
 // FindLastIndex returns the index of the last node that satisfies the given function.
 func (cs *CSDLinkList[T]) FindLastIndex(f func(T) bool) int {
 	cs.mu.RLock()
@@ -397,3 +565,214 @@ func (cs *CSDLinkList[T]) FindIndex(f func(T) bool) int {
 	defer cs.mu.RUnlock()
 	return cs.l.FindIndex(f)
 }
+
+// Iter returns an iterator over the values in the doubly linked list, from the head to the tail.
+// The iterator works on a snapshot of the list taken when the iteration starts,
+// so the list can be safely modified (even from within the loop) while iterating.
+func (cs *CSDLinkList[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, value := range cs.ToSlice() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// Iterator returns an iterator over a snapshot of the doubly linked list, from the head to the tail.
+func (cs *CSDLinkList[T]) Iterator() iterator.BidirectionalIterator[T] {
+	return iterator.FromSlice(cs.ToSlice())
+}
+
+// Enumerate returns an iterator over the index and the value of the nodes in the doubly linked list.
+// Like Iter, it works on a snapshot of the list taken when the iteration starts.
+func (cs *CSDLinkList[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		for i, value := range cs.ToSlice() {
+			if !yield(uint64(i), value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (cs *CSDLinkList[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(cs.Enumerate())
+}
+
+// Values returns an iterator over the items, in the same order as Enumerate
+// (it is Iter, under the name used by the standard library).
+func (cs *CSDLinkList[T]) Values() iter.Seq[T] {
+	return cs.Iter()
+}
+
+// Backward returns an iterator over the values in the doubly linked list, from the tail to the head.
+// Like Iter, it works on a snapshot of the list taken when the iteration starts.
+func (cs *CSDLinkList[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, value := range cs.ToSliceReverse() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// Encode writes a snapshot of the list to w using the given codec
+func (cs *CSDLinkList[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeSlice(w, codec, cs.ToSlice())
+}
+
+// Decode replaces the content of the list with the elements read from r using the given codec.
+// The data is decoded before acquiring the lock, so readers are blocked only while the list is rebuilt.
+// A zero value (as allocated by the decoders for pointer fields) is
+// initialized with the default options.
+func (cs *CSDLinkList[T]) Decode(r io.Reader, codec gods.Codec) error {
+	items, err := gods.DecodeSlice[T](r, codec)
+	if err != nil {
+		return err
+	}
+	if cs.mu == nil {
+		cs.init(dlinkList.New[T](), nil)
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.l.Clear()
+	for _, item := range items {
+		cs.l.Append(item)
+	}
+	return nil
+}
+
+// EncodeStream writes the list to w incrementally, in chunks encoded with the
+// given codec (see gods.EncodeStream), without copying its elements first.
+// Writers are blocked until the whole list has been written.
+func (cs *CSDLinkList[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.l.EncodeStream(w, codec)
+}
+
+// DecodeStream replaces the content of the list with the elements of a
+// stream written by EncodeStream. Unlike Decode, the elements are added as
+// they are read, so the lock is held until the whole stream has been read.
+func (cs *CSDLinkList[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	if cs.mu == nil {
+		cs.init(dlinkList.New[T](), nil)
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.l.DecodeStream(r, codec)
+}
+
+// MarshalText implements encoding.TextMarshaler: the list is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (cs *CSDLinkList[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(cs)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (cs *CSDLinkList[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(cs, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (cs *CSDLinkList[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(cs)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (cs *CSDLinkList[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(cs, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (cs *CSDLinkList[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(cs)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (cs *CSDLinkList[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(cs, data)
+}
+
+// MarshalYAML implements the Marshaler interface of the YAML packages
+// (gopkg.in/yaml.v2 and v3): the list is encoded as a sequence, in order.
+func (cs *CSDLinkList[T]) MarshalYAML() (any, error) {
+	return cs.ToSlice(), nil
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of gopkg.in/yaml.v2
+// (also honored by v3): the content of the list is replaced with the elements
+// of a YAML sequence.
+func (cs *CSDLinkList[T]) UnmarshalYAML(unmarshal func(any) error) error {
+	var items []T
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+	if cs.mu == nil {
+		// zero value, allocated by the decoder for a pointer field
+		cs.init(dlinkList.New[T](), nil)
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.l.Clear()
+	for _, item := range items {
+		cs.l.Append(item)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer: the list is stored as a JSON array, so it
+// can be saved in a JSON (or JSONB) column.
+func (cs *CSDLinkList[T]) Value() (driver.Value, error) {
+	return gods.ValueSlice(cs.ToSlice())
+}
+
+// Scan implements sql.Scanner: the content of the list is replaced with the
+// elements of the JSON array read from the database (NULL is an empty list).
+func (cs *CSDLinkList[T]) Scan(src any) error {
+	items, err := gods.ScanSlice[T](src)
+	if err != nil {
+		return err
+	}
+	if cs.mu == nil {
+		// zero value, e.g. a new(CSDLinkList[T]) passed to Scan
+		cs.init(dlinkList.New[T](), nil)
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.l.Clear()
+	for _, item := range items {
+		cs.l.Append(item)
+	}
+	return nil
+}
+
+// ContentionStats returns the lock statistics of the CSDLinkList. They are
+// collected only if it was created with the gods.WithContentionStats option.
+func (cs *CSDLinkList[T]) ContentionStats() gods.ContentionStats {
+	return gods.LockContentionStats(cs.mu)
+}
+
+// Locker returns the lock of the CSDLinkList (see gods.Atomically).
+func (cs *CSDLinkList[T]) Locker() gods.RWLocker {
+	return cs.mu
+}
+
+// Unsafe returns the underlying (non concurrency-safe) doubly linked list. It must only
+// be used while the lock is held, typically inside gods.Atomically.
+func (cs *CSDLinkList[T]) Unsafe() *dlinkList.DLinkList[T] {
+	return cs.l
+}
+
+// valueOf returns the value of n, or the zero value with err.
+func valueOf[T comparable](n *dlinkList.Node[T], err error) (T, error) {
+	if n == nil || err != nil {
+		var zero T
+		return zero, err
+	}
+	return n.Value, nil
+}
@@ -16,10 +16,14 @@
 package csdlinkList_test
 
 import (
+	"encoding/json"
+	"slices"
 	"sync"
 	"testing"
 
+	gods "github.com/pzaino/gods"
 	csdlinkList "github.com/pzaino/gods/pkg/csdlinkList"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 const (
@@ -285,6 +289,58 @@ func TestCSDLinkListGetAt(t *testing.T) {
 	})
 }
 
+func TestCSDLinkListValueAccessors(t *testing.T) {
+	cs := csdlinkList.New[int]()
+	if _, ok := cs.GetFirstValue(); ok {
+		t.Fatalf("expected an empty list")
+	}
+	for i := 0; i < 10; i++ {
+		cs.Append(i)
+	}
+	if v, err := cs.FindValue(3); err != nil || v != 3 {
+		t.Errorf("expected %v, got %v", 3, v)
+	}
+	if v, ok := cs.GetFirstValue(); !ok || v != 0 {
+		t.Errorf("expected %v, got %v", 0, v)
+	}
+	if v, ok := cs.GetLastValue(); !ok || v != 9 {
+		t.Errorf("expected %v, got %v", 9, v)
+	}
+	if v, err := cs.GetAtValue(5); err != nil || v != 5 {
+		t.Errorf("expected %v, got %v", 5, v)
+	}
+	if v, err := cs.FindLastValue(func(v int) bool { return v%2 == 0 }); err != nil || v != 8 {
+		t.Errorf("expected %v, got %v", 8, v)
+	}
+	if _, err := cs.FindValue(100); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+
+	// the deprecated methods still return the nodes of the list
+	count := 0
+	for n := cs.GetFirst(); n != nil; n = n.Next {
+		count++
+	}
+	if count != 10 {
+		t.Errorf("expected %v, got %v", 10, count)
+	}
+
+	// readers and writers race on the list (run with -race): the Value
+	// methods copy the items under the lock
+	runConcurrent(t, 1000, func(j int) {
+		if j%2 == 0 {
+			cs.Append(j)
+			cs.DeleteWithValue(j)
+			return
+		}
+		_, _ = cs.FindValue(3)
+		_, _ = cs.GetFirstValue()
+		_, _ = cs.GetLastValue()
+		_, _ = cs.GetAtValue(5)
+		_, _ = cs.FindLastValue(func(v int) bool { return v == 8 })
+	})
+}
+
 func TestCSDLinkListClear(t *testing.T) {
 	cs := csdlinkList.New[int]()
 	for i := 0; i < 1000; i++ {
@@ -656,3 +712,156 @@ func TestCSDLinkListRemove(t *testing.T) {
 		t.Fatalf("expected value 500 to be removed")
 	}
 }
+
+func TestCSDLinkListIter(t *testing.T) {
+	cs := csdlinkList.New[int]()
+	for i := 1; i <= 3; i++ {
+		cs.Append(i)
+	}
+	var items []int
+	for v := range cs.Iter() {
+		// modifying the list while iterating must not deadlock
+		cs.Prepend(v * 10)
+		items = append(items, v)
+	}
+	if !slices.Equal(items, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, items)
+	}
+	if cs.Size() != 6 {
+		t.Errorf("Expected size %d, got %d", 6, cs.Size())
+	}
+}
+
+func TestCSDLinkListIterator(t *testing.T) {
+	cs := csdlinkList.New[int]()
+	for i := 1; i <= 3; i++ {
+		cs.Append(i)
+	}
+	it := cs.Iterator()
+	cs.Append(4) // not part of the snapshot
+	if got := iterator.ToSlice[int](it); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, got)
+	}
+}
+
+func TestCSDLinkListEnumerate(t *testing.T) {
+	cs := csdlinkList.New[int]()
+	for i := 1; i <= 3; i++ {
+		cs.Append(i)
+	}
+	var indexes []uint64
+	var values []int
+	for i, v := range cs.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0, 1, 2}) {
+		t.Errorf("Expected %v, got %v", []uint64{0, 1, 2}, indexes)
+	}
+	if !slices.Equal(values, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, values)
+	}
+	if got := slices.Collect(cs.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+	if got := slices.Collect(cs.Values()); !slices.Equal(got, values) {
+		t.Errorf("Expected %v, got %v", values, got)
+	}
+	var backward []int
+	for v := range cs.Backward() {
+		// modifying the list while iterating must not deadlock
+		cs.Append(v * 10)
+		backward = append(backward, v)
+	}
+	if !slices.Equal(backward, []int{3, 2, 1}) {
+		t.Errorf("Expected %v, got %v", []int{3, 2, 1}, backward)
+	}
+}
+
+func TestCSDLinkListLockStrategies(t *testing.T) {
+	for _, opt := range []gods.Option{gods.WithRWMutex(), gods.WithMutex(), gods.WithSharding(4)} {
+		cs := csdlinkList.New[int](opt)
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					cs.Append(i)
+					_ = cs.Size()
+					_ = cs.ToSlice()
+				}
+			}()
+		}
+		wg.Wait()
+		if cs.Size() != 400 {
+			t.Errorf("Expected size %d, got %d", 400, cs.Size())
+		}
+		if c := cs.Copy(); c.Size() != 400 {
+			t.Errorf("Expected size %d, got %d", 400, c.Size())
+		}
+	}
+}
+
+func TestCSDLinkListContentionStats(t *testing.T) {
+	cs := csdlinkList.New[int](gods.WithContentionStats())
+	cs.Append(1)
+	_ = cs.ToSlice()
+	stats := cs.ContentionStats()
+	if stats.Acquisitions != 1 || stats.ReadAcquisitions != 1 {
+		t.Errorf("Expected 1 write and 1 read, got %+v", stats)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	list := csdlinkList.New[int]()
+	list.Append(3)
+	list.Append(1)
+	v, err := list.MarshalYAML()
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if items, ok := v.([]int); !ok || !slices.Equal(items, []int{3, 1}) {
+		t.Errorf("expected %v, got %v", []int{3, 1}, v)
+	}
+
+	// the YAML packages call UnmarshalYAML with a function decoding the
+	// node into the given value
+	unmarshal := func(v any) error {
+		return json.Unmarshal([]byte("[1, 2, 3]"), v)
+	}
+	if err := list.UnmarshalYAML(unmarshal); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if got := list.ToSlice(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("expected %v, got %v", []int{1, 2, 3}, got)
+	}
+
+	// a zero value, as allocated by the decoders for pointer fields
+	var zero csdlinkList.CSDLinkList[int]
+	if err := zero.UnmarshalYAML(unmarshal); err != nil || zero.Size() != 3 {
+		t.Errorf("expected %v, got %v", 3, zero.Size())
+	}
+}
+
+func TestCSDLinkListRemoveIf(t *testing.T) {
+	cs := csdlinkList.New[int]()
+	for i := 1; i <= 10; i++ {
+		cs.Append(i)
+	}
+	if n := cs.RemoveIf(func(v int) bool { return v%2 == 0 || v == 1 }); n != 6 {
+		t.Errorf("expected 6 nodes removed, got %d", n)
+	}
+	if got := cs.ToSlice(); !slices.Equal(got, []int{3, 5, 7, 9}) {
+		t.Errorf("expected %v, got %v", []int{3, 5, 7, 9}, got)
+	}
+	if cs.Size() != 4 {
+		t.Errorf("expected size 4, got %d", cs.Size())
+	}
+	if err := cs.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+	if n := cs.RemoveIf(func(int) bool { return true }); n != 4 || !cs.IsEmpty() {
+		t.Errorf("expected all the nodes removed, got %d", n)
+	}
+}
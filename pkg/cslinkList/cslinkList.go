@@ -16,29 +16,56 @@
 package cslinkList
 
 import (
-	"sync"
-
+	"context"
+	"database/sql/driver"
+	"io"
+	"iter"
+	"log/slog"
+	"sync/atomic"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 	linkList "github.com/pzaino/gods/pkg/linkList"
 )
 
 // CSLinkList is a concurrency-safe linked list.
 type CSLinkList[T comparable] struct {
-	mu sync.RWMutex
-	l  *linkList.LinkList[T]
+	mu   gods.RWLocker
+	l    *linkList.LinkList[T]
+	opts []gods.Option
+	size atomic.Uint64 // published on every write unlock, read without locking
 }
 
 // New creates a new concurrency-safe linked list.
-func New[T comparable]() *CSLinkList[T] {
-	return &CSLinkList[T]{l: linkList.New[T]()}
+// The options select the locking strategy (the default is a sync.RWMutex).
+func New[T comparable](opts ...gods.Option) *CSLinkList[T] {
+	return newCSLinkList(linkList.New[T](opts...), opts)
 }
 
-// NewFromSlice creates a new concurrency-safe linked list from a slice.
-func NewFromSlice[T comparable](items []T) *CSLinkList[T] {
-	cs := New[T]()
-	cs.l = linkList.NewFromSlice(items)
+// newCSLinkList wraps l in a CSLinkList using the locking strategy selected by opts
+func newCSLinkList[T comparable](l *linkList.LinkList[T], opts []gods.Option) *CSLinkList[T] {
+	cs := &CSLinkList[T]{}
+	cs.init(l, opts)
 	return cs
 }
 
+// init sets up cs to wrap l
+func (cs *CSLinkList[T]) init(l *linkList.LinkList[T], opts []gods.Option) {
+	cs.l, cs.opts = l, opts
+	cs.size.Store(l.Size())
+	cs.mu = gods.OnUnlock(gods.NewLocker(opts...), func() {
+		cs.size.Store(cs.l.Size())
+	})
+	gods.ObserveSize(cs.mu, cs.size.Load)
+}
+
+// NewFromSlice creates a new concurrency-safe linked list from a slice.
+func NewFromSlice[T comparable](items []T, opts ...gods.Option) *CSLinkList[T] {
+	return newCSLinkList(linkList.NewFromSlice(items, opts...), opts)
+}
+
 // Append adds a new node to the end of the list.
 func (cs *CSLinkList[T]) Append(value T) {
 	cs.mu.Lock()
@@ -67,20 +94,37 @@ func (cs *CSLinkList[T]) ToSlice() []T {
 	return cs.l.ToSlice()
 }
 
-// IsEmpty checks if the list is empty.
-func (cs *CSLinkList[T]) IsEmpty() bool {
+// Freeze returns an immutable copy of the list, taken under the read lock:
+// it can be read without locking (see gods.Frozen).
+func (cs *CSLinkList[T]) Freeze() *gods.Frozen[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return cs.l.IsEmpty()
+	return cs.l.Freeze()
+}
+
+// IsEmpty checks if the list is empty.
+func (cs *CSLinkList[T]) IsEmpty() bool {
+	return cs.size.Load() == 0
 }
 
 // Find returns the first node with the given value.
+// The node belongs to the list, so its links can be followed, but reading it
+// once the call returns races with the writers of the list.
+//
+// Deprecated: use FindValue, which copies the item under the lock.
 func (cs *CSLinkList[T]) Find(value T) (*linkList.Node[T], error) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.l.Find(value)
 }
 
+// FindValue returns a copy of the first item equal to value.
+func (cs *CSLinkList[T]) FindValue(value T) (T, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return valueOf(cs.l.Find(value))
+}
+
 // Reverse reverses the list.
 func (cs *CSLinkList[T]) Reverse() {
 	cs.mu.Lock()
@@ -88,34 +132,138 @@ func (cs *CSLinkList[T]) Reverse() {
 	cs.l.Reverse()
 }
 
+// Rotate rotates the list left by n positions (see linkList.Rotate).
+func (cs *CSLinkList[T]) Rotate(n int) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.l.Rotate(n)
+}
+
+// HasCycle returns true if the nodes of the list form a cycle (see
+// linkList.HasCycle).
+func (cs *CSLinkList[T]) HasCycle() bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.l.HasCycle()
+}
+
+// BreakCycle breaks the cycle of the list (see linkList.BreakCycle).
+func (cs *CSLinkList[T]) BreakCycle() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.l.BreakCycle()
+}
+
 // Size returns the number of nodes in the list.
 func (cs *CSLinkList[T]) Size() uint64 {
+	return cs.size.Load()
+}
+
+// MemoryUsage returns an estimate of the bytes held by the list (see the
+// gods package), adding the memory returned by extra for every item.
+func (cs *CSLinkList[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return cs.l.Size()
+	return uint64(unsafe.Sizeof(*cs)) + cs.l.MemoryUsage(extra...)
+}
+
+// Stats returns the statistics of the list: if it was created with
+// gods.WithLatencyHistograms, the latency histograms of its operations.
+func (cs *CSLinkList[T]) Stats() gods.Stats {
+	return gods.Stats{Latency: gods.LockLatency(cs.mu)}
+}
+
+// ResetLatency clears the latency histograms returned by Stats.
+func (cs *CSLinkList[T]) ResetLatency() {
+	gods.ResetLockLatency(cs.mu)
 }
 
-// GetFirst returns the first node in the list.
+// Validate checks the invariants of the list and its published size (see
+// gods.Validator).
+func (cs *CSLinkList[T]) Validate() error {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if err := cs.l.Validate(); err != nil {
+		return err
+	}
+	if size := cs.size.Load(); size != cs.l.Size() {
+		return gods.Invariantf("published size is %d but the list holds %d items", size, cs.l.Size())
+	}
+	return nil
+}
+
+// Dump writes a rendering of the wrapped structure to w, holding the read
+// lock, for debugging.
+func (cs *CSLinkList[T]) Dump(w io.Writer) error {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.l.Dump(w)
+}
+
+// GetFirst returns the first node in the list, or nil if the list is empty.
+// The node belongs to the list, so its links can be followed, but reading it
+// once the call returns races with the writers of the list.
+//
+// Deprecated: use GetFirstValue, which copies the item under the lock.
 func (cs *CSLinkList[T]) GetFirst() *linkList.Node[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.l.GetFirst()
 }
 
-// GetLast returns the last node in the list.
+// GetFirstValue returns a copy of the first item in the list, or false if
+// the list is empty.
+func (cs *CSLinkList[T]) GetFirstValue() (T, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if n := cs.l.GetFirst(); n != nil {
+		return n.Value, true
+	}
+	var zero T
+	return zero, false
+}
+
+// GetLast returns the last node in the list, or nil if the list is empty.
+// The node belongs to the list, so its links can be followed, but reading it
+// once the call returns races with the writers of the list.
+//
+// Deprecated: use GetLastValue, which copies the item under the lock.
 func (cs *CSLinkList[T]) GetLast() *linkList.Node[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.l.GetLast()
 }
 
+// GetLastValue returns a copy of the last item in the list, or false if
+// the list is empty.
+func (cs *CSLinkList[T]) GetLastValue() (T, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if n := cs.l.GetLast(); n != nil {
+		return n.Value, true
+	}
+	var zero T
+	return zero, false
+}
+
 // GetAt returns the node at the given index.
+// The node belongs to the list, so its links can be followed, but reading it
+// once the call returns races with the writers of the list.
+//
+// Deprecated: use GetAtValue, which copies the item under the lock.
 func (cs *CSLinkList[T]) GetAt(index uint64) (*linkList.Node[T], error) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.l.GetAt(index)
 }
 
+// GetAtValue returns a copy of the item at the given index.
+func (cs *CSLinkList[T]) GetAtValue(index uint64) (T, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return valueOf(cs.l.GetAt(index))
+}
+
 // InsertAt inserts a new node at the given index.
 func (cs *CSLinkList[T]) InsertAt(index uint64, value T) error {
 	cs.mu.Lock()
@@ -148,7 +296,7 @@ func (cs *CSLinkList[T]) Clear() {
 func (cs *CSLinkList[T]) Copy() *CSLinkList[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return &CSLinkList[T]{l: cs.l.Copy()}
+	return newCSLinkList(cs.l.Copy(), cs.opts)
 }
 
 // Merge appends all the nodes from another list to the current list.
@@ -161,14 +309,27 @@ func (cs *CSLinkList[T]) Merge(list *CSLinkList[T]) {
 	list.l.Clear()
 }
 
+// MergeSorted merges other into the list, both being sorted by less (see
+// linkList.MergeSorted). Both lists are locked for writing, in a canonical
+// order, so a.MergeSorted(b) and b.MergeSorted(a) can run concurrently
+// without deadlocks.
+func (cs *CSLinkList[T]) MergeSorted(other *CSLinkList[T], less cmpx.Less[T]) {
+	if cs == other {
+		return
+	}
+	_ = gods.Atomically(func() error {
+		cs.l.MergeSorted(other.l, less)
+		return nil
+	}, cs, other)
+}
+
 // Map generates a new list by applying the function to all the nodes in the list.
 func (cs *CSLinkList[T]) Map(f func(T) T) *CSLinkList[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 
 	newList := cs.l.Map(f)
-	newCSList := New[T]()
-	newCSList.l = newList
+	newCSList := newCSLinkList(newList, cs.opts)
 	return newCSList
 }
 
@@ -182,8 +343,7 @@ func (cs *CSLinkList[T]) MapFrom(start uint64, f func(T) T) (*CSLinkList[T], err
 		return nil, err
 	}
 
-	newCSList := New[T]()
-	newCSList.l = newList
+	newCSList := newCSLinkList(newList, cs.opts)
 	return newCSList, nil
 }
 
@@ -197,8 +357,7 @@ func (cs *CSLinkList[T]) MapRange(start, end uint64, f func(T) T) (*CSLinkList[T
 		return nil, err
 	}
 
-	newCSList := New[T]()
-	newCSList.l = newList
+	newCSList := newCSLinkList(newList, cs.opts)
 	return newCSList, nil
 }
 
@@ -290,19 +449,262 @@ func (cs *CSLinkList[T]) FindLastIndex(f func(T) bool) (uint64, error) {
 func (cs *CSLinkList[T]) FindAll(f func(T) bool) *CSLinkList[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return &CSLinkList[T]{l: cs.l.FindAll(f)}
+	return newCSLinkList(cs.l.FindAll(f), cs.opts)
 }
 
 // FindLast returns the last node that matches the predicate.
+// The node belongs to the list, so its links can be followed, but reading it
+// once the call returns races with the writers of the list.
+//
+// Deprecated: use FindLastValue, which copies the item under the lock.
 func (cs *CSLinkList[T]) FindLast(f func(T) bool) (*linkList.Node[T], error) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.l.FindLast(f)
 }
 
+// FindLastValue returns a copy of the last item that matches the predicate.
+func (cs *CSLinkList[T]) FindLastValue(f func(T) bool) (T, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return valueOf(cs.l.FindLast(f))
+}
+
 // FindAllIndexes returns the indexes of all nodes that match the predicate.
 func (cs *CSLinkList[T]) FindAllIndexes(f func(T) bool) []uint64 {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.l.FindAllIndexes(f)
 }
+
+// Iter returns an iterator over the values in the list, from the head to the tail.
+// The iterator works on a snapshot of the list taken when the iteration starts,
+// so the list can be safely modified (even from within the loop) while iterating.
+func (cs *CSLinkList[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, value := range cs.ToSlice() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// Iterator returns an iterator over a snapshot of the list, from the head to the tail.
+func (cs *CSLinkList[T]) Iterator() iterator.BidirectionalIterator[T] {
+	return iterator.FromSlice(cs.ToSlice())
+}
+
+// Enumerate returns an iterator over the index and the value of the nodes in the list.
+// Like Iter, it works on a snapshot of the list taken when the iteration starts.
+func (cs *CSLinkList[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		for i, value := range cs.ToSlice() {
+			if !yield(uint64(i), value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (cs *CSLinkList[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(cs.Enumerate())
+}
+
+// Values returns an iterator over the items, in the same order as Enumerate
+// (it is Iter, under the name used by the standard library).
+func (cs *CSLinkList[T]) Values() iter.Seq[T] {
+	return cs.Iter()
+}
+
+// Encode writes a snapshot of the list to w using the given codec
+func (cs *CSLinkList[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeSlice(w, codec, cs.ToSlice())
+}
+
+// Decode replaces the content of the list with the elements read from r using the given codec.
+// The data is decoded before acquiring the lock, so readers are blocked only while the list is rebuilt.
+// A zero value (as allocated by the decoders for pointer fields) is
+// initialized with the default options.
+func (cs *CSLinkList[T]) Decode(r io.Reader, codec gods.Codec) error {
+	items, err := gods.DecodeSlice[T](r, codec)
+	if err != nil {
+		return err
+	}
+	if cs.mu == nil {
+		cs.init(linkList.New[T](), nil)
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.l.Clear()
+	for _, item := range items {
+		cs.l.Append(item)
+	}
+	return nil
+}
+
+// EncodeStream writes the list to w incrementally, in chunks encoded with the
+// given codec (see gods.EncodeStream), without copying its elements first.
+// Writers are blocked until the whole list has been written.
+func (cs *CSLinkList[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.l.EncodeStream(w, codec)
+}
+
+// DecodeStream replaces the content of the list with the elements of a
+// stream written by EncodeStream. Unlike Decode, the elements are added as
+// they are read, so the lock is held until the whole stream has been read.
+func (cs *CSLinkList[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	if cs.mu == nil {
+		cs.init(linkList.New[T](), nil)
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.l.DecodeStream(r, codec)
+}
+
+// MarshalText implements encoding.TextMarshaler: the list is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (cs *CSLinkList[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(cs)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (cs *CSLinkList[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(cs, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (cs *CSLinkList[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(cs)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (cs *CSLinkList[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(cs, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (cs *CSLinkList[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(cs)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (cs *CSLinkList[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(cs, data)
+}
+
+// MarshalYAML implements the Marshaler interface of the YAML packages
+// (gopkg.in/yaml.v2 and v3): the list is encoded as a sequence, in order.
+func (cs *CSLinkList[T]) MarshalYAML() (any, error) {
+	return cs.ToSlice(), nil
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of gopkg.in/yaml.v2
+// (also honored by v3): the content of the list is replaced with the elements
+// of a YAML sequence.
+func (cs *CSLinkList[T]) UnmarshalYAML(unmarshal func(any) error) error {
+	var items []T
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+	if cs.mu == nil {
+		// zero value, allocated by the decoder for a pointer field
+		cs.init(linkList.New[T](), nil)
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.l.Clear()
+	for _, item := range items {
+		cs.l.Append(item)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer: the list is stored as a JSON array, so it
+// can be saved in a JSON (or JSONB) column.
+func (cs *CSLinkList[T]) Value() (driver.Value, error) {
+	return gods.ValueSlice(cs.ToSlice())
+}
+
+// Scan implements sql.Scanner: the content of the list is replaced with the
+// elements of the JSON array read from the database (NULL is an empty list).
+func (cs *CSLinkList[T]) Scan(src any) error {
+	items, err := gods.ScanSlice[T](src)
+	if err != nil {
+		return err
+	}
+	if cs.mu == nil {
+		// zero value, e.g. a new(CSLinkList[T]) passed to Scan
+		cs.init(linkList.New[T](), nil)
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.l.Clear()
+	for _, item := range items {
+		cs.l.Append(item)
+	}
+	return nil
+}
+
+// FilterCtx is like Filter but it can be aborted through the context: it
+// returns ctx.Err() as soon as the context is done, leaving the list unchanged.
+func (cs *CSLinkList[T]) FilterCtx(ctx context.Context, f func(T) bool) (err error) {
+	ctx, end := gods.Trace(ctx, "cslinkList.FilterCtx", slog.Uint64("size", cs.Size()))
+	defer func() { end(err) }()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.l.FilterCtx(ctx, f)
+}
+
+// MapCtx is like Map but it returns ctx.Err() as soon as the context is done.
+func (cs *CSLinkList[T]) MapCtx(ctx context.Context, f func(T) T) (_ *CSLinkList[T], err error) {
+	ctx, end := gods.Trace(ctx, "cslinkList.MapCtx", slog.Uint64("size", cs.Size()))
+	defer func() { end(err) }()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	newList, err := cs.l.MapCtx(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	newCSList := newCSLinkList(newList, cs.opts)
+	return newCSList, nil
+}
+
+// ForEachCtx is like ForEach but it returns ctx.Err() as soon as the context is done.
+func (cs *CSLinkList[T]) ForEachCtx(ctx context.Context, f func(*T)) (err error) {
+	ctx, end := gods.Trace(ctx, "cslinkList.ForEachCtx", slog.Uint64("size", cs.Size()))
+	defer func() { end(err) }()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.l.ForEachCtx(ctx, f)
+}
+
+// ContentionStats returns the lock statistics of the CSLinkList. They are
+// collected only if it was created with the gods.WithContentionStats option.
+func (cs *CSLinkList[T]) ContentionStats() gods.ContentionStats {
+	return gods.LockContentionStats(cs.mu)
+}
+
+// Locker returns the lock of the CSLinkList (see gods.Atomically).
+func (cs *CSLinkList[T]) Locker() gods.RWLocker {
+	return cs.mu
+}
+
+// Unsafe returns the underlying (non concurrency-safe) linked list. It must only
+// be used while the lock is held, typically inside gods.Atomically.
+func (cs *CSLinkList[T]) Unsafe() *linkList.LinkList[T] {
+	return cs.l
+}
+
+// valueOf returns the value of n, or the zero value with err.
+func valueOf[T comparable](n *linkList.Node[T], err error) (T, error) {
+	if n == nil || err != nil {
+		var zero T
+		return zero, err
+	}
+	return n.Value, nil
+}
@@ -16,10 +16,16 @@
 package cslinkList_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
 	"sync"
 	"testing"
 
+	gods "github.com/pzaino/gods"
 	cslinkList "github.com/pzaino/gods/pkg/cslinkList"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 const (
@@ -173,6 +179,58 @@ func TestCSLinkListGetAt(t *testing.T) {
 	})
 }
 
+func TestCSLinkListValueAccessors(t *testing.T) {
+	cs := cslinkList.New[int]()
+	if _, ok := cs.GetFirstValue(); ok {
+		t.Fatalf("expected an empty list")
+	}
+	for i := 0; i < 10; i++ {
+		cs.Append(i)
+	}
+	if v, err := cs.FindValue(3); err != nil || v != 3 {
+		t.Errorf("expected %v, got %v", 3, v)
+	}
+	if v, ok := cs.GetFirstValue(); !ok || v != 0 {
+		t.Errorf("expected %v, got %v", 0, v)
+	}
+	if v, ok := cs.GetLastValue(); !ok || v != 9 {
+		t.Errorf("expected %v, got %v", 9, v)
+	}
+	if v, err := cs.GetAtValue(5); err != nil || v != 5 {
+		t.Errorf("expected %v, got %v", 5, v)
+	}
+	if v, err := cs.FindLastValue(func(v int) bool { return v%2 == 0 }); err != nil || v != 8 {
+		t.Errorf("expected %v, got %v", 8, v)
+	}
+	if _, err := cs.FindValue(100); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+
+	// the deprecated methods still return the nodes of the list
+	count := 0
+	for n := cs.GetFirst(); n != nil; n = n.Next {
+		count++
+	}
+	if count != 10 {
+		t.Errorf("expected %v, got %v", 10, count)
+	}
+
+	// readers and writers race on the list (run with -race): the Value
+	// methods copy the items under the lock
+	runConcurrent(t, 1000, func(j int) {
+		if j%2 == 0 {
+			cs.Append(j)
+			cs.DeleteWithValue(j)
+			return
+		}
+		_, _ = cs.FindValue(3)
+		_, _ = cs.GetFirstValue()
+		_, _ = cs.GetLastValue()
+		_, _ = cs.GetAtValue(5)
+		_, _ = cs.FindLastValue(func(v int) bool { return v == 8 })
+	})
+}
+
 func TestCSLinkListInsertAt(t *testing.T) {
 	cs := cslinkList.New[int]()
 	for i := 0; i < 1000; i++ {
@@ -478,3 +536,229 @@ func TestCSLinkListMapRange(t *testing.T) {
 		}
 	})
 }
+
+func TestCSLinkListIter(t *testing.T) {
+	cs := cslinkList.NewFromSlice([]int{1, 2, 3})
+	var items []int
+	for v := range cs.Iter() {
+		// modifying the list while iterating must not deadlock
+		cs.Append(v * 10)
+		items = append(items, v)
+	}
+	if !slices.Equal(items, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, items)
+	}
+	if cs.Size() != 6 {
+		t.Errorf("Expected size %d, got %d", 6, cs.Size())
+	}
+}
+
+func TestCSLinkListIterator(t *testing.T) {
+	cs := cslinkList.NewFromSlice([]int{1, 2, 3})
+	it := cs.Iterator()
+	cs.Append(4) // not part of the snapshot
+	if got := iterator.ToSlice[int](it); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, got)
+	}
+}
+
+func TestCSLinkListEnumerate(t *testing.T) {
+	cs := cslinkList.NewFromSlice([]int{1, 2, 3})
+	var indexes []uint64
+	var values []int
+	for i, v := range cs.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0, 1, 2}) {
+		t.Errorf("Expected %v, got %v", []uint64{0, 1, 2}, indexes)
+	}
+	if !slices.Equal(values, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, values)
+	}
+	if got := slices.Collect(cs.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+	if got := slices.Collect(cs.Values()); !slices.Equal(got, values) {
+		t.Errorf("Expected %v, got %v", values, got)
+	}
+}
+
+func TestCSLinkListCtxVariants(t *testing.T) {
+	cs := cslinkList.NewFromSlice([]int{1, 2, 3})
+	mapped, err := cs.MapCtx(context.Background(), func(v int) int { return v * 2 })
+	if err != nil || !slices.Equal(mapped.ToSlice(), []int{2, 4, 6}) {
+		t.Errorf("Expected %v, got %v", []int{2, 4, 6}, mapped)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := cs.FilterCtx(ctx, func(int) bool { return false }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	if cs.Size() != 3 {
+		t.Errorf("Expected size %d, got %d", 3, cs.Size())
+	}
+	if err := cs.ForEachCtx(ctx, func(*int) {}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestCSLinkListLockStrategies(t *testing.T) {
+	for _, opt := range []gods.Option{gods.WithRWMutex(), gods.WithMutex(), gods.WithSharding(4)} {
+		cs := cslinkList.New[int](opt)
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					cs.Prepend(i)
+					_ = cs.Size()
+					_ = cs.ToSlice()
+				}
+			}()
+		}
+		wg.Wait()
+		if cs.Size() != 400 {
+			t.Errorf("Expected size %d, got %d", 400, cs.Size())
+		}
+		if c := cs.Copy(); c.Size() != 400 {
+			t.Errorf("Expected size %d, got %d", 400, c.Size())
+		}
+	}
+}
+
+func TestCSLinkListContentionStats(t *testing.T) {
+	cs := cslinkList.New[int](gods.WithContentionStats())
+	cs.Append(1)
+	_ = cs.ToSlice()
+	stats := cs.ContentionStats()
+	if stats.Acquisitions != 1 || stats.ReadAcquisitions != 1 {
+		t.Errorf("Expected 1 write and 1 read, got %+v", stats)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	list := cslinkList.New[int]()
+	list.Append(3)
+	list.Append(1)
+	v, err := list.MarshalYAML()
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if items, ok := v.([]int); !ok || !slices.Equal(items, []int{3, 1}) {
+		t.Errorf("expected %v, got %v", []int{3, 1}, v)
+	}
+
+	// the YAML packages call UnmarshalYAML with a function decoding the
+	// node into the given value
+	unmarshal := func(v any) error {
+		return json.Unmarshal([]byte("[1, 2, 3]"), v)
+	}
+	if err := list.UnmarshalYAML(unmarshal); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if got := list.ToSlice(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("expected %v, got %v", []int{1, 2, 3}, got)
+	}
+
+	// a zero value, as allocated by the decoders for pointer fields
+	var zero cslinkList.CSLinkList[int]
+	if err := zero.UnmarshalYAML(unmarshal); err != nil || zero.Size() != 3 {
+		t.Errorf("expected %v, got %v", 3, zero.Size())
+	}
+}
+
+func TestCSLinkListMergeSorted(t *testing.T) {
+	cs := cslinkList.NewFromSlice([]int{1, 3, 5})
+	other := cslinkList.NewFromSlice([]int{2, 4, 6})
+	cs.MergeSorted(other, func(a, b int) bool { return a < b })
+	if got := cs.ToSlice(); !slices.Equal(got, []int{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3, 4, 5, 6}, got)
+	}
+	if cs.Size() != 6 || other.Size() != 0 {
+		t.Errorf("Expected sizes 6 and 0, got %d and %d", cs.Size(), other.Size())
+	}
+}
+
+func TestHandOverHand(t *testing.T) {
+	h := cslinkList.NewHandOverHand[int]()
+	h.Append(2)
+	h.Append(4)
+	h.Prepend(1)
+	if !h.InsertAfter(2, 3) || h.InsertAfter(9, 10) {
+		t.Errorf("Expected InsertAfter to insert only after an existing value")
+	}
+	h.Append(5)
+	if got := h.ToSlice(); !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3, 4, 5}, got)
+	}
+	if !h.Delete(5) || h.Delete(5) || !h.Contains(4) || h.Contains(5) {
+		t.Errorf("Expected Delete to remove the value once")
+	}
+	h.Append(6)
+	if n := h.RemoveIf(func(v int) bool { return v%2 == 0 }); n != 3 {
+		t.Errorf("Expected %v, got %v", 3, n)
+	}
+	h.ForEach(func(v *int) { *v *= 10 })
+	if got := slices.Collect(h.Iter()); !slices.Equal(got, []int{10, 30}) {
+		t.Errorf("Expected %v, got %v", []int{10, 30}, got)
+	}
+	h.Clear()
+	if !h.IsEmpty() || len(h.ToSlice()) != 0 {
+		t.Errorf("Expected the list to be empty")
+	}
+	h.Append(7)
+	if got := h.ToSlice(); !slices.Equal(got, []int{7}) {
+		t.Errorf("Expected %v, got %v", []int{7}, got)
+	}
+}
+
+func TestHandOverHandConcurrent(t *testing.T) {
+	h := cslinkList.NewHandOverHand[int]()
+	runConcurrent(t, 20, func(j int) {
+		for i := 0; i < 100; i++ {
+			v := j*100 + i
+			if i%2 == 0 {
+				h.Append(v)
+			} else {
+				h.Prepend(v)
+			}
+			if i%4 == 1 {
+				h.Delete(v)
+			}
+			h.Contains(v)
+		}
+	})
+	if got := len(h.ToSlice()); h.Size() != 1500 || got != 1500 {
+		t.Fatalf(errExpectedSizeX, 1500, got)
+	}
+}
+
+func TestHandOverHandTraversalDoesntBlockAppend(t *testing.T) {
+	h := cslinkList.NewHandOverHand[int]()
+	for i := 0; i < 10; i++ {
+		h.Append(i)
+	}
+	held, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ForEach(func(v *int) {
+			if *v == 2 {
+				close(held)
+				<-release
+			}
+		})
+	}()
+	<-held
+	// the traversal holds the node with 2: both ends are still free
+	h.Append(10)
+	h.Prepend(-1)
+	close(release)
+	<-done
+	if h.Size() != 12 {
+		t.Errorf(errExpectedSizeX, 12, h.Size())
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cslinkList
+
+import (
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// hohNode is a node of a HandOverHand list, with its own lock
+type hohNode[T comparable] struct {
+	mu    sync.Mutex
+	value T
+	next  *hohNode[T]
+	end   bool // true for the sentinel after the last node
+}
+
+// HandOverHand is a concurrency-safe linked list with a lock per node
+// (hand-over-hand locking, or lock coupling): the traversals lock the node
+// they are on and the next one, never the whole list, so a long traversal
+// only blocks the operations on the nodes it's holding, and the Append and
+// Prepend calls don't wait for it (unless it's at that end of the list).
+//
+// It trades the rich API of CSLinkList (one lock around a linkList) for a
+// small set of operations that can run side by side. The callbacks run while
+// holding the lock of their node, so they must not change the list.
+type HandOverHand[T comparable] struct {
+	head   *hohNode[T] // sentinel before the first node, never removed
+	tailMu sync.Mutex
+	tail   *hohNode[T] // sentinel after the last node (guarded by tailMu)
+	size   atomic.Uint64
+}
+
+// NewHandOverHand creates a new empty HandOverHand list.
+func NewHandOverHand[T comparable]() *HandOverHand[T] {
+	tail := &hohNode[T]{end: true}
+	return &HandOverHand[T]{head: &hohNode[T]{next: tail}, tail: tail}
+}
+
+// Append adds a value at the end of the list. It doesn't wait for the
+// traversals, unless one is holding the last node.
+func (h *HandOverHand[T]) Append(value T) {
+	h.tailMu.Lock()
+	defer h.tailMu.Unlock()
+	// the tail sentinel becomes the new node, with a new sentinel after it,
+	// so there's no need to lock its predecessor
+	t := h.tail
+	t.mu.Lock()
+	t.value, t.end = value, false
+	t.next = &hohNode[T]{end: true}
+	h.tail = t.next
+	h.size.Add(1)
+	t.mu.Unlock()
+}
+
+// Prepend adds a value at the beginning of the list.
+func (h *HandOverHand[T]) Prepend(value T) {
+	h.head.mu.Lock()
+	defer h.head.mu.Unlock()
+	h.head.next = &hohNode[T]{value: value, next: h.head.next}
+	h.size.Add(1)
+}
+
+// Size returns the number of values in the list.
+func (h *HandOverHand[T]) Size() uint64 {
+	return h.size.Load()
+}
+
+// IsEmpty returns true if the list is empty.
+func (h *HandOverHand[T]) IsEmpty() bool {
+	return h.size.Load() == 0
+}
+
+// read calls visit with each node, in order, holding only the lock of that
+// node, until visit returns false
+func (h *HandOverHand[T]) read(visit func(n *hohNode[T]) bool) {
+	cur := h.head
+	cur.mu.Lock()
+	for {
+		next := cur.next
+		next.mu.Lock()
+		cur.mu.Unlock()
+		cur = next
+		if cur.end || !visit(cur) {
+			break
+		}
+	}
+	cur.mu.Unlock()
+}
+
+// walk calls visit with each node, in order, holding the locks of that node
+// and of the previous one, so visit can ask for the node to be removed; it
+// stops when visit returns stop
+func (h *HandOverHand[T]) walk(visit func(n *hohNode[T]) (remove, stop bool)) {
+	prev := h.head
+	prev.mu.Lock()
+	for {
+		cur := prev.next
+		cur.mu.Lock()
+		if cur.end {
+			cur.mu.Unlock()
+			break
+		}
+		remove, stop := visit(cur)
+		if remove {
+			// nobody else can be waiting for cur, they would be holding prev
+			prev.next = cur.next
+			h.size.Add(^uint64(0))
+			cur.mu.Unlock()
+		} else {
+			prev.mu.Unlock()
+			prev = cur
+		}
+		if stop {
+			break
+		}
+	}
+	prev.mu.Unlock()
+}
+
+// Contains returns true if the list contains the given value.
+func (h *HandOverHand[T]) Contains(value T) bool {
+	found := false
+	h.read(func(n *hohNode[T]) bool {
+		found = n.value == value
+		return !found
+	})
+	return found
+}
+
+// InsertAfter inserts newValue after the first node with the given value,
+// and returns false if there's no such node.
+func (h *HandOverHand[T]) InsertAfter(value, newValue T) bool {
+	inserted := false
+	h.read(func(n *hohNode[T]) bool {
+		if n.value != value {
+			return true
+		}
+		// holding n is enough to link a node after it
+		n.next = &hohNode[T]{value: newValue, next: n.next}
+		h.size.Add(1)
+		inserted = true
+		return false
+	})
+	return inserted
+}
+
+// Delete removes the first node with the given value, and returns false if
+// there's no such node.
+func (h *HandOverHand[T]) Delete(value T) bool {
+	deleted := false
+	h.walk(func(n *hohNode[T]) (bool, bool) {
+		deleted = n.value == value
+		return deleted, deleted
+	})
+	return deleted
+}
+
+// RemoveIf removes all the nodes whose value satisfies pred, in a single
+// pass, and returns how many were removed.
+func (h *HandOverHand[T]) RemoveIf(pred func(T) bool) int {
+	removed := 0
+	h.walk(func(n *hohNode[T]) (bool, bool) {
+		if pred(n.value) {
+			removed++
+			return true, false
+		}
+		return false, false
+	})
+	return removed
+}
+
+// Clear removes all the nodes of the list (the values appended while it's
+// running may be removed as well).
+func (h *HandOverHand[T]) Clear() {
+	h.walk(func(*hohNode[T]) (bool, bool) { return true, false })
+}
+
+// ForEach calls f with a pointer to each value of the list, in order, so f
+// can update the values in place.
+func (h *HandOverHand[T]) ForEach(f func(*T)) {
+	h.read(func(n *hohNode[T]) bool {
+		f(&n.value)
+		return true
+	})
+}
+
+// ToSlice returns the values of the list, in order.
+func (h *HandOverHand[T]) ToSlice() []T {
+	result := make([]T, 0, h.size.Load())
+	h.read(func(n *hohNode[T]) bool {
+		result = append(result, n.value)
+		return true
+	})
+	return result
+}
+
+// Iter returns an iterator over the values of the list. Each value is
+// yielded while holding the lock of its node, so the loop body must not
+// change the list.
+func (h *HandOverHand[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		h.read(func(n *hohNode[T]) bool {
+			return yield(n.value)
+		})
+	}
+}
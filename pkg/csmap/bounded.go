@@ -0,0 +1,212 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csmap
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+
+	gods "github.com/pzaino/gods"
+)
+
+const (
+	ErrMapIsFull = "map is full"
+)
+
+// EvictionPolicy selects what a bounded map does when a new key is added
+// while it's full.
+type EvictionPolicy int
+
+const (
+	// EvictLRU evicts the least recently used entry among a few sampled
+	// ones (an approximation of LRU that doesn't need the write lock to
+	// record the reads)
+	EvictLRU EvictionPolicy = iota
+	// EvictRandom evicts an entry picked uniformly among a few sampled ones
+	// (an approximation: the sampled entries are a run of the map iteration,
+	// which starts at a random position but doesn't shuffle the entries)
+	EvictRandom
+	// RejectNew doesn't add the new key
+	RejectNew
+)
+
+// lruSamples is the number of entries sampled by EvictLRU and EvictRandom
+const lruSamples = 8
+
+// bound holds the state of a bounded map
+type bound[K comparable, V any] struct {
+	capacity  uint64
+	policy    EvictionPolicy
+	onEvict   func(K, V)
+	evictions uint64
+
+	clock  atomic.Uint64
+	stamps map[K]*atomic.Uint64 // the last use of the keys (EvictLRU only)
+}
+
+// NewBounded creates a new concurrency-safe map holding at most capacity
+// entries (at least 1). When a new key is added to the full map, the policy
+// evicts an entry, passed to onEvict (if not nil), or rejects the key. The
+// entries are evicted while the lock is held, so onEvict must not use the
+// map. The options select the locking strategy, as for New.
+//
+// With RejectNew, the methods without an error result (Set, Swap, Update,
+// GetOrSet) silently drop the rejected keys: use TrySet to know.
+// Decode and ApplyChanges evict the entries beyond the capacity, whatever
+// the policy.
+func NewBounded[K comparable, V any](capacity uint64, policy EvictionPolicy, onEvict func(K, V), opts ...gods.Option) *CSMap[K, V] {
+	cm := New[K, V](opts...)
+	cm.bound = &bound[K, V]{capacity: max(capacity, 1), policy: policy, onEvict: onEvict}
+	if policy == EvictLRU {
+		cm.bound.stamps = make(map[K]*atomic.Uint64)
+	}
+	return cm
+}
+
+// Capacity returns the maximum number of entries of the map (0 if it's not
+// bounded).
+func (cm *CSMap[K, V]) Capacity() uint64 {
+	if cm.bound == nil {
+		return 0
+	}
+	return cm.bound.capacity
+}
+
+// Evictions returns the number of entries evicted to make room for new ones.
+func (cm *CSMap[K, V]) Evictions() uint64 {
+	if cm.bound == nil {
+		return 0
+	}
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.bound.evictions
+}
+
+// TrySet sets the value of key. It returns an ErrMapIsFull error, and leaves
+// the map unchanged, if key is new and the map is bounded, full and rejects
+// the new keys.
+func (cm *CSMap[K, V]) TrySet(key K, value V) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	_, existed := cm.m[key]
+	if !existed && !cm.admit() {
+		return errors.New(ErrMapIsFull)
+	}
+	cm.m[key] = value
+	cm.used(key)
+	cm.changed(key)
+	cm.publish(setKind(existed), key, value)
+	return nil
+}
+
+// touch records a read of key. It can be called with the read lock held.
+func (cm *CSMap[K, V]) touch(key K) {
+	if b := cm.bound; b != nil && b.stamps != nil {
+		if stamp, ok := b.stamps[key]; ok {
+			stamp.Store(b.clock.Add(1))
+		}
+	}
+}
+
+// used records a write of key. It must be called with the write lock held.
+func (cm *CSMap[K, V]) used(key K) {
+	if b := cm.bound; b != nil && b.stamps != nil {
+		stamp, ok := b.stamps[key]
+		if !ok {
+			stamp = new(atomic.Uint64)
+			b.stamps[key] = stamp
+		}
+		stamp.Store(b.clock.Add(1))
+	}
+}
+
+// forget drops the state of a removed key. It must be called with the write
+// lock held.
+func (cm *CSMap[K, V]) forget(key K) {
+	if b := cm.bound; b != nil && b.stamps != nil {
+		delete(b.stamps, key)
+	}
+}
+
+// admit makes room for a new key, evicting an entry if needed, and reports
+// whether the key can be added. It must be called with the write lock held.
+func (cm *CSMap[K, V]) admit() bool {
+	b := cm.bound
+	if b == nil || uint64(len(cm.m)) < b.capacity {
+		return true
+	}
+	if b.policy == RejectNew {
+		return false
+	}
+	cm.evict()
+	return true
+}
+
+// evict removes an entry chosen by the policy. It must be called with the
+// write lock held, on a non-empty map.
+func (cm *CSMap[K, V]) evict() {
+	b := cm.bound
+	var victim K
+	oldest, samples := uint64(0), 0
+	// the iteration of a map starts at a random entry
+	for k := range cm.m {
+		if b.stamps == nil {
+			// reservoir sampling: the n-th entry replaces the victim with
+			// probability 1/n
+			if rand.IntN(samples+1) == 0 {
+				victim = k
+			}
+		} else {
+			var stamp uint64
+			if s, ok := b.stamps[k]; ok {
+				stamp = s.Load()
+			}
+			if samples == 0 || stamp < oldest {
+				victim, oldest = k, stamp
+			}
+		}
+		if samples++; samples == lruSamples {
+			break
+		}
+	}
+	v := cm.m[victim]
+	delete(cm.m, victim)
+	cm.forget(victim)
+	b.evictions++
+	cm.changed(victim)
+	cm.publish(gods.EventEvicted, victim, v)
+	if b.onEvict != nil {
+		b.onEvict(victim, v)
+	}
+}
+
+// rebound restores the bound after the entries were replaced (Decode,
+// ApplyChanges): it drops the state of the removed keys and evicts the
+// entries beyond the capacity. It must be called with the write lock held.
+func (cm *CSMap[K, V]) rebound() {
+	b := cm.bound
+	if b == nil {
+		return
+	}
+	for k := range b.stamps {
+		if _, ok := cm.m[k]; !ok {
+			delete(b.stamps, k)
+		}
+	}
+	for uint64(len(cm.m)) > b.capacity {
+		cm.evict()
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csmap
+
+import (
+	"errors"
+	"io"
+
+	gods "github.com/pzaino/gods"
+)
+
+const (
+	ErrChangeTrackingOff = "change tracking is not enabled (see gods.WithChangeTracking)"
+)
+
+// changeSet is the serialized form of the changes of a map
+type changeSet[K comparable, V any] struct {
+	// From and To are the mutations the changes go from and to
+	From, To uint64
+	// Reset means that the map must be cleared before applying the changes
+	Reset   bool
+	Set     []record[K, V]
+	Deleted []K
+}
+
+// changed records a mutation of key. It must be called with the write lock
+// held.
+func (cm *CSMap[K, V]) changed(key K) {
+	if cm.changes != nil {
+		cm.seq++
+		cm.changes[key] = cm.seq
+	}
+}
+
+// replaced records a mutation replacing all the entries (like Clear). It must
+// be called with the write lock held.
+func (cm *CSMap[K, V]) replaced() {
+	if cm.changes != nil {
+		cm.seq++
+		cm.base = cm.seq
+		clear(cm.changes)
+	}
+}
+
+// Seq returns the number of the last mutation of the map (0 if it was not
+// created with gods.WithChangeTracking).
+func (cm *CSMap[K, V]) Seq() uint64 {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.seq
+}
+
+// EncodeChanges implements gods.ChangeTracker: it writes to w the entries set
+// and the keys deleted after the mutation since, or all the entries if since
+// is 0 or the changes since that mutation are not tracked any more (for
+// example because the map was cleared after it). It returns the number of the
+// last mutation included.
+func (cm *CSMap[K, V]) EncodeChanges(w io.Writer, codec gods.Codec, since uint64) (uint64, error) {
+	if codec == nil {
+		return 0, errors.New(gods.ErrCodecIsNil)
+	}
+	cm.mu.RLock()
+	if since > 0 && cm.changes == nil {
+		cm.mu.RUnlock()
+		return 0, errors.New(ErrChangeTrackingOff)
+	}
+	cs := changeSet[K, V]{From: since, To: cm.seq}
+	if since == 0 || since < cm.base {
+		cs.Reset = true
+		cs.Set = make([]record[K, V], 0, len(cm.m))
+		for k, v := range cm.m {
+			cs.Set = append(cs.Set, record[K, V]{Key: k, Value: v})
+		}
+	} else {
+		for k, seq := range cm.changes {
+			if seq <= since {
+				continue
+			}
+			if v, ok := cm.m[k]; ok {
+				cs.Set = append(cs.Set, record[K, V]{Key: k, Value: v})
+			} else {
+				cs.Deleted = append(cs.Deleted, k)
+			}
+		}
+	}
+	cm.mu.RUnlock()
+	return cs.To, codec.Encode(w, cs)
+}
+
+// ApplyChanges implements gods.ChangeTracker: it applies the changes written
+// by EncodeChanges. Unless they contain all the entries, the map must be at
+// the mutation they start from (which is the case after applying the previous
+// changes), otherwise gods.ErrChangesGap is returned. The changes are
+// decoded before acquiring the lock, and they are not tracked.
+func (cm *CSMap[K, V]) ApplyChanges(r io.Reader, codec gods.Codec) error {
+	if codec == nil {
+		return errors.New(gods.ErrCodecIsNil)
+	}
+	var cs changeSet[K, V]
+	if err := codec.Decode(r, &cs); err != nil {
+		return err
+	}
+	if cm.mu == nil {
+		cm.init(make(map[K]V), nil)
+	}
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if !cs.Reset && cs.From != cm.seq {
+		return errors.New(gods.ErrChangesGap)
+	}
+	if cs.Reset {
+		clear(cm.m)
+		cm.publish(gods.EventCleared, *new(K), *new(V))
+	}
+	for _, rec := range cs.Set {
+		_, existed := cm.m[rec.Key]
+		cm.m[rec.Key] = rec.Value
+		cm.used(rec.Key)
+		cm.publish(setKind(existed), rec.Key, rec.Value)
+	}
+	for _, k := range cs.Deleted {
+		if v, ok := cm.m[k]; ok {
+			delete(cm.m, k)
+			cm.forget(k)
+			cm.publish(gods.EventRemoved, k, v)
+		}
+	}
+	// the entries evicted to restore the bound are not tracked either
+	cm.rebound()
+	cm.seq, cm.base = cs.To, cs.To
+	if cm.changes != nil {
+		clear(cm.changes)
+	}
+	return nil
+}
+
+// ForgetChanges implements gods.ChangeTracker: it stops tracking the changes
+// up to the mutation seq.
+func (cm *CSMap[K, V]) ForgetChanges(seq uint64) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	for k, s := range cm.changes {
+		if s <= seq {
+			delete(cm.changes, k)
+		}
+	}
+	cm.base = max(cm.base, seq)
+}
@@ -0,0 +1,485 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package csmap provides a concurrency-safe, typed hash map.
+package csmap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	optional "github.com/pzaino/gods/pkg/optional"
+)
+
+const (
+	ErrKeyNotFound = "key not found"
+	ErrInvalidType = "entry has an invalid type"
+)
+
+// CSMap is a concurrency-safe map.
+type CSMap[K comparable, V any] struct {
+	mu   gods.RWLocker
+	m    map[K]V
+	opts []gods.Option
+	size atomic.Uint64 // published on every write unlock, read without locking
+
+	// change tracking (see changes.go)
+	seq     uint64       // the number of the last mutation
+	base    uint64       // the changes up to this mutation are not tracked
+	changes map[K]uint64 // the last mutation of each changed key, nil if not tracking
+
+	bus gods.Bus[K, V] // the subscribers to the changes (see events.go)
+
+	bound *bound[K, V] // nil if the map is not bounded (see bounded.go)
+}
+
+// New creates a new concurrency-safe map.
+// The options select the locking strategy (the default is a sync.RWMutex).
+func New[K comparable, V any](opts ...gods.Option) *CSMap[K, V] {
+	return newCSMap(make(map[K]V), opts)
+}
+
+// newCSMap wraps m in a CSMap using the locking strategy selected by opts
+func newCSMap[K comparable, V any](m map[K]V, opts []gods.Option) *CSMap[K, V] {
+	cm := &CSMap[K, V]{}
+	cm.init(m, opts)
+	return cm
+}
+
+// init sets up cm to wrap m
+func (cm *CSMap[K, V]) init(m map[K]V, opts []gods.Option) {
+	cm.m, cm.opts = m, opts
+	cm.size.Store(uint64(len(m)))
+	if gods.NewOptions(opts...).ChangeTracking {
+		// the initial content counts as the first mutation, so the changes
+		// since a snapshot of it are not mistaken for the whole content
+		cm.seq, cm.base = 1, 1
+		cm.changes = make(map[K]uint64)
+	}
+	cm.mu = gods.OnUnlock(gods.NewLocker(opts...), func() {
+		cm.size.Store(uint64(len(cm.m)))
+	})
+	gods.ObserveSize(cm.mu, cm.size.Load)
+}
+
+// NewFromMap creates a new concurrency-safe map with a copy of the entries of m.
+func NewFromMap[K comparable, V any](m map[K]V, opts ...gods.Option) *CSMap[K, V] {
+	return newCSMap(maps.Clone(m), opts)
+}
+
+// FromSyncMap creates a new concurrency-safe map with the entries of a
+// sync.Map. It returns an error if a key is not a K or a value is not a V.
+func FromSyncMap[K comparable, V any](sm *sync.Map, opts ...gods.Option) (*CSMap[K, V], error) {
+	m := make(map[K]V)
+	var err error
+	sm.Range(func(key, value any) bool {
+		k, ok := key.(K)
+		if !ok {
+			err = fmt.Errorf("%s: key %T", ErrInvalidType, key)
+			return false
+		}
+		v, ok := value.(V)
+		if !ok && value != nil {
+			err = fmt.Errorf("%s: value %T", ErrInvalidType, value)
+			return false
+		}
+		m[k] = v
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newCSMap(m, opts), nil
+}
+
+// ToSyncMap returns a sync.Map with the entries of the map.
+func (cm *CSMap[K, V]) ToSyncMap() *sync.Map {
+	sm := &sync.Map{}
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	for k, v := range cm.m {
+		sm.Store(k, v)
+	}
+	return sm
+}
+
+// Set sets the value of key.
+func (cm *CSMap[K, V]) Set(key K, value V) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	existed := false
+	if cm.bus.Active() || cm.bound != nil {
+		_, existed = cm.m[key]
+	}
+	if !existed && !cm.admit() {
+		return
+	}
+	cm.m[key] = value
+	cm.used(key)
+	cm.changed(key)
+	cm.publish(setKind(existed), key, value)
+}
+
+// Get returns the value of key and true, or the zero value and false if the
+// key is not in the map.
+func (cm *CSMap[K, V]) Get(key K) (V, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	v, ok := cm.m[key]
+	if ok {
+		cm.touch(key)
+	}
+	return v, ok
+}
+
+// GetOpt returns the value of key as an Option (None if the key is not in
+// the map).
+func (cm *CSMap[K, V]) GetOpt(key K) optional.Option[V] {
+	return optional.Of(cm.Get(key))
+}
+
+// Contains returns true if key is in the map.
+func (cm *CSMap[K, V]) Contains(key K) bool {
+	_, ok := cm.Get(key)
+	return ok
+}
+
+// Delete removes key from the map. It returns an error if the key is not in
+// the map.
+func (cm *CSMap[K, V]) Delete(key K) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	v, ok := cm.m[key]
+	if !ok {
+		return errors.New(ErrKeyNotFound)
+	}
+	delete(cm.m, key)
+	cm.forget(key)
+	cm.changed(key)
+	cm.publish(gods.EventRemoved, key, v)
+	return nil
+}
+
+// GetOrSet returns the value of key if it is in the map (and true);
+// otherwise it sets it to value and returns value (and false).
+func (cm *CSMap[K, V]) GetOrSet(key K, value V) (V, bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if v, ok := cm.m[key]; ok {
+		cm.touch(key)
+		return v, true
+	}
+	if !cm.admit() {
+		return value, false
+	}
+	cm.m[key] = value
+	cm.used(key)
+	cm.changed(key)
+	cm.publish(gods.EventAdded, key, value)
+	return value, false
+}
+
+// GetAndDelete removes key from the map, returning its previous value (and
+// whether it was in the map).
+func (cm *CSMap[K, V]) GetAndDelete(key K) (V, bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	v, ok := cm.m[key]
+	if ok {
+		delete(cm.m, key)
+		cm.forget(key)
+		cm.changed(key)
+		cm.publish(gods.EventRemoved, key, v)
+	}
+	return v, ok
+}
+
+// Swap sets the value of key and returns its previous value (and whether
+// there was one).
+func (cm *CSMap[K, V]) Swap(key K, value V) (V, bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	v, ok := cm.m[key]
+	if !ok && !cm.admit() {
+		return v, false
+	}
+	cm.m[key] = value
+	cm.used(key)
+	cm.changed(key)
+	cm.publish(setKind(ok), key, value)
+	return v, ok
+}
+
+// CompareAndSwap sets the value of key to newValue if its current value is
+// equal to old. As with sync.Map, V must be comparable at run time, or it
+// panics.
+func (cm *CSMap[K, V]) CompareAndSwap(key K, old, newValue V) bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	v, ok := cm.m[key]
+	if !ok || any(v) != any(old) {
+		return false
+	}
+	cm.m[key] = newValue
+	cm.used(key)
+	cm.changed(key)
+	cm.publish(gods.EventUpdated, key, newValue)
+	return true
+}
+
+// CompareAndDelete removes key if its value is equal to old. As with
+// sync.Map, V must be comparable at run time, or it panics.
+func (cm *CSMap[K, V]) CompareAndDelete(key K, old V) bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	v, ok := cm.m[key]
+	if !ok || any(v) != any(old) {
+		return false
+	}
+	delete(cm.m, key)
+	cm.forget(key)
+	cm.changed(key)
+	cm.publish(gods.EventRemoved, key, v)
+	return true
+}
+
+// Update sets the value of key to the result of fn, called with the current
+// value (and whether it exists) while the lock is held. fn isn't called if
+// the key is rejected by a full bounded map.
+func (cm *CSMap[K, V]) Update(key K, fn func(value V, ok bool) V) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	v, ok := cm.m[key]
+	if !ok && !cm.admit() {
+		return
+	}
+	v = fn(v, ok)
+	cm.m[key] = v
+	cm.used(key)
+	cm.changed(key)
+	cm.publish(setKind(ok), key, v)
+}
+
+// Size returns the number of entries in the map. It doesn't acquire the lock.
+func (cm *CSMap[K, V]) Size() uint64 {
+	return cm.size.Load()
+}
+
+// MemoryUsage returns an estimate of the bytes held by the map (see the gods
+// package), adding the memory returned by extra for every entry.
+func (cm *CSMap[K, V]) MemoryUsage(extra ...func(K, V) uint64) uint64 {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return uint64(unsafe.Sizeof(*cm)) + gods.MapMemory(cm.m, extra...)
+}
+
+// Stats returns the statistics of the map: if it was created with
+// gods.WithLatencyHistograms, the latency histograms of its operations.
+func (cm *CSMap[K, V]) Stats() gods.Stats {
+	return gods.Stats{Latency: gods.LockLatency(cm.mu)}
+}
+
+// ResetLatency clears the latency histograms returned by Stats.
+func (cm *CSMap[K, V]) ResetLatency() {
+	gods.ResetLockLatency(cm.mu)
+}
+
+// Validate checks the invariants of the map (see gods.Validator): the
+// published size, the capacity of a bounded map and, when tracking the
+// changes, the mutation numbers.
+func (cm *CSMap[K, V]) Validate() error {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	if size := cm.size.Load(); size != uint64(len(cm.m)) {
+		return gods.Invariantf("published size is %d but the map holds %d entries", size, len(cm.m))
+	}
+	if cm.bound != nil && uint64(len(cm.m)) > cm.bound.capacity {
+		return gods.Invariantf("the map holds %d entries, more than its capacity (%d)", len(cm.m), cm.bound.capacity)
+	}
+	if cm.base > cm.seq {
+		return gods.Invariantf("changes tracked since mutation %d, after the last one (%d)", cm.base, cm.seq)
+	}
+	for k, s := range cm.changes {
+		if s > cm.seq {
+			return gods.Invariantf("key %v changed by mutation %d, after the last one (%d)", k, s, cm.seq)
+		}
+	}
+	return nil
+}
+
+// Dump writes the entries of the map to w, sorted by their formatted key, and
+// the state of the change tracking, for debugging.
+func (cm *CSMap[K, V]) Dump(w io.Writer) error {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	d := gods.NewDumper(w)
+	d.Line(0, "CSMap size=%d", len(cm.m))
+	if cm.changes != nil {
+		d.Line(1, "change tracking: seq=%d base=%d changed keys=%d", cm.seq, cm.base, len(cm.changes))
+	}
+	lines := make([]string, 0, len(cm.m))
+	for k, v := range cm.m {
+		lines = append(lines, fmt.Sprintf("%v: %v", k, v))
+	}
+	slices.Sort(lines)
+	for _, line := range lines {
+		d.Line(1, "%s", line)
+	}
+	return d.Err()
+}
+
+// IsEmpty returns true if the map is empty. It doesn't acquire the lock.
+func (cm *CSMap[K, V]) IsEmpty() bool {
+	return cm.Size() == 0
+}
+
+// Clear removes all the entries.
+func (cm *CSMap[K, V]) Clear() {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	clear(cm.m)
+	if cm.bound != nil {
+		clear(cm.bound.stamps)
+	}
+	cm.replaced()
+	cm.publish(gods.EventCleared, *new(K), *new(V))
+}
+
+// Keys returns an iterator over a snapshot of the keys of the map (in no
+// particular order). Like All, the loop body can modify the map.
+func (cm *CSMap[K, V]) Keys() iter.Seq[K] {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return slices.Values(slices.Collect(maps.Keys(cm.m)))
+}
+
+// Values returns an iterator over a snapshot of the values of the map (in no
+// particular order). Like All, the loop body can modify the map.
+func (cm *CSMap[K, V]) Values() iter.Seq[V] {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return slices.Values(slices.Collect(maps.Values(cm.m)))
+}
+
+// ToMap returns a copy of the entries of the map.
+func (cm *CSMap[K, V]) ToMap() map[K]V {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return maps.Clone(cm.m)
+}
+
+// All returns an iterator over a snapshot of the entries of the map (in no
+// particular order). The lock is not held while iterating, so the loop body
+// can modify the map.
+func (cm *CSMap[K, V]) All() iter.Seq2[K, V] {
+	return maps.All(cm.ToMap())
+}
+
+// record is the serialized form of a map entry
+type record[K any, V any] struct {
+	Key   K
+	Value V
+}
+
+// Encode writes the entries of the map to w using the given codec
+func (cm *CSMap[K, V]) Encode(w io.Writer, codec gods.Codec) error {
+	cm.mu.RLock()
+	records := make([]record[K, V], 0, len(cm.m))
+	for k, v := range cm.m {
+		records = append(records, record[K, V]{Key: k, Value: v})
+	}
+	cm.mu.RUnlock()
+	return gods.EncodeSlice(w, codec, records)
+}
+
+// Decode replaces the content of the map with the entries read from r using the given codec.
+// The data is decoded before acquiring the lock, so readers are blocked only while the map is rebuilt.
+// A zero value (as allocated by the decoders for pointer fields) is
+// initialized with the default options.
+func (cm *CSMap[K, V]) Decode(r io.Reader, codec gods.Codec) error {
+	records, err := gods.DecodeSlice[record[K, V]](r, codec)
+	if err != nil {
+		return err
+	}
+	if cm.mu == nil {
+		cm.init(make(map[K]V), nil)
+	}
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	clear(cm.m)
+	for _, rec := range records {
+		cm.m[rec.Key] = rec.Value
+	}
+	cm.replaced()
+	cm.publishReplaced()
+	cm.rebound()
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler: the map is encoded as a JSON
+// array of key/value records (see gods.MarshalText).
+func (cm *CSMap[K, V]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(cm)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (cm *CSMap[K, V]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(cm, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (cm *CSMap[K, V]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(cm)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (cm *CSMap[K, V]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(cm, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (cm *CSMap[K, V]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(cm)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (cm *CSMap[K, V]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(cm, data)
+}
+
+// ContentionStats returns the lock statistics of the CSMap. They are
+// collected only if it was created with the gods.WithContentionStats option.
+func (cm *CSMap[K, V]) ContentionStats() gods.ContentionStats {
+	return gods.LockContentionStats(cm.mu)
+}
+
+// Locker returns the lock of the CSMap (see gods.Atomically).
+func (cm *CSMap[K, V]) Locker() gods.RWLocker {
+	return cm.mu
+}
+
+// Unsafe returns the underlying map. It must only be used while the lock is
+// held, typically inside gods.Atomically. The changes made through it are not
+// tracked by gods.WithChangeTracking, nor bounded by NewBounded.
+func (cm *CSMap[K, V]) Unsafe() map[K]V {
+	return cm.m
+}
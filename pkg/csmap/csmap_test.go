@@ -0,0 +1,431 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csmap_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	csmap "github.com/pzaino/gods/pkg/csmap"
+	godstest "github.com/pzaino/gods/pkg/godstest"
+)
+
+const (
+	errExpectedX       = "expected %v, got %v"
+	errExpectedNoError = "expected no error, got %v"
+)
+
+func TestBasicOperations(t *testing.T) {
+	m := csmap.New[string, int]()
+	m.Set("a", 1)
+	m.Set("b", 2)
+	if v, ok := m.Get("a"); !ok || v != 1 {
+		t.Errorf(errExpectedX, 1, v)
+	}
+	if m.Size() != 2 || m.IsEmpty() {
+		t.Errorf(errExpectedX, 2, m.Size())
+	}
+	if err := m.Delete("a"); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+	if err := m.Delete("a"); err == nil || err.Error() != csmap.ErrKeyNotFound {
+		t.Errorf(errExpectedX, csmap.ErrKeyNotFound, err)
+	}
+	if m.Contains("a") || !m.Contains("b") {
+		t.Errorf(errExpectedX, []string{"b"}, slices.Collect(m.Keys()))
+	}
+	m.Update("b", func(v int, ok bool) int { return v * 10 })
+	if v, _ := m.Get("b"); v != 20 {
+		t.Errorf(errExpectedX, 20, v)
+	}
+	if !slices.Equal(slices.Collect(m.Keys()), []string{"b"}) {
+		t.Errorf(errExpectedX, []string{"b"}, slices.Collect(m.Keys()))
+	}
+	if !slices.Equal(slices.Collect(m.Values()), []int{20}) {
+		t.Errorf(errExpectedX, []int{20}, slices.Collect(m.Values()))
+	}
+	m.Clear()
+	if !m.IsEmpty() {
+		t.Errorf(errExpectedX, 0, m.Size())
+	}
+}
+
+func TestAtomicOperations(t *testing.T) {
+	m := csmap.NewFromMap(map[string]int{"a": 1})
+	if v, loaded := m.GetOrSet("a", 5); !loaded || v != 1 {
+		t.Errorf(errExpectedX, 1, v)
+	}
+	if v, loaded := m.GetOrSet("b", 5); loaded || v != 5 {
+		t.Errorf(errExpectedX, 5, v)
+	}
+	if prev, loaded := m.Swap("b", 6); !loaded || prev != 5 {
+		t.Errorf(errExpectedX, 5, prev)
+	}
+	if m.CompareAndSwap("b", 5, 7) || !m.CompareAndSwap("b", 6, 7) {
+		t.Errorf("CompareAndSwap must succeed only with the current value")
+	}
+	if m.CompareAndDelete("b", 6) || !m.CompareAndDelete("b", 7) {
+		t.Errorf("CompareAndDelete must succeed only with the current value")
+	}
+	if v, ok := m.GetAndDelete("a"); !ok || v != 1 || !m.IsEmpty() {
+		t.Errorf(errExpectedX, 1, v)
+	}
+}
+
+func TestSyncMapConversions(t *testing.T) {
+	var sm sync.Map
+	sm.Store("a", 1)
+	sm.Store("b", 2)
+	m, err := csmap.FromSyncMap[string, int](&sm)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if !maps.Equal(m.ToMap(), map[string]int{"a": 1, "b": 2}) {
+		t.Errorf(errExpectedX, map[string]int{"a": 1, "b": 2}, m.ToMap())
+	}
+	back := m.ToSyncMap()
+	if v, ok := back.Load("b"); !ok || v != 2 {
+		t.Errorf(errExpectedX, 2, v)
+	}
+
+	sm.Store(3, "wrong")
+	if _, err := csmap.FromSyncMap[string, int](&sm); err == nil {
+		t.Errorf(errExpectedX, "an error", nil)
+	}
+}
+
+func TestSyncMapWrapper(t *testing.T) {
+	var m csmap.SyncMap[string, int]
+	godstest.Stress(8, 100, func(g, i int) {
+		m.Store("shared", i)
+		m.LoadOrStore("once", g)
+		m.Range(func(k string, v int) bool {
+			m.Delete("missing") // the callback can modify the map
+			return true
+		})
+	})
+	if _, ok := m.Load("once"); !ok || m.Map().Size() != 2 {
+		t.Errorf(errExpectedX, 2, m.Map().Size())
+	}
+	if v, loaded := m.LoadAndDelete("once"); !loaded || v < 0 {
+		t.Errorf(errExpectedX, "a value", v)
+	}
+	m.Store("x", 1)
+	if prev, _ := m.Swap("x", 2); prev != 1 || !m.CompareAndSwap("x", 2, 3) || !m.CompareAndDelete("x", 3) {
+		t.Errorf("unexpected Swap/CompareAndSwap/CompareAndDelete results")
+	}
+	count := 0
+	m.Range(func(string, int) bool { count++; return false })
+	if count != 1 {
+		t.Errorf(errExpectedX, 1, count)
+	}
+	m.Clear()
+	if m.Map().Size() != 0 {
+		t.Errorf(errExpectedX, 0, m.Map().Size())
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	m := csmap.NewFromMap(map[string]int{"a": 1, "b": 2})
+	for _, codec := range []gods.Codec{gods.JSONCodec{}, gods.GobCodec{}} {
+		var buf bytes.Buffer
+		if err := m.Encode(&buf, codec); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+		d := csmap.New[string, int]()
+		if err := d.Decode(&buf, codec); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+		if !maps.Equal(d.ToMap(), m.ToMap()) {
+			t.Errorf(errExpectedX, m.ToMap(), d.ToMap())
+		}
+	}
+	var config struct{ Limits *csmap.CSMap[string, int] }
+	data, _ := json.Marshal(map[string]any{"Limits": m})
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if v, _ := config.Limits.Get("b"); v != 2 || config.Limits.Size() != 2 {
+		t.Errorf(errExpectedX, 2, v)
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	m := csmap.New[int, int](gods.WithSharding(4))
+	godstest.Stress(8, 200, func(g, i int) {
+		m.Update(i%10, func(v int, _ bool) int { return v + 1 })
+		m.Get(i % 10)
+	})
+	total := 0
+	for _, v := range m.All() {
+		total += v
+	}
+	if total != 8*200 {
+		t.Errorf(errExpectedX, 8*200, total)
+	}
+}
+
+func TestChangeTracking(t *testing.T) {
+	m := csmap.NewFromMap(map[string]int{"a": 1, "b": 2}, gods.WithChangeTracking())
+	replica := csmap.New[string, int]()
+	var buf bytes.Buffer
+	full, err := m.EncodeChanges(&buf, gods.GobCodec{}, 0)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if err := replica.ApplyChanges(&buf, gods.GobCodec{}); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+
+	m.Set("c", 3)
+	_ = m.Delete("a")
+	m.Set("b", 20)
+	buf.Reset()
+	seq, err := m.EncodeChanges(&buf, gods.GobCodec{}, full)
+	if err != nil || seq != full+3 || seq != m.Seq() {
+		t.Fatalf(errExpectedX, full+3, seq)
+	}
+	delta := slices.Clone(buf.Bytes())
+	if err := replica.ApplyChanges(bytes.NewReader(delta), gods.GobCodec{}); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if !maps.Equal(replica.ToMap(), m.ToMap()) {
+		t.Errorf(errExpectedX, m.ToMap(), replica.ToMap())
+	}
+	// the same delta can't be applied twice
+	if err := replica.ApplyChanges(bytes.NewReader(delta), gods.GobCodec{}); err == nil || err.Error() != gods.ErrChangesGap {
+		t.Errorf(errExpectedX, gods.ErrChangesGap, err)
+	}
+
+	// after a Clear or ForgetChanges, older deltas contain all the entries
+	m.Clear()
+	m.Set("d", 4)
+	m.ForgetChanges(m.Seq())
+	buf.Reset()
+	if _, err := m.EncodeChanges(&buf, gods.GobCodec{}, seq); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if err := replica.ApplyChanges(&buf, gods.GobCodec{}); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if !maps.Equal(replica.ToMap(), map[string]int{"d": 4}) {
+		t.Errorf(errExpectedX, map[string]int{"d": 4}, replica.ToMap())
+	}
+
+	if _, err := replica.EncodeChanges(&buf, gods.GobCodec{}, 1); err == nil || err.Error() != csmap.ErrChangeTrackingOff {
+		t.Errorf(errExpectedX, csmap.ErrChangeTrackingOff, err)
+	}
+}
+
+func TestMemoryUsage(t *testing.T) {
+	cm := csmap.New[int, int]()
+	empty := cm.MemoryUsage()
+	for i := 0; i < 70; i++ {
+		cm.Set(i, i)
+	}
+	// 70 slots of 17 bytes, 7/8 full
+	if got := cm.MemoryUsage(); got != empty+70*17*8/7 {
+		t.Errorf(errExpectedX, empty+70*17*8/7, got)
+	}
+	if got := cm.MemoryUsage(func(int, int) uint64 { return 1 }); got != empty+70*17*8/7+70 {
+		t.Errorf(errExpectedX, empty+70*17*8/7+70, got)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	cm := csmap.New[string, int]()
+	cm.Set("a", 0)
+	sub := cm.Subscribe(16, gods.DropNewest)
+	cm.Set("a", 1)
+	cm.Set("b", 2)
+	cm.Update("b", func(v int, _ bool) int { return v + 1 })
+	_ = cm.Delete("a")
+	cm.Clear()
+	sub.Close()
+	cm.Set("c", 4)
+
+	want := []gods.Event[string, int]{
+		{Kind: gods.EventUpdated, Key: "a", Value: 1},
+		{Kind: gods.EventAdded, Key: "b", Value: 2},
+		{Kind: gods.EventUpdated, Key: "b", Value: 3},
+		{Kind: gods.EventRemoved, Key: "a", Value: 1},
+		{Kind: gods.EventCleared},
+	}
+	var got []gods.Event[string, int]
+	for e := range sub.C() {
+		got = append(got, e)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf(errExpectedX, want, got)
+	}
+}
+
+func TestGetOpt(t *testing.T) {
+	cm := csmap.New[string, int]()
+	cm.Set("a", 1)
+	if v := cm.GetOpt("a"); v.MustGet() != 1 {
+		t.Errorf(errExpectedX, 1, v)
+	}
+	if v := cm.GetOpt("b"); v.IsSome() || v.OrElse(7) != 7 {
+		t.Errorf(errExpectedX, "None", v)
+	}
+}
+
+func TestBounded(t *testing.T) {
+	var evicted []string
+	m := csmap.NewBounded[string, int](4, csmap.EvictLRU, func(k string, _ int) {
+		evicted = append(evicted, k)
+	})
+	for i, k := range []string{"a", "b", "c", "d"} {
+		m.Set(k, i)
+	}
+	m.Get("a") // "b" is now the least recently used
+	m.Set("e", 4)
+	m.Set("c", 20) // an update doesn't evict
+	m.Set("f", 5)
+	if !slices.Equal(evicted, []string{"b", "d"}) {
+		t.Errorf(errExpectedX, []string{"b", "d"}, evicted)
+	}
+	if m.Size() != 4 || m.Capacity() != 4 || m.Evictions() != 2 {
+		t.Errorf(errExpectedX, 4, m.Size())
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+
+	r := csmap.NewBounded[string, int](2, csmap.EvictRandom, nil)
+	for i := range 10 {
+		r.Swap(string(rune('a'+i)), i)
+	}
+	if r.Size() != 2 || r.Evictions() != 8 {
+		t.Errorf(errExpectedX, 2, r.Size())
+	}
+}
+
+func TestBoundedReject(t *testing.T) {
+	m := csmap.NewBounded[string, int](2, csmap.RejectNew, nil)
+	m.Set("a", 1)
+	m.Set("b", 2)
+	if err := m.TrySet("c", 3); err == nil || err.Error() != csmap.ErrMapIsFull {
+		t.Errorf(errExpectedX, csmap.ErrMapIsFull, err)
+	}
+	if err := m.TrySet("a", 10); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+	m.Set("c", 3)
+	if _, loaded := m.GetOrSet("d", 4); loaded {
+		t.Errorf(errExpectedX, false, loaded)
+	}
+	called := false
+	m.Update("e", func(v int, ok bool) int { called = true; return v })
+	if called || m.Contains("c") || m.Contains("d") || m.Contains("e") {
+		t.Errorf(errExpectedX, []string{"a", "b"}, slices.Sorted(m.Keys()))
+	}
+
+	// restoring more entries than the capacity evicts the extra ones
+	data, _ := json.Marshal(csmap.NewFromMap(map[string]int{"x": 1, "y": 2, "z": 3}))
+	if err := json.Unmarshal(data, m); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if m.Size() != 2 {
+		t.Errorf(errExpectedX, 2, m.Size())
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+}
+
+func TestBoundedEvents(t *testing.T) {
+	m := csmap.NewBounded[string, int](2, csmap.EvictLRU, nil)
+	m.Set("a", 1)
+	m.Set("b", 2)
+	sub := m.Subscribe(16, gods.DropNewest)
+	m.Get("a")
+	m.Set("c", 3)
+	_ = m.Delete("a")
+	sub.Close()
+
+	want := []gods.Event[string, int]{
+		{Kind: gods.EventEvicted, Key: "b", Value: 2},
+		{Kind: gods.EventAdded, Key: "c", Value: 3},
+		{Kind: gods.EventRemoved, Key: "a", Value: 1},
+	}
+	var got []gods.Event[string, int]
+	for e := range sub.C() {
+		got = append(got, e)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf(errExpectedX, want, got)
+	}
+}
+
+func TestBoundedApplyChanges(t *testing.T) {
+	m := csmap.New[string, int](gods.WithChangeTracking())
+	replica := csmap.NewBounded[string, int](2, csmap.EvictLRU, nil, gods.WithChangeTracking())
+	var seq uint64
+	// the evictions made while applying a delta are not tracked, so the
+	// replica stays at the mutation the next delta starts from
+	for i, k := range []string{"a", "b", "c", "d", "e", "f"} {
+		m.Set(k, i)
+		if i%2 == 0 {
+			continue
+		}
+		var buf bytes.Buffer
+		next, err := m.EncodeChanges(&buf, gods.GobCodec{}, seq)
+		if err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+		if err := replica.ApplyChanges(&buf, gods.GobCodec{}); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+		if replica.Seq() != next || replica.Size() != 2 {
+			t.Fatalf(errExpectedX, next, replica.Seq())
+		}
+		seq = next
+	}
+	if !maps.Equal(replica.ToMap(), map[string]int{"e": 4, "f": 5}) || replica.Evictions() != 4 {
+		t.Errorf(errExpectedX, map[string]int{"e": 4, "f": 5}, replica.ToMap())
+	}
+	if err := replica.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+}
+
+func TestBoundedConcurrent(t *testing.T) {
+	m := csmap.NewBounded[int, int](100, csmap.EvictLRU, nil)
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				m.Set(g*1000+i, i)
+				m.Get(g*1000 + i/2)
+			}
+		}()
+	}
+	wg.Wait()
+	if m.Size() != 100 || m.Evictions() != 7900 {
+		t.Errorf(errExpectedX, 100, m.Size())
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csmap
+
+import (
+	gods "github.com/pzaino/gods"
+)
+
+// Subscribe returns a subscription receiving the changes of the map made from
+// now on: gods.EventAdded and gods.EventUpdated with the new value of a key,
+// gods.EventRemoved with the value of a deleted key, gods.EventEvicted with the
+// value of a key evicted by a bounded map (see NewBounded) and
+// gods.EventCleared. Restoring the map with Decode publishes
+// gods.EventCleared followed by the restored entries, and ApplyChanges
+// publishes the changes applied. The events are published while the lock is
+// held, so with the gods.Block policy a subscriber must not use the map.
+func (cm *CSMap[K, V]) Subscribe(buffer int, policy gods.OverflowPolicy) *gods.Subscription[K, V] {
+	return cm.bus.Subscribe(buffer, policy)
+}
+
+// setKind returns the kind of the event published by setting a key, given
+// whether it was already in the map
+func setKind(existed bool) gods.EventKind {
+	if existed {
+		return gods.EventUpdated
+	}
+	return gods.EventAdded
+}
+
+// publish publishes a change of key, if the map has subscribers. It must be
+// called with the write lock held.
+func (cm *CSMap[K, V]) publish(kind gods.EventKind, key K, value V) {
+	if cm.bus.Active() {
+		cm.bus.Publish(gods.Event[K, V]{Kind: kind, Key: key, Value: value})
+	}
+}
+
+// publishReplaced publishes the replacement of all the entries, as a
+// gods.EventCleared followed by the new entries. It must be called with the
+// write lock held.
+func (cm *CSMap[K, V]) publishReplaced() {
+	if !cm.bus.Active() {
+		return
+	}
+	cm.bus.Publish(gods.Event[K, V]{Kind: gods.EventCleared})
+	for k, v := range cm.m {
+		cm.bus.Publish(gods.Event[K, V]{Kind: gods.EventAdded, Key: k, Value: v})
+	}
+}
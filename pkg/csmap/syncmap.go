@@ -0,0 +1,105 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csmap
+
+import (
+	"sync"
+)
+
+// SyncMap has the method set of sync.Map, with typed keys and values, over a
+// CSMap. Replacing a sync.Map with a SyncMap only requires to change its
+// declaration (and to remove the type assertions):
+//
+//	var m csmap.SyncMap[string, int] // was: var m sync.Map
+//	m.Store("a", 1)
+//	v, ok := m.Load("a")
+//
+// The zero value is an empty map ready to use. A SyncMap must not be copied
+// after first use.
+type SyncMap[K comparable, V any] struct {
+	once sync.Once
+	m    *CSMap[K, V]
+}
+
+// NewSyncMap returns a SyncMap over cm
+func NewSyncMap[K comparable, V any](cm *CSMap[K, V]) *SyncMap[K, V] {
+	return &SyncMap[K, V]{m: cm}
+}
+
+// Map returns the CSMap used by the SyncMap
+func (s *SyncMap[K, V]) Map() *CSMap[K, V] {
+	s.once.Do(func() {
+		if s.m == nil {
+			s.m = New[K, V]()
+		}
+	})
+	return s.m
+}
+
+// Load returns the value stored for key, if any
+func (s *SyncMap[K, V]) Load(key K) (value V, ok bool) {
+	return s.Map().Get(key)
+}
+
+// Store sets the value for key
+func (s *SyncMap[K, V]) Store(key K, value V) {
+	s.Map().Set(key, value)
+}
+
+// LoadOrStore returns the existing value for key if present (loaded is
+// true); otherwise it stores and returns value
+func (s *SyncMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	return s.Map().GetOrSet(key, value)
+}
+
+// LoadAndDelete deletes the value for key, returning the previous value if any
+func (s *SyncMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	return s.Map().GetAndDelete(key)
+}
+
+// Delete deletes the value for key
+func (s *SyncMap[K, V]) Delete(key K) {
+	s.Map().GetAndDelete(key)
+}
+
+// Swap stores value for key and returns the previous value if any
+func (s *SyncMap[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	return s.Map().Swap(key, value)
+}
+
+// CompareAndSwap stores newValue for key if the current value is equal to old
+func (s *SyncMap[K, V]) CompareAndSwap(key K, old, newValue V) (swapped bool) {
+	return s.Map().CompareAndSwap(key, old, newValue)
+}
+
+// CompareAndDelete deletes key if its value is equal to old
+func (s *SyncMap[K, V]) CompareAndDelete(key K, old V) (deleted bool) {
+	return s.Map().CompareAndDelete(key, old)
+}
+
+// Range calls f for each key and value of a snapshot of the map, until f
+// returns false. As with sync.Map, f can modify the map.
+func (s *SyncMap[K, V]) Range(f func(key K, value V) bool) {
+	for k, v := range s.Map().All() {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+// Clear deletes all the entries
+func (s *SyncMap[K, V]) Clear() {
+	s.Map().Clear()
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package csqueue provides a concurrency-safe queue (FIFO) using the queue
+// package. The queue can be bounded, and it can block the consumers until an
+// item is available and the producers until there is room for a new item.
+package csqueue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	gods "github.com/pzaino/gods"
+	optional "github.com/pzaino/gods/pkg/optional"
+	queue "github.com/pzaino/gods/pkg/queue"
+)
+
+const (
+	ErrQueueIsEmpty = queue.ErrQueueIsEmpty
+	ErrQueueIsFull  = queue.ErrQueueIsFull
+)
+
+// CSQueue is a concurrency-safe queue.
+type CSQueue[T comparable] struct {
+	mu       gods.RWLocker
+	q        *queue.Queue[T]
+	capacity uint64 // 0 if the queue is unbounded
+	opts     []gods.Option
+	size     atomic.Uint64 // published on every write unlock, read without locking
+
+	// changed is closed (and cleared) when the size of the queue changes, to
+	// wake up the goroutines waiting for an item or for room for one. It's
+	// created by the first of them, so the queue doesn't allocate it when
+	// nobody is waiting.
+	changed chan struct{}
+
+	retries map[T]*retry // the items enqueued with EnqueueWithRetry
+	dead    deadLetter[T]
+}
+
+// New creates a new unbounded concurrency-safe queue.
+// The options select the locking strategy (the default is a sync.RWMutex).
+func New[T comparable](opts ...gods.Option) *CSQueue[T] {
+	return NewWithCapacity[T](0, opts...)
+}
+
+// NewWithCapacity creates a new concurrency-safe queue holding at most
+// capacity items (0 for an unbounded queue).
+func NewWithCapacity[T comparable](capacity uint64, opts ...gods.Option) *CSQueue[T] {
+	cq := &CSQueue[T]{q: queue.New[T](), capacity: capacity, opts: opts}
+	cq.mu = gods.OnUnlock(gods.NewLocker(opts...), cq.publish)
+	gods.ObserveSize(cq.mu, cq.size.Load)
+	return cq
+}
+
+// publish stores the size of the queue and wakes up the waiting goroutines if
+// it changed. It runs under the write lock.
+func (cq *CSQueue[T]) publish() {
+	size := cq.q.Size()
+	if cq.size.Swap(size) != size && cq.changed != nil {
+		close(cq.changed)
+		cq.changed = nil
+	}
+}
+
+// Enqueue adds an item to the end of the queue. It returns an ErrQueueIsFull
+// error if the queue is bounded and full.
+func (cq *CSQueue[T]) Enqueue(item T) error {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	if !cq.tryEnqueue(item) {
+		return errors.New(ErrQueueIsFull)
+	}
+	return nil
+}
+
+// Dequeue removes and returns the first item in the queue.
+func (cq *CSQueue[T]) Dequeue() (T, error) {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	return cq.q.Dequeue()
+}
+
+// Peek returns the first item in the queue without removing it.
+func (cq *CSQueue[T]) Peek() (T, error) {
+	cq.mu.RLock()
+	defer cq.mu.RUnlock()
+	return cq.q.Peek()
+}
+
+// DequeueOpt removes and returns the first item as an Option (see
+// queue.DequeueOpt).
+func (cq *CSQueue[T]) DequeueOpt() optional.Option[T] {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	return cq.q.DequeueOpt()
+}
+
+// PeekOpt returns the first item as an Option (see queue.PeekOpt).
+func (cq *CSQueue[T]) PeekOpt() optional.Option[T] {
+	cq.mu.RLock()
+	defer cq.mu.RUnlock()
+	return cq.q.PeekOpt()
+}
+
+// Put adds an item to the end of the queue, waiting for room for it if the
+// queue is bounded and full. It returns ctx.Err() if the context is done
+// before the item is added.
+func (cq *CSQueue[T]) Put(ctx context.Context, item T) error {
+	return cq.await(ctx, func() bool { return cq.tryEnqueue(item) })
+}
+
+// Take removes and returns the first item in the queue, waiting for one if
+// the queue is empty. It returns ctx.Err() if the context is done first.
+func (cq *CSQueue[T]) Take(ctx context.Context) (item T, err error) {
+	err = cq.await(ctx, func() bool {
+		var derr error
+		item, derr = cq.q.Dequeue()
+		return derr == nil
+	})
+	return item, err
+}
+
+// Offer adds an item to the end of the queue, waiting at most timeout for
+// room for it if the queue is full. It returns false if the timeout expired
+// first. With a timeout of 0 or less it doesn't wait.
+func (cq *CSQueue[T]) Offer(item T, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return cq.Put(ctx, item) == nil
+}
+
+// Poll removes and returns the first item in the queue, waiting at most
+// timeout for one if the queue is empty. It returns false if the timeout
+// expired first. With a timeout of 0 or less it doesn't wait.
+func (cq *CSQueue[T]) Poll(timeout time.Duration) (T, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	item, err := cq.Take(ctx)
+	return item, err == nil
+}
+
+// DrainAll waits until the queue holds at least one item, then removes and
+// returns up to max items (all of them if max is 0 or less) with a single
+// lock acquisition. It returns nil if the context is done before an item is
+// available.
+func (cq *CSQueue[T]) DrainAll(ctx context.Context, max int) []T {
+	var items []T
+	_ = cq.await(ctx, func() bool {
+		n := cq.q.Size()
+		if max > 0 {
+			n = min(n, uint64(max))
+		}
+		for range n {
+			item, _ := cq.q.Dequeue()
+			items = append(items, item)
+		}
+		return n > 0
+	})
+	return items
+}
+
+// tryEnqueue adds item if there is room for it. It runs under the write lock.
+func (cq *CSQueue[T]) tryEnqueue(item T) bool {
+	if cq.capacity != 0 && cq.q.Size() >= cq.capacity {
+		return false
+	}
+	cq.q.Enqueue(item)
+	return true
+}
+
+// await calls try under the write lock until it returns true, waiting for the
+// size of the queue to change between the attempts. try is called at least
+// once, even if ctx is already done.
+func (cq *CSQueue[T]) await(ctx context.Context, try func() bool) error {
+	for {
+		cq.mu.Lock()
+		if try() {
+			cq.mu.Unlock()
+			return nil
+		}
+		if cq.changed == nil {
+			cq.changed = make(chan struct{})
+		}
+		changed := cq.changed
+		cq.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Size returns the number of items in the queue.
+func (cq *CSQueue[T]) Size() uint64 {
+	return cq.size.Load()
+}
+
+// IsEmpty returns true if the queue is empty.
+func (cq *CSQueue[T]) IsEmpty() bool {
+	return cq.size.Load() == 0
+}
+
+// Capacity returns the maximum number of items in the queue (0 if the queue
+// is unbounded).
+func (cq *CSQueue[T]) Capacity() uint64 {
+	return cq.capacity
+}
+
+// Clear removes all the items from the queue, and stops tracking the items
+// enqueued with EnqueueWithRetry.
+func (cq *CSQueue[T]) Clear() {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	cq.q.Clear()
+	cq.retries = nil
+}
+
+// ToSlice returns the items in the queue, from the first to the last.
+func (cq *CSQueue[T]) ToSlice() []T {
+	cq.mu.RLock()
+	defer cq.mu.RUnlock()
+	return cq.q.ToSlice()
+}
+
+// Freeze returns an immutable copy of the queue, taken under the read lock:
+// it can be read without locking (see gods.Frozen).
+func (cq *CSQueue[T]) Freeze() *gods.Frozen[T] {
+	cq.mu.RLock()
+	defer cq.mu.RUnlock()
+	return cq.q.Freeze()
+}
@@ -0,0 +1,225 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csqueue_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	csqueue "github.com/pzaino/gods/pkg/csqueue"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedSizeX   = "expected size %d, got %d"
+	errExpectedX       = "expected %v, got %v"
+)
+
+func TestEnqueueDequeue(t *testing.T) {
+	cq := csqueue.NewWithCapacity[int](2)
+	for i := 1; i <= 2; i++ {
+		if err := cq.Enqueue(i); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+	}
+	if err := cq.Enqueue(3); err == nil || err.Error() != csqueue.ErrQueueIsFull {
+		t.Errorf(errExpectedX, csqueue.ErrQueueIsFull, err)
+	}
+	if cq.Size() != 2 {
+		t.Errorf(errExpectedSizeX, 2, cq.Size())
+	}
+	if item, err := cq.Dequeue(); err != nil || item != 1 {
+		t.Errorf(errExpectedX, 1, item)
+	}
+	if got := cq.ToSlice(); !slices.Equal(got, []int{2}) {
+		t.Errorf(errExpectedX, []int{2}, got)
+	}
+	cq.Clear()
+	if _, err := cq.Dequeue(); err == nil || err.Error() != csqueue.ErrQueueIsEmpty {
+		t.Errorf(errExpectedX, csqueue.ErrQueueIsEmpty, err)
+	}
+}
+
+func TestPollOffer(t *testing.T) {
+	cq := csqueue.NewWithCapacity[int](1)
+	if _, ok := cq.Poll(0); ok {
+		t.Errorf("expected Poll to fail on an empty queue")
+	}
+	start := time.Now()
+	if _, ok := cq.Poll(10 * time.Millisecond); ok {
+		t.Errorf("expected Poll to time out")
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("expected Poll to wait for the timeout, waited %v", elapsed)
+	}
+
+	if !cq.Offer(1, 0) {
+		t.Errorf("expected Offer to succeed on an empty queue")
+	}
+	if cq.Offer(2, 10*time.Millisecond) {
+		t.Errorf("expected Offer to time out on a full queue")
+	}
+
+	// a waiting Offer succeeds as soon as an item is taken
+	done := make(chan bool)
+	go func() { done <- cq.Offer(2, time.Second) }()
+	if item, ok := cq.Poll(time.Second); !ok || item != 1 {
+		t.Errorf(errExpectedX, 1, item)
+	}
+	if !<-done {
+		t.Errorf("expected the waiting Offer to succeed")
+	}
+	if item, ok := cq.Poll(time.Second); !ok || item != 2 {
+		t.Errorf(errExpectedX, 2, item)
+	}
+}
+
+func TestConcurrentPollOffer(t *testing.T) {
+	cq := csqueue.NewWithCapacity[int](4)
+	const producers, items = 4, 100
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < items; i++ {
+				if !cq.Offer(p*items+i, time.Second) {
+					t.Errorf("expected Offer to succeed")
+				}
+			}
+		}(p)
+	}
+
+	seen := make([]bool, producers*items)
+	for range producers * items {
+		item, ok := cq.Poll(time.Second)
+		if !ok {
+			t.Fatalf("expected Poll to succeed")
+		}
+		seen[item] = true
+	}
+	wg.Wait()
+	for i, ok := range seen {
+		if !ok {
+			t.Errorf("expected item %d to be taken", i)
+		}
+	}
+	if !cq.IsEmpty() {
+		t.Errorf(errExpectedSizeX, 0, cq.Size())
+	}
+}
+
+func TestRetryAndDeadLetter(t *testing.T) {
+	cq, dlq := csqueue.New[string](), csqueue.New[string]()
+	var dead []string
+	cq.SetDeadLetter(dlq, func(item string, attempts int) {
+		if attempts != 3 {
+			t.Errorf(errExpectedX, 3, attempts)
+		}
+		dead = append(dead, item)
+	})
+	if err := cq.EnqueueWithRetry("poison", 3); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if err := cq.EnqueueWithRetry("ok", 3); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+
+	for cq.Size() > 0 {
+		item, err := cq.Dequeue()
+		if err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+		if item == "ok" {
+			cq.Ack(item)
+			continue
+		}
+		if err := cq.Nack(item); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+	}
+	if !slices.Equal(dead, []string{"poison"}) {
+		t.Errorf(errExpectedX, []string{"poison"}, dead)
+	}
+	if got := dlq.ToSlice(); !slices.Equal(got, []string{"poison"}) {
+		t.Errorf(errExpectedX, []string{"poison"}, got)
+	}
+	if err := cq.Nack("ok"); err == nil || err.Error() != csqueue.ErrNotRetried {
+		t.Errorf(errExpectedX, csqueue.ErrNotRetried, err)
+	}
+}
+
+func TestNackAttempts(t *testing.T) {
+	cq := csqueue.New[int]()
+	if err := cq.EnqueueWithRetry(1, 5); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	for i := 1; i <= 2; i++ {
+		item, _ := cq.Dequeue()
+		if err := cq.Nack(item); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+		if cq.Attempts(item) != i {
+			t.Errorf(errExpectedX, i, cq.Attempts(item))
+		}
+	}
+	if cq.Size() != 1 {
+		t.Errorf(errExpectedSizeX, 1, cq.Size())
+	}
+}
+
+func TestDrainAll(t *testing.T) {
+	cq := csqueue.New[int]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if items := cq.DrainAll(ctx, 10); items != nil {
+		t.Errorf(errExpectedX, nil, items)
+	}
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		for i := 1; i <= 5; i++ {
+			_ = cq.Enqueue(i)
+		}
+	}()
+	var got []int
+	for len(got) < 5 {
+		items := cq.DrainAll(context.Background(), 3)
+		if len(items) == 0 || len(items) > 3 {
+			t.Fatalf("expected 1 to 3 items, got %v", items)
+		}
+		got = append(got, items...)
+	}
+	if !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf(errExpectedX, []int{1, 2, 3, 4, 5}, got)
+	}
+}
+
+func TestDequeueOptPeekOpt(t *testing.T) {
+	cq := csqueue.New[int]()
+	if cq.DequeueOpt().IsSome() || cq.PeekOpt().IsSome() {
+		t.Errorf(errExpectedX, "None", cq.PeekOpt())
+	}
+	cq.Enqueue(1)
+	if got := cq.PeekOpt().String(); got != "Some(1)" {
+		t.Errorf(errExpectedX, "Some(1)", got)
+	}
+	if v := cq.DequeueOpt().OrElse(-1); v != 1 || !cq.IsEmpty() {
+		t.Errorf(errExpectedX, 1, v)
+	}
+}
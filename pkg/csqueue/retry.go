@@ -0,0 +1,118 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csqueue
+
+import (
+	"errors"
+)
+
+const (
+	ErrNotRetried = "item was not enqueued with EnqueueWithRetry"
+)
+
+// retry tracks an item enqueued with EnqueueWithRetry
+type retry struct {
+	attempts    int
+	maxAttempts int
+}
+
+// deadLetter is the dead-letter queue attached with SetDeadLetter
+type deadLetter[T comparable] struct {
+	q      *CSQueue[T]
+	onDead func(item T, attempts int)
+}
+
+// SetDeadLetter attaches a dead-letter queue, receiving the items that
+// failed maxAttempts times (see Nack), and a callback called for each of them
+// after it's been added to dlq. Both can be nil: the items are then dropped,
+// or the callback isn't called.
+func (cq *CSQueue[T]) SetDeadLetter(dlq *CSQueue[T], onDead func(item T, attempts int)) {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	cq.dead = deadLetter[T]{q: dlq, onDead: onDead}
+}
+
+// EnqueueWithRetry adds an item to the end of the queue, like Enqueue, and
+// tracks its attempts: a consumer failing to process the item calls Nack to
+// put it back in the queue, up to maxAttempts times, and Ack once it's been
+// processed. The items tracked must be distinct.
+func (cq *CSQueue[T]) EnqueueWithRetry(item T, maxAttempts int) error {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	if !cq.tryEnqueue(item) {
+		return errors.New(ErrQueueIsFull)
+	}
+	if cq.retries == nil {
+		cq.retries = make(map[T]*retry)
+	}
+	cq.retries[item] = &retry{maxAttempts: max(maxAttempts, 1)}
+	return nil
+}
+
+// Nack records a failed attempt to process an item dequeued after
+// EnqueueWithRetry. The item is added back to the end of the queue or, once
+// it failed maxAttempts times, moved to the dead-letter queue. It returns an
+// ErrNotRetried error if the item is not tracked, and ErrQueueIsFull if it
+// can't be added back (it stays tracked, so Nack can be called again).
+func (cq *CSQueue[T]) Nack(item T) error {
+	cq.mu.Lock()
+	r, ok := cq.retries[item]
+	if !ok {
+		cq.mu.Unlock()
+		return errors.New(ErrNotRetried)
+	}
+	if r.attempts+1 < r.maxAttempts {
+		if !cq.tryEnqueue(item) {
+			cq.mu.Unlock()
+			return errors.New(ErrQueueIsFull)
+		}
+		r.attempts++
+		cq.mu.Unlock()
+		return nil
+	}
+	delete(cq.retries, item)
+	dead := cq.dead
+	cq.mu.Unlock()
+
+	// the dead-letter queue and the callback are used without holding the
+	// lock of the queue
+	var err error
+	if dead.q != nil {
+		err = dead.q.Enqueue(item)
+	}
+	if dead.onDead != nil {
+		dead.onDead(item, r.attempts+1)
+	}
+	return err
+}
+
+// Ack stops tracking an item enqueued with EnqueueWithRetry, once it has
+// been processed.
+func (cq *CSQueue[T]) Ack(item T) {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	delete(cq.retries, item)
+}
+
+// Attempts returns how many times the processing of an item enqueued with
+// EnqueueWithRetry has failed so far.
+func (cq *CSQueue[T]) Attempts(item T) int {
+	cq.mu.RLock()
+	defer cq.mu.RUnlock()
+	if r, ok := cq.retries[item]; ok {
+		return r.attempts
+	}
+	return 0
+}
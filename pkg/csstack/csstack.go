@@ -16,30 +16,68 @@
 package csstack
 
 import (
+	"context"
 	"errors"
-	"sync"
-
+	"io"
+	"iter"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	iterator "github.com/pzaino/gods/pkg/iterator"
+	optional "github.com/pzaino/gods/pkg/optional"
 	stack "github.com/pzaino/gods/pkg/stack"
 )
 
-// CSStack is a concurrency-safe stack.
+// CSStack is a concurrency-safe stack. Its items are stored in a linked list
+// of fixed-size chunks (see stack.Chunked), so a push never copies the items
+// already in the stack while holding the lock, however large it is.
 type CSStack[T comparable] struct {
-	mu sync.RWMutex
-	s  *stack.Stack[T]
+	mu   gods.RWLocker
+	s    *stack.Chunked[T]
+	opts []gods.Option
+	size atomic.Uint64 // published on every write unlock, read without locking
 }
 
 // New creates a new concurrency-safe stack.
-func New[T comparable]() *CSStack[T] {
-	return &CSStack[T]{s: stack.New[T]()}
+// The options select the locking strategy (the default is a sync.RWMutex).
+func New[T comparable](opts ...gods.Option) *CSStack[T] {
+	return newCSStack(stack.NewChunked[T](0), opts)
 }
 
-// NewFromSlice creates a new concurrency-safe stack from a slice.
-func NewFromSlice[T comparable](items []T) *CSStack[T] {
-	cs := New[T]()
-	cs.s.PushAll(items)
+// NewChunked creates a new concurrency-safe stack made of chunks of
+// chunkSize items (stack.DefaultChunkSize if it's 0): larger chunks mean
+// fewer allocations, smaller ones less memory held by a small stack.
+func NewChunked[T comparable](chunkSize int, opts ...gods.Option) *CSStack[T] {
+	return newCSStack(stack.NewChunked[T](chunkSize), opts)
+}
+
+// newCSStack wraps s in a CSStack using the locking strategy selected by opts
+func newCSStack[T comparable](s *stack.Chunked[T], opts []gods.Option) *CSStack[T] {
+	cs := &CSStack[T]{}
+	cs.init(s, opts)
 	return cs
 }
 
+// init sets up cs to wrap s
+func (cs *CSStack[T]) init(s *stack.Chunked[T], opts []gods.Option) {
+	cs.s, cs.opts = s, opts
+	cs.size.Store(s.Size())
+	cs.mu = gods.OnUnlock(gods.NewLocker(opts...), func() {
+		cs.size.Store(cs.s.Size())
+	})
+	gods.ObserveSize(cs.mu, cs.size.Load)
+}
+
+// NewFromSlice creates a new concurrency-safe stack from a slice.
+func NewFromSlice[T comparable](items []T, opts ...gods.Option) *CSStack[T] {
+	s := stack.NewChunked[T](0)
+	s.PushAll(items)
+	return newCSStack(s, opts)
+}
+
 // Push adds an item to the stack.
 func (cs *CSStack[T]) Push(item T) {
 	cs.mu.Lock()
@@ -49,9 +87,7 @@ func (cs *CSStack[T]) Push(item T) {
 
 // IsEmpty checks if the stack is empty.
 func (cs *CSStack[T]) IsEmpty() bool {
-	cs.mu.Lock()
-	defer cs.mu.Unlock()
-	return cs.s.IsEmpty()
+	return cs.size.Load() == 0
 }
 
 // Pop removes and returns the top item from the stack.
@@ -68,11 +104,19 @@ func (cs *CSStack[T]) ToSlice() []T {
 	return cs.s.ToSlice()
 }
 
-// ToStack returns the stack as a stack (non-concurrent-safe).
-func (cs *CSStack[T]) ToStack() *stack.Stack[T] {
+// Freeze returns an immutable copy of the stack, taken under the read lock:
+// it can be read without locking (see gods.Frozen).
+func (cs *CSStack[T]) Freeze() *gods.Frozen[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return cs.s
+	return cs.s.Freeze()
+}
+
+// ToStack returns a copy of the stack as a (non-concurrent-safe) stack.Stack.
+func (cs *CSStack[T]) ToStack() *stack.Stack[T] {
+	items := cs.ToSlice()
+	slices.Reverse(items)
+	return stack.NewFromSlice(items)
 }
 
 // Reverse reverses the stack.
@@ -89,25 +133,124 @@ func (cs *CSStack[T]) Swap() error {
 	return cs.s.Swap()
 }
 
-// Top returns the top item from the stack without removing it.
+// SwapAt swaps the items at the positions i and j, counted from the top of
+// the stack (0 is the top item).
+func (cs *CSStack[T]) SwapAt(i, j uint64) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.s.SwapAt(i, j)
+}
+
+// Top returns a pointer to a copy of the top item, without removing it.
+//
+// Deprecated: use TopValue, which doesn't allocate the copy.
 func (cs *CSStack[T]) Top() (*T, error) {
-	cs.mu.RLock()
-	defer cs.mu.RUnlock()
-	return cs.s.Top()
+	return pointer(cs.TopValue())
 }
 
 // Peek is a wrapper around Top (for those more used to using Peek).
+//
+// Deprecated: use PeekValue, which doesn't allocate the copy.
 func (cs *CSStack[T]) Peek() (*T, error) {
+	return pointer(cs.TopValue())
+}
+
+// TopValue returns a copy of the top item, without removing it.
+func (cs *CSStack[T]) TopValue() (T, error) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return cs.s.Peek()
+	if o := cs.s.TopOpt(); o.IsSome() {
+		return o.MustGet(), nil
+	}
+	var zero T
+	return zero, errors.New(stack.ErrStackIsEmpty)
+}
+
+// PeekValue is a wrapper around TopValue.
+func (cs *CSStack[T]) PeekValue() (T, error) {
+	return cs.TopValue()
+}
+
+// pointer returns a pointer to v, or nil with err. The pointer-returning
+// methods use it so the items they return never alias the storage of the
+// stack, which other goroutines write once the lock is released.
+func pointer[T any](v T, err error) (*T, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// PopOpt removes and returns the top item as an Option (see stack.PopOpt).
+func (cs *CSStack[T]) PopOpt() optional.Option[T] {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.s.PopOpt()
+}
+
+// TopOpt returns the top item as an Option (see stack.TopOpt).
+func (cs *CSStack[T]) TopOpt() optional.Option[T] {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.s.TopOpt()
 }
 
 // Size returns the number of items in the stack.
 func (cs *CSStack[T]) Size() uint64 {
+	return cs.size.Load()
+}
+
+// Chunks returns the number of chunks holding the items.
+func (cs *CSStack[T]) Chunks() int {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.s.Chunks()
+}
+
+// MemoryUsage returns an estimate of the bytes held by the stack (see the
+// gods package), adding the memory returned by extra for every item.
+func (cs *CSStack[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return uint64(unsafe.Sizeof(*cs)) + cs.s.MemoryUsage(extra...)
+}
+
+// Validate checks the invariants of the stack and its published size (see
+// gods.Validator).
+func (cs *CSStack[T]) Validate() error {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if err := cs.s.Validate(); err != nil {
+		return err
+	}
+	if size := cs.size.Load(); size != cs.s.Size() {
+		return gods.Invariantf("published size is %d but the stack holds %d items", size, cs.s.Size())
+	}
+	return nil
+}
+
+// Dump writes a rendering of the wrapped structure to w, holding the read
+// lock, for debugging.
+func (cs *CSStack[T]) Dump(w io.Writer) error {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return cs.s.Size()
+	return cs.s.Dump(w)
+}
+
+// Stats returns the statistics of the stack: its peak size, how many times
+// it allocated a chunk (see stack.Chunked.Stats) and, if it was created with
+// gods.WithLatencyHistograms, the latency histograms of its operations.
+func (cs *CSStack[T]) Stats() gods.Stats {
+	cs.mu.RLock()
+	stats := cs.s.Stats()
+	cs.mu.RUnlock()
+	stats.Latency = gods.LockLatency(cs.mu)
+	return stats
+}
+
+// ResetLatency clears the latency histograms returned by Stats.
+func (cs *CSStack[T]) ResetLatency() {
+	gods.ResetLockLatency(cs.mu)
 }
 
 // Clear removes all items from the stack.
@@ -128,7 +271,7 @@ func (cs *CSStack[T]) Contains(item T) bool {
 func (cs *CSStack[T]) Copy() *CSStack[T] {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return &CSStack[T]{s: cs.s.Copy()}
+	return newCSStack(cs.s.Copy(), cs.opts)
 }
 
 // Equal checks if two stacks are equal.
@@ -156,6 +299,15 @@ func (cs *CSStack[T]) PopN(n uint64) ([]T, error) {
 	return cs.s.PopN(n)
 }
 
+// PopWhile pops the items while pred returns true for the top item, and
+// returns them in the order they were popped. The whole operation holds the
+// lock, so no item can be pushed in between; pred must not use the stack.
+func (cs *CSStack[T]) PopWhile(pred func(T) bool) []T {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.s.PopWhile(pred)
+}
+
 // PushN adds multiple items to the stack.
 func (cs *CSStack[T]) PushN(items ...T) {
 	cs.mu.Lock()
@@ -184,14 +336,12 @@ func (cs *CSStack[T]) Filter(predicate func(T) bool) {
 	cs.s.Filter(predicate)
 }
 
-// Map creates a new stack with the results of applying the function to each item.
+// Map creates a new stack with the results of applying the function to each
+// item. The error is always nil.
 func (cs *CSStack[T]) Map(fn func(T) T) (*CSStack[T], error) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	csStack := &CSStack[T]{}
-	var err error
-	csStack.s, err = cs.s.Map(fn)
-	return csStack, err
+	return newCSStack(cs.s.Map(fn), cs.opts), nil
 }
 
 // Reduce reduces the stack to a single value.
@@ -236,11 +386,28 @@ func (cs *CSStack[T]) All(predicate func(T) bool) bool {
 	return cs.s.All(predicate)
 }
 
-// Find returns the first item that matches the predicate.
+// Find returns a pointer to a copy of the first item that matches the
+// predicate.
+//
+// Deprecated: use FindValue, which doesn't allocate the copy.
 func (cs *CSStack[T]) Find(predicate func(T) bool) (*T, error) {
+	return pointer(cs.FindValue(predicate))
+}
+
+// FindValue returns a copy of the first item that matches the predicate.
+func (cs *CSStack[T]) FindValue(predicate func(T) bool) (T, error) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return cs.s.Find(predicate)
+	return value(cs.s.Find(predicate))
+}
+
+// value returns a copy of the item at p, read while the lock is held
+func value[T any](p *T, err error) (T, error) {
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return *p, nil
 }
 
 // FindIndex returns the index of the first item that matches the predicate.
@@ -250,11 +417,19 @@ func (cs *CSStack[T]) FindIndex(predicate func(T) bool) (uint64, error) {
 	return cs.s.FindIndex(predicate)
 }
 
-// FindLast returns the last item that matches the predicate.
+// FindLast returns a pointer to a copy of the last item that matches the
+// predicate.
+//
+// Deprecated: use FindLastValue, which doesn't allocate the copy.
 func (cs *CSStack[T]) FindLast(predicate func(T) bool) (*T, error) {
+	return pointer(cs.FindLastValue(predicate))
+}
+
+// FindLastValue returns a copy of the last item that matches the predicate.
+func (cs *CSStack[T]) FindLastValue(predicate func(T) bool) (T, error) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	return cs.s.FindLast(predicate)
+	return value(cs.s.FindLast(predicate))
 }
 
 // FindLastIndex returns the index of the last item that matches the predicate.
@@ -277,3 +452,174 @@ func (cs *CSStack[T]) FindIndices(predicate func(T) bool) []uint64 {
 	defer cs.mu.RUnlock()
 	return cs.s.FindIndices(predicate)
 }
+
+// Iter returns an iterator over the items of the stack, from the top to the bottom.
+// The iterator works on a snapshot of the stack taken when the iteration starts,
+// so the stack can be safely modified (even from within the loop) while iterating.
+func (cs *CSStack[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, item := range cs.ToSlice() {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// Iterator returns an iterator over a snapshot of the stack, from the top to the bottom.
+func (cs *CSStack[T]) Iterator() iterator.BidirectionalIterator[T] {
+	return iterator.FromSlice(cs.ToSlice())
+}
+
+// Enumerate returns an iterator over the index and the value of the items of
+// the stack, from the top (index 0) to the bottom.
+// Like Iter, it works on a snapshot of the stack taken when the iteration starts.
+func (cs *CSStack[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		for i, item := range cs.ToSlice() {
+			if !yield(uint64(i), item) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (cs *CSStack[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(cs.Enumerate())
+}
+
+// Values returns an iterator over the items, in the same order as Enumerate
+// (it is Iter, under the name used by the standard library).
+func (cs *CSStack[T]) Values() iter.Seq[T] {
+	return cs.Iter()
+}
+
+// Encode writes a snapshot of the stack to w using the given codec
+func (cs *CSStack[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeSlice(w, codec, cs.ToSlice())
+}
+
+// Decode replaces the content of the stack with the elements read from r using the given codec.
+// The data is decoded before acquiring the lock, so readers are blocked only while the stack is rebuilt.
+// A zero value (as allocated by the decoders for pointer fields) is
+// initialized with the default options.
+func (cs *CSStack[T]) Decode(r io.Reader, codec gods.Codec) error {
+	items, err := gods.DecodeSlice[T](r, codec)
+	if err != nil {
+		return err
+	}
+	if cs.mu == nil {
+		cs.init(stack.NewChunked[T](0), nil)
+	}
+	// items are encoded from the top
+	slices.Reverse(items)
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.s.Clear()
+	cs.s.PushN(items...)
+	return nil
+}
+
+// EncodeStream writes the stack to w incrementally, in chunks encoded with the
+// given codec (see gods.EncodeStream), without copying its elements first.
+// Writers are blocked until the whole stack has been written.
+func (cs *CSStack[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.s.EncodeStream(w, codec)
+}
+
+// DecodeStream replaces the content of the stack with the elements of a
+// stream written by EncodeStream. Unlike Decode, the elements are added as
+// they are read, so the lock is held until the whole stream has been read.
+func (cs *CSStack[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	if cs.mu == nil {
+		cs.init(stack.NewChunked[T](0), nil)
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.s.DecodeStream(r, codec)
+}
+
+// MarshalText implements encoding.TextMarshaler: the stack is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (cs *CSStack[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(cs)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (cs *CSStack[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(cs, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (cs *CSStack[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(cs)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (cs *CSStack[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(cs, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (cs *CSStack[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(cs)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (cs *CSStack[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(cs, data)
+}
+
+// FilterCtx is like Filter but it can be aborted through the context: it
+// returns ctx.Err() as soon as the context is done, leaving the stack unchanged.
+func (cs *CSStack[T]) FilterCtx(ctx context.Context, predicate func(T) bool) (err error) {
+	ctx, end := gods.Trace(ctx, "csstack.FilterCtx", slog.Uint64("size", cs.Size()))
+	defer func() { end(err) }()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.s.FilterCtx(ctx, predicate)
+}
+
+// MapCtx is like Map but it returns ctx.Err() as soon as the context is done.
+func (cs *CSStack[T]) MapCtx(ctx context.Context, fn func(T) T) (_ *CSStack[T], err error) {
+	ctx, end := gods.Trace(ctx, "csstack.MapCtx", slog.Uint64("size", cs.Size()))
+	defer func() { end(err) }()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	s, err := cs.s.MapCtx(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	return newCSStack(s, cs.opts), nil
+}
+
+// ForEachCtx is like ForEach but it returns ctx.Err() as soon as the context is done.
+func (cs *CSStack[T]) ForEachCtx(ctx context.Context, fn func(*T) error) (err error) {
+	ctx, end := gods.Trace(ctx, "csstack.ForEachCtx", slog.Uint64("size", cs.Size()))
+	defer func() { end(err) }()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.s.ForEachCtx(ctx, fn)
+}
+
+// ContentionStats returns the lock statistics of the CSStack. They are
+// collected only if it was created with the gods.WithContentionStats option.
+func (cs *CSStack[T]) ContentionStats() gods.ContentionStats {
+	return gods.LockContentionStats(cs.mu)
+}
+
+// Locker returns the lock of the CSStack (see gods.Atomically).
+func (cs *CSStack[T]) Locker() gods.RWLocker {
+	return cs.mu
+}
+
+// Unsafe returns the underlying (non concurrency-safe) chunked stack. It
+// must only be used while the lock is held, typically inside
+// gods.Atomically.
+func (cs *CSStack[T]) Unsafe() *stack.Chunked[T] {
+	return cs.s
+}
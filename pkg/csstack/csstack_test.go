@@ -16,10 +16,17 @@
 package csstack_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"slices"
 	"sync"
 	"testing"
 
+	gods "github.com/pzaino/gods"
 	csstack "github.com/pzaino/gods/pkg/csstack"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 const (
@@ -487,3 +494,371 @@ func TestNewFromSlice(t *testing.T) {
 		}
 	}
 }
+
+func TestCSStackIter(t *testing.T) {
+	cs := csstack.NewFromSlice([]int{1, 2, 3})
+	var items []int
+	for item := range cs.Iter() {
+		// modifying the stack while iterating must not deadlock
+		cs.Push(item * 10)
+		items = append(items, item)
+	}
+	if !reflect.DeepEqual(items, []int{3, 2, 1}) {
+		t.Fatalf("expected %v, got %v", []int{3, 2, 1}, items)
+	}
+	if cs.Size() != 6 {
+		t.Fatalf(errExpectedSizeX, 6, cs.Size())
+	}
+}
+
+func TestCSStackIterator(t *testing.T) {
+	cs := csstack.NewFromSlice([]int{1, 2, 3})
+	it := cs.Iterator()
+	cs.Push(4) // not part of the snapshot
+	items := iterator.ToSlice[int](it)
+	if !reflect.DeepEqual(items, []int{3, 2, 1}) {
+		t.Fatalf("expected %v, got %v", []int{3, 2, 1}, items)
+	}
+	if !it.Prev() || it.Value() != 1 {
+		t.Fatalf("expected %v, got %v", 1, it.Value())
+	}
+}
+
+func TestCSStackEnumerate(t *testing.T) {
+	cs := csstack.NewFromSlice([]int{1, 2, 3})
+	var indexes []uint64
+	var values []int
+	for i, v := range cs.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0, 1, 2}) {
+		t.Errorf("Expected %v, got %v", []uint64{0, 1, 2}, indexes)
+	}
+	if !slices.Equal(values, []int{3, 2, 1}) {
+		t.Errorf("Expected %v, got %v", []int{3, 2, 1}, values)
+	}
+	if got := slices.Collect(cs.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+	if got := slices.Collect(cs.Values()); !slices.Equal(got, values) {
+		t.Errorf("Expected %v, got %v", values, got)
+	}
+}
+
+func TestCSStackEncodeDecode(t *testing.T) {
+	cs := csstack.NewFromSlice([]int{1, 2, 3})
+	var buf bytes.Buffer
+	if err := cs.Encode(&buf, gods.GobCodec{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	decoded := csstack.New[int]()
+	if err := decoded.Decode(&buf, gods.GobCodec{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !decoded.Equal(cs) {
+		t.Fatalf("expected %v, got %v", cs.ToSlice(), decoded.ToSlice())
+	}
+}
+
+func TestCSStackCtxVariants(t *testing.T) {
+	cs := csstack.NewFromSlice([]int{1, 2, 3})
+	mapped, err := cs.MapCtx(context.Background(), func(v int) int { return v * 2 })
+	if err != nil || !reflect.DeepEqual(mapped.ToSlice(), []int{6, 4, 2}) {
+		t.Fatalf("expected %v, got %v", []int{6, 4, 2}, mapped)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := cs.FilterCtx(ctx, func(int) bool { return false }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if cs.Size() != 3 {
+		t.Fatalf(errExpectedSizeX, 3, cs.Size())
+	}
+	if err := cs.ForEachCtx(ctx, func(*int) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestCSStackLockStrategies(t *testing.T) {
+	for _, opt := range []gods.Option{gods.WithRWMutex(), gods.WithMutex(), gods.WithSharding(4)} {
+		cs := csstack.New[int](opt)
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					cs.Push(i)
+					_ = cs.Size()
+					_ = cs.ToSlice()
+				}
+			}()
+		}
+		wg.Wait()
+		if cs.Size() != 400 {
+			t.Errorf("Expected size %d, got %d", 400, cs.Size())
+		}
+		if c := cs.Copy(); c.Size() != 400 {
+			t.Errorf("Expected size %d, got %d", 400, c.Size())
+		}
+	}
+}
+
+func TestCSStackContentionStats(t *testing.T) {
+	cs := csstack.New[int](gods.WithContentionStats())
+	cs.Push(1)
+	cs.Push(2)
+	_ = cs.ToSlice()
+	stats := cs.ContentionStats()
+	if stats.Acquisitions != 2 || stats.ReadAcquisitions != 1 {
+		t.Errorf("Expected 2 writes and 1 read, got %+v", stats)
+	}
+	if c := cs.Copy(); c.ContentionStats().ReadAcquisitions != 0 {
+		t.Errorf("Expected the copy to have its own statistics")
+	}
+	if stats := csstack.New[int]().ContentionStats(); stats.Acquisitions != 0 {
+		t.Errorf("Expected no statistics without the option")
+	}
+}
+
+func TestCSStackSizeWithoutLocking(t *testing.T) {
+	cs := csstack.NewFromSlice([]int{1, 2, 3})
+	if cs.Size() != 3 || cs.IsEmpty() {
+		t.Errorf("Expected size %d, got %d", 3, cs.Size())
+	}
+	// Size must not need the lock, so it can be polled during a long write
+	_ = cs.ForEach(func(*int) error {
+		done := make(chan struct{})
+		go func() {
+			_ = cs.Size()
+			close(done)
+		}()
+		<-done
+		return nil
+	})
+	// updates made through Atomically are published too
+	_ = gods.Atomically(func() error {
+		cs.Unsafe().Clear()
+		return nil
+	}, cs)
+	if cs.Size() != 0 || !cs.IsEmpty() {
+		t.Errorf("Expected size %d, got %d", 0, cs.Size())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cs := csstack.New[int]()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cs.Push(j)
+				if j%2 == 0 {
+					_, _ = cs.Pop()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := cs.Validate(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+}
+
+func TestPopWhile(t *testing.T) {
+	cs := csstack.New[int]()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 250; j++ {
+				cs.Push(j)
+			}
+		}()
+	}
+	var popped int
+	for popped < 1000 {
+		popped += len(cs.PopWhile(func(int) bool { return true }))
+	}
+	wg.Wait()
+	if !cs.IsEmpty() {
+		t.Fatalf(errExpectedSizeX, 0, cs.Size())
+	}
+}
+
+func TestSwapAt(t *testing.T) {
+	cs := csstack.NewFromSlice([]int{1, 2, 3})
+	if err := cs.SwapAt(0, 2); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if top, _ := cs.Top(); *top != 1 {
+		t.Errorf("expected top 1, got %d", *top)
+	}
+	if err := cs.SwapAt(3, 0); err == nil {
+		t.Errorf("expected an out of range error")
+	}
+}
+
+func TestPopOptTopOpt(t *testing.T) {
+	cs := csstack.New[int]()
+	if cs.PopOpt().IsSome() || cs.TopOpt().IsSome() {
+		t.Errorf(errExpectedStackEmpty)
+	}
+	cs.Push(1)
+	if v, ok := cs.TopOpt().Get(); !ok || v != 1 {
+		t.Errorf("expected Some(1), got %v", cs.TopOpt())
+	}
+	if v, ok := cs.PopOpt().Get(); !ok || v != 1 || !cs.IsEmpty() {
+		t.Errorf("expected Some(1), got %v", v)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	cs := csstack.NewChunked[int](4)
+	if _, err := cs.Pop(); err == nil {
+		t.Errorf(errExpectedStackEmpty)
+	}
+	if cs.PopOpt().IsSome() || cs.TopOpt().IsSome() {
+		t.Errorf(errExpectedStackEmpty)
+	}
+	for i := range 10 {
+		cs.Push(i)
+	}
+	cs.PushN(10, 11, 12, 13, 14)
+	if cs.Size() != 15 || cs.Chunks() != 4 {
+		t.Errorf(errExpectedSizeX, 15, cs.Size())
+	}
+	if top, err := cs.TopValue(); err != nil || top != 14 {
+		t.Errorf("expected %v, got %v", 14, top)
+	}
+	if !cs.Contains(0) || cs.Contains(15) {
+		t.Errorf("expected %v, got %v", true, cs.Contains(0))
+	}
+
+	items, err := cs.PopN(6)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if want := []int{14, 13, 12, 11, 10, 9}; !slices.Equal(items, want) {
+		t.Errorf("expected %v, got %v", want, items)
+	}
+	if _, err := cs.PopN(10); err == nil || err.Error() != "Stack has less than n items" {
+		t.Errorf("expected %v, got %v", "Stack has less than n items", err)
+	}
+	if v, ok := cs.PopOpt().Get(); !ok || v != 8 {
+		t.Errorf("expected %v, got %v", 8, v)
+	}
+	if want := []int{7, 6, 5, 4, 3, 2, 1, 0}; !slices.Equal(cs.ToSlice(), want) || !slices.Equal(slices.Collect(cs.Iter()), want) {
+		t.Errorf("expected %v, got %v", want, cs.ToSlice())
+	}
+	if err := cs.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+	if want := []int{7, 6, 5, 4, 3, 2, 1, 0}; !slices.Equal(cs.PopAll(), want) {
+		t.Errorf("expected %v, got %v", want, cs.ToSlice())
+	}
+	if !cs.IsEmpty() || cs.Chunks() != 0 {
+		t.Errorf(errExpectedStackEmpty)
+	}
+}
+
+func TestChunksConcurrent(t *testing.T) {
+	cs := csstack.NewChunked[int](16)
+	runConcurrent(t, 8, func(j int) {
+		for i := range 1000 {
+			cs.Push(i)
+			if i%3 == 0 {
+				cs.PopOpt()
+			}
+		}
+	})
+	if cs.Size() != 8*(1000-334) {
+		t.Errorf(errExpectedSizeX, 8*(1000-334), cs.Size())
+	}
+	if err := cs.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+}
+
+func TestValueAccessors(t *testing.T) {
+	cs := csstack.New[int]()
+	if _, err := cs.TopValue(); err == nil {
+		t.Errorf(errExpectedStackEmpty)
+	}
+	cs.PushN(1, 2, 3, 4)
+	if v, err := cs.TopValue(); err != nil || v != 4 {
+		t.Errorf("expected %v, got %v", 4, v)
+	}
+	if v, err := cs.PeekValue(); err != nil || v != 4 {
+		t.Errorf("expected %v, got %v", 4, v)
+	}
+	if v, err := cs.FindValue(func(v int) bool { return v%2 == 0 }); err != nil || v != 2 {
+		t.Errorf("expected %v, got %v", 2, v)
+	}
+	if v, err := cs.FindLastValue(func(v int) bool { return v%2 == 1 }); err != nil || v != 3 {
+		t.Errorf("expected %v, got %v", 3, v)
+	}
+	if _, err := cs.FindValue(func(v int) bool { return v > 10 }); err == nil {
+		t.Errorf("expected an error, got %v", err)
+	}
+
+	// the pointers returned by the deprecated methods don't alias the stack
+	p, err := cs.Find(func(v int) bool { return v == 1 })
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	*p = 100
+	if cs.Contains(100) {
+		t.Errorf("expected %v, got %v", cs.ToSlice(), p)
+	}
+}
+
+func TestStats(t *testing.T) {
+	cs := csstack.NewChunked[int](16)
+	runConcurrent(t, 4, func(int) {
+		for i := 0; i < 25; i++ {
+			cs.Push(i)
+		}
+	})
+	if _, err := cs.PopN(60); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	cs.PushN(1, 2, 3)
+	growth := cs.Stats().Growth
+	if growth.PeakSize != 100 || growth.PeakCapacity != 112 {
+		t.Errorf("expected %v, got %v", 100, growth.PeakSize)
+	}
+	// the first chunk grows from 8 to 16 items, then 6 more chunks are
+	// allocated
+	if growth.Resizes != 8 || growth.Allocated != 8+16+6*16 {
+		t.Errorf("expected %v, got %v", 8, growth.Resizes)
+	}
+}
+
+// batch is the number of items pushed and popped by the batch benchmarks
+const batch = 1024
+
+func BenchmarkPushNPopN(b *testing.B) {
+	cs := csstack.New[int]()
+	items := make([]int, batch)
+	b.ReportAllocs()
+	for range b.N {
+		cs.PushN(items...)
+		_, _ = cs.PopN(batch)
+	}
+}
+
+func BenchmarkPushAllPopAll(b *testing.B) {
+	cs := csstack.New[int]()
+	items := make([]int, batch)
+	b.ReportAllocs()
+	for range b.N {
+		cs.PushAll(items)
+		_ = cs.PopAll()
+	}
+}
@@ -15,12 +15,28 @@
 // Package dlinkList provides a non-concurrent-safe doubly linked list.
 package dlinkList
 
-import "errors"
+import (
+	"container/list"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"sync"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	arena "github.com/pzaino/gods/pkg/arena"
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+	iterator "github.com/pzaino/gods/pkg/iterator"
+)
 
 const (
 	ErrIndexOutOfBound = "index out of bounds"
 	ErrFailedToInsert  = "failed to insert"
 	ErrValueNotFound   = "value not found"
+	ErrInvalidType     = "element has an invalid type"
 )
 
 // Node is a representation of a node in a doubly linked list
@@ -32,19 +48,90 @@ type Node[T comparable] struct {
 
 // DLinkList is a representation of a doubly linked list
 type DLinkList[T comparable] struct {
-	Head *Node[T]
-	Tail *Node[T]
-	size uint64
+	Head  *Node[T]
+	Tail  *Node[T]
+	size  uint64
+	pool  *sync.Pool            // reused nodes (nil unless created with gods.WithNodePool)
+	arena *arena.Arena[Node[T]] // node allocator (nil unless created with NewWithArena)
+	gen   uint64                // bumped by every structural change, invalidates the views (see SubList)
+}
+
+// New creates a new doubly linked list.
+// The gods.WithNodePool option enables the reuse of the removed nodes.
+func New[T comparable](opts ...gods.Option) *DLinkList[T] {
+	l := &DLinkList[T]{}
+	if len(opts) > 0 && gods.NewOptions(opts...).NodePool {
+		l.pool = &sync.Pool{New: func() any { return new(Node[T]) }}
+	}
+	return l
+}
+
+// NewWithArena creates a new doubly linked list whose nodes are allocated from a. The
+// nodes are released only by a.Free(), all at once, so removing elements
+// doesn't free any memory; after a.Free() the list must not be used any more.
+func NewWithArena[T comparable](a *arena.Arena[Node[T]]) *DLinkList[T] {
+	return &DLinkList[T]{arena: a}
 }
 
-// New creates a new doubly linked list
-func New[T comparable]() *DLinkList[T] {
-	return &DLinkList[T]{}
+// FromList creates a new doubly linked list with the values of a
+// container/list List, in the same order. It returns an error if one of the
+// values is not a T.
+func FromList[T comparable](src *list.List, opts ...gods.Option) (*DLinkList[T], error) {
+	l := New[T](opts...)
+	for e := src.Front(); e != nil; e = e.Next() {
+		value, ok := e.Value.(T)
+		if !ok {
+			return nil, fmt.Errorf("%s: %T", ErrInvalidType, e.Value)
+		}
+		l.Append(value)
+	}
+	return l, nil
+}
+
+// ToList returns a container/list List with the values of the list, in the
+// same order.
+func (l *DLinkList[T]) ToList() *list.List {
+	dst := list.New()
+	for current := l.Head; current != nil; current = current.Next {
+		dst.PushBack(current.Value)
+	}
+	return dst
+}
+
+// derive returns a new empty list sharing the node pool (or arena) of l
+func (l *DLinkList[T]) derive() *DLinkList[T] {
+	return &DLinkList[T]{pool: l.pool, arena: l.arena}
+}
+
+// newNode returns a node with the given value, from the pool or the arena if
+// there is one
+func (l *DLinkList[T]) newNode(value T) *Node[T] {
+	l.gen++
+	if l.arena != nil {
+		node := l.arena.Alloc()
+		node.Value = value
+		return node
+	}
+	if l.pool == nil {
+		return &Node[T]{Value: value}
+	}
+	node := l.pool.Get().(*Node[T])
+	node.Value = value
+	return node
+}
+
+// freeNode returns a removed node to the pool (if there is one)
+func (l *DLinkList[T]) freeNode(node *Node[T]) {
+	l.gen++
+	if l.pool != nil {
+		*node = Node[T]{}
+		l.pool.Put(node)
+	}
 }
 
 // Append adds a new node to the end of the doubly linked list
 func (l *DLinkList[T]) Append(value T) {
-	newNode := &Node[T]{Value: value}
+	newNode := l.newNode(value)
 
 	if l.Head == nil {
 		l.Head = newNode
@@ -61,7 +148,7 @@ func (l *DLinkList[T]) Append(value T) {
 
 // Prepend adds a new node to the beginning of the doubly linked list
 func (l *DLinkList[T]) Prepend(value T) {
-	newNode := &Node[T]{Value: value}
+	newNode := l.newNode(value)
 
 	if l.Head == nil {
 		l.Head = newNode
@@ -94,7 +181,7 @@ func (l *DLinkList[T]) InsertAfter(value, newValue T) {
 		return
 	}
 
-	newNode := &Node[T]{Value: newValue}
+	newNode := l.newNode(newValue)
 	newNode.Next = node.Next
 	newNode.Prev = node
 	node.Next = newNode
@@ -110,7 +197,7 @@ func (l *DLinkList[T]) InsertBefore(value, newValue T) {
 		return
 	}
 
-	newNode := &Node[T]{Value: newValue}
+	newNode := l.newNode(newValue)
 	newNode.Next = node
 	newNode.Prev = node.Prev
 	node.Prev = newNode
@@ -142,7 +229,7 @@ func (l *DLinkList[T]) InsertAt(index uint64, value T) error {
 		return errors.New(ErrIndexOutOfBound)
 	}
 
-	newNode := &Node[T]{Value: value}
+	newNode := l.newNode(value)
 	newNode.Next = current.Next
 	newNode.Prev = current
 	current.Next = newNode
@@ -161,11 +248,13 @@ func (l *DLinkList[T]) DeleteWithValue(value T) {
 	}
 
 	if l.Head.Value == value {
+		removed := l.Head
 		l.Head = l.Head.Next
 		if l.Head != nil {
 			l.Head.Prev = nil
 		}
 		l.size--
+		l.freeNode(removed)
 		return
 	}
 
@@ -175,11 +264,13 @@ func (l *DLinkList[T]) DeleteWithValue(value T) {
 			return
 		}
 		if current.Next.Value == value {
+			removed := current.Next
 			current.Next = current.Next.Next
 			if current.Next != nil {
 				current.Next.Prev = current
 			}
 			l.size--
+			l.freeNode(removed)
 			return
 		}
 		current = current.Next
@@ -207,6 +298,7 @@ func (l *DLinkList[T]) Delete(value T) {
 			l.Head.Prev = nil
 		}
 		l.size--
+		l.freeNode(node)
 		return
 	}
 
@@ -214,12 +306,14 @@ func (l *DLinkList[T]) Delete(value T) {
 		l.Tail = node.Prev
 		l.Tail.Next = nil
 		l.size--
+		l.freeNode(node)
 		return
 	}
 
 	node.Prev.Next = node.Next
 	node.Next.Prev = node.Prev
 	l.size--
+	l.freeNode(node)
 }
 
 // DeleteLast deletes the last node in the doubly linked list
@@ -228,16 +322,19 @@ func (l *DLinkList[T]) DeleteLast() {
 		return
 	}
 
+	removed := l.Tail
 	if l.Tail.Prev == nil {
 		l.Head = nil
 		l.Tail = nil
 		l.size--
+		l.freeNode(removed)
 		return
 	}
 
 	l.Tail = l.Tail.Prev
 	l.Tail.Next = nil
 	l.size--
+	l.freeNode(removed)
 }
 
 // DeleteFirst deletes the first node in the doubly linked list
@@ -246,16 +343,19 @@ func (l *DLinkList[T]) DeleteFirst() {
 		return
 	}
 
+	removed := l.Head
 	if l.Head.Next == nil {
 		l.Head = nil
 		l.Tail = nil
 		l.size--
+		l.freeNode(removed)
 		return
 	}
 
 	l.Head = l.Head.Next
 	l.Head.Prev = nil
 	l.size--
+	l.freeNode(removed)
 }
 
 // DeleteAt deletes the node at the given index
@@ -269,9 +369,15 @@ func (l *DLinkList[T]) DeleteAt(index uint64) error {
 		if l.Head == nil {
 			return errors.New(ErrIndexOutOfBound)
 		}
+		removed := l.Head
 		l.Head = l.Head.Next
-		l.Head.Prev = nil
+		if l.Head != nil {
+			l.Head.Prev = nil
+		} else {
+			l.Tail = nil
+		}
 		l.size--
+		l.freeNode(removed)
 		return nil
 	}
 
@@ -292,7 +398,9 @@ func (l *DLinkList[T]) DeleteAt(index uint64) error {
 	// this is the last node
 	if current.Next == nil {
 		current.Prev.Next = nil
+		l.Tail = current.Prev
 		l.size--
+		l.freeNode(current)
 		return nil
 	}
 
@@ -300,6 +408,7 @@ func (l *DLinkList[T]) DeleteAt(index uint64) error {
 	current.Prev.Next = current.Next
 	current.Next.Prev = current.Prev
 	l.size--
+	l.freeNode(current)
 
 	return nil
 }
@@ -317,6 +426,12 @@ func (l *DLinkList[T]) ToSlice() []T {
 	return result
 }
 
+// Freeze returns an immutable copy of the list, which can be
+// shared without risking changes to it (see gods.Frozen).
+func (l *DLinkList[T]) Freeze() *gods.Frozen[T] {
+	return gods.NewFrozen(l.ToSlice())
+}
+
 // ToSliceReverse converts the doubly linked list to a slice in reverse order
 func (l *DLinkList[T]) ToSliceReverse() []T {
 	var result []T
@@ -368,7 +483,8 @@ func (l *DLinkList[T]) ToSliceReverseFromIndex(index uint64) []T {
 	return result
 }
 
-// Reverse reverses the doubly linked list
+// Reverse reverses the doubly linked list in place, relinking its nodes (the
+// nodes and their values are not moved or copied)
 func (l *DLinkList[T]) Reverse() {
 	current := l.Head
 	var prev *Node[T]
@@ -382,6 +498,38 @@ func (l *DLinkList[T]) Reverse() {
 	}
 
 	l.Head, l.Tail = l.Tail, l.Head
+	l.gen++
+}
+
+// Rotate rotates the list left by n positions, so the node at index n
+// becomes the head (a negative n rotates the list right). The nodes are
+// relinked in place, without allocating or copying their values.
+func (l *DLinkList[T]) Rotate(n int) {
+	if l.size < 2 {
+		return
+	}
+	size := int(l.size)
+	k := ((n % size) + size) % size
+	if k == 0 {
+		return
+	}
+	// find the new head from the closest end
+	var newHead *Node[T]
+	if k <= size/2 {
+		newHead = l.Head
+		for i := 0; i < k; i++ {
+			newHead = newHead.Next
+		}
+	} else {
+		newHead = l.Tail
+		for i := size - 1; i > k; i-- {
+			newHead = newHead.Prev
+		}
+	}
+	l.Tail.Next, l.Head.Prev = l.Head, l.Tail
+	l.Head, l.Tail = newHead, newHead.Prev
+	l.Head.Prev, l.Tail.Next = nil, nil
+	l.gen++
 }
 
 // Find returns the first node with the given value
@@ -445,6 +593,74 @@ func (l *DLinkList[T]) Size() uint64 {
 	return l.size
 }
 
+// MemoryUsage returns an estimate of the bytes held by the list and its nodes
+// (see the gods package), adding the memory returned by extra for every item.
+// The nodes kept by the node pool or by the arena are not included.
+func (l *DLinkList[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
+	return uint64(unsafe.Sizeof(*l)) + gods.NodesMemory[Node[T]](l.size) + gods.ExtraMemory(l.Iter(), extra...)
+}
+
+// Validate checks the invariants of the list: the links between the nodes, the
+// head and the tail and the number of nodes (see gods.Validator).
+func (l *DLinkList[T]) Validate() error {
+	if l.Head == nil || l.Tail == nil {
+		if l.Head != l.Tail || l.size != 0 {
+			return gods.Invariantf("head and tail must both be nil in an empty list (size %d)", l.size)
+		}
+		return nil
+	}
+	if l.Head.Prev != nil {
+		return gods.Invariantf("the head has a previous node")
+	}
+	if l.Tail.Next != nil {
+		return gods.Invariantf("the tail has a next node")
+	}
+	var count uint64
+	for n := l.Head; n != nil; n = n.Next {
+		if count++; count > l.size {
+			return gods.Invariantf("more than size (%d) nodes reachable from the head, or a cycle", l.size)
+		}
+		if n.Next != nil && n.Next.Prev != n {
+			return gods.Invariantf("the previous node of node %d is not node %d", count, count-1)
+		}
+		if n.Next == nil && n != l.Tail {
+			return gods.Invariantf("the last node reachable from the head is not the tail")
+		}
+	}
+	if count != l.size {
+		return gods.Invariantf("size is %d but %d nodes are reachable from the head", l.size, count)
+	}
+	return nil
+}
+
+// Dump writes the chain of the list to w, for debugging, marking the nodes
+// whose previous link doesn't point back to the node before them. It stops
+// after size nodes, so it terminates on a list with a cycle too.
+func (l *DLinkList[T]) Dump(w io.Writer) error {
+	d := gods.NewDumper(w)
+	d.Line(0, "DLinkList size=%d", l.size)
+	chain := []string{"nil"}
+	var prev *Node[T]
+	n := l.Head
+	for i := uint64(0); n != nil && i < l.size; i++ {
+		link := " <-> "
+		if n.Prev != prev {
+			link = " -> (broken prev) "
+		}
+		chain = append(chain, link, fmt.Sprint(n.Value))
+		if n == l.Tail {
+			chain = append(chain, " (tail)")
+		}
+		prev, n = n, n.Next
+	}
+	end := " -> nil"
+	if n != nil {
+		end = " -> ... (more nodes than size)"
+	}
+	d.Line(1, "head: %s%s", strings.Join(chain, ""), end)
+	return d.Err()
+}
+
 // CheckSize recalculates the size of the doubly linked list
 func (l *DLinkList[T]) CheckSize() {
 	size := uint64(0)
@@ -459,9 +675,17 @@ func (l *DLinkList[T]) CheckSize() {
 
 // Clear removes all nodes from the doubly linked list
 func (l *DLinkList[T]) Clear() {
+	if l.pool != nil {
+		for current := l.Head; current != nil; {
+			next := current.Next
+			l.freeNode(current)
+			current = next
+		}
+	}
 	l.Head = nil
 	l.Tail = nil
 	l.size = 0
+	l.gen++
 }
 
 // Contains returns true if the doubly linked list contains the given value
@@ -694,6 +918,7 @@ func (l *DLinkList[T]) removeNode(node *Node[T]) {
 	}
 
 	l.size--
+	l.freeNode(node)
 }
 
 // Filter returns a new doubly linked list containing only the nodes that satisfy the given function
@@ -717,9 +942,24 @@ func (l *DLinkList[T]) Filter(f func(T) bool) {
 	}
 }
 
+// RemoveIf removes all the nodes whose value satisfies pred, in a single
+// pass, and returns how many were removed
+func (l *DLinkList[T]) RemoveIf(pred func(T) bool) int {
+	removed := 0
+	for current := l.Head; current != nil; {
+		next := current.Next
+		if pred(current.Value) {
+			l.removeNode(current)
+			removed++
+		}
+		current = next
+	}
+	return removed
+}
+
 // Map returns a new doubly linked list containing the result of applying the given function to each node
 func (l *DLinkList[T]) Map(f func(T) T) *DLinkList[T] {
-	result := New[T]()
+	result := l.derive()
 
 	current := l.Head
 	for current != nil {
@@ -732,7 +972,7 @@ func (l *DLinkList[T]) Map(f func(T) T) *DLinkList[T] {
 
 // MapFrom returns a new doubly linked list containing the result of applying the given function to each node starting from the given index
 func (l *DLinkList[T]) MapFrom(index uint64, f func(T) T) *DLinkList[T] {
-	result := New[T]()
+	result := l.derive()
 
 	if index > l.size {
 		return result
@@ -760,7 +1000,7 @@ func (l *DLinkList[T]) MapFrom(index uint64, f func(T) T) *DLinkList[T] {
 
 // MapRange returns a new doubly linked list containing the result of applying the given function to each node in the range [start, end)
 func (l *DLinkList[T]) MapRange(start, end uint64, f func(T) T) *DLinkList[T] {
-	result := New[T]()
+	result := l.derive()
 
 	if start > end || start > l.size || end > l.size {
 		return result
@@ -805,7 +1045,7 @@ func (l *DLinkList[T]) Reduce(f func(T, T) T) T {
 
 // Copy returns a new doubly linked list with the same nodes as the original doubly linked list
 func (l *DLinkList[T]) Copy() *DLinkList[T] {
-	newList := New[T]()
+	newList := l.derive()
 
 	current := l.Head
 	for current != nil {
@@ -831,9 +1071,52 @@ func (l *DLinkList[T]) Merge(list *DLinkList[T]) {
 	list.Clear()
 }
 
+// MergeSorted merges other into the list in O(n+m), both being sorted by
+// less, keeping the list sorted: the nodes of other are relinked into the
+// list (unless the lists use different arenas, then their values are copied
+// into new nodes), and other is left empty. On ties the nodes of the list
+// come first, so the merge is stable.
+func (l *DLinkList[T]) MergeSorted(other *DLinkList[T], less cmpx.Less[T]) {
+	if l == other || other.Head == nil {
+		return
+	}
+	var head Node[T] // the node before the head of the merged list
+	tail := &head
+	a, b := l.Head, other.Head
+	for a != nil || b != nil {
+		var n *Node[T]
+		if b == nil || (a != nil && !less(b.Value, a.Value)) {
+			n, a = a, a.Next
+		} else {
+			next := b.Next
+			n, b = l.adopt(other, b), next
+		}
+		tail.Next, n.Prev = n, tail
+		tail = n
+	}
+	tail.Next = nil
+	l.Head, l.Tail = head.Next, tail
+	l.Head.Prev = nil
+	l.size += other.size
+	l.gen++
+	other.Head, other.Tail, other.size = nil, nil, 0
+	other.gen++
+}
+
+// adopt returns n, a node of other, as a node of l: n itself if the lists
+// allocate their nodes in the same way, or a copy
+func (l *DLinkList[T]) adopt(other *DLinkList[T], n *Node[T]) *Node[T] {
+	if l.arena == other.arena {
+		return n
+	}
+	node := l.newNode(n.Value)
+	other.freeNode(n)
+	return node
+}
+
 // ReverseCopy returns a new doubly linked list with the nodes of the original doubly linked list in reverse order
 func (l *DLinkList[T]) ReverseCopy() *DLinkList[T] {
-	newList := New[T]()
+	newList := l.derive()
 
 	current := l.Tail
 	for current != nil {
@@ -922,6 +1205,7 @@ func (l *DLinkList[T]) Sort(f func(T, T) bool) {
 		nodes[i+1].Prev = nodes[i]
 	}
 	nodes[i].Next = nil
+	l.gen++
 }
 
 func quickSort[T comparable](nodes []*Node[T], f func(T, T) bool, low, high int) {
@@ -950,7 +1234,7 @@ func partition[T comparable](nodes []*Node[T], f func(T, T) bool, low, high int)
 
 // FindAll returns a new doubly linked list containing all nodes that satisfy the given function
 func (l *DLinkList[T]) FindAll(f func(T) bool) *DLinkList[T] {
-	newList := New[T]()
+	newList := l.derive()
 
 	current := l.Head
 	for current != nil {
@@ -1012,3 +1296,220 @@ func (l *DLinkList[T]) FindIndex(f func(T) bool) int {
 
 	return -1
 }
+
+// Iter returns an iterator over the values in the doubly linked list, from the head to the tail
+func (l *DLinkList[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for current := l.Head; current != nil; current = current.Next {
+			if !yield(current.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Iterator is a bidirectional iterator over the values of a doubly linked list
+type Iterator[T comparable] struct {
+	list    *DLinkList[T]
+	current *Node[T]
+	// before and after mark the positions before the head and after the tail
+	before bool
+	after  bool
+}
+
+// Iterator returns a bidirectional iterator over the values in the doubly linked list
+// The iterator starts before the head of the list.
+func (l *DLinkList[T]) Iterator() iterator.BidirectionalIterator[T] {
+	return &Iterator[T]{list: l, before: true}
+}
+
+// Next moves the iterator to the next node
+func (it *Iterator[T]) Next() bool {
+	switch {
+	case it.after:
+		return false
+	case it.before:
+		it.before = false
+		it.current = it.list.Head
+	default:
+		it.current = it.current.Next
+	}
+	if it.current == nil {
+		it.after = true
+		return false
+	}
+	return true
+}
+
+// Prev moves the iterator to the previous node
+func (it *Iterator[T]) Prev() bool {
+	switch {
+	case it.before:
+		return false
+	case it.after:
+		it.after = false
+		it.current = it.list.Tail
+	default:
+		it.current = it.current.Prev
+	}
+	if it.current == nil {
+		it.before = true
+		return false
+	}
+	return true
+}
+
+// Value returns the value of the current node
+func (it *Iterator[T]) Value() T {
+	if it.current == nil {
+		var zero T
+		return zero
+	}
+	return it.current.Value
+}
+
+// Enumerate returns an iterator over the index and the value of the nodes in the doubly linked list
+func (l *DLinkList[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		i := uint64(0)
+		for current := l.Head; current != nil; current = current.Next {
+			if !yield(i, current.Value) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (l *DLinkList[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(l.Enumerate())
+}
+
+// Values returns an iterator over the items, in the same order as Enumerate
+// (it is Iter, under the name used by the standard library).
+func (l *DLinkList[T]) Values() iter.Seq[T] {
+	return l.Iter()
+}
+
+// Backward returns an iterator over the values in the doubly linked list, from the tail to the head
+func (l *DLinkList[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for current := l.Tail; current != nil; current = current.Prev {
+			if !yield(current.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Encode writes the list to w using the given codec
+func (l *DLinkList[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeSlice(w, codec, l.ToSlice())
+}
+
+// Decode replaces the content of the list with the elements read from r using the given codec
+func (l *DLinkList[T]) Decode(r io.Reader, codec gods.Codec) error {
+	items, err := gods.DecodeSlice[T](r, codec)
+	if err != nil {
+		return err
+	}
+	l.Clear()
+	for _, item := range items {
+		l.Append(item)
+	}
+	return nil
+}
+
+// EncodeStream writes the list to w incrementally, in chunks encoded with the
+// given codec (see gods.EncodeStream), without copying its elements first.
+func (l *DLinkList[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeStream(w, codec, l.Iter())
+}
+
+// DecodeStream replaces the content of the list with the elements of a
+// stream written by EncodeStream, adding them as they are read.
+func (l *DLinkList[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	l.Clear()
+	return gods.DecodeStream(r, codec, func(item T) error {
+		l.Append(item)
+		return nil
+	})
+}
+
+// MarshalText implements encoding.TextMarshaler: the list is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (l *DLinkList[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(l)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (l *DLinkList[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(l, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText.
+// It has a value receiver, so a list stored by value in a struct field is
+// encoded as an array even when the struct is marshaled by value (and its
+// elements use their own marshalers, if they have any).
+func (l DLinkList[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(&l)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (l *DLinkList[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(l, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary),
+// which gob uses to encode the list. Like MarshalJSON it has a value receiver.
+func (l DLinkList[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(&l)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (l *DLinkList[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(l, data)
+}
+
+// MarshalYAML implements the Marshaler interface of the YAML packages
+// (gopkg.in/yaml.v2 and v3): the list is encoded as a sequence, in order.
+func (l *DLinkList[T]) MarshalYAML() (any, error) {
+	return l.ToSlice(), nil
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of gopkg.in/yaml.v2
+// (also honored by v3): the content of the list is replaced with the elements
+// of a YAML sequence.
+func (l *DLinkList[T]) UnmarshalYAML(unmarshal func(any) error) error {
+	var items []T
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+	l.Clear()
+	for _, item := range items {
+		l.Append(item)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer: the list is stored as a JSON array, so it
+// can be saved in a JSON (or JSONB) column.
+func (l *DLinkList[T]) Value() (driver.Value, error) {
+	return gods.ValueSlice(l.ToSlice())
+}
+
+// Scan implements sql.Scanner: the content of the list is replaced with the
+// elements of the JSON array read from the database (NULL is an empty list).
+func (l *DLinkList[T]) Scan(src any) error {
+	items, err := gods.ScanSlice[T](src)
+	if err != nil {
+		return err
+	}
+	l.Clear()
+	for _, item := range items {
+		l.Append(item)
+	}
+	return nil
+}
@@ -16,10 +16,20 @@
 package dlinkList_test
 
 import (
+	"bytes"
+	"container/list"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
+	gods "github.com/pzaino/gods"
+	arena "github.com/pzaino/gods/pkg/arena"
 	dlinkList "github.com/pzaino/gods/pkg/dlinkList"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 const (
@@ -2246,3 +2256,454 @@ func TestForEachReverseEmpty(t *testing.T) {
 		t.Errorf(errExpectedEmpty, result)
 	}
 }
+
+func TestIter(t *testing.T) {
+	list := dlinkList.New[int]()
+	for i := 1; i <= 3; i++ {
+		list.Append(i)
+	}
+	if got := slices.Collect(list.Iter()); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, got)
+	}
+	for v := range list.Iter() {
+		if v != 1 {
+			t.Errorf("Expected %v, got %v", 1, v)
+		}
+		break
+	}
+}
+
+func TestIterator(t *testing.T) {
+	list := dlinkList.New[int]()
+	for i := 1; i <= 3; i++ {
+		list.Append(i)
+	}
+
+	it := list.Iterator()
+	if it.Prev() {
+		t.Error("Expected Prev to return false before the head")
+	}
+	if got := iterator.ToSlice[int](it); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, got)
+	}
+	if it.Next() {
+		t.Error("Expected Next to return false after the tail")
+	}
+
+	var back []int
+	for it.Prev() {
+		back = append(back, it.Value())
+	}
+	if !slices.Equal(back, []int{3, 2, 1}) {
+		t.Errorf("Expected %v, got %v", []int{3, 2, 1}, back)
+	}
+	if it.Value() != 0 {
+		t.Errorf("Expected %v, got %v", 0, it.Value())
+	}
+	if !it.Next() || it.Value() != 1 {
+		t.Errorf("Expected %v, got %v", 1, it.Value())
+	}
+
+	if dlinkList.New[int]().Iterator().Next() {
+		t.Error("Expected empty iteration")
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	list := dlinkList.New[int]()
+	for i := 1; i <= 3; i++ {
+		list.Append(i)
+	}
+	var indexes []uint64
+	var values []int
+	for i, v := range list.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0, 1, 2}) {
+		t.Errorf("Expected %v, got %v", []uint64{0, 1, 2}, indexes)
+	}
+	if !slices.Equal(values, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, values)
+	}
+	if got := slices.Collect(list.Backward()); !slices.Equal(got, []int{3, 2, 1}) {
+		t.Errorf("Expected %v, got %v", []int{3, 2, 1}, got)
+	}
+	if got := slices.Collect(list.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+	if got := slices.Collect(list.Values()); !slices.Equal(got, values) {
+		t.Errorf("Expected %v, got %v", values, got)
+	}
+}
+
+func TestNodePool(t *testing.T) {
+	l := dlinkList.New[int](gods.WithNodePool())
+	for i := 0; i < 5; i++ {
+		l.Append(i)
+	}
+	// heavy churn: removed nodes are reused by the following inserts
+	for i := 5; i < 105; i++ {
+		l.DeleteFirst()
+		l.Append(i)
+		l.DeleteLast()
+		l.Append(i)
+	}
+	expected := []int{100, 101, 102, 103, 104}
+	if !slices.Equal(l.ToSlice(), expected) {
+		t.Errorf(errExpectedX, expected, l.ToSlice())
+	}
+	if !slices.Equal(slices.Collect(l.Backward()), []int{104, 103, 102, 101, 100}) {
+		t.Errorf(errExpectedX, "the reversed list", slices.Collect(l.Backward()))
+	}
+
+	// derived lists share the pool but not the nodes
+	c := l.Copy()
+	l.Clear()
+	l.Append(1)
+	if !slices.Equal(c.ToSlice(), expected) {
+		t.Errorf(errExpectedX, expected, c.ToSlice())
+	}
+}
+
+func TestDeleteAtUpdatesTail(t *testing.T) {
+	l := dlinkList.New[int]()
+	l.Append(1)
+	l.Append(2)
+	if err := l.DeleteAt(1); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	l.Append(3)
+	if !slices.Equal(slices.Collect(l.Backward()), []int{3, 1}) {
+		t.Errorf(errExpectedX, []int{3, 1}, slices.Collect(l.Backward()))
+	}
+
+	l = dlinkList.New[int]()
+	l.Append(1)
+	if err := l.DeleteAt(0); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if !l.IsEmpty() || l.Tail != nil {
+		t.Errorf(errListNotEmpty)
+	}
+}
+
+func TestNewWithArena(t *testing.T) {
+	a := arena.New[dlinkList.Node[int]](4)
+	l := dlinkList.NewWithArena(a)
+	for i := 0; i < 10; i++ {
+		l.Append(i)
+	}
+	c := l.Copy()
+	l.DeleteFirst()
+	l.DeleteLast()
+	if a.Len() != 20 {
+		t.Errorf(errExpectedX, 20, a.Len())
+	}
+	if !slices.Equal(l.ToSlice(), []int{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf(errExpectedX, []int{1, 2, 3, 4, 5, 6, 7, 8}, l.ToSlice())
+	}
+	if c.Size() != 10 {
+		t.Errorf(errWrongSize, 10, c.Size())
+	}
+}
+
+func TestYAML(t *testing.T) {
+	list := dlinkList.New[int]()
+	list.Append(3)
+	list.Append(1)
+	v, err := list.MarshalYAML()
+	if err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if items, ok := v.([]int); !ok || !slices.Equal(items, []int{3, 1}) {
+		t.Errorf(errExpectedX, []int{3, 1}, v)
+	}
+
+	// the YAML packages call UnmarshalYAML with a function decoding the
+	// node into the given value
+	unmarshal := func(v any) error {
+		return json.Unmarshal([]byte("[1, 2, 3]"), v)
+	}
+	if err := list.UnmarshalYAML(unmarshal); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if got := list.ToSlice(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf(errExpectedX, []int{1, 2, 3}, got)
+	}
+}
+
+func TestContainerListInterop(t *testing.T) {
+	src := list.New()
+	src.PushBack(1)
+	src.PushBack(2)
+	src.PushFront(0)
+	l, err := dlinkList.FromList[int](src)
+	if err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if got := l.ToSlice(); !slices.Equal(got, []int{0, 1, 2}) || l.Tail.Value != 2 {
+		t.Errorf(errExpectedX, []int{0, 1, 2}, got)
+	}
+
+	dst := l.ToList()
+	var values []int
+	for e := dst.Front(); e != nil; e = e.Next() {
+		values = append(values, e.Value.(int))
+	}
+	if !slices.Equal(values, []int{0, 1, 2}) {
+		t.Errorf(errExpectedX, []int{0, 1, 2}, values)
+	}
+
+	src.PushBack("three")
+	if _, err := dlinkList.FromList[int](src); err == nil {
+		t.Errorf(errYesError)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	list := dlinkList.New[int]()
+	if err := list.Validate(); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	for i := 0; i < 5; i++ {
+		list.Append(i)
+	}
+	if err := list.Validate(); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	list.Head.Next.Next.Prev = list.Head
+	if err := list.Validate(); err == nil {
+		t.Errorf(errYesError)
+	}
+	list.Head.Next.Next.Prev = list.Head.Next
+	list.Tail = list.Tail.Prev
+	if err := list.Validate(); err == nil {
+		t.Errorf(errYesError)
+	}
+}
+
+func TestDump(t *testing.T) {
+	list := dlinkList.New[int]()
+	for i := 1; i <= 3; i++ {
+		list.Append(i)
+	}
+	var sb strings.Builder
+	if err := list.Dump(&sb); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	want := "DLinkList size=3\n  head: nil <-> 1 <-> 2 <-> 3 (tail) -> nil\n"
+	if sb.String() != want {
+		t.Errorf(errExpectedX, want, sb.String())
+	}
+
+	list.Head.Next.Next.Prev = list.Head
+	sb.Reset()
+	_ = list.Dump(&sb)
+	if !strings.Contains(sb.String(), "2 -> (broken prev) 3") {
+		t.Errorf("Expected the broken link to be marked, but got %q", sb.String())
+	}
+}
+
+func TestRotate(t *testing.T) {
+	list := dlinkList.New[int]()
+	for i := 1; i <= 5; i++ {
+		list.Append(i)
+	}
+	head := list.GetFirst()
+	for _, tc := range []struct {
+		n    int
+		want []int
+	}{
+		{1, []int{2, 3, 4, 5, 1}},
+		{4, []int{1, 2, 3, 4, 5}},
+		{-1, []int{5, 1, 2, 3, 4}},
+		{6, []int{1, 2, 3, 4, 5}},
+	} {
+		list.Rotate(tc.n)
+		if got := list.ToSlice(); !slices.Equal(got, tc.want) {
+			t.Errorf(errExpectedX, tc.want, got)
+		}
+		if err := list.Validate(); err != nil {
+			t.Errorf(errNoError, err)
+		}
+	}
+	if list.GetFirst() != head {
+		t.Errorf("Expected the head to be the same node")
+	}
+}
+
+func TestMergeSorted(t *testing.T) {
+	list, other := dlinkList.New[int](), dlinkList.New[int]()
+	for _, v := range []int{2, 3, 8} {
+		list.Append(v)
+	}
+	for _, v := range []int{1, 3, 5, 9, 12} {
+		other.Append(v)
+	}
+	list.MergeSorted(other, func(a, b int) bool { return a < b })
+	want := []int{1, 2, 3, 3, 5, 8, 9, 12}
+	if got := list.ToSlice(); !slices.Equal(got, want) {
+		t.Errorf(errExpectedX, want, got)
+	}
+	if got := list.ToSliceReverse(); !slices.Equal(got, []int{12, 9, 8, 5, 3, 3, 2, 1}) {
+		t.Errorf(errExpectedX, []int{12, 9, 8, 5, 3, 3, 2, 1}, got)
+	}
+	if !other.IsEmpty() || other.GetFirst() != nil || other.GetLast() != nil {
+		t.Errorf("Expected other to be empty")
+	}
+	if err := list.Validate(); err != nil {
+		t.Errorf(errNoError, err)
+	}
+}
+
+func TestSubList(t *testing.T) {
+	newList := func(n int) *dlinkList.DLinkList[int] {
+		l := dlinkList.New[int]()
+		for i := 0; i < n; i++ {
+			l.Append(i)
+		}
+		return l
+	}
+	// every window, from both ends
+	for from := uint64(0); from <= 6; from++ {
+		for to := from; to <= 6; to++ {
+			view, err := newList(6).SubList(from, to)
+			if err != nil {
+				t.Fatalf(errNoError, err)
+			}
+			got, _ := view.ToSlice()
+			want := []int{0, 1, 2, 3, 4, 5}[from:to]
+			if !slices.Equal(got, want) {
+				t.Errorf(errExpectedX, want, got)
+			}
+		}
+	}
+	if _, err := newList(3).SubList(2, 1); err == nil {
+		t.Errorf(errYesError)
+	}
+	if _, err := newList(3).SubList(0, 4); err == nil {
+		t.Errorf(errYesError)
+	}
+
+	list := newList(6)
+	view, _ := list.SubList(2, 4)
+	if v, err := view.Get(1); err != nil || v != 3 {
+		t.Errorf(errExpectedX, 3, v)
+	}
+	if _, err := view.Get(2); err == nil {
+		t.Errorf(errYesError)
+	}
+	if err := view.Set(0, 20); err != nil {
+		t.Errorf(errNoError, err)
+	}
+	view.Append(40)
+	view.Prepend(10)
+	if err := view.DeleteAt(2); err != nil {
+		t.Errorf(errNoError, err)
+	}
+	if got := list.ToSlice(); !slices.Equal(got, []int{0, 1, 10, 20, 40, 4, 5}) {
+		t.Errorf(errExpectedX, []int{0, 1, 10, 20, 40, 4, 5}, got)
+	}
+	if view.Size() != 3 || list.Size() != 7 {
+		t.Errorf(errExpectedX, 3, view.Size())
+	}
+	view.ForEach(func(v *int) { *v++ })
+	if got := slices.Collect(view.Iter()); !slices.Equal(got, []int{11, 21, 41}) {
+		t.Errorf(errExpectedX, []int{11, 21, 41}, got)
+	}
+	if err := view.Clear(); err != nil || !view.IsEmpty() {
+		t.Errorf(errNoError, err)
+	}
+	view.Append(7)
+	if got := list.ToSlice(); !slices.Equal(got, []int{0, 1, 7, 4, 5}) {
+		t.Errorf(errExpectedX, []int{0, 1, 7, 4, 5}, got)
+	}
+	if err := list.Validate(); err != nil {
+		t.Errorf(errNoError, err)
+	}
+
+	// changing the values of the list keeps the view valid
+	list.Swap(0, 1)
+	if !view.Valid() {
+		t.Errorf("Expected the view to be valid")
+	}
+	// changing its structure doesn't
+	list.Append(6)
+	if view.Valid() {
+		t.Errorf("Expected the view to be stale")
+	}
+	if _, err := view.ToSlice(); err == nil || err.Error() != dlinkList.ErrStaleView {
+		t.Errorf(errExpectedX, dlinkList.ErrStaleView, err)
+	}
+	if err := view.Append(1); err == nil {
+		t.Errorf(errYesError)
+	}
+	if got := slices.Collect(view.Iter()); len(got) != 0 {
+		t.Errorf(errExpectedEmpty, got)
+	}
+}
+
+// money has custom JSON and gob encodings, which the lists must honor
+type money struct{ cents int64 }
+
+func (m money) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("\"%d.%02d\"", m.cents/100, m.cents%100)), nil
+}
+
+func (m *money) UnmarshalJSON(data []byte) error {
+	var units, cents int64
+	if _, err := fmt.Sscanf(string(data), "\"%d.%d\"", &units, &cents); err != nil {
+		return err
+	}
+	m.cents = units*100 + cents
+	return nil
+}
+
+func (m money) GobEncode() ([]byte, error) {
+	return []byte(fmt.Sprint(m.cents)), nil
+}
+
+func (m *money) GobDecode(data []byte) error {
+	_, err := fmt.Sscan(string(data), &m.cents)
+	return err
+}
+
+func TestMarshalCustomElements(t *testing.T) {
+	type order struct {
+		Ptr *dlinkList.DLinkList[money]
+		Val dlinkList.DLinkList[money]
+	}
+	src := order{Ptr: dlinkList.New[money]()}
+	src.Ptr.Append(money{150})
+	src.Ptr.Append(money{2})
+	src.Val.Append(money{1999})
+
+	// the struct is marshaled by value: the list field must still be an array
+	data, err := json.Marshal(src)
+	if err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if want := `{"Ptr":["1.50","0.02"],"Val":["19.99"]}`; string(data) != want {
+		t.Errorf(errExpectedX, want, string(data))
+	}
+	var dst order
+	if err := json.Unmarshal(data, &dst); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if !slices.Equal(dst.Ptr.ToSlice(), src.Ptr.ToSlice()) || !slices.Equal(dst.Val.ToSlice(), src.Val.ToSlice()) {
+		t.Errorf(errExpectedX, src, dst)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(src); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	dst = order{}
+	if err := gob.NewDecoder(&buf).Decode(&dst); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if !slices.Equal(dst.Ptr.ToSlice(), src.Ptr.ToSlice()) || !slices.Equal(dst.Val.ToSlice(), src.Val.ToSlice()) {
+		t.Errorf(errExpectedX, src, dst)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dlinkList
+
+import (
+	"errors"
+	"iter"
+)
+
+// ErrStaleView is returned by the methods of a View whose list has been
+// structurally modified other than through the view itself
+const ErrStaleView = "stale view: the list was modified"
+
+// View is a window over a range of consecutive nodes of a DLinkList,
+// returned by SubList. It doesn't copy the nodes: reading the view reads the
+// list, and changing the view (Set, Append, Prepend, DeleteAt, Clear)
+// changes the list.
+//
+// A view is bound to the structure of the list when it's created: once the
+// list has nodes inserted, removed or relinked other than through the view,
+// the view becomes stale and its methods return ErrStaleView. Changing the
+// values only (Swap, ForEach, ...) doesn't invalidate it. Changing the
+// Head, Tail or the nodes links directly can't be detected.
+type View[T comparable] struct {
+	list   *DLinkList[T]
+	before *Node[T] // the node before the window (nil if it starts at the head)
+	after  *Node[T] // the node after the window (nil if it ends at the tail)
+	size   uint64
+	gen    uint64
+}
+
+// SubList returns a view of the nodes in [from, to). It returns
+// ErrIndexOutOfBound if from > to or to > Size(). It takes O(min(to, n-from)).
+func (l *DLinkList[T]) SubList(from, to uint64) (*View[T], error) {
+	if from > to || to > l.size {
+		return nil, errors.New(ErrIndexOutOfBound)
+	}
+	v := &View[T]{list: l, size: to - from, gen: l.gen}
+	// the nodes at from-1 and to, from the closest end
+	if from <= l.size-to {
+		n := l.Head
+		for i := uint64(0); i < from; i++ {
+			v.before, n = n, n.Next
+		}
+		for i := from; i < to; i++ {
+			n = n.Next
+		}
+		v.after = n
+	} else {
+		n := l.Tail
+		for i := l.size; i > to+1; i-- {
+			n = n.Prev
+		}
+		if to < l.size {
+			v.after, n = n, n.Prev
+		}
+		for i := to; i > from; i-- {
+			n = n.Prev
+		}
+		v.before = n
+	}
+	return v, nil
+}
+
+// Valid returns true if the view is still usable (it's not stale)
+func (v *View[T]) Valid() bool {
+	return v.gen == v.list.gen
+}
+
+// Size returns the number of nodes in the view
+func (v *View[T]) Size() uint64 {
+	return v.size
+}
+
+// IsEmpty returns true if the view has no nodes
+func (v *View[T]) IsEmpty() bool {
+	return v.size == 0
+}
+
+// first returns the first node of the view (or v.after if it's empty)
+func (v *View[T]) first() *Node[T] {
+	if v.before == nil {
+		return v.list.Head
+	}
+	return v.before.Next
+}
+
+// last returns the last node of the view (or v.before if it's empty)
+func (v *View[T]) last() *Node[T] {
+	if v.after == nil {
+		return v.list.Tail
+	}
+	return v.after.Prev
+}
+
+// check returns ErrStaleView if the view is stale
+func (v *View[T]) check() error {
+	if !v.Valid() {
+		return errors.New(ErrStaleView)
+	}
+	return nil
+}
+
+// nodeAt returns the node at index i of the view, walking from its closest end
+func (v *View[T]) nodeAt(i uint64) (*Node[T], error) {
+	if err := v.check(); err != nil {
+		return nil, err
+	}
+	if i >= v.size {
+		return nil, errors.New(ErrIndexOutOfBound)
+	}
+	if i < v.size/2 {
+		n := v.first()
+		for ; i > 0; i-- {
+			n = n.Next
+		}
+		return n, nil
+	}
+	n := v.last()
+	for j := v.size - 1; j > i; j-- {
+		n = n.Prev
+	}
+	return n, nil
+}
+
+// Get returns the value at index i of the view
+func (v *View[T]) Get(i uint64) (T, error) {
+	n, err := v.nodeAt(i)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return n.Value, nil
+}
+
+// Set replaces the value at index i of the view
+func (v *View[T]) Set(i uint64, value T) error {
+	n, err := v.nodeAt(i)
+	if err != nil {
+		return err
+	}
+	n.Value = value
+	return nil
+}
+
+// link inserts a new node with the given value between prev and next
+// (either can be nil at the ends of the list), keeping the view valid
+func (v *View[T]) link(prev, next *Node[T], value T) {
+	l := v.list
+	node := l.newNode(value)
+	node.Prev, node.Next = prev, next
+	if prev == nil {
+		l.Head = node
+	} else {
+		prev.Next = node
+	}
+	if next == nil {
+		l.Tail = node
+	} else {
+		next.Prev = node
+	}
+	l.size++
+	v.size++
+	v.gen = l.gen
+}
+
+// Append adds a value at the end of the view (inserting it in the list)
+func (v *View[T]) Append(value T) error {
+	if err := v.check(); err != nil {
+		return err
+	}
+	prev := v.before
+	if v.size > 0 {
+		prev = v.last()
+	}
+	v.link(prev, v.after, value)
+	return nil
+}
+
+// Prepend adds a value at the start of the view (inserting it in the list)
+func (v *View[T]) Prepend(value T) error {
+	if err := v.check(); err != nil {
+		return err
+	}
+	next := v.after
+	if v.size > 0 {
+		next = v.first()
+	}
+	v.link(v.before, next, value)
+	return nil
+}
+
+// DeleteAt removes the node at index i of the view from the list
+func (v *View[T]) DeleteAt(i uint64) error {
+	n, err := v.nodeAt(i)
+	if err != nil {
+		return err
+	}
+	v.list.removeNode(n)
+	v.size--
+	v.gen = v.list.gen
+	return nil
+}
+
+// Clear removes all the nodes of the view from the list
+func (v *View[T]) Clear() error {
+	if err := v.check(); err != nil {
+		return err
+	}
+	for n := v.first(); n != v.after; {
+		next := n.Next
+		v.list.removeNode(n)
+		n = next
+	}
+	v.size = 0
+	v.gen = v.list.gen
+	return nil
+}
+
+// ToSlice returns the values of the view, in order
+func (v *View[T]) ToSlice() ([]T, error) {
+	if err := v.check(); err != nil {
+		return nil, err
+	}
+	result := make([]T, 0, v.size)
+	for n := v.first(); n != v.after; n = n.Next {
+		result = append(result, n.Value)
+	}
+	return result, nil
+}
+
+// ForEach calls f with a pointer to each value of the view, in order, so f
+// can update the values in place
+func (v *View[T]) ForEach(f func(*T)) error {
+	if err := v.check(); err != nil {
+		return err
+	}
+	for n := v.first(); n != v.after; n = n.Next {
+		f(&n.Value)
+	}
+	return nil
+}
+
+// Iter returns an iterator over the values of the view. The iteration stops
+// early if the view becomes stale.
+func (v *View[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if !v.Valid() {
+			return
+		}
+		for n := v.first(); n != v.after; {
+			next := n.Next
+			if !yield(n.Value) || !v.Valid() {
+				return
+			}
+			n = next
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package durable provides a concurrency-safe queue and stack backed by an
+// append-only log on disk.
+//
+// Every Enqueue/Push is appended to the log before it returns, every
+// Dequeue/Pop appends a tombstone. When a container is reopened, the log is
+// replayed to rebuild its content. Tombstones are removed by a compaction,
+// started in the background when they outnumber the live elements.
+//
+// The log is a directory of segment files: when the active segment grows
+// beyond the segment size (see WithSegmentSize), it is synced and a new one
+// is started. Every record carries a CRC-32C checksum of its content.
+//
+// The durability of the writes depends on the SyncPolicy: with SyncAlways
+// (the default) an element is on stable storage as soon as Enqueue/Push
+// returns. Concurrent writers share their syncs (group commit): while a sync
+// is running the following records are batched, and synced all together by
+// the next one. A write is visible to the other goroutines as soon as it's in
+// the log, possibly before its sync is over.
+//
+// The delivery guarantee is at-least-once: an element removed just before a
+// crash can be returned again after reopening the container, if its
+// tombstone was not synced yet.
+//
+// # Recovery
+//
+// Opening a container recovers the log left by a crash:
+//
+//  1. Temporary files of an interrupted compaction are removed.
+//  2. The segments written before the last completed compaction are removed
+//     (the compacted segment holds all their live elements).
+//  3. The segments are replayed in order. In the last segment, the first
+//     record that is truncated or fails its checksum is the torn tail of a
+//     write interrupted by the crash: the segment is truncated before it and
+//     the log continues from there. With SyncAlways, those records were never
+//     acknowledged to the caller.
+//  4. A bad record in any other segment, which was synced before the next
+//     one was started, is real corruption: the container can't be opened
+//     (ErrCorruptedLog) and the files are left untouched for inspection.
+//
+// If a sync fails, the state of the file on disk is unknown: the container
+// stops accepting writes, returning the error of the sync, and must be
+// reopened to recover.
+package durable
+
+import (
+	"sync"
+	"time"
+
+	gods "github.com/pzaino/gods"
+)
+
+const (
+	ErrClosed        = "durable container is closed"
+	ErrCorruptedLog  = "corrupted log record"
+	ErrRecordTooLong = "log record too long"
+)
+
+// SyncPolicy selects when the log is synced (fsync) to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncAlways syncs the log on every write, before it returns.
+	SyncAlways SyncPolicy = iota
+	// SyncPeriodic syncs the log in the background, every sync interval (see
+	// WithSyncInterval). A crash can lose the writes of the last interval.
+	SyncPeriodic
+	// SyncNever leaves the sync to the operating system. The writes survive
+	// a crash of the process, but not of the machine.
+	SyncNever
+)
+
+const (
+	// DefaultSyncInterval is the sync interval of SyncPeriodic
+	DefaultSyncInterval = time.Second
+	// DefaultCompactionThreshold is the minimum number of tombstones that
+	// triggers a compaction
+	DefaultCompactionThreshold = 1024
+	// DefaultSegmentSize is the size that triggers the rotation of a segment
+	DefaultSegmentSize = 64 << 20
+)
+
+// Option configures a durable container.
+type Option func(*config)
+
+type config struct {
+	policy       SyncPolicy
+	syncInterval time.Duration
+	compactAt    int
+	segmentSize  int64
+	codec        gods.Codec
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		policy:       SyncAlways,
+		syncInterval: DefaultSyncInterval,
+		compactAt:    DefaultCompactionThreshold,
+		segmentSize:  DefaultSegmentSize,
+		codec:        gods.JSONCodec{},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithSyncPolicy selects when the log is synced to stable storage.
+func WithSyncPolicy(policy SyncPolicy) Option {
+	return func(cfg *config) {
+		cfg.policy = policy
+	}
+}
+
+// WithSyncInterval sets the interval of the SyncPeriodic policy.
+func WithSyncInterval(d time.Duration) Option {
+	return func(cfg *config) {
+		if d > 0 {
+			cfg.syncInterval = d
+		}
+	}
+}
+
+// WithCompactionThreshold sets the minimum number of tombstones that
+// triggers a compaction (a compaction also requires the tombstones to
+// outnumber the live elements).
+func WithCompactionThreshold(n int) Option {
+	return func(cfg *config) {
+		if n > 0 {
+			cfg.compactAt = n
+		}
+	}
+}
+
+// WithSegmentSize sets the size of a segment of the log: when it's exceeded,
+// the segment is synced and a new one is started.
+func WithSegmentSize(n int64) Option {
+	return func(cfg *config) {
+		if n > 0 {
+			cfg.segmentSize = n
+		}
+	}
+}
+
+// WithCodec selects the codec used to encode the elements in the log (the
+// default is gods.JSONCodec).
+func WithCodec(codec gods.Codec) Option {
+	return func(cfg *config) {
+		cfg.codec = codec
+	}
+}
+
+// background runs the periodic sync and the compactions of a durable
+// container
+type background struct {
+	mu      sync.Locker
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	busy    bool // a compaction is running
+	stopped bool
+}
+
+func newBackground(mu sync.Locker, cfg *config, sync func() error) *background {
+	b := &background{mu: mu, stop: make(chan struct{})}
+	if cfg.policy == SyncPeriodic {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			ticker := time.NewTicker(cfg.syncInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					b.mu.Lock()
+					_ = sync()
+					b.mu.Unlock()
+				case <-b.stop:
+					return
+				}
+			}
+		}()
+	}
+	return b
+}
+
+// compact starts compact in a goroutine, unless a compaction is already
+// running. It must be called with the lock held.
+func (b *background) compact(compact func() error) {
+	if b.busy || b.stopped {
+		return
+	}
+	b.busy = true
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		_ = compact()
+		b.busy = false
+	}()
+}
+
+// close stops the background goroutines and waits for them, including a
+// running compaction. It must be called without the lock, and it is a no-op
+// after the first call.
+func (b *background) close() {
+	b.mu.Lock()
+	stopped := b.stopped
+	b.stopped = true
+	b.mu.Unlock()
+	if stopped {
+		return
+	}
+	close(b.stop)
+	b.wg.Wait()
+}
@@ -0,0 +1,309 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package durable_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	durable "github.com/pzaino/gods/pkg/durable"
+	godstest "github.com/pzaino/gods/pkg/godstest"
+)
+
+const (
+	errExpectedX       = "expected %v, got %v"
+	errExpectedNoError = "expected no error, got %v"
+)
+
+func TestQueueReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue")
+	q, err := durable.OpenQueue[int](path)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	for i := 1; i <= 4; i++ {
+		if err := q.Enqueue(i); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+	}
+	if v, err := q.Dequeue(); err != nil || v != 1 {
+		t.Fatalf(errExpectedX, 1, v)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+
+	q, err = durable.OpenQueue[int](path)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	defer q.Close()
+	if got := q.ToSlice(); !slices.Equal(got, []int{2, 3, 4}) {
+		t.Errorf(errExpectedX, []int{2, 3, 4}, got)
+	}
+}
+
+func TestStackReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stack")
+	s, err := durable.OpenStack[string](path, durable.WithSyncPolicy(durable.SyncNever))
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	for _, v := range []string{"a", "b", "c"} {
+		if err := s.Push(v); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+	}
+	if v, err := s.Pop(); err != nil || v != "c" {
+		t.Fatalf(errExpectedX, "c", v)
+	}
+	if err := s.Compact(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+
+	s, err = durable.OpenStack[string](path)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	defer s.Close()
+	if got := s.ToSlice(); !slices.Equal(got, []string{"b", "a"}) {
+		t.Errorf(errExpectedX, []string{"b", "a"}, got)
+	}
+}
+
+// logSize returns the total size of the segments in dir
+func logSize(t *testing.T, dir string) int64 {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var size int64
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += info.Size()
+	}
+	return size
+}
+
+func TestCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue")
+	q, err := durable.OpenQueue[int](path, durable.WithCompactionThreshold(8))
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	for i := 0; i < 100; i++ {
+		_ = q.Enqueue(i)
+	}
+	full := logSize(t, path)
+	for i := 0; i < 99; i++ {
+		_, _ = q.Dequeue()
+	}
+	// Close waits for the background compaction
+	if err := q.Close(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if compacted := logSize(t, path); compacted >= full {
+		t.Errorf("expected the log to shrink below %d bytes, got %d", full, compacted)
+	}
+
+	q, err = durable.OpenQueue[int](path)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	defer q.Close()
+	if got := q.ToSlice(); !slices.Equal(got, []int{99}) {
+		t.Errorf(errExpectedX, []int{99}, got)
+	}
+}
+
+func TestSegmentRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue")
+	q, err := durable.OpenQueue[int](path, durable.WithSegmentSize(64))
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	for i := 0; i < 50; i++ {
+		_ = q.Enqueue(i)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if entries, _ := os.ReadDir(path); len(entries) < 2 {
+		t.Fatalf("expected several segments, got %d", len(entries))
+	}
+
+	q, err = durable.OpenQueue[int](path)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	defer q.Close()
+	if q.Size() != 50 {
+		t.Errorf(errExpectedX, 50, q.Size())
+	}
+}
+
+func TestClosed(t *testing.T) {
+	q, err := durable.OpenQueue[int](filepath.Join(t.TempDir(), "queue"),
+		durable.WithSyncPolicy(durable.SyncPeriodic), durable.WithSyncInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	_ = q.Enqueue(1)
+	time.Sleep(5 * time.Millisecond)
+	if err := q.Close(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if err := q.Enqueue(2); err == nil || err.Error() != durable.ErrClosed {
+		t.Errorf(errExpectedX, durable.ErrClosed, err)
+	}
+	if err := q.Close(); err == nil || err.Error() != durable.ErrClosed {
+		t.Errorf(errExpectedX, durable.ErrClosed, err)
+	}
+}
+
+// segments returns the paths of the segments in dir, in order
+func segments(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func TestTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue")
+	q, err := durable.OpenQueue[int](path)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	for i := 1; i <= 3; i++ {
+		_ = q.Enqueue(i)
+	}
+	_ = q.Close()
+
+	// simulate a crash in the middle of the last write
+	last := segments(t, path)[0]
+	info, _ := os.Stat(last)
+	if err := os.Truncate(last, info.Size()-1); err != nil {
+		t.Fatal(err)
+	}
+	q, err = durable.OpenQueue[int](path)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if got := q.ToSlice(); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf(errExpectedX, []int{1, 2}, got)
+	}
+	// the log continues after the truncated record
+	_ = q.Enqueue(4)
+	_ = q.Close()
+	q, err = durable.OpenQueue[int](path)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	defer q.Close()
+	if got := q.ToSlice(); !slices.Equal(got, []int{1, 2, 4}) {
+		t.Errorf(errExpectedX, []int{1, 2, 4}, got)
+	}
+}
+
+func TestCorruptedLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue")
+	q, err := durable.OpenQueue[int](path, durable.WithSegmentSize(32))
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	for i := 0; i < 10; i++ {
+		_ = q.Enqueue(i)
+	}
+	_ = q.Close()
+
+	// flip a byte of the payload in a sealed segment
+	first := segments(t, path)[0]
+	data, _ := os.ReadFile(first)
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(first, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := durable.OpenQueue[int](path); err == nil || err.Error() != durable.ErrCorruptedLog {
+		t.Errorf(errExpectedX, durable.ErrCorruptedLog, err)
+	}
+}
+
+func TestConcurrentQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue")
+	q, err := durable.OpenQueue[int](path,
+		durable.WithSyncPolicy(durable.SyncNever), durable.WithCompactionThreshold(16))
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	godstest.Stress(8, 200, func(g, i int) {
+		if i%2 == 0 {
+			_ = q.Enqueue(g*1000 + i)
+		} else {
+			_, _ = q.Dequeue()
+		}
+	})
+	want := q.Size()
+	if err := q.Close(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+
+	q, err = durable.OpenQueue[int](path)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	defer q.Close()
+	if q.Size() != want {
+		t.Errorf(errExpectedX, want, q.Size())
+	}
+}
+
+func TestGroupCommit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stack")
+	s, err := durable.OpenStack[int](path, durable.WithSegmentSize(256))
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	godstest.Stress(8, 50, func(g, i int) {
+		if err := s.Push(g*1000 + i); err != nil {
+			t.Error(err)
+		}
+	})
+	if err := s.Close(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+
+	s, err = durable.OpenStack[int](path)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	defer s.Close()
+	if s.Size() != 400 {
+		t.Errorf(errExpectedX, 400, s.Size())
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package durable
+
+import (
+	"sync"
+
+	queue "github.com/pzaino/gods/pkg/queue"
+)
+
+// Queue is a concurrency-safe FIFO queue backed by an append-only log.
+type Queue[T comparable] struct {
+	mu  sync.Mutex
+	q   *queue.Queue[T]
+	log *wal[T]
+	bg  *background
+}
+
+// OpenQueue opens the queue stored in the log directory dir, creating it if
+// it doesn't exist, and recovers it after a crash (see the package
+// documentation).
+func OpenQueue[T comparable](dir string, opts ...Option) (*Queue[T], error) {
+	cfg := newConfig(opts)
+	dq := &Queue[T]{q: queue.New[T]()}
+	log, err := openWAL(dir, cfg, func(op byte, item *T) error {
+		switch op {
+		case opAdd:
+			dq.q.Enqueue(*item)
+		case opRemove:
+			_, err := dq.q.Dequeue()
+			return err
+		default:
+			dq.q.Clear()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	dq.log = log
+	dq.bg = newBackground(&dq.mu, cfg, log.sync)
+	return dq, nil
+}
+
+// Enqueue appends item to the log and then adds it to the queue. With
+// SyncAlways, it returns when the log is synced.
+func (dq *Queue[T]) Enqueue(item T) error {
+	dq.mu.Lock()
+	seq, err := dq.log.append(opAdd, &item)
+	if err == nil {
+		dq.q.Enqueue(item)
+	}
+	dq.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return dq.log.commit(seq)
+}
+
+// Dequeue removes and returns the first element of the queue, appending a
+// tombstone to the log.
+func (dq *Queue[T]) Dequeue() (T, error) {
+	var zero T
+	dq.mu.Lock()
+	item, err := dq.q.Peek()
+	if err != nil {
+		dq.mu.Unlock()
+		return zero, err
+	}
+	seq, err := dq.log.append(opRemove, nil)
+	if err != nil {
+		dq.mu.Unlock()
+		return zero, err
+	}
+	_, _ = dq.q.Dequeue()
+	if dq.log.needsCompaction() {
+		dq.bg.compact(dq.compact)
+	}
+	dq.mu.Unlock()
+	if err := dq.log.commit(seq); err != nil {
+		return zero, err
+	}
+	return item, nil
+}
+
+// Peek returns the first element of the queue without removing it.
+func (dq *Queue[T]) Peek() (T, error) {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	return dq.q.Peek()
+}
+
+// Size returns the number of elements in the queue.
+func (dq *Queue[T]) Size() uint64 {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	return dq.q.Size()
+}
+
+// IsEmpty returns true if the queue is empty.
+func (dq *Queue[T]) IsEmpty() bool {
+	return dq.Size() == 0
+}
+
+// ToSlice returns the elements of the queue, from the first one.
+func (dq *Queue[T]) ToSlice() []T {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	return dq.q.ToSlice()
+}
+
+// Compact rewrites the log without the tombstones. It is done automatically
+// in the background, but it can be forced, e.g. before a backup.
+func (dq *Queue[T]) Compact() error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	return dq.compact()
+}
+
+func (dq *Queue[T]) compact() error {
+	return dq.log.compact(dq.q.ToSlice())
+}
+
+// Sync flushes the log to stable storage, whatever the sync policy.
+func (dq *Queue[T]) Sync() error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	return dq.log.sync()
+}
+
+// Close syncs and closes the log. The queue can't be used any more.
+func (dq *Queue[T]) Close() error {
+	dq.bg.close()
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	return dq.log.close()
+}
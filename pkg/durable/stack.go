@@ -0,0 +1,159 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package durable
+
+import (
+	"slices"
+	"sync"
+
+	stack "github.com/pzaino/gods/pkg/stack"
+)
+
+// Stack is a concurrency-safe LIFO stack backed by an append-only log.
+type Stack[T comparable] struct {
+	mu  sync.Mutex
+	s   *stack.Stack[T]
+	log *wal[T]
+	bg  *background
+}
+
+// OpenStack opens the stack stored in the log directory dir, creating it if
+// it doesn't exist, and recovers it after a crash (see the package
+// documentation).
+func OpenStack[T comparable](dir string, opts ...Option) (*Stack[T], error) {
+	cfg := newConfig(opts)
+	ds := &Stack[T]{s: stack.New[T]()}
+	log, err := openWAL(dir, cfg, func(op byte, item *T) error {
+		switch op {
+		case opAdd:
+			ds.s.Push(*item)
+		case opRemove:
+			_, err := ds.s.Pop()
+			return err
+		default:
+			ds.s.Clear()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ds.log = log
+	ds.bg = newBackground(&ds.mu, cfg, log.sync)
+	return ds, nil
+}
+
+// Push appends item to the log and then pushes it on the stack. With
+// SyncAlways, it returns when the log is synced.
+func (ds *Stack[T]) Push(item T) error {
+	ds.mu.Lock()
+	seq, err := ds.log.append(opAdd, &item)
+	if err == nil {
+		ds.s.Push(item)
+	}
+	ds.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return ds.log.commit(seq)
+}
+
+// Pop removes and returns the top of the stack, appending a tombstone to the
+// log.
+func (ds *Stack[T]) Pop() (T, error) {
+	var zero T
+	ds.mu.Lock()
+	top, err := ds.s.Top()
+	if err != nil {
+		ds.mu.Unlock()
+		return zero, err
+	}
+	item := *top
+	seq, err := ds.log.append(opRemove, nil)
+	if err != nil {
+		ds.mu.Unlock()
+		return zero, err
+	}
+	_, _ = ds.s.Pop()
+	if ds.log.needsCompaction() {
+		ds.bg.compact(ds.compact)
+	}
+	ds.mu.Unlock()
+	if err := ds.log.commit(seq); err != nil {
+		return zero, err
+	}
+	return item, nil
+}
+
+// Top returns the top of the stack without removing it.
+func (ds *Stack[T]) Top() (T, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	top, err := ds.s.Top()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return *top, nil
+}
+
+// Size returns the number of elements in the stack.
+func (ds *Stack[T]) Size() uint64 {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.s.Size()
+}
+
+// IsEmpty returns true if the stack is empty.
+func (ds *Stack[T]) IsEmpty() bool {
+	return ds.Size() == 0
+}
+
+// ToSlice returns the elements of the stack, from the top.
+func (ds *Stack[T]) ToSlice() []T {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.s.ToSlice()
+}
+
+// Compact rewrites the log without the tombstones. It is done automatically
+// in the background, but it can be forced, e.g. before a backup.
+func (ds *Stack[T]) Compact() error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.compact()
+}
+
+func (ds *Stack[T]) compact() error {
+	// the log is replayed from the bottom of the stack
+	items := ds.s.ToSlice()
+	slices.Reverse(items)
+	return ds.log.compact(items)
+}
+
+// Sync flushes the log to stable storage, whatever the sync policy.
+func (ds *Stack[T]) Sync() error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.log.sync()
+}
+
+// Close syncs and closes the log. The stack can't be used any more.
+func (ds *Stack[T]) Close() error {
+	ds.bg.close()
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.log.close()
+}
@@ -0,0 +1,522 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package durable
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// log record types
+const (
+	opAdd    byte = 1
+	opRemove byte = 2
+	// opReset starts a compacted segment: the records before it are discarded
+	opReset byte = 3
+)
+
+// A record is its type, the length of the payload (uint32 LE), the CRC-32C
+// of the type, length and payload (uint32 LE), then the payload.
+const recordHeaderLen = 9
+
+// maxRecordLen limits the size of a record read from the log, so a corrupted
+// length can't make the replay allocate a huge buffer
+const maxRecordLen = 1 << 30
+
+const (
+	segmentExt = ".log"
+	tmpExt     = ".tmp"
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// wal is the append-only log of a durable container. Its methods must be
+// called with the lock of the container held, except commit.
+type wal[T any] struct {
+	dir        string
+	cfg        *config
+	segments   []uint64 // ids of the segments, the last one is active
+	f          *os.File // active segment
+	w          *bufio.Writer
+	size       int64 // of the active segment
+	live       int   // elements in the container
+	tombstones int
+	closed     bool
+	buf        bytes.Buffer
+	gc         groupCommit
+}
+
+func segmentName(id uint64) string {
+	return fmt.Sprintf("%016x%s", id, segmentExt)
+}
+
+// openWAL opens (or creates) the log in dir, recovers it and replays it,
+// calling apply for every record (item is nil for the tombstones and the
+// resets)
+func openWAL[T any](dir string, cfg *config, apply func(op byte, item *T) error) (*wal[T], error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	l := &wal[T]{dir: dir, cfg: cfg}
+	l.gc.cond = sync.NewCond(&l.gc.mu)
+	if err := l.recover(); err != nil {
+		return nil, err
+	}
+	for i, id := range l.segments {
+		last := i == len(l.segments)-1
+		if err := l.replay(id, last, apply); err != nil {
+			return nil, err
+		}
+	}
+	if len(l.segments) == 0 {
+		if err := l.rotate(); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+	f, err := os.OpenFile(filepath.Join(dir, segmentName(l.segments[len(l.segments)-1])), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	l.f, l.w, l.gc.f = f, bufio.NewWriter(f), f
+	return l, nil
+}
+
+// recover lists the segments, removing the temporary files and the
+// segments preceding the last compacted one
+func (l *wal[T]) recover() error {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasSuffix(name, segmentExt+tmpExt) {
+			if err := os.Remove(filepath.Join(l.dir, name)); err != nil {
+				return err
+			}
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 16, 64)
+		if err != nil || !strings.HasSuffix(name, segmentExt) {
+			continue // not a segment
+		}
+		l.segments = append(l.segments, id)
+	}
+	slices.Sort(l.segments)
+	for i := len(l.segments) - 1; i > 0; i-- {
+		compacted, err := l.isCompacted(l.segments[i])
+		if err != nil {
+			return err
+		}
+		if compacted {
+			if err := l.removeSegments(l.segments[i]); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// isCompacted reports whether a segment starts with a reset record
+func (l *wal[T]) isCompacted(id uint64) (bool, error) {
+	f, err := os.Open(filepath.Join(l.dir, segmentName(id)))
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	var op [1]byte
+	if _, err := io.ReadFull(f, op[:]); err != nil {
+		return false, nil
+	}
+	return op[0] == opReset, nil
+}
+
+// removeSegments removes the segments preceding id
+func (l *wal[T]) removeSegments(id uint64) error {
+	i := 0
+	for ; i < len(l.segments) && l.segments[i] < id; i++ {
+		if err := os.Remove(filepath.Join(l.dir, segmentName(l.segments[i]))); err != nil {
+			l.segments = l.segments[i:]
+			return err
+		}
+	}
+	l.segments = l.segments[i:]
+	syncDir(l.dir)
+	return nil
+}
+
+// replay replays a segment. A bad record in the last segment is a torn tail
+// and it's truncated, in any other segment it's corruption.
+func (l *wal[T]) replay(id uint64, last bool, apply func(op byte, item *T) error) error {
+	path := filepath.Join(l.dir, segmentName(id))
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var offset int64
+	for {
+		op, payload, n, err := readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if !last {
+				return err
+			}
+			l.size = offset
+			return os.Truncate(path, offset)
+		}
+		offset += n
+		var item *T
+		switch op {
+		case opAdd:
+			item = new(T)
+			if err := l.cfg.codec.Decode(bytes.NewReader(payload), item); err != nil {
+				return err
+			}
+			l.live++
+		case opRemove:
+			l.live--
+			l.tombstones++
+		case opReset:
+			l.live, l.tombstones = 0, 0
+		}
+		if err := apply(op, item); err != nil {
+			return err
+		}
+	}
+	if last {
+		l.size = offset
+	}
+	return nil
+}
+
+// readRecord reads and verifies a record, returning its type, payload and
+// length. It returns io.EOF at the clean end of a segment.
+func readRecord(r io.Reader) (byte, []byte, int64, error) {
+	var header [recordHeaderLen]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return 0, nil, 0, err
+		}
+		return 0, nil, 0, errors.New(ErrCorruptedLog)
+	}
+	op, n := header[0], binary.LittleEndian.Uint32(header[1:5])
+	if n > maxRecordLen || op < opAdd || op > opReset {
+		return 0, nil, 0, errors.New(ErrCorruptedLog)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, 0, errors.New(ErrCorruptedLog)
+	}
+	crc := crc32.Update(crc32.Checksum(header[:5], crcTable), crcTable, payload)
+	if crc != binary.LittleEndian.Uint32(header[5:]) {
+		return 0, nil, 0, errors.New(ErrCorruptedLog)
+	}
+	return op, payload, recordHeaderLen + int64(n), nil
+}
+
+// writeRecord writes a record to the buffered writer
+func (l *wal[T]) writeRecord(op byte, item *T) error {
+	l.buf.Reset()
+	if item != nil {
+		if err := l.cfg.codec.Encode(&l.buf, *item); err != nil {
+			return err
+		}
+	}
+	if l.buf.Len() > maxRecordLen {
+		return errors.New(ErrRecordTooLong)
+	}
+	var header [recordHeaderLen]byte
+	header[0] = op
+	binary.LittleEndian.PutUint32(header[1:5], uint32(l.buf.Len()))
+	crc := crc32.Update(crc32.Checksum(header[:5], crcTable), crcTable, l.buf.Bytes())
+	binary.LittleEndian.PutUint32(header[5:], crc)
+	if _, err := l.w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := l.w.Write(l.buf.Bytes()); err != nil {
+		return err
+	}
+	l.size += recordHeaderLen + int64(l.buf.Len())
+	return nil
+}
+
+// append writes a record to the active segment and returns its sequence
+// number, to wait for its sync with commit
+func (l *wal[T]) append(op byte, item *T) (uint64, error) {
+	if l.closed {
+		return 0, errors.New(ErrClosed)
+	}
+	if err := l.gc.failed(); err != nil {
+		return 0, err
+	}
+	if err := l.writeRecord(op, item); err != nil {
+		return 0, err
+	}
+	// always flush, so the record survives a crash of the process
+	if err := l.w.Flush(); err != nil {
+		return 0, err
+	}
+	seq := l.gc.wrote()
+	if op == opAdd {
+		l.live++
+	} else {
+		l.live--
+		l.tombstones++
+	}
+	if l.size >= l.cfg.segmentSize {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	return seq, nil
+}
+
+// commit waits for the record seq to be synced, according to the sync
+// policy. It must be called without the lock of the container.
+func (l *wal[T]) commit(seq uint64) error {
+	if l.cfg.policy != SyncAlways {
+		return nil
+	}
+	return l.gc.wait(seq)
+}
+
+// rotate seals the active segment (if any) and starts a new one
+func (l *wal[T]) rotate() error {
+	var id uint64 = 1
+	if len(l.segments) > 0 {
+		id = l.segments[len(l.segments)-1] + 1
+	}
+	old := l.f
+	err := l.gc.swap(func() (*os.File, error) {
+		return os.OpenFile(filepath.Join(l.dir, segmentName(id)), os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o600)
+	})
+	if err != nil {
+		return err
+	}
+	syncDir(l.dir)
+	if old != nil {
+		_ = old.Close()
+	}
+	l.segments = append(l.segments, id)
+	l.f, l.w, l.size = l.gc.f, bufio.NewWriter(l.gc.f), 0
+	return nil
+}
+
+// needsCompaction reports whether the tombstones should be compacted
+func (l *wal[T]) needsCompaction() bool {
+	return !l.closed && l.tombstones >= l.cfg.compactAt && l.tombstones >= l.live
+}
+
+// compact writes a new segment with a reset record and the given live items
+// (in replay order), then removes all the previous segments. The segment is
+// written to a temporary file and renamed, so it appears complete or not at
+// all.
+func (l *wal[T]) compact(items []T) (err error) {
+	if l.closed {
+		return errors.New(ErrClosed)
+	}
+	if err := l.gc.failed(); err != nil {
+		return err
+	}
+	id := l.segments[len(l.segments)-1] + 1
+	path := filepath.Join(l.dir, segmentName(id))
+	tmp, err := os.OpenFile(path+tmpExt, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(path + tmpExt)
+		}
+	}()
+	oldW, oldSize := l.w, l.size
+	l.w, l.size = bufio.NewWriter(tmp), 0
+	defer func() {
+		if err != nil {
+			l.w, l.size = oldW, oldSize
+		}
+	}()
+	if err = l.writeRecord(opReset, nil); err != nil {
+		return err
+	}
+	for i := range items {
+		if err = l.writeRecord(opAdd, &items[i]); err != nil {
+			return err
+		}
+	}
+	if err = l.w.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = os.Rename(path+tmpExt, path); err != nil {
+		return err
+	}
+	syncDir(l.dir)
+	oldF := l.f
+	if err = l.gc.swap(func() (*os.File, error) { return tmp, nil }); err != nil {
+		return err
+	}
+	_ = oldF.Close()
+	l.f = tmp
+	l.segments = append(l.segments, id)
+	l.live, l.tombstones = len(items), 0
+	// a failure here leaves old segments behind, removed by the next open
+	_ = l.removeSegments(id)
+	return nil
+}
+
+// sync flushes and syncs the active segment
+func (l *wal[T]) sync() error {
+	if l.closed {
+		return errors.New(ErrClosed)
+	}
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	return l.gc.wait(l.gc.last())
+}
+
+// close syncs and closes the log
+func (l *wal[T]) close() error {
+	if l.closed {
+		return errors.New(ErrClosed)
+	}
+	l.closed = true
+	err := l.gc.swap(func() (*os.File, error) { return nil, nil })
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// syncDir syncs a directory, so a rename in it is durable. Errors are ignored:
+// not all the platforms support it.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
+// groupCommit batches the syncs of concurrent writers: a writer waits for
+// its record to be synced, and the first one waiting syncs all the records
+// written so far on behalf of the others.
+type groupCommit struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	f       *os.File
+	written uint64 // sequence number of the last record written
+	synced  uint64 // sequence number of the last record synced
+	syncing bool
+	err     error // sticky: after a failed sync the file is in an unknown state
+}
+
+// wrote assigns a sequence number to a record written to the file
+func (g *groupCommit) wrote() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.written++
+	return g.written
+}
+
+// last returns the sequence number of the last record written
+func (g *groupCommit) last() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.written
+}
+
+// failed returns the error of a failed sync
+func (g *groupCommit) failed() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// wait returns when the record seq is synced
+func (g *groupCommit) wait(seq uint64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.synced < seq && g.err == nil {
+		if g.syncing {
+			g.cond.Wait()
+			continue
+		}
+		g.syncing = true
+		f, target := g.f, g.written
+		g.mu.Unlock()
+		err := f.Sync()
+		g.mu.Lock()
+		g.syncing = false
+		if err != nil {
+			g.err = err
+		} else if target > g.synced {
+			g.synced = target
+		}
+		g.cond.Broadcast()
+	}
+	return g.err
+}
+
+// swap syncs the file, then replaces it with the one returned by open. It
+// must be called with the lock of the container held, so nothing is being
+// written.
+func (g *groupCommit) swap(open func() (*os.File, error)) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.syncing {
+		g.cond.Wait()
+	}
+	if g.err != nil {
+		return g.err
+	}
+	if g.f != nil {
+		if err := g.f.Sync(); err != nil {
+			g.err = err
+			g.cond.Broadcast()
+			return err
+		}
+		g.synced = g.written
+		g.cond.Broadcast()
+	}
+	f, err := open()
+	if err != nil {
+		return err
+	}
+	g.f = f
+	return nil
+}
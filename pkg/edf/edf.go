@@ -0,0 +1,248 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package edf provides an earliest-deadline-first queue, the scheduling
+// primitive of the tasks carrying an absolute deadline.
+//
+// PopDue returns the tasks whose deadline has passed, earliest deadline
+// first, and Peek or NextDeadline tell when the next one will be due (to arm
+// a timer). The tasks are indexed by a Handle, so they can be cancelled or
+// rescheduled in O(log n). With OnOverdue, the tasks found too late (more
+// than a tolerance past their deadline) are routed to a callback instead of
+// being returned.
+package edf
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	ErrQueueIsEmpty  = "queue is empty"
+	ErrUnknownHandle = "unknown handle"
+)
+
+// Handle identifies a task in a Queue.
+type Handle uint64
+
+// Task is an item with its deadline.
+type Task[T any] struct {
+	Handle   Handle
+	Item     T
+	Deadline time.Time
+}
+
+// entry is a task in the heap
+type entry[T any] struct {
+	Task[T]
+	index int
+}
+
+// Queue is an earliest-deadline-first queue. The tasks with the same
+// deadline are returned in the order they were pushed. It is not
+// concurrency-safe.
+type Queue[T any] struct {
+	heap      []*entry[T]
+	handles   map[Handle]*entry[T]
+	next      Handle
+	tolerance time.Duration
+	overdue   func(Task[T])
+}
+
+// New creates a new empty Queue.
+func New[T any]() *Queue[T] {
+	return &Queue[T]{handles: make(map[Handle]*entry[T])}
+}
+
+// OnOverdue makes PopDue and DrainDue pass to f, instead of returning them,
+// the tasks that are more than tolerance past their deadline. A nil f
+// disables the routing.
+func (q *Queue[T]) OnOverdue(tolerance time.Duration, f func(Task[T])) {
+	q.tolerance, q.overdue = max(tolerance, 0), f
+}
+
+// less reports whether the task at i is due before the one at j
+func (q *Queue[T]) less(i, j int) bool {
+	a, b := q.heap[i], q.heap[j]
+	if !a.Deadline.Equal(b.Deadline) {
+		return a.Deadline.Before(b.Deadline)
+	}
+	return a.Handle < b.Handle
+}
+
+func (q *Queue[T]) swap(i, j int) {
+	q.heap[i], q.heap[j] = q.heap[j], q.heap[i]
+	q.heap[i].index, q.heap[j].index = i, j
+}
+
+func (q *Queue[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !q.less(i, parent) {
+			return
+		}
+		q.swap(i, parent)
+		i = parent
+	}
+}
+
+func (q *Queue[T]) down(i int) {
+	for {
+		first, left := i, 2*i+1
+		if left < len(q.heap) && q.less(left, first) {
+			first = left
+		}
+		if right := left + 1; right < len(q.heap) && q.less(right, first) {
+			first = right
+		}
+		if first == i {
+			return
+		}
+		q.swap(i, first)
+		i = first
+	}
+}
+
+// fix restores the heap after the deadline of the task at i changed
+func (q *Queue[T]) fix(i int) {
+	q.up(i)
+	q.down(i)
+}
+
+// remove removes the task at i from the heap and the index
+func (q *Queue[T]) remove(i int) Task[T] {
+	e := q.heap[i]
+	last := len(q.heap) - 1
+	if i != last {
+		q.swap(i, last)
+	}
+	q.heap[last] = nil
+	q.heap = q.heap[:last]
+	if i != last {
+		q.fix(i)
+	}
+	delete(q.handles, e.Handle)
+	return e.Task
+}
+
+// Push adds an item due at deadline and returns its handle.
+func (q *Queue[T]) Push(item T, deadline time.Time) Handle {
+	q.next++
+	e := &entry[T]{Task: Task[T]{Handle: q.next, Item: item, Deadline: deadline}, index: len(q.heap)}
+	q.heap = append(q.heap, e)
+	q.handles[e.Handle] = e
+	q.up(e.index)
+	return e.Handle
+}
+
+// Reschedule changes the deadline of a task. It returns an ErrUnknownHandle
+// error if the task isn't in the queue.
+func (q *Queue[T]) Reschedule(h Handle, deadline time.Time) error {
+	e, ok := q.handles[h]
+	if !ok {
+		return errors.New(ErrUnknownHandle)
+	}
+	e.Deadline = deadline
+	q.fix(e.index)
+	return nil
+}
+
+// Cancel removes a task and returns it. It returns false if the task isn't
+// in the queue.
+func (q *Queue[T]) Cancel(h Handle) (Task[T], bool) {
+	e, ok := q.handles[h]
+	if !ok {
+		return Task[T]{}, false
+	}
+	return q.remove(e.index), true
+}
+
+// Get returns a task by handle.
+func (q *Queue[T]) Get(h Handle) (Task[T], bool) {
+	e, ok := q.handles[h]
+	if !ok {
+		return Task[T]{}, false
+	}
+	return e.Task, true
+}
+
+// Peek returns the task with the earliest deadline, due or not.
+func (q *Queue[T]) Peek() (Task[T], error) {
+	if len(q.heap) == 0 {
+		return Task[T]{}, errors.New(ErrQueueIsEmpty)
+	}
+	return q.heap[0].Task, nil
+}
+
+// Pop removes and returns the task with the earliest deadline, due or not
+// (the overdue callback isn't involved).
+func (q *Queue[T]) Pop() (Task[T], error) {
+	if len(q.heap) == 0 {
+		return Task[T]{}, errors.New(ErrQueueIsEmpty)
+	}
+	return q.remove(0), nil
+}
+
+// NextDeadline returns the earliest deadline, if the queue isn't empty.
+func (q *Queue[T]) NextDeadline() (time.Time, bool) {
+	if len(q.heap) == 0 {
+		return time.Time{}, false
+	}
+	return q.heap[0].Deadline, true
+}
+
+// PopDue removes and returns the task with the earliest deadline if it's due
+// at now (its deadline isn't after now). The overdue tasks met on the way are
+// passed to the OnOverdue callback.
+func (q *Queue[T]) PopDue(now time.Time) (Task[T], bool) {
+	for len(q.heap) > 0 && !q.heap[0].Deadline.After(now) {
+		task := q.remove(0)
+		if q.overdue != nil && now.Sub(task.Deadline) > q.tolerance {
+			q.overdue(task)
+			continue
+		}
+		return task, true
+	}
+	return Task[T]{}, false
+}
+
+// DrainDue removes and returns all the tasks due at now, earliest deadline
+// first. The overdue ones are passed to the OnOverdue callback.
+func (q *Queue[T]) DrainDue(now time.Time) []Task[T] {
+	var tasks []Task[T]
+	for {
+		task, ok := q.PopDue(now)
+		if !ok {
+			return tasks
+		}
+		tasks = append(tasks, task)
+	}
+}
+
+// Size returns the number of tasks in the queue.
+func (q *Queue[T]) Size() uint64 {
+	return uint64(len(q.heap))
+}
+
+// IsEmpty returns true if the queue is empty.
+func (q *Queue[T]) IsEmpty() bool {
+	return len(q.heap) == 0
+}
+
+// Clear removes all the tasks.
+func (q *Queue[T]) Clear() {
+	clear(q.heap)
+	q.heap = q.heap[:0]
+	clear(q.handles)
+}
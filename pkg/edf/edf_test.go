@@ -0,0 +1,154 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edf_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	edf "github.com/pzaino/gods/pkg/edf"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(s int) time.Time {
+	return t0.Add(time.Duration(s) * time.Second)
+}
+
+func items(tasks []edf.Task[string]) []string {
+	var out []string
+	for _, task := range tasks {
+		out = append(out, task.Item)
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPopDue(t *testing.T) {
+	q := edf.New[string]()
+	if _, err := q.Peek(); err == nil || err.Error() != edf.ErrQueueIsEmpty {
+		t.Errorf(errExpectedX, edf.ErrQueueIsEmpty, err)
+	}
+	q.Push("c", at(30))
+	q.Push("a", at(10))
+	q.Push("b", at(20))
+	q.Push("a2", at(10)) // same deadline: after "a"
+
+	if _, ok := q.PopDue(at(5)); ok {
+		t.Errorf(errExpectedX, false, ok)
+	}
+	if d, _ := q.NextDeadline(); !d.Equal(at(10)) {
+		t.Errorf(errExpectedX, at(10), d)
+	}
+	if got := items(q.DrainDue(at(20))); !equal(got, []string{"a", "a2", "b"}) {
+		t.Errorf(errExpectedX, []string{"a", "a2", "b"}, got)
+	}
+	if task, err := q.Pop(); err != nil || task.Item != "c" {
+		t.Errorf(errExpectedX, "c", task.Item)
+	}
+	if !q.IsEmpty() {
+		t.Errorf(errExpectedX, 0, q.Size())
+	}
+}
+
+func TestCancelReschedule(t *testing.T) {
+	q := edf.New[string]()
+	a := q.Push("a", at(10))
+	b := q.Push("b", at(20))
+	c := q.Push("c", at(30))
+
+	if err := q.Reschedule(c, at(5)); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if task, ok := q.Cancel(a); !ok || task.Item != "a" {
+		t.Errorf(errExpectedX, "a", task.Item)
+	}
+	if _, ok := q.Cancel(a); ok {
+		t.Errorf(errExpectedX, false, ok)
+	}
+	if err := q.Reschedule(a, at(1)); err == nil || err.Error() != edf.ErrUnknownHandle {
+		t.Errorf(errExpectedX, edf.ErrUnknownHandle, err)
+	}
+	if task, ok := q.Get(b); !ok || !task.Deadline.Equal(at(20)) {
+		t.Errorf(errExpectedX, at(20), task.Deadline)
+	}
+	if got := items(q.DrainDue(at(100))); !equal(got, []string{"c", "b"}) {
+		t.Errorf(errExpectedX, []string{"c", "b"}, got)
+	}
+}
+
+func TestOverdue(t *testing.T) {
+	q := edf.New[string]()
+	var late []string
+	q.OnOverdue(5*time.Second, func(task edf.Task[string]) {
+		late = append(late, task.Item)
+	})
+	q.Push("old", at(0))
+	q.Push("recent", at(8))
+	q.Push("future", at(20))
+
+	if got := items(q.DrainDue(at(10))); !equal(got, []string{"recent"}) {
+		t.Errorf(errExpectedX, []string{"recent"}, got)
+	}
+	if !equal(late, []string{"old"}) {
+		t.Errorf(errExpectedX, []string{"old"}, late)
+	}
+	if n := q.Size(); n != 1 {
+		t.Errorf(errExpectedX, 1, n)
+	}
+}
+
+func TestRandomOrder(t *testing.T) {
+	q := edf.New[string]()
+	handles := make([]edf.Handle, 0, 1000)
+	for range 1000 {
+		handles = append(handles, q.Push("", at(rand.Intn(500))))
+	}
+	for _, h := range handles[:300] {
+		if rand.Intn(2) == 0 {
+			q.Cancel(h)
+		} else {
+			_ = q.Reschedule(h, at(rand.Intn(500)))
+		}
+	}
+
+	size := q.Size()
+	tasks := q.DrainDue(at(500))
+	if uint64(len(tasks)) != size || !q.IsEmpty() {
+		t.Fatalf(errExpectedX, size, len(tasks))
+	}
+	for i := 1; i < len(tasks); i++ {
+		if tasks[i].Deadline.Before(tasks[i-1].Deadline) {
+			t.Fatalf(errExpectedX, "ascending deadlines", tasks[i].Deadline)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fanout provides a broadcast queue: one producer publishes items in
+// a shared ring and every subscriber reads all of them through its own
+// cursor, without copying the items per subscriber. When the slowest
+// subscriber is a full ring behind, the Policy decides what happens: the
+// producer waits (Block), the subscriber loses the oldest items (DropOldest)
+// or the subscriber is disconnected (Disconnect).
+package fanout
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const (
+	ErrInvalidCapacity = "capacity must be greater than zero"
+	ErrClosed          = "fanout is closed"
+	ErrDisconnected    = "subscriber is disconnected"
+)
+
+// Policy is what a Fanout does when publishing an item would overwrite one
+// that a subscriber hasn't read yet.
+type Policy int
+
+const (
+	// Block makes Publish wait until every subscriber has room
+	Block Policy = iota
+	// DropOldest overwrites the item: the slow subscribers skip it and it's
+	// counted in their Dropped
+	DropOldest
+	// Disconnect disconnects the slow subscribers: they read the items they
+	// haven't lost yet, then get ErrDisconnected
+	Disconnect
+)
+
+// Fanout is a broadcast queue of items of type T. It's safe for concurrent
+// use, although items are published in one sequence (concurrent Publish calls
+// are serialized).
+type Fanout[T any] struct {
+	mu      sync.Mutex
+	ring    []T
+	head    uint64 // sequence number of the next item
+	policy  Policy
+	subs    map[*Subscriber[T]]struct{}
+	changed chan struct{} // closed (and reset) on every change
+	closed  bool
+}
+
+// Subscriber is a cursor over the items of a Fanout.
+type Subscriber[T any] struct {
+	f            *Fanout[T]
+	next         uint64 // sequence number of the next item to read
+	end          uint64 // sequence number after the last readable item once disconnected
+	dropped      uint64
+	disconnected bool
+}
+
+// New creates a Fanout keeping the last capacity items for its subscribers.
+func New[T any](capacity int, policy Policy) (*Fanout[T], error) {
+	if capacity <= 0 {
+		return nil, errors.New(ErrInvalidCapacity)
+	}
+	return &Fanout[T]{
+		ring:   make([]T, capacity),
+		policy: policy,
+		subs:   make(map[*Subscriber[T]]struct{}),
+	}, nil
+}
+
+// notify wakes up the goroutines waiting for a change. It runs under the lock.
+func (f *Fanout[T]) notify() {
+	if f.changed != nil {
+		close(f.changed)
+		f.changed = nil
+	}
+}
+
+// wait releases the lock until the next change or the end of ctx, and
+// reacquires it.
+func (f *Fanout[T]) wait(ctx context.Context) error {
+	if f.changed == nil {
+		f.changed = make(chan struct{})
+	}
+	changed := f.changed
+	f.mu.Unlock()
+	defer f.mu.Lock()
+
+	select {
+	case <-changed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// full reports whether s is a full ring behind. It runs under the lock.
+func (f *Fanout[T]) full(s *Subscriber[T]) bool {
+	return f.head-s.next >= uint64(len(f.ring))
+}
+
+// Publish adds an item for all the current subscribers. With the Block policy
+// it waits until the slowest subscriber has room or ctx is done.
+func (f *Fanout[T]) Publish(ctx context.Context, item T) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for {
+		if f.closed {
+			return errors.New(ErrClosed)
+		}
+		if f.policy != Block || !f.anyFull() {
+			break
+		}
+		if err := f.wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	if f.policy == Disconnect {
+		for s := range f.subs {
+			if f.full(s) {
+				s.disconnected = true
+				s.end = f.head
+				delete(f.subs, s)
+			}
+		}
+	}
+
+	f.ring[f.head%uint64(len(f.ring))] = item
+	f.head++
+	f.notify()
+	return nil
+}
+
+// anyFull reports whether any subscriber is a full ring behind. It runs under
+// the lock.
+func (f *Fanout[T]) anyFull() bool {
+	for s := range f.subs {
+		if f.full(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribe registers a new subscriber, receiving the items published from
+// now on.
+func (f *Fanout[T]) Subscribe() *Subscriber[T] {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := &Subscriber[T]{f: f, next: f.head}
+	if !f.closed {
+		f.subs[s] = struct{}{}
+	}
+	return s
+}
+
+// Subscribers returns the number of registered subscribers.
+func (f *Fanout[T]) Subscribers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.subs)
+}
+
+// Published returns the number of items published so far.
+func (f *Fanout[T]) Published() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.head
+}
+
+// MaxLag returns the lag of the slowest subscriber.
+func (f *Fanout[T]) MaxLag() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var lag uint64
+	for s := range f.subs {
+		lag = max(lag, s.lag())
+	}
+	return lag
+}
+
+// Close stops the publication: the subscribers read the items left, then get
+// ErrClosed.
+func (f *Fanout[T]) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	f.notify()
+}
+
+// lag returns the number of items s can still read. It runs under the lock.
+func (s *Subscriber[T]) lag() uint64 {
+	end := s.f.head
+	if s.disconnected {
+		end = s.end
+	}
+	if end <= s.next {
+		return 0
+	}
+	return min(end-s.next, uint64(len(s.f.ring)))
+}
+
+// skip moves the cursor past the items overwritten since the last read. It
+// runs under the lock.
+func (s *Subscriber[T]) skip() {
+	if oldest := s.f.head - min(s.f.head, uint64(len(s.f.ring))); s.next < oldest {
+		s.dropped += oldest - s.next
+		s.next = oldest
+	}
+}
+
+// take returns the next item if there's one. It runs under the lock.
+func (s *Subscriber[T]) take() (item T, ok bool, err error) {
+	f := s.f
+	s.skip()
+	end := f.head
+	if s.disconnected {
+		end = s.end
+	}
+	if s.next < end {
+		item = f.ring[s.next%uint64(len(f.ring))]
+		s.next++
+		f.notify()
+		return item, true, nil
+	}
+	switch {
+	case s.disconnected:
+		return item, false, errors.New(ErrDisconnected)
+	case f.closed:
+		return item, false, errors.New(ErrClosed)
+	}
+	return item, false, nil
+}
+
+// Next returns the next item, waiting for it to be published or ctx to be
+// done. It returns an ErrClosed error once the Fanout is closed and the items
+// are all read, and an ErrDisconnected error once the subscriber has been
+// disconnected (Disconnect policy) or has unsubscribed.
+func (s *Subscriber[T]) Next(ctx context.Context) (T, error) {
+	f := s.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for {
+		item, ok, err := s.take()
+		if ok || err != nil {
+			return item, err
+		}
+		if err := f.wait(ctx); err != nil {
+			return item, err
+		}
+	}
+}
+
+// TryNext returns the next item if it's already published.
+func (s *Subscriber[T]) TryNext() (T, bool) {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	item, ok, _ := s.take()
+	return item, ok
+}
+
+// Lag returns the number of published items the subscriber hasn't read yet.
+func (s *Subscriber[T]) Lag() uint64 {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	return s.lag()
+}
+
+// Dropped returns the number of items the subscriber lost because it was too
+// slow (DropOldest policy).
+func (s *Subscriber[T]) Dropped() uint64 {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	s.skip()
+	return s.dropped
+}
+
+// Disconnected reports whether the subscriber has been disconnected.
+func (s *Subscriber[T]) Disconnected() bool {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+
+	return s.disconnected
+}
+
+// Unsubscribe removes the subscriber: it no longer holds back the producer
+// and Next returns an ErrDisconnected error.
+func (s *Subscriber[T]) Unsubscribe() {
+	f := s.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !s.disconnected {
+		s.disconnected = true
+		s.end = s.next
+		delete(f.subs, s)
+		f.notify()
+	}
+}
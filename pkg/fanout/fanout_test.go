@@ -0,0 +1,225 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fanout_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	fanout "github.com/pzaino/gods/pkg/fanout"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+// publish publishes the items, failing the test on error
+func publish(t *testing.T, f *fanout.Fanout[int], items ...int) {
+	t.Helper()
+	for _, item := range items {
+		if err := f.Publish(context.Background(), item); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+	}
+}
+
+// drain returns the items a subscriber can read without waiting
+func drain(s *fanout.Subscriber[int]) []int {
+	var items []int
+	for {
+		item, ok := s.TryNext()
+		if !ok {
+			return items
+		}
+		items = append(items, item)
+	}
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNew(t *testing.T) {
+	if _, err := fanout.New[int](0, fanout.Block); err == nil || err.Error() != fanout.ErrInvalidCapacity {
+		t.Errorf(errExpectedX, fanout.ErrInvalidCapacity, err)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	f, _ := fanout.New[int](8, fanout.Block)
+	publish(t, f, 1) // nobody receives it
+	a, b := f.Subscribe(), f.Subscribe()
+	publish(t, f, 2, 3, 4)
+
+	if got := drain(a); !equal(got, []int{2, 3, 4}) {
+		t.Errorf(errExpectedX, []int{2, 3, 4}, got)
+	}
+	if lag := b.Lag(); lag != 3 {
+		t.Errorf(errExpectedX, 3, lag)
+	}
+	if lag := f.MaxLag(); lag != 3 {
+		t.Errorf(errExpectedX, 3, lag)
+	}
+	if got := drain(b); !equal(got, []int{2, 3, 4}) {
+		t.Errorf(errExpectedX, []int{2, 3, 4}, got)
+	}
+	if n := f.Published(); n != 4 {
+		t.Errorf(errExpectedX, 4, n)
+	}
+
+	b.Unsubscribe()
+	if n := f.Subscribers(); n != 1 {
+		t.Errorf(errExpectedX, 1, n)
+	}
+	if _, err := b.Next(context.Background()); err == nil || err.Error() != fanout.ErrDisconnected {
+		t.Errorf(errExpectedX, fanout.ErrDisconnected, err)
+	}
+
+	publish(t, f, 5)
+	f.Close()
+	if item, err := a.Next(context.Background()); err != nil || item != 5 {
+		t.Errorf(errExpectedX, 5, item)
+	}
+	if _, err := a.Next(context.Background()); err == nil || err.Error() != fanout.ErrClosed {
+		t.Errorf(errExpectedX, fanout.ErrClosed, err)
+	}
+	if err := f.Publish(context.Background(), 6); err == nil || err.Error() != fanout.ErrClosed {
+		t.Errorf(errExpectedX, fanout.ErrClosed, err)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	f, _ := fanout.New[int](2, fanout.Block)
+	s := f.Subscribe()
+	publish(t, f, 1, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := f.Publish(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf(errExpectedX, context.DeadlineExceeded, err)
+	}
+
+	done := make(chan error)
+	go func() { done <- f.Publish(context.Background(), 3) }()
+	if item, _ := s.Next(context.Background()); item != 1 {
+		t.Errorf(errExpectedX, 1, item)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if got := drain(s); !equal(got, []int{2, 3}) {
+		t.Errorf(errExpectedX, []int{2, 3}, got)
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	f, _ := fanout.New[int](3, fanout.DropOldest)
+	slow, fast := f.Subscribe(), f.Subscribe()
+	for i := 1; i <= 5; i++ {
+		publish(t, f, i)
+		if item, _ := fast.TryNext(); item != i {
+			t.Errorf(errExpectedX, i, item)
+		}
+	}
+
+	if lag := slow.Lag(); lag != 3 {
+		t.Errorf(errExpectedX, 3, lag)
+	}
+	if n := slow.Dropped(); n != 2 {
+		t.Errorf(errExpectedX, 2, n)
+	}
+	if got := drain(slow); !equal(got, []int{3, 4, 5}) {
+		t.Errorf(errExpectedX, []int{3, 4, 5}, got)
+	}
+	if n := fast.Dropped(); n != 0 {
+		t.Errorf(errExpectedX, 0, n)
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	f, _ := fanout.New[int](2, fanout.Disconnect)
+	slow, fast := f.Subscribe(), f.Subscribe()
+	publish(t, f, 1, 2)
+	drain(fast)
+	publish(t, f, 3) // slow is a full ring behind
+
+	if !slow.Disconnected() || fast.Disconnected() {
+		t.Fatalf(errExpectedX, true, slow.Disconnected())
+	}
+	if n := f.Subscribers(); n != 1 {
+		t.Errorf(errExpectedX, 1, n)
+	}
+	// slow reads what it hasn't lost yet
+	if item, err := slow.Next(context.Background()); err != nil || item != 2 {
+		t.Errorf(errExpectedX, 2, item)
+	}
+	if _, err := slow.Next(context.Background()); err == nil || err.Error() != fanout.ErrDisconnected {
+		t.Errorf(errExpectedX, fanout.ErrDisconnected, err)
+	}
+	if n := slow.Dropped(); n != 1 {
+		t.Errorf(errExpectedX, 1, n)
+	}
+	if got := drain(fast); !equal(got, []int{3}) {
+		t.Errorf(errExpectedX, []int{3}, got)
+	}
+}
+
+func TestConcurrentSubscribers(t *testing.T) {
+	const items = 1000
+	f, _ := fanout.New[int](16, fanout.Block)
+	subs := make([]*fanout.Subscriber[int], 8)
+	for i := range subs {
+		subs[i] = f.Subscribe()
+	}
+
+	var wg sync.WaitGroup
+	sums := make([]int, len(subs))
+	for i, s := range subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				item, err := s.Next(context.Background())
+				if err != nil {
+					return
+				}
+				sums[i] += item
+			}
+		}()
+	}
+	for i := 1; i <= items; i++ {
+		publish(t, f, i)
+	}
+	f.Close()
+	wg.Wait()
+
+	for _, sum := range sums {
+		if sum != items*(items+1)/2 {
+			t.Errorf(errExpectedX, items*(items+1)/2, sum)
+		}
+	}
+}
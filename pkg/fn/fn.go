@@ -0,0 +1,136 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fn provides functional utilities that work across collections
+// (Zip, Chunk, Partition, GroupBy, Windows, FlatMap).
+//
+// All the functions take their input as an iter.Seq, so they work with the
+// Iter method of any container in the library (and with any other sequence),
+// and return their results as buffers, preserving the input order.
+package fn
+
+import (
+	"errors"
+	"iter"
+
+	buffer "github.com/pzaino/gods/pkg/buffer"
+)
+
+const (
+	ErrInvalidSize = "size must be greater than 0"
+)
+
+// Pair is a couple of values produced by Zip.
+type Pair[A comparable, B comparable] struct {
+	First  A
+	Second B
+}
+
+// Zip returns a buffer with the pairs of elements at the same position in a
+// and b. It stops at the end of the shortest sequence.
+func Zip[A comparable, B comparable](a iter.Seq[A], b iter.Seq[B]) *buffer.Buffer[Pair[A, B]] {
+	result := buffer.New[Pair[A, B]]()
+	nextB, stop := iter.Pull(b)
+	defer stop()
+	for va := range a {
+		vb, ok := nextB()
+		if !ok {
+			break
+		}
+		_ = result.Append(Pair[A, B]{First: va, Second: vb})
+	}
+	return result
+}
+
+// Chunk splits the sequence in consecutive buffers of size elements (the
+// last one may be shorter).
+func Chunk[T comparable](seq iter.Seq[T], size uint64) ([]*buffer.Buffer[T], error) {
+	if size == 0 {
+		return nil, errors.New(ErrInvalidSize)
+	}
+	var chunks []*buffer.Buffer[T]
+	var current *buffer.Buffer[T]
+	for v := range seq {
+		if current == nil || current.Size() == size {
+			current = buffer.New[T]()
+			chunks = append(chunks, current)
+		}
+		_ = current.Append(v)
+	}
+	return chunks, nil
+}
+
+// Windows returns all the sliding windows of size consecutive elements of the
+// sequence (so an input of n elements produces n-size+1 windows). If the
+// sequence has less than size elements, no windows are returned.
+func Windows[T comparable](seq iter.Seq[T], size uint64) ([]*buffer.Buffer[T], error) {
+	if size == 0 {
+		return nil, errors.New(ErrInvalidSize)
+	}
+	var windows []*buffer.Buffer[T]
+	var last []T
+	for v := range seq {
+		last = append(last, v)
+		if uint64(len(last)) > size {
+			last = last[1:]
+		}
+		if uint64(len(last)) == size {
+			w := buffer.New[T]()
+			_ = w.PushN(last...)
+			windows = append(windows, w)
+		}
+	}
+	return windows, nil
+}
+
+// Partition splits the sequence in two buffers: the elements that match the
+// predicate and the ones that don't.
+func Partition[T comparable](seq iter.Seq[T], predicate func(T) bool) (matched, rest *buffer.Buffer[T]) {
+	matched, rest = buffer.New[T](), buffer.New[T]()
+	for v := range seq {
+		if predicate(v) {
+			_ = matched.Append(v)
+		} else {
+			_ = rest.Append(v)
+		}
+	}
+	return matched, rest
+}
+
+// GroupBy groups the elements of the sequence by the key returned by keyFn.
+func GroupBy[T comparable, K comparable](seq iter.Seq[T], keyFn func(T) K) map[K]*buffer.Buffer[T] {
+	groups := make(map[K]*buffer.Buffer[T])
+	for v := range seq {
+		k := keyFn(v)
+		group, ok := groups[k]
+		if !ok {
+			group = buffer.New[T]()
+			groups[k] = group
+		}
+		_ = group.Append(v)
+	}
+	return groups
+}
+
+// FlatMap applies fn to every element of the sequence and returns a buffer
+// with all the elements of the resulting sequences.
+func FlatMap[T any, U comparable](seq iter.Seq[T], fn func(T) iter.Seq[U]) *buffer.Buffer[U] {
+	result := buffer.New[U]()
+	for v := range seq {
+		for u := range fn(v) {
+			_ = result.Append(u)
+		}
+	}
+	return result
+}
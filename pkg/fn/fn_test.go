@@ -0,0 +1,119 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fn provides functional utilities that work across collections.
+package fn_test
+
+import (
+	"iter"
+	"slices"
+	"testing"
+
+	buffer "github.com/pzaino/gods/pkg/buffer"
+	fn "github.com/pzaino/gods/pkg/fn"
+	linkList "github.com/pzaino/gods/pkg/linkList"
+	stack "github.com/pzaino/gods/pkg/stack"
+)
+
+const (
+	errNoError   = "Expected no error, but got %v"
+	errYesError  = "Expected an error, but got nil"
+	errExpectedX = "Expected %v, but got %v"
+)
+
+func toSlices[T comparable](buffers []*buffer.Buffer[T]) [][]T {
+	var result [][]T
+	for _, b := range buffers {
+		result = append(result, b.ToSlice())
+	}
+	return result
+}
+
+func equalSlices(a, b [][]int) bool {
+	return slices.EqualFunc(a, b, func(x, y []int) bool { return slices.Equal(x, y) })
+}
+
+func TestZip(t *testing.T) {
+	names := linkList.NewFromSlice([]string{"a", "b", "c"})
+	numbers := stack.NewFromSlice([]int{1, 2}) // iterated from the top
+	pairs := fn.Zip(names.Iter(), numbers.Iter()).ToSlice()
+	expected := []fn.Pair[string, int]{{"a", 2}, {"b", 1}}
+	if !slices.Equal(pairs, expected) {
+		t.Errorf(errExpectedX, expected, pairs)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	chunks, err := fn.Chunk(slices.Values([]int{1, 2, 3, 4, 5}), 2)
+	if err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	expected := [][]int{{1, 2}, {3, 4}, {5}}
+	if got := toSlices(chunks); !equalSlices(got, expected) {
+		t.Errorf(errExpectedX, expected, got)
+	}
+
+	if _, err := fn.Chunk(slices.Values([]int{1}), 0); err == nil || err.Error() != fn.ErrInvalidSize {
+		t.Errorf(errYesError)
+	}
+}
+
+func TestWindows(t *testing.T) {
+	windows, err := fn.Windows(slices.Values([]int{1, 2, 3, 4}), 3)
+	if err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	expected := [][]int{{1, 2, 3}, {2, 3, 4}}
+	if got := toSlices(windows); !equalSlices(got, expected) {
+		t.Errorf(errExpectedX, expected, got)
+	}
+
+	windows, _ = fn.Windows(slices.Values([]int{1, 2}), 3)
+	if len(windows) != 0 {
+		t.Errorf(errExpectedX, 0, len(windows))
+	}
+	if _, err := fn.Windows(slices.Values([]int{1}), 0); err == nil {
+		t.Errorf(errYesError)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	even, odd := fn.Partition(slices.Values([]int{1, 2, 3, 4, 5}), func(v int) bool { return v%2 == 0 })
+	if got := even.ToSlice(); !slices.Equal(got, []int{2, 4}) {
+		t.Errorf(errExpectedX, []int{2, 4}, got)
+	}
+	if got := odd.ToSlice(); !slices.Equal(got, []int{1, 3, 5}) {
+		t.Errorf(errExpectedX, []int{1, 3, 5}, got)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	words := linkList.NewFromSlice([]string{"apple", "avocado", "banana", "cherry", "blueberry"})
+	groups := fn.GroupBy(words.Iter(), func(s string) byte { return s[0] })
+	if len(groups) != 3 {
+		t.Errorf(errExpectedX, 3, len(groups))
+	}
+	if got := groups['b'].ToSlice(); !slices.Equal(got, []string{"banana", "blueberry"}) {
+		t.Errorf(errExpectedX, []string{"banana", "blueberry"}, got)
+	}
+}
+
+func TestFlatMap(t *testing.T) {
+	result := fn.FlatMap(slices.Values([]int{1, 2, 3}), func(v int) iter.Seq[int] {
+		return slices.Values(slices.Repeat([]int{v}, v))
+	})
+	if got := result.ToSlice(); !slices.Equal(got, []int{1, 2, 2, 3, 3, 3}) {
+		t.Errorf(errExpectedX, []int{1, 2, 2, 3, 3, 3}, got)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package godstest provides utilities to test the data structures of the
+// library and any wrapper built on top of them:
+//
+//   - reference-model checkers, which run random sequences of operations on a
+//     container and on a trivial slice-based model and report the first
+//     difference;
+//   - invariant validators (e.g. for heaps);
+//   - a concurrency stress harness, meant to be used with the race detector.
+//
+// The checkers return an error instead of failing a test, so they can be used
+// from tests, fuzzers and benchmarks alike:
+//
+//	if err := godstest.CheckStack[int](csstack.New[int](), 10000, 1); err != nil {
+//		t.Fatal(err)
+//	}
+package godstest
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+)
+
+// Stack is the set of stack methods used by CheckStack (implemented, for
+// example, by stack.Stack and csstack.CSStack).
+type Stack[T any] interface {
+	Push(item T)
+	Pop() (*T, error)
+	Size() uint64
+	IsEmpty() bool
+	ToSlice() []T
+}
+
+// Queue is the set of queue methods used by CheckQueue.
+type Queue[T any] interface {
+	Enqueue(elem T)
+	Dequeue() (T, error)
+	Size() uint64
+	IsEmpty() bool
+	ToSlice() []T
+}
+
+// PriorityQueue is the set of priority queue methods used by CheckPriorityQueue.
+type PriorityQueue[T any] interface {
+	Enqueue(value T, priority int)
+	Dequeue() (T, error)
+	Size() uint64
+	IsEmpty() bool
+}
+
+// ModelError describes the first difference found between a container and
+// its reference model.
+type ModelError struct {
+	// Step is the index of the operation that failed
+	Step int
+	// Op is a description of the operation that failed
+	Op string
+	// Msg describes the difference
+	Msg string
+}
+
+// Error returns the error message
+func (e *ModelError) Error() string {
+	return fmt.Sprintf("step %d (%s): %s", e.Step, e.Op, e.Msg)
+}
+
+// CheckStack runs ops random operations on an (initially empty) stack and on
+// a slice model and returns an error at the first difference. The same seed
+// always produces the same sequence of operations.
+func CheckStack[T ~int](s Stack[T], ops int, seed int64) error {
+	rnd := rand.New(rand.NewSource(seed))
+	var model []T
+	for step := 0; step < ops; step++ {
+		switch rnd.Intn(4) {
+		case 0, 1:
+			v := T(rnd.Intn(1000))
+			s.Push(v)
+			model = append(model, v)
+		case 2:
+			item, err := s.Pop()
+			if len(model) == 0 {
+				if err == nil {
+					return &ModelError{step, "Pop", "expected an error on an empty stack"}
+				}
+				continue
+			}
+			expected := model[len(model)-1]
+			model = model[:len(model)-1]
+			if err != nil || item == nil || *item != expected {
+				return &ModelError{step, "Pop", fmt.Sprintf("expected %v, got %v (err %v)", expected, deref(item), err)}
+			}
+		case 3:
+			// the stack lists its items from the top
+			expected := slices.Clone(model)
+			slices.Reverse(expected)
+			if got := s.ToSlice(); !slices.Equal(got, expected) {
+				return &ModelError{step, "ToSlice", fmt.Sprintf("expected %v, got %v", expected, got)}
+			}
+		}
+		if err := checkSize(step, s.Size(), s.IsEmpty(), len(model)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckQueue runs ops random operations on an (initially empty) FIFO queue
+// and on a slice model and returns an error at the first difference.
+func CheckQueue[T ~int](q Queue[T], ops int, seed int64) error {
+	rnd := rand.New(rand.NewSource(seed))
+	var model []T
+	for step := 0; step < ops; step++ {
+		switch rnd.Intn(4) {
+		case 0, 1:
+			v := T(rnd.Intn(1000))
+			q.Enqueue(v)
+			model = append(model, v)
+		case 2:
+			item, err := q.Dequeue()
+			if len(model) == 0 {
+				if err == nil {
+					return &ModelError{step, "Dequeue", "expected an error on an empty queue"}
+				}
+				continue
+			}
+			expected := model[0]
+			model = model[1:]
+			if err != nil || item != expected {
+				return &ModelError{step, "Dequeue", fmt.Sprintf("expected %v, got %v (err %v)", expected, item, err)}
+			}
+		case 3:
+			if got := q.ToSlice(); !slices.Equal(got, model) {
+				return &ModelError{step, "ToSlice", fmt.Sprintf("expected %v, got %v", model, got)}
+			}
+		}
+		if err := checkSize(step, q.Size(), q.IsEmpty(), len(model)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckPriorityQueue runs ops random operations on an (initially empty)
+// max-priority queue and returns an error as soon as Dequeue returns an
+// element that doesn't have the highest priority.
+func CheckPriorityQueue[T ~int](pq PriorityQueue[T], ops int, seed int64) error {
+	rnd := rand.New(rand.NewSource(seed))
+	// values are unique (the step number), so they identify their priority
+	model := make(map[T]int)
+	for step := 0; step < ops; step++ {
+		if rnd.Intn(3) < 2 {
+			priority := rnd.Intn(100)
+			pq.Enqueue(T(step), priority)
+			model[T(step)] = priority
+		} else {
+			item, err := pq.Dequeue()
+			if len(model) == 0 {
+				if err == nil {
+					return &ModelError{step, "Dequeue", "expected an error on an empty queue"}
+				}
+				continue
+			}
+			priority, ok := model[item]
+			if err != nil || !ok {
+				return &ModelError{step, "Dequeue", fmt.Sprintf("unexpected element %v (err %v)", item, err)}
+			}
+			for _, p := range model {
+				if p > priority {
+					return &ModelError{step, "Dequeue", fmt.Sprintf("got priority %d while %d was available", priority, p)}
+				}
+			}
+			delete(model, item)
+		}
+		if err := checkSize(step, pq.Size(), pq.IsEmpty(), len(model)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkSize(step int, size uint64, empty bool, expected int) error {
+	if size != uint64(expected) {
+		return &ModelError{step, "Size", fmt.Sprintf("expected %d, got %d", expected, size)}
+	}
+	if empty != (expected == 0) {
+		return &ModelError{step, "IsEmpty", fmt.Sprintf("expected %v, got %v", expected == 0, empty)}
+	}
+	return nil
+}
+
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// ValidateHeap checks that items, stored as an implicit binary heap (the
+// children of i are 2i+1 and 2i+2), respect the heap property: no child is
+// ordered before its parent according to less. Use less.Reverse() to
+// validate a max-heap.
+func ValidateHeap[T any](items []T, less cmpx.Less[T]) error {
+	for i := 1; i < len(items); i++ {
+		parent := (i - 1) / 2
+		if less(items[i], items[parent]) {
+			return fmt.Errorf("heap property violated: element %d (%v) is ordered before its parent %d (%v)",
+				i, items[i], parent, items[parent])
+		}
+	}
+	return nil
+}
+
+// Stress runs op from the given number of goroutines, each one calling it
+// iterations times, and waits for all of them to finish. All the goroutines
+// are released at the same time to maximize contention. Run it with the race
+// detector enabled (go test -race) to find data races in concurrent wrappers.
+func Stress(goroutines, iterations int, op func(goroutine, iteration int)) {
+	var start, done sync.WaitGroup
+	start.Add(1)
+	for g := 0; g < goroutines; g++ {
+		done.Add(1)
+		go func(g int) {
+			defer done.Done()
+			start.Wait()
+			for i := 0; i < iterations; i++ {
+				op(g, i)
+			}
+		}(g)
+	}
+	start.Done()
+	done.Wait()
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package godstest provides utilities to test the data structures.
+package godstest_test
+
+import (
+	"errors"
+	"testing"
+
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+	godstest "github.com/pzaino/gods/pkg/godstest"
+	pqueue "github.com/pzaino/gods/pkg/pqueue"
+	queue "github.com/pzaino/gods/pkg/queue"
+	stack "github.com/pzaino/gods/pkg/stack"
+)
+
+const (
+	errNoError  = "Expected no error, but got %v"
+	errYesError = "Expected an error, but got nil"
+)
+
+// brokenStack forgets every 10th pushed item
+type brokenStack struct {
+	*stack.Stack[int]
+	pushes int
+}
+
+func (b *brokenStack) Push(item int) {
+	b.pushes++
+	if b.pushes%10 == 0 {
+		return
+	}
+	b.Stack.Push(item)
+}
+
+func TestCheckStack(t *testing.T) {
+	if err := godstest.CheckStack[int](stack.New[int](), 5000, 1); err != nil {
+		t.Errorf(errNoError, err)
+	}
+	if err := godstest.CheckStack[int](csstack.New[int](), 5000, 2); err != nil {
+		t.Errorf(errNoError, err)
+	}
+
+	err := godstest.CheckStack[int](&brokenStack{Stack: stack.New[int]()}, 5000, 1)
+	var modelErr *godstest.ModelError
+	if !errors.As(err, &modelErr) {
+		t.Fatalf(errYesError)
+	}
+	if modelErr.Op == "" || modelErr.Error() == "" {
+		t.Errorf("Expected a description of the failing operation")
+	}
+}
+
+func TestCheckQueue(t *testing.T) {
+	if err := godstest.CheckQueue[int](queue.New[int](), 5000, 1); err != nil {
+		t.Errorf(errNoError, err)
+	}
+}
+
+func TestCheckPriorityQueue(t *testing.T) {
+	if err := godstest.CheckPriorityQueue[int](pqueue.New[int](), 5000, 1); err != nil {
+		t.Errorf(errNoError, err)
+	}
+}
+
+func TestValidateHeap(t *testing.T) {
+	less := cmpx.NaturalOrder[int]()
+	if err := godstest.ValidateHeap([]int{1, 3, 2, 7, 4}, less); err != nil {
+		t.Errorf(errNoError, err)
+	}
+	if err := godstest.ValidateHeap([]int{1, 3, 2, 0}, less); err == nil {
+		t.Errorf(errYesError)
+	}
+	if err := godstest.ValidateHeap([]int{7, 4, 3}, less.Reverse()); err != nil {
+		t.Errorf(errNoError, err)
+	}
+	if err := godstest.ValidateHeap(nil, less); err != nil {
+		t.Errorf(errNoError, err)
+	}
+}
+
+func TestStress(t *testing.T) {
+	cs := csstack.New[int]()
+	godstest.Stress(8, 500, func(g, i int) {
+		cs.Push(i)
+		if i%2 == 1 {
+			_, _ = cs.Pop()
+		}
+	})
+	if cs.Size() != 8*250 {
+		t.Errorf("Expected size %d, got %d", 8*250, cs.Size())
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package histogram provides a high-dynamic-range (HDR) histogram: it records
+// integer values (latencies, sizes, ...) from 1 to a highest trackable value
+// with a fixed number of significant decimal digits, in constant memory and
+// constant time, and answers quantile queries over them.
+//
+// The values are grouped in buckets of doubling ranges, each one split in the
+// same number of sub-buckets, so the precision is relative: with 3
+// significant digits the value returned for a quantile is within 0.1% of the
+// recorded one. Recording is concurrency-safe and scales with the number of
+// goroutines: the counts are spread over stripes, merged by the queries.
+package histogram
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+)
+
+const (
+	ErrInvalidDigits   = "significant digits must be between 1 and 5"
+	ErrInvalidHighest  = "the highest trackable value must be at least 2"
+	ErrValueOutOfRange = "value out of range"
+	ErrInvalidData     = "invalid histogram data"
+)
+
+// encodingVersion is the first byte of the binary encoding
+const encodingVersion = 1
+
+// Option configures a Histogram created with New.
+type Option func(*config)
+
+type config struct {
+	stripes int
+}
+
+// WithStripes sets the number of counter stripes (the default is GOMAXPROCS,
+// at most 8). More stripes reduce the contention between the recording
+// goroutines, at the cost of memory and slower queries.
+func WithStripes(n int) Option {
+	return func(cfg *config) {
+		cfg.stripes = max(n, 1)
+	}
+}
+
+// stripe is a set of counters, updated atomically
+type stripe struct {
+	counts []atomic.Int64
+	total  atomic.Int64
+	sum    atomic.Int64
+}
+
+// Histogram is an HDR histogram. Record is safe for concurrent use with
+// everything; the queries see a consistent view once the recording stopped.
+type Histogram struct {
+	highest int64
+	digits  int
+
+	subBucketHalfCountMagnitude uint
+	subBucketHalfCount          int
+	subBucketMask               int64
+	bucketCount                 int
+	countsLen                   int
+
+	stripes []stripe
+	min     atomic.Int64
+	max     atomic.Int64
+}
+
+// New creates a Histogram recording the values from 1 to highest with the
+// given number of significant decimal digits (1 to 5).
+func New(highest int64, digits int, opts ...Option) (*Histogram, error) {
+	if digits < 1 || digits > 5 {
+		return nil, errors.New(ErrInvalidDigits)
+	}
+	if highest < 2 {
+		return nil, errors.New(ErrInvalidHighest)
+	}
+	cfg := config{stripes: min(runtime.GOMAXPROCS(0), 8)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	h := &Histogram{highest: highest, digits: digits}
+	// every sub-bucket of the first bucket has a unit width: they must cover
+	// 2*10^digits values to have the requested precision everywhere
+	singleUnitResolution := 2 * int64(math.Pow10(digits))
+	subBucketCountMagnitude := uint(bits.Len64(uint64(singleUnitResolution - 1)))
+	h.subBucketHalfCountMagnitude = subBucketCountMagnitude - 1
+	h.subBucketHalfCount = 1 << h.subBucketHalfCountMagnitude
+	subBucketCount := int64(1) << subBucketCountMagnitude
+	h.subBucketMask = subBucketCount - 1
+
+	h.bucketCount = 1
+	for limit := subBucketCount; limit <= highest; h.bucketCount++ {
+		if limit > math.MaxInt64/2 {
+			h.bucketCount++
+			break
+		}
+		limit <<= 1
+	}
+	h.countsLen = (h.bucketCount + 1) * h.subBucketHalfCount
+
+	h.stripes = make([]stripe, cfg.stripes)
+	for i := range h.stripes {
+		h.stripes[i].counts = make([]atomic.Int64, h.countsLen)
+	}
+	h.min.Store(math.MaxInt64)
+	return h, nil
+}
+
+// HighestTrackableValue returns the largest value the histogram can record.
+func (h *Histogram) HighestTrackableValue() int64 {
+	return h.highest
+}
+
+// SignificantDigits returns the number of significant decimal digits.
+func (h *Histogram) SignificantDigits() int {
+	return h.digits
+}
+
+// countsIndex returns the index of the counter of v
+func (h *Histogram) countsIndex(v int64) int {
+	bucket := bits.Len64(uint64(v|h.subBucketMask)) - int(h.subBucketHalfCountMagnitude) - 1
+	subBucket := int(v >> uint(bucket))
+	return (bucket+1)<<h.subBucketHalfCountMagnitude + subBucket - h.subBucketHalfCount
+}
+
+// valueRange returns the lowest value counted at index i and the width of
+// the range of values counted there
+func (h *Histogram) valueRange(i int) (lowest, width int64) {
+	bucket := i>>h.subBucketHalfCountMagnitude - 1
+	subBucket := i&(h.subBucketHalfCount-1) + h.subBucketHalfCount
+	if bucket < 0 {
+		subBucket -= h.subBucketHalfCount
+		bucket = 0
+	}
+	return int64(subBucket) << uint(bucket), int64(1) << uint(bucket)
+}
+
+// highestEquivalentValue returns the highest value counted with v
+func (h *Histogram) highestEquivalentValue(v int64) int64 {
+	lowest, width := h.valueRange(h.countsIndex(v))
+	return lowest + width - 1
+}
+
+// Record records a value. It returns an ErrValueOutOfRange error if the value
+// is below 1 or above the highest trackable value.
+func (h *Histogram) Record(v int64) error {
+	return h.RecordN(v, 1)
+}
+
+// RecordN records a value n times.
+func (h *Histogram) RecordN(v, n int64) error {
+	if v < 1 || v > h.highest {
+		return fmt.Errorf("%s: %d", ErrValueOutOfRange, v)
+	}
+	if n > 0 {
+		h.add(v, n)
+		h.updateBounds(v, v)
+	}
+	return nil
+}
+
+// add counts n times the value v, without updating the bounds
+func (h *Histogram) add(v, n int64) {
+	s := &h.stripes[0]
+	if len(h.stripes) > 1 {
+		s = &h.stripes[rand.N(len(h.stripes))]
+	}
+	s.counts[h.countsIndex(v)].Add(n)
+	s.total.Add(n)
+	s.sum.Add(v * n)
+}
+
+// updateBounds extends the recorded min and max to lo and hi
+func (h *Histogram) updateBounds(lo, hi int64) {
+	for old := h.min.Load(); lo < old && !h.min.CompareAndSwap(old, lo); old = h.min.Load() {
+	}
+	for old := h.max.Load(); hi > old && !h.max.CompareAndSwap(old, hi); old = h.max.Load() {
+	}
+}
+
+// count returns the number of values counted at index i
+func (h *Histogram) count(i int) int64 {
+	var n int64
+	for s := range h.stripes {
+		n += h.stripes[s].counts[i].Load()
+	}
+	return n
+}
+
+// TotalCount returns the number of recorded values.
+func (h *Histogram) TotalCount() int64 {
+	var n int64
+	for s := range h.stripes {
+		n += h.stripes[s].total.Load()
+	}
+	return n
+}
+
+// Min returns the smallest recorded value (0 if there's none).
+func (h *Histogram) Min() int64 {
+	if v := h.min.Load(); v != math.MaxInt64 {
+		return v
+	}
+	return 0
+}
+
+// Max returns the largest recorded value (0 if there's none).
+func (h *Histogram) Max() int64 {
+	return h.max.Load()
+}
+
+// Mean returns the mean of the recorded values (0 if there's none).
+func (h *Histogram) Mean() float64 {
+	var total, sum int64
+	for s := range h.stripes {
+		total += h.stripes[s].total.Load()
+		sum += h.stripes[s].sum.Load()
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(sum) / float64(total)
+}
+
+// ValueAtQuantile returns the value below or at which a fraction q (0 to 1)
+// of the recorded values fall, within the precision of the histogram. It
+// returns 0 if the histogram is empty.
+func (h *Histogram) ValueAtQuantile(q float64) int64 {
+	total := h.TotalCount()
+	if total == 0 {
+		return 0
+	}
+	q = min(max(q, 0), 1)
+	target := max(int64(math.Ceil(q*float64(total))), 1)
+
+	var seen int64
+	for i := range h.countsLen {
+		if seen += h.count(i); seen >= target {
+			lowest, width := h.valueRange(i)
+			return min(lowest+width-1, h.Max())
+		}
+	}
+	return h.Max()
+}
+
+// Merge records all the values of other. The histograms can have different
+// ranges and precisions: the values of other are recorded at the precision
+// of other (as the middle of the range they were counted in). It returns an ErrValueOutOfRange error, and records nothing, if
+// other has values above the highest trackable value of h.
+func (h *Histogram) Merge(other *Histogram) error {
+	if other.TotalCount() == 0 {
+		return nil
+	}
+	lo, hi := other.Min(), other.Max()
+	if hi > h.highest {
+		return fmt.Errorf("%s: %d", ErrValueOutOfRange, hi)
+	}
+	for i := range other.countsLen {
+		if n := other.count(i); n > 0 {
+			lowest, width := other.valueRange(i)
+			h.add(min(max(lowest+width/2, lo), hi), n)
+		}
+	}
+	h.updateBounds(lo, hi)
+	return nil
+}
+
+// Reset removes all the recorded values.
+func (h *Histogram) Reset() {
+	for s := range h.stripes {
+		st := &h.stripes[s]
+		for i := range st.counts {
+			st.counts[i].Store(0)
+		}
+		st.total.Store(0)
+		st.sum.Store(0)
+	}
+	h.min.Store(math.MaxInt64)
+	h.max.Store(0)
+}
+
+// MarshalBinary encodes the histogram compactly: its parameters, then the
+// counters as varints, the runs of empty counters collapsed into one
+// negative number.
+func (h *Histogram) MarshalBinary() ([]byte, error) {
+	data := []byte{encodingVersion, byte(h.digits)}
+	data = binary.AppendUvarint(data, uint64(h.highest))
+	data = binary.AppendUvarint(data, uint64(h.Min()))
+	data = binary.AppendUvarint(data, uint64(h.Max()))
+
+	zeros := int64(0)
+	for i := range h.countsLen {
+		n := h.count(i)
+		if n == 0 {
+			zeros++
+			continue
+		}
+		if zeros > 0 {
+			data = binary.AppendVarint(data, -zeros)
+			zeros = 0
+		}
+		data = binary.AppendVarint(data, n)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary replaces the histogram with the one encoded in data by
+// MarshalBinary (with a single stripe if h wasn't created by New).
+func (h *Histogram) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 || data[0] != encodingVersion {
+		return errors.New(ErrInvalidData)
+	}
+	digits := int(data[1])
+	data = data[2:]
+	var header [3]uint64
+	for i := range header {
+		v, n := binary.Uvarint(data)
+		if n <= 0 || v > math.MaxInt64 {
+			return errors.New(ErrInvalidData)
+		}
+		header[i], data = v, data[n:]
+	}
+
+	stripes := max(len(h.stripes), 1)
+	decoded, err := New(int64(header[0]), digits, WithStripes(stripes))
+	if err != nil {
+		return fmt.Errorf("%s: %w", ErrInvalidData, err)
+	}
+	s := &decoded.stripes[0]
+	for i := 0; len(data) > 0; {
+		n, size := binary.Varint(data)
+		if size <= 0 {
+			return errors.New(ErrInvalidData)
+		}
+		data = data[size:]
+		if n < 0 {
+			i += int(-n)
+			continue
+		}
+		if i >= decoded.countsLen {
+			return errors.New(ErrInvalidData)
+		}
+		lowest, width := decoded.valueRange(i)
+		s.counts[i].Store(n)
+		s.total.Add(n)
+		s.sum.Add(min(max(lowest+width/2, int64(header[1])), int64(header[2])) * n)
+		i++
+	}
+	if s.total.Load() > 0 {
+		decoded.min.Store(int64(header[1]))
+		decoded.max.Store(int64(header[2]))
+	}
+
+	h.highest, h.digits = decoded.highest, decoded.digits
+	h.subBucketHalfCountMagnitude = decoded.subBucketHalfCountMagnitude
+	h.subBucketHalfCount = decoded.subBucketHalfCount
+	h.subBucketMask = decoded.subBucketMask
+	h.bucketCount, h.countsLen = decoded.bucketCount, decoded.countsLen
+	h.stripes = decoded.stripes
+	h.min.Store(decoded.min.Load())
+	h.max.Store(decoded.max.Load())
+	return nil
+}
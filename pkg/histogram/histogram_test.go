@@ -0,0 +1,188 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package histogram_test
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	histogram "github.com/pzaino/gods/pkg/histogram"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+// within reports whether got is within the relative precision of digits
+// significant digits from want
+func within(got, want int64, digits int) bool {
+	return math.Abs(float64(got-want)) <= float64(want)*math.Pow10(-digits)
+}
+
+func TestNew(t *testing.T) {
+	if _, err := histogram.New(1000, 0); err == nil || err.Error() != histogram.ErrInvalidDigits {
+		t.Errorf(errExpectedX, histogram.ErrInvalidDigits, err)
+	}
+	if _, err := histogram.New(1, 3); err == nil || err.Error() != histogram.ErrInvalidHighest {
+		t.Errorf(errExpectedX, histogram.ErrInvalidHighest, err)
+	}
+	h, _ := histogram.New(1000, 3)
+	if err := h.Record(1001); err == nil {
+		t.Errorf(errExpectedX, histogram.ErrValueOutOfRange, err)
+	}
+	if err := h.Record(0); err == nil {
+		t.Errorf(errExpectedX, histogram.ErrValueOutOfRange, err)
+	}
+	if v := h.ValueAtQuantile(0.5); v != 0 {
+		t.Errorf(errExpectedX, 0, v)
+	}
+}
+
+func TestValueAtQuantile(t *testing.T) {
+	for _, digits := range []int{1, 2, 3, 4} {
+		h, err := histogram.New(3_600_000_000_000, digits)
+		if err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+		values := make([]int64, 100_000)
+		for i := range values {
+			values[i] = int64(math.Exp(rand.Float64()*25)) + 1 // up to ~7e10
+			if err := h.Record(values[i]); err != nil {
+				t.Fatalf(errExpectedNoError, err)
+			}
+		}
+		slices.Sort(values)
+
+		for _, q := range []float64{0.001, 0.25, 0.5, 0.9, 0.99, 0.999, 1} {
+			want := values[int(math.Ceil(q*float64(len(values))))-1]
+			if got := h.ValueAtQuantile(q); !within(got, want, digits) {
+				t.Errorf("digits %d, quantile %v: "+errExpectedX, digits, q, want, got)
+			}
+		}
+		if h.Min() != values[0] || h.Max() != values[len(values)-1] {
+			t.Errorf(errExpectedX, []int64{values[0], values[len(values)-1]}, []int64{h.Min(), h.Max()})
+		}
+		if n := h.TotalCount(); n != int64(len(values)) {
+			t.Errorf(errExpectedX, len(values), n)
+		}
+	}
+}
+
+func TestSmallValuesAreExact(t *testing.T) {
+	h, _ := histogram.New(1_000_000, 3)
+	for v := int64(1); v <= 2000; v++ {
+		_ = h.Record(v)
+	}
+	for _, q := range []float64{0.1, 0.5, 0.75, 1} {
+		want := int64(q * 2000)
+		if got := h.ValueAtQuantile(q); got != want {
+			t.Errorf(errExpectedX, want, got)
+		}
+	}
+	if mean := h.Mean(); mean != 1000.5 {
+		t.Errorf(errExpectedX, 1000.5, mean)
+	}
+}
+
+func TestConcurrentRecord(t *testing.T) {
+	h, _ := histogram.New(1_000_000, 3, histogram.WithStripes(4))
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 10_000 {
+				_ = h.Record(int64(g*10_000 + i + 1))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := h.TotalCount(); n != 80_000 {
+		t.Errorf(errExpectedX, 80_000, n)
+	}
+	if h.Min() != 1 || h.Max() != 80_000 {
+		t.Errorf(errExpectedX, []int64{1, 80_000}, []int64{h.Min(), h.Max()})
+	}
+	if got := h.ValueAtQuantile(0.5); !within(got, 40_000, 3) {
+		t.Errorf(errExpectedX, 40_000, got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, _ := histogram.New(1_000_000, 3)
+	b, _ := histogram.New(10_000, 2)
+	for v := int64(1); v <= 1000; v++ {
+		_ = a.Record(v)
+		_ = b.Record(v + 1000)
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if n := a.TotalCount(); n != 2000 {
+		t.Errorf(errExpectedX, 2000, n)
+	}
+	if a.Min() != 1 || a.Max() != 2000 {
+		t.Errorf(errExpectedX, []int64{1, 2000}, []int64{a.Min(), a.Max()})
+	}
+	if got := a.ValueAtQuantile(0.75); !within(got, 1500, 2) {
+		t.Errorf(errExpectedX, 1500, got)
+	}
+
+	small, _ := histogram.New(100, 3)
+	if err := small.Merge(a); err == nil {
+		t.Errorf(errExpectedX, histogram.ErrValueOutOfRange, err)
+	}
+	if n := small.TotalCount(); n != 0 {
+		t.Errorf(errExpectedX, 0, n)
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	h, _ := histogram.New(3_600_000_000, 3)
+	for range 10_000 {
+		_ = h.Record(rand.Int63n(1_000_000) + 1)
+	}
+	_ = h.RecordN(3_000_000_000, 5)
+
+	data, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	var decoded histogram.Histogram
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if decoded.TotalCount() != h.TotalCount() || decoded.Min() != h.Min() || decoded.Max() != h.Max() {
+		t.Errorf(errExpectedX, h.TotalCount(), decoded.TotalCount())
+	}
+	for _, q := range []float64{0, 0.5, 0.99, 1} {
+		if decoded.ValueAtQuantile(q) != h.ValueAtQuantile(q) {
+			t.Errorf(errExpectedX, h.ValueAtQuantile(q), decoded.ValueAtQuantile(q))
+		}
+	}
+	// decoded still records
+	if err := decoded.Record(42); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+
+	if err := decoded.UnmarshalBinary(data[:1]); err == nil || err.Error() != histogram.ErrInvalidData {
+		t.Errorf(errExpectedX, histogram.ErrInvalidData, err)
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package indexedlist provides a doubly linked list of key/value pairs with a
+// hash index from the keys to the nodes: the pairs are kept in the order
+// chosen by the caller, and they can be found, removed or moved to either end
+// of the list by key in O(1). It's the building block of LRU and MRU caches
+// and of "recently used" lists.
+package indexedlist
+
+import (
+	"iter"
+
+	gods "github.com/pzaino/gods"
+)
+
+// node is a node of a List
+type node[K comparable, V any] struct {
+	key        K
+	value      V
+	prev, next *node[K, V]
+}
+
+// List is a doubly linked list of key/value pairs indexed by key. The keys
+// are unique. It is not concurrency-safe.
+type List[K comparable, V any] struct {
+	root  node[K, V] // sentinel: root.next is the front, root.prev the back
+	index map[K]*node[K, V]
+}
+
+// New creates a new empty List.
+func New[K comparable, V any]() *List[K, V] {
+	l := &List[K, V]{index: make(map[K]*node[K, V])}
+	l.root.next, l.root.prev = &l.root, &l.root
+	return l
+}
+
+// Size returns the number of pairs in the list
+func (l *List[K, V]) Size() uint64 {
+	return uint64(len(l.index))
+}
+
+// IsEmpty returns true if the list is empty
+func (l *List[K, V]) IsEmpty() bool {
+	return len(l.index) == 0
+}
+
+// Contains returns true if the list holds key
+func (l *List[K, V]) Contains(key K) bool {
+	_, ok := l.index[key]
+	return ok
+}
+
+// Get returns the value of key, without moving it
+func (l *List[K, V]) Get(key K) (V, bool) {
+	if n, ok := l.index[key]; ok {
+		return n.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// PushFront adds the pair at the front of the list. If the key is already in
+// the list, its value is replaced and it's moved to the front. It returns
+// true if the key is new.
+func (l *List[K, V]) PushFront(key K, value V) bool {
+	return l.push(key, value, &l.root)
+}
+
+// PushBack adds the pair at the back of the list. If the key is already in
+// the list, its value is replaced and it's moved to the back. It returns
+// true if the key is new.
+func (l *List[K, V]) PushBack(key K, value V) bool {
+	return l.push(key, value, l.root.prev)
+}
+
+// push adds or moves the pair of key after the node at
+func (l *List[K, V]) push(key K, value V, at *node[K, V]) bool {
+	if n, ok := l.index[key]; ok {
+		n.value = value
+		l.move(n, at)
+		return false
+	}
+	n := &node[K, V]{key: key, value: value}
+	l.index[key] = n
+	l.insert(n, at)
+	return true
+}
+
+// Remove removes key from the list and returns its value
+func (l *List[K, V]) Remove(key K) (V, bool) {
+	n, ok := l.index[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	l.remove(n)
+	return n.value, true
+}
+
+// MoveToFront moves key to the front of the list. It returns false if the key
+// is not in the list.
+func (l *List[K, V]) MoveToFront(key K) bool {
+	n, ok := l.index[key]
+	if ok {
+		l.move(n, &l.root)
+	}
+	return ok
+}
+
+// MoveToBack moves key to the back of the list. It returns false if the key
+// is not in the list.
+func (l *List[K, V]) MoveToBack(key K) bool {
+	n, ok := l.index[key]
+	if ok {
+		l.move(n, l.root.prev)
+	}
+	return ok
+}
+
+// Front returns the pair at the front of the list
+func (l *List[K, V]) Front() (K, V, bool) {
+	return l.pair(l.root.next)
+}
+
+// Back returns the pair at the back of the list
+func (l *List[K, V]) Back() (K, V, bool) {
+	return l.pair(l.root.prev)
+}
+
+// PopFront removes and returns the pair at the front of the list
+func (l *List[K, V]) PopFront() (K, V, bool) {
+	return l.pop(l.root.next)
+}
+
+// PopBack removes and returns the pair at the back of the list (the least
+// recently used one, if the pairs are pushed or moved to the front when used)
+func (l *List[K, V]) PopBack() (K, V, bool) {
+	return l.pop(l.root.prev)
+}
+
+// Clear removes all the pairs from the list
+func (l *List[K, V]) Clear() {
+	clear(l.index)
+	l.root.next, l.root.prev = &l.root, &l.root
+}
+
+// All returns an iterator over the pairs of the list, from the front to the
+// back. The list must not be modified during the iteration.
+func (l *List[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for n := l.root.next; n != &l.root; n = n.next {
+			if !yield(n.key, n.value) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the pairs of the list, from the back to
+// the front. The list must not be modified during the iteration.
+func (l *List[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for n := l.root.prev; n != &l.root; n = n.prev {
+			if !yield(n.key, n.value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys of the list, from the front to the
+// back
+func (l *List[K, V]) Keys() iter.Seq[K] {
+	return gods.Keys(l.All())
+}
+
+// Values returns an iterator over the values of the list, from the front to
+// the back
+func (l *List[K, V]) Values() iter.Seq[V] {
+	return gods.Values(l.All())
+}
+
+// Validate checks the invariants of the list: the links of the nodes and the
+// index (see gods.Validator).
+func (l *List[K, V]) Validate() error {
+	count := 0
+	for n := l.root.next; n != &l.root; n = n.next {
+		if n.next.prev != n {
+			return gods.Invariantf("the node of key %v is not the prev of its next", n.key)
+		}
+		if l.index[n.key] != n {
+			return gods.Invariantf("the index of key %v doesn't point to its node", n.key)
+		}
+		if count++; count > len(l.index) {
+			return gods.Invariantf("the list has more nodes than the %d keys of the index", len(l.index))
+		}
+	}
+	if count != len(l.index) {
+		return gods.Invariantf("the index has %d keys but the list has %d nodes", len(l.index), count)
+	}
+	return nil
+}
+
+// insert links n after the node at
+func (l *List[K, V]) insert(n, at *node[K, V]) {
+	n.prev, n.next = at, at.next
+	at.next.prev = n
+	at.next = n
+}
+
+// unlink removes n from the links of the list
+func (l *List[K, V]) unlink(n *node[K, V]) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev, n.next = nil, nil
+}
+
+// move moves n after the node at
+func (l *List[K, V]) move(n, at *node[K, V]) {
+	if n == at {
+		return
+	}
+	l.unlink(n)
+	l.insert(n, at)
+}
+
+// remove removes n from the list and the index
+func (l *List[K, V]) remove(n *node[K, V]) {
+	l.unlink(n)
+	delete(l.index, n.key)
+}
+
+// pair returns the pair of n, or false if n is the sentinel
+func (l *List[K, V]) pair(n *node[K, V]) (K, V, bool) {
+	if n == &l.root {
+		var k K
+		var v V
+		return k, v, false
+	}
+	return n.key, n.value, true
+}
+
+// pop removes n and returns its pair, or false if n is the sentinel
+func (l *List[K, V]) pop(n *node[K, V]) (K, V, bool) {
+	k, v, ok := l.pair(n)
+	if ok {
+		l.remove(n)
+	}
+	return k, v, ok
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package indexedlist_test
+
+import (
+	"slices"
+	"testing"
+
+	indexedlist "github.com/pzaino/gods/pkg/indexedlist"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+func TestPushAndMove(t *testing.T) {
+	l := indexedlist.New[string, int]()
+	l.PushBack("b", 2)
+	l.PushBack("c", 3)
+	l.PushFront("a", 1)
+	if got := slices.Collect(l.Keys()); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf(errExpectedX, []string{"a", "b", "c"}, got)
+	}
+
+	// pushing an existing key replaces its value and moves it
+	if l.PushFront("c", 30) {
+		t.Errorf("expected c not to be new")
+	}
+	if v, ok := l.Get("c"); !ok || v != 30 {
+		t.Errorf(errExpectedX, 30, v)
+	}
+	if !l.MoveToBack("a") || l.MoveToFront("missing") {
+		t.Errorf("expected MoveToBack to find a and MoveToFront not to find missing")
+	}
+	if got := slices.Collect(l.Keys()); !slices.Equal(got, []string{"c", "b", "a"}) {
+		t.Errorf(errExpectedX, []string{"c", "b", "a"}, got)
+	}
+	if got := slices.Collect(l.Values()); !slices.Equal(got, []int{30, 2, 1}) {
+		t.Errorf(errExpectedX, []int{30, 2, 1}, got)
+	}
+	var backward []string
+	for k := range l.Backward() {
+		backward = append(backward, k)
+	}
+	if !slices.Equal(backward, []string{"a", "b", "c"}) {
+		t.Errorf(errExpectedX, []string{"a", "b", "c"}, backward)
+	}
+	if err := l.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+}
+
+func TestRemoveAndPop(t *testing.T) {
+	l := indexedlist.New[int, string]()
+	for i, v := range []string{"zero", "one", "two", "three"} {
+		l.PushBack(i, v)
+	}
+	if v, ok := l.Remove(1); !ok || v != "one" {
+		t.Errorf(errExpectedX, "one", v)
+	}
+	if _, ok := l.Remove(1); ok {
+		t.Errorf("expected 1 to be removed already")
+	}
+	if k, v, ok := l.PopBack(); !ok || k != 3 || v != "three" {
+		t.Errorf(errExpectedX, "3 three", v)
+	}
+	if k, _, ok := l.PopFront(); !ok || k != 0 {
+		t.Errorf(errExpectedX, 0, k)
+	}
+	if k, _, ok := l.Front(); !ok || k != 2 || l.Size() != 1 {
+		t.Errorf(errExpectedX, 2, k)
+	}
+	l.Clear()
+	if _, _, ok := l.Back(); ok || !l.IsEmpty() || l.Contains(2) {
+		t.Errorf("expected the list to be empty")
+	}
+	if err := l.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+}
+
+// TestLRU uses the list as the recency order of an LRU cache of 2 entries
+func TestLRU(t *testing.T) {
+	l := indexedlist.New[string, int]()
+	use := func(key string, value int) {
+		l.PushFront(key, value)
+		if l.Size() > 2 {
+			l.PopBack()
+		}
+	}
+	use("a", 1)
+	use("b", 2)
+	l.MoveToFront("a")
+	use("c", 3)
+	if l.Contains("b") || !l.Contains("a") || !l.Contains("c") {
+		t.Errorf(errExpectedX, []string{"c", "a"}, slices.Collect(l.Keys()))
+	}
+}
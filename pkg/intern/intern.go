@@ -0,0 +1,195 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package intern provides a concurrency-safe intern pool: it returns a
+// canonical instance for every distinct string (or []byte content), so the
+// copies of the same keys flowing through queues and maps millions of times
+// share one allocation.
+//
+// The pool is split in shards, each one with its own lock, and it can be
+// capped (WithMaxSize): then the least recently used strings are evicted,
+// approximately, with the CLOCK algorithm. An evicted string stays valid, it
+// just stops being the canonical instance.
+package intern
+
+import (
+	"hash/maphash"
+	"strings"
+	"sync"
+)
+
+// Option configures a Pool created with New.
+type Option func(*config)
+
+type config struct {
+	shards  int
+	maxSize int
+}
+
+// WithShards sets the number of shards (the default is 16). More shards
+// mean less contention between goroutines.
+func WithShards(n int) Option {
+	return func(cfg *config) {
+		cfg.shards = max(n, 1)
+	}
+}
+
+// WithMaxSize caps the number of strings in the pool (the default is no
+// cap). The cap is split evenly among the shards.
+func WithMaxSize(n int) Option {
+	return func(cfg *config) {
+		cfg.maxSize = max(n, 0)
+	}
+}
+
+// Stats are the counters of a Pool.
+type Stats struct {
+	Size      int    // the strings in the pool
+	Hits      uint64 // the lookups finding a canonical instance
+	Misses    uint64 // the lookups adding a new one
+	Evictions uint64 // the strings evicted to respect the cap
+}
+
+// entry is a string of a shard, with its CLOCK reference bit
+type entry struct {
+	s   string
+	ref bool
+}
+
+// shard is a part of the pool, with its own lock
+type shard struct {
+	mu      sync.Mutex
+	index   map[string]int // position in entries
+	entries []entry
+	hand    int // the CLOCK hand, the next eviction candidate
+	max     int // 0 is unlimited
+	stats   Stats
+	_       [64]byte // keep the locks of the shards on different cache lines
+}
+
+// Pool is a concurrency-safe intern pool.
+type Pool struct {
+	shards []shard
+	seed   maphash.Seed
+}
+
+// New creates a new empty Pool.
+func New(opts ...Option) *Pool {
+	cfg := config{shards: 16}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	p := &Pool{shards: make([]shard, cfg.shards), seed: maphash.MakeSeed()}
+	perShard := 0
+	if cfg.maxSize > 0 {
+		perShard = max((cfg.maxSize+cfg.shards-1)/cfg.shards, 1)
+	}
+	for i := range p.shards {
+		p.shards[i].index = make(map[string]int)
+		p.shards[i].max = perShard
+	}
+	return p
+}
+
+// String returns the canonical instance of s: the first string equal to s
+// given to the pool (a copy of it, so a substring doesn't keep alive the
+// string it's part of).
+func (p *Pool) String(s string) string {
+	sh := &p.shards[maphash.String(p.seed, s)%uint64(len(p.shards))]
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if i, ok := sh.index[s]; ok {
+		return sh.hit(i)
+	}
+	return sh.add(strings.Clone(s))
+}
+
+// Bytes returns the canonical string with the content of b. It doesn't
+// allocate when b is already in the pool.
+func (p *Pool) Bytes(b []byte) string {
+	sh := &p.shards[maphash.Bytes(p.seed, b)%uint64(len(p.shards))]
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if i, ok := sh.index[string(b)]; ok {
+		return sh.hit(i)
+	}
+	return sh.add(string(b))
+}
+
+// hit returns the string at position i, marking it as used
+func (sh *shard) hit(i int) string {
+	sh.stats.Hits++
+	sh.entries[i].ref = true
+	return sh.entries[i].s
+}
+
+// add adds s to the shard, evicting a string if the shard is full
+func (sh *shard) add(s string) string {
+	sh.stats.Misses++
+	if sh.max == 0 || len(sh.entries) < sh.max {
+		sh.index[s] = len(sh.entries)
+		sh.entries = append(sh.entries, entry{s: s})
+		return s
+	}
+	// second chance: skip (and clear) the recently used strings
+	for sh.entries[sh.hand].ref {
+		sh.entries[sh.hand].ref = false
+		sh.hand = (sh.hand + 1) % len(sh.entries)
+	}
+	delete(sh.index, sh.entries[sh.hand].s)
+	sh.stats.Evictions++
+	sh.entries[sh.hand] = entry{s: s}
+	sh.index[s] = sh.hand
+	sh.hand = (sh.hand + 1) % len(sh.entries)
+	return s
+}
+
+// Size returns the number of strings in the pool
+func (p *Pool) Size() int {
+	n := 0
+	for i := range p.shards {
+		sh := &p.shards[i]
+		sh.mu.Lock()
+		n += len(sh.entries)
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+// Stats returns the counters of the pool, summed over the shards
+func (p *Pool) Stats() Stats {
+	var st Stats
+	for i := range p.shards {
+		sh := &p.shards[i]
+		sh.mu.Lock()
+		st.Size += len(sh.entries)
+		st.Hits += sh.stats.Hits
+		st.Misses += sh.stats.Misses
+		st.Evictions += sh.stats.Evictions
+		sh.mu.Unlock()
+	}
+	return st
+}
+
+// Clear removes all the strings from the pool (the counters are kept)
+func (p *Pool) Clear() {
+	for i := range p.shards {
+		sh := &p.shards[i]
+		sh.mu.Lock()
+		clear(sh.index)
+		clear(sh.entries)
+		sh.entries, sh.hand = sh.entries[:0], 0
+		sh.mu.Unlock()
+	}
+}
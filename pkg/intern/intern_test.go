@@ -0,0 +1,100 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intern_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+
+	intern "github.com/pzaino/gods/pkg/intern"
+)
+
+const errExpectedX = "expected %v, got %v"
+
+// same returns true if a and b share their bytes
+func same(a, b string) bool {
+	return len(a) == len(b) && unsafe.StringData(a) == unsafe.StringData(b)
+}
+
+func TestIntern(t *testing.T) {
+	p := intern.New()
+	a := p.String(strings.Repeat("k", 3))
+	b := p.String(strings.Repeat("k", 3))
+	c := p.Bytes([]byte("kkk"))
+	if a != "kkk" || !same(a, b) || !same(a, c) {
+		t.Errorf("expected the canonical instance")
+	}
+	big := "prefix-key-suffix"
+	if sub := p.String(big[7:10]); same(sub, big[7:10]) {
+		t.Errorf("expected a copy of the substring")
+	}
+	key := []byte("kkk")
+	if allocs := testing.AllocsPerRun(100, func() { p.Bytes(key) }); allocs != 0 {
+		t.Errorf(errExpectedX, 0, allocs)
+	}
+	st := p.Stats()
+	if st.Size != 2 || st.Misses != 2 || st.Hits < 2 || st.Evictions != 0 {
+		t.Errorf(errExpectedX, "2 strings, 2 misses", st)
+	}
+	p.Clear()
+	if p.Size() != 0 {
+		t.Errorf(errExpectedX, 0, p.Size())
+	}
+}
+
+func TestInternMaxSize(t *testing.T) {
+	p := intern.New(intern.WithShards(1), intern.WithMaxSize(3))
+	hot := p.String("hot")
+	for i := 0; i < 10; i++ {
+		p.String(fmt.Sprint(i))
+		// the hot string is used all the time, so it's never evicted
+		if got := p.String("hot"); !same(got, hot) {
+			t.Fatalf("expected the hot string to stay in the pool")
+		}
+	}
+	st := p.Stats()
+	if st.Size != 3 || st.Evictions != 8 {
+		t.Errorf(errExpectedX, "3 strings, 8 evictions", st)
+	}
+}
+
+func TestInternConcurrent(t *testing.T) {
+	p := intern.New(intern.WithShards(4))
+	var wg sync.WaitGroup
+	results := make([][]string, 8)
+	for g := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				results[g] = append(results[g], p.Bytes([]byte(fmt.Sprint("key", i))))
+			}
+		}()
+	}
+	wg.Wait()
+	for g := 1; g < len(results); g++ {
+		for i := range results[g] {
+			if !same(results[g][i], results[0][i]) {
+				t.Fatalf("expected every goroutine to get the canonical instance")
+			}
+		}
+	}
+	if p.Size() != 100 {
+		t.Errorf(errExpectedX, 100, p.Size())
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package iterator provides the iterator interfaces used by all the data
+// structures in the library and a set of adapters (Map, Filter, Take, Skip,
+// Concat) to compose them.
+//
+// An iterator starts positioned before the first element, so the typical
+// usage is:
+//
+//	it := myList.Iterator()
+//	for it.Next() {
+//		fmt.Println(it.Value())
+//	}
+package iterator
+
+import "iter"
+
+// Iterator is a forward iterator.
+type Iterator[T any] interface {
+	// Next moves the iterator to the next element and returns false when
+	// there are no more elements.
+	Next() bool
+	// Value returns the element the iterator is positioned on.
+	Value() T
+}
+
+// BidirectionalIterator is an iterator that can also move backward.
+type BidirectionalIterator[T any] interface {
+	Iterator[T]
+	// Prev moves the iterator to the previous element and returns false when
+	// there are no more elements before the current one.
+	Prev() bool
+}
+
+// funcIterator is a forward iterator built on top of a "next" function
+type funcIterator[T any] struct {
+	next  func() (T, bool)
+	value T
+	done  bool
+}
+
+// FromFunc creates a forward iterator that calls next to produce each element.
+// next must return false when there are no more elements, after that it is
+// never called again.
+func FromFunc[T any](next func() (T, bool)) Iterator[T] {
+	return &funcIterator[T]{next: next}
+}
+
+// Next moves the iterator to the next element
+func (it *funcIterator[T]) Next() bool {
+	if it.done {
+		return false
+	}
+	value, ok := it.next()
+	if !ok {
+		var zero T
+		it.value = zero
+		it.done = true
+		return false
+	}
+	it.value = value
+	return true
+}
+
+// Value returns the current element
+func (it *funcIterator[T]) Value() T {
+	return it.value
+}
+
+// SliceIterator is a bidirectional iterator over a slice.
+type SliceIterator[T any] struct {
+	items []T
+	pos   int
+}
+
+// FromSlice creates a bidirectional iterator over the given slice.
+// The slice is not copied.
+func FromSlice[T any](items []T) *SliceIterator[T] {
+	return &SliceIterator[T]{items: items, pos: -1}
+}
+
+// Next moves the iterator to the next element
+func (it *SliceIterator[T]) Next() bool {
+	if it.pos < len(it.items) {
+		it.pos++
+	}
+	return it.pos < len(it.items)
+}
+
+// Prev moves the iterator to the previous element
+func (it *SliceIterator[T]) Prev() bool {
+	if it.pos >= 0 {
+		it.pos--
+	}
+	return it.pos >= 0
+}
+
+// Value returns the current element
+func (it *SliceIterator[T]) Value() T {
+	if it.pos < 0 || it.pos >= len(it.items) {
+		var zero T
+		return zero
+	}
+	return it.items[it.pos]
+}
+
+// Map returns an iterator that applies fn to every element of it.
+func Map[T, U any](it Iterator[T], fn func(T) U) Iterator[U] {
+	return FromFunc(func() (U, bool) {
+		if !it.Next() {
+			var zero U
+			return zero, false
+		}
+		return fn(it.Value()), true
+	})
+}
+
+// Filter returns an iterator over the elements of it that match the predicate.
+func Filter[T any](it Iterator[T], predicate func(T) bool) Iterator[T] {
+	return FromFunc(func() (T, bool) {
+		for it.Next() {
+			if value := it.Value(); predicate(value) {
+				return value, true
+			}
+		}
+		var zero T
+		return zero, false
+	})
+}
+
+// Take returns an iterator over the first n elements of it.
+func Take[T any](it Iterator[T], n uint64) Iterator[T] {
+	var taken uint64
+	return FromFunc(func() (T, bool) {
+		if taken >= n || !it.Next() {
+			var zero T
+			return zero, false
+		}
+		taken++
+		return it.Value(), true
+	})
+}
+
+// Skip returns an iterator over the elements of it after the first n.
+func Skip[T any](it Iterator[T], n uint64) Iterator[T] {
+	skipped := false
+	return FromFunc(func() (T, bool) {
+		if !skipped {
+			skipped = true
+			for i := uint64(0); i < n; i++ {
+				if !it.Next() {
+					var zero T
+					return zero, false
+				}
+			}
+		}
+		if !it.Next() {
+			var zero T
+			return zero, false
+		}
+		return it.Value(), true
+	})
+}
+
+// Concat returns an iterator over the elements of all the given iterators, one after the other.
+func Concat[T any](its ...Iterator[T]) Iterator[T] {
+	return FromFunc(func() (T, bool) {
+		for len(its) > 0 {
+			if its[0].Next() {
+				return its[0].Value(), true
+			}
+			its = its[1:]
+		}
+		var zero T
+		return zero, false
+	})
+}
+
+// ToSlice consumes the iterator and returns its remaining elements as a slice.
+func ToSlice[T any](it Iterator[T]) []T {
+	var items []T
+	for it.Next() {
+		items = append(items, it.Value())
+	}
+	return items
+}
+
+// Seq adapts the iterator to an iter.Seq, so it can be used in a for range loop.
+// Ranging over the returned sequence consumes the iterator.
+func Seq[T any](it Iterator[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for it.Next() {
+			if !yield(it.Value()) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package iterator provides the iterator interfaces and adapters.
+package iterator_test
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	iterator "github.com/pzaino/gods/pkg/iterator"
+)
+
+const (
+	errExpectedX = "Expected %v, but got %v"
+)
+
+func TestFromSlice(t *testing.T) {
+	it := iterator.FromSlice([]int{1, 2, 3})
+	if got := iterator.ToSlice[int](it); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf(errExpectedX, []int{1, 2, 3}, got)
+	}
+	if it.Next() {
+		t.Error("Expected Next to return false on an exhausted iterator")
+	}
+
+	// walk back
+	var back []int
+	for it.Prev() {
+		back = append(back, it.Value())
+	}
+	if !slices.Equal(back, []int{3, 2, 1}) {
+		t.Errorf(errExpectedX, []int{3, 2, 1}, back)
+	}
+	if it.Value() != 0 {
+		t.Errorf(errExpectedX, 0, it.Value())
+	}
+	if !it.Next() || it.Value() != 1 {
+		t.Errorf(errExpectedX, 1, it.Value())
+	}
+}
+
+func TestFromFunc(t *testing.T) {
+	calls := 0
+	it := iterator.FromFunc(func() (int, bool) {
+		calls++
+		if calls > 2 {
+			return 0, false
+		}
+		return calls, true
+	})
+	if got := iterator.ToSlice(it); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf(errExpectedX, []int{1, 2}, got)
+	}
+	if it.Next() {
+		t.Error("Expected Next to return false on an exhausted iterator")
+	}
+	if calls != 3 {
+		t.Errorf("Expected next to be called %d times, but it was called %d times", 3, calls)
+	}
+}
+
+func TestMapFilter(t *testing.T) {
+	it := iterator.Map(
+		iterator.Filter[int](iterator.FromSlice([]int{1, 2, 3, 4, 5, 6}), func(v int) bool { return v%2 == 0 }),
+		strconv.Itoa,
+	)
+	if got := iterator.ToSlice(it); !slices.Equal(got, []string{"2", "4", "6"}) {
+		t.Errorf(errExpectedX, []string{"2", "4", "6"}, got)
+	}
+}
+
+func TestTakeSkip(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	if got := iterator.ToSlice(iterator.Take[int](iterator.FromSlice(items), 2)); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf(errExpectedX, []int{1, 2}, got)
+	}
+	if got := iterator.ToSlice(iterator.Take[int](iterator.FromSlice(items), 10)); !slices.Equal(got, items) {
+		t.Errorf(errExpectedX, items, got)
+	}
+	if got := iterator.ToSlice(iterator.Skip[int](iterator.FromSlice(items), 3)); !slices.Equal(got, []int{4, 5}) {
+		t.Errorf(errExpectedX, []int{4, 5}, got)
+	}
+	if got := iterator.ToSlice(iterator.Skip[int](iterator.FromSlice(items), 10)); len(got) != 0 {
+		t.Errorf(errExpectedX, []int{}, got)
+	}
+
+	// Skip and Take compose to a window
+	window := iterator.Take(iterator.Skip[int](iterator.FromSlice(items), 1), 3)
+	if got := iterator.ToSlice(window); !slices.Equal(got, []int{2, 3, 4}) {
+		t.Errorf(errExpectedX, []int{2, 3, 4}, got)
+	}
+}
+
+func TestConcat(t *testing.T) {
+	it := iterator.Concat[int](
+		iterator.FromSlice([]int{1, 2}),
+		iterator.FromSlice[int](nil),
+		iterator.FromSlice([]int{3}),
+	)
+	if got := iterator.ToSlice(it); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf(errExpectedX, []int{1, 2, 3}, got)
+	}
+	if got := iterator.ToSlice(iterator.Concat[int]()); len(got) != 0 {
+		t.Errorf(errExpectedX, []int{}, got)
+	}
+}
+
+func TestSeq(t *testing.T) {
+	var got []int
+	for v := range iterator.Seq[int](iterator.FromSlice([]int{1, 2, 3})) {
+		if v == 3 {
+			break
+		}
+		got = append(got, v)
+	}
+	if !slices.Equal(got, []int{1, 2}) {
+		t.Errorf(errExpectedX, []int{1, 2}, got)
+	}
+}
@@ -15,11 +15,27 @@
 // Package linkList provides a non-concurrent-safe linked list.
 package linkList
 
-import "errors"
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"sync"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	arena "github.com/pzaino/gods/pkg/arena"
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+	iterator "github.com/pzaino/gods/pkg/iterator"
+)
 
 const (
 	ErrIndexOutOfBound = "index out of bounds"
 	ErrValueNotFound   = "value not found"
+	ErrNoCycle         = "list has no cycle"
 )
 
 // Node represents a node in the linked list
@@ -30,18 +46,61 @@ type Node[T comparable] struct {
 
 // LinkList represents a linked list
 type LinkList[T comparable] struct {
-	Head *Node[T]
-	size uint64
+	Head  *Node[T]
+	size  uint64
+	pool  *sync.Pool            // reused nodes (nil unless created with gods.WithNodePool)
+	arena *arena.Arena[Node[T]] // node allocator (nil unless created with NewWithArena)
+}
+
+// New creates a new LinkList.
+// The gods.WithNodePool option enables the reuse of the removed nodes.
+func New[T comparable](opts ...gods.Option) *LinkList[T] {
+	l := &LinkList[T]{}
+	if len(opts) > 0 && gods.NewOptions(opts...).NodePool {
+		l.pool = &sync.Pool{New: func() any { return new(Node[T]) }}
+	}
+	return l
 }
 
-// New creates a new LinkList
-func New[T comparable]() *LinkList[T] {
-	return &LinkList[T]{}
+// NewWithArena creates a new LinkList whose nodes are allocated from a. The
+// nodes are released only by a.Free(), all at once, so removing elements
+// doesn't free any memory; after a.Free() the list must not be used any more.
+func NewWithArena[T comparable](a *arena.Arena[Node[T]]) *LinkList[T] {
+	return &LinkList[T]{arena: a}
+}
+
+// derive returns a new empty list sharing the node pool (or arena) of l
+func (l *LinkList[T]) derive() *LinkList[T] {
+	return &LinkList[T]{pool: l.pool, arena: l.arena}
+}
+
+// newNode returns a node with the given value, from the pool or the arena if
+// there is one
+func (l *LinkList[T]) newNode(value T) *Node[T] {
+	if l.arena != nil {
+		node := l.arena.Alloc()
+		node.Value = value
+		return node
+	}
+	if l.pool == nil {
+		return &Node[T]{Value: value}
+	}
+	node := l.pool.Get().(*Node[T])
+	node.Value = value
+	return node
+}
+
+// freeNode returns a removed node to the pool (if there is one)
+func (l *LinkList[T]) freeNode(node *Node[T]) {
+	if l.pool != nil {
+		*node = Node[T]{}
+		l.pool.Put(node)
+	}
 }
 
 // NewFromSlice creates a new LinkList from a slice
-func NewFromSlice[T comparable](items []T) *LinkList[T] {
-	l := New[T]()
+func NewFromSlice[T comparable](items []T, opts ...gods.Option) *LinkList[T] {
+	l := New[T](opts...)
 	for i := 0; i < len(items); i++ {
 		l.Append(items[i])
 	}
@@ -50,7 +109,7 @@ func NewFromSlice[T comparable](items []T) *LinkList[T] {
 
 // Append adds a new node to the end of the list
 func (l *LinkList[T]) Append(value T) {
-	newNode := &Node[T]{Value: value}
+	newNode := l.newNode(value)
 
 	if l.Head == nil {
 		l.Head = newNode
@@ -69,7 +128,7 @@ func (l *LinkList[T]) Append(value T) {
 
 // Prepend adds a new node to the beginning of the list
 func (l *LinkList[T]) Prepend(value T) {
-	newNode := &Node[T]{Value: value}
+	newNode := l.newNode(value)
 
 	newNode.Next = l.Head
 	l.Head = newNode
@@ -83,16 +142,20 @@ func (l *LinkList[T]) DeleteWithValue(value T) {
 	}
 
 	if l.Head.Value == value {
+		removed := l.Head
 		l.Head = l.Head.Next
 		l.size--
+		l.freeNode(removed)
 		return
 	}
 
 	current := l.Head
 	for current.Next != nil {
 		if current.Next.Value == value {
+			removed := current.Next
 			current.Next = current.Next.Next
 			l.size--
+			l.freeNode(removed)
 			return
 		}
 		current = current.Next
@@ -118,6 +181,12 @@ func (l *LinkList[T]) ToSlice() []T {
 	return result
 }
 
+// Freeze returns an immutable copy of the list, which can be
+// shared without risking changes to it (see gods.Frozen).
+func (l *LinkList[T]) Freeze() *gods.Frozen[T] {
+	return gods.NewFrozen(l.ToSlice())
+}
+
 // IsEmpty checks if the list is empty
 func (l *LinkList[T]) IsEmpty() bool {
 	return l.Head == nil
@@ -136,7 +205,8 @@ func (l *LinkList[T]) Find(value T) (*Node[T], error) {
 	return nil, errors.New(ErrValueNotFound)
 }
 
-// Reverse reverses the list
+// Reverse reverses the list in place, relinking its nodes (the nodes and their
+// values are not moved or copied)
 func (l *LinkList[T]) Reverse() {
 	var prev *Node[T]
 	current := l.Head
@@ -151,11 +221,120 @@ func (l *LinkList[T]) Reverse() {
 	l.Head = prev
 }
 
+// Rotate rotates the list left by n positions, so the node at index n
+// becomes the head (a negative n rotates the list right). The nodes are
+// relinked in place, without allocating or copying their values.
+func (l *LinkList[T]) Rotate(n int) {
+	if l.size < 2 {
+		return
+	}
+	size := int(l.size)
+	k := ((n % size) + size) % size
+	if k == 0 {
+		return
+	}
+	newTail := l.Head
+	for i := 1; i < k; i++ {
+		newTail = newTail.Next
+	}
+	last := newTail
+	for last.Next != nil {
+		last = last.Next
+	}
+	last.Next = l.Head
+	l.Head = newTail.Next
+	newTail.Next = nil
+}
+
 // Size returns the number of nodes in the list
 func (l *LinkList[T]) Size() uint64 {
 	return l.size
 }
 
+// MemoryUsage returns an estimate of the bytes held by the list and its nodes
+// (see the gods package), adding the memory returned by extra for every item.
+// The nodes kept by the node pool or by the arena are not included.
+func (l *LinkList[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
+	return uint64(unsafe.Sizeof(*l)) + gods.NodesMemory[Node[T]](l.size) + gods.ExtraMemory(l.Iter(), extra...)
+}
+
+// Validate checks the invariants of the list: the number of nodes reachable from
+// the head (without cycles) (see gods.Validator).
+func (l *LinkList[T]) Validate() error {
+	var count uint64
+	for n := l.Head; n != nil; n = n.Next {
+		if count++; count > l.size {
+			return gods.Invariantf("more than size (%d) nodes reachable from the head, or a cycle", l.size)
+		}
+	}
+	if count != l.size {
+		return gods.Invariantf("size is %d but %d nodes are reachable from the head", l.size, count)
+	}
+	return nil
+}
+
+// HasCycle returns true if the nodes reachable from the head form a cycle
+// (Floyd's algorithm, in O(n) time and O(1) memory). A list can only have a
+// cycle if its nodes were linked by hand.
+func (l *LinkList[T]) HasCycle() bool {
+	return l.cycleStart() != nil
+}
+
+// BreakCycle breaks the cycle of the list, unlinking the node that links
+// back to the first node of the cycle, and recalculates the size of the list.
+// It returns an ErrNoCycle error if the list has no cycle.
+func (l *LinkList[T]) BreakCycle() error {
+	start := l.cycleStart()
+	if start == nil {
+		return errors.New(ErrNoCycle)
+	}
+	last := start
+	for last.Next != start {
+		last = last.Next
+	}
+	last.Next = nil
+	l.CheckSize()
+	return nil
+}
+
+// cycleStart returns the first node of the cycle of the list, or nil if the
+// list has no cycle
+func (l *LinkList[T]) cycleStart() *Node[T] {
+	slow, fast := l.Head, l.Head
+	for fast != nil && fast.Next != nil {
+		slow, fast = slow.Next, fast.Next.Next
+		if slow == fast {
+			// the distance from the head to the start of the cycle is the
+			// same as from the meeting point
+			slow = l.Head
+			for slow != fast {
+				slow, fast = slow.Next, fast.Next
+			}
+			return slow
+		}
+	}
+	return nil
+}
+
+// Dump writes the chain of the list to w, for debugging. It stops after size
+// nodes, so it terminates on a list with a cycle too.
+func (l *LinkList[T]) Dump(w io.Writer) error {
+	d := gods.NewDumper(w)
+	d.Line(0, "LinkList size=%d", l.size)
+	var chain []string
+	n := l.Head
+	for i := uint64(0); n != nil && i < l.size; i++ {
+		chain = append(chain, fmt.Sprint(n.Value))
+		n = n.Next
+	}
+	end := "nil"
+	if n != nil {
+		end = "... (more nodes than size)"
+	}
+	d.Line(1, "head -> %s", strings.Join(append(chain, end), " -> "))
+	return d.Err()
+}
+
 // CheckSize recalculates the size of the list
 func (l *LinkList[T]) CheckSize() {
 	var size uint64
@@ -243,7 +422,7 @@ func (l *LinkList[T]) InsertAt(index uint64, value T) error {
 		return errors.New(ErrIndexOutOfBound)
 	}
 
-	newNode := &Node[T]{Value: value}
+	newNode := l.newNode(value)
 	newNode.Next = current.Next
 	current.Next = newNode
 
@@ -260,8 +439,10 @@ func (l *LinkList[T]) DeleteAt(index uint64) error {
 		if l.Head == nil {
 			return errors.New(ErrIndexOutOfBound)
 		}
+		removed := l.Head
 		l.Head = l.Head.Next
 		l.size--
+		l.freeNode(removed)
 		return nil
 	}
 
@@ -277,8 +458,10 @@ func (l *LinkList[T]) DeleteAt(index uint64) error {
 		return errors.New(ErrIndexOutOfBound)
 	}
 
+	removed := current.Next
 	current.Next = current.Next.Next
 	l.size--
+	l.freeNode(removed)
 
 	return nil
 }
@@ -290,13 +473,20 @@ func (l *LinkList[T]) Remove(value T) {
 
 // Clear removes all nodes from the list
 func (l *LinkList[T]) Clear() {
+	if l.pool != nil {
+		for current := l.Head; current != nil; {
+			next := current.Next
+			l.freeNode(current)
+			current = next
+		}
+	}
 	l.Head = nil
 	l.size = 0
 }
 
 // Copy returns a copy of the list
 func (l *LinkList[T]) Copy() *LinkList[T] {
-	newList := New[T]()
+	newList := l.derive()
 
 	current := l.Head
 	for current != nil {
@@ -319,9 +509,47 @@ func (l *LinkList[T]) Merge(list *LinkList[T]) {
 	list.Clear()
 }
 
+// MergeSorted merges other into the list in O(n+m), both being sorted by
+// less, keeping the list sorted: the nodes of other are relinked into the
+// list (unless the lists use different arenas, then their values are copied
+// into new nodes), and other is left empty. On ties the nodes of the list
+// come first, so the merge is stable.
+func (l *LinkList[T]) MergeSorted(other *LinkList[T], less cmpx.Less[T]) {
+	if l == other || other.Head == nil {
+		return
+	}
+	var head Node[T] // the node before the head of the merged list
+	tail := &head
+	a, b := l.Head, other.Head
+	for a != nil || b != nil {
+		if b == nil || (a != nil && !less(b.Value, a.Value)) {
+			tail.Next, a = a, a.Next
+		} else {
+			next := b.Next
+			tail.Next, b = l.adopt(other, b), next
+		}
+		tail = tail.Next
+	}
+	tail.Next = nil
+	l.Head = head.Next
+	l.size += other.size
+	other.Head, other.size = nil, 0
+}
+
+// adopt returns n, a node of other, as a node of l: n itself if the lists
+// allocate their nodes in the same way, or a copy
+func (l *LinkList[T]) adopt(other *LinkList[T], n *Node[T]) *Node[T] {
+	if l.arena == other.arena {
+		return n
+	}
+	node := l.newNode(n.Value)
+	other.freeNode(n)
+	return node
+}
+
 // Map generates a new list by applying the function to all the nodes in the list
 func (l *LinkList[T]) Map(f func(T) T) *LinkList[T] {
-	newList := New[T]()
+	newList := l.derive()
 	current := l.Head
 	for current != nil {
 		newList.Append(f(current.Value))
@@ -336,7 +564,7 @@ func (l *LinkList[T]) MapFrom(start uint64, f func(T) T) (*LinkList[T], error) {
 		return nil, errors.New(ErrIndexOutOfBound)
 	}
 
-	newList := New[T]()
+	newList := l.derive()
 	current, err := l.GetAt(start)
 	if err != nil {
 		return nil, err
@@ -360,7 +588,7 @@ func (l *LinkList[T]) MapRange(start, end uint64, f func(T) T) (*LinkList[T], er
 		return nil, errors.New(ErrIndexOutOfBound)
 	}
 
-	newList := New[T]()
+	newList := l.derive()
 	current, err := l.GetAt(start)
 	if err != nil {
 		return nil, err
@@ -383,15 +611,19 @@ func (l *LinkList[T]) Filter(f func(T) bool) {
 
 	// Move the head to the first node that matches the predicate
 	for l.Head != nil && !f(l.Head.Value) {
+		removed := l.Head
 		l.Head = l.Head.Next
+		l.freeNode(removed)
 	}
 
 	// Proceed with the rest of the list
 	current := l.Head
 	for current != nil && current.Next != nil {
 		if !f(current.Next.Value) {
+			removed := current.Next
 			current.Next = current.Next.Next
 			l.size--
+			l.freeNode(removed)
 		} else {
 			current = current.Next
 		}
@@ -587,7 +819,7 @@ func (l *LinkList[T]) FindLastIndex(f func(T) bool) (uint64, error) {
 
 // FindAll returns all nodes that match the predicate
 func (l *LinkList[T]) FindAll(f func(T) bool) *LinkList[T] {
-	newList := New[T]()
+	newList := l.derive()
 
 	current := l.Head
 	for current != nil {
@@ -635,3 +867,228 @@ func (l *LinkList[T]) FindAllIndexes(f func(T) bool) []uint64 {
 
 	return result
 }
+
+// Iter returns an iterator over the values in the list, from the head to the tail
+func (l *LinkList[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for current := l.Head; current != nil; current = current.Next {
+			if !yield(current.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Iterator returns an iterator over the values in the list, from the head to the tail
+func (l *LinkList[T]) Iterator() iterator.Iterator[T] {
+	current := l.Head
+	return iterator.FromFunc(func() (T, bool) {
+		if current == nil {
+			var zero T
+			return zero, false
+		}
+		value := current.Value
+		current = current.Next
+		return value, true
+	})
+}
+
+// Enumerate returns an iterator over the index and the value of the nodes in the list
+func (l *LinkList[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		i := uint64(0)
+		for current := l.Head; current != nil; current = current.Next {
+			if !yield(i, current.Value) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (l *LinkList[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(l.Enumerate())
+}
+
+// Values returns an iterator over the items, in the same order as Enumerate
+// (it is Iter, under the name used by the standard library).
+func (l *LinkList[T]) Values() iter.Seq[T] {
+	return l.Iter()
+}
+
+// Encode writes the list to w using the given codec
+func (l *LinkList[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeSlice(w, codec, l.ToSlice())
+}
+
+// Decode replaces the content of the list with the elements read from r using the given codec
+func (l *LinkList[T]) Decode(r io.Reader, codec gods.Codec) error {
+	items, err := gods.DecodeSlice[T](r, codec)
+	if err != nil {
+		return err
+	}
+	l.Clear()
+	for _, item := range items {
+		l.Append(item)
+	}
+	return nil
+}
+
+// EncodeStream writes the list to w incrementally, in chunks encoded with the
+// given codec (see gods.EncodeStream), without copying its elements first.
+func (l *LinkList[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeStream(w, codec, l.Iter())
+}
+
+// DecodeStream replaces the content of the list with the elements of a
+// stream written by EncodeStream, adding them as they are read.
+func (l *LinkList[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	l.Clear()
+	return gods.DecodeStream(r, codec, func(item T) error {
+		l.Append(item)
+		return nil
+	})
+}
+
+// MarshalText implements encoding.TextMarshaler: the list is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (l *LinkList[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(l)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (l *LinkList[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(l, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText.
+// It has a value receiver, so a list stored by value in a struct field is
+// encoded as an array even when the struct is marshaled by value (and its
+// elements use their own marshalers, if they have any).
+func (l LinkList[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(&l)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (l *LinkList[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(l, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary),
+// which gob uses to encode the list. Like MarshalJSON it has a value receiver.
+func (l LinkList[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(&l)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (l *LinkList[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(l, data)
+}
+
+// MarshalYAML implements the Marshaler interface of the YAML packages
+// (gopkg.in/yaml.v2 and v3): the list is encoded as a sequence, in order.
+func (l *LinkList[T]) MarshalYAML() (any, error) {
+	return l.ToSlice(), nil
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of gopkg.in/yaml.v2
+// (also honored by v3): the content of the list is replaced with the elements
+// of a YAML sequence.
+func (l *LinkList[T]) UnmarshalYAML(unmarshal func(any) error) error {
+	var items []T
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+	l.Clear()
+	for _, item := range items {
+		l.Append(item)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer: the list is stored as a JSON array, so it
+// can be saved in a JSON (or JSONB) column.
+func (l *LinkList[T]) Value() (driver.Value, error) {
+	return gods.ValueSlice(l.ToSlice())
+}
+
+// Scan implements sql.Scanner: the content of the list is replaced with the
+// elements of the JSON array read from the database (NULL is an empty list).
+func (l *LinkList[T]) Scan(src any) error {
+	items, err := gods.ScanSlice[T](src)
+	if err != nil {
+		return err
+	}
+	l.Clear()
+	for _, item := range items {
+		l.Append(item)
+	}
+	return nil
+}
+
+// FilterCtx is like Filter but it stops as soon as the context is done,
+// returning ctx.Err(). In that case the list is left unchanged.
+func (l *LinkList[T]) FilterCtx(ctx context.Context, f func(T) bool) error {
+	// evaluate the predicate first, so a cancellation leaves the list untouched
+	var keep []bool
+	for current := l.Head; current != nil; current = current.Next {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		keep = append(keep, f(current.Value))
+	}
+	// then unlink the nodes that don't match
+	var prev *Node[T]
+	i := 0
+	for current := l.Head; current != nil; {
+		next := current.Next
+		if keep[i] {
+			prev = current
+		} else {
+			if prev == nil {
+				l.Head = next
+			} else {
+				prev.Next = next
+			}
+			l.size--
+			l.freeNode(current)
+		}
+		current = next
+		i++
+	}
+	return nil
+}
+
+// MapCtx is like Map but it stops as soon as the context is done, returning ctx.Err().
+func (l *LinkList[T]) MapCtx(ctx context.Context, f func(T) T) (*LinkList[T], error) {
+	newList := l.derive()
+	var tail *Node[T]
+	for current := l.Head; current != nil; current = current.Next {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		node := newList.newNode(f(current.Value))
+		if tail == nil {
+			newList.Head = node
+		} else {
+			tail.Next = node
+		}
+		tail = node
+		newList.size++
+	}
+	return newList, nil
+}
+
+// ForEachCtx is like ForEach but it stops as soon as the context is done,
+// returning ctx.Err().
+func (l *LinkList[T]) ForEachCtx(ctx context.Context, f func(*T)) error {
+	for current := l.Head; current != nil; current = current.Next {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f(&current.Value)
+	}
+	return nil
+}
@@ -16,9 +16,19 @@
 package linkList_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
 
+	gods "github.com/pzaino/gods"
+	arena "github.com/pzaino/gods/pkg/arena"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 	linkList "github.com/pzaino/gods/pkg/linkList"
 )
 
@@ -33,6 +43,7 @@ const (
 	errExpectedErr         = "Expected an error, but got nil"
 	errExpectedSliceElem   = "Expected slice element %d to be %d, but got %d"
 	errExpectedNodeValue   = "Expected node value to be %v, but got %v"
+	errExpectedX           = "Expected %v, but got %v"
 )
 
 func TestNew(t *testing.T) {
@@ -1242,3 +1253,358 @@ func TestCheckSize(t *testing.T) {
 		t.Errorf(errExpectedItems, 0, list.Size())
 	}
 }
+
+func TestIter(t *testing.T) {
+	list := linkList.NewFromSlice([]int{1, 2, 3})
+	if got := slices.Collect(list.Iter()); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, got)
+	}
+	for v := range list.Iter() {
+		if v != 1 {
+			t.Errorf("Expected %v, got %v", 1, v)
+		}
+		break
+	}
+	if got := slices.Collect(linkList.New[int]().Iter()); len(got) != 0 {
+		t.Errorf("Expected empty iteration, got %v", got)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	list := linkList.NewFromSlice([]int{1, 2, 3})
+	it := iterator.Filter(list.Iterator(), func(v int) bool { return v != 2 })
+	if got := iterator.ToSlice(it); !slices.Equal(got, []int{1, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 3}, got)
+	}
+	if linkList.New[int]().Iterator().Next() {
+		t.Error("Expected empty iteration")
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	list := linkList.NewFromSlice([]int{1, 2, 3})
+	var indexes []uint64
+	var values []int
+	for i, v := range list.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0, 1, 2}) {
+		t.Errorf("Expected %v, got %v", []uint64{0, 1, 2}, indexes)
+	}
+	if !slices.Equal(values, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, values)
+	}
+	if got := slices.Collect(list.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+	if got := slices.Collect(list.Values()); !slices.Equal(got, values) {
+		t.Errorf("Expected %v, got %v", values, got)
+	}
+}
+
+func TestCtxVariants(t *testing.T) {
+	list := linkList.NewFromSlice([]int{1, 2, 3, 4, 5})
+
+	if err := list.FilterCtx(context.Background(), func(v int) bool { return v%2 == 1 }); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := list.ToSlice(); !slices.Equal(got, []int{1, 3, 5}) || list.Size() != 3 {
+		t.Errorf("Expected %v, got %v", []int{1, 3, 5}, got)
+	}
+
+	mapped, err := list.MapCtx(context.Background(), func(v int) int { return v + 1 })
+	if err != nil || !slices.Equal(mapped.ToSlice(), []int{2, 4, 6}) || mapped.Size() != 3 {
+		t.Errorf("Expected %v, got %v", []int{2, 4, 6}, mapped.ToSlice())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = list.FilterCtx(ctx, func(v int) bool {
+		if v == 3 {
+			cancel()
+		}
+		return false
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	if got := list.ToSlice(); !slices.Equal(got, []int{1, 3, 5}) {
+		t.Errorf("Expected the list to be unchanged, got %v", got)
+	}
+	if err := list.ForEachCtx(ctx, func(*int) {}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestNodePool(t *testing.T) {
+	l := linkList.NewFromSlice([]int{1, 2, 3, 4, 5}, gods.WithNodePool())
+	var model []int
+	for i := 1; i <= 5; i++ {
+		model = append(model, i)
+	}
+	// heavy churn: removed nodes are reused by the following inserts
+	for i := 0; i < 100; i++ {
+		l.DeleteWithValue(model[0])
+		model = model[1:]
+		l.Prepend(i)
+		model = append([]int{i}, model...)
+		if err := l.DeleteAt(l.Size() - 1); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+		model = model[:len(model)-1]
+		l.Prepend(-i)
+		model = append([]int{-i}, model...)
+	}
+	if !slices.Equal(l.ToSlice(), model) {
+		t.Errorf(errExpectedX, model, l.ToSlice())
+	}
+
+	// derived lists share the pool but not the nodes
+	c := l.Copy()
+	l.Filter(func(v int) bool { return v > 0 })
+	if !slices.Equal(c.ToSlice(), model) {
+		t.Errorf(errExpectedX, model, c.ToSlice())
+	}
+	c.Clear()
+	if !c.IsEmpty() || c.Size() != 0 {
+		t.Errorf(errListNotEmpty)
+	}
+	for _, v := range l.ToSlice() {
+		if v <= 0 {
+			t.Errorf(errExpectedX, "positive values", l.ToSlice())
+		}
+	}
+}
+
+func TestNewWithArena(t *testing.T) {
+	a := arena.New[linkList.Node[int]](4)
+	l := linkList.NewWithArena(a)
+	for i := 0; i < 10; i++ {
+		l.Prepend(i)
+	}
+	m := l.Map(func(v int) int { return v * 2 })
+	if a.Len() != 20 {
+		t.Errorf(errExpectedX, 20, a.Len())
+	}
+	l.Filter(func(v int) bool { return v%2 == 0 })
+	if !slices.Equal(l.ToSlice(), []int{8, 6, 4, 2, 0}) {
+		t.Errorf(errExpectedX, []int{8, 6, 4, 2, 0}, l.ToSlice())
+	}
+	if m.Size() != 10 || m.GetFirst().Value != 18 {
+		t.Errorf(errExpectedItems, 10, m.Size())
+	}
+	a.Free()
+	if a.Len() != 0 {
+		t.Errorf(errExpectedX, 0, a.Len())
+	}
+}
+
+func TestYAML(t *testing.T) {
+	list := linkList.New[int]()
+	list.Append(3)
+	list.Append(1)
+	v, err := list.MarshalYAML()
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if items, ok := v.([]int); !ok || !slices.Equal(items, []int{3, 1}) {
+		t.Errorf("Expected %v, but got %v", []int{3, 1}, v)
+	}
+
+	// the YAML packages call UnmarshalYAML with a function decoding the
+	// node into the given value
+	unmarshal := func(v any) error {
+		return json.Unmarshal([]byte("[1, 2, 3]"), v)
+	}
+	if err := list.UnmarshalYAML(unmarshal); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if got := list.ToSlice(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, but got %v", []int{1, 2, 3}, got)
+	}
+}
+
+func TestMemoryUsage(t *testing.T) {
+	list := linkList.New[int]()
+	empty := list.MemoryUsage()
+	list.Append(1)
+	node := list.MemoryUsage() - empty
+	list.Append(2)
+	list.Append(3)
+	if got := list.MemoryUsage(); got != empty+3*node {
+		t.Errorf("Expected %v, but got %v", empty+3*node, got)
+	}
+	if got := list.MemoryUsage(func(int) uint64 { return 8 }); got != empty+3*node+24 {
+		t.Errorf("Expected %v, but got %v", empty+3*node+24, got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	list := linkList.NewFromSlice([]int{1, 2, 3})
+	if err := list.Validate(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	// a cycle through the exported links
+	list.Head.Next.Next.Next = list.Head
+	err := list.Validate()
+	if err == nil || !strings.HasPrefix(err.Error(), gods.ErrInvariantViolated) {
+		t.Errorf("Expected %v, but got %v", gods.ErrInvariantViolated, err)
+	}
+	list.Head.Next.Next = nil
+	if err := list.Validate(); err == nil {
+		t.Errorf("Expected an error for a list shorter than its size")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	list := linkList.NewFromSlice([]int{1, 2, 3, 4, 5})
+	head := list.GetFirst()
+	list.Rotate(2)
+	if got := list.ToSlice(); !slices.Equal(got, []int{3, 4, 5, 1, 2}) {
+		t.Errorf("Expected %v, but got %v", []int{3, 4, 5, 1, 2}, got)
+	}
+	list.Rotate(-7)
+	if got := list.ToSlice(); !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Expected %v, but got %v", []int{1, 2, 3, 4, 5}, got)
+	}
+	// the nodes are relinked, not copied
+	if list.GetFirst() != head {
+		t.Errorf("Expected the head to be the same node")
+	}
+	if err := list.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	list := linkList.NewFromSlice([]int{1, 2, 3, 4, 5})
+	if list.HasCycle() {
+		t.Errorf("Expected no cycle")
+	}
+	if err := list.BreakCycle(); err == nil || err.Error() != linkList.ErrNoCycle {
+		t.Errorf("Expected %v, but got %v", linkList.ErrNoCycle, err)
+	}
+
+	// link the last node back to the third one
+	third, _ := list.GetAt(2)
+	last := list.GetLast()
+	last.Next = third
+	if !list.HasCycle() {
+		t.Fatalf("Expected a cycle")
+	}
+	if err := list.BreakCycle(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if list.HasCycle() || last.Next != nil {
+		t.Errorf("Expected the cycle to be broken after the last node")
+	}
+	if got := list.ToSlice(); !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Expected %v, but got %v", []int{1, 2, 3, 4, 5}, got)
+	}
+
+	// a node linked to itself
+	single := linkList.NewFromSlice([]int{1})
+	single.Head.Next = single.Head
+	if !single.HasCycle() {
+		t.Fatalf("Expected a cycle")
+	}
+	if err := single.BreakCycle(); err != nil || single.Size() != 1 {
+		t.Errorf("Expected the cycle to be broken, got %v", err)
+	}
+}
+
+func TestMergeSorted(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+	list := linkList.NewFromSlice([]int{1, 4, 4, 9})
+	other := linkList.NewFromSlice([]int{0, 2, 4, 10, 11})
+	first, _ := other.GetAt(0)
+	list.MergeSorted(other, less)
+	want := []int{0, 1, 2, 4, 4, 4, 9, 10, 11}
+	if got := list.ToSlice(); !slices.Equal(got, want) {
+		t.Errorf("Expected %v, but got %v", want, got)
+	}
+	if list.GetFirst() != first {
+		t.Errorf("Expected the nodes of other to be relinked")
+	}
+	if !other.IsEmpty() || other.Size() != 0 {
+		t.Errorf("Expected other to be empty")
+	}
+	if err := list.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+
+	// lists with different arenas copy the values
+	a := linkList.NewWithArena(arena.New[linkList.Node[int]](4))
+	a.Append(5)
+	list.MergeSorted(a, less)
+	if got := list.ToSlice(); !slices.Equal(got, []int{0, 1, 2, 4, 4, 4, 5, 9, 10, 11}) {
+		t.Errorf("Expected 5 to be merged, but got %v", got)
+	}
+	if err := list.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+}
+
+// money has custom JSON and gob encodings, which the lists must honor
+type money struct{ cents int64 }
+
+func (m money) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("\"%d.%02d\"", m.cents/100, m.cents%100)), nil
+}
+
+func (m *money) UnmarshalJSON(data []byte) error {
+	var units, cents int64
+	if _, err := fmt.Sscanf(string(data), "\"%d.%d\"", &units, &cents); err != nil {
+		return err
+	}
+	m.cents = units*100 + cents
+	return nil
+}
+
+func (m money) GobEncode() ([]byte, error) {
+	return []byte(fmt.Sprint(m.cents)), nil
+}
+
+func (m *money) GobDecode(data []byte) error {
+	_, err := fmt.Sscan(string(data), &m.cents)
+	return err
+}
+
+func TestMarshalCustomElements(t *testing.T) {
+	type order struct {
+		Ptr *linkList.LinkList[money]
+		Val linkList.LinkList[money]
+	}
+	src := order{Ptr: linkList.New[money]()}
+	src.Ptr.Append(money{150})
+	src.Ptr.Append(money{2})
+	src.Val.Append(money{1999})
+
+	// the struct is marshaled by value: the list field must still be an array
+	data, err := json.Marshal(src)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if want := `{"Ptr":["1.50","0.02"],"Val":["19.99"]}`; string(data) != want {
+		t.Errorf(errExpectedX, want, string(data))
+	}
+	var dst order
+	if err := json.Unmarshal(data, &dst); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if !slices.Equal(dst.Ptr.ToSlice(), src.Ptr.ToSlice()) || !slices.Equal(dst.Val.ToSlice(), src.Val.ToSlice()) {
+		t.Errorf(errExpectedX, src, dst)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(src); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	dst = order{}
+	if err := gob.NewDecoder(&buf).Decode(&dst); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if !slices.Equal(dst.Ptr.ToSlice(), src.Ptr.ToSlice()) || !slices.Equal(dst.Val.ToSlice(), src.Val.ToSlice()) {
+		t.Errorf(errExpectedX, src, dst)
+	}
+}
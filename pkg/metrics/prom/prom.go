@@ -0,0 +1,202 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prom exposes the metrics of gods containers to Prometheus.
+//
+// It has no dependencies: a Collector is an http.Handler serving the
+// Prometheus text exposition format, to be scraped on its own path (or
+// appended to the output of another handler with WriteTo).
+package prom
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	gods "github.com/pzaino/gods"
+)
+
+const (
+	ErrInvalidName   = "invalid metric or label name"
+	ErrNameInUse     = "a container is already registered with this name"
+	ErrReservedLabel = "the container and mode labels are reserved"
+)
+
+// contentType is the content type of the text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var validName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Collector gathers the metrics of the registered containers (see
+// gods.ReadMetrics) every time it is scraped.
+type Collector struct {
+	namespace string
+
+	mu         sync.Mutex
+	containers []registered
+}
+
+// registered is a container registered in a Collector
+type registered struct {
+	name   string
+	labels string // the rendered labels, container first
+	c      gods.Sizer
+}
+
+// NewCollector returns a Collector whose metric names start with namespace
+// (for example "myapp" gives "myapp_container_size"). It returns an error if
+// namespace is not a valid metric name.
+func NewCollector(namespace string) (*Collector, error) {
+	if !validName.MatchString(namespace) {
+		return nil, errors.New(ErrInvalidName)
+	}
+	return &Collector{namespace: namespace}, nil
+}
+
+// Register adds c to the collected containers. Its metrics have the label
+// container set to name plus the given labels (for example the tenant or the
+// shard); the labels container and mode are reserved.
+func (col *Collector) Register(name string, c gods.Sizer, labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if !validName.MatchString(k) || strings.HasPrefix(k, "__") {
+			return errors.New(ErrInvalidName)
+		}
+		if k == "container" || k == "mode" {
+			return errors.New(ErrReservedLabel)
+		}
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var sb strings.Builder
+	sb.WriteString(`container="` + escape(name) + `"`)
+	for _, k := range keys {
+		sb.WriteString(`,` + k + `="` + escape(labels[k]) + `"`)
+	}
+
+	col.mu.Lock()
+	defer col.mu.Unlock()
+	for _, r := range col.containers {
+		if r.name == name {
+			return errors.New(ErrNameInUse)
+		}
+	}
+	col.containers = append(col.containers, registered{name: name, labels: sb.String(), c: c})
+	return nil
+}
+
+// Unregister removes the container registered with name.
+func (col *Collector) Unregister(name string) {
+	col.mu.Lock()
+	defer col.mu.Unlock()
+	col.containers = slices.DeleteFunc(col.containers, func(r registered) bool { return r.name == name })
+}
+
+// sample is a value of a metric for a container
+type sample struct {
+	labels string
+	value  float64
+}
+
+// family is a metric with its samples
+type family struct {
+	name, kind, help string
+	samples          []sample
+}
+
+// gather reads the metrics of all the containers
+func (col *Collector) gather() []*family {
+	col.mu.Lock()
+	containers := slices.Clone(col.containers)
+	col.mu.Unlock()
+
+	prefix := col.namespace + "_container_"
+	size := &family{name: prefix + "size", kind: "gauge", help: "Number of elements in the container."}
+	capacity := &family{name: prefix + "capacity", kind: "gauge", help: "Capacity of the container."}
+	acquisitions := &family{name: prefix + "lock_acquisitions_total", kind: "counter", help: "Number of lock acquisitions (operations) by mode."}
+	wait := &family{name: prefix + "lock_wait_seconds_total", kind: "counter", help: "Time spent waiting for the lock by mode."}
+	hold := &family{name: prefix + "lock_hold_seconds_total", kind: "counter", help: "Time the lock was held by mode."}
+	maxWait := &family{name: prefix + "lock_max_wait_seconds", kind: "gauge", help: "Longest wait for the lock."}
+	for _, r := range containers {
+		m := gods.ReadMetrics(r.c)
+		size.samples = append(size.samples, sample{r.labels, float64(m.Size)})
+		if m.Capacity != nil {
+			capacity.samples = append(capacity.samples, sample{r.labels, float64(*m.Capacity)})
+		}
+		if s := m.Lock; s != nil {
+			write, read := r.labels+`,mode="write"`, r.labels+`,mode="read"`
+			acquisitions.samples = append(acquisitions.samples,
+				sample{write, float64(s.Acquisitions)}, sample{read, float64(s.ReadAcquisitions)})
+			wait.samples = append(wait.samples,
+				sample{write, s.WaitTime.Seconds()}, sample{read, s.ReadWaitTime.Seconds()})
+			hold.samples = append(hold.samples,
+				sample{write, s.HoldTime.Seconds()}, sample{read, s.ReadHoldTime.Seconds()})
+			maxWait.samples = append(maxWait.samples, sample{r.labels, s.MaxWaitTime.Seconds()})
+		}
+	}
+	return []*family{size, capacity, acquisitions, wait, hold, maxWait}
+}
+
+// WriteTo writes the metrics of the registered containers to w in the
+// Prometheus text exposition format.
+func (col *Collector) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, f := range col.gather() {
+		if len(f.samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		for _, s := range f.samples {
+			fmt.Fprintf(cw, "%s{%s} %g\n", f.name, s.labels, s.value)
+		}
+	}
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+// ServeHTTP serves the metrics to a Prometheus scraper.
+func (col *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	_, _ = col.WriteTo(w)
+}
+
+// countingWriter counts the bytes written and keeps the first error
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// escape escapes a label value
+func escape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
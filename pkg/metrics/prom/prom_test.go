@@ -0,0 +1,94 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prom_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	buffer "github.com/pzaino/gods/pkg/buffer"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+	prom "github.com/pzaino/gods/pkg/metrics/prom"
+)
+
+const (
+	errExpectedX     = "Expected %v, but got %v"
+	errUnexpectedErr = "Unexpected error: %v"
+)
+
+func TestCollector(t *testing.T) {
+	col, err := prom.NewCollector("app")
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	s := csstack.New[int](gods.WithContentionStats())
+	s.Push(1)
+	s.Push(2)
+	b := buffer.New[int]()
+	b.SetCapacity(8)
+	if err := col.Register("jobs", s, map[string]string{"tenant": `a"b`}); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if err := col.Register("buf", b, nil); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+
+	rec := httptest.NewRecorder()
+	col.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE app_container_size gauge\n",
+		`app_container_size{container="jobs",tenant="a\"b"} 2` + "\n",
+		`app_container_size{container="buf"} 0` + "\n",
+		`app_container_capacity{container="buf"} 8` + "\n",
+		`app_container_lock_acquisitions_total{container="jobs",tenant="a\"b",mode="write"} 2` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf(errExpectedX, want, out)
+		}
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf(errExpectedX, "the text exposition format", rec.Header().Get("Content-Type"))
+	}
+
+	col.Unregister("jobs")
+	var sb strings.Builder
+	if _, err := col.WriteTo(&sb); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if strings.Contains(sb.String(), "jobs") || strings.Contains(sb.String(), "lock") {
+		t.Errorf(errExpectedX, "no metrics for jobs", sb.String())
+	}
+}
+
+func TestCollectorErrors(t *testing.T) {
+	if _, err := prom.NewCollector("my-app"); err == nil || err.Error() != prom.ErrInvalidName {
+		t.Errorf(errExpectedX, prom.ErrInvalidName, err)
+	}
+	col, _ := prom.NewCollector("app")
+	s := csstack.New[int]()
+	if err := col.Register("s", s, map[string]string{"container": "x"}); err == nil || err.Error() != prom.ErrReservedLabel {
+		t.Errorf(errExpectedX, prom.ErrReservedLabel, err)
+	}
+	if err := col.Register("s", s, map[string]string{"bad label": "x"}); err == nil || err.Error() != prom.ErrInvalidName {
+		t.Errorf(errExpectedX, prom.ErrInvalidName, err)
+	}
+	_ = col.Register("s", s, nil)
+	if err := col.Register("s", s, nil); err == nil || err.Error() != prom.ErrNameInUse {
+		t.Errorf(errExpectedX, prom.ErrNameInUse, err)
+	}
+}
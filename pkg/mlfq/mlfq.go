@@ -0,0 +1,201 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mlfq provides a multi-level feedback queue, to build schedulers
+// that favour short and interactive tasks without starving the long ones.
+//
+// The queue has a number of levels, each one a FIFO queue with a quantum:
+// new tasks enter the top level (0), and Dequeue returns the first task of
+// the highest non-empty level. Once a task has run for the quantum of its
+// level (over one or more turns, as reported to Requeue) it's demoted to the
+// level below. With WithAging, the tasks waiting longer than the aging
+// period are promoted to the level above, so the tasks of the low levels
+// aren't starved.
+package mlfq
+
+import (
+	"errors"
+	"time"
+
+	queue "github.com/pzaino/gods/pkg/queue"
+)
+
+const (
+	ErrQueueIsEmpty   = queue.ErrQueueIsEmpty
+	ErrNoLevels       = "at least one level is needed"
+	ErrInvalidQuantum = "the quantum of a level must be positive"
+	ErrInvalidLevel   = "invalid level"
+)
+
+// Option configures a Queue created with New.
+type Option func(*config)
+
+type config struct {
+	aging time.Duration
+	now   func() time.Time
+}
+
+// WithAging promotes to the level above the tasks that have been waiting
+// in a level for at least d. The promotions are done by Dequeue.
+func WithAging(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.aging = d
+	}
+}
+
+// WithClock sets the function returning the current time, used for aging
+// (the default is time.Now).
+func WithClock(now func() time.Time) Option {
+	return func(cfg *config) {
+		cfg.now = now
+	}
+}
+
+// Task is a task returned by Dequeue. It must be passed back to Requeue if
+// the task has to run again.
+type Task[T comparable] struct {
+	Item T
+	// Level is the level the task was dequeued from (0 is the highest)
+	Level int
+	// Quantum is how long the task can run before it's demoted: the quantum
+	// of its level, less the time it already ran at this level
+	Quantum time.Duration
+
+	used time.Duration
+}
+
+// entry is a task waiting in a level
+type entry[T comparable] struct {
+	item  T
+	used  time.Duration // the time run at the current level
+	since time.Time     // when the task entered the level
+}
+
+// Queue is a multi-level feedback queue. It is not concurrency-safe.
+type Queue[T comparable] struct {
+	levels []*queue.Queue[entry[T]]
+	quanta []time.Duration
+	aging  time.Duration
+	now    func() time.Time
+	size   uint64
+}
+
+// New creates a new Queue with a level for every quantum, from the highest
+// level (usually with the shortest quantum) to the lowest.
+func New[T comparable](quanta []time.Duration, opts ...Option) (*Queue[T], error) {
+	if len(quanta) == 0 {
+		return nil, errors.New(ErrNoLevels)
+	}
+	for _, q := range quanta {
+		if q <= 0 {
+			return nil, errors.New(ErrInvalidQuantum)
+		}
+	}
+	cfg := config{now: time.Now}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	mq := &Queue[T]{quanta: append([]time.Duration(nil), quanta...), aging: cfg.aging, now: cfg.now}
+	for range quanta {
+		mq.levels = append(mq.levels, queue.New[entry[T]]())
+	}
+	return mq, nil
+}
+
+// Enqueue adds a new task to the top level
+func (mq *Queue[T]) Enqueue(item T) {
+	mq.push(0, entry[T]{item: item})
+}
+
+// Dequeue removes and returns the first task of the highest non-empty
+// level, after promoting the tasks that waited for the aging period.
+func (mq *Queue[T]) Dequeue() (Task[T], error) {
+	mq.promote()
+	for level, q := range mq.levels {
+		if e, err := q.Dequeue(); err == nil {
+			mq.size--
+			return Task[T]{Item: e.item, Level: level, Quantum: mq.quanta[level] - e.used, used: e.used}, nil
+		}
+	}
+	return Task[T]{}, errors.New(ErrQueueIsEmpty)
+}
+
+// Requeue adds back a task returned by Dequeue that ran for used. The task
+// is demoted to the level below (if any) once it has run for the quantum of
+// its level, otherwise it goes back to the end of its level.
+func (mq *Queue[T]) Requeue(task Task[T], used time.Duration) error {
+	if task.Level < 0 || task.Level >= len(mq.levels) {
+		return errors.New(ErrInvalidLevel)
+	}
+	level, e := task.Level, entry[T]{item: task.Item, used: task.used + max(used, 0)}
+	if e.used >= mq.quanta[level] {
+		level = min(level+1, len(mq.levels)-1)
+		e.used = 0
+	}
+	mq.push(level, e)
+	return nil
+}
+
+// push adds e to the end of a level
+func (mq *Queue[T]) push(level int, e entry[T]) {
+	if mq.aging > 0 {
+		e.since = mq.now()
+	}
+	mq.levels[level].Enqueue(e)
+	mq.size++
+}
+
+// promote moves to the level above the tasks that waited for the aging
+// period. Every level is FIFO, so they are at the front of their level.
+func (mq *Queue[T]) promote() {
+	if mq.aging <= 0 {
+		return
+	}
+	now := mq.now()
+	for level := 1; level < len(mq.levels); level++ {
+		q := mq.levels[level]
+		for {
+			e, err := q.Peek()
+			if err != nil || now.Sub(e.since) < mq.aging {
+				break
+			}
+			_, _ = q.Dequeue()
+			mq.size--
+			mq.push(level-1, entry[T]{item: e.item})
+		}
+	}
+}
+
+// Size returns the number of tasks waiting in the queue
+func (mq *Queue[T]) Size() uint64 {
+	return mq.size
+}
+
+// IsEmpty returns true if no task is waiting in the queue
+func (mq *Queue[T]) IsEmpty() bool {
+	return mq.size == 0
+}
+
+// Levels returns the number of levels
+func (mq *Queue[T]) Levels() int {
+	return len(mq.levels)
+}
+
+// LevelSize returns the number of tasks waiting in a level
+func (mq *Queue[T]) LevelSize(level int) uint64 {
+	if level < 0 || level >= len(mq.levels) {
+		return 0
+	}
+	return mq.levels[level].Size()
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mlfq_test
+
+import (
+	"testing"
+	"time"
+
+	mlfq "github.com/pzaino/gods/pkg/mlfq"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+func TestNewErrors(t *testing.T) {
+	if _, err := mlfq.New[int](nil); err == nil || err.Error() != mlfq.ErrNoLevels {
+		t.Errorf(errExpectedX, mlfq.ErrNoLevels, err)
+	}
+	if _, err := mlfq.New[int]([]time.Duration{time.Second, 0}); err == nil || err.Error() != mlfq.ErrInvalidQuantum {
+		t.Errorf(errExpectedX, mlfq.ErrInvalidQuantum, err)
+	}
+}
+
+func TestDemotion(t *testing.T) {
+	mq, err := mlfq.New[string]([]time.Duration{10 * time.Millisecond, 40 * time.Millisecond})
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	mq.Enqueue("long")
+	mq.Enqueue("short")
+
+	// long uses its whole quantum and is demoted
+	task, err := mq.Dequeue()
+	if err != nil || task.Item != "long" || task.Level != 0 || task.Quantum != 10*time.Millisecond {
+		t.Fatalf(errExpectedX, "long at level 0", task)
+	}
+	if err := mq.Requeue(task, 10*time.Millisecond); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if mq.LevelSize(1) != 1 {
+		t.Errorf(errExpectedX, 1, mq.LevelSize(1))
+	}
+
+	// short yields early and stays at the top level with the rest of its quantum
+	task, _ = mq.Dequeue()
+	if task.Item != "short" {
+		t.Fatalf(errExpectedX, "short", task.Item)
+	}
+	_ = mq.Requeue(task, 4*time.Millisecond)
+	task, _ = mq.Dequeue()
+	if task.Item != "short" || task.Level != 0 || task.Quantum != 6*time.Millisecond {
+		t.Errorf(errExpectedX, "short at level 0 with 6ms left", task)
+	}
+
+	task, _ = mq.Dequeue()
+	if task.Item != "long" || task.Level != 1 {
+		t.Errorf(errExpectedX, "long at level 1", task)
+	}
+	// the lowest level keeps its tasks
+	_ = mq.Requeue(task, time.Second)
+	if mq.LevelSize(1) != 1 || mq.Size() != 1 {
+		t.Errorf(errExpectedX, 1, mq.LevelSize(1))
+	}
+	if err := mq.Requeue(mlfq.Task[string]{Level: 2}, 0); err == nil || err.Error() != mlfq.ErrInvalidLevel {
+		t.Errorf(errExpectedX, mlfq.ErrInvalidLevel, err)
+	}
+}
+
+func TestAging(t *testing.T) {
+	now := time.Unix(0, 0)
+	mq, err := mlfq.New[string]([]time.Duration{time.Millisecond, time.Millisecond, time.Millisecond},
+		mlfq.WithAging(time.Second), mlfq.WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	mq.Enqueue("batch")
+	for level := 0; level < 2; level++ {
+		task, _ := mq.Dequeue()
+		_ = mq.Requeue(task, time.Millisecond)
+	}
+	if mq.LevelSize(2) != 1 {
+		t.Fatalf(errExpectedX, 1, mq.LevelSize(2))
+	}
+
+	// after the aging period batch is promoted one level at a time
+	mq.Enqueue("new")
+	now = now.Add(time.Second)
+	task, _ := mq.Dequeue()
+	if task.Item != "new" {
+		t.Errorf(errExpectedX, "new", task.Item)
+	}
+	if mq.LevelSize(1) != 1 {
+		t.Errorf(errExpectedX, 1, mq.LevelSize(1))
+	}
+	now = now.Add(time.Second)
+	task, _ = mq.Dequeue()
+	if task.Item != "batch" || task.Level != 0 {
+		t.Errorf(errExpectedX, "batch at level 0", task)
+	}
+	if _, err := mq.Dequeue(); err == nil || err.Error() != mlfq.ErrQueueIsEmpty {
+		t.Errorf(errExpectedX, mlfq.ErrQueueIsEmpty, err)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package optional provides the Option and Result types: values that may be
+// missing, or that may be an error. They are plain structs (no allocation)
+// returned by the alternate accessors of the containers (PopOpt, PeekOpt,
+// DequeueOpt, GetOpt, ...), as an alternative to the (*T, error) and
+// (T, error) results of the regular ones.
+package optional
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	ErrNoValue = "option has no value"
+)
+
+// Option is a value that may be missing. The zero Option is None.
+type Option[T any] struct {
+	value T
+	ok    bool
+}
+
+// Some returns an Option holding v
+func Some[T any](v T) Option[T] {
+	return Option[T]{value: v, ok: true}
+}
+
+// None returns an empty Option
+func None[T any]() Option[T] {
+	return Option[T]{}
+}
+
+// Of returns Some(v) if ok, otherwise None: it turns the (value, ok) results
+// into an Option
+func Of[T any](v T, ok bool) Option[T] {
+	if !ok {
+		return None[T]()
+	}
+	return Some(v)
+}
+
+// IsSome returns true if the Option holds a value
+func (o Option[T]) IsSome() bool {
+	return o.ok
+}
+
+// IsNone returns true if the Option is empty
+func (o Option[T]) IsNone() bool {
+	return !o.ok
+}
+
+// Get returns the value and true, or the zero value and false if the Option
+// is empty
+func (o Option[T]) Get() (T, bool) {
+	return o.value, o.ok
+}
+
+// MustGet returns the value, and panics if the Option is empty
+func (o Option[T]) MustGet() T {
+	if !o.ok {
+		panic(ErrNoValue)
+	}
+	return o.value
+}
+
+// OrElse returns the value, or def if the Option is empty
+func (o Option[T]) OrElse(def T) T {
+	if !o.ok {
+		return def
+	}
+	return o.value
+}
+
+// OrElseGet returns the value, or the result of f if the Option is empty
+func (o Option[T]) OrElseGet(f func() T) T {
+	if !o.ok {
+		return f()
+	}
+	return o.value
+}
+
+// String returns "Some(value)" or "None"
+func (o Option[T]) String() string {
+	if !o.ok {
+		return "None"
+	}
+	return fmt.Sprintf("Some(%v)", o.value)
+}
+
+// MarshalJSON implements json.Marshaler: None is encoded as null
+func (o Option[T]) MarshalJSON() ([]byte, error) {
+	if !o.ok {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler: null is decoded as None
+func (o *Option[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*o = None[T]()
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*o = Some(v)
+	return nil
+}
+
+// Map returns Some(f(value)), or None if o is empty
+func Map[T, U any](o Option[T], f func(T) U) Option[U] {
+	if !o.ok {
+		return None[U]()
+	}
+	return Some(f(o.value))
+}
+
+// Result is a value or an error.
+type Result[T any] struct {
+	value T
+	err   error
+}
+
+// Ok returns a Result holding v
+func Ok[T any](v T) Result[T] {
+	return Result[T]{value: v}
+}
+
+// Err returns a Result holding err (if err is nil, a Result holding
+// ErrNoValue)
+func Err[T any](err error) Result[T] {
+	if err == nil {
+		err = errors.New(ErrNoValue)
+	}
+	return Result[T]{err: err}
+}
+
+// Try returns a Result from the (value, error) results of a function call:
+// Ok(v) if err is nil, otherwise Err(err)
+func Try[T any](v T, err error) Result[T] {
+	if err != nil {
+		return Err[T](err)
+	}
+	return Ok(v)
+}
+
+// IsOk returns true if the Result holds a value
+func (r Result[T]) IsOk() bool {
+	return r.err == nil
+}
+
+// IsErr returns true if the Result holds an error
+func (r Result[T]) IsErr() bool {
+	return r.err != nil
+}
+
+// Get returns the value and a nil error, or the zero value and the error
+func (r Result[T]) Get() (T, error) {
+	return r.value, r.err
+}
+
+// Err returns the error, or nil if the Result holds a value
+func (r Result[T]) Err() error {
+	return r.err
+}
+
+// MustGet returns the value, and panics with the error if there's one
+func (r Result[T]) MustGet() T {
+	if r.err != nil {
+		panic(r.err)
+	}
+	return r.value
+}
+
+// OrElse returns the value, or def if the Result holds an error
+func (r Result[T]) OrElse(def T) T {
+	if r.err != nil {
+		return def
+	}
+	return r.value
+}
+
+// Option returns the value as an Option, dropping the error
+func (r Result[T]) Option() Option[T] {
+	return Option[T]{value: r.value, ok: r.err == nil}
+}
+
+// String returns "Ok(value)" or "Err(error)"
+func (r Result[T]) String() string {
+	if r.err != nil {
+		return fmt.Sprintf("Err(%v)", r.err)
+	}
+	return fmt.Sprintf("Ok(%v)", r.value)
+}
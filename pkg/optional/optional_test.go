@@ -0,0 +1,124 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package optional_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	optional "github.com/pzaino/gods/pkg/optional"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+func TestOption(t *testing.T) {
+	some, none := optional.Some(3), optional.None[int]()
+	if !some.IsSome() || some.IsNone() || none.IsSome() || !none.IsNone() {
+		t.Errorf("expected Some and None to be told apart")
+	}
+	if v, ok := some.Get(); !ok || v != 3 {
+		t.Errorf(errExpectedX, 3, v)
+	}
+	if none.OrElse(5) != 5 || some.OrElse(5) != 3 || none.OrElseGet(func() int { return 6 }) != 6 {
+		t.Errorf("expected OrElse to return the default only for None")
+	}
+	if some.String() != "Some(3)" || none.String() != "None" {
+		t.Errorf(errExpectedX, "Some(3) None", some.String()+" "+none.String())
+	}
+	if m := optional.Map(some, strconv.Itoa); m.MustGet() != "3" || optional.Map(none, strconv.Itoa).IsSome() {
+		t.Errorf(errExpectedX, "Some(3)", m)
+	}
+	if optional.Of(1, false).IsSome() || !optional.Of(1, true).IsSome() {
+		t.Errorf("expected Of to follow ok")
+	}
+	var zero optional.Option[int]
+	if zero.IsSome() {
+		t.Errorf("expected the zero Option to be None")
+	}
+	defer func() {
+		if r := recover(); r != optional.ErrNoValue {
+			t.Errorf(errExpectedX, optional.ErrNoValue, r)
+		}
+	}()
+	none.MustGet()
+}
+
+func TestOptionJSON(t *testing.T) {
+	type user struct {
+		Name  string
+		Email optional.Option[string]
+	}
+	data, err := json.Marshal([]user{{"a", optional.Some("a@x")}, {"b", optional.None[string]()}})
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if want := `[{"Name":"a","Email":"a@x"},{"Name":"b","Email":null}]`; string(data) != want {
+		t.Errorf(errExpectedX, want, string(data))
+	}
+	var users []user
+	if err := json.Unmarshal(data, &users); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if users[0].Email.MustGet() != "a@x" || users[1].Email.IsSome() {
+		t.Errorf(errExpectedX, "Some(a@x) None", users)
+	}
+	var o optional.Option[int]
+	if err := json.Unmarshal([]byte(`"x"`), &o); err == nil {
+		t.Errorf("expected a type error")
+	}
+}
+
+func TestResult(t *testing.T) {
+	boom := errors.New("boom")
+	ok, bad := optional.Ok(1), optional.Err[int](boom)
+	if !ok.IsOk() || ok.IsErr() || bad.IsOk() || !bad.IsErr() {
+		t.Errorf("expected Ok and Err to be told apart")
+	}
+	if v, err := ok.Get(); v != 1 || err != nil {
+		t.Errorf(errExpectedX, 1, v)
+	}
+	if _, err := bad.Get(); err != boom || bad.Err() != boom {
+		t.Errorf(errExpectedX, boom, err)
+	}
+	if bad.OrElse(2) != 2 || ok.OrElse(2) != 1 {
+		t.Errorf("expected OrElse to return the default only for Err")
+	}
+	if ok.String() != "Ok(1)" || bad.String() != "Err(boom)" {
+		t.Errorf(errExpectedX, "Ok(1) Err(boom)", ok.String()+" "+bad.String())
+	}
+	if !ok.Option().IsSome() || bad.Option().IsSome() {
+		t.Errorf("expected Option to drop the error")
+	}
+	if r := optional.Try(strconv.Atoi("x")); r.IsOk() {
+		t.Errorf(errExpectedX, "an error", r)
+	}
+	if r := optional.Try(strconv.Atoi("7")); r.MustGet() != 7 {
+		t.Errorf(errExpectedX, 7, r)
+	}
+	if optional.Err[int](nil).IsOk() {
+		t.Errorf("expected Err(nil) to hold an error")
+	}
+	defer func() {
+		if r := recover(); r != boom {
+			t.Errorf(errExpectedX, boom, r)
+		}
+	}()
+	bad.MustGet()
+}
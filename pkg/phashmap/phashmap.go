@@ -0,0 +1,772 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package phashmap provides a persistent (immutable) hash map implemented as
+// a Hash Array Mapped Trie (HAMT).
+//
+// Every "mutating" operation (Assoc, Dissoc) returns a new version of the map
+// and leaves the original untouched. Versions share all the unchanged nodes,
+// so a new version costs O(log32 n) new nodes. Because a Map is never modified
+// after it has been created, it can be shared between goroutines without any
+// locking.
+//
+// For batch updates use a Transient, which mutates the nodes it owns in place
+// and can be turned back into a persistent Map in O(1).
+package phashmap
+
+import (
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"iter"
+	"math"
+	"math/bits"
+	"strconv"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+)
+
+const (
+	ErrKeyNotFound        = "key not found"
+	ErrTransientPersisted = "transient has already been persisted"
+)
+
+const (
+	bitsPerLevel = 5
+	levelMask    = (1 << bitsPerLevel) - 1
+	hashBits     = 64
+)
+
+// entry is either a key/value leaf or a pointer to a sub-trie (child != nil)
+type entry[K any, V any] struct {
+	hash  uint64
+	key   K
+	value V
+	child *node[K, V]
+}
+
+// node is a HAMT node. Nodes at a shift >= hashBits are collision nodes, their
+// entries all share the same hash and are stored unordered (bitmap is unused).
+type node[K any, V any] struct {
+	bitmap  uint32
+	entries []entry[K, V]
+	owner   *owner
+}
+
+// owner identifies the Transient that is allowed to mutate a node in place
+// (it must not be zero-sized, or distinct owners could share the same address)
+type owner struct {
+	_ byte
+}
+
+// Map is a persistent hash map.
+type Map[K any, V any] struct {
+	root  *node[K, V]
+	size  uint64
+	hash  func(K) uint64
+	equal func(K, K) bool
+}
+
+var seed = maphash.MakeSeed()
+
+// New creates a new, empty, persistent hash map.
+func New[K comparable, V any]() *Map[K, V] {
+	return NewWithHashFunc[K, V](defaultHash[K])
+}
+
+// NewWithHashFunc creates a new, empty, persistent hash map that uses the
+// given hash function for its keys.
+func NewWithHashFunc[K comparable, V any](hash func(K) uint64) *Map[K, V] {
+	return &Map[K, V]{
+		hash:  hash,
+		equal: func(a, b K) bool { return a == b },
+	}
+}
+
+// NewWithHasher creates a new, empty, persistent hash map that uses the given
+// Hasher and Equaler for its keys. It allows to use keys that are not
+// comparable, for example:
+//
+//	m := phashmap.NewWithHasher[[]byte, int](gods.BytesHasher{}, gods.BytesHasher{})
+func NewWithHasher[K any, V any](hasher gods.Hasher[K], equaler gods.Equaler[K]) *Map[K, V] {
+	return &Map[K, V]{
+		hash:  hasher.Hash,
+		equal: equaler.Equal,
+	}
+}
+
+// defaultHash hashes the most common key types directly and falls back on
+// their Go-syntax representation for everything else.
+func defaultHash[K comparable](key K) uint64 {
+	var h maphash.Hash
+	h.SetSeed(seed)
+	switch k := any(key).(type) {
+	case string:
+		_, _ = h.WriteString(k)
+	case int:
+		writeUint64(&h, uint64(k))
+	case int8:
+		writeUint64(&h, uint64(k))
+	case int16:
+		writeUint64(&h, uint64(k))
+	case int32:
+		writeUint64(&h, uint64(k))
+	case int64:
+		writeUint64(&h, uint64(k))
+	case uint:
+		writeUint64(&h, uint64(k))
+	case uint8:
+		writeUint64(&h, uint64(k))
+	case uint16:
+		writeUint64(&h, uint64(k))
+	case uint32:
+		writeUint64(&h, uint64(k))
+	case uint64:
+		writeUint64(&h, k)
+	case uintptr:
+		writeUint64(&h, uint64(k))
+	case float32:
+		writeFloat(&h, float64(k))
+	case float64:
+		writeFloat(&h, k)
+	case bool:
+		if k {
+			_ = h.WriteByte(1)
+		} else {
+			_ = h.WriteByte(0)
+		}
+	default:
+		_, _ = h.WriteString(fmt.Sprintf("%T:%#v", key, key))
+	}
+	return h.Sum64()
+}
+
+func writeUint64(h *maphash.Hash, v uint64) {
+	var buf [8]byte
+	for i := 0; i < 8; i++ {
+		buf[i] = byte(v >> (8 * i))
+	}
+	_, _ = h.Write(buf[:])
+}
+
+func writeFloat(h *maphash.Hash, v float64) {
+	if v == 0 {
+		// +0 and -0 are equal, so they must hash the same
+		v = 0
+	}
+	writeUint64(h, math.Float64bits(v))
+}
+
+// IsEmpty returns true if the map has no entries
+func (m *Map[K, V]) IsEmpty() bool {
+	if m == nil {
+		return true
+	}
+	return m.size == 0
+}
+
+// Size returns the number of entries in the map
+func (m *Map[K, V]) Size() uint64 {
+	if m == nil {
+		return 0
+	}
+	return m.size
+}
+
+// MemoryUsage returns an estimate of the bytes held by the map and its trie
+// nodes (see the gods package), adding the memory returned by extra for
+// every entry. The nodes shared with other versions of the map are included.
+func (m *Map[K, V]) MemoryUsage(extra ...func(K, V) uint64) uint64 {
+	if m == nil {
+		return 0
+	}
+	n := uint64(unsafe.Sizeof(*m)) + nodeMemory(m.root)
+	for _, fn := range extra {
+		for k, v := range m.All() {
+			n += fn(k, v)
+		}
+	}
+	return n
+}
+
+// nodeMemory returns the memory held by n and its sub-tries
+func nodeMemory[K, V any](n *node[K, V]) uint64 {
+	if n == nil {
+		return 0
+	}
+	size := uint64(unsafe.Sizeof(*n)) + gods.SliceMemory(n.entries)
+	for _, e := range n.entries {
+		size += nodeMemory(e.child)
+	}
+	return size
+}
+
+// Validate checks the invariants of the map (see gods.Validator): every node
+// has an entry for each bit of its bitmap, every leaf is stored under the
+// hash of its key, in the position selected by the hash, and the number of
+// leaves is the size of the map.
+func (m *Map[K, V]) Validate() error {
+	if m == nil {
+		return nil
+	}
+	count, err := m.validateNode(m.root, 0, 0)
+	if err != nil {
+		return err
+	}
+	if count != m.size {
+		return gods.Invariantf("size is %d but the trie holds %d entries", m.size, count)
+	}
+	return nil
+}
+
+// Dump writes the shape of the trie to w, for debugging: every node with its
+// bitmap, every entry with its position and its sub-trie indented under it.
+func (m *Map[K, V]) Dump(w io.Writer) error {
+	d := gods.NewDumper(w)
+	d.Line(0, "Map size=%d", m.Size())
+	if m != nil {
+		dumpNode(d, m.root, 0, 1)
+	}
+	return d.Err()
+}
+
+// dumpNode writes the sub-trie n at the given shift
+func dumpNode[K, V any](d *gods.Dumper, n *node[K, V], shift uint, depth int) {
+	if n == nil {
+		return
+	}
+	if shift >= hashBits {
+		d.Line(depth, "collision node (%d entries)", len(n.entries))
+	} else {
+		d.Line(depth, "node shift=%d bitmap=%032b", shift, n.bitmap)
+	}
+	bitmap := n.bitmap
+	for i := range n.entries {
+		e := &n.entries[i]
+		pos := "-"
+		if shift < hashBits {
+			pos = strconv.Itoa(bits.TrailingZeros32(bitmap))
+			bitmap &= bitmap - 1
+		}
+		if e.child != nil {
+			d.Line(depth+1, "[%s] ->", pos)
+			dumpNode(d, e.child, shift+bitsPerLevel, depth+2)
+			continue
+		}
+		d.Line(depth+1, "[%s] %v: %v (hash %#016x)", pos, e.key, e.value, e.hash)
+	}
+}
+
+// validateNode checks the sub-trie n, whose hashes all have the given lower
+// shift bits, and returns the number of its leaves
+func (m *Map[K, V]) validateNode(n *node[K, V], shift uint, prefix uint64) (uint64, error) {
+	if n == nil {
+		return 0, nil
+	}
+	if len(n.entries) == 0 {
+		return 0, gods.Invariantf("empty node at shift %d", shift)
+	}
+	if shift < hashBits && bits.OnesCount32(n.bitmap) != len(n.entries) {
+		return 0, gods.Invariantf("node at shift %d has %d entries for %d bitmap bits", shift, len(n.entries), bits.OnesCount32(n.bitmap))
+	}
+	mask := uint64(1)<<min(shift, hashBits) - 1
+	var count uint64
+	bitmap := n.bitmap
+	for i := range n.entries {
+		e := &n.entries[i]
+		pos := uint32(bits.TrailingZeros32(bitmap))
+		bitmap &= bitmap - 1
+		if e.child != nil {
+			if shift >= hashBits {
+				return 0, gods.Invariantf("collision node with a sub-trie")
+			}
+			c, err := m.validateNode(e.child, shift+bitsPerLevel, prefix|uint64(pos)<<shift)
+			if err != nil {
+				return 0, err
+			}
+			count += c
+			continue
+		}
+		if e.hash != m.hash(e.key) {
+			return 0, gods.Invariantf("entry %v is stored under the hash %#x of another key", e.key, e.hash)
+		}
+		if e.hash&mask != prefix || (shift < hashBits && index(e.hash, shift) != pos) {
+			return 0, gods.Invariantf("entry %v is not in the position selected by its hash at shift %d", e.key, shift)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Get returns the value associated with the given key
+func (m *Map[K, V]) Get(key K) (V, error) {
+	if m.IsEmpty() {
+		var rVal V
+		return rVal, errors.New(ErrKeyNotFound)
+	}
+	return get(m.root, 0, m.hash(key), key, m.equal)
+}
+
+// Contains returns true if the map has an entry for the given key
+func (m *Map[K, V]) Contains(key K) bool {
+	_, err := m.Get(key)
+	return err == nil
+}
+
+// Assoc returns a new version of the map with the given key set to value.
+// The original map is not modified.
+func (m *Map[K, V]) Assoc(key K, value V) *Map[K, V] {
+	root, added := assoc(m.root, 0, m.hash(key), key, value, nil, m.equal)
+	newMap := &Map[K, V]{root: root, size: m.size, hash: m.hash, equal: m.equal}
+	if added {
+		newMap.size++
+	}
+	return newMap
+}
+
+// Dissoc returns a new version of the map without the given key.
+// If the key is not present the same map is returned.
+func (m *Map[K, V]) Dissoc(key K) *Map[K, V] {
+	if m.IsEmpty() {
+		return m
+	}
+	root, removed := dissoc(m.root, 0, m.hash(key), key, nil, m.equal)
+	if !removed {
+		return m
+	}
+	return &Map[K, V]{root: root, size: m.size - 1, hash: m.hash, equal: m.equal}
+}
+
+// ForEach calls f for every entry in the map (in no particular order)
+func (m *Map[K, V]) ForEach(f func(K, V)) {
+	if m.IsEmpty() {
+		return
+	}
+	walk(m.root, func(e *entry[K, V]) bool {
+		f(e.key, e.value)
+		return true
+	})
+}
+
+// All returns an iterator over the entries of the map (in no particular order)
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.IsEmpty() {
+			return
+		}
+		walk(m.root, func(e *entry[K, V]) bool {
+			return yield(e.key, e.value)
+		})
+	}
+}
+
+// Keys returns an iterator over the keys of the map (in no particular order)
+func (m *Map[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range m.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values of the map (in no particular order)
+func (m *Map[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range m.All() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// ToMap returns the content of the persistent map as a Go map.
+// It requires the key type to be comparable.
+func ToMap[K comparable, V any](m *Map[K, V]) map[K]V {
+	result := make(map[K]V, m.Size())
+	m.ForEach(func(k K, v V) {
+		result[k] = v
+	})
+	return result
+}
+
+// FromMap creates a new persistent map with the content of a Go map.
+func FromMap[K comparable, V any](items map[K]V) *Map[K, V] {
+	t := New[K, V]().Transient()
+	for k, v := range items {
+		_ = t.Assoc(k, v)
+	}
+	m, _ := t.Persistent()
+	return m
+}
+
+// Transient returns a mutable copy of the map for batch updates. The original
+// map is not affected by changes made through the Transient.
+func (m *Map[K, V]) Transient() *Transient[K, V] {
+	return &Transient[K, V]{
+		root:  m.root,
+		size:  m.size,
+		hash:  m.hash,
+		equal: m.equal,
+		owner: &owner{},
+	}
+}
+
+// Transient is a mutable builder for a persistent Map. It is not safe for
+// concurrent use and it cannot be used anymore once Persistent is called.
+type Transient[K any, V any] struct {
+	root  *node[K, V]
+	size  uint64
+	hash  func(K) uint64
+	equal func(K, K) bool
+	owner *owner
+}
+
+// Size returns the number of entries in the transient
+func (t *Transient[K, V]) Size() uint64 {
+	return t.size
+}
+
+// Get returns the value associated with the given key
+func (t *Transient[K, V]) Get(key K) (V, error) {
+	if t.size == 0 {
+		var rVal V
+		return rVal, errors.New(ErrKeyNotFound)
+	}
+	return get(t.root, 0, t.hash(key), key, t.equal)
+}
+
+// Assoc sets the given key to value
+func (t *Transient[K, V]) Assoc(key K, value V) error {
+	if t.owner == nil {
+		return errors.New(ErrTransientPersisted)
+	}
+	root, added := assoc(t.root, 0, t.hash(key), key, value, t.owner, t.equal)
+	t.root = root
+	if added {
+		t.size++
+	}
+	return nil
+}
+
+// Dissoc removes the given key
+func (t *Transient[K, V]) Dissoc(key K) error {
+	if t.owner == nil {
+		return errors.New(ErrTransientPersisted)
+	}
+	if t.size == 0 {
+		return nil
+	}
+	root, removed := dissoc(t.root, 0, t.hash(key), key, t.owner, t.equal)
+	t.root = root
+	if removed {
+		t.size--
+	}
+	return nil
+}
+
+// Persistent returns a persistent Map with the content of the transient.
+// After this call the transient cannot be modified anymore.
+func (t *Transient[K, V]) Persistent() (*Map[K, V], error) {
+	if t.owner == nil {
+		return nil, errors.New(ErrTransientPersisted)
+	}
+	t.owner = nil
+	return &Map[K, V]{root: t.root, size: t.size, hash: t.hash, equal: t.equal}, nil
+}
+
+// Helper functions for trie operations
+
+// index returns the position of the hash bits for the given shift
+func index(hash uint64, shift uint) uint32 {
+	return uint32((hash >> shift) & levelMask)
+}
+
+// position returns the position in the entries slice of the given bit
+func (n *node[K, V]) position(bit uint32) int {
+	return bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// editable returns a node that can be modified by the given owner
+func (n *node[K, V]) editable(o *owner) *node[K, V] {
+	if o != nil && n.owner == o {
+		return n
+	}
+	entries := make([]entry[K, V], len(n.entries), len(n.entries)+1)
+	copy(entries, n.entries)
+	return &node[K, V]{bitmap: n.bitmap, entries: entries, owner: o}
+}
+
+func get[K any, V any](n *node[K, V], shift uint, hash uint64, key K, equal func(K, K) bool) (V, error) {
+	for n != nil {
+		if shift >= hashBits {
+			for i := range n.entries {
+				if equal(n.entries[i].key, key) {
+					return n.entries[i].value, nil
+				}
+			}
+			break
+		}
+		bit := uint32(1) << index(hash, shift)
+		if n.bitmap&bit == 0 {
+			break
+		}
+		e := &n.entries[n.position(bit)]
+		if e.child == nil {
+			if e.hash == hash && equal(e.key, key) {
+				return e.value, nil
+			}
+			break
+		}
+		n = e.child
+		shift += bitsPerLevel
+	}
+	var rVal V
+	return rVal, errors.New(ErrKeyNotFound)
+}
+
+func assoc[K any, V any](n *node[K, V], shift uint, hash uint64, key K, value V, o *owner, equal func(K, K) bool) (*node[K, V], bool) {
+	leaf := entry[K, V]{hash: hash, key: key, value: value}
+	if n == nil {
+		n = &node[K, V]{owner: o}
+		if shift >= hashBits {
+			n.entries = []entry[K, V]{leaf}
+			return n, true
+		}
+		n.bitmap = uint32(1) << index(hash, shift)
+		n.entries = []entry[K, V]{leaf}
+		return n, true
+	}
+
+	if shift >= hashBits {
+		// collision node
+		for i := range n.entries {
+			if equal(n.entries[i].key, key) {
+				n = n.editable(o)
+				n.entries[i].value = value
+				return n, false
+			}
+		}
+		n = n.editable(o)
+		n.entries = append(n.entries, leaf)
+		return n, true
+	}
+
+	bit := uint32(1) << index(hash, shift)
+	pos := n.position(bit)
+	if n.bitmap&bit == 0 {
+		n = n.editable(o)
+		n.entries = append(n.entries, entry[K, V]{})
+		copy(n.entries[pos+1:], n.entries[pos:])
+		n.entries[pos] = leaf
+		n.bitmap |= bit
+		return n, true
+	}
+
+	e := n.entries[pos]
+	if e.child != nil {
+		child, added := assoc(e.child, shift+bitsPerLevel, hash, key, value, o, equal)
+		if child != e.child {
+			n = n.editable(o)
+			n.entries[pos].child = child
+		}
+		return n, added
+	}
+
+	if e.hash == hash && equal(e.key, key) {
+		n = n.editable(o)
+		n.entries[pos].value = value
+		return n, false
+	}
+
+	n = n.editable(o)
+	n.entries[pos] = entry[K, V]{child: mergeLeaves(shift+bitsPerLevel, e, leaf, o)}
+	return n, true
+}
+
+// mergeLeaves creates a sub-trie containing two leaves with different keys
+func mergeLeaves[K any, V any](shift uint, a, b entry[K, V], o *owner) *node[K, V] {
+	if shift >= hashBits {
+		return &node[K, V]{entries: []entry[K, V]{a, b}, owner: o}
+	}
+	ia, ib := index(a.hash, shift), index(b.hash, shift)
+	if ia == ib {
+		child := mergeLeaves(shift+bitsPerLevel, a, b, o)
+		return &node[K, V]{
+			bitmap:  uint32(1) << ia,
+			entries: []entry[K, V]{{child: child}},
+			owner:   o,
+		}
+	}
+	if ia > ib {
+		a, b = b, a
+	}
+	return &node[K, V]{
+		bitmap:  (uint32(1) << ia) | (uint32(1) << ib),
+		entries: []entry[K, V]{a, b},
+		owner:   o,
+	}
+}
+
+func dissoc[K any, V any](n *node[K, V], shift uint, hash uint64, key K, o *owner, equal func(K, K) bool) (*node[K, V], bool) {
+	if shift >= hashBits {
+		for i := range n.entries {
+			if equal(n.entries[i].key, key) {
+				if len(n.entries) == 1 {
+					return nil, true
+				}
+				n = n.editable(o)
+				n.entries = append(n.entries[:i], n.entries[i+1:]...)
+				return n, true
+			}
+		}
+		return n, false
+	}
+
+	bit := uint32(1) << index(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	pos := n.position(bit)
+	e := n.entries[pos]
+
+	if e.child != nil {
+		child, removed := dissoc(e.child, shift+bitsPerLevel, hash, key, o, equal)
+		if !removed {
+			return n, false
+		}
+		if child == nil {
+			return n.removeEntry(pos, bit, o), true
+		}
+		n = n.editable(o)
+		if len(child.entries) == 1 && child.entries[0].child == nil {
+			// collapse the single leaf into this node
+			n.entries[pos] = child.entries[0]
+		} else {
+			n.entries[pos].child = child
+		}
+		return n, true
+	}
+
+	if e.hash != hash || !equal(e.key, key) {
+		return n, false
+	}
+	return n.removeEntry(pos, bit, o), true
+}
+
+// removeEntry removes the entry at the given position (nil if the node becomes empty)
+func (n *node[K, V]) removeEntry(pos int, bit uint32, o *owner) *node[K, V] {
+	if len(n.entries) == 1 {
+		return nil
+	}
+	n = n.editable(o)
+	n.entries = append(n.entries[:pos], n.entries[pos+1:]...)
+	n.bitmap &^= bit
+	return n
+}
+
+// walk visits all the leaves of the trie, it stops if f returns false
+func walk[K any, V any](n *node[K, V], f func(*entry[K, V]) bool) bool {
+	if n == nil {
+		return true
+	}
+	for i := range n.entries {
+		e := &n.entries[i]
+		if e.child != nil {
+			if !walk(e.child, f) {
+				return false
+			}
+			continue
+		}
+		if !f(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// record is the serialized form of a map entry
+type record[K any, V any] struct {
+	Key   K
+	Value V
+}
+
+// Encode writes the entries of the map to w using the given codec
+func (m *Map[K, V]) Encode(w io.Writer, codec gods.Codec) error {
+	records := make([]record[K, V], 0, m.Size())
+	for k, v := range m.All() {
+		records = append(records, record[K, V]{Key: k, Value: v})
+	}
+	return gods.EncodeSlice(w, codec, records)
+}
+
+// MarshalText implements encoding.TextMarshaler: the map is encoded as a JSON
+// array of key/value records (see gods.MarshalText). Maps are immutable, so
+// there is no UnmarshalText: use Decode with gods.JSONCodec instead.
+func (m *Map[K, V]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(m)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (m *Map[K, V]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(m)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (m *Map[K, V]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(m)
+}
+
+// Decode reads a map written by Encode from r using the given codec.
+// Since maps are immutable, Decode returns a new map instead of being a method.
+func Decode[K comparable, V any](r io.Reader, codec gods.Codec) (*Map[K, V], error) {
+	records, err := gods.DecodeSlice[record[K, V]](r, codec)
+	if err != nil {
+		return nil, err
+	}
+	t := New[K, V]().Transient()
+	for _, rec := range records {
+		_ = t.Assoc(rec.Key, rec.Value)
+	}
+	return t.Persistent()
+}
+
+// EncodeStream writes the map to w incrementally, in chunks of records
+// encoded with the given codec (see gods.EncodeStream).
+func (m *Map[K, V]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeStream(w, codec, func(yield func(record[K, V]) bool) {
+		for k, v := range m.All() {
+			if !yield(record[K, V]{Key: k, Value: v}) {
+				return
+			}
+		}
+	})
+}
+
+// DecodeStream reads a map written by EncodeStream from r using the given
+// codec, adding the records to a transient map as they are read.
+func DecodeStream[K comparable, V any](r io.Reader, codec gods.Codec) (*Map[K, V], error) {
+	t := New[K, V]().Transient()
+	err := gods.DecodeStream(r, codec, func(rec record[K, V]) error {
+		return t.Assoc(rec.Key, rec.Value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t.Persistent()
+}
@@ -0,0 +1,387 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package phashmap provides a persistent (immutable) hash map.
+package phashmap_test
+
+import (
+	"bytes"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	phashmap "github.com/pzaino/gods/pkg/phashmap"
+)
+
+const (
+	errNoError        = "Expected no error, but got %v"
+	errYesError       = "Expected an error, but got nil"
+	errExpectedSize   = "Expected size to be %d, but got %d"
+	errExpectedValueX = "Expected value to be %v, but got %v"
+)
+
+func TestNew(t *testing.T) {
+	m := phashmap.New[string, int]()
+	if m == nil {
+		t.Fatal("Expected map to be initialized, but got nil")
+	}
+	if !m.IsEmpty() {
+		t.Error("Expected map to be empty")
+	}
+	if m.Size() != 0 {
+		t.Errorf(errExpectedSize, 0, m.Size())
+	}
+}
+
+func TestAssocGet(t *testing.T) {
+	m := phashmap.New[string, int]()
+	m1 := m.Assoc("a", 1)
+	m2 := m1.Assoc("b", 2)
+
+	if m.Size() != 0 || m1.Size() != 1 || m2.Size() != 2 {
+		t.Fatalf("Unexpected sizes: %d %d %d", m.Size(), m1.Size(), m2.Size())
+	}
+
+	v, err := m2.Get("a")
+	if err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if v != 1 {
+		t.Errorf(errExpectedValueX, 1, v)
+	}
+
+	if _, err := m1.Get("b"); err == nil {
+		t.Error(errYesError)
+	}
+	if _, err := m.Get("a"); err == nil {
+		t.Error(errYesError)
+	}
+}
+
+func TestAssocReplace(t *testing.T) {
+	m1 := phashmap.New[string, int]().Assoc("a", 1)
+	m2 := m1.Assoc("a", 2)
+
+	if m2.Size() != 1 {
+		t.Errorf(errExpectedSize, 1, m2.Size())
+	}
+	v, _ := m1.Get("a")
+	if v != 1 {
+		t.Errorf(errExpectedValueX, 1, v)
+	}
+	v, _ = m2.Get("a")
+	if v != 2 {
+		t.Errorf(errExpectedValueX, 2, v)
+	}
+}
+
+func TestDissoc(t *testing.T) {
+	m := phashmap.New[int, int]()
+	for i := 0; i < 1000; i++ {
+		m = m.Assoc(i, i*2)
+	}
+	full := m
+	for i := 0; i < 1000; i += 2 {
+		m = m.Dissoc(i)
+	}
+
+	if m.Size() != 500 {
+		t.Errorf(errExpectedSize, 500, m.Size())
+	}
+	if full.Size() != 1000 {
+		t.Errorf(errExpectedSize, 1000, full.Size())
+	}
+	for i := 0; i < 1000; i++ {
+		v, err := m.Get(i)
+		if i%2 == 0 {
+			if err == nil {
+				t.Fatalf("Expected key %d to be removed", i)
+			}
+			continue
+		}
+		if err != nil || v != i*2 {
+			t.Fatalf("Expected key %d to be %d, got %d (%v)", i, i*2, v, err)
+		}
+		if _, err := full.Get(i); err != nil {
+			t.Fatalf(errNoError, err)
+		}
+	}
+
+	same := m.Dissoc(-1)
+	if same != m {
+		t.Error("Expected Dissoc of a missing key to return the same map")
+	}
+
+	for i := 1; i < 1000; i += 2 {
+		m = m.Dissoc(i)
+	}
+	if !m.IsEmpty() {
+		t.Errorf(errExpectedSize, 0, m.Size())
+	}
+}
+
+func TestCollisions(t *testing.T) {
+	// every key ends up in the same bucket
+	m := phashmap.NewWithHashFunc[int, string](func(int) uint64 { return 42 })
+	for i := 0; i < 10; i++ {
+		m = m.Assoc(i, strconv.Itoa(i))
+	}
+	if m.Size() != 10 {
+		t.Fatalf(errExpectedSize, 10, m.Size())
+	}
+	m = m.Assoc(3, "three")
+	for i := 0; i < 10; i++ {
+		v, err := m.Get(i)
+		if err != nil {
+			t.Fatalf(errNoError, err)
+		}
+		expected := strconv.Itoa(i)
+		if i == 3 {
+			expected = "three"
+		}
+		if v != expected {
+			t.Errorf(errExpectedValueX, expected, v)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		m = m.Dissoc(i)
+		if m.Contains(i) {
+			t.Fatalf("Expected key %d to be removed", i)
+		}
+	}
+	if !m.IsEmpty() {
+		t.Errorf(errExpectedSize, 0, m.Size())
+	}
+}
+
+func TestPartialCollisions(t *testing.T) {
+	// keys share the lowest bits, so they are spread across deep levels
+	m := phashmap.NewWithHashFunc[uint64, uint64](func(k uint64) uint64 { return k << 40 })
+	for i := uint64(0); i < 300; i++ {
+		m = m.Assoc(i, i)
+	}
+	for i := uint64(0); i < 300; i++ {
+		if v, err := m.Get(i); err != nil || v != i {
+			t.Fatalf("Expected key %d to be %d, got %d (%v)", i, i, v, err)
+		}
+	}
+	for i := uint64(0); i < 300; i += 3 {
+		m = m.Dissoc(i)
+	}
+	if m.Size() != 200 {
+		t.Errorf(errExpectedSize, 200, m.Size())
+	}
+}
+
+func TestForEachKeysValues(t *testing.T) {
+	m := phashmap.FromMap(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	keys := slices.Sorted(m.Keys())
+	if len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != "c" {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+
+	sum := 0
+	for v := range m.Values() {
+		sum += v
+	}
+	if sum != 6 {
+		t.Errorf(errExpectedValueX, 6, sum)
+	}
+
+	copied := phashmap.ToMap(m)
+	if len(copied) != 3 || copied["b"] != 2 {
+		t.Errorf("Unexpected map %v", copied)
+	}
+}
+
+func TestAll(t *testing.T) {
+	m := phashmap.New[int, int]()
+	for i := 0; i < 100; i++ {
+		m = m.Assoc(i, i*2)
+	}
+
+	seen := 0
+	for k, v := range m.All() {
+		if v != k*2 {
+			t.Errorf(errExpectedValueX, k*2, v)
+		}
+		seen++
+	}
+	if seen != 100 {
+		t.Errorf(errExpectedValueX, 100, seen)
+	}
+
+	// Early break must stop the walk
+	seen = 0
+	for range m.All() {
+		seen++
+		if seen == 10 {
+			break
+		}
+	}
+	if seen != 10 {
+		t.Errorf(errExpectedValueX, 10, seen)
+	}
+
+	for range phashmap.New[int, int]().All() {
+		t.Errorf("Expected no entries in an empty map")
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	m := phashmap.FromMap(map[string]int{"a": 1, "b": 2, "c": 3})
+	for _, codec := range []gods.Codec{gods.JSONCodec{}, gods.GobCodec{}} {
+		var buf bytes.Buffer
+		if err := m.Encode(&buf, codec); err != nil {
+			t.Fatalf(errNoError, err)
+		}
+		decoded, err := phashmap.Decode[string, int](&buf, codec)
+		if err != nil {
+			t.Fatalf(errNoError, err)
+		}
+		if decoded.Size() != 3 {
+			t.Errorf(errExpectedSize, 3, decoded.Size())
+		}
+		if v, _ := decoded.Get("b"); v != 2 {
+			t.Errorf(errExpectedValueX, 2, v)
+		}
+	}
+
+	if _, err := phashmap.Decode[string, int](bytes.NewBufferString("{"), gods.JSONCodec{}); err == nil {
+		t.Errorf(errYesError)
+	}
+}
+
+func TestNewWithHasher(t *testing.T) {
+	m := phashmap.NewWithHasher[[]byte, int](gods.BytesHasher{}, gods.BytesHasher{})
+	m = m.Assoc([]byte("a"), 1)
+	m = m.Assoc([]byte("b"), 2)
+	m = m.Assoc([]byte("a"), 3) // replaces the first entry
+
+	if m.Size() != 2 {
+		t.Errorf(errExpectedSize, 2, m.Size())
+	}
+	if v, err := m.Get([]byte("a")); err != nil || v != 3 {
+		t.Errorf(errExpectedValueX, 3, v)
+	}
+	if m.Dissoc([]byte("b")).Contains([]byte("b")) {
+		t.Errorf("Expected key to be removed")
+	}
+}
+
+func TestTransient(t *testing.T) {
+	base := phashmap.New[int, int]().Assoc(-1, -1)
+	tr := base.Transient()
+	for i := 0; i < 500; i++ {
+		if err := tr.Assoc(i, i); err != nil {
+			t.Fatalf(errNoError, err)
+		}
+	}
+	if err := tr.Dissoc(-1); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if tr.Size() != 500 {
+		t.Errorf(errExpectedSize, 500, tr.Size())
+	}
+	if v, err := tr.Get(10); err != nil || v != 10 {
+		t.Errorf(errExpectedValueX, 10, v)
+	}
+
+	m, err := tr.Persistent()
+	if err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if m.Size() != 500 {
+		t.Errorf(errExpectedSize, 500, m.Size())
+	}
+	if base.Size() != 1 || !base.Contains(-1) {
+		t.Error("Expected the original map to be unchanged")
+	}
+
+	if err := tr.Assoc(1000, 1000); err == nil {
+		t.Error(errYesError)
+	}
+	if err := tr.Dissoc(1); err == nil {
+		t.Error(errYesError)
+	}
+	if _, err := tr.Persistent(); err == nil {
+		t.Error(errYesError)
+	}
+
+	// a new transient must not modify nodes shared with m
+	tr2 := m.Transient()
+	_ = tr2.Assoc(1, 100)
+	_ = tr2.Dissoc(2)
+	if v, _ := m.Get(1); v != 1 {
+		t.Errorf(errExpectedValueX, 1, v)
+	}
+	if !m.Contains(2) {
+		t.Error("Expected key 2 to still be present")
+	}
+}
+
+func TestConcurrentReaders(t *testing.T) {
+	m := phashmap.New[int, int]()
+	for i := 0; i < 1000; i++ {
+		m = m.Assoc(i, i)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			local := m
+			for i := 0; i < 1000; i++ {
+				if v, err := m.Get(i); err != nil || v != i {
+					t.Errorf("Expected key %d to be %d, got %d (%v)", i, i, v, err)
+					return
+				}
+				local = local.Assoc(i, g)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestValidate(t *testing.T) {
+	// few distinct hashes, so the trie has collision nodes too
+	m := phashmap.NewWithHashFunc[int, int](func(k int) uint64 { return uint64(k % 7) })
+	for i := 0; i < 100; i++ {
+		m = m.Assoc(i, i)
+		if i%3 == 0 {
+			m = m.Dissoc(i / 2)
+		}
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+
+	m2 := phashmap.New[string, int]()
+	for i := 0; i < 1000; i++ {
+		m2 = m2.Assoc(strconv.Itoa(i), i)
+	}
+	for i := 0; i < 1000; i += 2 {
+		m2 = m2.Dissoc(strconv.Itoa(i))
+	}
+	if err := m2.Validate(); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if m2.Size() != 500 {
+		t.Errorf(errExpectedSize, 500, m2.Size())
+	}
+}
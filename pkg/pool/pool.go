@@ -0,0 +1,297 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pool provides a typed object pool for expensive objects (buffers,
+// parsers, connections, ...). Unlike sync.Pool, which the garbage collector
+// can empty at any time, the pool keeps its idle objects until they expire
+// (WithIdleTimeout), bounds how many are kept (WithMaxIdle) and how many
+// exist at all (WithMaxTotal, Acquire waits for a free one), validates them
+// before handing them out and resets them when they come back.
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	ErrNoNewFunc  = "the factory has no New function"
+	ErrPoolClosed = "pool is closed"
+)
+
+// Factory holds the callbacks managing the objects of a Pool. Only New is
+// required.
+type Factory[T any] struct {
+	// New creates a new object
+	New func(ctx context.Context) (T, error)
+	// Validate reports whether an idle object can still be used: it's called
+	// by Acquire before returning an idle object, and the objects failing it
+	// are destroyed
+	Validate func(T) bool
+	// Reset prepares a released object to be used again
+	Reset func(T)
+	// Destroy frees the resources of an object the pool discards
+	Destroy func(T)
+}
+
+// Option configures a Pool created with New.
+type Option func(*config)
+
+type config struct {
+	maxIdle     int // -1 is unlimited
+	maxTotal    int // 0 is unlimited
+	idleTimeout time.Duration
+	now         func() time.Time
+}
+
+// WithMaxIdle sets how many idle objects are kept (the default is no limit):
+// the objects released beyond that are destroyed.
+func WithMaxIdle(n int) Option {
+	return func(cfg *config) {
+		cfg.maxIdle = max(n, 0)
+	}
+}
+
+// WithMaxTotal sets how many objects can exist at the same time, idle or
+// acquired (the default is no limit): when they are all acquired, Acquire
+// waits for one to be released.
+func WithMaxTotal(n int) Option {
+	return func(cfg *config) {
+		cfg.maxTotal = max(n, 0)
+	}
+}
+
+// WithIdleTimeout destroys the objects that have been idle for at least d.
+// They are reaped by a background goroutine, stopped by Close (Reap can also
+// be called directly).
+func WithIdleTimeout(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.idleTimeout = d
+	}
+}
+
+// WithClock sets the function returning the current time, used for the idle
+// timeout (the default is time.Now).
+func WithClock(now func() time.Time) Option {
+	return func(cfg *config) {
+		cfg.now = now
+	}
+}
+
+// idleObject is an object waiting in the pool
+type idleObject[T any] struct {
+	obj   T
+	since time.Time
+}
+
+// Pool is a concurrency-safe pool of objects of type T.
+type Pool[T any] struct {
+	mu      sync.Mutex
+	factory Factory[T]
+	cfg     config
+	idle    []idleObject[T] // the most recently released last
+	total   int             // idle and acquired objects
+	freed   chan struct{}   // closed when an object is released or destroyed
+	closed  bool
+	stop    chan struct{} // stops the reaper
+}
+
+// New creates a new Pool managing its objects with factory.
+func New[T any](factory Factory[T], opts ...Option) (*Pool[T], error) {
+	if factory.New == nil {
+		return nil, errors.New(ErrNoNewFunc)
+	}
+	cfg := config{maxIdle: -1, now: time.Now}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.maxTotal > 0 && (cfg.maxIdle < 0 || cfg.maxIdle > cfg.maxTotal) {
+		cfg.maxIdle = cfg.maxTotal
+	}
+	p := &Pool[T]{factory: factory, cfg: cfg, freed: make(chan struct{}), stop: make(chan struct{})}
+	if cfg.idleTimeout > 0 {
+		go p.reaper()
+	}
+	return p, nil
+}
+
+// notify wakes up the Acquire calls waiting for an object (with p.mu held)
+func (p *Pool[T]) notify() {
+	close(p.freed)
+	p.freed = make(chan struct{})
+}
+
+// destroy discards objects that are no longer counted in the pool
+func (p *Pool[T]) destroy(objs ...T) {
+	if p.factory.Destroy == nil {
+		return
+	}
+	for _, obj := range objs {
+		p.factory.Destroy(obj)
+	}
+}
+
+// Acquire returns an idle object, or a new one if there's none. If the pool
+// already has WithMaxTotal objects, it waits until one is released or ctx
+// is done (then it returns ctx.Err()).
+func (p *Pool[T]) Acquire(ctx context.Context) (T, error) {
+	var zero T
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return zero, errors.New(ErrPoolClosed)
+		}
+		if n := len(p.idle); n > 0 {
+			obj := p.idle[n-1].obj
+			p.idle[n-1] = idleObject[T]{}
+			p.idle = p.idle[:n-1]
+			p.mu.Unlock()
+			if p.factory.Validate == nil || p.factory.Validate(obj) {
+				return obj, nil
+			}
+			p.Discard(obj)
+			continue
+		}
+		if p.cfg.maxTotal == 0 || p.total < p.cfg.maxTotal {
+			p.total++
+			p.mu.Unlock()
+			obj, err := p.factory.New(ctx)
+			if err != nil {
+				p.mu.Lock()
+				p.total--
+				p.notify()
+				p.mu.Unlock()
+				return zero, err
+			}
+			return obj, nil
+		}
+		freed := p.freed
+		p.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-freed:
+		}
+	}
+}
+
+// Release gives back an object returned by Acquire: it's reset and kept for
+// the next Acquire, or destroyed if the pool already has WithMaxIdle idle
+// objects or it's closed.
+func (p *Pool[T]) Release(obj T) {
+	if p.factory.Reset != nil {
+		p.factory.Reset(obj)
+	}
+	p.mu.Lock()
+	if p.closed || (p.cfg.maxIdle >= 0 && len(p.idle) >= p.cfg.maxIdle) {
+		p.total--
+		p.notify()
+		p.mu.Unlock()
+		p.destroy(obj)
+		return
+	}
+	p.idle = append(p.idle, idleObject[T]{obj: obj, since: p.cfg.now()})
+	p.notify()
+	p.mu.Unlock()
+}
+
+// Discard destroys an object returned by Acquire instead of releasing it
+// (for example because it's broken), making room for a new one.
+func (p *Pool[T]) Discard(obj T) {
+	p.mu.Lock()
+	p.total--
+	p.notify()
+	p.mu.Unlock()
+	p.destroy(obj)
+}
+
+// Reap destroys the objects that have been idle for at least the idle
+// timeout, and returns how many were destroyed. It does nothing without
+// WithIdleTimeout.
+func (p *Pool[T]) Reap() int {
+	if p.cfg.idleTimeout <= 0 {
+		return 0
+	}
+	p.mu.Lock()
+	now := p.cfg.now()
+	n := 0
+	// the oldest objects are at the front
+	for n < len(p.idle) && now.Sub(p.idle[n].since) >= p.cfg.idleTimeout {
+		n++
+	}
+	expired := make([]T, n)
+	for i := range expired {
+		expired[i] = p.idle[i].obj
+	}
+	p.idle = append(p.idle[:0], p.idle[n:]...)
+	p.total -= n
+	if n > 0 {
+		p.notify()
+	}
+	p.mu.Unlock()
+	p.destroy(expired...)
+	return n
+}
+
+// reaper calls Reap periodically, until the pool is closed
+func (p *Pool[T]) reaper() {
+	ticker := time.NewTicker(max(p.cfg.idleTimeout/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.Reap()
+		}
+	}
+}
+
+// Close destroys the idle objects and stops the reaper. The Acquire calls
+// fail with ErrPoolClosed from now on, and the objects still acquired are
+// destroyed when they are released.
+func (p *Pool[T]) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.total -= len(idle)
+	close(p.stop)
+	p.notify()
+	p.mu.Unlock()
+	for _, o := range idle {
+		p.destroy(o.obj)
+	}
+}
+
+// Idle returns the number of idle objects
+func (p *Pool[T]) Idle() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle)
+}
+
+// InUse returns the number of acquired objects
+func (p *Pool[T]) InUse() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.total - len(p.idle)
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pool "github.com/pzaino/gods/pkg/pool"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+type conn struct {
+	id      int64
+	dirty   bool
+	broken  bool
+	closed  bool
+	resetOK bool
+}
+
+// newFactory returns a factory of conns counting the ones created
+func newFactory(created *atomic.Int64) pool.Factory[*conn] {
+	return pool.Factory[*conn]{
+		New: func(context.Context) (*conn, error) {
+			return &conn{id: created.Add(1)}, nil
+		},
+		Validate: func(c *conn) bool { return !c.broken },
+		Reset:    func(c *conn) { c.dirty, c.resetOK = false, true },
+		Destroy:  func(c *conn) { c.closed = true },
+	}
+}
+
+func TestPoolReuse(t *testing.T) {
+	var created atomic.Int64
+	p, err := pool.New(newFactory(&created))
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	ctx := context.Background()
+	c, _ := p.Acquire(ctx)
+	c.dirty = true
+	p.Release(c)
+	again, _ := p.Acquire(ctx)
+	if again != c || again.dirty || !again.resetOK {
+		t.Errorf("expected the released object, reset")
+	}
+	if created.Load() != 1 || p.InUse() != 1 || p.Idle() != 0 {
+		t.Errorf(errExpectedX, 1, created.Load())
+	}
+	// an invalid idle object is destroyed and replaced
+	again.broken = true
+	p.Release(again)
+	fresh, _ := p.Acquire(ctx)
+	if fresh == again || !again.closed || created.Load() != 2 {
+		t.Errorf("expected the broken object to be destroyed")
+	}
+	p.Discard(fresh)
+	if !fresh.closed || p.InUse() != 0 {
+		t.Errorf("expected the discarded object to be destroyed")
+	}
+
+	if _, err := pool.New(pool.Factory[int]{}); err == nil || err.Error() != pool.ErrNoNewFunc {
+		t.Errorf(errExpectedX, pool.ErrNoNewFunc, err)
+	}
+}
+
+func TestPoolLimits(t *testing.T) {
+	var created atomic.Int64
+	p, _ := pool.New(newFactory(&created), pool.WithMaxTotal(2), pool.WithMaxIdle(1))
+	ctx := context.Background()
+	a, _ := p.Acquire(ctx)
+	b, _ := p.Acquire(ctx)
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Acquire(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf(errExpectedX, context.DeadlineExceeded, err)
+	}
+
+	got := make(chan *conn)
+	go func() {
+		c, _ := p.Acquire(ctx)
+		got <- c
+	}()
+	time.Sleep(10 * time.Millisecond)
+	p.Release(a)
+	if c := <-got; c != a {
+		t.Errorf(errExpectedX, a.id, c.id)
+	}
+
+	// only one idle object is kept
+	p.Release(a)
+	p.Release(b)
+	if p.Idle() != 1 || !b.closed || a.closed {
+		t.Errorf(errExpectedX, 1, p.Idle())
+	}
+}
+
+func TestPoolIdleTimeout(t *testing.T) {
+	var created atomic.Int64
+	now := time.Unix(0, 0)
+	p, _ := pool.New(newFactory(&created), pool.WithIdleTimeout(time.Hour),
+		pool.WithClock(func() time.Time { return now }))
+	defer p.Close()
+	ctx := context.Background()
+	a, _ := p.Acquire(ctx)
+	b, _ := p.Acquire(ctx)
+	p.Release(a)
+	now = now.Add(30 * time.Minute)
+	p.Release(b)
+	now = now.Add(30 * time.Minute)
+	if n := p.Reap(); n != 1 || !a.closed || b.closed {
+		t.Errorf(errExpectedX, 1, n)
+	}
+	if p.Idle() != 1 {
+		t.Errorf(errExpectedX, 1, p.Idle())
+	}
+}
+
+func TestPoolReaper(t *testing.T) {
+	var created atomic.Int64
+	p, _ := pool.New(newFactory(&created), pool.WithIdleTimeout(time.Millisecond))
+	defer p.Close()
+	c, _ := p.Acquire(context.Background())
+	p.Release(c)
+	deadline := time.Now().Add(time.Second)
+	for p.Idle() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if p.Idle() != 0 {
+		t.Errorf("expected the reaper to destroy the idle object")
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	var created atomic.Int64
+	p, _ := pool.New(newFactory(&created))
+	ctx := context.Background()
+	a, _ := p.Acquire(ctx)
+	b, _ := p.Acquire(ctx)
+	p.Release(a)
+	p.Close()
+	p.Close()
+	if !a.closed {
+		t.Errorf("expected the idle object to be destroyed")
+	}
+	if _, err := p.Acquire(ctx); err == nil || err.Error() != pool.ErrPoolClosed {
+		t.Errorf(errExpectedX, pool.ErrPoolClosed, err)
+	}
+	p.Release(b)
+	if !b.closed || p.InUse() != 0 {
+		t.Errorf("expected the released object to be destroyed")
+	}
+}
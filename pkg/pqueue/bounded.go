@@ -0,0 +1,278 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pqueue
+
+import (
+	"errors"
+	"math/bits"
+	"slices"
+
+	gods "github.com/pzaino/gods"
+)
+
+// Bounded is a priority queue holding at most a fixed number of elements:
+// enqueuing into a full queue evicts the element with the lowest priority, so
+// it keeps the best N elements seen (top-K). It's backed by a min-max heap,
+// so both the highest and the lowest priority elements are found in constant
+// time and removed in O(log n).
+type Bounded[T comparable] struct {
+	data     []Element[T] // a min-max heap, the lowest priority at the root
+	capacity uint64
+	stable   bool
+	seq      uint64
+}
+
+// NewBounded creates a new Bounded priority queue holding at most capacity
+// elements. It accepts the same options as New; with WithStableOrder the
+// most recent of the elements with the lowest priority is evicted first.
+func NewBounded[T comparable](capacity uint64, opts ...Option) *Bounded[T] {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Bounded[T]{capacity: capacity, stable: cfg.stable}
+}
+
+// lower returns true if a must be dequeued after b
+func (b *Bounded[T]) lower(x, y *Element[T]) bool {
+	if x.Priority != y.Priority {
+		return x.Priority < y.Priority
+	}
+	return b.stable && x.seq > y.seq
+}
+
+// Enqueue adds an element to the queue. If the queue is full, the element
+// with the lowest priority (possibly the new one) is evicted and returned,
+// with evicted set to true.
+func (b *Bounded[T]) Enqueue(value T, priority int) (_ Element[T], evicted bool) {
+	e := Element[T]{Value: value, Priority: priority, seq: b.seq}
+	b.seq++
+	if uint64(len(b.data)) < b.capacity {
+		b.data = append(b.data, e)
+		b.bubbleUp(len(b.data) - 1)
+		return Element[T]{}, false
+	}
+	if len(b.data) == 0 || !b.lower(&b.data[0], &e) {
+		return e, true
+	}
+	lowest := b.data[0]
+	b.data[0] = e
+	b.trickleDown(0)
+	return lowest, true
+}
+
+// Dequeue removes and returns the highest priority element in the queue
+func (b *Bounded[T]) Dequeue() (T, error) {
+	if len(b.data) == 0 {
+		var rVal T
+		return rVal, errors.New(ErrQueueIsEmpty)
+	}
+	return b.remove(b.maxIndex()).Value, nil
+}
+
+// DequeueLowest removes and returns the lowest priority element in the queue
+func (b *Bounded[T]) DequeueLowest() (T, error) {
+	if len(b.data) == 0 {
+		var rVal T
+		return rVal, errors.New(ErrQueueIsEmpty)
+	}
+	return b.remove(0).Value, nil
+}
+
+// Peek returns the highest priority element in the queue without removing it
+func (b *Bounded[T]) Peek() (T, error) {
+	if len(b.data) == 0 {
+		var rVal T
+		return rVal, errors.New(ErrQueueIsEmpty)
+	}
+	return b.data[b.maxIndex()].Value, nil
+}
+
+// PeekLowest returns the lowest priority element in the queue (the next one
+// to be evicted) without removing it
+func (b *Bounded[T]) PeekLowest() (T, error) {
+	if len(b.data) == 0 {
+		var rVal T
+		return rVal, errors.New(ErrQueueIsEmpty)
+	}
+	return b.data[0].Value, nil
+}
+
+// Size returns the number of elements in the queue
+func (b *Bounded[T]) Size() uint64 {
+	return uint64(len(b.data))
+}
+
+// Capacity returns the maximum number of elements in the queue
+func (b *Bounded[T]) Capacity() uint64 {
+	return b.capacity
+}
+
+// IsEmpty returns true if the queue is empty
+func (b *Bounded[T]) IsEmpty() bool {
+	return len(b.data) == 0
+}
+
+// IsFull returns true if the next Enqueue will evict an element
+func (b *Bounded[T]) IsFull() bool {
+	return uint64(len(b.data)) >= b.capacity
+}
+
+// Clear removes all elements from the queue
+func (b *Bounded[T]) Clear() {
+	b.data = nil
+}
+
+// Sorted returns the elements in the queue from the highest to the lowest
+// priority, without removing them.
+func (b *Bounded[T]) Sorted() []Element[T] {
+	sorted := slices.Clone(b.data)
+	slices.SortFunc(sorted, func(x, y Element[T]) int {
+		switch {
+		case b.lower(&y, &x):
+			return -1
+		case b.lower(&x, &y):
+			return 1
+		}
+		return 0
+	})
+	return sorted
+}
+
+// Validate checks the invariants of the queue: its size and the min-max
+// heap property (see gods.Validator).
+func (b *Bounded[T]) Validate() error {
+	if uint64(len(b.data)) > b.capacity {
+		return gods.Invariantf("the queue holds %d elements, more than its capacity %d", len(b.data), b.capacity)
+	}
+	for i := 1; i < len(b.data); i++ {
+		p := (i - 1) / 2
+		lo, hi := p, p // the parent bounds i from below on a max level, from above on a min level
+		if minLevel(i) {
+			lo = -1
+			if i > 2 {
+				lo = (p - 1) / 2
+			}
+		} else {
+			hi = -1
+			if i > 2 {
+				hi = (p - 1) / 2
+			}
+		}
+		if lo >= 0 && b.lower(&b.data[i], &b.data[lo]) {
+			return gods.Invariantf("element %d has a lower priority (%d) than element %d (%d)",
+				i, b.data[i].Priority, lo, b.data[lo].Priority)
+		}
+		if hi >= 0 && b.lower(&b.data[hi], &b.data[i]) {
+			return gods.Invariantf("element %d has a higher priority (%d) than element %d (%d)",
+				i, b.data[i].Priority, hi, b.data[hi].Priority)
+		}
+	}
+	return nil
+}
+
+// minLevel returns true if the element at index i is on a min level of the
+// heap (the levels alternate, starting with the min level of the root)
+func minLevel(i int) bool {
+	return bits.Len(uint(i+1))%2 == 1
+}
+
+// maxIndex returns the index of the highest priority element (the queue must
+// not be empty)
+func (b *Bounded[T]) maxIndex() int {
+	switch {
+	case len(b.data) == 1:
+		return 0
+	case len(b.data) == 2 || b.lower(&b.data[2], &b.data[1]):
+		return 1
+	}
+	return 2
+}
+
+// remove removes and returns the element at index i
+func (b *Bounded[T]) remove(i int) Element[T] {
+	e := b.data[i]
+	last := len(b.data) - 1
+	b.data[i] = b.data[last]
+	b.data = b.data[:last]
+	if i < last {
+		b.trickleDown(i)
+	}
+	return e
+}
+
+// before returns true if the element at index i belongs above the element at
+// index j on a min level (lower priority) or a max level (higher priority)
+func (b *Bounded[T]) before(i, j int, min bool) bool {
+	if min {
+		return b.lower(&b.data[i], &b.data[j])
+	}
+	return b.lower(&b.data[j], &b.data[i])
+}
+
+// swap swaps the elements at index i and j
+func (b *Bounded[T]) swap(i, j int) {
+	b.data[i], b.data[j] = b.data[j], b.data[i]
+}
+
+// bubbleUp moves the element at index i up to its place
+func (b *Bounded[T]) bubbleUp(i int) {
+	if i == 0 {
+		return
+	}
+	min := minLevel(i)
+	if p := (i - 1) / 2; b.before(p, i, min) {
+		// the element belongs to the levels of the other kind
+		b.swap(i, p)
+		i, min = p, !min
+	}
+	for i > 2 {
+		g := ((i-1)/2 - 1) / 2
+		if !b.before(i, g, min) {
+			break
+		}
+		b.swap(i, g)
+		i = g
+	}
+}
+
+// trickleDown moves the element at index i down to its place
+func (b *Bounded[T]) trickleDown(i int) {
+	min := minLevel(i)
+	for {
+		// m is the first of the children and grandchildren of i
+		first := 2*i + 1
+		if first >= len(b.data) {
+			return
+		}
+		m := first
+		for _, j := range [...]int{first + 1, 2*first + 1, 2*first + 2, 2*first + 3, 2*first + 4} {
+			if j < len(b.data) && b.before(j, m, min) {
+				m = j
+			}
+		}
+		if !b.before(m, i, min) {
+			return
+		}
+		b.swap(m, i)
+		if m <= first+1 {
+			return // a child
+		}
+		if p := (m - 1) / 2; b.before(p, m, min) {
+			b.swap(m, p)
+		}
+		i = m
+	}
+}
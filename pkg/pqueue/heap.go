@@ -0,0 +1,88 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pqueue
+
+import (
+	"container/heap"
+)
+
+// Heap adapts a PriorityQueue to heap.Interface, so code written for
+// container/heap can work on it while being migrated. The adapter and the
+// queue share the same elements, kept as a max-heap by priority: operations
+// done through the heap package are visible through the queue and vice versa.
+//
+// As with any heap.Interface, Push and Pop must be called through the heap
+// package (heap.Push(h, element), heap.Pop(h).(pqueue.Element[T])); their
+// argument and result are Elements.
+type Heap[T comparable] struct {
+	pq *PriorityQueue[T]
+}
+
+// compile-time check
+var _ heap.Interface = (*Heap[int])(nil)
+
+// AsHeap returns a heap.Interface adapter over the priority queue.
+func (pq *PriorityQueue[T]) AsHeap() *Heap[T] {
+	return &Heap[T]{pq: pq}
+}
+
+// Queue returns the adapted priority queue
+func (h *Heap[T]) Queue() *PriorityQueue[T] {
+	return h.pq
+}
+
+// Len returns the number of elements
+func (h *Heap[T]) Len() int {
+	return len(h.pq.data)
+}
+
+// Less orders the elements by decreasing priority (the queue is a max-heap),
+// and by insertion order if the queue is stable
+func (h *Heap[T]) Less(i, j int) bool {
+	return h.pq.higher(&h.pq.data[i], &h.pq.data[j])
+}
+
+// Swap swaps two elements
+func (h *Heap[T]) Swap(i, j int) {
+	h.pq.data[i], h.pq.data[j] = h.pq.data[j], h.pq.data[i]
+}
+
+// Push appends x, which must be an Element[T], at the end of the heap (call
+// heap.Push instead)
+func (h *Heap[T]) Push(x any) {
+	h.pq.data = append(h.pq.data, x.(Element[T]))
+	h.pq.size++
+}
+
+// Pop removes the last element and returns it as an Element[T] (call
+// heap.Pop instead)
+func (h *Heap[T]) Pop() any {
+	last := h.pq.data[len(h.pq.data)-1]
+	h.pq.data = h.pq.data[:len(h.pq.data)-1]
+	h.pq.size--
+	return last
+}
+
+// FromHeap creates a new priority queue with the elements of a
+// heap.Interface, that are removed from it with heap.Pop. Each popped value is
+// converted to an element by toElement.
+func FromHeap[T comparable](h heap.Interface, toElement func(x any) Element[T]) *PriorityQueue[T] {
+	pq := New[T]()
+	for h.Len() > 0 {
+		e := toElement(heap.Pop(h))
+		pq.Enqueue(e.Value, e.Priority)
+	}
+	return pq
+}
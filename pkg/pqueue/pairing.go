@@ -0,0 +1,209 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pqueue
+
+import (
+	"errors"
+	"iter"
+
+	gods "github.com/pzaino/gods"
+)
+
+// PairingHeap is a priority queue backed by a pairing heap. Unlike
+// PriorityQueue, two queues can be merged with Meld in constant time, without
+// moving their elements, which makes it the right choice to merge the work
+// queues of several producers. Enqueue and Meld are O(1), Dequeue is
+// O(log n) amortized.
+type PairingHeap[T comparable] struct {
+	root   *pairingNode[T]
+	size   uint64
+	stable bool
+	seq    uint64
+}
+
+// pairingNode is a node of a PairingHeap: its children are a list linked by
+// sibling, and none of them has a higher priority than the node
+type pairingNode[T comparable] struct {
+	element Element[T]
+	child   *pairingNode[T]
+	sibling *pairingNode[T]
+}
+
+// NewPairingHeap creates a new PairingHeap. It accepts the same options as
+// New.
+func NewPairingHeap[T comparable](opts ...Option) *PairingHeap[T] {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &PairingHeap[T]{stable: cfg.stable}
+}
+
+// higher returns true if a must be dequeued before b
+func (h *PairingHeap[T]) higher(a, b *Element[T]) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return h.stable && a.seq < b.seq
+}
+
+// link makes the root with the lower priority a child of the other one and
+// returns the new root
+func (h *PairingHeap[T]) link(a, b *pairingNode[T]) *pairingNode[T] {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if h.higher(&b.element, &a.element) {
+		a, b = b, a
+	}
+	b.sibling = a.child
+	a.child = b
+	return a
+}
+
+// IsEmpty returns true if the heap is empty
+func (h *PairingHeap[T]) IsEmpty() bool {
+	return h.size == 0
+}
+
+// Size returns the number of elements in the heap
+func (h *PairingHeap[T]) Size() uint64 {
+	return h.size
+}
+
+// Enqueue adds an element to the heap
+func (h *PairingHeap[T]) Enqueue(value T, priority int) {
+	n := &pairingNode[T]{element: Element[T]{Value: value, Priority: priority, seq: h.seq}}
+	h.seq++
+	h.root = h.link(h.root, n)
+	h.size++
+}
+
+// Peek returns the highest priority element in the heap without removing it
+func (h *PairingHeap[T]) Peek() (T, error) {
+	if h.root == nil {
+		var rVal T
+		return rVal, errors.New(ErrQueueIsEmpty)
+	}
+	return h.root.element.Value, nil
+}
+
+// Dequeue removes and returns the highest priority element in the heap
+func (h *PairingHeap[T]) Dequeue() (T, error) {
+	if h.root == nil {
+		var rVal T
+		return rVal, errors.New(ErrQueueIsEmpty)
+	}
+	value := h.root.element.Value
+	h.root = h.mergePairs(h.root.child)
+	h.size--
+	return value, nil
+}
+
+// mergePairs merges a list of siblings into a single tree with the two-pass
+// strategy: the siblings are linked in pairs from left to right, then the
+// pairs are linked from right to left
+func (h *PairingHeap[T]) mergePairs(first *pairingNode[T]) *pairingNode[T] {
+	var pairs []*pairingNode[T]
+	for first != nil {
+		a, b := first, first.sibling
+		if b == nil {
+			a.sibling = nil
+			pairs = append(pairs, a)
+			break
+		}
+		first = b.sibling
+		a.sibling, b.sibling = nil, nil
+		pairs = append(pairs, h.link(a, b))
+	}
+	var root *pairingNode[T]
+	for i := len(pairs) - 1; i >= 0; i-- {
+		root = h.link(root, pairs[i])
+	}
+	return root
+}
+
+// Meld moves all the elements of other into the heap in constant time,
+// leaving other empty. With WithStableOrder, the elements with the same
+// priority keep their insertion order only with respect to the elements
+// enqueued in the same heap.
+func (h *PairingHeap[T]) Meld(other *PairingHeap[T]) {
+	if h == other {
+		return
+	}
+	h.root = h.link(h.root, other.root)
+	h.size += other.size
+	h.seq = max(h.seq, other.seq)
+	other.Clear()
+}
+
+// Clear removes all elements from the heap
+func (h *PairingHeap[T]) Clear() {
+	h.root = nil
+	h.size = 0
+}
+
+// Iter returns an iterator over the values in the heap
+// Please note: the values are returned in heap order, not in priority order.
+func (h *PairingHeap[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var stack []*pairingNode[T]
+		if h.root != nil {
+			stack = append(stack, h.root)
+		}
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !yield(n.element.Value) {
+				return
+			}
+			for c := n.child; c != nil; c = c.sibling {
+				stack = append(stack, c)
+			}
+		}
+	}
+}
+
+// Validate checks the invariants of the heap: its size and the heap
+// property (see gods.Validator).
+func (h *PairingHeap[T]) Validate() error {
+	var count uint64
+	var stack []*pairingNode[T]
+	if h.root != nil {
+		if h.root.sibling != nil {
+			return gods.Invariantf("the root has a sibling")
+		}
+		stack = append(stack, h.root)
+	}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		count++
+		for c := n.child; c != nil; c = c.sibling {
+			if h.higher(&c.element, &n.element) {
+				return gods.Invariantf("element with priority %d is a child of an element with priority %d",
+					c.element.Priority, n.element.Priority)
+			}
+			stack = append(stack, c)
+		}
+	}
+	if count != h.size {
+		return gods.Invariantf("size is %d but the heap holds %d elements", h.size, count)
+	}
+	return nil
+}
@@ -17,7 +17,15 @@ package pqueue
 
 import (
 	"errors"
+	"io"
+	"iter"
+	"slices"
 	"strings"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	iterator "github.com/pzaino/gods/pkg/iterator"
+	optional "github.com/pzaino/gods/pkg/optional"
 )
 
 const (
@@ -30,12 +38,46 @@ const (
 type Element[T comparable] struct {
 	Value    T
 	Priority int
+	seq      uint64 // insertion order, breaking the ties of a stable queue
 }
 
 // PriorityQueue is a priority queue data structure
 type PriorityQueue[T comparable] struct {
-	data []Element[T]
-	size uint64
+	data   []Element[T]
+	size   uint64
+	growth gods.Growth
+	stable bool   // ties are broken by insertion order
+	seq    uint64 // the insertion order of the next element
+}
+
+// config is the configuration of a PriorityQueue, set by the options of New
+type config struct {
+	stable bool
+}
+
+// Option configures a PriorityQueue created with New.
+type Option func(*config)
+
+// WithStableOrder makes the queue dequeue the elements with the same
+// priority in the order they were enqueued (FIFO), instead of in an
+// unspecified order. The ties are broken by an insertion counter.
+func WithStableOrder() Option {
+	return func(cfg *config) {
+		cfg.stable = true
+	}
+}
+
+// higher returns true if a must be dequeued before b
+func (pq *PriorityQueue[T]) higher(a, b *Element[T]) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return pq.stable && a.seq < b.seq
+}
+
+// empty returns a new empty queue with the same configuration as pq
+func (pq *PriorityQueue[T]) empty() *PriorityQueue[T] {
+	return &PriorityQueue[T]{stable: pq.stable}
 }
 
 // Helper functions for heap operations
@@ -44,7 +86,7 @@ type PriorityQueue[T comparable] struct {
 func (pq *PriorityQueue[T]) upHeap(index uint64) {
 	for index > 0 {
 		parent := (index - 1) / 2
-		if pq.data[index].Priority <= pq.data[parent].Priority {
+		if !pq.higher(&pq.data[index], &pq.data[parent]) {
 			break
 		}
 		pq.data[index], pq.data[parent] = pq.data[parent], pq.data[index]
@@ -63,10 +105,10 @@ func (pq *PriorityQueue[T]) downHeap(index uint64) {
 		}
 		right := left + 1
 		child := left
-		if right <= lastIndex && pq.data[right].Priority > pq.data[left].Priority {
+		if right <= lastIndex && pq.higher(&pq.data[right], &pq.data[left]) {
 			child = right
 		}
-		if element.Priority >= pq.data[child].Priority {
+		if !pq.higher(&pq.data[child], &element) {
 			break
 		}
 		pq.data[index] = pq.data[child]
@@ -76,8 +118,12 @@ func (pq *PriorityQueue[T]) downHeap(index uint64) {
 }
 
 // New creates a new PriorityQueue
-func New[T comparable]() *PriorityQueue[T] {
-	return &PriorityQueue[T]{}
+func New[T comparable](opts ...Option) *PriorityQueue[T] {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &PriorityQueue[T]{stable: cfg.stable}
 }
 
 // IsEmpty returns true if the priority queue is empty
@@ -87,8 +133,13 @@ func (pq *PriorityQueue[T]) IsEmpty() bool {
 
 // Enqueue adds an element to the priority queue
 func (pq *PriorityQueue[T]) Enqueue(value T, priority int) {
-	element := Element[T]{Value: value, Priority: priority}
-	pq.data = append(pq.data, element)
+	pq.push(Element[T]{Value: value, Priority: priority, seq: pq.seq})
+}
+
+// push adds an element keeping its insertion order
+func (pq *PriorityQueue[T]) push(element Element[T]) {
+	pq.seq = max(pq.seq, element.seq+1)
+	pq.data = gods.Append(&pq.growth, pq.data, element)
 	pq.size++
 	pq.upHeap(pq.size - 1)
 }
@@ -191,11 +242,79 @@ func (pq *PriorityQueue[T]) Peek() (T, error) {
 	return pq.data[0].Value, nil
 }
 
+// DequeueOpt is like Dequeue, but it returns the value as an Option (None if
+// the queue is empty), without allocating an error.
+func (pq *PriorityQueue[T]) DequeueOpt() optional.Option[T] {
+	if pq.IsEmpty() {
+		return optional.None[T]()
+	}
+	value, _ := pq.Dequeue()
+	return optional.Some(value)
+}
+
+// PeekOpt is like Peek, but it returns the value as an Option.
+func (pq *PriorityQueue[T]) PeekOpt() optional.Option[T] {
+	if pq.IsEmpty() {
+		return optional.None[T]()
+	}
+	return optional.Some(pq.data[0].Value)
+}
+
 // Size returns the number of elements in the priority queue
 func (pq *PriorityQueue[T]) Size() uint64 {
 	return pq.size
 }
 
+// MemoryUsage returns an estimate of the bytes held by the queue (see the
+// gods package), adding the memory returned by extra for every value.
+func (pq *PriorityQueue[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
+	n := uint64(unsafe.Sizeof(*pq)) + gods.SliceMemory(pq.data)
+	for _, fn := range extra {
+		for _, e := range pq.data {
+			n += fn(e.Value)
+		}
+	}
+	return n
+}
+
+// Stats returns the statistics of the queue: its peak size and how many
+// times its backing array was reallocated to grow.
+func (pq *PriorityQueue[T]) Stats() gods.Stats {
+	return gods.Stats{Growth: pq.growth.Stats()}
+}
+
+// Validate checks the invariants of the priority queue: its size and the heap property (see gods.Validator).
+func (pq *PriorityQueue[T]) Validate() error {
+	if pq.size != uint64(len(pq.data)) {
+		return gods.Invariantf("size is %d but the queue holds %d elements", pq.size, len(pq.data))
+	}
+	for i := 1; i < len(pq.data); i++ {
+		if parent := (i - 1) / 2; pq.higher(&pq.data[i], &pq.data[parent]) {
+			return gods.Invariantf("element %d has priority %d, higher than its parent %d (%d)",
+				i, pq.data[i].Priority, parent, pq.data[parent].Priority)
+		}
+	}
+	return nil
+}
+
+// Dump writes the shape of the heap to w, every element indented under its
+// parent, for debugging.
+func (pq *PriorityQueue[T]) Dump(w io.Writer) error {
+	d := gods.NewDumper(w)
+	d.Line(0, "PriorityQueue size=%d cap=%d", pq.size, cap(pq.data))
+	var dump func(i, depth int)
+	dump = func(i, depth int) {
+		if i >= len(pq.data) {
+			return
+		}
+		d.Line(depth, "[%d] priority=%d value=%v", i, pq.data[i].Priority, pq.data[i].Value)
+		dump(2*i+1, depth+1)
+		dump(2*i+2, depth+1)
+	}
+	dump(0, 1)
+	return d.Err()
+}
+
 // CheckSize recalculate the size of the priority queue
 func (pq *PriorityQueue[T]) CheckSize() {
 	pq.size = uint64(len(pq.data))
@@ -245,9 +364,9 @@ func (pq *PriorityQueue[T]) Equals(other *PriorityQueue[T]) bool {
 
 // Copy returns a copy of the priority queue
 func (pq *PriorityQueue[T]) Copy() *PriorityQueue[T] {
-	copy := New[T]()
+	copy := pq.empty()
 	copy.data = append(copy.data, pq.data...)
-	copy.size = pq.size
+	copy.size, copy.seq = pq.size, pq.seq
 	return copy
 }
 
@@ -281,9 +400,10 @@ func (pq *PriorityQueue[T]) dataString(f func(T) string) string {
 
 // Map creates a new priority queue with the results of applying the function to each element
 func (pq *PriorityQueue[T]) Map(f func(T) T) *PriorityQueue[T] {
-	newQueue := New[T]()
-	for i := 0; i < len(pq.data); i++ {
-		newQueue.Enqueue(f(pq.data[i].Value), pq.data[i].Priority)
+	newQueue := pq.empty()
+	for _, e := range pq.data {
+		e.Value = f(e.Value)
+		newQueue.push(e)
 	}
 	return newQueue
 }
@@ -408,10 +528,10 @@ func (pq *PriorityQueue[T]) FindLastIndex(f func(T) bool) (uint64, error) {
 
 // FindAll returns all elements that match the predicate
 func (pq *PriorityQueue[T]) FindAll(f func(T) bool) *PriorityQueue[T] {
-	newQueue := New[T]()
+	newQueue := pq.empty()
 	for i := uint64(0); i < pq.size; i++ {
 		if f(pq.data[i].Value) {
-			newQueue.Enqueue(pq.data[i].Value, pq.data[i].Priority)
+			newQueue.push(pq.data[i])
 		}
 	}
 	return newQueue
@@ -443,3 +563,136 @@ func (pq *PriorityQueue[T]) FindAllIndexes(f func(T) bool) []uint64 {
 	}
 	return result
 }
+
+// ToSlice returns the values in the priority queue (it does not remove them!)
+// Please note: the values are returned in heap order, not in priority order.
+func (pq *PriorityQueue[T]) ToSlice() []T {
+	return pq.Values()
+}
+
+// Iter returns an iterator over the values in the priority queue
+// Please note: the values are returned in heap order, not in priority order.
+func (pq *PriorityQueue[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := 0; i < len(pq.data); i++ {
+			if !yield(pq.data[i].Value) {
+				return
+			}
+		}
+	}
+}
+
+// Iterator returns an iterator over the values in the priority queue
+// Please note: the values are returned in heap order, not in priority order.
+func (pq *PriorityQueue[T]) Iterator() iterator.Iterator[T] {
+	i := 0
+	return iterator.FromFunc(func() (T, bool) {
+		if i >= len(pq.data) {
+			var zero T
+			return zero, false
+		}
+		i++
+		return pq.data[i-1].Value, true
+	})
+}
+
+// Enumerate returns an iterator over the heap index and the value of the elements
+// in the priority queue
+func (pq *PriorityQueue[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		for i := 0; i < len(pq.data); i++ {
+			if !yield(uint64(i), pq.data[i].Value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (pq *PriorityQueue[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(pq.Enumerate())
+}
+
+// AllSorted returns an iterator over the values in the priority queue in
+// priority order, highest first, without removing them. It iterates over a
+// snapshot taken when the iteration starts, dequeuing from a copy of the heap
+// as the values are requested, so stopping early costs O(n + k log n) for k
+// values.
+func (pq *PriorityQueue[T]) AllSorted() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		snapshot := pq.Copy()
+		for !snapshot.IsEmpty() {
+			value, _ := snapshot.Dequeue()
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// Encode writes the elements of the priority queue (with their priorities) to w using the given codec
+func (pq *PriorityQueue[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeSlice(w, codec, pq.data)
+}
+
+// Decode replaces the content of the priority queue with the elements read from r using the given codec
+func (pq *PriorityQueue[T]) Decode(r io.Reader, codec gods.Codec) error {
+	items, err := gods.DecodeSlice[Element[T]](r, codec)
+	if err != nil {
+		return err
+	}
+	pq.Clear()
+	for _, item := range items {
+		pq.Enqueue(item.Value, item.Priority)
+	}
+	return nil
+}
+
+// EncodeStream writes the priority queue (values and priorities) to w
+// incrementally, in chunks encoded with the given codec (see
+// gods.EncodeStream), without copying its elements first.
+func (pq *PriorityQueue[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeStream(w, codec, slices.Values(pq.data))
+}
+
+// DecodeStream replaces the content of the priority queue with the elements
+// of a stream written by EncodeStream, adding them as they are read.
+func (pq *PriorityQueue[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	pq.Clear()
+	return gods.DecodeStream(r, codec, func(item Element[T]) error {
+		pq.Enqueue(item.Value, item.Priority)
+		return nil
+	})
+}
+
+// MarshalText implements encoding.TextMarshaler: the queue is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (pq *PriorityQueue[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(pq)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (pq *PriorityQueue[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(pq, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (pq *PriorityQueue[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(pq)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (pq *PriorityQueue[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(pq, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (pq *PriorityQueue[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(pq)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (pq *PriorityQueue[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(pq, data)
+}
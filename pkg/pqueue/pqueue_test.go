@@ -16,9 +16,14 @@
 package pqueue_test
 
 import (
+	"bytes"
+	"container/heap"
 	"fmt"
+	"slices"
 	"testing"
 
+	gods "github.com/pzaino/gods"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 	"github.com/pzaino/gods/pkg/pqueue"
 )
 
@@ -480,3 +485,370 @@ func TestCheckSize(t *testing.T) {
 		t.Fatal("Expected priority queue size to be 3 after calling CheckSize")
 	}
 }
+
+func TestToSliceAndIter(t *testing.T) {
+	pq := pqueue.New[int]()
+	pq.Enqueue(1, 1)
+	pq.Enqueue(3, 3)
+	pq.Enqueue(2, 2)
+
+	items := pq.ToSlice()
+	if len(items) != 3 || items[0] != 3 {
+		t.Errorf("Expected 3 items with the highest priority first, got %v", items)
+	}
+	if got := slices.Collect(pq.Iter()); !slices.Equal(got, items) {
+		t.Errorf("Expected %v, got %v", items, got)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	pq := pqueue.New[int]()
+	pq.Enqueue(1, 1)
+	pq.Enqueue(3, 3)
+	pq.Enqueue(2, 2)
+	if got := iterator.ToSlice(pq.Iterator()); !slices.Equal(got, pq.Values()) {
+		t.Errorf("Expected %v, got %v", pq.Values(), got)
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	pq := pqueue.New[int]()
+	pq.Enqueue(1, 1)
+	var indexes []uint64
+	var values []int
+	for i, v := range pq.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0}) {
+		t.Errorf("Expected %v, got %v", []uint64{0}, indexes)
+	}
+	if !slices.Equal(values, []int{1}) {
+		t.Errorf("Expected %v, got %v", []int{1}, values)
+	}
+	for range pqueue.New[int]().Enumerate() {
+		t.Error("Expected empty iteration")
+	}
+	if got := slices.Collect(pq.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	pq := pqueue.New[string]()
+	pq.Enqueue("low", 1)
+	pq.Enqueue("high", 10)
+	pq.Enqueue("mid", 5)
+
+	var buf bytes.Buffer
+	if err := pq.Encode(&buf, gods.JSONCodec{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	decoded := pqueue.New[string]()
+	if err := decoded.Decode(&buf, gods.JSONCodec{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// priorities must survive the round trip
+	for _, expected := range []string{"high", "mid", "low"} {
+		item, err := decoded.Dequeue()
+		if err != nil || item != expected {
+			t.Errorf("Expected %v, got %v (%v)", expected, item, err)
+		}
+	}
+}
+
+func TestHeapAdapter(t *testing.T) {
+	pq := pqueue.New[string]()
+	pq.Enqueue("b", 2)
+	h := pq.AsHeap()
+	heap.Push(h, pqueue.Element[string]{Value: "c", Priority: 3})
+	heap.Push(h, pqueue.Element[string]{Value: "a", Priority: 1})
+	if pq.Size() != 3 || h.Len() != 3 {
+		t.Fatalf("Expected 3 elements, got %d and %d", pq.Size(), h.Len())
+	}
+	if e := heap.Pop(h).(pqueue.Element[string]); e.Value != "c" {
+		t.Errorf("Expected c, got %v", e.Value)
+	}
+	// the queue sees the changes made through the heap package
+	if v, _ := pq.Dequeue(); v != "b" {
+		t.Errorf("Expected b, got %v", v)
+	}
+	if v, _ := pq.Dequeue(); v != "a" || h.Len() != 0 {
+		t.Errorf("Expected a and an empty heap, got %v and %d elements", v, h.Len())
+	}
+}
+
+// intHeap is a typical container/heap min-heap
+type intHeap []int
+
+func (h intHeap) Len() int           { return len(h) }
+func (h intHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *intHeap) Push(x any)        { *h = append(*h, x.(int)) }
+func (h *intHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func TestFromHeap(t *testing.T) {
+	h := &intHeap{5, 1, 3}
+	heap.Init(h)
+	// smaller values first: use the negated value as priority
+	pq := pqueue.FromHeap(h, func(x any) pqueue.Element[int] {
+		return pqueue.Element[int]{Value: x.(int), Priority: -x.(int)}
+	})
+	if h.Len() != 0 {
+		t.Errorf("Expected the source heap to be drained, got %d elements", h.Len())
+	}
+	values, _ := pq.DequeueAll()
+	if !slices.Equal(values, []int{1, 3, 5}) {
+		t.Errorf("Expected [1 3 5], got %v", values)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	pq := pqueue.New[string]()
+	for i, v := range []string{"a", "b", "c", "d"} {
+		pq.Enqueue(v, i)
+	}
+	if err := pq.Validate(); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	// swapping the elements directly breaks the heap property
+	pq.AsHeap().Swap(0, 3)
+	if err := pq.Validate(); err == nil {
+		t.Errorf("Expected a heap property violation")
+	}
+}
+
+func TestDump(t *testing.T) {
+	pq := pqueue.New[string]()
+	for i, v := range []string{"a", "b", "c", "d"} {
+		pq.Enqueue(v, i)
+	}
+	var buf bytes.Buffer
+	if err := pq.Dump(&buf); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	want := `PriorityQueue size=4 cap=4
+  [0] priority=3 value=d
+    [1] priority=2 value=c
+      [3] priority=0 value=a
+    [2] priority=1 value=b
+`
+	if buf.String() != want {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", want, buf.String())
+	}
+}
+
+func TestStableOrder(t *testing.T) {
+	pq := pqueue.New[int](pqueue.WithStableOrder())
+	// the values encode the priority (tens) and the insertion order (units)
+	for i := 0; i < 10; i++ {
+		pq.Enqueue(10*(i%3)+i, i%3)
+	}
+	if err := pq.Validate(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	copy := pq.Copy()
+	want := []int{22, 25, 28, 11, 14, 17, 0, 3, 6, 9}
+	for _, q := range []*pqueue.PriorityQueue[int]{pq, copy} {
+		got, err := q.DequeueAll()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestPairingHeapMeld(t *testing.T) {
+	a, b := pqueue.NewPairingHeap[int](), pqueue.NewPairingHeap[int]()
+	for i := 0; i < 50; i++ {
+		a.Enqueue(2*i, 2*i)
+		b.Enqueue(2*i+1, 2*i+1)
+	}
+	a.Meld(b)
+	if !b.IsEmpty() || a.Size() != 100 {
+		t.Fatalf("Expected 100 and 0 elements, got %d and %d", a.Size(), b.Size())
+	}
+	if err := a.Validate(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if v, err := a.Peek(); err != nil || v != 99 {
+		t.Errorf("Expected 99, got %v", v)
+	}
+	for want := 99; want >= 0; want-- {
+		v, err := a.Dequeue()
+		if err != nil || v != want {
+			t.Fatalf("Expected %d, got %v (%v)", want, v, err)
+		}
+		if want%10 == 0 {
+			if err := a.Validate(); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+	}
+	if _, err := a.Dequeue(); err == nil || err.Error() != pqueue.ErrQueueIsEmpty {
+		t.Errorf("Expected %v, got %v", pqueue.ErrQueueIsEmpty, err)
+	}
+}
+
+func TestPairingHeapStableOrder(t *testing.T) {
+	h := pqueue.NewPairingHeap[string](pqueue.WithStableOrder())
+	for _, v := range []string{"a", "b", "c", "d"} {
+		h.Enqueue(v, 1)
+	}
+	h.Enqueue("first", 2)
+	var got []string
+	for !h.IsEmpty() {
+		v, _ := h.Dequeue()
+		got = append(got, v)
+	}
+	if want := []string{"first", "a", "b", "c", "d"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestBounded(t *testing.T) {
+	b := pqueue.NewBounded[int](5)
+	var evicted []int
+	for _, p := range []int{5, 1, 9, 3, 7, 2, 8, 6, 4, 0} {
+		if e, ok := b.Enqueue(p, p); ok {
+			evicted = append(evicted, e.Value)
+		}
+		if err := b.Validate(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if want := []int{1, 2, 3, 4, 0}; !slices.Equal(evicted, want) {
+		t.Errorf("Expected %v, got %v", want, evicted)
+	}
+	if v, err := b.PeekLowest(); err != nil || v != 5 {
+		t.Errorf("Expected 5, got %v", v)
+	}
+	var sorted []int
+	for _, e := range b.Sorted() {
+		sorted = append(sorted, e.Value)
+	}
+	if want := []int{9, 8, 7, 6, 5}; !slices.Equal(sorted, want) {
+		t.Errorf("Expected %v, got %v", want, sorted)
+	}
+	for _, want := range []int{9, 8, 7, 6, 5} {
+		if v, err := b.Dequeue(); err != nil || v != want {
+			t.Errorf("Expected %d, got %v (%v)", want, v, err)
+		}
+	}
+	if _, err := b.Dequeue(); err == nil || err.Error() != pqueue.ErrQueueIsEmpty {
+		t.Errorf("Expected %v, got %v", pqueue.ErrQueueIsEmpty, err)
+	}
+}
+
+func TestBoundedRandom(t *testing.T) {
+	b := pqueue.NewBounded[int](64)
+	var kept []int
+	seed := uint32(7)
+	for i := 0; i < 2000; i++ {
+		seed = seed*1664525 + 1013904223
+		p := int(seed>>16) % 1000
+		switch seed % 4 {
+		case 0:
+			v, err := b.DequeueLowest()
+			if len(kept) == 0 {
+				if err == nil {
+					t.Fatalf("Expected an error")
+				}
+				continue
+			}
+			if err != nil || v != kept[0] {
+				t.Fatalf("Expected %d, got %v (%v)", kept[0], v, err)
+			}
+			kept = kept[1:]
+		case 1:
+			v, err := b.Dequeue()
+			if len(kept) == 0 {
+				if err == nil {
+					t.Fatalf("Expected an error")
+				}
+				continue
+			}
+			if err != nil || v != kept[len(kept)-1] {
+				t.Fatalf("Expected %d, got %v (%v)", kept[len(kept)-1], v, err)
+			}
+			kept = kept[:len(kept)-1]
+		default:
+			b.Enqueue(p, p)
+			kept = append(kept, p)
+			slices.Sort(kept)
+			if len(kept) > 64 {
+				kept = kept[1:]
+			}
+		}
+		if err := b.Validate(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+}
+
+func TestAllSorted(t *testing.T) {
+	pq := pqueue.New[int]()
+	for _, p := range []int{3, 9, 1, 7, 5} {
+		pq.Enqueue(p, p)
+	}
+	if got := slices.Collect(pq.AllSorted()); !slices.Equal(got, []int{9, 7, 5, 3, 1}) {
+		t.Errorf("Expected [9 7 5 3 1], got %v", got)
+	}
+	for v := range pq.AllSorted() {
+		if v != 9 {
+			t.Errorf("Expected 9, got %v", v)
+		}
+		break
+	}
+	if pq.Size() != 5 {
+		t.Errorf("Expected size 5, got %d", pq.Size())
+	}
+	if err := pq.Validate(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestDequeueOptPeekOpt(t *testing.T) {
+	pq := pqueue.New[string]()
+	if pq.DequeueOpt().IsSome() || pq.PeekOpt().IsSome() {
+		t.Errorf("Expected None from an empty queue")
+	}
+	pq.Enqueue("low", 1)
+	pq.Enqueue("high", 9)
+	if v := pq.PeekOpt().MustGet(); v != "high" {
+		t.Errorf("Expected %v, got %v", "high", v)
+	}
+	if v := pq.DequeueOpt().MustGet(); v != "high" || pq.Size() != 1 {
+		t.Errorf("Expected %v, got %v", "high", v)
+	}
+}
+
+func TestStats(t *testing.T) {
+	pq := pqueue.New[int]()
+	for i := 0; i < 100; i++ {
+		pq.Enqueue(i, i%7)
+	}
+	for i := 0; i < 60; i++ {
+		_, _ = pq.Dequeue()
+	}
+	pq.Enqueue(1, 1)
+	growth := pq.Stats().Growth
+	if growth.PeakSize != 100 {
+		t.Errorf("expected peak size %d, got %d", 100, growth.PeakSize)
+	}
+	// the backing array doubles while it is small
+	if growth.Resizes < 7 || growth.Resizes > 10 {
+		t.Errorf("expected 7 to 10 resizes, got %d", growth.Resizes)
+	}
+	if growth.PeakCapacity < 100 || growth.Allocated < growth.PeakCapacity {
+		t.Errorf("expected a peak capacity >= 100, got %d", growth.PeakCapacity)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"errors"
+	"iter"
+
+	gods "github.com/pzaino/gods"
+)
+
+const (
+	ErrQueueIsFull = "queue is full"
+)
+
+// FullPolicy selects what a Circular queue does when an element is enqueued
+// while it's full.
+type FullPolicy int
+
+const (
+	// RejectWhenFull makes Enqueue fail with an ErrQueueIsFull error
+	RejectWhenFull FullPolicy = iota
+	// OverwriteWhenFull makes Enqueue drop the oldest element
+	OverwriteWhenFull
+)
+
+// Circular is a FIFO queue with a fixed capacity, backed by an array
+// allocated once by NewCircular: enqueuing and dequeuing never allocate, which
+// makes it suitable for real-time code.
+type Circular[T comparable] struct {
+	data   []T
+	head   int // the index of the first element
+	size   int
+	policy FullPolicy
+}
+
+// NewCircular creates a new Circular queue holding at most capacity elements
+// (at least 1), with the given policy for the elements enqueued while the
+// queue is full.
+func NewCircular[T comparable](capacity uint64, policy FullPolicy) *Circular[T] {
+	return &Circular[T]{data: make([]T, max(capacity, 1)), policy: policy}
+}
+
+// Enqueue adds an element to the end of the queue. If the queue is full it
+// returns an ErrQueueIsFull error or, with OverwriteWhenFull, it drops the
+// first element to make room for the new one.
+func (q *Circular[T]) Enqueue(elem T) error {
+	if q.size == len(q.data) {
+		if q.policy != OverwriteWhenFull {
+			return errors.New(ErrQueueIsFull)
+		}
+		q.data[q.head] = elem
+		q.head = q.index(1)
+		return nil
+	}
+	q.data[q.index(q.size)] = elem
+	q.size++
+	return nil
+}
+
+// Dequeue removes and returns the first element in the queue
+func (q *Circular[T]) Dequeue() (T, error) {
+	var zero T
+	if q.size == 0 {
+		return zero, errors.New(ErrQueueIsEmpty)
+	}
+	elem := q.data[q.head]
+	q.data[q.head] = zero // don't keep a reference to the dequeued element
+	q.head = q.index(1)
+	q.size--
+	return elem, nil
+}
+
+// Peek returns the first element in the queue without removing it
+func (q *Circular[T]) Peek() (T, error) {
+	if q.size == 0 {
+		var zero T
+		return zero, errors.New(ErrQueueIsEmpty)
+	}
+	return q.data[q.head], nil
+}
+
+// Size returns the number of elements in the queue
+func (q *Circular[T]) Size() uint64 {
+	return uint64(q.size)
+}
+
+// Capacity returns the maximum number of elements in the queue
+func (q *Circular[T]) Capacity() uint64 {
+	return uint64(len(q.data))
+}
+
+// IsEmpty returns true if the queue is empty
+func (q *Circular[T]) IsEmpty() bool {
+	return q.size == 0
+}
+
+// IsFull returns true if the queue is full
+func (q *Circular[T]) IsFull() bool {
+	return q.size == len(q.data)
+}
+
+// Clear removes all elements from the queue, keeping its array
+func (q *Circular[T]) Clear() {
+	clear(q.data)
+	q.head, q.size = 0, 0
+}
+
+// Iter returns an iterator over the elements of the queue, from the front to the back
+func (q *Circular[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := 0; i < q.size; i++ {
+			if !yield(q.data[q.index(i)]) {
+				return
+			}
+		}
+	}
+}
+
+// ToSlice returns a copy of the elements in the queue, from the front to the back
+func (q *Circular[T]) ToSlice() []T {
+	items := make([]T, 0, q.size)
+	for elem := range q.Iter() {
+		items = append(items, elem)
+	}
+	return items
+}
+
+// Validate checks the invariants of the queue: its head and size (see
+// gods.Validator).
+func (q *Circular[T]) Validate() error {
+	if q.head < 0 || q.head >= len(q.data) {
+		return gods.Invariantf("head is %d, out of the array of %d elements", q.head, len(q.data))
+	}
+	if q.size < 0 || q.size > len(q.data) {
+		return gods.Invariantf("size is %d, out of the capacity %d", q.size, len(q.data))
+	}
+	return nil
+}
+
+// index returns the index in the array of the i-th element of the queue
+func (q *Circular[T]) index(i int) int {
+	i += q.head
+	if i >= len(q.data) {
+		i -= len(q.data)
+	}
+	return i
+}
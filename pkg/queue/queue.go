@@ -17,7 +17,14 @@ package queue
 
 import (
 	"errors"
+	"io"
+	"iter"
 	"strings"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	iterator "github.com/pzaino/gods/pkg/iterator"
+	optional "github.com/pzaino/gods/pkg/optional"
 )
 
 const (
@@ -27,8 +34,9 @@ const (
 
 // Queue is a FIFO data structure
 type Queue[T comparable] struct {
-	data []T
-	size uint64
+	data   []T
+	size   uint64
+	growth gods.Growth
 }
 
 // New creates a new Queue
@@ -43,7 +51,7 @@ func (q *Queue[T]) IsEmpty() bool {
 
 // Enqueue adds an element to the end of the queue
 func (q *Queue[T]) Enqueue(elem T) {
-	q.data = append(q.data, elem)
+	q.data = gods.Append(&q.growth, q.data, elem)
 	q.size++
 }
 
@@ -68,11 +76,68 @@ func (q *Queue[T]) Peek() (T, error) {
 	return q.data[0], nil
 }
 
+// DequeueOpt is like Dequeue, but it returns the element as an Option (None
+// if the queue is empty), without allocating an error.
+func (q *Queue[T]) DequeueOpt() optional.Option[T] {
+	if q.IsEmpty() {
+		return optional.None[T]()
+	}
+	elem, _ := q.Dequeue()
+	return optional.Some(elem)
+}
+
+// PeekOpt is like Peek, but it returns the element as an Option.
+func (q *Queue[T]) PeekOpt() optional.Option[T] {
+	if q.IsEmpty() {
+		return optional.None[T]()
+	}
+	return optional.Some(q.data[0])
+}
+
 // Size returns the number of elements in the queue
 func (q *Queue[T]) Size() uint64 {
 	return q.size
 }
 
+// MemoryUsage returns an estimate of the bytes held by the queue (see the
+// gods package), adding the memory returned by extra for every item.
+func (q *Queue[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
+	return uint64(unsafe.Sizeof(*q)) + gods.SliceMemory(q.data, extra...)
+}
+
+// Stats returns the statistics of the queue: its peak size and how many
+// times its backing array was reallocated to grow.
+func (q *Queue[T]) Stats() gods.Stats {
+	return gods.Stats{Growth: q.growth.Stats()}
+}
+
+// Validate checks the invariants of the queue (see gods.Validator).
+func (q *Queue[T]) Validate() error {
+	if q.size != uint64(len(q.data)) {
+		return gods.Invariantf("size is %d but the queue holds %d elements", q.size, len(q.data))
+	}
+	return nil
+}
+
+// Dump writes a rendering of the queue to w, from the front, for debugging.
+func (q *Queue[T]) Dump(w io.Writer) error {
+	d := gods.NewDumper(w)
+	d.Line(0, "Queue size=%d cap=%d", q.size, cap(q.data))
+	for i, v := range q.data {
+		marker := ""
+		switch {
+		case i == 0 && i == len(q.data)-1:
+			marker = " <- front, back"
+		case i == 0:
+			marker = " <- front"
+		case i == len(q.data)-1:
+			marker = " <- back"
+		}
+		d.Line(1, "[%d] %v%s", i, v, marker)
+	}
+	return d.Err()
+}
+
 // Clear removes all elements from the queue
 func (q *Queue[T]) Clear() {
 	q.data = []T{}
@@ -354,3 +419,119 @@ func (q *Queue[T]) FindAllIndexes(f func(T) bool) []uint64 {
 	}
 	return result
 }
+
+// ToSlice returns a copy of the elements in the queue, from the front to the back
+func (q *Queue[T]) ToSlice() []T {
+	if q.IsEmpty() {
+		return nil
+	}
+	items := make([]T, len(q.data))
+	copy(items, q.data)
+	return items
+}
+
+// Freeze returns an immutable copy of the queue (from the front), which can be
+// shared without risking changes to it (see gods.Frozen).
+func (q *Queue[T]) Freeze() *gods.Frozen[T] {
+	return gods.NewFrozen(q.ToSlice())
+}
+
+// Iter returns an iterator over the elements of the queue, from the front to the back
+func (q *Queue[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := 0; i < len(q.data); i++ {
+			if !yield(q.data[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Iterator returns an iterator over the elements of the queue, from the front to the back
+// The queue must not be modified while iterating.
+func (q *Queue[T]) Iterator() iterator.BidirectionalIterator[T] {
+	return iterator.FromSlice(q.data)
+}
+
+// Enumerate returns an iterator over the index and the value of the elements of
+// the queue, from the front (index 0) to the back
+func (q *Queue[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		for i := 0; i < len(q.data); i++ {
+			if !yield(uint64(i), q.data[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (q *Queue[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(q.Enumerate())
+}
+
+// Encode writes the queue to w using the given codec
+func (q *Queue[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeSlice(w, codec, q.ToSlice())
+}
+
+// Decode replaces the content of the queue with the elements read from r using the given codec
+func (q *Queue[T]) Decode(r io.Reader, codec gods.Codec) error {
+	items, err := gods.DecodeSlice[T](r, codec)
+	if err != nil {
+		return err
+	}
+	q.Clear()
+	for _, item := range items {
+		q.Enqueue(item)
+	}
+	return nil
+}
+
+// EncodeStream writes the queue to w incrementally, in chunks encoded with the
+// given codec (see gods.EncodeStream), without copying its elements first.
+func (q *Queue[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeStream(w, codec, q.Iter())
+}
+
+// DecodeStream replaces the content of the queue with the elements of a
+// stream written by EncodeStream, adding them as they are read.
+func (q *Queue[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	q.Clear()
+	return gods.DecodeStream(r, codec, func(item T) error {
+		q.Enqueue(item)
+		return nil
+	})
+}
+
+// MarshalText implements encoding.TextMarshaler: the queue is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (q *Queue[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(q)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (q *Queue[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(q, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (q *Queue[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(q)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (q *Queue[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(q, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (q *Queue[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(q)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (q *Queue[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(q, data)
+}
@@ -16,9 +16,13 @@
 package queue_test
 
 import (
+	"context"
+	"slices"
 	"strconv"
 	"testing"
+	"time"
 
+	iterator "github.com/pzaino/gods/pkg/iterator"
 	queue "github.com/pzaino/gods/pkg/queue"
 )
 
@@ -820,3 +824,198 @@ func TestMapFrom(t *testing.T) {
 		t.Errorf("Mapped queue should have value 6 at index 1")
 	}
 }
+
+func TestToSliceAndIter(t *testing.T) {
+	q := queue.New[int]()
+	if q.ToSlice() != nil {
+		t.Errorf("Expected nil slice, got %v", q.ToSlice())
+	}
+	for i := 1; i <= 3; i++ {
+		q.Enqueue(i)
+	}
+
+	items := q.ToSlice()
+	if !slices.Equal(items, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, items)
+	}
+	// ToSlice returns a copy
+	items[0] = 100
+	if v, _ := q.Peek(); v != 1 {
+		t.Errorf("Expected %v, got %v", 1, v)
+	}
+
+	if got := slices.Collect(q.Iter()); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, got)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	q := queue.New[int]()
+	for i := 1; i <= 3; i++ {
+		q.Enqueue(i)
+	}
+	it := q.Iterator()
+	if got := iterator.ToSlice[int](it); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, got)
+	}
+	if !it.Prev() || it.Value() != 3 {
+		t.Errorf("Expected %v, got %v", 3, it.Value())
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	q := queue.New[int]()
+	for i := 1; i <= 3; i++ {
+		q.Enqueue(i)
+	}
+	var indexes []uint64
+	var values []int
+	for i, v := range q.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0, 1, 2}) {
+		t.Errorf("Expected %v, got %v", []uint64{0, 1, 2}, indexes)
+	}
+	if !slices.Equal(values, []int{1, 2, 3}) {
+		t.Errorf("Expected %v, got %v", []int{1, 2, 3}, values)
+	}
+	if got := slices.Collect(q.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+}
+
+func TestCircular(t *testing.T) {
+	q := queue.NewCircular[int](3, queue.RejectWhenFull)
+	for i := 1; i <= 3; i++ {
+		if err := q.Enqueue(i); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+	}
+	if !q.IsFull() {
+		t.Errorf("expected queue to be full")
+	}
+	if err := q.Enqueue(4); err == nil || err.Error() != queue.ErrQueueIsFull {
+		t.Errorf("expected %v, got %v", queue.ErrQueueIsFull, err)
+	}
+	if v, err := q.Dequeue(); err != nil || v != 1 {
+		t.Errorf("expected 1, got %v", v)
+	}
+	// wrap around the end of the array
+	if err := q.Enqueue(4); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if got := q.ToSlice(); !slices.Equal(got, []int{2, 3, 4}) {
+		t.Errorf("expected [2 3 4], got %v", got)
+	}
+	for _, want := range []int{2, 3, 4} {
+		if v, err := q.Dequeue(); err != nil || v != want {
+			t.Errorf("expected %d, got %v", want, v)
+		}
+	}
+	if !q.IsEmpty() {
+		t.Errorf(errExpectedQueueEmpty)
+	}
+	if _, err := q.Dequeue(); err == nil || err.Error() != queue.ErrQueueIsEmpty {
+		t.Errorf("expected %v, got %v", queue.ErrQueueIsEmpty, err)
+	}
+}
+
+func TestCircularOverwrite(t *testing.T) {
+	q := queue.NewCircular[int](3, queue.OverwriteWhenFull)
+	for i := 1; i <= 5; i++ {
+		if err := q.Enqueue(i); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+	}
+	if got := q.ToSlice(); !slices.Equal(got, []int{3, 4, 5}) {
+		t.Errorf("expected [3 4 5], got %v", got)
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf(errExpectedNoError, err)
+	}
+	if v, err := q.Peek(); err != nil || v != 3 {
+		t.Errorf("expected 3, got %v", v)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = q.Enqueue(6)
+		_, _ = q.Dequeue()
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestThrottled(t *testing.T) {
+	q := queue.New[int]()
+	for i := 0; i < 5; i++ {
+		q.Enqueue(i)
+	}
+	tq := queue.NewThrottled[int](q, 100, 2)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if v, err := tq.Dequeue(context.Background()); err != nil || v != i {
+			t.Fatalf("expected %d, got %v (%v)", i, v, err)
+		}
+	}
+	// a burst of 2, then 3 elements at 100 per second
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("expected the dequeues to be throttled, took %v", elapsed)
+	}
+
+	// an empty queue gives the token back
+	if _, err := tq.Dequeue(context.Background()); err == nil || err.Error() != queue.ErrQueueIsEmpty {
+		t.Errorf("expected %v, got %v", queue.ErrQueueIsEmpty, err)
+	}
+
+	slow := queue.NewThrottled[int](q, 0.001, 1)
+	q.Enqueue(1)
+	_, _ = slow.Dequeue(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := slow.Dequeue(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestDequeueOptPeekOpt(t *testing.T) {
+	q := queue.New[int]()
+	if q.DequeueOpt().IsSome() || q.PeekOpt().IsSome() {
+		t.Errorf(errExpectedQueueEmpty)
+	}
+	q.Enqueue(1)
+	q.Enqueue(2)
+	if v := q.PeekOpt().MustGet(); v != 1 {
+		t.Errorf(errDeqShouldReturn, 1)
+	}
+	if v := q.DequeueOpt().MustGet(); v != 1 || q.Size() != 1 {
+		t.Errorf(errDeqShouldReturn, 1)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { q.DequeueOpt() }); allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestStats(t *testing.T) {
+	q := queue.New[int]()
+	for i := 0; i < 100; i++ {
+		q.Enqueue(i)
+	}
+	for i := 0; i < 60; i++ {
+		if _, err := q.Dequeue(); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+	}
+	growth := q.Stats().Growth
+	if growth.PeakSize != 100 {
+		t.Errorf("expected peak size %d, got %d", 100, growth.PeakSize)
+	}
+	// the backing array doubles while it is small
+	if growth.Resizes < 7 || growth.Resizes > 10 {
+		t.Errorf("expected 7 to 10 resizes, got %d", growth.Resizes)
+	}
+	if growth.PeakCapacity < 100 || growth.Allocated < growth.PeakCapacity {
+		t.Errorf("expected a peak capacity >= 100, got %d", growth.PeakCapacity)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Dequeuer is a queue that Throttled can wrap, like a Queue, a priority
+// queue or a csqueue.
+type Dequeuer[T any] interface {
+	Dequeue() (T, error)
+}
+
+// Throttled wraps a queue, limiting the rate of its Dequeue calls with a token
+// bucket: the bucket holds up to burst tokens and is refilled at rate tokens
+// per second, and every element dequeued takes a token. Throttled can be
+// used by several goroutines if the wrapped queue is concurrency-safe.
+type Throttled[T any] struct {
+	q     Dequeuer[T]
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewThrottled wraps q, allowing at most rate elements per second to be
+// dequeued (rate must be positive), and bursts of up to burst elements (at
+// least 1). The bucket starts full.
+func NewThrottled[T any](q Dequeuer[T], rate float64, burst int) *Throttled[T] {
+	b := float64(max(burst, 1))
+	return &Throttled[T]{q: q, rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// Dequeue waits for a token, then removes and returns the first element of
+// the queue. It returns ctx.Err() if the context is done before a token is
+// available. If the queue is empty, its error is returned and the token is
+// given back, so an empty queue doesn't consume the budget.
+func (t *Throttled[T]) Dequeue(ctx context.Context) (T, error) {
+	if err := t.take(ctx); err != nil {
+		var zero T
+		return zero, err
+	}
+	elem, err := t.q.Dequeue()
+	if err != nil {
+		t.mu.Lock()
+		t.tokens = min(t.tokens+1, t.burst)
+		t.mu.Unlock()
+	}
+	return elem, err
+}
+
+// take waits for a token and takes it
+func (t *Throttled[T]) take(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		now := time.Now()
+		t.tokens = min(t.tokens+now.Sub(t.last).Seconds()*t.rate, t.burst)
+		t.last = now
+		if t.tokens >= 1 {
+			t.tokens--
+			t.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - t.tokens) / t.rate * float64(time.Second))
+		t.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,485 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package radix provides a radix tree (a compressed prefix tree) of
+// patterns, usable to back HTTP routers and topic matchers.
+//
+// A pattern is a sequence of segments, separated by '/' (see WithSeparator).
+// A segment starting with ':' is a parameter, matching any non-empty
+// segment, and a final segment starting with '*' is a wildcard, matching the
+// rest of the path (even empty). The names of the parameters and the
+// wildcard are captured by Match:
+//
+//	t := radix.New[string]()
+//	_ = t.Put("/users/:id/files/*path", "files")
+//	v, params, ok := t.Match("/users/42/files/a/b.txt")
+//	// v == "files", params.Get("id") == "42", params.Get("path") == "a/b.txt"
+//
+// When several patterns match a path, the static segments win over the
+// parameters, which win over the wildcards, segment by segment (with
+// backtracking, so a path matches if any pattern does).
+package radix
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+
+	gods "github.com/pzaino/gods"
+)
+
+const (
+	ErrInvalidPattern = "invalid pattern"
+	ErrConflict       = "conflicting parameter names"
+)
+
+// Option configures a Tree created with New.
+type Option func(*config)
+
+type config struct {
+	sep byte
+}
+
+// WithSeparator sets the segment separator (the default is '/'), for
+// instance '.' for topics.
+func WithSeparator(sep byte) Option {
+	return func(cfg *config) {
+		cfg.sep = sep
+	}
+}
+
+// Param is a parameter captured by Match.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params are the parameters captured by Match, in the order of the pattern.
+type Params []Param
+
+// Get returns the value of the parameter name (and whether it was captured).
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// node is a node of the tree. A static node is reached from its parent by
+// its prefix, a parameter node by a segment and a wildcard node by the rest
+// of the path.
+type node[V any] struct {
+	prefix   string     // the static text leading to the node
+	children []*node[V] // the static children, sorted by first byte
+	param    *node[V]
+	wildcard *node[V]
+	name     string // the name of a parameter or wildcard node
+
+	hasValue bool
+	value    V
+	pattern  string // the pattern of the value
+}
+
+// Tree is a radix tree of patterns. It is not concurrency-safe.
+type Tree[V any] struct {
+	root *node[V]
+	size int
+	sep  byte
+}
+
+// New creates a new empty Tree.
+func New[V any](opts ...Option) *Tree[V] {
+	cfg := config{sep: '/'}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Tree[V]{root: &node[V]{}, sep: cfg.sep}
+}
+
+// token kinds
+const (
+	static = iota
+	param
+	wildcard
+)
+
+// token is a part of a pattern: static text, a parameter or a wildcard
+type token struct {
+	kind int
+	text string // the static text, or the name
+}
+
+// parse splits a pattern in tokens
+func (t *Tree[V]) parse(pattern string) ([]token, error) {
+	var tokens []token
+	start := 0
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		if (c != ':' && c != '*') || (i > 0 && pattern[i-1] != t.sep) {
+			i++
+			continue
+		}
+		if i > start {
+			tokens = append(tokens, token{static, pattern[start:i]})
+		}
+		end := strings.IndexByte(pattern[i:], t.sep)
+		if end < 0 {
+			end = len(pattern)
+		} else {
+			end += i
+		}
+		if end == i+1 {
+			return nil, fmt.Errorf("%s: unnamed parameter in %q", ErrInvalidPattern, pattern)
+		}
+		kind := param
+		if c == '*' {
+			if end != len(pattern) {
+				return nil, fmt.Errorf("%s: wildcard before the end of %q", ErrInvalidPattern, pattern)
+			}
+			kind = wildcard
+		}
+		tokens = append(tokens, token{kind, pattern[i+1 : end]})
+		i, start = end, end
+	}
+	if start < len(pattern) {
+		tokens = append(tokens, token{static, pattern[start:]})
+	}
+	return tokens, nil
+}
+
+// child returns the static child of n starting with c (nil if there's none)
+// and the position where it is or would be
+func (n *node[V]) child(c byte) (*node[V], int) {
+	i, found := slices.BinarySearchFunc(n.children, c, func(child *node[V], c byte) int {
+		return int(child.prefix[0]) - int(c)
+	})
+	if found {
+		return n.children[i], i
+	}
+	return nil, i
+}
+
+// insertStatic returns the node reached from n by s, creating it (and
+// splitting the prefixes) if needed
+func insertStatic[V any](n *node[V], s string) *node[V] {
+	for s != "" {
+		c, i := n.child(s[0])
+		if c == nil {
+			c = &node[V]{prefix: s}
+			n.children = slices.Insert(n.children, i, c)
+			return c
+		}
+		common := commonPrefix(c.prefix, s)
+		if common < len(c.prefix) {
+			mid := &node[V]{prefix: c.prefix[:common], children: []*node[V]{c}}
+			c.prefix = c.prefix[common:]
+			n.children[i] = mid
+			c = mid
+		}
+		n, s = c, s[common:]
+	}
+	return n
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// Put sets the value of a pattern. It returns an ErrInvalidPattern error if
+// a parameter or the wildcard has no name or the wildcard isn't the last
+// segment, and an ErrConflict error if a parameter or wildcard has another
+// name than the one of an existing pattern at the same place (as in
+// "/users/:id" and "/users/:name/posts").
+func (t *Tree[V]) Put(pattern string, value V) error {
+	tokens, err := t.parse(pattern)
+	if err != nil {
+		return err
+	}
+	// check the names before modifying the tree
+	n := t.root
+	for _, tok := range tokens {
+		if n = t.next(n, tok); n == nil {
+			break
+		}
+		if tok.kind != static && n.name != tok.text {
+			return fmt.Errorf("%s: %q and %q in %q", ErrConflict, n.name, tok.text, pattern)
+		}
+	}
+
+	n = t.root
+	for _, tok := range tokens {
+		switch tok.kind {
+		case static:
+			n = insertStatic(n, tok.text)
+		case param:
+			if n.param == nil {
+				n.param = &node[V]{name: tok.text}
+			}
+			n = n.param
+		case wildcard:
+			if n.wildcard == nil {
+				n.wildcard = &node[V]{name: tok.text}
+			}
+			n = n.wildcard
+		}
+	}
+	if !n.hasValue {
+		t.size++
+	}
+	n.hasValue, n.value, n.pattern = true, value, pattern
+	return nil
+}
+
+// next returns the node reached from n by a token (nil if there's none)
+func (t *Tree[V]) next(n *node[V], tok token) *node[V] {
+	switch tok.kind {
+	case param:
+		return n.param
+	case wildcard:
+		return n.wildcard
+	}
+	for s := tok.text; s != ""; {
+		c, _ := n.child(s[0])
+		if c == nil || !strings.HasPrefix(s, c.prefix) {
+			return nil
+		}
+		n, s = c, s[len(c.prefix):]
+	}
+	return n
+}
+
+// find returns the node of a pattern (nil if there's none)
+func (t *Tree[V]) find(pattern string) *node[V] {
+	tokens, err := t.parse(pattern)
+	if err != nil {
+		return nil
+	}
+	n := t.root
+	for _, tok := range tokens {
+		if n = t.next(n, tok); n == nil || (tok.kind != static && n.name != tok.text) {
+			return nil
+		}
+	}
+	return n
+}
+
+// Get returns the value of a pattern (and whether it's in the tree). The
+// pattern is compared literally: Get("/users/:id") returns the value of
+// that pattern, use Match to find the pattern matching a path.
+func (t *Tree[V]) Get(pattern string) (V, bool) {
+	if n := t.find(pattern); n != nil && n.hasValue {
+		return n.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Delete removes a pattern and returns true if it was in the tree.
+func (t *Tree[V]) Delete(pattern string) bool {
+	tokens, err := t.parse(pattern)
+	if err != nil {
+		return false
+	}
+	// path holds the nodes from the root to the node of the pattern
+	path := []*node[V]{t.root}
+	for _, tok := range tokens {
+		n := path[len(path)-1]
+		switch tok.kind {
+		case param, wildcard:
+			if n = t.next(n, tok); n == nil || n.name != tok.text {
+				return false
+			}
+			path = append(path, n)
+			continue
+		}
+		for s := tok.text; s != ""; {
+			c, _ := n.child(s[0])
+			if c == nil || !strings.HasPrefix(s, c.prefix) {
+				return false
+			}
+			path = append(path, c)
+			n, s = c, s[len(c.prefix):]
+		}
+	}
+	n := path[len(path)-1]
+	if !n.hasValue {
+		return false
+	}
+	var zero V
+	n.hasValue, n.value, n.pattern = false, zero, ""
+	t.size--
+
+	// remove the nodes left empty and merge the static chains
+	for i := len(path) - 1; i > 0; i-- {
+		n, parent := path[i], path[i-1]
+		if n.hasValue || n.param != nil || n.wildcard != nil || len(n.children) > 1 {
+			break
+		}
+		switch {
+		case parent.param == n:
+			if len(n.children) > 0 {
+				return true
+			}
+			parent.param = nil
+		case parent.wildcard == n:
+			parent.wildcard = nil
+		case len(n.children) == 1:
+			c := n.children[0]
+			c.prefix = n.prefix + c.prefix
+			_, j := parent.child(n.prefix[0])
+			parent.children[j] = c
+			return true
+		default:
+			_, j := parent.child(n.prefix[0])
+			parent.children = slices.Delete(parent.children, j, j+1)
+		}
+	}
+	return true
+}
+
+// Match returns the value of the pattern matching path, with the captured
+// parameters (and whether a pattern matches). The static segments are
+// preferred to the parameters, and the parameters to the wildcards.
+func (t *Tree[V]) Match(path string) (V, Params, bool) {
+	var params Params
+	if n := t.match(t.root, path, &params); n != nil {
+		return n.value, params, true
+	}
+	var zero V
+	return zero, nil, false
+}
+
+// MatchPattern returns the pattern matching path (and whether there's one).
+func (t *Tree[V]) MatchPattern(path string) (string, bool) {
+	var params Params
+	if n := t.match(t.root, path, &params); n != nil {
+		return n.pattern, true
+	}
+	return "", false
+}
+
+// match returns the node of the pattern matching path from n, adding the
+// captured parameters to params (nil if no pattern matches)
+func (t *Tree[V]) match(n *node[V], path string, params *Params) *node[V] {
+	if path == "" && n.hasValue {
+		return n
+	}
+	if path != "" {
+		if c, _ := n.child(path[0]); c != nil && strings.HasPrefix(path, c.prefix) {
+			if found := t.match(c, path[len(c.prefix):], params); found != nil {
+				return found
+			}
+		}
+		if n.param != nil {
+			end := strings.IndexByte(path, t.sep)
+			if end < 0 {
+				end = len(path)
+			}
+			if end > 0 {
+				*params = append(*params, Param{n.param.name, path[:end]})
+				if found := t.match(n.param, path[end:], params); found != nil {
+					return found
+				}
+				*params = (*params)[:len(*params)-1]
+			}
+		}
+	}
+	if n.wildcard != nil && n.wildcard.hasValue {
+		*params = append(*params, Param{n.wildcard.name, path})
+		return n.wildcard
+	}
+	return nil
+}
+
+// LongestPrefix returns the longest static pattern (without parameters nor
+// wildcard) that is a prefix of s, with its value (and whether there's one).
+func (t *Tree[V]) LongestPrefix(s string) (string, V, bool) {
+	var best *node[V]
+	n, rest := t.root, s
+	for {
+		if n.hasValue {
+			best = n
+		}
+		if rest == "" {
+			break
+		}
+		c, _ := n.child(rest[0])
+		if c == nil || !strings.HasPrefix(rest, c.prefix) {
+			break
+		}
+		n, rest = c, rest[len(c.prefix):]
+	}
+	if best == nil {
+		var zero V
+		return "", zero, false
+	}
+	return best.pattern, best.value, true
+}
+
+// All returns an iterator over the patterns and their value: the static
+// children in lexicographic order first, then the parameters, then the
+// wildcards. The tree must not be modified while iterating.
+func (t *Tree[V]) All() iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		walk(t.root, yield)
+	}
+}
+
+// Keys returns an iterator over the patterns (in the order of All).
+func (t *Tree[V]) Keys() iter.Seq[string] {
+	return gods.Keys(t.All())
+}
+
+// Values returns an iterator over the values (in the order of All).
+func (t *Tree[V]) Values() iter.Seq[V] {
+	return gods.Values(t.All())
+}
+
+func walk[V any](n *node[V], yield func(string, V) bool) bool {
+	if n.hasValue && !yield(n.pattern, n.value) {
+		return false
+	}
+	for _, c := range n.children {
+		if !walk(c, yield) {
+			return false
+		}
+	}
+	if n.param != nil && !walk(n.param, yield) {
+		return false
+	}
+	return n.wildcard == nil || walk(n.wildcard, yield)
+}
+
+// Size returns the number of patterns.
+func (t *Tree[V]) Size() int {
+	return t.size
+}
+
+// IsEmpty returns true if the tree has no patterns.
+func (t *Tree[V]) IsEmpty() bool {
+	return t.size == 0
+}
+
+// Clear removes all the patterns.
+func (t *Tree[V]) Clear() {
+	t.root = &node[V]{}
+	t.size = 0
+}
@@ -0,0 +1,226 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package radix_test
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	radix "github.com/pzaino/gods/pkg/radix"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+func TestStaticKeys(t *testing.T) {
+	tr := radix.New[int]()
+	keys := []string{"romane", "romanus", "romulus", "rubens", "ruber", "rubicon", "rubicundus", "rom"}
+	for i, k := range keys {
+		if err := tr.Put(k, i); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+	}
+	for i, k := range keys {
+		if v, ok := tr.Get(k); !ok || v != i {
+			t.Errorf(errExpectedX, i, v)
+		}
+	}
+	if _, ok := tr.Get("roma"); ok {
+		t.Errorf(errExpectedX, false, ok)
+	}
+	if k, v, ok := tr.LongestPrefix("romanesque"); !ok || k != "romane" || v != 0 {
+		t.Errorf(errExpectedX, "romane", k)
+	}
+	if k, _, ok := tr.LongestPrefix("romulan"); !ok || k != "rom" {
+		t.Errorf(errExpectedX, "rom", k)
+	}
+
+	var got []string
+	for k := range tr.All() {
+		got = append(got, k)
+	}
+	want := slices.Sorted(slices.Values(keys))
+	if !slices.Equal(got, want) {
+		t.Errorf(errExpectedX, want, got)
+	}
+	if got := slices.Collect(tr.Keys()); !slices.Equal(got, want) {
+		t.Errorf(errExpectedX, want, got)
+	}
+	values := slices.Collect(tr.Values())
+	for i, k := range want {
+		if values[i] != slices.Index(keys, k) {
+			t.Errorf(errExpectedX, slices.Index(keys, k), values[i])
+		}
+	}
+}
+
+func TestDeleteMatchesMap(t *testing.T) {
+	tr := radix.New[int]()
+	ref := map[string]int{}
+	for i := range 2000 {
+		b := make([]byte, 1+rand.Intn(6))
+		for j := range b {
+			b[j] = "abc/"[rand.Intn(4)]
+		}
+		k := string(b)
+		if rand.Intn(3) == 0 {
+			_, inRef := ref[k]
+			if tr.Delete(k) != inRef {
+				t.Fatalf(errExpectedX, inRef, !inRef)
+			}
+			delete(ref, k)
+		} else {
+			_ = tr.Put(k, i)
+			ref[k] = i
+		}
+	}
+	if tr.Size() != len(ref) {
+		t.Fatalf(errExpectedX, len(ref), tr.Size())
+	}
+	for k, v := range ref {
+		if got, ok := tr.Get(k); !ok || got != v {
+			t.Fatalf(errExpectedX, v, got)
+		}
+	}
+	for k := range ref {
+		tr.Delete(k)
+	}
+	if !tr.IsEmpty() {
+		t.Errorf(errExpectedX, 0, tr.Size())
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	tr := radix.New[string]()
+	routes := []string{
+		"/",
+		"/users",
+		"/users/new",
+		"/users/:id",
+		"/users/:id/posts/:post",
+		"/files/*path",
+		"/users/:id/*rest",
+		"/static/css/app.css",
+	}
+	for _, r := range routes {
+		if err := tr.Put(r, r); err != nil {
+			t.Fatalf(errExpectedNoError, err)
+		}
+	}
+
+	tests := []struct {
+		path    string
+		pattern string
+		params  string
+	}{
+		{"/", "/", ""},
+		{"/users", "/users", ""},
+		{"/users/new", "/users/new", ""},           // static wins
+		{"/users/newer", "/users/:id", "id=newer"}, // backtracks to the parameter
+		{"/users/42", "/users/:id", "id=42"},
+		{"/users/42/posts/7", "/users/:id/posts/:post", "id=42 post=7"},
+		{"/users/42/posts", "/users/:id/*rest", "id=42 rest=posts"}, // the parameter wins, then the wildcard
+		{"/users/42/", "/users/:id/*rest", "id=42 rest="},
+		{"/files/a/b.txt", "/files/*path", "path=a/b.txt"},
+		{"/files/", "/files/*path", "path="},
+		{"/static/css/app.css", "/static/css/app.css", ""},
+		{"/static/css", "", ""},
+		{"/users/", "", ""}, // a parameter needs a non-empty segment
+		{"/nope", "", ""},
+	}
+	for _, tt := range tests {
+		v, params, ok := tr.Match(tt.path)
+		if ok != (tt.pattern != "") || v != tt.pattern {
+			t.Errorf("%s: "+errExpectedX, tt.path, tt.pattern, v)
+			continue
+		}
+		var got []string
+		for _, p := range params {
+			got = append(got, p.Key+"="+p.Value)
+		}
+		if strings.Join(got, " ") != tt.params {
+			t.Errorf("%s: "+errExpectedX, tt.path, tt.params, strings.Join(got, " "))
+		}
+	}
+
+	if _, params, _ := tr.Match("/users/42/posts/7"); params != nil {
+		if id, ok := params.Get("id"); !ok || id != "42" {
+			t.Errorf(errExpectedX, "42", id)
+		}
+	}
+	if p, ok := tr.MatchPattern("/files/x"); !ok || p != "/files/*path" {
+		t.Errorf(errExpectedX, "/files/*path", p)
+	}
+	if v, ok := tr.Get("/users/:id"); !ok || v != "/users/:id" {
+		t.Errorf(errExpectedX, "/users/:id", v)
+	}
+
+	// deleting a pattern restores the lower-priority matches
+	if !tr.Delete("/users/:id") || tr.Delete("/users/:name") {
+		t.Fatalf(errExpectedX, true, false)
+	}
+	if _, _, ok := tr.Match("/users/42"); ok {
+		t.Errorf(errExpectedX, false, ok)
+	}
+	if v, _, _ := tr.Match("/users/42/posts/7"); v != "/users/:id/posts/:post" {
+		t.Errorf(errExpectedX, "/users/:id/posts/:post", v)
+	}
+	if tr.Size() != len(routes)-1 {
+		t.Errorf(errExpectedX, len(routes)-1, tr.Size())
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	tr := radix.New[int]()
+	for _, p := range []string{"/users/:", "/files/*", "/files/*path/more"} {
+		if err := tr.Put(p, 0); err == nil || !strings.HasPrefix(err.Error(), radix.ErrInvalidPattern) {
+			t.Errorf(errExpectedX, radix.ErrInvalidPattern, err)
+		}
+	}
+	_ = tr.Put("/users/:id", 0)
+	if err := tr.Put("/users/:name/posts", 0); err == nil || !strings.HasPrefix(err.Error(), radix.ErrConflict) {
+		t.Errorf(errExpectedX, radix.ErrConflict, err)
+	}
+	if tr.Size() != 1 {
+		t.Errorf(errExpectedX, 1, tr.Size())
+	}
+	// a ':' inside a segment is literal
+	if err := tr.Put("/time/12:30", 1); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if v, params, ok := tr.Match("/time/12:30"); !ok || v != 1 || len(params) != 0 {
+		t.Errorf(errExpectedX, 1, v)
+	}
+}
+
+func TestTopics(t *testing.T) {
+	tr := radix.New[string](radix.WithSeparator('.'))
+	for _, p := range []string{"sensors.:room.temperature", "sensors.*any", "sensors.kitchen.temperature"} {
+		_ = tr.Put(p, p)
+	}
+	for path, want := range map[string]string{
+		"sensors.kitchen.temperature": "sensors.kitchen.temperature",
+		"sensors.garage.temperature":  "sensors.:room.temperature",
+		"sensors.garage.humidity":     "sensors.*any",
+	} {
+		if v, _, _ := tr.Match(path); v != want {
+			t.Errorf("%s: "+errExpectedX, path, want, v)
+		}
+	}
+}
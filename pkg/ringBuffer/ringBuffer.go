@@ -17,6 +17,14 @@ package ringBuffer
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 )
 
 const (
@@ -84,6 +92,58 @@ func (cb *CircularBuffer[T]) Size() uint64 {
 	return cb.size
 }
 
+// MemoryUsage returns an estimate of the bytes held by the buffer (see the
+// gods package), adding the memory returned by extra for every item.
+func (cb *CircularBuffer[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
+	// only the slots in use hold an item
+	return uint64(unsafe.Sizeof(*cb)) + gods.SliceMemory(cb.data[:0]) + gods.ExtraMemory(cb.Iter(), extra...)
+}
+
+// Validate checks the invariants of the ring buffer: its size and its head and tail indexes (see gods.Validator).
+func (cb *CircularBuffer[T]) Validate() error {
+	if uint64(len(cb.data)) != cb.capacity {
+		return gods.Invariantf("capacity is %d but the ring holds %d slots", cb.capacity, len(cb.data))
+	}
+	if cb.size > cb.capacity {
+		return gods.Invariantf("size %d exceeds the capacity %d", cb.size, cb.capacity)
+	}
+	if cb.capacity == 0 {
+		return nil
+	}
+	if cb.head >= cb.capacity || cb.tail >= cb.capacity {
+		return gods.Invariantf("head %d or tail %d out of the ring of %d slots", cb.head, cb.tail, cb.capacity)
+	}
+	if (cb.head+cb.size)%cb.capacity != cb.tail {
+		return gods.Invariantf("tail is %d but head %d plus size %d is %d", cb.tail, cb.head, cb.size, (cb.head+cb.size)%cb.capacity)
+	}
+	return nil
+}
+
+// Dump writes the layout of the ring buffer to w, with every slot and the
+// head and tail markers, for debugging.
+func (cb *CircularBuffer[T]) Dump(w io.Writer) error {
+	d := gods.NewDumper(w)
+	d.Line(0, "CircularBuffer size=%d capacity=%d head=%d tail=%d", cb.size, cb.capacity, cb.head, cb.tail)
+	for i := uint64(0); i < cb.capacity; i++ {
+		var markers []string
+		if i == cb.head {
+			markers = append(markers, "head")
+		}
+		if i == cb.tail {
+			markers = append(markers, "tail")
+		}
+		slot := "-"
+		if (i+cb.capacity-cb.head)%cb.capacity < cb.size {
+			slot = fmt.Sprint(cb.data[i])
+		}
+		if len(markers) > 0 {
+			slot += " <- " + strings.Join(markers, ", ")
+		}
+		d.Line(1, "[%d] %s", i, slot)
+	}
+	return d.Err()
+}
+
 // Capacity returns the capacity of the buffer.
 func (cb *CircularBuffer[T]) Capacity() uint64 {
 	return cb.capacity
@@ -134,3 +194,159 @@ func (cb *CircularBuffer[T]) Contains(value T) bool {
 	}
 	return false
 }
+
+// Any checks if any element in the buffer matches the predicate.
+func (cb *CircularBuffer[T]) Any(f func(T) bool) bool {
+	for i := uint64(0); i < cb.size; i++ {
+		if f(cb.data[(cb.head+i)%cb.capacity]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Iter returns an iterator over the elements in the buffer from oldest to newest.
+func (cb *CircularBuffer[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := uint64(0); i < cb.size; i++ {
+			if !yield(cb.data[(cb.head+i)%cb.capacity]) {
+				return
+			}
+		}
+	}
+}
+
+// Iterator returns an iterator over the elements in the buffer from oldest to newest.
+func (cb *CircularBuffer[T]) Iterator() iterator.Iterator[T] {
+	i := uint64(0)
+	return iterator.FromFunc(func() (T, bool) {
+		if i >= cb.size {
+			var zero T
+			return zero, false
+		}
+		i++
+		return cb.data[(cb.head+i-1)%cb.capacity], true
+	})
+}
+
+// Enumerate returns an iterator over the logical index (0 being the oldest) and
+// the value of the elements in the buffer.
+func (cb *CircularBuffer[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		for i := uint64(0); i < cb.size; i++ {
+			if !yield(i, cb.data[(cb.head+i)%cb.capacity]) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (cb *CircularBuffer[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(cb.Enumerate())
+}
+
+// Values returns an iterator over the items, in the same order as Enumerate
+// (it is Iter, under the name used by the standard library).
+func (cb *CircularBuffer[T]) Values() iter.Seq[T] {
+	return cb.Iter()
+}
+
+// All returns an iterator over the elements in the buffer in logical order,
+// from oldest to newest, like Iter. It ranges over the two segments returned
+// by Slices, without computing the position of every element.
+func (cb *CircularBuffer[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		a, b := cb.Slices()
+		for _, seg := range [2][]T{a, b} {
+			for _, v := range seg {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Slices returns the elements in the buffer as two segments of the underlying
+// storage, without copying them: the elements in logical order are those in a
+// followed by those in b. b is empty unless the elements wrap around the end
+// of the storage. The segments are only valid until the buffer is modified,
+// and must not be modified by the caller.
+func (cb *CircularBuffer[T]) Slices() (a, b []T) {
+	if cb.size == 0 {
+		return nil, nil
+	}
+	end := cb.head + cb.size
+	if end <= cb.capacity {
+		return cb.data[cb.head:end], nil
+	}
+	return cb.data[cb.head:], cb.data[:end-cb.capacity]
+}
+
+// Encode writes the buffer to w using the given codec
+func (cb *CircularBuffer[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeSlice(w, codec, cb.ToSlice())
+}
+
+// Decode replaces the content of the buffer with the elements read from r using the given codec.
+// If there are more elements than the buffer capacity, only the newest ones are kept.
+func (cb *CircularBuffer[T]) Decode(r io.Reader, codec gods.Codec) error {
+	items, err := gods.DecodeSlice[T](r, codec)
+	if err != nil {
+		return err
+	}
+	cb.Clear()
+	for _, item := range items {
+		cb.Append(item)
+	}
+	return nil
+}
+
+// EncodeStream writes the buffer to w incrementally, in chunks encoded with the
+// given codec (see gods.EncodeStream), without copying its elements first.
+func (cb *CircularBuffer[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeStream(w, codec, cb.Iter())
+}
+
+// DecodeStream replaces the content of the buffer with the elements of a
+// stream written by EncodeStream, adding them as they are read.
+func (cb *CircularBuffer[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	cb.Clear()
+	return gods.DecodeStream(r, codec, func(item T) error {
+		cb.Append(item)
+		return nil
+	})
+}
+
+// MarshalText implements encoding.TextMarshaler: the buffer is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (cb *CircularBuffer[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(cb)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (cb *CircularBuffer[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(cb, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (cb *CircularBuffer[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(cb)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (cb *CircularBuffer[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(cb, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (cb *CircularBuffer[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(cb)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (cb *CircularBuffer[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(cb, data)
+}
@@ -1,8 +1,13 @@
 package ringBuffer_test
 
 import (
+	"bytes"
+	"slices"
+	"strings"
 	"testing"
 
+	gods "github.com/pzaino/gods"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 	cBuf "github.com/pzaino/gods/pkg/ringBuffer"
 )
 
@@ -271,3 +276,126 @@ func TestContains(t *testing.T) {
 		t.Errorf("Expected buffer to not contain value 1 after overwrite")
 	}
 }
+
+func TestIterAndAny(t *testing.T) {
+	cb := cBuf.New[int](4)
+	for i := 1; i <= 6; i++ {
+		cb.Append(i)
+	}
+	if got := slices.Collect(cb.Iter()); !slices.Equal(got, []int{3, 4, 5, 6}) {
+		t.Errorf("Expected %v, got %v", []int{3, 4, 5, 6}, got)
+	}
+	if !cb.Any(func(v int) bool { return v == 6 }) {
+		t.Error("Expected Any to return true")
+	}
+	if cb.Any(func(v int) bool { return v == 1 }) {
+		t.Error("Expected Any to return false")
+	}
+}
+
+func TestIterator(t *testing.T) {
+	cb := cBuf.New[int](4)
+	for i := 1; i <= 6; i++ {
+		cb.Append(i)
+	}
+	if got := iterator.ToSlice(cb.Iterator()); !slices.Equal(got, []int{3, 4, 5, 6}) {
+		t.Errorf("Expected %v, got %v", []int{3, 4, 5, 6}, got)
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	cb := cBuf.New[int](3)
+	for i := 1; i <= 5; i++ {
+		cb.Append(i)
+	}
+	var indexes []uint64
+	var values []int
+	for i, v := range cb.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0, 1, 2}) {
+		t.Errorf("Expected %v, got %v", []uint64{0, 1, 2}, indexes)
+	}
+	if !slices.Equal(values, []int{3, 4, 5}) {
+		t.Errorf("Expected %v, got %v", []int{3, 4, 5}, values)
+	}
+	if got := slices.Collect(cb.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+	if got := slices.Collect(cb.Values()); !slices.Equal(got, values) {
+		t.Errorf("Expected %v, got %v", values, got)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	cb := cBuf.New[int](5)
+	for i := 1; i <= 5; i++ {
+		cb.Append(i)
+	}
+	var buf bytes.Buffer
+	if err := cb.Encode(&buf, gods.JSONCodec{}); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+
+	// decoding into a smaller buffer keeps only the newest elements
+	small := cBuf.New[int](3)
+	if err := small.Decode(&buf, gods.JSONCodec{}); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if got := small.ToSlice(); !slices.Equal(got, []int{3, 4, 5}) {
+		t.Errorf("Expected %v, got %v", []int{3, 4, 5}, got)
+	}
+}
+
+func TestDump(t *testing.T) {
+	buffer := cBuf.New[int](4)
+	for i := 1; i <= 5; i++ {
+		buffer.Append(i)
+	}
+	_, _ = buffer.Remove()
+	var sb strings.Builder
+	if err := buffer.Dump(&sb); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	want := `CircularBuffer size=3 capacity=4 head=2 tail=1
+  [0] 5
+  [1] - <- tail
+  [2] 3 <- head
+  [3] 4
+`
+	if sb.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, sb.String())
+	}
+}
+
+func TestSlicesAndAll(t *testing.T) {
+	cb := cBuf.New[int](4)
+	if a, b := cb.Slices(); len(a) != 0 || len(b) != 0 {
+		t.Errorf("Expected no segments, got %v and %v", a, b)
+	}
+	for i := 1; i <= 3; i++ {
+		cb.Append(i)
+	}
+	if a, b := cb.Slices(); !slices.Equal(a, []int{1, 2, 3}) || len(b) != 0 {
+		t.Errorf("Expected [1 2 3] and [], got %v and %v", a, b)
+	}
+
+	// wrap around the end of the storage
+	cb.Append(4)
+	cb.Append(5)
+	cb.Append(6)
+	a, b := cb.Slices()
+	if !slices.Equal(a, []int{3, 4}) || !slices.Equal(b, []int{5, 6}) {
+		t.Errorf("Expected [3 4] and [5 6], got %v and %v", a, b)
+	}
+	if got := slices.Collect(cb.All()); !slices.Equal(got, []int{3, 4, 5, 6}) {
+		t.Errorf("Expected [3 4 5 6], got %v", got)
+	}
+	for v := range cb.All() {
+		if v != 3 {
+			t.Errorf("Expected 3, got %v", v)
+		}
+		break
+	}
+}
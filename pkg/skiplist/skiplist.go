@@ -0,0 +1,385 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package skiplist provides a sorted set implemented as an indexable skip
+// list: besides the usual O(log n) search, insertion and removal, every link
+// keeps its span (the number of elements it skips), so the elements can also
+// be accessed by rank (their position in the order) in O(log n). That's what
+// leaderboards need: the scores ordering plus "who is 10th" and "what's my
+// position" queries.
+package skiplist
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math/rand/v2"
+
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+)
+
+const (
+	ErrRankOutOfRange = "rank out of range"
+	ErrBadSpan        = "skip list span mismatch"
+	ErrBadOrder       = "skip list elements out of order"
+)
+
+const (
+	maxLevel = 32
+	// p = 1/4: each level holds about a quarter of the elements of the one
+	// below, as in Redis sorted sets
+	pBits = 2
+)
+
+// link points to the next node on a level; span is the number of elements
+// it moves forward (to the end of the list when node is nil)
+type link[T any] struct {
+	node *node[T]
+	span uint64
+}
+
+// node is an element of the skip list, linked on len(next) levels
+type node[T any] struct {
+	item T
+	next []link[T]
+}
+
+// SkipList is a sorted set of elements ordered by a less function. Two
+// elements are the same if neither is less than the other, so the order must
+// tell apart the elements that must be kept separately (e.g. by score and
+// then by name). It is not concurrency-safe.
+type SkipList[T any] struct {
+	head  node[T] // sentinel with maxLevel links
+	level int     // the number of levels in use
+	size  uint64
+	less  cmpx.Less[T]
+}
+
+// New creates a new empty skip list ordered by less.
+func New[T any](less cmpx.Less[T]) *SkipList[T] {
+	s := &SkipList[T]{less: less, level: 1}
+	s.head.next = make([]link[T], maxLevel)
+	return s
+}
+
+// Size returns the number of elements
+func (s *SkipList[T]) Size() uint64 {
+	return s.size
+}
+
+// IsEmpty returns true if the skip list has no elements
+func (s *SkipList[T]) IsEmpty() bool {
+	return s.size == 0
+}
+
+// Clear removes all the elements
+func (s *SkipList[T]) Clear() {
+	clear(s.head.next)
+	s.level, s.size = 1, 0
+}
+
+// randomLevel returns the number of levels of a new node
+func randomLevel() int {
+	level := 1
+	for level < maxLevel && rand.Uint32()&(1<<pBits-1) == 0 {
+		level++
+	}
+	return level
+}
+
+// find returns, for each level, the last node before item and its rank
+// (the head has rank 0, the first element rank 1)
+func (s *SkipList[T]) find(item T, update *[maxLevel]*node[T], rank *[maxLevel]uint64) {
+	x := &s.head
+	for i := s.level - 1; i >= 0; i-- {
+		if i < s.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for next := x.next[i]; next.node != nil && s.less(next.node.item, item); next = x.next[i] {
+			rank[i] += next.span
+			x = next.node
+		}
+		update[i] = x
+	}
+}
+
+// equal returns true if a and b are the same element for the skip list
+func (s *SkipList[T]) equal(a, b T) bool {
+	return !s.less(a, b) && !s.less(b, a)
+}
+
+// Insert adds item in O(log n), and returns false (leaving the skip list
+// unchanged) if the same element is already there. Use Replace to update it.
+func (s *SkipList[T]) Insert(item T) bool {
+	var update [maxLevel]*node[T]
+	var rank [maxLevel]uint64
+	s.find(item, &update, &rank)
+	if n := update[0].next[0].node; n != nil && s.equal(n.item, item) {
+		return false
+	}
+
+	level := randomLevel()
+	if level > s.level {
+		for i := s.level; i < level; i++ {
+			update[i] = &s.head
+			s.head.next[i].span = s.size
+		}
+		s.level = level
+	}
+	n := &node[T]{item: item, next: make([]link[T], level)}
+	for i := 0; i < level; i++ {
+		prev := &update[i].next[i]
+		// rank[0]-rank[i] elements between update[i] and the new node
+		n.next[i] = link[T]{node: prev.node, span: prev.span - (rank[0] - rank[i])}
+		*prev = link[T]{node: n, span: rank[0] - rank[i] + 1}
+	}
+	// the higher levels now skip one more element
+	for i := level; i < s.level; i++ {
+		update[i].next[i].span++
+	}
+	s.size++
+	return true
+}
+
+// Replace adds item, or replaces the element that is the same, and returns
+// true if item is new.
+func (s *SkipList[T]) Replace(item T) bool {
+	var update [maxLevel]*node[T]
+	var rank [maxLevel]uint64
+	s.find(item, &update, &rank)
+	if n := update[0].next[0].node; n != nil && s.equal(n.item, item) {
+		n.item = item
+		return false
+	}
+	return s.Insert(item)
+}
+
+// Delete removes the element that is the same as item in O(log n), and
+// returns false if there's no such element.
+func (s *SkipList[T]) Delete(item T) bool {
+	var update [maxLevel]*node[T]
+	var rank [maxLevel]uint64
+	s.find(item, &update, &rank)
+	x := update[0].next[0].node
+	if x == nil || !s.equal(x.item, item) {
+		return false
+	}
+	for i := 0; i < s.level; i++ {
+		prev := &update[i].next[i]
+		if prev.node == x {
+			*prev = link[T]{node: x.next[i].node, span: prev.span + x.next[i].span - 1}
+		} else {
+			prev.span--
+		}
+	}
+	for s.level > 1 && s.head.next[s.level-1].node == nil {
+		s.level--
+	}
+	s.size--
+	return true
+}
+
+// Get returns the element that is the same as item
+func (s *SkipList[T]) Get(item T) (T, bool) {
+	var update [maxLevel]*node[T]
+	var rank [maxLevel]uint64
+	s.find(item, &update, &rank)
+	if n := update[0].next[0].node; n != nil && s.equal(n.item, item) {
+		return n.item, true
+	}
+	var zero T
+	return zero, false
+}
+
+// Contains returns true if the skip list holds the same element as item
+func (s *SkipList[T]) Contains(item T) bool {
+	_, ok := s.Get(item)
+	return ok
+}
+
+// Ceiling returns the smallest element not less than item, and false if
+// there's none
+func (s *SkipList[T]) Ceiling(item T) (T, bool) {
+	var update [maxLevel]*node[T]
+	var rank [maxLevel]uint64
+	s.find(item, &update, &rank)
+	if n := update[0].next[0].node; n != nil {
+		return n.item, true
+	}
+	var zero T
+	return zero, false
+}
+
+// Floor returns the largest element not greater than item, and false if
+// there's none
+func (s *SkipList[T]) Floor(item T) (T, bool) {
+	var update [maxLevel]*node[T]
+	var rank [maxLevel]uint64
+	s.find(item, &update, &rank)
+	if n := update[0].next[0].node; n != nil && !s.less(item, n.item) {
+		return n.item, true
+	}
+	if update[0] != &s.head {
+		return update[0].item, true
+	}
+	var zero T
+	return zero, false
+}
+
+// Seek returns an iterator over the elements not less than item, in order,
+// starting in O(log n). The skip list must not be changed during the
+// iteration.
+func (s *SkipList[T]) Seek(item T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var update [maxLevel]*node[T]
+		var rank [maxLevel]uint64
+		s.find(item, &update, &rank)
+		for n := update[0].next[0].node; n != nil; n = n.next[0].node {
+			if !yield(n.item) {
+				return
+			}
+		}
+	}
+}
+
+// nodeAt returns the node at the given 0-based rank in O(log n)
+func (s *SkipList[T]) nodeAt(rank uint64) *node[T] {
+	if rank >= s.size {
+		return nil
+	}
+	target := rank + 1
+	traversed := uint64(0)
+	x := &s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for next := x.next[i]; next.node != nil && traversed+next.span <= target; next = x.next[i] {
+			traversed += next.span
+			x = next.node
+		}
+		if traversed == target {
+			return x
+		}
+	}
+	return nil
+}
+
+// GetByRank returns the element at the given rank (0 is the smallest
+// element) in O(log n). It returns ErrRankOutOfRange if rank >= Size().
+func (s *SkipList[T]) GetByRank(rank uint64) (T, error) {
+	n := s.nodeAt(rank)
+	if n == nil {
+		var zero T
+		return zero, errors.New(ErrRankOutOfRange)
+	}
+	return n.item, nil
+}
+
+// RankOf returns the rank of the element that is the same as item (0 for
+// the smallest element) in O(log n), and false if there's no such element.
+func (s *SkipList[T]) RankOf(item T) (uint64, bool) {
+	rank := uint64(0)
+	x := &s.head
+	for i := s.level - 1; i >= 0; i-- {
+		// move to the last node not greater than item
+		for next := x.next[i]; next.node != nil && !s.less(item, next.node.item); next = x.next[i] {
+			rank += next.span
+			x = next.node
+		}
+		if x != &s.head && !s.less(x.item, item) {
+			return rank - 1, true
+		}
+	}
+	return 0, false
+}
+
+// RangeByRank returns the elements with rank in [from, to), in order, in
+// O(log n + to-from). The range is clamped to Size().
+func (s *SkipList[T]) RangeByRank(from, to uint64) []T {
+	to = min(to, s.size)
+	if from >= to {
+		return nil
+	}
+	result := make([]T, 0, to-from)
+	for n := s.nodeAt(from); len(result) < cap(result); n = n.next[0].node {
+		result = append(result, n.item)
+	}
+	return result
+}
+
+// Min returns the smallest element, and false if the skip list is empty
+func (s *SkipList[T]) Min() (T, bool) {
+	if n := s.head.next[0].node; n != nil {
+		return n.item, true
+	}
+	var zero T
+	return zero, false
+}
+
+// Max returns the largest element, and false if the skip list is empty
+func (s *SkipList[T]) Max() (T, bool) {
+	if s.size == 0 {
+		var zero T
+		return zero, false
+	}
+	return s.nodeAt(s.size - 1).item, true
+}
+
+// All returns an iterator over the elements, in order
+func (s *SkipList[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for n := s.head.next[0].node; n != nil; n = n.next[0].node {
+			if !yield(n.item) {
+				return
+			}
+		}
+	}
+}
+
+// ToSlice returns the elements, in order
+func (s *SkipList[T]) ToSlice() []T {
+	return s.RangeByRank(0, s.size)
+}
+
+// Validate checks the structure of the skip list: the elements are in
+// order on level 0 and every link spans the right number of elements (the
+// links to the end included). It returns the first problem found.
+func (s *SkipList[T]) Validate() error {
+	// ranks of the nodes, from level 0
+	ranks := map[*node[T]]uint64{&s.head: 0}
+	var prev *node[T]
+	for n := s.head.next[0].node; n != nil; n = n.next[0].node {
+		if prev != nil && !s.less(prev.item, n.item) {
+			return fmt.Errorf("%s: rank %d", ErrBadOrder, ranks[prev])
+		}
+		ranks[n] = uint64(len(ranks))
+		prev = n
+	}
+	if got := uint64(len(ranks) - 1); got != s.size {
+		return fmt.Errorf("%s: %d elements, size %d", ErrBadSpan, got, s.size)
+	}
+	for i := 0; i < s.level; i++ {
+		for x := &s.head; ; x = x.next[i].node {
+			end := s.size
+			if x.next[i].node != nil {
+				end = ranks[x.next[i].node]
+			}
+			if x.next[i].span != end-ranks[x] {
+				return fmt.Errorf("%s: level %d, rank %d", ErrBadSpan, i, ranks[x])
+			}
+			if x.next[i].node == nil {
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package skiplist_test
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	skiplist "github.com/pzaino/gods/pkg/skiplist"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+func less(a, b int) bool { return a < b }
+
+func TestSkipListAgainstSlice(t *testing.T) {
+	s := skiplist.New(less)
+	var ref []int
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 3000; i++ {
+		v := r.IntN(500)
+		pos, found := slices.BinarySearch(ref, v)
+		if r.IntN(3) == 0 {
+			if s.Delete(v) != found {
+				t.Fatalf(errExpectedX, found, !found)
+			}
+			if found {
+				ref = slices.Delete(ref, pos, pos+1)
+			}
+		} else {
+			if s.Insert(v) == found {
+				t.Fatalf(errExpectedX, !found, found)
+			}
+			if !found {
+				ref = slices.Insert(ref, pos, v)
+			}
+		}
+		if i%100 == 0 {
+			if err := s.Validate(); err != nil {
+				t.Fatalf(errExpectedNoError, err)
+			}
+		}
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if got := s.ToSlice(); !slices.Equal(got, ref) {
+		t.Fatalf(errExpectedX, ref, got)
+	}
+	for rank, v := range ref {
+		if got, err := s.GetByRank(uint64(rank)); err != nil || got != v {
+			t.Fatalf(errExpectedX, v, got)
+		}
+		if got, ok := s.RankOf(v); !ok || got != uint64(rank) {
+			t.Fatalf(errExpectedX, rank, got)
+		}
+	}
+	if _, ok := s.RankOf(-1); ok {
+		t.Errorf("expected -1 to have no rank")
+	}
+	if _, err := s.GetByRank(s.Size()); err == nil || err.Error() != skiplist.ErrRankOutOfRange {
+		t.Errorf(errExpectedX, skiplist.ErrRankOutOfRange, err)
+	}
+	if got := s.RangeByRank(10, 20); !slices.Equal(got, ref[10:20]) {
+		t.Errorf(errExpectedX, ref[10:20], got)
+	}
+	if got := s.RangeByRank(uint64(len(ref))-3, 1000); !slices.Equal(got, ref[len(ref)-3:]) {
+		t.Errorf(errExpectedX, ref[len(ref)-3:], got)
+	}
+	if got := s.RangeByRank(5, 5); len(got) != 0 {
+		t.Errorf(errExpectedX, 0, len(got))
+	}
+	if lo, _ := s.Min(); lo != ref[0] {
+		t.Errorf(errExpectedX, ref[0], lo)
+	}
+	if hi, _ := s.Max(); hi != ref[len(ref)-1] {
+		t.Errorf(errExpectedX, ref[len(ref)-1], hi)
+	}
+	s.Clear()
+	if !s.IsEmpty() || s.Validate() != nil {
+		t.Errorf("expected an empty skip list")
+	}
+	if _, ok := s.Max(); ok {
+		t.Errorf("expected no max")
+	}
+}
+
+func TestSkipListLeaderboard(t *testing.T) {
+	type player struct {
+		name  string
+		score int
+	}
+	// highest score first, then by name
+	board := skiplist.New(func(a, b player) bool {
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return a.name < b.name
+	})
+	for _, p := range []player{{"ann", 30}, {"bob", 50}, {"cid", 30}, {"dee", 10}} {
+		board.Insert(p)
+	}
+	if rank, _ := board.RankOf(player{"cid", 30}); rank != 2 {
+		t.Errorf(errExpectedX, 2, rank)
+	}
+	// a new score: remove the old entry and insert the new one
+	board.Delete(player{"dee", 10})
+	board.Insert(player{"dee", 40})
+	top := board.RangeByRank(0, 2)
+	names := []string{top[0].name, top[1].name}
+	if !slices.Equal(names, []string{"bob", "dee"}) {
+		t.Errorf(errExpectedX, []string{"bob", "dee"}, names)
+	}
+	if p, _ := board.GetByRank(3); p.name != "cid" {
+		t.Errorf(errExpectedX, "cid", p.name)
+	}
+}
+
+func TestSkipListReplace(t *testing.T) {
+	type entry struct {
+		key   string
+		value int
+	}
+	s := skiplist.New(func(a, b entry) bool { return cmp.Less(a.key, b.key) })
+	if !s.Replace(entry{"a", 1}) || s.Replace(entry{"a", 2}) || s.Insert(entry{"a", 3}) {
+		t.Errorf("expected only the first call to add the element")
+	}
+	if e, ok := s.Get(entry{key: "a"}); !ok || e.value != 2 {
+		t.Errorf(errExpectedX, 2, e.value)
+	}
+	if !s.Contains(entry{key: "a"}) || s.Contains(entry{key: "b"}) || s.Size() != 1 {
+		t.Errorf(errExpectedX, 1, s.Size())
+	}
+}
+
+func TestSkipListFloorCeilingSeek(t *testing.T) {
+	s := skiplist.New(less)
+	for _, v := range []int{10, 20, 30} {
+		s.Insert(v)
+	}
+	for _, tc := range []struct {
+		item                 int
+		floor, ceiling       int
+		hasFloor, hasCeiling bool
+	}{
+		{5, 0, 10, false, true},
+		{10, 10, 10, true, true},
+		{15, 10, 20, true, true},
+		{30, 30, 30, true, true},
+		{35, 30, 0, true, false},
+	} {
+		if f, ok := s.Floor(tc.item); ok != tc.hasFloor || f != tc.floor {
+			t.Errorf(errExpectedX, tc.floor, f)
+		}
+		if c, ok := s.Ceiling(tc.item); ok != tc.hasCeiling || c != tc.ceiling {
+			t.Errorf(errExpectedX, tc.ceiling, c)
+		}
+	}
+	if got := slices.Collect(s.Seek(15)); !slices.Equal(got, []int{20, 30}) {
+		t.Errorf(errExpectedX, []int{20, 30}, got)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package slotmap provides a slot map (a generational index allocator): a
+// container handing out stable handles to its values, with O(1) insertion,
+// removal and lookup. A handle is an index plus a generation, so once its
+// value is removed and the slot reused, the old handle is detected as stale
+// instead of silently pointing to the new value.
+//
+// The values are kept densely packed (removing one moves the last value in
+// its place), so iterating over them is as fast as over a slice, while the
+// handles stay valid: they point to slots, which point to the values.
+package slotmap
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"slices"
+)
+
+// Handle is a stable reference to a value of a SlotMap. The zero Handle is
+// never valid.
+type Handle struct {
+	index uint32
+	gen   uint32
+}
+
+// Index returns the slot index of the handle
+func (h Handle) Index() uint32 {
+	return h.index
+}
+
+// Generation returns the generation of the handle
+func (h Handle) Generation() uint32 {
+	return h.gen
+}
+
+// String returns the handle as "index:generation"
+func (h Handle) String() string {
+	return fmt.Sprintf("%d:%d", h.index, h.gen)
+}
+
+// none marks the end of the free list
+const none = math.MaxUint32
+
+// slot maps a handle to its value. The generation is odd while the slot is
+// occupied, so the handles always have an odd generation and the zero
+// Handle can't match.
+type slot struct {
+	gen uint32
+	// the position of the value in SlotMap.values if the slot is occupied,
+	// otherwise the next free slot
+	pos uint32
+}
+
+// SlotMap stores values of type T addressed by Handle. It is not
+// concurrency-safe.
+type SlotMap[T any] struct {
+	slots  []slot
+	values []T
+	owners []uint32 // owners[i] is the slot of values[i]
+	free   uint32   // the first free slot
+}
+
+// New creates a new empty SlotMap.
+func New[T any]() *SlotMap[T] {
+	return &SlotMap[T]{free: none}
+}
+
+// NewWithCapacity creates a new empty SlotMap with room for n values.
+func NewWithCapacity[T any](n int) *SlotMap[T] {
+	return &SlotMap[T]{
+		slots:  make([]slot, 0, n),
+		values: make([]T, 0, n),
+		owners: make([]uint32, 0, n),
+		free:   none,
+	}
+}
+
+// Size returns the number of values
+func (m *SlotMap[T]) Size() uint64 {
+	return uint64(len(m.values))
+}
+
+// IsEmpty returns true if the map has no values
+func (m *SlotMap[T]) IsEmpty() bool {
+	return len(m.values) == 0
+}
+
+// Insert adds value and returns its handle, in O(1)
+func (m *SlotMap[T]) Insert(value T) Handle {
+	var index uint32
+	if m.free != none {
+		index = m.free
+		m.free = m.slots[index].pos
+	} else {
+		index = uint32(len(m.slots))
+		m.slots = append(m.slots, slot{})
+	}
+	s := &m.slots[index]
+	s.gen++ // even to odd: occupied
+	s.pos = uint32(len(m.values))
+	m.values = append(m.values, value)
+	m.owners = append(m.owners, index)
+	return Handle{index: index, gen: s.gen}
+}
+
+// lookup returns the slot of h, or nil if h is stale
+func (m *SlotMap[T]) lookup(h Handle) *slot {
+	if h.index >= uint32(len(m.slots)) {
+		return nil
+	}
+	s := &m.slots[h.index]
+	if s.gen != h.gen || h.gen&1 == 0 {
+		return nil
+	}
+	return s
+}
+
+// Contains returns true if h refers to a value of the map
+func (m *SlotMap[T]) Contains(h Handle) bool {
+	return m.lookup(h) != nil
+}
+
+// Get returns the value of h, and false if h is stale
+func (m *SlotMap[T]) Get(h Handle) (T, bool) {
+	if s := m.lookup(h); s != nil {
+		return m.values[s.pos], true
+	}
+	var zero T
+	return zero, false
+}
+
+// GetPtr returns a pointer to the value of h, or nil if h is stale. The
+// pointer is valid until the next Insert or Remove.
+func (m *SlotMap[T]) GetPtr(h Handle) *T {
+	if s := m.lookup(h); s != nil {
+		return &m.values[s.pos]
+	}
+	return nil
+}
+
+// Set replaces the value of h, and returns false if h is stale
+func (m *SlotMap[T]) Set(h Handle, value T) bool {
+	s := m.lookup(h)
+	if s == nil {
+		return false
+	}
+	m.values[s.pos] = value
+	return true
+}
+
+// Remove removes the value of h in O(1) and returns it, or false if h is
+// stale. From now on h (and all its copies) are stale.
+func (m *SlotMap[T]) Remove(h Handle) (T, bool) {
+	s := m.lookup(h)
+	if s == nil {
+		var zero T
+		return zero, false
+	}
+	pos, last := s.pos, uint32(len(m.values)-1)
+	value := m.values[pos]
+	// move the last value in the hole
+	m.values[pos], m.owners[pos] = m.values[last], m.owners[last]
+	m.slots[m.owners[pos]].pos = pos
+	var zero T
+	m.values[last] = zero
+	m.values, m.owners = m.values[:last], m.owners[:last]
+	m.release(h.index)
+	return value, true
+}
+
+// release marks a slot as free. A slot whose generation would wrap around
+// is retired instead, so its old handles can never become valid again.
+func (m *SlotMap[T]) release(index uint32) {
+	s := &m.slots[index]
+	s.gen++ // odd to even: free
+	if s.gen == math.MaxUint32-1 {
+		return
+	}
+	s.pos, m.free = m.free, index
+}
+
+// Clear removes all the values, making all the handles stale
+func (m *SlotMap[T]) Clear() {
+	for _, index := range m.owners {
+		m.release(index)
+	}
+	clear(m.values)
+	m.values, m.owners = m.values[:0], m.owners[:0]
+}
+
+// All returns an iterator over the handles and values, in no particular
+// order. The map must not be changed during the iteration.
+func (m *SlotMap[T]) All() iter.Seq2[Handle, T] {
+	return func(yield func(Handle, T) bool) {
+		for i, value := range m.values {
+			index := m.owners[i]
+			if !yield(Handle{index: index, gen: m.slots[index].gen}, value) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values, in no particular order (the
+// one of ToSlice).
+func (m *SlotMap[T]) Values() iter.Seq[T] {
+	return slices.Values(m.values)
+}
+
+// ToSlice returns the values, densely packed, in no particular order. The
+// slice is shared with the map: it can be used to update the values in
+// place, until the next Insert or Remove.
+func (m *SlotMap[T]) ToSlice() []T {
+	return m.values
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slotmap_test
+
+import (
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	slotmap "github.com/pzaino/gods/pkg/slotmap"
+)
+
+const errExpectedX = "expected %v, got %v"
+
+func TestSlotMap(t *testing.T) {
+	m := slotmap.New[string]()
+	a := m.Insert("a")
+	b := m.Insert("b")
+	c := m.Insert("c")
+	if v, ok := m.Get(b); !ok || v != "b" {
+		t.Errorf(errExpectedX, "b", v)
+	}
+	if v, ok := m.Remove(a); !ok || v != "a" {
+		t.Errorf(errExpectedX, "a", v)
+	}
+	// the handles of the moved values are still valid
+	if v, _ := m.Get(c); v != "c" || m.Size() != 2 {
+		t.Errorf(errExpectedX, "c", v)
+	}
+	// the slot of a is reused, but a is stale
+	d := m.Insert("d")
+	if d.Index() != a.Index() || d.Generation() == a.Generation() {
+		t.Errorf(errExpectedX, a.Index(), d.Index())
+	}
+	if _, ok := m.Get(a); ok || m.Contains(a) || m.Set(a, "x") {
+		t.Errorf("expected %v to be stale", a)
+	}
+	if _, ok := m.Remove(a); ok {
+		t.Errorf("expected %v to be stale", a)
+	}
+	if m.Contains(slotmap.Handle{}) {
+		t.Errorf("expected the zero handle to be invalid")
+	}
+	if !m.Set(d, "D") {
+		t.Errorf("expected Set to succeed")
+	}
+	*m.GetPtr(b) = "B"
+	got := maps.Collect(m.All())
+	want := map[slotmap.Handle]string{b: "B", c: "c", d: "D"}
+	if !maps.Equal(got, want) {
+		t.Errorf(errExpectedX, want, got)
+	}
+	values := slices.Sorted(m.Values())
+	if !slices.Equal(values, []string{"B", "D", "c"}) {
+		t.Errorf(errExpectedX, []string{"B", "D", "c"}, values)
+	}
+	if got := slices.Sorted(slices.Values(m.ToSlice())); !slices.Equal(got, values) {
+		t.Errorf(errExpectedX, values, got)
+	}
+	m.Clear()
+	if !m.IsEmpty() || m.Contains(b) || m.GetPtr(c) != nil {
+		t.Errorf("expected all the handles to be stale")
+	}
+}
+
+func TestSlotMapAgainstMap(t *testing.T) {
+	m := slotmap.NewWithCapacity[int](16)
+	ref := map[slotmap.Handle]int{}
+	var stale []slotmap.Handle
+	r := rand.New(rand.NewPCG(3, 4))
+	for i := 0; i < 5000; i++ {
+		if len(ref) > 0 && r.IntN(2) == 0 {
+			for h, v := range ref {
+				if got, ok := m.Remove(h); !ok || got != v {
+					t.Fatalf(errExpectedX, v, got)
+				}
+				delete(ref, h)
+				stale = append(stale, h)
+				break
+			}
+		} else {
+			ref[m.Insert(i)] = i
+		}
+	}
+	if m.Size() != uint64(len(ref)) {
+		t.Fatalf(errExpectedX, len(ref), m.Size())
+	}
+	for h, v := range ref {
+		if got, ok := m.Get(h); !ok || got != v {
+			t.Fatalf(errExpectedX, v, got)
+		}
+	}
+	for _, h := range stale {
+		if m.Contains(h) {
+			t.Fatalf("expected %v to be stale", h)
+		}
+	}
+}
@@ -0,0 +1,773 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	optional "github.com/pzaino/gods/pkg/optional"
+)
+
+// DefaultChunkSize is the number of items of a chunk of a Chunked stack
+// created with a chunk size of 0.
+const DefaultChunkSize = 1024
+
+// minChunkCap is the capacity of the first chunk of a Chunked stack, which
+// grows like a slice up to the chunk size
+const minChunkCap = 8
+
+// chunk is a block of at most chunkSize items, linked to the chunks below
+// and above it
+type chunk[T any] struct {
+	items      []T
+	prev, next *chunk[T]
+}
+
+// Chunked is a non-concurrent-safe stack backed by a linked list of
+// fixed-size chunks instead of a single slice. A push never reallocates nor
+// copies the items already pushed (only the first chunk grows like a slice,
+// up to the chunk size), so its cost doesn't depend on the size of the
+// stack. It is the storage of csstack.CSStack.
+//
+// It has the methods of Stack, with the same indexes (the ones of SwapAt and
+// ForRange count from the top, the ones returned by FindIndex and
+// FindIndices from the bottom); the methods taking an index walk the chunks
+// to reach it.
+//
+// The chunk left empty by a pop is kept as a spare, so pushing and popping
+// around a chunk boundary doesn't allocate.
+type Chunked[T comparable] struct {
+	bottom, top *chunk[T]
+	spare       *chunk[T]
+	chunkSize   int
+	chunks      int // the chunks in the stack, not counting the spare
+	size        uint64
+	growth      gods.Growth
+}
+
+// NewChunked creates a new Chunked stack made of chunks of chunkSize items
+// (DefaultChunkSize if it's 0).
+func NewChunked[T comparable](chunkSize int) *Chunked[T] {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	return &Chunked[T]{chunkSize: chunkSize}
+}
+
+// link adds an empty chunk on top: the spare one if there's one, a full-size
+// one above another chunk, and a small one at the bottom, so that small
+// stacks stay small.
+func (s *Chunked[T]) link() {
+	c := s.spare
+	s.spare = nil
+	if c == nil {
+		c = &chunk[T]{}
+		if s.top != nil {
+			c.items = make([]T, 0, s.chunkSize)
+			s.growth.Alloc(uint64(s.chunkSize))
+		}
+	}
+	c.prev = s.top
+	if s.top != nil {
+		s.top.next = c
+	} else {
+		s.bottom = c
+	}
+	s.top = c
+	s.chunks++
+}
+
+// unlink removes the empty top chunk, keeping it as the spare
+func (s *Chunked[T]) unlink() {
+	c := s.top
+	s.top, c.prev = c.prev, nil
+	if s.top != nil {
+		s.top.next = nil
+	} else {
+		s.bottom = nil
+	}
+	s.spare = c
+	s.chunks--
+}
+
+// reserve makes room on top for up to n items and returns how many fit in
+// the top chunk. The items already pushed are copied only while the top
+// chunk is smaller than the chunk size.
+func (s *Chunked[T]) reserve(n int) int {
+	if s.top == nil || len(s.top.items) == s.chunkSize {
+		s.link()
+	}
+	c := s.top
+	n = min(n, s.chunkSize-len(c.items))
+	if len(c.items)+n > cap(c.items) {
+		items := make([]T, len(c.items), min(max(2*cap(c.items), len(c.items)+n, minChunkCap), s.chunkSize))
+		copy(items, c.items)
+		c.items = items
+		s.growth.Alloc(uint64(cap(items)))
+	}
+	return n
+}
+
+// track records the size and the capacity of the stack after a push (the
+// chunks below the top one are full)
+func (s *Chunked[T]) track() {
+	s.growth.Track(s.size, uint64(s.chunks-1)*uint64(s.chunkSize)+uint64(cap(s.top.items)))
+}
+
+// drop removes the n top items, clearing their slots so they don't keep the
+// items alive
+func (s *Chunked[T]) drop(n uint64) {
+	for n > 0 {
+		c := s.top
+		k := min(n, uint64(len(c.items)))
+		rest := len(c.items) - int(k)
+		clear(c.items[rest:])
+		c.items = c.items[:rest]
+		s.size -= k
+		n -= k
+		if rest == 0 {
+			s.unlink()
+		}
+	}
+}
+
+// up yields the items from the bottom, with their index (0 is the bottom
+// item)
+func (s *Chunked[T]) up() iter.Seq2[uint64, *T] {
+	return func(yield func(uint64, *T) bool) {
+		var i uint64
+		for c := s.bottom; c != nil; c = c.next {
+			for j := range c.items {
+				if !yield(i, &c.items[j]) {
+					return
+				}
+				i++
+			}
+		}
+	}
+}
+
+// down yields the items from the top, with their index counted from the
+// bottom (like up)
+func (s *Chunked[T]) down() iter.Seq2[uint64, *T] {
+	return func(yield func(uint64, *T) bool) {
+		i := s.size
+		for c := s.top; c != nil; c = c.prev {
+			for j := len(c.items) - 1; j >= 0; j-- {
+				i--
+				if !yield(i, &c.items[j]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// at returns the item at the position i counted from the top, which must be
+// less than the size
+func (s *Chunked[T]) at(i uint64) *T {
+	c := s.top
+	for i >= uint64(len(c.items)) {
+		i -= uint64(len(c.items))
+		c = c.prev
+	}
+	return &c.items[uint64(len(c.items))-1-i]
+}
+
+// Push adds an item to the stack.
+func (s *Chunked[T]) Push(item T) {
+	s.reserve(1)
+	s.top.items = append(s.top.items, item)
+	s.size++
+	s.track()
+}
+
+// PushN adds multiple items to the stack (the last one on top), copying them
+// chunk by chunk.
+func (s *Chunked[T]) PushN(items ...T) {
+	if len(items) == 0 {
+		return
+	}
+	for len(items) > 0 {
+		n := s.reserve(len(items))
+		s.top.items = append(s.top.items, items[:n]...)
+		s.size += uint64(n)
+		items = items[n:]
+	}
+	s.track()
+}
+
+// PushAll adds multiple items to the stack (the last one on top).
+func (s *Chunked[T]) PushAll(items []T) {
+	s.PushN(items...)
+}
+
+// IsEmpty checks if the stack is empty.
+func (s *Chunked[T]) IsEmpty() bool {
+	return s == nil || s.size == 0
+}
+
+// Size returns the number of items in the stack.
+func (s *Chunked[T]) Size() uint64 {
+	if s == nil {
+		return 0
+	}
+	return s.size
+}
+
+// Chunks returns the number of chunks holding the items.
+func (s *Chunked[T]) Chunks() int {
+	return s.chunks
+}
+
+// Pop removes and returns the top item from the stack.
+func (s *Chunked[T]) Pop() (*T, error) {
+	if item, ok := s.PopOpt().Get(); ok {
+		return &item, nil
+	}
+	return nil, errors.New(ErrStackIsEmpty)
+}
+
+// PopOpt is like Pop, but it returns the item as an Option (None if the
+// stack is empty), without allocating.
+func (s *Chunked[T]) PopOpt() optional.Option[T] {
+	if s.IsEmpty() {
+		return optional.None[T]()
+	}
+	item := s.top.items[len(s.top.items)-1]
+	s.drop(1)
+	return optional.Some(item)
+}
+
+// Top returns a pointer to a copy of the top item, without removing it.
+func (s *Chunked[T]) Top() (*T, error) {
+	if item, ok := s.TopOpt().Get(); ok {
+		return &item, nil
+	}
+	return nil, errors.New(ErrStackIsEmpty)
+}
+
+// Peek is a wrapper around Top.
+func (s *Chunked[T]) Peek() (*T, error) {
+	return s.Top()
+}
+
+// TopOpt is like Top, but it returns the item as an Option (None if the
+// stack is empty), without allocating.
+func (s *Chunked[T]) TopOpt() optional.Option[T] {
+	if s.IsEmpty() {
+		return optional.None[T]()
+	}
+	return optional.Some(s.top.items[len(s.top.items)-1])
+}
+
+// PopN removes and returns the top n items from the stack, from the top.
+func (s *Chunked[T]) PopN(n uint64) ([]T, error) {
+	if s.IsEmpty() {
+		return nil, errors.New(ErrStackIsEmpty)
+	}
+	if s.size < n {
+		return nil, errors.New(ErrNotEnoughItems)
+	}
+	return s.popTop(n), nil
+}
+
+// popTop removes the top n items and returns them from the top, with one
+// allocation and a bulk copy per chunk.
+func (s *Chunked[T]) popTop(n uint64) []T {
+	items := make([]T, n)
+	// items is filled from the end, with the top chunk first
+	end := n
+	for c := s.top; end > 0; c = c.prev {
+		k := min(end, uint64(len(c.items)))
+		copy(items[end-k:end], c.items[uint64(len(c.items))-k:])
+		end -= k
+	}
+	slices.Reverse(items)
+	s.drop(n)
+	return items
+}
+
+// PopAll removes and returns all items from the stack, from the top.
+func (s *Chunked[T]) PopAll() []T {
+	return s.popTop(s.size)
+}
+
+// PopWhile pops the items while pred returns true for the top item, and
+// returns them in the order they were popped (nil if pred is false for the
+// top item or the stack is empty).
+func (s *Chunked[T]) PopWhile(pred func(T) bool) []T {
+	var items []T
+	for !s.IsEmpty() && pred(s.top.items[len(s.top.items)-1]) {
+		items = append(items, s.PopOpt().MustGet())
+	}
+	return items
+}
+
+// ToSlice returns the stack as a slice, from the top.
+func (s *Chunked[T]) ToSlice() []T {
+	if s.IsEmpty() {
+		return nil
+	}
+	items := make([]T, 0, s.size)
+	for _, p := range s.down() {
+		items = append(items, *p)
+	}
+	return items
+}
+
+// Freeze returns an immutable copy of the stack (from the top), which can be
+// shared without risking changes to it (see gods.Frozen).
+func (s *Chunked[T]) Freeze() *gods.Frozen[T] {
+	return gods.NewFrozen(s.ToSlice())
+}
+
+// Reverse reverses the stack.
+func (s *Chunked[T]) Reverse() {
+	items := s.ToSlice()
+	for i, p := range s.up() {
+		*p = items[i]
+	}
+}
+
+// Swap swaps the top two items on the stack.
+func (s *Chunked[T]) Swap() error {
+	if s.Size() < 2 {
+		return errors.New(ErrLessThanTwoItems)
+	}
+	return s.SwapAt(0, 1)
+}
+
+// SwapAt swaps the items at the positions i and j, counted from the top of
+// the stack (0 is the top item, so SwapAt(0, 1) is like Swap).
+func (s *Chunked[T]) SwapAt(i, j uint64) error {
+	if i >= s.size || j >= s.size {
+		return errors.New(ErrIndexOOR)
+	}
+	a, b := s.at(i), s.at(j)
+	*a, *b = *b, *a
+	return nil
+}
+
+// MemoryUsage returns an estimate of the bytes held by the stack and its
+// chunks (see the gods package), adding the memory returned by extra for
+// every item.
+func (s *Chunked[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
+	n := uint64(unsafe.Sizeof(*s))
+	for c := s.bottom; c != nil; c = c.next {
+		n += gods.NodesMemory[chunk[T]](1) + gods.SliceMemory(c.items, extra...)
+	}
+	if s.spare != nil {
+		n += gods.NodesMemory[chunk[T]](1) + gods.SliceMemory(s.spare.items)
+	}
+	return n
+}
+
+// Stats returns the statistics of the stack: its peak size and how many
+// times it allocated a chunk (or grew the first one).
+func (s *Chunked[T]) Stats() gods.Stats {
+	return gods.Stats{Growth: s.growth.Stats()}
+}
+
+// Validate checks the invariants of the stack: the links between the chunks,
+// the chunks below the top one being full and the number of items (see
+// gods.Validator).
+func (s *Chunked[T]) Validate() error {
+	var prev *chunk[T]
+	chunks, size := 0, uint64(0)
+	for c := s.bottom; c != nil; prev, c = c, c.next {
+		chunks++
+		if c.prev != prev {
+			return gods.Invariantf("chunk %d isn't linked to the chunk below it", chunks)
+		}
+		if c.next != nil && len(c.items) != s.chunkSize {
+			return gods.Invariantf("chunk %d holds %d items instead of %d", chunks, len(c.items), s.chunkSize)
+		}
+		if len(c.items) == 0 {
+			return gods.Invariantf("chunk %d is empty", chunks)
+		}
+		size += uint64(len(c.items))
+	}
+	if prev != s.top {
+		return gods.Invariantf("the top chunk isn't the last one")
+	}
+	if chunks != s.chunks || size != s.size {
+		return gods.Invariantf("%d chunks holding %d items, but %d chunks and %d items recorded", chunks, size, s.chunks, s.size)
+	}
+	return nil
+}
+
+// Dump writes a rendering of the stack to w, from the top item, for
+// debugging.
+func (s *Chunked[T]) Dump(w io.Writer) error {
+	d := gods.NewDumper(w)
+	d.Line(0, "Stack size=%d chunks=%d chunkSize=%d", s.size, s.chunks, s.chunkSize)
+	for i, p := range s.down() {
+		marker := ""
+		if i == s.size-1 {
+			marker = " <- top"
+		}
+		d.Line(1, "[%d] %v%s", i, *p, marker)
+	}
+	return d.Err()
+}
+
+// Clear removes all items from the stack, keeping one chunk as the spare.
+func (s *Chunked[T]) Clear() {
+	s.drop(s.size)
+}
+
+// Contains checks if the stack contains an item.
+func (s *Chunked[T]) Contains(item T) bool {
+	for _, p := range s.up() {
+		if *p == item {
+			return true
+		}
+	}
+	return false
+}
+
+// Copy returns a new Chunked stack with the same items and chunk size.
+func (s *Chunked[T]) Copy() *Chunked[T] {
+	c := NewChunked[T](s.chunkSize)
+	for from := s.bottom; from != nil; from = from.next {
+		c.PushN(from.items...)
+	}
+	return c
+}
+
+// Equal checks if two stacks hold the same items (whatever their chunk
+// size).
+func (s *Chunked[T]) Equal(other *Chunked[T]) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+	if s.size != other.size {
+		return false
+	}
+	next, stop := iter.Pull2(other.up())
+	defer stop()
+	for _, p := range s.up() {
+		if _, q, _ := next(); *p != *q {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns a string representation of the stack, from the bottom
+// (like Stack.String).
+func (s *Chunked[T]) String() string {
+	if s.IsEmpty() {
+		return "[]"
+	}
+	items := s.ToSlice()
+	slices.Reverse(items)
+	return fmt.Sprintf("%v", items)
+}
+
+// Filter removes items from the stack that don't match the predicate.
+func (s *Chunked[T]) Filter(predicate func(T) bool) {
+	var items []T
+	for _, p := range s.up() {
+		if predicate(*p) {
+			items = append(items, *p)
+		}
+	}
+	s.keep(items)
+}
+
+// keep replaces the items of the stack with the given ones (no more than
+// the items of the stack), from the bottom, reusing the chunks.
+func (s *Chunked[T]) keep(items []T) {
+	for i, p := range s.up() {
+		if i == uint64(len(items)) {
+			break
+		}
+		*p = items[i]
+	}
+	s.drop(s.size - uint64(len(items)))
+}
+
+// Map creates a new stack with the results of applying the function to each
+// item.
+func (s *Chunked[T]) Map(fn func(T) T) *Chunked[T] {
+	m := NewChunked[T](s.chunkSize)
+	for _, p := range s.up() {
+		m.Push(fn(*p))
+	}
+	return m
+}
+
+// Reduce reduces the stack to a single value, from the bottom.
+func (s *Chunked[T]) Reduce(fn func(T, T) T) (T, error) {
+	var result T
+	if s.IsEmpty() {
+		return result, errors.New(ErrStackIsEmpty)
+	}
+	for i, p := range s.up() {
+		if i == 0 {
+			result = *p
+			continue
+		}
+		result = fn(result, *p)
+	}
+	return result, nil
+}
+
+// ForEach applies the function to each item in the stack, from the top.
+func (s *Chunked[T]) ForEach(fn func(*T) error) error {
+	if s.IsEmpty() {
+		return nil
+	}
+	return s.ForRange(0, s.size-1, fn)
+}
+
+// ForRange applies the function to each item in the stack within the
+// specified range (start and end are inclusive, and 0 is the top item).
+func (s *Chunked[T]) ForRange(start, end uint64, fn func(*T) error) error {
+	if s.IsEmpty() {
+		return nil
+	}
+	if start >= s.size {
+		return errors.New(ErrStartIndexOOR)
+	}
+	if end >= s.size {
+		return errors.New(ErrEndIndexOOR)
+	}
+	if start > end {
+		return errors.New(ErrSIndexGreater)
+	}
+	for i, p := range s.down() {
+		pos := s.size - 1 - i
+		if pos < start {
+			continue
+		}
+		if pos > end {
+			break
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForFrom applies the function to each item in the stack starting from the
+// specified index (0 is the top item).
+func (s *Chunked[T]) ForFrom(start uint64, fn func(*T) error) error {
+	if s.IsEmpty() {
+		return nil
+	}
+	return s.ForRange(start, s.size-1, fn)
+}
+
+// Any checks if any item in the stack matches the predicate.
+func (s *Chunked[T]) Any(predicate func(T) bool) bool {
+	for _, p := range s.up() {
+		if predicate(*p) {
+			return true
+		}
+	}
+	return false
+}
+
+// All checks if all items in the stack match the predicate (false if the
+// stack is empty, like Stack.All).
+func (s *Chunked[T]) All(predicate func(T) bool) bool {
+	if s.IsEmpty() {
+		return false
+	}
+	for _, p := range s.up() {
+		if !predicate(*p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns the first item, from the bottom, that matches the predicate.
+func (s *Chunked[T]) Find(predicate func(T) bool) (*T, error) {
+	for _, p := range s.up() {
+		if predicate(*p) {
+			return p, nil
+		}
+	}
+	return nil, errors.New(ErrItemNotFound)
+}
+
+// FindIndex returns the index (from the bottom) of the first item that
+// matches the predicate.
+func (s *Chunked[T]) FindIndex(predicate func(T) bool) (uint64, error) {
+	for i, p := range s.up() {
+		if predicate(*p) {
+			return i, nil
+		}
+	}
+	return 0, errors.New(ErrItemNotFound)
+}
+
+// FindLast returns the last item, from the bottom, that matches the
+// predicate.
+func (s *Chunked[T]) FindLast(predicate func(T) bool) (*T, error) {
+	for _, p := range s.down() {
+		if predicate(*p) {
+			return p, nil
+		}
+	}
+	return nil, errors.New(ErrItemNotFound)
+}
+
+// FindLastIndex returns the index (from the bottom) of the last item that
+// matches the predicate.
+func (s *Chunked[T]) FindLastIndex(predicate func(T) bool) (uint64, error) {
+	for i, p := range s.down() {
+		if predicate(*p) {
+			return i, nil
+		}
+	}
+	return 0, errors.New(ErrItemNotFound)
+}
+
+// FindAll returns all items that match the predicate, from the bottom.
+func (s *Chunked[T]) FindAll(predicate func(T) bool) []T {
+	var items []T
+	for _, p := range s.up() {
+		if predicate(*p) {
+			items = append(items, *p)
+		}
+	}
+	return items
+}
+
+// FindIndices returns the indices (from the bottom) of all items that match
+// the predicate.
+func (s *Chunked[T]) FindIndices(predicate func(T) bool) []uint64 {
+	var indices []uint64
+	for i, p := range s.up() {
+		if predicate(*p) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// Iter returns an iterator over the items of the stack, from the top to the
+// bottom.
+func (s *Chunked[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, p := range s.down() {
+			if !yield(*p) {
+				return
+			}
+		}
+	}
+}
+
+// Enumerate returns an iterator over the index and the value of the items of
+// the stack, from the top (index 0) to the bottom.
+func (s *Chunked[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		var i uint64
+		for _, p := range s.down() {
+			if !yield(i, *p) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (s *Chunked[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(s.Enumerate())
+}
+
+// Values returns an iterator over the items, in the same order as Enumerate
+// (it is Iter, under the name used by the standard library).
+func (s *Chunked[T]) Values() iter.Seq[T] {
+	return s.Iter()
+}
+
+// EncodeStream writes the stack to w incrementally (from the top, like
+// Stack.EncodeStream), in chunks encoded with the given codec (see
+// gods.EncodeStream), without copying its elements first.
+func (s *Chunked[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeStream(w, codec, s.Iter())
+}
+
+// DecodeStream replaces the content of the stack with the elements of a
+// stream written by EncodeStream, adding them as they are read.
+func (s *Chunked[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	s.Clear()
+	err := gods.DecodeStream(r, codec, func(item T) error {
+		s.Push(item)
+		return nil
+	})
+	// items are encoded from the top, so the ones pushed so far are upside down
+	s.Reverse()
+	return err
+}
+
+// FilterCtx is like Filter but it stops as soon as the context is done,
+// returning ctx.Err(). In that case the stack is left unchanged.
+func (s *Chunked[T]) FilterCtx(ctx context.Context, predicate func(T) bool) error {
+	var items []T
+	for _, p := range s.up() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if predicate(*p) {
+			items = append(items, *p)
+		}
+	}
+	s.keep(items)
+	return nil
+}
+
+// MapCtx is like Map but it stops as soon as the context is done, returning
+// ctx.Err().
+func (s *Chunked[T]) MapCtx(ctx context.Context, fn func(T) T) (*Chunked[T], error) {
+	m := NewChunked[T](s.chunkSize)
+	for _, p := range s.up() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		m.Push(fn(*p))
+	}
+	return m, nil
+}
+
+// ForEachCtx is like ForEach (from the top to the bottom) but it stops as
+// soon as the context is done, returning ctx.Err().
+func (s *Chunked[T]) ForEachCtx(ctx context.Context, fn func(*T) error) error {
+	for _, p := range s.down() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
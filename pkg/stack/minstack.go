@@ -0,0 +1,99 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"errors"
+
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+)
+
+// MinStack is a non-concurrent-safe stack that keeps track of its minimum
+// item, so Min is O(1). The order is given by a less function, so the same
+// type tracks the maximum with a reversed order:
+//
+//	maxes := stack.NewMinStack(cmpx.NaturalOrder[int]().Reverse())
+//
+// Besides the items, it keeps a stack of the successive minimums (only the
+// items that are less than or equal to the minimum when they are pushed).
+type MinStack[T any] struct {
+	items []T
+	mins  []T
+	less  cmpx.Less[T]
+}
+
+// NewMinStack creates a new MinStack ordered by less.
+func NewMinStack[T any](less cmpx.Less[T]) *MinStack[T] {
+	return &MinStack[T]{less: less}
+}
+
+// Push adds an item to the stack.
+func (s *MinStack[T]) Push(item T) {
+	s.items = append(s.items, item)
+	if len(s.mins) == 0 || !s.less(s.mins[len(s.mins)-1], item) {
+		s.mins = append(s.mins, item)
+	}
+}
+
+// Pop removes and returns the top item.
+func (s *MinStack[T]) Pop() (T, error) {
+	var zero T
+	if len(s.items) == 0 {
+		return zero, errors.New(ErrStackIsEmpty)
+	}
+	item := s.items[len(s.items)-1]
+	s.items[len(s.items)-1] = zero
+	s.items = s.items[:len(s.items)-1]
+	if !s.less(item, s.mins[len(s.mins)-1]) && !s.less(s.mins[len(s.mins)-1], item) {
+		s.mins[len(s.mins)-1] = zero
+		s.mins = s.mins[:len(s.mins)-1]
+	}
+	return item, nil
+}
+
+// Top returns the top item without removing it.
+func (s *MinStack[T]) Top() (T, error) {
+	if len(s.items) == 0 {
+		var zero T
+		return zero, errors.New(ErrStackIsEmpty)
+	}
+	return s.items[len(s.items)-1], nil
+}
+
+// Min returns the minimum item (the maximum, with a reversed order).
+func (s *MinStack[T]) Min() (T, error) {
+	if len(s.mins) == 0 {
+		var zero T
+		return zero, errors.New(ErrStackIsEmpty)
+	}
+	return s.mins[len(s.mins)-1], nil
+}
+
+// Size returns the number of items in the stack.
+func (s *MinStack[T]) Size() uint64 {
+	return uint64(len(s.items))
+}
+
+// IsEmpty returns true if the stack is empty.
+func (s *MinStack[T]) IsEmpty() bool {
+	return len(s.items) == 0
+}
+
+// Clear removes all the items.
+func (s *MinStack[T]) Clear() {
+	clear(s.items)
+	clear(s.mins)
+	s.items, s.mins = s.items[:0], s.mins[:0]
+}
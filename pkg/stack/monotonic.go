@@ -0,0 +1,106 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"errors"
+
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+)
+
+// MonotonicStack is a non-concurrent-safe stack whose items are kept in
+// non-decreasing order (by less) from the bottom to the top: Push first pops
+// the items greater than the new one, reporting each of them to a callback
+// together with the item that pushed it out. It solves the "next smaller
+// element" family of problems (like the largest rectangle in a histogram);
+// with a reversed order it finds the next greater element:
+//
+//	// next[i] is the index of the first value after i greater than values[i]
+//	s := stack.Monotonic(func(i, j int) bool { return values[i] > values[j] },
+//		func(popped, pushed int) { next[popped] = pushed })
+//	for i := range values {
+//		s.Push(i)
+//	}
+//
+// The items left in the stack have no such element.
+type MonotonicStack[T any] struct {
+	items []T
+	less  cmpx.Less[T]
+	onPop func(popped, pushed T)
+}
+
+// Monotonic creates a new MonotonicStack ordered by less. onPop (which can be
+// nil) is called for every item popped by Push, in the order they are popped.
+func Monotonic[T any](less cmpx.Less[T], onPop func(popped, pushed T)) *MonotonicStack[T] {
+	return &MonotonicStack[T]{less: less, onPop: onPop}
+}
+
+// Push pops the items greater than item, then pushes item.
+func (s *MonotonicStack[T]) Push(item T) {
+	for len(s.items) > 0 {
+		top := s.items[len(s.items)-1]
+		if !s.less(item, top) {
+			break
+		}
+		s.items = s.items[:len(s.items)-1]
+		if s.onPop != nil {
+			s.onPop(top, item)
+		}
+	}
+	s.items = append(s.items, item)
+}
+
+// Pop removes and returns the top item (the greatest one).
+func (s *MonotonicStack[T]) Pop() (T, error) {
+	var zero T
+	if len(s.items) == 0 {
+		return zero, errors.New(ErrStackIsEmpty)
+	}
+	item := s.items[len(s.items)-1]
+	s.items[len(s.items)-1] = zero
+	s.items = s.items[:len(s.items)-1]
+	return item, nil
+}
+
+// Top returns the top item (the greatest one) without removing it.
+func (s *MonotonicStack[T]) Top() (T, error) {
+	if len(s.items) == 0 {
+		var zero T
+		return zero, errors.New(ErrStackIsEmpty)
+	}
+	return s.items[len(s.items)-1], nil
+}
+
+// Items returns a copy of the items, from the bottom (the least one) to the
+// top.
+func (s *MonotonicStack[T]) Items() []T {
+	return append([]T(nil), s.items...)
+}
+
+// Size returns the number of items in the stack.
+func (s *MonotonicStack[T]) Size() uint64 {
+	return uint64(len(s.items))
+}
+
+// IsEmpty returns true if the stack is empty.
+func (s *MonotonicStack[T]) IsEmpty() bool {
+	return len(s.items) == 0
+}
+
+// Clear removes all the items, without reporting them.
+func (s *MonotonicStack[T]) Clear() {
+	clear(s.items)
+	s.items = s.items[:0]
+}
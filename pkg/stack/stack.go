@@ -16,24 +16,37 @@
 package stack
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"iter"
+	"slices"
 	"sync"
+	"unsafe"
+
+	gods "github.com/pzaino/gods"
+	iterator "github.com/pzaino/gods/pkg/iterator"
+	optional "github.com/pzaino/gods/pkg/optional"
 )
 
 // Error messages
 const (
-	ErrItemNotFound  = "item not found"
-	ErrStackIsEmpty  = "stack is empty"
-	ErrStartIndexOOR = "start index out of range"
-	ErrEndIndexOOR   = "end index out of range"
-	ErrSIndexGreater = "start index is greater than end index"
+	ErrItemNotFound     = "item not found"
+	ErrStackIsEmpty     = "stack is empty"
+	ErrStartIndexOOR    = "start index out of range"
+	ErrEndIndexOOR      = "end index out of range"
+	ErrSIndexGreater    = "start index is greater than end index"
+	ErrIndexOOR         = "index out of range"
+	ErrNotEnoughItems   = "stack has less items than requested"
+	ErrLessThanTwoItems = "Stack has less than 2 items"
 )
 
 // Stack is a non-concurrent-safe stack.
 type Stack[T comparable] struct {
-	items []T
-	size  uint64
+	items  []T
+	size   uint64
+	growth gods.Growth
 }
 
 // New creates a new Stack.
@@ -58,7 +71,7 @@ func NewFromSlice[T comparable](items []T) *Stack[T] {
 
 // Push adds an item to the stack.
 func (s *Stack[T]) Push(item T) {
-	s.items = append(s.items, item)
+	s.items = gods.Append(&s.growth, s.items, item)
 	s.size++
 }
 
@@ -96,6 +109,12 @@ func (s *Stack[T]) ToSlice() []T {
 	return items
 }
 
+// Freeze returns an immutable copy of the stack (from the top), which can be
+// shared without risking changes to it (see gods.Frozen).
+func (s *Stack[T]) Freeze() *gods.Frozen[T] {
+	return gods.NewFrozen(s.ToSlice())
+}
+
 // Reverse reverses the stack.
 func (s *Stack[T]) Reverse() {
 	if s.IsEmpty() {
@@ -111,13 +130,24 @@ func (s *Stack[T]) Reverse() {
 // Swap swaps the top two items on the stack.
 func (s *Stack[T]) Swap() error {
 	if s.IsEmpty() || s.size < 2 {
-		return errors.New("Stack has less than 2 items")
+		return errors.New(ErrLessThanTwoItems)
 	}
 
 	s.items[len(s.items)-1], s.items[len(s.items)-2] = s.items[len(s.items)-2], s.items[len(s.items)-1]
 	return nil
 }
 
+// SwapAt swaps the items at the positions i and j, counted from the top of
+// the stack (0 is the top item, so SwapAt(0, 1) is like Swap).
+func (s *Stack[T]) SwapAt(i, j uint64) error {
+	if i >= s.size || j >= s.size {
+		return errors.New(ErrIndexOOR)
+	}
+	i, j = s.size-1-i, s.size-1-j
+	s.items[i], s.items[j] = s.items[j], s.items[i]
+	return nil
+}
+
 // Top returns the top item from the stack without removing it.
 func (s *Stack[T]) Top() (*T, error) {
 	if s.IsEmpty() {
@@ -133,6 +163,27 @@ func (s *Stack[T]) Peek() (*T, error) {
 	return s.Top()
 }
 
+// PopOpt is like Pop, but it returns the item as an Option (None if the
+// stack is empty), without allocating.
+func (s *Stack[T]) PopOpt() optional.Option[T] {
+	if s.IsEmpty() {
+		return optional.None[T]()
+	}
+	item := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	s.size--
+	return optional.Some(item)
+}
+
+// TopOpt is like Top, but it returns the item as an Option (None if the
+// stack is empty), without allocating.
+func (s *Stack[T]) TopOpt() optional.Option[T] {
+	if s.IsEmpty() {
+		return optional.None[T]()
+	}
+	return optional.Some(s.items[len(s.items)-1])
+}
+
 // Size returns the number of items in the stack.
 func (s *Stack[T]) Size() uint64 {
 	if s.IsEmpty() {
@@ -141,6 +192,41 @@ func (s *Stack[T]) Size() uint64 {
 	return s.size
 }
 
+// MemoryUsage returns an estimate of the bytes held by the stack (see the
+// gods package), adding the memory returned by extra for every item.
+func (s *Stack[T]) MemoryUsage(extra ...func(T) uint64) uint64 {
+	return uint64(unsafe.Sizeof(*s)) + gods.SliceMemory(s.items, extra...)
+}
+
+// Stats returns the statistics of the stack: its peak size and how many
+// times its backing array was reallocated to grow.
+func (s *Stack[T]) Stats() gods.Stats {
+	return gods.Stats{Growth: s.growth.Stats()}
+}
+
+// Validate checks the invariants of the stack (see gods.Validator).
+func (s *Stack[T]) Validate() error {
+	if s.size != uint64(len(s.items)) {
+		return gods.Invariantf("size is %d but the stack holds %d items", s.size, len(s.items))
+	}
+	return nil
+}
+
+// Dump writes a rendering of the stack to w, from the top item, for
+// debugging.
+func (s *Stack[T]) Dump(w io.Writer) error {
+	d := gods.NewDumper(w)
+	d.Line(0, "Stack size=%d cap=%d", s.size, cap(s.items))
+	for i := len(s.items) - 1; i >= 0; i-- {
+		marker := ""
+		if i == len(s.items)-1 {
+			marker = " <- top"
+		}
+		d.Line(1, "[%d] %v%s", i, s.items[i], marker)
+	}
+	return d.Err()
+}
+
 // CheckSize recalculate the size of the stack.
 func (s *Stack[T]) CheckSize() {
 	if s.IsEmpty() {
@@ -224,7 +310,7 @@ func (s *Stack[T]) String() string {
 	return fmt.Sprintf("%v", s.items)
 }
 
-// PopN removes and returns the top n items from the stack.
+// PopN removes and returns the top n items from the stack, from the top.
 func (s *Stack[T]) PopN(n uint64) ([]T, error) {
 	if s.IsEmpty() {
 		return nil, errors.New(ErrStackIsEmpty)
@@ -233,38 +319,52 @@ func (s *Stack[T]) PopN(n uint64) ([]T, error) {
 		return nil, errors.New("Stack has less items than requested")
 	}
 
+	return s.popTop(int(n)), nil
+}
+
+// popTop removes the top n items and returns them from the top, with one
+// allocation and a bulk copy. The vacated slots are cleared, so they don't
+// keep the items alive.
+func (s *Stack[T]) popTop(n int) []T {
+	rest := len(s.items) - n
 	items := make([]T, n)
-	for i := uint64(0); i < n; i++ {
-		item, err := s.Pop()
-		if err != nil {
-			return nil, err
-		}
-		items[i] = *item
+	copy(items, s.items[rest:])
+	slices.Reverse(items)
+	clear(s.items[rest:])
+	s.items = s.items[:rest]
+	s.size -= uint64(n)
+	return items
+}
+
+// PopWhile pops the items while pred returns true for the top item, and
+// returns them in the order they were popped (nil if pred is false for the
+// top item or the stack is empty).
+func (s *Stack[T]) PopWhile(pred func(T) bool) []T {
+	var items []T
+	for len(s.items) > 0 && pred(s.items[len(s.items)-1]) {
+		items = append(items, s.items[len(s.items)-1])
+		s.items = s.items[:len(s.items)-1]
+		s.size--
 	}
-	return items, nil
+	return items
 }
 
-// PushN adds multiple items to the stack.
+// PushN adds multiple items to the stack (the last one on top), growing its
+// storage at most once.
 func (s *Stack[T]) PushN(items ...T) {
-	s.items = append(s.items, items...)
+	s.items = gods.Append(&s.growth, s.items, items...)
 	s.size += uint64(len(items))
 }
 
 // PopAll removes and returns all items from the stack.
 func (s *Stack[T]) PopAll() []T {
-	items := make([]T, len(s.items))
-	for i := len(s.items) - 1; i >= 0; i-- {
-		items[len(s.items)-i-1] = s.items[i]
-	}
-	s.items = s.items[:0]
-	s.size = 0
-	return items
+	return s.popTop(len(s.items))
 }
 
-// PushAll adds multiple items to the stack.
+// PushAll adds multiple items to the stack (the last one on top), growing
+// its storage at most once.
 func (s *Stack[T]) PushAll(items []T) {
-	s.items = append(s.items, items...)
-	s.size += uint64(len(items))
+	s.PushN(items...)
 }
 
 // Filter removes items from the stack that don't match the predicate.
@@ -578,3 +678,171 @@ func (s *Stack[T]) FindIndices(predicate func(T) bool) []uint64 {
 	}
 	return indices
 }
+
+// Iter returns an iterator over the items of the stack, from the top to the bottom.
+func (s *Stack[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if s.IsEmpty() {
+			return
+		}
+		for i := s.size; i > 0; i-- {
+			if !yield(s.items[i-1]) {
+				return
+			}
+		}
+	}
+}
+
+// Iterator returns an iterator over the items of the stack, from the top to the bottom.
+// The stack must not be modified while iterating.
+func (s *Stack[T]) Iterator() iterator.Iterator[T] {
+	i := s.Size()
+	return iterator.FromFunc(func() (T, bool) {
+		if i == 0 {
+			var zero T
+			return zero, false
+		}
+		i--
+		return s.items[i], true
+	})
+}
+
+// Enumerate returns an iterator over the index and the value of the items of
+// the stack, from the top (index 0) to the bottom.
+func (s *Stack[T]) Enumerate() iter.Seq2[uint64, T] {
+	return func(yield func(uint64, T) bool) {
+		if s.IsEmpty() {
+			return
+		}
+		for i := uint64(0); i < s.size; i++ {
+			if !yield(i, s.items[s.size-i-1]) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the indexes of the items, the ones yielded by
+// Enumerate.
+func (s *Stack[T]) Keys() iter.Seq[uint64] {
+	return gods.Keys(s.Enumerate())
+}
+
+// Values returns an iterator over the items, in the same order as Enumerate
+// (it is Iter, under the name used by the standard library).
+func (s *Stack[T]) Values() iter.Seq[T] {
+	return s.Iter()
+}
+
+// Encode writes the stack to w using the given codec
+func (s *Stack[T]) Encode(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeSlice(w, codec, s.ToSlice())
+}
+
+// Decode replaces the content of the stack with the elements read from r using the given codec
+func (s *Stack[T]) Decode(r io.Reader, codec gods.Codec) error {
+	items, err := gods.DecodeSlice[T](r, codec)
+	if err != nil {
+		return err
+	}
+	s.Clear()
+	// items are encoded from the top, so push them back in reverse order
+	for i := len(items) - 1; i >= 0; i-- {
+		s.Push(items[i])
+	}
+	return nil
+}
+
+// EncodeStream writes the stack to w incrementally (from the top, like
+// Encode), in chunks encoded with the given codec (see gods.EncodeStream),
+// without copying its elements first.
+func (s *Stack[T]) EncodeStream(w io.Writer, codec gods.Codec) error {
+	return gods.EncodeStream(w, codec, s.Iter())
+}
+
+// DecodeStream replaces the content of the stack with the elements of a
+// stream written by EncodeStream, adding them as they are read.
+func (s *Stack[T]) DecodeStream(r io.Reader, codec gods.Codec) error {
+	s.Clear()
+	err := gods.DecodeStream(r, codec, func(item T) error {
+		s.Push(item)
+		return nil
+	})
+	// items are encoded from the top, so the ones pushed so far are upside down
+	slices.Reverse(s.items[:s.size])
+	return err
+}
+
+// MarshalText implements encoding.TextMarshaler: the stack is encoded as a
+// JSON array, in the same order as Encode (see gods.MarshalText).
+func (s *Stack[T]) MarshalText() ([]byte, error) {
+	return gods.MarshalText(s)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *Stack[T]) UnmarshalText(data []byte) error {
+	return gods.UnmarshalText(s, data)
+}
+
+// MarshalJSON implements json.Marshaler, with the same format as MarshalText
+func (s *Stack[T]) MarshalJSON() ([]byte, error) {
+	return gods.MarshalText(s)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *Stack[T]) UnmarshalJSON(data []byte) error {
+	return gods.UnmarshalText(s, data)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (see gods.MarshalBinary)
+func (s *Stack[T]) MarshalBinary() ([]byte, error) {
+	return gods.MarshalBinary(s)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (s *Stack[T]) UnmarshalBinary(data []byte) error {
+	return gods.UnmarshalBinary(s, data)
+}
+
+// FilterCtx is like Filter but it stops as soon as the context is done,
+// returning ctx.Err(). In that case the stack is left unchanged.
+func (s *Stack[T]) FilterCtx(ctx context.Context, predicate func(T) bool) error {
+	var items []T
+	for i := uint64(0); i < s.size; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if predicate(s.items[i]) {
+			items = append(items, s.items[i])
+		}
+	}
+	s.items = items
+	s.size = uint64(len(items))
+	return nil
+}
+
+// MapCtx is like Map but it stops as soon as the context is done, returning ctx.Err().
+func (s *Stack[T]) MapCtx(ctx context.Context, fn func(T) T) (*Stack[T], error) {
+	newStack := New[T]()
+	for i := uint64(0); i < s.size; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		newStack.Push(fn(s.items[i]))
+	}
+	return newStack, nil
+}
+
+// ForEachCtx is like ForEach (from the top to the bottom) but it stops as soon
+// as the context is done, returning ctx.Err().
+func (s *Stack[T]) ForEachCtx(ctx context.Context, fn func(*T) error) error {
+	for i := s.size; i > 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&s.items[i-1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -16,12 +16,20 @@
 package stack_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
 	"strconv"
 	"sync"
 	"testing"
 
+	gods "github.com/pzaino/gods"
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+	iterator "github.com/pzaino/gods/pkg/iterator"
 	stack "github.com/pzaino/gods/pkg/stack"
 )
 
@@ -1285,3 +1293,445 @@ func TestConfinedForFrom(t *testing.T) {
 		t.Errorf("Expected result to be either %v or %v, but got %v", expected1, expected2, result)
 	}
 }
+
+func TestIter(t *testing.T) {
+	s := stack.NewFromSlice([]int{1, 2, 3})
+	var items []int
+	for item := range s.Iter() {
+		items = append(items, item)
+	}
+	if !reflect.DeepEqual(items, []int{3, 2, 1}) {
+		t.Errorf(errExpectedStack, []int{3, 2, 1}, items)
+	}
+
+	// Stop early
+	items = items[:0]
+	for item := range s.Iter() {
+		items = append(items, item)
+		break
+	}
+	if !reflect.DeepEqual(items, []int{3}) {
+		t.Errorf(errExpectedStack, []int{3}, items)
+	}
+
+	for range stack.New[int]().Iter() {
+		t.Error(errStackNotEmpty)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	s := stack.NewFromSlice([]int{1, 2, 3})
+	items := iterator.ToSlice(s.Iterator())
+	if !reflect.DeepEqual(items, []int{3, 2, 1}) {
+		t.Errorf(errExpectedStack, []int{3, 2, 1}, items)
+	}
+	if stack.New[int]().Iterator().Next() {
+		t.Error(errStackNotEmpty)
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	s := stack.NewFromSlice([]int{1, 2, 3})
+	var indexes []uint64
+	var values []int
+	for i, v := range s.Enumerate() {
+		indexes = append(indexes, i)
+		values = append(values, v)
+	}
+	if !slices.Equal(indexes, []uint64{0, 1, 2}) {
+		t.Errorf("Expected %v, got %v", []uint64{0, 1, 2}, indexes)
+	}
+	if !slices.Equal(values, []int{3, 2, 1}) {
+		t.Errorf("Expected %v, got %v", []int{3, 2, 1}, values)
+	}
+	if got := slices.Collect(s.Keys()); !slices.Equal(got, indexes) {
+		t.Errorf("Expected %v, got %v", indexes, got)
+	}
+	if got := slices.Collect(s.Values()); !slices.Equal(got, values) {
+		t.Errorf("Expected %v, got %v", values, got)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	s := stack.NewFromSlice([]int{1, 2, 3})
+	var buf bytes.Buffer
+	if err := s.Encode(&buf, gods.JSONCodec{}); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+
+	decoded := stack.New[int]()
+	decoded.Push(42) // must be replaced
+	if err := decoded.Decode(&buf, gods.JSONCodec{}); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if !decoded.Equal(s) {
+		t.Errorf(errExpectedStack, s.ToSlice(), decoded.ToSlice())
+	}
+
+	if err := decoded.Decode(bytes.NewBufferString("not json"), gods.JSONCodec{}); err == nil {
+		t.Errorf(errYesError)
+	}
+}
+
+func TestCtxVariants(t *testing.T) {
+	s := stack.NewFromSlice([]int{1, 2, 3, 4})
+
+	if err := s.FilterCtx(context.Background(), func(v int) bool { return v%2 == 0 }); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if got := s.ToSlice(); !reflect.DeepEqual(got, []int{4, 2}) {
+		t.Errorf(errExpectedStack, []int{4, 2}, got)
+	}
+
+	mapped, err := s.MapCtx(context.Background(), func(v int) int { return v * 10 })
+	if err != nil || !reflect.DeepEqual(mapped.ToSlice(), []int{40, 20}) {
+		t.Errorf(errExpectedStack, []int{40, 20}, mapped)
+	}
+
+	// a cancellation in the middle of the walk leaves the stack unchanged
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err = s.FilterCtx(ctx, func(v int) bool {
+		calls++
+		cancel()
+		return false
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf(errExpectedResult, context.Canceled, err)
+	}
+	if s.Size() != 2 {
+		t.Errorf(errExpectedResult, 2, s.Size())
+	}
+	if _, err := s.MapCtx(ctx, func(v int) int { return v }); !errors.Is(err, context.Canceled) {
+		t.Errorf(errExpectedResult, context.Canceled, err)
+	}
+	if err := s.ForEachCtx(ctx, func(*int) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf(errExpectedResult, context.Canceled, err)
+	}
+
+	var visited []int
+	_ = s.ForEachCtx(context.Background(), func(v *int) error {
+		visited = append(visited, *v)
+		return nil
+	})
+	if !reflect.DeepEqual(visited, []int{4, 2}) {
+		t.Errorf(errExpectedResult, []int{4, 2}, visited)
+	}
+}
+
+func TestMemoryUsage(t *testing.T) {
+	s := stack.New[string]()
+	empty := s.MemoryUsage()
+	for i := 0; i < 100; i++ {
+		s.Push(strconv.Itoa(i))
+	}
+	items := s.MemoryUsage()
+	if items < empty+100*16 {
+		t.Errorf(errExpectedResult, fmt.Sprintf(">= %d", empty+100*16), items)
+	}
+	// 10 strings of 1 byte and 90 of 2 bytes
+	withBytes := s.MemoryUsage(func(v string) uint64 { return uint64(len(v)) })
+	if withBytes != items+190 {
+		t.Errorf(errExpectedResult, items+190, withBytes)
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := stack.New[int]()
+	for i := 0; i < 100; i++ {
+		s.Push(i)
+	}
+	for i := 0; i < 60; i++ {
+		_, _ = s.Pop()
+	}
+	s.PushN(1, 2, 3)
+	growth := s.Stats().Growth
+	if growth.PeakSize != 100 {
+		t.Errorf(errExpectedResult, 100, growth.PeakSize)
+	}
+	if growth.PeakCapacity < 100 {
+		t.Errorf(errExpectedResult, ">= 100", growth.PeakCapacity)
+	}
+	// the backing array doubles while it is small
+	if growth.Resizes < 7 || growth.Resizes > 10 {
+		t.Errorf(errExpectedResult, "7 to 10 resizes", growth.Resizes)
+	}
+	if growth.Allocated < growth.PeakCapacity {
+		t.Errorf(errExpectedResult, fmt.Sprintf(">= %d", growth.PeakCapacity), growth.Allocated)
+	}
+}
+
+func TestPopWhile(t *testing.T) {
+	s := stack.NewFromSlice([]int{5, 1, 2, 8, 3})
+	got := s.PopWhile(func(v int) bool { return v < 5 || v == 8 })
+	if !reflect.DeepEqual(got, []int{3, 8, 2, 1}) {
+		t.Errorf(errExpectedResult, []int{3, 8, 2, 1}, got)
+	}
+	if s.Size() != 1 {
+		t.Errorf(errExpectedResult, 1, s.Size())
+	}
+	if got := s.PopWhile(func(v int) bool { return v < 5 }); got != nil {
+		t.Errorf(errExpectedResult, nil, got)
+	}
+	s.Clear()
+	if got := s.PopWhile(func(int) bool { return true }); got != nil {
+		t.Errorf(errExpectedResult, nil, got)
+	}
+}
+
+func TestSwapAt(t *testing.T) {
+	s := stack.NewFromSlice([]int{1, 2, 3, 4})
+	if err := s.SwapAt(0, 3); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if err := s.SwapAt(2, 2); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	if got := s.PopAll(); !reflect.DeepEqual(got, []int{1, 3, 2, 4}) {
+		t.Errorf(errExpectedResult, []int{1, 3, 2, 4}, got)
+	}
+	if err := s.SwapAt(0, 1); err == nil || err.Error() != stack.ErrIndexOOR {
+		t.Errorf(errExpectedResult, stack.ErrIndexOOR, err)
+	}
+}
+
+func TestMinStack(t *testing.T) {
+	s := stack.NewMinStack(cmpx.NaturalOrder[int]())
+	if _, err := s.Min(); err == nil || err.Error() != stack.ErrStackIsEmpty {
+		t.Errorf(errExpectedResult, stack.ErrStackIsEmpty, err)
+	}
+	var mins []int
+	for _, v := range []int{5, 3, 7, 3, 1, 8} {
+		s.Push(v)
+	}
+	for !s.IsEmpty() {
+		m, _ := s.Min()
+		mins = append(mins, m)
+		_, _ = s.Pop()
+	}
+	if !reflect.DeepEqual(mins, []int{1, 1, 3, 3, 3, 5}) {
+		t.Errorf(errExpectedResult, []int{1, 1, 3, 3, 3, 5}, mins)
+	}
+
+	maxes := stack.NewMinStack(cmpx.NaturalOrder[int]().Reverse())
+	for _, v := range []int{2, 9, 4} {
+		maxes.Push(v)
+	}
+	if m, _ := maxes.Min(); m != 9 {
+		t.Errorf(errExpectedResult, 9, m)
+	}
+	if top, _ := maxes.Top(); top != 4 || maxes.Size() != 3 {
+		t.Errorf(errExpectedResult, 4, top)
+	}
+	maxes.Clear()
+	if _, err := maxes.Pop(); err == nil {
+		t.Errorf(errYesError)
+	}
+}
+
+func TestMonotonic(t *testing.T) {
+	// next greater element
+	values := []int{2, 1, 2, 4, 3}
+	next := []int{-1, -1, -1, -1, -1}
+	s := stack.Monotonic(func(i, j int) bool { return values[i] > values[j] },
+		func(popped, pushed int) { next[popped] = pushed })
+	for i := range values {
+		s.Push(i)
+	}
+	if !reflect.DeepEqual(next, []int{3, 2, 3, -1, -1}) {
+		t.Errorf(errExpectedResult, []int{3, 2, 3, -1, -1}, next)
+	}
+	if got := s.Items(); !reflect.DeepEqual(got, []int{3, 4}) {
+		t.Errorf(errExpectedResult, []int{3, 4}, got)
+	}
+
+	inc := stack.Monotonic[int](cmpx.NaturalOrder[int](), nil)
+	for _, v := range []int{3, 1, 4, 1, 5} {
+		inc.Push(v)
+	}
+	if top, _ := inc.Top(); top != 5 || inc.Size() != 3 {
+		t.Errorf(errExpectedResult, 5, top)
+	}
+	if v, _ := inc.Pop(); v != 5 {
+		t.Errorf(errExpectedResult, 5, v)
+	}
+	inc.Clear()
+	if _, err := inc.Pop(); err == nil {
+		t.Errorf(errYesError)
+	}
+}
+
+func TestPopOptTopOpt(t *testing.T) {
+	s := stack.New[int]()
+	if s.PopOpt().IsSome() || s.TopOpt().IsSome() {
+		t.Errorf(errStackNotEmpty)
+	}
+	s.Push(1)
+	s.Push(2)
+	if v := s.TopOpt().MustGet(); v != 2 {
+		t.Errorf(errExpectedItemX, 2, v)
+	}
+	if v := s.PopOpt().OrElse(-1); v != 2 || s.Size() != 1 {
+		t.Errorf(errExpectedItemX, 2, v)
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		s.Push(3)
+		s.PopOpt()
+		s.TopOpt()
+	}); allocs != 0 {
+		t.Errorf(errExpectedItemX, 0, allocs)
+	}
+}
+
+// batch is the number of items pushed and popped by the batch benchmarks
+const batch = 1024
+
+// TestChunked checks a Chunked stack against a Stack holding the same items
+func TestChunked(t *testing.T) {
+	s, c := stack.New[int](), stack.NewChunked[int](4)
+	same := func(step string) {
+		t.Helper()
+		if !slices.Equal(c.ToSlice(), s.ToSlice()) || c.String() != s.String() {
+			t.Fatalf("%s: "+errExpectedStack, step, s.ToSlice(), c.ToSlice())
+		}
+		if err := c.Validate(); err != nil {
+			t.Fatalf("%s: "+errNoError, step, err)
+		}
+	}
+	if _, err := c.Pop(); err == nil {
+		t.Errorf(errYesError)
+	}
+	for i := range 10 {
+		s.Push(i)
+		c.Push(i)
+	}
+	s.PushN(10, 11, 12, 13, 14)
+	c.PushN(10, 11, 12, 13, 14)
+	same("push")
+	if c.Chunks() != 4 {
+		t.Errorf(errExpectedResult, 4, c.Chunks())
+	}
+
+	want, _ := s.PopN(6)
+	got, err := c.PopN(6)
+	if err != nil || !slices.Equal(got, want) {
+		t.Errorf(errExpectedResult, want, got)
+	}
+	if _, err := c.PopN(10); err == nil || err.Error() != stack.ErrNotEnoughItems {
+		t.Errorf(errExpectedResult, stack.ErrNotEnoughItems, err)
+	}
+	same("PopN")
+
+	_ = s.SwapAt(0, 7)
+	_ = c.SwapAt(0, 7)
+	_ = s.Swap()
+	_ = c.Swap()
+	s.Reverse()
+	c.Reverse()
+	same("swap and reverse")
+	if want := maps.Collect(s.Enumerate()); !maps.Equal(maps.Collect(c.Enumerate()), want) {
+		t.Errorf(errExpectedResult, want, maps.Collect(c.Enumerate()))
+	}
+	if !slices.Equal(slices.Collect(c.Values()), slices.Collect(s.Values())) || !slices.Equal(slices.Collect(c.Keys()), slices.Collect(s.Keys())) {
+		t.Errorf(errExpectedStack, slices.Collect(s.Values()), slices.Collect(c.Values()))
+	}
+
+	even := func(v int) bool { return v%2 == 0 }
+	wantFirst, _ := s.FindIndex(even)
+	if i, _ := c.FindIndex(even); i != wantFirst {
+		t.Errorf(errExpectedResult, wantFirst, i)
+	}
+	if !slices.Equal(c.FindIndices(even), s.FindIndices(even)) || !slices.Equal(c.FindAll(even), s.FindAll(even)) {
+		t.Errorf(errExpectedResult, s.FindIndices(even), c.FindIndices(even))
+	}
+	wantLast, _ := s.FindLastIndex(even)
+	if i, _ := c.FindLastIndex(even); i != wantLast {
+		t.Errorf(errExpectedResult, wantLast, i)
+	}
+	var visited []int
+	_ = c.ForRange(2, 5, func(v *int) error {
+		visited = append(visited, *v)
+		return nil
+	})
+	if want := s.ToSlice()[2:6]; !slices.Equal(visited, want) {
+		t.Errorf(errExpectedResult, want, visited)
+	}
+	sum := func(a, b int) int { return a + b }
+	wantSum, _ := s.Reduce(sum)
+	if got, _ := c.Reduce(sum); got != wantSum {
+		t.Errorf(errExpectedResult, wantSum, got)
+	}
+
+	s.Filter(even)
+	c.Filter(even)
+	same("filter")
+	if !c.Copy().Equal(c) || c.Map(func(v int) int { return v + 1 }).Equal(c) {
+		t.Errorf(errExpected2Stacks)
+	}
+
+	var buf bytes.Buffer
+	if err := c.EncodeStream(&buf, gods.JSONCodec{}); err != nil {
+		t.Fatalf(errNoError, err)
+	}
+	d := stack.NewChunked[int](2)
+	if err := d.DecodeStream(&buf, gods.JSONCodec{}); err != nil || !d.Equal(c) {
+		t.Errorf(errExpectedStack, c.ToSlice(), d.ToSlice())
+	}
+
+	if want := s.PopAll(); !slices.Equal(c.PopAll(), want) || !c.IsEmpty() || c.Chunks() != 0 {
+		t.Errorf(errStackNotEmpty)
+	}
+	same("PopAll")
+}
+
+func TestChunkedStats(t *testing.T) {
+	c := stack.NewChunked[int](16)
+	for i := 0; i < 100; i++ {
+		c.Push(i)
+	}
+	for i := 0; i < 60; i++ {
+		_, _ = c.Pop()
+	}
+	c.PushN(1, 2, 3)
+	growth := c.Stats().Growth
+	if growth.PeakSize != 100 || growth.PeakCapacity != 112 {
+		t.Errorf(errExpectedResult, 100, growth.PeakSize)
+	}
+	// the first chunk grows from 8 to 16 items, then 6 more chunks are
+	// allocated
+	if growth.Resizes != 8 || growth.Allocated != 8+16+6*16 {
+		t.Errorf(errExpectedResult, 8, growth.Resizes)
+	}
+	// the 43 items left fill 3 chunks, and a fourth one is the spare
+	if c.MemoryUsage() < 4*16*8 {
+		t.Errorf(errExpectedResult, ">= 512", c.MemoryUsage())
+	}
+}
+
+func BenchmarkPushN(b *testing.B) {
+	items := make([]int, batch)
+	b.ReportAllocs()
+	for range b.N {
+		s := stack.New[int]()
+		s.PushN(items...)
+	}
+}
+
+func BenchmarkPopN(b *testing.B) {
+	s := stack.NewFromSlice(make([]int, batch))
+	b.ReportAllocs()
+	for range b.N {
+		items, _ := s.PopN(batch)
+		s.PushAll(items)
+	}
+}
+
+func BenchmarkPopAll(b *testing.B) {
+	s := stack.New[int]()
+	items := make([]int, batch)
+	b.ReportAllocs()
+	for range b.N {
+		s.PushAll(items)
+		_ = s.PopAll()
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stream provides lazy pipelines over the data structures of the
+// library (or any other sequence).
+//
+// A Stream is built from a source, transformed by intermediate operations
+// (Filter, Map, Take, Skip, Distinct, Sorted) and consumed by a terminal
+// operation (ToSlice, Collect, ForEach, Reduce, Count). Nothing is evaluated
+// until the terminal operation runs, and only the elements needed by it are
+// pulled from the source:
+//
+//	evens := stream.Map(stream.From[int](myList).
+//		Filter(func(v int) bool { return v%2 == 0 }),
+//		func(v int) string { return strconv.Itoa(v) }).
+//		Take(10).
+//		ToSlice()
+package stream
+
+import (
+	"iter"
+	"slices"
+	"sync"
+
+	gods "github.com/pzaino/gods"
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+)
+
+// Stream is a lazy sequence of elements.
+type Stream[T any] struct {
+	seq     iter.Seq[T]
+	workers int
+}
+
+// FromSeq creates a stream from a sequence.
+func FromSeq[T any](seq iter.Seq[T]) *Stream[T] {
+	return &Stream[T]{seq: seq, workers: 1}
+}
+
+// From creates a stream from any collection of the library (in its iteration order).
+func From[T any](c gods.Collection[T]) *Stream[T] {
+	return FromSeq(c.Iter())
+}
+
+// FromSlice creates a stream from a slice.
+func FromSlice[T any](items []T) *Stream[T] {
+	return FromSeq(slices.Values(items))
+}
+
+// FromChannel creates a stream that receives its elements from a channel,
+// until the channel is closed. A stream built from a channel can be consumed
+// only once.
+func FromChannel[T any](ch <-chan T) *Stream[T] {
+	return FromSeq(func(yield func(T) bool) {
+		for v := range ch {
+			if !yield(v) {
+				return
+			}
+		}
+	})
+}
+
+// derive returns a new stream with the given sequence and the same execution mode
+func derive[T any, U any](s *Stream[T], seq iter.Seq[U]) *Stream[U] {
+	return &Stream[U]{seq: seq, workers: s.workers}
+}
+
+// Parallel enables the parallel execution mode: the functions passed to Map,
+// Filter and ForEach are run by up to workers goroutines at the same time.
+// Map and Filter still produce their results in the input order. The
+// functions must be safe to call concurrently. A value lower than 2 disables
+// the parallel mode.
+func (s *Stream[T]) Parallel(workers int) *Stream[T] {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Stream[T]{seq: s.seq, workers: workers}
+}
+
+// Seq returns the stream as an iter.Seq.
+func (s *Stream[T]) Seq() iter.Seq[T] {
+	return s.seq
+}
+
+// Filter returns a stream with the elements that match the predicate.
+func (s *Stream[T]) Filter(predicate func(T) bool) *Stream[T] {
+	if s.workers > 1 {
+		type checked struct {
+			value T
+			keep  bool
+		}
+		results := parallelMap(s.seq, s.workers, func(v T) checked {
+			return checked{value: v, keep: predicate(v)}
+		})
+		return derive(s, func(yield func(T) bool) {
+			for r := range results {
+				if r.keep && !yield(r.value) {
+					return
+				}
+			}
+		})
+	}
+	return derive(s, func(yield func(T) bool) {
+		for v := range s.seq {
+			if predicate(v) && !yield(v) {
+				return
+			}
+		}
+	})
+}
+
+// Map returns a stream with the results of applying fn to the elements of s.
+// It is a function, rather than a method, because it changes the element type.
+func Map[T any, U any](s *Stream[T], fn func(T) U) *Stream[U] {
+	if s.workers > 1 {
+		return derive(s, parallelMap(s.seq, s.workers, fn))
+	}
+	return derive(s, func(yield func(U) bool) {
+		for v := range s.seq {
+			if !yield(fn(v)) {
+				return
+			}
+		}
+	})
+}
+
+// Take returns a stream with (at most) the first n elements of s.
+func (s *Stream[T]) Take(n uint64) *Stream[T] {
+	return derive(s, func(yield func(T) bool) {
+		if n == 0 {
+			return
+		}
+		var taken uint64
+		for v := range s.seq {
+			if !yield(v) {
+				return
+			}
+			taken++
+			if taken >= n {
+				return
+			}
+		}
+	})
+}
+
+// Skip returns a stream without the first n elements of s.
+func (s *Stream[T]) Skip(n uint64) *Stream[T] {
+	return derive(s, func(yield func(T) bool) {
+		var skipped uint64
+		for v := range s.seq {
+			if skipped < n {
+				skipped++
+				continue
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	})
+}
+
+// Distinct returns a stream without duplicated elements (only the first
+// occurrence of each element is kept).
+func Distinct[T comparable](s *Stream[T]) *Stream[T] {
+	return derive(s, func(yield func(T) bool) {
+		seen := make(map[T]struct{})
+		for v := range s.seq {
+			if _, ok := seen[v]; ok {
+				continue
+			}
+			seen[v] = struct{}{}
+			if !yield(v) {
+				return
+			}
+		}
+	})
+}
+
+// Sorted returns a stream with the elements of s sorted by less. The sort is
+// stable. Note that Sorted needs to read the whole source before producing
+// its first element.
+func (s *Stream[T]) Sorted(less cmpx.Less[T]) *Stream[T] {
+	return derive(s, func(yield func(T) bool) {
+		items := slices.Collect(s.seq)
+		slices.SortStableFunc(items, less.Compare)
+		for _, v := range items {
+			if !yield(v) {
+				return
+			}
+		}
+	})
+}
+
+// ToSlice runs the pipeline and returns its elements as a slice.
+func (s *Stream[T]) ToSlice() []T {
+	return slices.Collect(s.seq)
+}
+
+// Collect runs the pipeline and adds every element to a container using the
+// given add function (for example a Push, Enqueue or Append method value).
+func (s *Stream[T]) Collect(add func(T)) {
+	for v := range s.seq {
+		add(v)
+	}
+}
+
+// ForEach runs the pipeline and calls fn for every element. In parallel mode
+// fn is called concurrently and in no particular order.
+func (s *Stream[T]) ForEach(fn func(T)) {
+	if s.workers <= 1 {
+		for v := range s.seq {
+			fn(v)
+		}
+		return
+	}
+
+	items := make(chan T)
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range items {
+				fn(v)
+			}
+		}()
+	}
+	for v := range s.seq {
+		items <- v
+	}
+	close(items)
+	wg.Wait()
+}
+
+// Reduce runs the pipeline and combines its elements, starting from initial.
+func Reduce[T any, A any](s *Stream[T], initial A, fn func(A, T) A) A {
+	result := initial
+	for v := range s.seq {
+		result = fn(result, v)
+	}
+	return result
+}
+
+// Count runs the pipeline and returns the number of elements.
+func (s *Stream[T]) Count() uint64 {
+	var n uint64
+	for range s.seq {
+		n++
+	}
+	return n
+}
+
+// AnyMatch runs the pipeline until an element matches the predicate.
+func (s *Stream[T]) AnyMatch(predicate func(T) bool) bool {
+	for v := range s.seq {
+		if predicate(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// parallelMap applies fn to the elements of seq using up to workers
+// goroutines. Elements are processed in batches of workers elements and the
+// results are produced in the input order.
+func parallelMap[T any, U any](seq iter.Seq[T], workers int, fn func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		batch := make([]T, 0, workers)
+		results := make([]U, workers)
+		flush := func() bool {
+			var wg sync.WaitGroup
+			for i := range batch {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					results[i] = fn(batch[i])
+				}(i)
+			}
+			wg.Wait()
+			for i := range batch {
+				if !yield(results[i]) {
+					return false
+				}
+			}
+			batch = batch[:0]
+			return true
+		}
+		for v := range seq {
+			batch = append(batch, v)
+			if len(batch) == workers && !flush() {
+				return
+			}
+		}
+		if len(batch) > 0 {
+			flush()
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stream provides lazy pipelines over the data structures.
+package stream_test
+
+import (
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+	linkList "github.com/pzaino/gods/pkg/linkList"
+	queue "github.com/pzaino/gods/pkg/queue"
+	stream "github.com/pzaino/gods/pkg/stream"
+)
+
+const (
+	errExpectedX = "Expected %v, but got %v"
+)
+
+func isEven(v int) bool { return v%2 == 0 }
+
+func TestPipeline(t *testing.T) {
+	l := linkList.NewFromSlice([]int{1, 2, 3, 4, 5, 6, 7, 8})
+	got := stream.Map(stream.From[int](l).Filter(isEven), strconv.Itoa).
+		Skip(1).
+		Take(2).
+		ToSlice()
+	if !slices.Equal(got, []string{"4", "6"}) {
+		t.Errorf(errExpectedX, []string{"4", "6"}, got)
+	}
+}
+
+func TestLaziness(t *testing.T) {
+	var calls int
+	s := stream.Map(stream.FromSlice([]int{1, 2, 3, 4, 5}), func(v int) int {
+		calls++
+		return v
+	})
+	if calls != 0 {
+		t.Errorf(errExpectedX, 0, calls)
+	}
+	s.Take(2).ToSlice()
+	if calls != 2 {
+		t.Errorf(errExpectedX, 2, calls)
+	}
+}
+
+func TestDistinctAndSorted(t *testing.T) {
+	s := stream.Distinct(stream.FromSlice([]int{3, 1, 3, 2, 1}))
+	if got := s.ToSlice(); !slices.Equal(got, []int{3, 1, 2}) {
+		t.Errorf(errExpectedX, []int{3, 1, 2}, got)
+	}
+	sorted := s.Sorted(cmpx.NaturalOrder[int]().Reverse()).ToSlice()
+	if !slices.Equal(sorted, []int{3, 2, 1}) {
+		t.Errorf(errExpectedX, []int{3, 2, 1}, sorted)
+	}
+}
+
+func TestTerminals(t *testing.T) {
+	s := stream.FromSlice([]int{1, 2, 3, 4})
+	if n := s.Count(); n != 4 {
+		t.Errorf(errExpectedX, 4, n)
+	}
+	if sum := stream.Reduce(s, 0, func(acc, v int) int { return acc + v }); sum != 10 {
+		t.Errorf(errExpectedX, 10, sum)
+	}
+	if !s.AnyMatch(isEven) || s.Filter(isEven).AnyMatch(func(v int) bool { return v == 3 }) {
+		t.Errorf("Unexpected AnyMatch result")
+	}
+
+	q := queue.New[int]()
+	s.Filter(isEven).Collect(q.Enqueue)
+	if got := q.ToSlice(); !slices.Equal(got, []int{2, 4}) {
+		t.Errorf(errExpectedX, []int{2, 4}, got)
+	}
+
+	var sum int
+	s.ForEach(func(v int) { sum += v })
+	if sum != 10 {
+		t.Errorf(errExpectedX, 10, sum)
+	}
+}
+
+func TestFromChannel(t *testing.T) {
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+	if got := stream.FromChannel(ch).ToSlice(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf(errExpectedX, []int{1, 2, 3}, got)
+	}
+}
+
+func TestParallel(t *testing.T) {
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i
+	}
+	s := stream.FromSlice(items).Parallel(8)
+
+	// Map and Filter keep the input order
+	got := stream.Map(s.Filter(isEven), func(v int) int { return v * 2 }).ToSlice()
+	if len(got) != 500 || got[0] != 0 || got[1] != 4 || got[499] != 1996 {
+		t.Errorf("Unexpected parallel result %v", got[:3])
+	}
+
+	var sum atomic.Int64
+	s.ForEach(func(v int) { sum.Add(int64(v)) })
+	if sum.Load() != 499500 {
+		t.Errorf(errExpectedX, 499500, sum.Load())
+	}
+
+	// early termination must not leak or panic
+	if got := stream.Map(s, strconv.Itoa).Take(3).ToSlice(); !slices.Equal(got, []string{"0", "1", "2"}) {
+		t.Errorf(errExpectedX, []string{"0", "1", "2"}, got)
+	}
+}
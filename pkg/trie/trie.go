@@ -0,0 +1,371 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package trie provides a prefix tree of words with a value and a weight,
+// usable as an autocomplete and spell-suggestion component: besides the
+// exact and prefix lookups, Suggest returns the heaviest completions of a
+// prefix and SearchWithin the words within an edit distance of a word.
+//
+// The words are split in runes, and the children of every node are sorted,
+// so the words are iterated in lexicographic order. Every node also records
+// the highest weight below it, which lets Suggest visit only the branches
+// that can hold one of the best completions.
+package trie
+
+import (
+	"cmp"
+	"container/heap"
+	"iter"
+	"math"
+	"slices"
+
+	gods "github.com/pzaino/gods"
+)
+
+// Match is a word found by Suggest or SearchWithin.
+type Match[V any] struct {
+	Word   string
+	Value  V
+	Weight int64
+	// Distance is the edit distance from the searched word (SearchWithin)
+	Distance int
+}
+
+// node is a node of the trie, reached by the rune label
+type node[V any] struct {
+	label    rune
+	children []*node[V] // sorted by label
+	terminal bool       // a word ends here
+	value    V
+	weight   int64
+	best     int64 // the highest weight of the words in the subtree
+}
+
+// Trie is a prefix tree. It is not concurrency-safe.
+type Trie[V any] struct {
+	root *node[V]
+	size int
+}
+
+// New creates a new empty Trie.
+func New[V any]() *Trie[V] {
+	return &Trie[V]{root: &node[V]{best: math.MinInt64}}
+}
+
+// child returns the child of n with label r (nil if there's none) and the
+// position where it is or would be
+func (n *node[V]) child(r rune) (*node[V], int) {
+	i, found := slices.BinarySearchFunc(n.children, r, func(c *node[V], r rune) int {
+		return cmp.Compare(c.label, r)
+	})
+	if found {
+		return n.children[i], i
+	}
+	return nil, i
+}
+
+// update recomputes the highest weight of the subtree of n
+func (n *node[V]) update() {
+	n.best = math.MinInt64
+	if n.terminal {
+		n.best = n.weight
+	}
+	for _, c := range n.children {
+		n.best = max(n.best, c.best)
+	}
+}
+
+// find returns the node reached by s (nil if there's none)
+func (t *Trie[V]) find(s string) *node[V] {
+	n := t.root
+	for _, r := range s {
+		if n, _ = n.child(r); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// Put sets the value of word, adding it with weight 0 if it's new (an
+// existing word keeps its weight).
+func (t *Trie[V]) Put(word string, value V) {
+	t.put(word, value, 0, false)
+}
+
+// PutWeighted sets the value and the weight of word. Suggest returns the
+// completions with the highest weight first.
+func (t *Trie[V]) PutWeighted(word string, value V, weight int64) {
+	t.put(word, value, weight, true)
+}
+
+func (t *Trie[V]) put(word string, value V, weight int64, setWeight bool) {
+	path := []*node[V]{t.root}
+	n := t.root
+	for _, r := range word {
+		c, i := n.child(r)
+		if c == nil {
+			c = &node[V]{label: r, best: math.MinInt64}
+			n.children = slices.Insert(n.children, i, c)
+		}
+		n = c
+		path = append(path, n)
+	}
+	if !n.terminal {
+		n.terminal = true
+		n.weight = 0
+		t.size++
+	}
+	n.value = value
+	if setWeight {
+		n.weight = weight
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		path[i].update()
+	}
+}
+
+// Get returns the value of word (and whether it's in the trie).
+func (t *Trie[V]) Get(word string) (V, bool) {
+	if n := t.find(word); n != nil && n.terminal {
+		return n.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Weight returns the weight of word (and whether it's in the trie).
+func (t *Trie[V]) Weight(word string) (int64, bool) {
+	if n := t.find(word); n != nil && n.terminal {
+		return n.weight, true
+	}
+	return 0, false
+}
+
+// Contains returns true if word is in the trie.
+func (t *Trie[V]) Contains(word string) bool {
+	n := t.find(word)
+	return n != nil && n.terminal
+}
+
+// HasPrefix returns true if a word of the trie starts with prefix.
+func (t *Trie[V]) HasPrefix(prefix string) bool {
+	n := t.find(prefix)
+	return n != nil && (n.terminal || len(n.children) > 0)
+}
+
+// Delete removes word and returns true if it was in the trie.
+func (t *Trie[V]) Delete(word string) bool {
+	path := []*node[V]{t.root}
+	for _, r := range word {
+		c, _ := path[len(path)-1].child(r)
+		if c == nil {
+			return false
+		}
+		path = append(path, c)
+	}
+	n := path[len(path)-1]
+	if !n.terminal {
+		return false
+	}
+	var zero V
+	n.terminal, n.value, n.weight = false, zero, 0
+	t.size--
+	for i := len(path) - 1; i >= 0; i-- {
+		n := path[i]
+		if i > 0 && !n.terminal && len(n.children) == 0 {
+			// prune the nodes left without words
+			parent := path[i-1]
+			_, j := parent.child(n.label)
+			parent.children = slices.Delete(parent.children, j, j+1)
+		}
+		n.update()
+	}
+	return true
+}
+
+// Size returns the number of words.
+func (t *Trie[V]) Size() int {
+	return t.size
+}
+
+// IsEmpty returns true if the trie has no words.
+func (t *Trie[V]) IsEmpty() bool {
+	return t.size == 0
+}
+
+// Clear removes all the words.
+func (t *Trie[V]) Clear() {
+	t.root = &node[V]{best: math.MinInt64}
+	t.size = 0
+}
+
+// WithPrefix returns an iterator over the words starting with prefix and
+// their value, in lexicographic order. The trie must not be modified while
+// iterating.
+func (t *Trie[V]) WithPrefix(prefix string) iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		if n := t.find(prefix); n != nil {
+			walk(n, []rune(prefix), yield)
+		}
+	}
+}
+
+// All returns an iterator over all the words and their value, in
+// lexicographic order.
+func (t *Trie[V]) All() iter.Seq2[string, V] {
+	return t.WithPrefix("")
+}
+
+// Keys returns an iterator over the words (in the order of All).
+func (t *Trie[V]) Keys() iter.Seq[string] {
+	return gods.Keys(t.All())
+}
+
+// Values returns an iterator over the values (in the order of All).
+func (t *Trie[V]) Values() iter.Seq[V] {
+	return gods.Values(t.All())
+}
+
+// walk yields the words of the subtree of n, whose path is word
+func walk[V any](n *node[V], word []rune, yield func(string, V) bool) bool {
+	if n.terminal && !yield(string(word), n.value) {
+		return false
+	}
+	for _, c := range n.children {
+		if !walk(c, append(word, c.label), yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// candidate is a word, or a subtree that may hold words, waiting in the
+// best-first search of Suggest
+type candidate[V any] struct {
+	node   *node[V]
+	word   string
+	weight int64 // the weight of the word, or the best one of the subtree
+	final  bool  // a word rather than a subtree
+}
+
+// candidates is a max-heap of candidates: highest weight first, then
+// lowest word, so the words come out in the order Suggest returns them
+type candidates[V any] []candidate[V]
+
+func (h candidates[V]) Len() int { return len(h) }
+
+func (h candidates[V]) Less(i, j int) bool {
+	if h[i].weight != h[j].weight {
+		return h[i].weight > h[j].weight
+	}
+	if h[i].word != h[j].word {
+		// the words of a subtree are not lower than its path
+		return h[i].word < h[j].word
+	}
+	return h[i].final && !h[j].final
+}
+
+func (h candidates[V]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *candidates[V]) Push(x any) { *h = append(*h, x.(candidate[V])) }
+
+func (h *candidates[V]) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// Suggest returns at most n words starting with prefix, with the highest
+// weight first (then in lexicographic order). It only visits the branches
+// that can hold one of them.
+func (t *Trie[V]) Suggest(prefix string, n int) []Match[V] {
+	start := t.find(prefix)
+	if n <= 0 || start == nil || start.best == math.MinInt64 {
+		return nil
+	}
+	var matches []Match[V]
+	h := &candidates[V]{{node: start, word: prefix, weight: start.best}}
+	for h.Len() > 0 && len(matches) < n {
+		c := heap.Pop(h).(candidate[V])
+		if c.final {
+			matches = append(matches, Match[V]{Word: c.word, Value: c.node.value, Weight: c.weight})
+			continue
+		}
+		if c.node.terminal {
+			heap.Push(h, candidate[V]{node: c.node, word: c.word, weight: c.node.weight, final: true})
+		}
+		for _, child := range c.node.children {
+			heap.Push(h, candidate[V]{node: child, word: c.word + string(child.label), weight: child.best})
+		}
+	}
+	return matches
+}
+
+// SearchWithin returns the words within maxDistance edits (insertions,
+// deletions and substitutions of runes) from word, closest first, then
+// heaviest, then in lexicographic order.
+//
+// It runs a Levenshtein automaton over the trie: every node extends the row
+// of edit distances of its parent by one rune, and the branches whose row
+// has no distance within maxDistance are skipped.
+func (t *Trie[V]) SearchWithin(word string, maxDistance int) []Match[V] {
+	if maxDistance < 0 {
+		return nil
+	}
+	target := []rune(word)
+	row := make([]int, len(target)+1)
+	for i := range row {
+		row[i] = i
+	}
+
+	var matches []Match[V]
+	if t.root.terminal && row[len(target)] <= maxDistance {
+		matches = append(matches, Match[V]{Value: t.root.value, Weight: t.root.weight, Distance: row[len(target)]})
+	}
+	var path []rune
+	var search func(n *node[V], prev []int)
+	search = func(n *node[V], prev []int) {
+		path = append(path, n.label)
+		defer func() { path = path[:len(path)-1] }()
+
+		row := make([]int, len(prev))
+		row[0] = prev[0] + 1
+		lowest := row[0]
+		for i := 1; i < len(row); i++ {
+			cost := 1
+			if target[i-1] == n.label {
+				cost = 0
+			}
+			row[i] = min(row[i-1]+1, prev[i]+1, prev[i-1]+cost)
+			lowest = min(lowest, row[i])
+		}
+		if d := row[len(row)-1]; n.terminal && d <= maxDistance {
+			matches = append(matches, Match[V]{Word: string(path), Value: n.value, Weight: n.weight, Distance: d})
+		}
+		if lowest <= maxDistance {
+			for _, c := range n.children {
+				search(c, row)
+			}
+		}
+	}
+	for _, c := range t.root.children {
+		search(c, row)
+	}
+
+	slices.SortFunc(matches, func(a, b Match[V]) int {
+		return cmp.Or(cmp.Compare(a.Distance, b.Distance), cmp.Compare(b.Weight, a.Weight), cmp.Compare(a.Word, b.Word))
+	})
+	return matches
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie_test
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	trie "github.com/pzaino/gods/pkg/trie"
+)
+
+const (
+	errExpectedX = "expected %v, got %v"
+)
+
+func words[V any](matches []trie.Match[V]) []string {
+	var out []string
+	for _, m := range matches {
+		out = append(out, m.Word)
+	}
+	return out
+}
+
+func TestBasicOperations(t *testing.T) {
+	tr := trie.New[int]()
+	for i, w := range []string{"car", "cart", "care", "cat", "dog", "", "über"} {
+		tr.Put(w, i)
+	}
+	if tr.Size() != 7 || tr.IsEmpty() {
+		t.Errorf(errExpectedX, 7, tr.Size())
+	}
+	if v, ok := tr.Get("cart"); !ok || v != 1 {
+		t.Errorf(errExpectedX, 1, v)
+	}
+	if tr.Contains("ca") || !tr.HasPrefix("ca") || tr.HasPrefix("cb") || !tr.Contains("") {
+		t.Errorf(errExpectedX, "prefix ca only", tr.Contains("ca"))
+	}
+
+	var got []string
+	for w := range tr.WithPrefix("car") {
+		got = append(got, w)
+	}
+	if want := []string{"car", "care", "cart"}; !slices.Equal(got, want) {
+		t.Errorf(errExpectedX, want, got)
+	}
+
+	if !tr.Delete("car") || tr.Delete("car") || tr.Delete("ca") {
+		t.Errorf(errExpectedX, "car deleted once", tr.Contains("car"))
+	}
+	if !tr.Delete("über") || tr.HasPrefix("ü") {
+		t.Errorf(errExpectedX, "über pruned", tr.HasPrefix("ü"))
+	}
+	got = got[:0]
+	for w := range tr.All() {
+		got = append(got, w)
+	}
+	if want := []string{"", "care", "cart", "cat", "dog"}; !slices.Equal(got, want) {
+		t.Errorf(errExpectedX, want, got)
+	}
+	if keys := slices.Collect(tr.Keys()); !slices.Equal(keys, got) {
+		t.Errorf(errExpectedX, got, keys)
+	}
+	values := slices.Collect(tr.Values())
+	for i, w := range got {
+		if v, _ := tr.Get(w); values[i] != v {
+			t.Errorf(errExpectedX, v, values[i])
+		}
+	}
+	tr.Clear()
+	if !tr.IsEmpty() || tr.HasPrefix("") {
+		t.Errorf(errExpectedX, 0, tr.Size())
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tr := trie.New[string]()
+	tr.PutWeighted("go", "", 50)
+	tr.PutWeighted("golang", "", 90)
+	tr.PutWeighted("gopher", "", 70)
+	tr.PutWeighted("google", "", 90)
+	tr.PutWeighted("gone", "", 10)
+	tr.PutWeighted("rust", "", 100)
+	tr.Put("gopher", "mascot") // keeps its weight
+
+	if got, want := words(tr.Suggest("go", 3)), []string{"golang", "google", "gopher"}; !slices.Equal(got, want) {
+		t.Errorf(errExpectedX, want, got)
+	}
+	if got := tr.Suggest("gop", 5); len(got) != 1 || got[0].Value != "mascot" || got[0].Weight != 70 {
+		t.Errorf(errExpectedX, "gopher", got)
+	}
+	if got := tr.Suggest("x", 5); got != nil {
+		t.Errorf(errExpectedX, nil, got)
+	}
+
+	// the weights stay consistent after a deletion
+	tr.Delete("golang")
+	tr.Delete("google")
+	if got, want := words(tr.Suggest("", 2)), []string{"rust", "gopher"}; !slices.Equal(got, want) {
+		t.Errorf(errExpectedX, want, got)
+	}
+}
+
+func TestSuggestMatchesSort(t *testing.T) {
+	tr := trie.New[int]()
+	all := map[string]int64{}
+	for range 500 {
+		w := randomWord(6)
+		weight := rand.Int63n(20)
+		tr.PutWeighted(w, 0, weight)
+		all[w] = weight
+	}
+	for _, prefix := range []string{"", "a", "ab", "c"} {
+		var want []string
+		for w := range all {
+			if strings.HasPrefix(w, prefix) {
+				want = append(want, w)
+			}
+		}
+		slices.SortFunc(want, func(a, b string) int {
+			if all[a] != all[b] {
+				return int(all[b] - all[a])
+			}
+			return strings.Compare(a, b)
+		})
+		want = want[:min(len(want), 10)]
+		if got := words(tr.Suggest(prefix, 10)); !slices.Equal(got, want) {
+			t.Errorf("prefix %q: "+errExpectedX, prefix, want, got)
+		}
+	}
+}
+
+func TestSearchWithin(t *testing.T) {
+	tr := trie.New[int]()
+	for _, w := range []string{"kitten", "sitting", "mitten", "bitten", "kitchen", "knitting"} {
+		tr.Put(w, 0)
+	}
+	tr.PutWeighted("sitten", 0, 5)
+
+	got := tr.SearchWithin("kitten", 1)
+	if want := []string{"kitten", "sitten", "bitten", "mitten"}; !slices.Equal(words(got), want) {
+		t.Errorf(errExpectedX, want, words(got))
+	}
+	if got[0].Distance != 0 || got[1].Distance != 1 {
+		t.Errorf(errExpectedX, []int{0, 1}, []int{got[0].Distance, got[1].Distance})
+	}
+	if got := words(tr.SearchWithin("kitten", 2)); !slices.Contains(got, "kitchen") || slices.Contains(got, "sitting") {
+		t.Errorf(errExpectedX, "kitchen but not sitting", got)
+	}
+}
+
+func TestSearchWithinMatchesBruteForce(t *testing.T) {
+	tr := trie.New[int]()
+	var all []string
+	for range 300 {
+		w := randomWord(5)
+		tr.Put(w, 0)
+		all = append(all, w)
+	}
+	for range 20 {
+		query := randomWord(5)
+		var want []string
+		for _, w := range all {
+			if levenshtein(query, w) <= 2 && !slices.Contains(want, w) {
+				want = append(want, w)
+			}
+		}
+		got := words(tr.SearchWithin(query, 2))
+		slices.Sort(want)
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("query %q: "+errExpectedX, query, want, got)
+		}
+	}
+}
+
+func randomWord(maxLen int) string {
+	b := make([]byte, 1+rand.Intn(maxLen))
+	for i := range b {
+		b[i] = "abcd"[rand.Intn(4)]
+	}
+	return string(b)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		row := make([]int, len(b)+1)
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			row[j] = min(row[j-1]+1, prev[j]+1, prev[j-1]+cost)
+		}
+		prev = row
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tuple provides the Pair and Triple types, to store multi-value
+// entries in the containers without defining a struct for each of them.
+//
+// A Pair (or Triple) of comparable types is comparable, so it can be used
+// with every container of the library. The tuples are encoded in JSON as
+// arrays ([first, second]), and the Less and Compare functions order them
+// lexicographically.
+package tuple
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+
+	cmpx "github.com/pzaino/gods/pkg/cmpx"
+)
+
+const (
+	ErrWrongLength = "wrong number of JSON array elements"
+)
+
+// Pair is a couple of values.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// NewPair returns the pair (a, b)
+func NewPair[A, B any](a A, b B) Pair[A, B] {
+	return Pair[A, B]{First: a, Second: b}
+}
+
+// Values returns the values of the pair
+func (p Pair[A, B]) Values() (A, B) {
+	return p.First, p.Second
+}
+
+// Swap returns the pair with its values swapped
+func (p Pair[A, B]) Swap() Pair[B, A] {
+	return Pair[B, A]{First: p.Second, Second: p.First}
+}
+
+// String returns the pair as "(first, second)"
+func (p Pair[A, B]) String() string {
+	return fmt.Sprintf("(%v, %v)", p.First, p.Second)
+}
+
+// MarshalJSON implements json.Marshaler: the pair is encoded as a two
+// elements array
+func (p Pair[A, B]) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{p.First, p.Second})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (p *Pair[A, B]) UnmarshalJSON(data []byte) error {
+	return unmarshalArray(data, &p.First, &p.Second)
+}
+
+// PairLess returns a Less ordering the pairs by their first value, then by
+// the second one.
+func PairLess[A, B cmp.Ordered]() cmpx.Less[Pair[A, B]] {
+	return PairLessWith(cmp.Less[A], cmp.Less[B])
+}
+
+// PairLessWith returns a Less ordering the pairs by their first value with
+// lessA, then by the second one with lessB.
+func PairLessWith[A, B any](lessA cmpx.Less[A], lessB cmpx.Less[B]) cmpx.Less[Pair[A, B]] {
+	return cmpx.Chain(
+		cmpx.ByKeyWith(func(p Pair[A, B]) A { return p.First }, lessA),
+		cmpx.ByKeyWith(func(p Pair[A, B]) B { return p.Second }, lessB),
+	)
+}
+
+// ComparePairs compares two pairs lexicographically, for slices.SortFunc
+// and the like.
+func ComparePairs[A, B cmp.Ordered](x, y Pair[A, B]) int {
+	if c := cmp.Compare(x.First, y.First); c != 0 {
+		return c
+	}
+	return cmp.Compare(x.Second, y.Second)
+}
+
+// Triple is a group of three values.
+type Triple[A, B, C any] struct {
+	First  A
+	Second B
+	Third  C
+}
+
+// NewTriple returns the triple (a, b, c)
+func NewTriple[A, B, C any](a A, b B, c C) Triple[A, B, C] {
+	return Triple[A, B, C]{First: a, Second: b, Third: c}
+}
+
+// Values returns the values of the triple
+func (t Triple[A, B, C]) Values() (A, B, C) {
+	return t.First, t.Second, t.Third
+}
+
+// String returns the triple as "(first, second, third)"
+func (t Triple[A, B, C]) String() string {
+	return fmt.Sprintf("(%v, %v, %v)", t.First, t.Second, t.Third)
+}
+
+// MarshalJSON implements json.Marshaler: the triple is encoded as a three
+// elements array
+func (t Triple[A, B, C]) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{t.First, t.Second, t.Third})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *Triple[A, B, C]) UnmarshalJSON(data []byte) error {
+	return unmarshalArray(data, &t.First, &t.Second, &t.Third)
+}
+
+// TripleLess returns a Less ordering the triples by their first value, then
+// by the second one and then by the third one.
+func TripleLess[A, B, C cmp.Ordered]() cmpx.Less[Triple[A, B, C]] {
+	return TripleLessWith(cmp.Less[A], cmp.Less[B], cmp.Less[C])
+}
+
+// TripleLessWith returns a Less ordering the triples lexicographically,
+// comparing their values with lessA, lessB and lessC.
+func TripleLessWith[A, B, C any](lessA cmpx.Less[A], lessB cmpx.Less[B], lessC cmpx.Less[C]) cmpx.Less[Triple[A, B, C]] {
+	return cmpx.Chain(
+		cmpx.ByKeyWith(func(t Triple[A, B, C]) A { return t.First }, lessA),
+		cmpx.ByKeyWith(func(t Triple[A, B, C]) B { return t.Second }, lessB),
+		cmpx.ByKeyWith(func(t Triple[A, B, C]) C { return t.Third }, lessC),
+	)
+}
+
+// CompareTriples compares two triples lexicographically, for
+// slices.SortFunc and the like.
+func CompareTriples[A, B, C cmp.Ordered](x, y Triple[A, B, C]) int {
+	if c := cmp.Compare(x.First, y.First); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(x.Second, y.Second); c != 0 {
+		return c
+	}
+	return cmp.Compare(x.Third, y.Third)
+}
+
+// unmarshalArray decodes a JSON array into dst, one element each
+func unmarshalArray(data []byte, dst ...any) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != len(dst) {
+		return fmt.Errorf("%s: %d instead of %d", ErrWrongLength, len(raw), len(dst))
+	}
+	for i, r := range raw {
+		if err := json.Unmarshal(r, dst[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tuple_test
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	linkList "github.com/pzaino/gods/pkg/linkList"
+	tuple "github.com/pzaino/gods/pkg/tuple"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+func TestPair(t *testing.T) {
+	p := tuple.NewPair("a", 1)
+	if a, b := p.Values(); a != "a" || b != 1 {
+		t.Errorf(errExpectedX, p, tuple.NewPair(a, b))
+	}
+	if s := p.Swap(); s != tuple.NewPair(1, "a") {
+		t.Errorf(errExpectedX, tuple.NewPair(1, "a"), s)
+	}
+	if p.String() != "(a, 1)" {
+		t.Errorf(errExpectedX, "(a, 1)", p.String())
+	}
+
+	// pairs of comparable types can be stored in the containers
+	l := linkList.New[tuple.Pair[string, int]]()
+	l.Append(p)
+	if !l.Contains(tuple.NewPair("a", 1)) {
+		t.Errorf("expected the list to contain %v", p)
+	}
+
+	pairs := []tuple.Pair[int, string]{{2, "b"}, {1, "z"}, {2, "a"}}
+	want := []tuple.Pair[int, string]{{1, "z"}, {2, "a"}, {2, "b"}}
+	slices.SortFunc(pairs, tuple.ComparePairs)
+	if !slices.Equal(pairs, want) {
+		t.Errorf(errExpectedX, want, pairs)
+	}
+	less := tuple.PairLess[int, string]()
+	if !less(want[1], want[2]) || less(want[2], want[1]) || less(want[1], want[1]) {
+		t.Errorf("expected PairLess to order the pairs lexicographically")
+	}
+}
+
+func TestTriple(t *testing.T) {
+	triples := []tuple.Triple[int, int, string]{{1, 2, "b"}, {1, 2, "a"}, {0, 9, "z"}}
+	want := []tuple.Triple[int, int, string]{{0, 9, "z"}, {1, 2, "a"}, {1, 2, "b"}}
+	slices.SortFunc(triples, tuple.CompareTriples)
+	if !slices.Equal(triples, want) {
+		t.Errorf(errExpectedX, want, triples)
+	}
+	byLess := slices.Clone(want)
+	slices.Reverse(byLess)
+	less := tuple.TripleLess[int, int, string]()
+	slices.SortFunc(byLess, func(a, b tuple.Triple[int, int, string]) int {
+		if less(a, b) {
+			return -1
+		}
+		if less(b, a) {
+			return 1
+		}
+		return 0
+	})
+	if !slices.Equal(byLess, want) {
+		t.Errorf(errExpectedX, want, byLess)
+	}
+	if a, b, c := want[0].Values(); a != 0 || b != 9 || c != "z" || want[0].String() != "(0, 9, z)" {
+		t.Errorf(errExpectedX, "(0, 9, z)", want[0])
+	}
+}
+
+func TestTupleJSON(t *testing.T) {
+	type entry struct {
+		Key   tuple.Pair[string, int]
+		Value tuple.Triple[bool, float64, []string]
+	}
+	src := entry{tuple.NewPair("k", 7), tuple.NewTriple(true, 1.5, []string{"x"})}
+	data, err := json.Marshal(src)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if want := `{"Key":["k",7],"Value":[true,1.5,["x"]]}`; string(data) != want {
+		t.Errorf(errExpectedX, want, string(data))
+	}
+	var dst entry
+	if err := json.Unmarshal(data, &dst); err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if dst.Key != src.Key || dst.Value.Third[0] != "x" || dst.Value.Second != 1.5 {
+		t.Errorf(errExpectedX, src, dst)
+	}
+
+	var p tuple.Pair[string, int]
+	if err := json.Unmarshal([]byte(`["a", 1, 2]`), &p); err == nil || !strings.HasPrefix(err.Error(), tuple.ErrWrongLength) {
+		t.Errorf(errExpectedX, tuple.ErrWrongLength, err)
+	}
+	if err := json.Unmarshal([]byte(`[1, 1]`), &p); err == nil {
+		t.Errorf("expected a type error")
+	}
+}
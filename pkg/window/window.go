@@ -0,0 +1,272 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package window provides a sliding-window aggregator: it keeps the last N
+// samples (NewCount) or the samples of the last period (NewTime), with their
+// running sum, mean, min and max, all updated in O(1) amortized time per
+// sample (the min and max use monotonic deques).
+package window
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	ErrInvalidSize = "the window size must be positive"
+	ErrInvalidSpan = "the window span must be positive"
+)
+
+// Number is the constraint of the sample types
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Option configures a Window.
+type Option func(*config)
+
+type config struct {
+	now func() time.Time
+}
+
+// WithClock sets the function returning the current time, used by the
+// time-based windows (the default is time.Now).
+func WithClock(now func() time.Time) Option {
+	return func(cfg *config) {
+		cfg.now = now
+	}
+}
+
+// sample is a value in the window; seq tells apart the equal values in the
+// min and max deques
+type sample[T Number] struct {
+	value T
+	at    time.Time
+	seq   uint64
+}
+
+// Snapshot is the state of a Window at a point in time, for metrics.
+type Snapshot[T Number] struct {
+	Count int
+	Sum   T
+	Mean  float64 // 0 if the window is empty
+	Min   T
+	Max   T
+}
+
+// Window is a sliding window of samples. The time-based windows drop the
+// expired samples when they are read too, so every method changes the
+// window: it is not concurrency-safe.
+//
+// The sum is updated by adding and subtracting the samples, so with floats
+// it can drift from the exact sum of the samples in the window over long
+// runs (call Reset to start over).
+type Window[T Number] struct {
+	samples deque[sample[T]]
+	mins    deque[sample[T]] // increasing values, the min first
+	maxs    deque[sample[T]] // decreasing values, the max first
+	sum     T
+	seq     uint64
+	size    int           // for count-based windows, 0 otherwise
+	span    time.Duration // for time-based windows, 0 otherwise
+	now     func() time.Time
+}
+
+// NewCount creates a window holding the last size samples.
+func NewCount[T Number](size int) (*Window[T], error) {
+	if size <= 0 {
+		return nil, errors.New(ErrInvalidSize)
+	}
+	return &Window[T]{size: size, now: time.Now}, nil
+}
+
+// NewTime creates a window holding the samples added in the last span.
+func NewTime[T Number](span time.Duration, opts ...Option) (*Window[T], error) {
+	if span <= 0 {
+		return nil, errors.New(ErrInvalidSpan)
+	}
+	cfg := config{now: time.Now}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Window[T]{span: span, now: cfg.now}, nil
+}
+
+// Add adds a sample to the window, dropping the samples that fall out of it
+func (w *Window[T]) Add(value T) {
+	s := sample[T]{value: value, seq: w.seq}
+	w.seq++
+	if w.span > 0 {
+		s.at = w.now()
+		w.expire(s.at)
+	} else if w.samples.len() == w.size {
+		w.dropOldest()
+	}
+	w.samples.pushBack(s)
+	w.sum += value
+	for w.mins.len() > 0 && w.mins.back().value >= value {
+		w.mins.popBack()
+	}
+	w.mins.pushBack(s)
+	for w.maxs.len() > 0 && w.maxs.back().value <= value {
+		w.maxs.popBack()
+	}
+	w.maxs.pushBack(s)
+}
+
+// dropOldest removes the oldest sample
+func (w *Window[T]) dropOldest() {
+	s := w.samples.popFront()
+	w.sum -= s.value
+	if w.mins.front().seq == s.seq {
+		w.mins.popFront()
+	}
+	if w.maxs.front().seq == s.seq {
+		w.maxs.popFront()
+	}
+}
+
+// expire drops the samples older than the span, for time-based windows
+func (w *Window[T]) expire(now time.Time) {
+	if w.span == 0 {
+		return
+	}
+	for w.samples.len() > 0 && now.Sub(w.samples.front().at) >= w.span {
+		w.dropOldest()
+	}
+}
+
+// refresh drops the expired samples before a read
+func (w *Window[T]) refresh() {
+	if w.span > 0 {
+		w.expire(w.now())
+	}
+}
+
+// Count returns the number of samples in the window
+func (w *Window[T]) Count() int {
+	w.refresh()
+	return w.samples.len()
+}
+
+// Sum returns the sum of the samples in the window
+func (w *Window[T]) Sum() T {
+	w.refresh()
+	return w.sum
+}
+
+// Mean returns the mean of the samples in the window (0 if it's empty)
+func (w *Window[T]) Mean() float64 {
+	w.refresh()
+	if w.samples.len() == 0 {
+		return 0
+	}
+	return float64(w.sum) / float64(w.samples.len())
+}
+
+// Min returns the smallest sample in the window, and false if it's empty
+func (w *Window[T]) Min() (T, bool) {
+	w.refresh()
+	if w.mins.len() == 0 {
+		var zero T
+		return zero, false
+	}
+	return w.mins.front().value, true
+}
+
+// Max returns the largest sample in the window, and false if it's empty
+func (w *Window[T]) Max() (T, bool) {
+	w.refresh()
+	if w.maxs.len() == 0 {
+		var zero T
+		return zero, false
+	}
+	return w.maxs.front().value, true
+}
+
+// Snapshot returns all the aggregates at once
+func (w *Window[T]) Snapshot() Snapshot[T] {
+	w.refresh()
+	snap := Snapshot[T]{Count: w.samples.len(), Sum: w.sum}
+	if snap.Count > 0 {
+		snap.Mean = float64(w.sum) / float64(snap.Count)
+		snap.Min, snap.Max = w.mins.front().value, w.maxs.front().value
+	}
+	return snap
+}
+
+// Reset removes all the samples
+func (w *Window[T]) Reset() {
+	w.samples.clear()
+	w.mins.clear()
+	w.maxs.clear()
+	w.sum = 0
+}
+
+// deque is a slice-backed double-ended queue, compacted when half of it is
+// unused, so all the operations are O(1) amortized
+type deque[E any] struct {
+	buf  []E
+	head int
+}
+
+func (d *deque[E]) len() int {
+	return len(d.buf) - d.head
+}
+
+func (d *deque[E]) front() E {
+	return d.buf[d.head]
+}
+
+func (d *deque[E]) back() E {
+	return d.buf[len(d.buf)-1]
+}
+
+func (d *deque[E]) pushBack(e E) {
+	if d.head > 0 && d.head >= len(d.buf)/2 && len(d.buf) == cap(d.buf) {
+		n := copy(d.buf, d.buf[d.head:])
+		clear(d.buf[n:])
+		d.buf, d.head = d.buf[:n], 0
+	}
+	d.buf = append(d.buf, e)
+}
+
+func (d *deque[E]) popFront() E {
+	var zero E
+	e := d.buf[d.head]
+	d.buf[d.head] = zero
+	d.head++
+	if d.head == len(d.buf) {
+		d.buf, d.head = d.buf[:0], 0
+	}
+	return e
+}
+
+func (d *deque[E]) popBack() E {
+	var zero E
+	e := d.buf[len(d.buf)-1]
+	d.buf[len(d.buf)-1] = zero
+	d.buf = d.buf[:len(d.buf)-1]
+	if d.head == len(d.buf) {
+		d.buf, d.head = d.buf[:0], 0
+	}
+	return e
+}
+
+func (d *deque[E]) clear() {
+	clear(d.buf)
+	d.buf, d.head = d.buf[:0], 0
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package window_test
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	window "github.com/pzaino/gods/pkg/window"
+)
+
+const (
+	errExpectedNoError = "expected no error, got %v"
+	errExpectedX       = "expected %v, got %v"
+)
+
+func TestCountWindow(t *testing.T) {
+	w, err := window.NewCount[int](5)
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	if _, ok := w.Min(); ok || w.Mean() != 0 {
+		t.Errorf("expected an empty window")
+	}
+	r := rand.New(rand.NewPCG(5, 6))
+	var all []int
+	for i := 0; i < 1000; i++ {
+		v := r.IntN(100) - 50
+		w.Add(v)
+		all = append(all, v)
+		last := all[max(0, len(all)-5):]
+		sum := 0
+		for _, x := range last {
+			sum += x
+		}
+		want := window.Snapshot[int]{
+			Count: len(last), Sum: sum, Mean: float64(sum) / float64(len(last)),
+			Min: slices.Min(last), Max: slices.Max(last),
+		}
+		if got := w.Snapshot(); got != want {
+			t.Fatalf(errExpectedX, want, got)
+		}
+	}
+	w.Reset()
+	if w.Count() != 0 || w.Sum() != 0 {
+		t.Errorf(errExpectedX, 0, w.Count())
+	}
+	if _, err := window.NewCount[int](0); err == nil || err.Error() != window.ErrInvalidSize {
+		t.Errorf(errExpectedX, window.ErrInvalidSize, err)
+	}
+}
+
+func TestTimeWindow(t *testing.T) {
+	now := time.Unix(0, 0)
+	w, err := window.NewTime[float64](time.Minute, window.WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf(errExpectedNoError, err)
+	}
+	w.Add(3)
+	now = now.Add(20 * time.Second)
+	w.Add(1)
+	now = now.Add(20 * time.Second)
+	w.Add(2)
+	if w.Count() != 3 || w.Sum() != 6 || w.Mean() != 2 {
+		t.Errorf(errExpectedX, 3, w.Count())
+	}
+	// the first sample expires, even without a new Add
+	now = now.Add(20 * time.Second)
+	if hi, _ := w.Max(); hi != 2 || w.Count() != 2 {
+		t.Errorf(errExpectedX, 2, hi)
+	}
+	if lo, _ := w.Min(); lo != 1 {
+		t.Errorf(errExpectedX, 1, lo)
+	}
+	now = now.Add(time.Hour)
+	if snap := w.Snapshot(); snap != (window.Snapshot[float64]{}) {
+		t.Errorf(errExpectedX, window.Snapshot[float64]{}, snap)
+	}
+	if _, err := window.NewTime[int](0); err == nil || err.Error() != window.ErrInvalidSpan {
+		t.Errorf(errExpectedX, window.ErrInvalidSpan, err)
+	}
+}
@@ -0,0 +1,338 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+const (
+	ErrInvalidSnapshot    = "invalid snapshot file"
+	ErrSnapshotChecksum   = "snapshot checksum mismatch"
+	ErrSnapshotCompressed = "snapshot is compressed, restore it WithCompression"
+	ErrSnapshotEncrypted  = "snapshot is encrypted, restore it WithEncryption"
+)
+
+// Snapshot file layout:
+//
+//	magic "GODS" | version (1 byte) | flags (1 byte) | payload | payload length (uint64 LE) | CRC-32C of the payload (uint32 LE)
+//
+// The payload is the container encoded with the snapshot codec, compressed if
+// the snapshotCompressed flag is set and then encrypted (see encryptWriter) if
+// the snapshotEncrypted flag is set.
+const (
+	snapshotMagic      = "GODS"
+	snapshotVersion    = 1
+	snapshotHeaderSize = len(snapshotMagic) + 2
+	snapshotTrailerLen = 8 + 4
+
+	snapshotTempInfix = ".tmp-"
+
+	snapshotCompressed = 1 << 0
+	snapshotEncrypted  = 1 << 1
+)
+
+var snapshotCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// SnapshotOption configures Snapshot and Restore.
+type SnapshotOption func(*snapshotConfig)
+
+type snapshotConfig struct {
+	codec       Codec
+	compression Compression
+	keys        []EncryptionKey
+}
+
+func newSnapshotConfig(opts []SnapshotOption) *snapshotConfig {
+	cfg := &snapshotConfig{codec: GobCodec{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithSnapshotCodec selects the codec used to encode the container (the
+// default is GobCodec). Restore must use the same codec.
+func WithSnapshotCodec(codec Codec) SnapshotOption {
+	return func(cfg *snapshotConfig) {
+		cfg.codec = codec
+	}
+}
+
+// Compression is a streaming compression algorithm for the snapshots.
+// GzipCompression is provided; other algorithms (like zstd) can be plugged in
+// by wrapping their encoder and decoder.
+type Compression interface {
+	// NewWriter returns a writer compressing to w. Closing it must flush the
+	// compressed data but not close w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader decompressing r
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// GzipCompression compresses the snapshots with compress/gzip.
+type GzipCompression struct {
+	// Level is the gzip compression level (0 selects gzip.DefaultCompression)
+	Level int
+}
+
+// NewWriter returns a gzip writer compressing to w
+func (g GzipCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if g.Level == 0 {
+		return gzip.NewWriter(w), nil
+	}
+	return gzip.NewWriterLevel(w, g.Level)
+}
+
+// NewReader returns a gzip reader decompressing r
+func (GzipCompression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// WithCompression compresses the payload of the snapshot while it is written,
+// so the memory used does not depend on the size of the container. Restore
+// needs the same option to read a compressed snapshot, but it can read the
+// uncompressed ones with it too.
+func WithCompression(compression Compression) SnapshotOption {
+	return func(cfg *snapshotConfig) {
+		cfg.compression = compression
+	}
+}
+
+// Snapshot writes the content of c to the file at path atomically: it is
+// written to a temporary file in the same directory, synced to disk and then
+// renamed over path, so path always contains either the previous snapshot or
+// the new one, even if the process crashes. The file includes a checksum
+// that Restore verifies. Concurrent containers encode a consistent view of
+// their content, so they can be snapshotted while in use.
+func Snapshot(c Encodable, path string, opts ...SnapshotOption) (err error) {
+	_, end := Trace(context.Background(), "gods.Snapshot", slog.String("path", path))
+	defer func() { end(err) }()
+	cfg := newSnapshotConfig(opts)
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return writeSnapshot(w, c, cfg)
+	})
+}
+
+// writeFileAtomic replaces the file at path with the data written by write:
+// the data goes to a temporary file in the same directory, which is synced
+// and renamed over path
+func writeFileAtomic(path string, write func(io.Writer) error) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+snapshotTempInfix+"*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	if err = write(w); err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// Restore replaces the content of c with the snapshot at path. The checksum
+// is verified before decoding, so a corrupted or truncated file returns an
+// error and leaves c unchanged.
+func Restore(path string, c Serializable, opts ...SnapshotOption) (err error) {
+	_, end := Trace(context.Background(), "gods.Restore", slog.String("path", path))
+	defer func() { end(err) }()
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return restoreFile(f, c, newSnapshotConfig(opts))
+}
+
+// writeSnapshot writes the snapshot of c (header, payload and trailer) to w
+func writeSnapshot(w io.Writer, c Encodable, cfg *snapshotConfig) error {
+	var flags byte
+	if cfg.compression != nil {
+		flags |= snapshotCompressed
+	}
+	if len(cfg.keys) > 0 {
+		flags |= snapshotEncrypted
+	}
+	header := append([]byte(snapshotMagic), snapshotVersion, flags)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	payload := &checksumWriter{w: w, crc: crc32.New(snapshotCRCTable)}
+	if err := encodePayload(c, payload, header, cfg); err != nil {
+		return err
+	}
+	trailer := binary.LittleEndian.AppendUint64(nil, payload.n)
+	trailer = binary.LittleEndian.AppendUint32(trailer, payload.crc.Sum32())
+	_, err := w.Write(trailer)
+	return err
+}
+
+// restoreFile verifies the snapshot in f and decodes it into c
+func restoreFile(f *os.File, c Serializable, cfg *snapshotConfig) error {
+	payload, header, err := verifySnapshot(f)
+	if err != nil {
+		return err
+	}
+	flags := header[len(snapshotMagic)+1]
+	r := io.Reader(bufio.NewReader(payload))
+	switch {
+	case flags&snapshotEncrypted != 0:
+		if len(cfg.keys) == 0 {
+			return errors.New(ErrSnapshotEncrypted)
+		}
+		if r, err = newDecryptReader(r, cfg.keys, header); err != nil {
+			return err
+		}
+	case len(cfg.keys) > 0:
+		// the checksum is not a MAC: a snapshot that was replaced by (or
+		// downgraded to) a plaintext one must not be trusted
+		return errors.New(ErrSnapshotNotEncrypted)
+	}
+	if flags&snapshotCompressed != 0 {
+		if cfg.compression == nil {
+			return errors.New(ErrSnapshotCompressed)
+		}
+		zr, err := cfg.compression.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = bufio.NewReader(zr)
+	}
+	return c.Decode(r, cfg.codec)
+}
+
+// encodePayload encodes c to w, compressing and encrypting it as configured
+// by cfg (the encryption authenticates the snapshot header too)
+func encodePayload(c Encodable, w io.Writer, header []byte, cfg *snapshotConfig) error {
+	var stages []io.WriteCloser
+	if len(cfg.keys) > 0 {
+		ew, err := newEncryptWriter(w, cfg.keys[0], header)
+		if err != nil {
+			return err
+		}
+		stages = append(stages, ew)
+		w = ew
+	}
+	if cfg.compression != nil {
+		zw, err := cfg.compression.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		stages = append(stages, zw)
+		w = zw
+	}
+	if err := c.Encode(w, cfg.codec); err != nil {
+		return err
+	}
+	// close the outermost stage first, so it flushes into the next one
+	for i := len(stages) - 1; i >= 0; i-- {
+		if err := stages[i].Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifySnapshot checks the header and the checksum of a snapshot file and
+// returns a reader over its payload and the header
+func verifySnapshot(f *os.File) (*io.SectionReader, []byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := info.Size()
+	if size < int64(snapshotHeaderSize+snapshotTrailerLen) {
+		return nil, nil, errors.New(ErrInvalidSnapshot)
+	}
+	header := make([]byte, snapshotHeaderSize)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return nil, nil, err
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic || header[len(snapshotMagic)] != snapshotVersion {
+		return nil, nil, errors.New(ErrInvalidSnapshot)
+	}
+	trailer := make([]byte, snapshotTrailerLen)
+	if _, err := f.ReadAt(trailer, size-snapshotTrailerLen); err != nil {
+		return nil, nil, err
+	}
+	length := binary.LittleEndian.Uint64(trailer)
+	if length != uint64(size)-uint64(snapshotHeaderSize+snapshotTrailerLen) {
+		return nil, nil, errors.New(ErrInvalidSnapshot)
+	}
+	payload := io.NewSectionReader(f, int64(snapshotHeaderSize), int64(length))
+	crc := crc32.New(snapshotCRCTable)
+	if _, err := io.Copy(crc, payload); err != nil {
+		return nil, nil, err
+	}
+	if crc.Sum32() != binary.LittleEndian.Uint32(trailer[8:]) {
+		return nil, nil, errors.New(ErrSnapshotChecksum)
+	}
+	return io.NewSectionReader(f, int64(snapshotHeaderSize), int64(length)), header, nil
+}
+
+// checksumWriter counts and checksums the bytes written through it
+type checksumWriter struct {
+	w   io.Writer
+	crc hash.Hash32
+	n   uint64
+}
+
+func (c *checksumWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += uint64(n)
+	_, _ = c.crc.Write(p[:n])
+	return n, err
+}
+
+// syncDir syncs a directory, so a rename in it is durable. Errors are ignored:
+// not all the platforms support it.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
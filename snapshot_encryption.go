@@ -0,0 +1,241 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	ErrInvalidKeyID         = "encryption key ID longer than 255 bytes"
+	ErrSnapshotUnknownKey   = "snapshot encrypted with an unknown key"
+	ErrSnapshotDecryption   = "snapshot decryption failed"
+	ErrSnapshotTruncatedEnc = "encrypted snapshot is truncated"
+	ErrSnapshotNotEncrypted = "snapshot is not encrypted, but encryption keys are configured"
+)
+
+// Encrypted payload layout:
+//
+//	key ID length (1 byte) | key ID | nonce prefix (8 bytes) | chunk...
+//
+// and every chunk is:
+//
+//	final (1 byte) | ciphertext length (uint32 LE) | AES-GCM ciphertext of up to encryptChunkSize bytes
+//
+// The nonce of a chunk is the nonce prefix followed by the index of the chunk
+// (uint32 BE). The additional data authenticated with every chunk is the
+// snapshot header, the key ID length, the key ID, the nonce prefix and the
+// final byte, so a changed header (e.g. flags), reordered, dropped or
+// truncated chunks fail to decrypt. The last chunk is the only one with final
+// set to 1.
+const (
+	encryptChunkSize   = 64 * 1024
+	encryptNoncePrefix = 8
+)
+
+// EncryptionKey is an AES key (16, 24 or 32 bytes long) with its identifier.
+// The identifier is written in clear in the snapshots, so Restore can select
+// the right key while the keys are being rotated.
+type EncryptionKey struct {
+	ID  string
+	Key []byte
+}
+
+// WithEncryption encrypts the snapshots with AES-GCM using key. Restore
+// decrypts the snapshots written with key or with any of the previous keys
+// (matched by ID), so snapshots taken before a key rotation can still be
+// restored, and rejects the snapshots that are not encrypted
+// (ErrSnapshotNotEncrypted):
+//
+//	gods.Restore(path, q, gods.WithEncryption(newKey, oldKey))
+//
+// The payload is encrypted in chunks while it is written, so the memory used
+// does not depend on the size of the container. When combined with
+// WithCompression the payload is compressed before being encrypted.
+func WithEncryption(key EncryptionKey, previous ...EncryptionKey) SnapshotOption {
+	return func(cfg *snapshotConfig) {
+		cfg.keys = append([]EncryptionKey{key}, previous...)
+	}
+}
+
+// newGCM returns the AES-GCM AEAD for key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptWriter encrypts what is written to it in chunks
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	nonce  []byte
+	aad    []byte // the additional data, up to the final byte
+	index  uint32
+	buf    []byte
+	sealed []byte
+}
+
+// newEncryptWriter writes the encryption header to w and returns a writer
+// encrypting to w with key, authenticating the snapshot header too. Close
+// writes the final chunk.
+func newEncryptWriter(w io.Writer, key EncryptionKey, snapshotHeader []byte) (*encryptWriter, error) {
+	if len(key.ID) > 255 {
+		return nil, errors.New(ErrInvalidKeyID)
+	}
+	aead, err := newGCM(key.Key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce[:encryptNoncePrefix]); err != nil {
+		return nil, err
+	}
+	header := append([]byte{byte(len(key.ID))}, key.ID...)
+	header = append(header, nonce[:encryptNoncePrefix]...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	aad := append(append([]byte{}, snapshotHeader...), header...)
+	return &encryptWriter{w: w, aead: aead, nonce: nonce, aad: aad, buf: make([]byte, 0, encryptChunkSize)}, nil
+}
+
+// Write buffers p, encrypting every full chunk
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if len(e.buf) == encryptChunkSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):encryptChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close encrypts the final chunk (it does not close the underlying writer)
+func (e *encryptWriter) Close() error {
+	return e.seal(true)
+}
+
+// seal encrypts and writes the buffered chunk
+func (e *encryptWriter) seal(final bool) error {
+	var flag byte
+	if final {
+		flag = 1
+	}
+	binary.BigEndian.PutUint32(e.nonce[encryptNoncePrefix:], e.index)
+	e.index++
+	e.sealed = append(e.sealed[:0], flag, 0, 0, 0, 0)
+	e.sealed = e.aead.Seal(e.sealed, e.nonce, e.buf, append(e.aad, flag))
+	binary.LittleEndian.PutUint32(e.sealed[1:5], uint32(len(e.sealed)-5))
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(e.sealed)
+	return err
+}
+
+// decryptReader decrypts the chunks written by encryptWriter
+type decryptReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	nonce  []byte
+	aad    []byte // the additional data, up to the final byte
+	index  uint32
+	plain  []byte // the decrypted bytes not read yet
+	sealed []byte
+	final  bool
+}
+
+// newDecryptReader reads the encryption header from r and returns a reader
+// decrypting r with the key named in the header, and verifying the snapshot
+// header
+func newDecryptReader(r io.Reader, keys []EncryptionKey, snapshotHeader []byte) (*decryptReader, error) {
+	var idLen [1]byte
+	if _, err := io.ReadFull(r, idLen[:]); err != nil {
+		return nil, errors.New(ErrSnapshotTruncatedEnc)
+	}
+	header := make([]byte, int(idLen[0])+encryptNoncePrefix)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.New(ErrSnapshotTruncatedEnc)
+	}
+	id := string(header[:idLen[0]])
+	for _, key := range keys {
+		if key.ID != id {
+			continue
+		}
+		aead, err := newGCM(key.Key)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		copy(nonce, header[idLen[0]:])
+		aad := append(append(append([]byte{}, snapshotHeader...), idLen[0]), header...)
+		return &decryptReader{r: r, aead: aead, nonce: nonce, aad: aad}, nil
+	}
+	return nil, errors.New(ErrSnapshotUnknownKey)
+}
+
+// Read returns the decrypted payload, decrypting a chunk at a time
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.final {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next chunk
+func (d *decryptReader) open() error {
+	var header [5]byte
+	if _, err := io.ReadFull(d.r, header[:]); err != nil {
+		return errors.New(ErrSnapshotTruncatedEnc)
+	}
+	length := binary.LittleEndian.Uint32(header[1:])
+	if header[0] > 1 || length > encryptChunkSize+uint32(d.aead.Overhead()) {
+		return errors.New(ErrSnapshotDecryption)
+	}
+	if cap(d.sealed) < int(length) {
+		d.sealed = make([]byte, length)
+	}
+	d.sealed = d.sealed[:length]
+	if _, err := io.ReadFull(d.r, d.sealed); err != nil {
+		return errors.New(ErrSnapshotTruncatedEnc)
+	}
+	binary.BigEndian.PutUint32(d.nonce[encryptNoncePrefix:], d.index)
+	d.index++
+	plain, err := d.aead.Open(d.sealed[:0], d.nonce, d.sealed, append(d.aad, header[0]))
+	if err != nil {
+		return errors.New(ErrSnapshotDecryption)
+	}
+	d.plain, d.final = plain, header[0] == 1
+	return nil
+}
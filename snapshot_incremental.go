@@ -0,0 +1,222 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ErrChangesGap = "changes do not follow the current state of the container"
+)
+
+// ChangeTracker is implemented by the containers that can encode only the
+// changes made since a given mutation (like a csmap created with the
+// WithChangeTracking option). Every mutation of the container is numbered,
+// starting from 1.
+type ChangeTracker interface {
+	// EncodeChanges writes to w the changes made after the mutation since
+	// (the whole content if since is 0) and returns the number of the last
+	// mutation included
+	EncodeChanges(w io.Writer, codec Codec, since uint64) (uint64, error)
+	// ApplyChanges applies the changes written by EncodeChanges. The changes
+	// since a mutation must be applied to the state reached by that mutation,
+	// otherwise an ErrChangesGap error is returned.
+	ApplyChanges(r io.Reader, codec Codec) error
+	// ForgetChanges stops tracking the changes up to the mutation seq, once
+	// they have been saved in a full snapshot
+	ForgetChanges(seq uint64)
+}
+
+// Incremental writes the snapshots of a ChangeTracker to a SnapshotStore as a
+// full snapshot followed by deltas, each one with the changes since the
+// previous snapshot, so the cost of a snapshot depends on the number of
+// changes rather than on the size of the container. A new full snapshot is
+// written every maxDeltas deltas (and by the first Snapshot), after which the
+// previous snapshots are deleted.
+//
+// The snapshots are stored as "<name>.<index>.full" and
+// "<name>.<index>.delta", where index grows with every snapshot.
+type Incremental struct {
+	c         ChangeTracker
+	store     SnapshotStore
+	name      string
+	maxDeltas int
+	opts      []SnapshotOption
+
+	mu     sync.Mutex
+	next   uint64 // the index of the next snapshot (0 until the store is listed)
+	last   uint64 // the last mutation in the stored snapshots
+	deltas int    // the number of deltas since the last full snapshot
+	full   bool   // true once a full snapshot has been stored
+}
+
+// NewIncremental returns an Incremental writing the snapshots of c to store.
+func NewIncremental(c ChangeTracker, store SnapshotStore, name string, maxDeltas int, opts ...SnapshotOption) *Incremental {
+	return &Incremental{c: c, store: store, name: name, maxDeltas: maxDeltas, opts: opts}
+}
+
+// Snapshot stores a delta with the changes since the previous snapshot, or a
+// full snapshot if there isn't one yet or maxDeltas deltas have been stored.
+func (in *Incremental) Snapshot(ctx context.Context) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.snapshot(ctx, !in.full || in.deltas >= in.maxDeltas)
+}
+
+// Full stores a full snapshot and deletes the previous ones.
+func (in *Incremental) Full(ctx context.Context) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.snapshot(ctx, true)
+}
+
+// snapshot stores a full snapshot or a delta
+func (in *Incremental) snapshot(ctx context.Context, full bool) (err error) {
+	ctx, end := Trace(ctx, "gods.Incremental.Snapshot", slog.String("name", in.name), slog.Bool("full", full))
+	defer func() { end(err) }()
+	if in.next == 0 {
+		files, err := listIncremental(ctx, in.store, in.name)
+		if err != nil {
+			return err
+		}
+		in.next = 1
+		if len(files) > 0 {
+			in.next = files[len(files)-1].index + 1
+		}
+	}
+	enc := &changesCodec{c: in.c}
+	kind := "delta"
+	if full {
+		kind = "full"
+	} else {
+		enc.since = in.last
+	}
+	index := in.next
+	if err := SnapshotTo(ctx, in.store, incrementalName(in.name, index, kind), enc, in.opts...); err != nil {
+		return err
+	}
+	in.next++
+	in.last = enc.seq
+	if !full {
+		in.deltas++
+		return nil
+	}
+	in.full, in.deltas = true, 0
+	in.c.ForgetChanges(enc.seq)
+	files, err := listIncremental(ctx, in.store, in.name)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.index < index {
+			if err := in.store.Delete(ctx, f.name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RestoreIncremental restores c from the last full snapshot called name in
+// store and the deltas stored after it, applied in order. c should be
+// created with the same options as the snapshotted container. If a delta
+// can't be restored, the error is returned and c is left with the changes
+// of the previous deltas.
+func RestoreIncremental(ctx context.Context, store SnapshotStore, name string, c ChangeTracker, opts ...SnapshotOption) (err error) {
+	ctx, end := Trace(ctx, "gods.RestoreIncremental", slog.String("name", name))
+	defer func() { end(err) }()
+	files, err := listIncremental(ctx, store, name)
+	if err != nil {
+		return err
+	}
+	start := -1
+	for i, f := range files {
+		if f.full {
+			start = i
+		}
+	}
+	if start < 0 {
+		return fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	for _, f := range files[start:] {
+		if err := RestoreFrom(ctx, store, f.name, &changesCodec{c: c}, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changesCodec adapts a ChangeTracker to Serializable, to write and read its
+// changes with SnapshotTo and RestoreFrom
+type changesCodec struct {
+	c     ChangeTracker
+	since uint64
+	seq   uint64 // the last mutation encoded
+}
+
+// Encode writes the changes since cc.since
+func (cc *changesCodec) Encode(w io.Writer, codec Codec) error {
+	seq, err := cc.c.EncodeChanges(w, codec, cc.since)
+	cc.seq = seq
+	return err
+}
+
+// Decode applies the changes read from r
+func (cc *changesCodec) Decode(r io.Reader, codec Codec) error {
+	return cc.c.ApplyChanges(r, codec)
+}
+
+// incrementalFile is a snapshot written by Incremental
+type incrementalFile struct {
+	name  string
+	index uint64
+	full  bool
+}
+
+// incrementalName returns the name of a snapshot written by Incremental.
+// The index is zero padded, so the names sort in the order of the indexes.
+func incrementalName(name string, index uint64, kind string) string {
+	return fmt.Sprintf("%s.%020d.%s", name, index, kind)
+}
+
+// listIncremental returns the snapshots written by Incremental for name, in
+// order
+func listIncremental(ctx context.Context, store SnapshotStore, name string) ([]incrementalFile, error) {
+	names, err := store.List(ctx, name+".")
+	if err != nil {
+		return nil, err
+	}
+	var files []incrementalFile
+	for _, n := range names {
+		index, kind, ok := strings.Cut(strings.TrimPrefix(n, name+"."), ".")
+		if !ok || len(index) != 20 || (kind != "full" && kind != "delta") {
+			continue
+		}
+		i, err := strconv.ParseUint(index, 10, 64)
+		if err != nil {
+			continue
+		}
+		files = append(files, incrementalFile{name: n, index: i, full: kind == "full"})
+	}
+	return files, nil
+}
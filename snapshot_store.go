@@ -0,0 +1,185 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	ErrInvalidSnapshotName = "invalid snapshot name"
+)
+
+// SnapshotStore stores snapshots by name. FileStore keeps them in a
+// directory; other backends (like S3 or GCS buckets) can be plugged in by
+// implementing this interface.
+type SnapshotStore interface {
+	// Put stores the data read from r under name, replacing the previous
+	// snapshot with that name only once all the data has been stored
+	Put(ctx context.Context, name string, r io.Reader) error
+	// Get returns the snapshot stored under name. It returns an error
+	// matching fs.ErrNotExist if there is no such snapshot.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the names of the snapshots starting with prefix, sorted
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete removes the snapshot stored under name
+	Delete(ctx context.Context, name string) error
+}
+
+// SnapshotTo is like Snapshot but it writes the snapshot of c to store, under
+// name. The snapshot is streamed to the store while it is encoded.
+func SnapshotTo(ctx context.Context, store SnapshotStore, name string, c Encodable, opts ...SnapshotOption) (err error) {
+	ctx, end := Trace(ctx, "gods.SnapshotTo", slog.String("name", name))
+	defer func() { end(err) }()
+	cfg := newSnapshotConfig(opts)
+	pr, pw := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		w := bufio.NewWriter(pw)
+		err := writeSnapshot(w, c, cfg)
+		if err == nil {
+			err = w.Flush()
+		}
+		_ = pw.CloseWithError(err)
+		written <- err
+	}()
+	err = store.Put(ctx, name, pr)
+	// unblock the encoder if the store stopped reading early
+	_ = pr.Close()
+	if werr := <-written; err == nil {
+		err = werr
+	}
+	return err
+}
+
+// RestoreFrom is like Restore but it reads the snapshot called name from
+// store. The snapshot is copied to a temporary file first, so its checksum
+// can be verified before decoding it.
+func RestoreFrom(ctx context.Context, store SnapshotStore, name string, c Serializable, opts ...SnapshotOption) (err error) {
+	ctx, end := Trace(ctx, "gods.RestoreFrom", slog.String("name", name))
+	defer func() { end(err) }()
+	r, err := store.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tmp, err := os.CreateTemp("", "gods-restore-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := io.Copy(tmp, r); err != nil {
+		return err
+	}
+	return restoreFile(tmp, c, newSnapshotConfig(opts))
+}
+
+// FileStore is a SnapshotStore keeping every snapshot in a file of a
+// directory. Put replaces the files atomically, like Snapshot.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a FileStore on dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Put writes the data read from r to the file called name
+func (fs *FileStore) Put(ctx context.Context, name string, r io.Reader) error {
+	path, err := fs.path(ctx, name)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// Get opens the file called name
+func (fs *FileStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	path, err := fs.path(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// List returns the names of the files starting with prefix, sorted (the
+// temporary files of the snapshots being written are skipped)
+func (fs *FileStore) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(fs.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.Type().IsRegular() && strings.HasPrefix(name, prefix) && validSnapshotName(name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Delete removes the file called name
+func (fs *FileStore) Delete(ctx context.Context, name string) error {
+	path, err := fs.path(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	syncDir(fs.dir)
+	return nil
+}
+
+// path returns the path of the file called name
+func (fs *FileStore) path(ctx context.Context, name string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if !validSnapshotName(name) {
+		return "", errors.New(ErrInvalidSnapshotName)
+	}
+	return filepath.Join(fs.dir, name), nil
+}
+
+// validSnapshotName returns true if name can be used as a file name in a
+// FileStore: a single local path element, not looking like a temporary file
+func validSnapshotName(name string) bool {
+	return filepath.IsLocal(name) && !strings.ContainsAny(name, `/\`) &&
+		!strings.Contains(name, snapshotTempInfix)
+}
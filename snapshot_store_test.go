@@ -0,0 +1,117 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	csmap "github.com/pzaino/gods/pkg/csmap"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := gods.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	s := csstack.NewFromSlice([]int{1, 2, 3})
+	for _, name := range []string{"stack-2", "stack-1", "other"} {
+		if err := gods.SnapshotTo(ctx, store, name, s, gods.WithCompression(gods.GzipCompression{})); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+	}
+	names, err := store.List(ctx, "stack-")
+	if err != nil || !slices.Equal(names, []string{"stack-1", "stack-2"}) {
+		t.Errorf(errExpectedX, []string{"stack-1", "stack-2"}, names)
+	}
+
+	restored := csstack.New[int]()
+	if err := gods.RestoreFrom(ctx, store, "stack-1", restored, gods.WithCompression(gods.GzipCompression{})); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if !slices.Equal(restored.ToSlice(), s.ToSlice()) {
+		t.Errorf(errExpectedX, s.ToSlice(), restored.ToSlice())
+	}
+
+	if err := store.Delete(ctx, "stack-1"); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if err := gods.RestoreFrom(ctx, store, "stack-1", restored); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(errExpectedX, fs.ErrNotExist, err)
+	}
+	for _, name := range []string{"", "../escape", "a/b", "x.tmp-1"} {
+		if err := store.Put(ctx, name, strings.NewReader("data")); err == nil || err.Error() != gods.ErrInvalidSnapshotName {
+			t.Errorf(errExpectedX, gods.ErrInvalidSnapshotName, err)
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := gods.SnapshotTo(cancelled, store, "stack-3", s); !errors.Is(err, context.Canceled) {
+		t.Errorf(errExpectedX, context.Canceled, err)
+	}
+}
+
+func TestIncrementalSnapshots(t *testing.T) {
+	ctx := context.Background()
+	store, err := gods.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	m := csmap.New[int, int](gods.WithChangeTracking())
+	in := gods.NewIncremental(m, store, "map", 2)
+	for i := range 4 {
+		m.Set(i, i)
+		if i > 1 {
+			_ = m.Delete(i - 2)
+		}
+		if err := in.Snapshot(ctx); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+	}
+	// full, delta, delta, then a new full replacing them
+	names, _ := store.List(ctx, "map.")
+	if len(names) != 1 || !strings.HasSuffix(names[0], ".full") {
+		t.Errorf(errExpectedX, "a single full snapshot", names)
+	}
+	m.Set(10, 10)
+	_ = m.Delete(2)
+	if err := in.Snapshot(ctx); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	names, _ = store.List(ctx, "map.")
+	if len(names) != 2 || !strings.HasSuffix(names[1], ".delta") {
+		t.Errorf(errExpectedX, "a full snapshot and a delta", names)
+	}
+
+	restored := csmap.New[int, int]()
+	if err := gods.RestoreIncremental(ctx, store, "map", restored); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if !maps.Equal(restored.ToMap(), m.ToMap()) {
+		t.Errorf(errExpectedX, m.ToMap(), restored.ToMap())
+	}
+	if err := gods.RestoreIncremental(ctx, store, "missing", restored); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(errExpectedX, fs.ErrNotExist, err)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	csmap "github.com/pzaino/gods/pkg/csmap"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stack.snap")
+	s := csstack.NewFromSlice([]int{1, 2, 3})
+	for _, opts := range [][]gods.SnapshotOption{nil, {gods.WithSnapshotCodec(gods.JSONCodec{})}} {
+		if err := gods.Snapshot(s, path, opts...); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		restored := csstack.New[int]()
+		if err := gods.Restore(path, restored, opts...); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		if !slices.Equal(restored.ToSlice(), s.ToSlice()) {
+			t.Errorf(errExpectedX, s.ToSlice(), restored.ToSlice())
+		}
+	}
+
+	// a new snapshot replaces the previous one and leaves no temporary files
+	m := csmap.NewFromMap(map[string]int{"a": 1})
+	if err := gods.Snapshot(m, path); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	restored := csmap.New[string, int]()
+	if err := gods.Restore(path, restored); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if !maps.Equal(restored.ToMap(), m.ToMap()) {
+		t.Errorf(errExpectedX, m.ToMap(), restored.ToMap())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf(errExpectedX, 1, len(entries))
+	}
+}
+
+func TestRestoreCorruptedSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stack.snap")
+	if err := gods.Snapshot(csstack.NewFromSlice([]int{1, 2, 3}), path); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	data, _ := os.ReadFile(path)
+
+	target := csstack.NewFromSlice([]int{42})
+	corrupted := slices.Clone(data)
+	corrupted[len(corrupted)/2] ^= 0xff
+	_ = os.WriteFile(path, corrupted, 0o600)
+	if err := gods.Restore(path, target); err == nil || err.Error() != gods.ErrSnapshotChecksum {
+		t.Errorf(errExpectedX, gods.ErrSnapshotChecksum, err)
+	}
+	_ = os.WriteFile(path, data[:len(data)-5], 0o600)
+	if err := gods.Restore(path, target); err == nil || err.Error() != gods.ErrInvalidSnapshot {
+		t.Errorf(errExpectedX, gods.ErrInvalidSnapshot, err)
+	}
+	_ = os.WriteFile(path, []byte("not a snapshot at all"), 0o600)
+	if err := gods.Restore(path, target); err == nil || err.Error() != gods.ErrInvalidSnapshot {
+		t.Errorf(errExpectedX, gods.ErrInvalidSnapshot, err)
+	}
+	// the target is left untouched
+	if !slices.Equal(target.ToSlice(), []int{42}) {
+		t.Errorf(errExpectedX, []int{42}, target.ToSlice())
+	}
+	if err := gods.Restore(filepath.Join(filepath.Dir(path), "missing"), target); !os.IsNotExist(err) {
+		t.Errorf(errExpectedX, "a not exist error", err)
+	}
+}
+
+func TestSnapshotCompression(t *testing.T) {
+	dir := t.TempDir()
+	items := make([]int, 10000)
+	s := csstack.NewFromSlice(items)
+	plain, compressed := filepath.Join(dir, "plain.snap"), filepath.Join(dir, "compressed.snap")
+	if err := gods.Snapshot(s, plain); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	gzip := gods.WithCompression(gods.GzipCompression{})
+	if err := gods.Snapshot(s, compressed, gzip); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	plainInfo, _ := os.Stat(plain)
+	compressedInfo, _ := os.Stat(compressed)
+	if compressedInfo.Size() >= plainInfo.Size() {
+		t.Errorf("Expected the compressed snapshot (%d bytes) to be smaller than %d bytes", compressedInfo.Size(), plainInfo.Size())
+	}
+
+	restored := csstack.New[int]()
+	if err := gods.Restore(compressed, restored); err == nil || err.Error() != gods.ErrSnapshotCompressed {
+		t.Errorf(errExpectedX, gods.ErrSnapshotCompressed, err)
+	}
+	// the option reads both compressed and uncompressed snapshots
+	for _, path := range []string{compressed, plain} {
+		if err := gods.Restore(path, restored, gzip); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		if restored.Size() != uint64(len(items)) {
+			t.Errorf(errExpectedX, len(items), restored.Size())
+		}
+	}
+}
+
+func TestSnapshotEncryption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stack.snap")
+	oldKey := gods.EncryptionKey{ID: "2024", Key: bytes.Repeat([]byte{1}, 32)}
+	newKey := gods.EncryptionKey{ID: "2025", Key: bytes.Repeat([]byte{2}, 16)}
+	// more than one encryption chunk
+	items := make([]int, 50000)
+	for i := range items {
+		items[i] = i
+	}
+	s := csstack.NewFromSlice(items)
+	for _, compression := range []gods.SnapshotOption{nil, gods.WithCompression(gods.GzipCompression{})} {
+		opts := []gods.SnapshotOption{gods.WithEncryption(oldKey), gods.WithSnapshotCodec(gods.JSONCodec{})}
+		if compression != nil {
+			opts = append(opts, compression)
+		}
+		if err := gods.Snapshot(s, path, opts...); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		data, _ := os.ReadFile(path)
+		if bytes.Contains(data, []byte("49999")) {
+			t.Errorf("Expected the snapshot to be encrypted")
+		}
+
+		// after a rotation the old snapshots can still be restored
+		restored := csstack.New[int]()
+		opts[0] = gods.WithEncryption(newKey, oldKey)
+		if err := gods.Restore(path, restored, opts...); err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		if !slices.Equal(restored.ToSlice(), s.ToSlice()) {
+			t.Errorf(errExpectedX, s.Size(), restored.Size())
+		}
+	}
+
+	if err := gods.Snapshot(s, path, gods.WithEncryption(oldKey), gods.WithCompression(gods.GzipCompression{})); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if err := gods.Restore(path, csstack.New[int]()); err == nil || err.Error() != gods.ErrSnapshotEncrypted {
+		t.Errorf(errExpectedX, gods.ErrSnapshotEncrypted, err)
+	}
+	err := gods.Restore(path, csstack.New[int](), gods.WithEncryption(newKey), gods.WithCompression(gods.GzipCompression{}))
+	if err == nil || err.Error() != gods.ErrSnapshotUnknownKey {
+		t.Errorf(errExpectedX, gods.ErrSnapshotUnknownKey, err)
+	}
+	wrongKey := gods.EncryptionKey{ID: oldKey.ID, Key: newKey.Key}
+	err = gods.Restore(path, csstack.New[int](), gods.WithEncryption(wrongKey), gods.WithCompression(gods.GzipCompression{}))
+	if err == nil || err.Error() != gods.ErrSnapshotDecryption {
+		t.Errorf(errExpectedX, gods.ErrSnapshotDecryption, err)
+	}
+	// the header is authenticated: dropping the compression flag (the
+	// checksum covers only the payload) fails the decryption
+	data, _ := os.ReadFile(path)
+	data[5] &^= 1
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	err = gods.Restore(path, csstack.New[int](), gods.WithEncryption(oldKey))
+	if err == nil || err.Error() != gods.ErrSnapshotDecryption {
+		t.Errorf(errExpectedX, gods.ErrSnapshotDecryption, err)
+	}
+	// and a plaintext snapshot is rejected when keys are configured
+	if err := gods.Snapshot(s, path); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	err = gods.Restore(path, csstack.New[int](), gods.WithEncryption(oldKey))
+	if err == nil || err.Error() != gods.ErrSnapshotNotEncrypted {
+		t.Errorf(errExpectedX, gods.ErrSnapshotNotEncrypted, err)
+	}
+	if err := gods.Snapshot(s, path, gods.WithEncryption(gods.EncryptionKey{ID: "short", Key: []byte("key")})); err == nil {
+		t.Errorf("Expected an error for an invalid AES key")
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	ErrInvalidScanSource = "unsupported scan source type"
+)
+
+// ValueSlice returns items as a JSON array, to be stored in a JSON (or
+// JSONB) column. It is the helper used by the containers to implement
+// driver.Valuer.
+func ValueSlice[T any](items []T) (driver.Value, error) {
+	if items == nil {
+		// an empty container is an empty array, not NULL
+		items = []T{}
+	}
+	return json.Marshal(items)
+}
+
+// ScanSlice decodes the JSON array in src (a []byte or a string, as returned
+// by the database driver) into a slice. A NULL value (src == nil) is an
+// empty slice. It is the helper used by the containers to implement
+// sql.Scanner.
+func ScanSlice[T any](src any) ([]T, error) {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil, fmt.Errorf("%s: %T", ErrInvalidScanSource, src)
+	}
+	var items []T
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
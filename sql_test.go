@@ -0,0 +1,85 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"slices"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	circularLinkList "github.com/pzaino/gods/pkg/circularLinkList"
+	csdlinkList "github.com/pzaino/gods/pkg/csdlinkList"
+	cslinkList "github.com/pzaino/gods/pkg/cslinkList"
+	dlinkList "github.com/pzaino/gods/pkg/dlinkList"
+	linkList "github.com/pzaino/gods/pkg/linkList"
+)
+
+// sqlList is what the SQL round trip test needs from a list
+type sqlList interface {
+	driver.Valuer
+	sql.Scanner
+	Append(value int)
+	ToSlice() []int
+}
+
+func TestSQLRoundTrip(t *testing.T) {
+	items := []int{3, 1, 2}
+	lists := []struct{ src, dst sqlList }{
+		{linkList.New[int](), linkList.New[int]()},
+		{dlinkList.New[int](), dlinkList.New[int]()},
+		{circularLinkList.New[int](), circularLinkList.New[int]()},
+		{cslinkList.New[int](), new(cslinkList.CSLinkList[int])},
+		{csdlinkList.New[int](), new(csdlinkList.CSDLinkList[int])},
+	}
+	for _, l := range lists {
+		for _, item := range items {
+			l.src.Append(item)
+		}
+		v, err := l.src.Value()
+		if err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		if string(v.([]byte)) != "[3,1,2]" {
+			t.Errorf("%T: "+errExpectedX, l.src, "[3,1,2]", string(v.([]byte)))
+		}
+		// drivers may return the column as a string too
+		for _, src := range []any{v, string(v.([]byte))} {
+			if err := l.dst.Scan(src); err != nil {
+				t.Fatalf(errUnexpectedErr, err)
+			}
+			if got := l.dst.ToSlice(); !slices.Equal(got, items) {
+				t.Errorf("%T: "+errExpectedX, l.dst, items, got)
+			}
+		}
+		if err := l.dst.Scan(nil); err != nil || len(l.dst.ToSlice()) != 0 {
+			t.Errorf("%T: "+errExpectedX, l.dst, "an empty list", l.dst.ToSlice())
+		}
+	}
+}
+
+func TestSQLHelpers(t *testing.T) {
+	v, err := gods.ValueSlice[int](nil)
+	if err != nil || string(v.([]byte)) != "[]" {
+		t.Errorf(errExpectedX, "[]", v)
+	}
+	if _, err := gods.ScanSlice[int](42); err == nil {
+		t.Errorf(errExpectedX, "an error scanning an int", nil)
+	}
+	if _, err := gods.ScanSlice[int]("[1,"); err == nil {
+		t.Errorf(errExpectedX, "an error scanning invalid JSON", nil)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"iter"
+)
+
+const (
+	// StreamChunkSize is the maximum number of elements in a chunk written
+	// by EncodeStream
+	StreamChunkSize = 4096
+
+	ErrStreamChunkTooLarge = "stream chunk too large"
+)
+
+// maxStreamChunkBytes limits the size of a chunk accepted by DecodeStream, so
+// a corrupted length can't make it read an unbounded amount of data in memory
+const maxStreamChunkBytes = 1 << 30
+
+// EncodeStream writes the elements of seq to w incrementally: they are
+// encoded with codec in chunks of up to StreamChunkSize elements, each one
+// prefixed with its length in bytes (an unsigned varint), and the stream is
+// terminated by an empty chunk. Only one chunk is kept in memory at a time, so
+// very large collections can be written without copying them first.
+func EncodeStream[T any](w io.Writer, codec Codec, seq iter.Seq[T]) error {
+	if codec == nil {
+		return errors.New(ErrCodecIsNil)
+	}
+	var payload bytes.Buffer
+	chunk := make([]T, 0, StreamChunkSize)
+	flush := func() error {
+		payload.Reset()
+		if err := codec.Encode(&payload, chunk); err != nil {
+			return err
+		}
+		if err := writeChunkLength(w, uint64(payload.Len())); err != nil {
+			return err
+		}
+		_, err := w.Write(payload.Bytes())
+		chunk = chunk[:0]
+		return err
+	}
+	for v := range seq {
+		chunk = append(chunk, v)
+		if len(chunk) == StreamChunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(chunk) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	return writeChunkLength(w, 0)
+}
+
+// DecodeStream reads a stream written by EncodeStream from r and calls add for
+// every element, in order, stopping at the first error. It reads exactly the
+// bytes of the stream, so other data can follow it in r.
+func DecodeStream[T any](r io.Reader, codec Codec, add func(T) error) error {
+	if codec == nil {
+		return errors.New(ErrCodecIsNil)
+	}
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = &oneByteReader{r: r}
+	}
+	var payload bytes.Buffer
+	for {
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		if n > maxStreamChunkBytes {
+			return errors.New(ErrStreamChunkTooLarge)
+		}
+		payload.Reset()
+		// CopyN grows the buffer as the data arrives
+		if _, err := io.CopyN(&payload, r, int64(n)); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		items, err := DecodeSlice[T](&payload, codec)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := add(item); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeChunkLength(w io.Writer, n uint64) error {
+	_, err := w.Write(binary.AppendUvarint(nil, n))
+	return err
+}
+
+// oneByteReader reads the length prefixes from readers that are not
+// io.ByteReaders, without reading past them
+type oneByteReader struct {
+	r   io.Reader
+	buf [1]byte
+}
+
+func (b *oneByteReader) ReadByte() (byte, error) {
+	_, err := io.ReadFull(b.r, b.buf[:])
+	return b.buf[0], err
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"slices"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	phashmap "github.com/pzaino/gods/pkg/phashmap"
+	pqueue "github.com/pzaino/gods/pkg/pqueue"
+)
+
+// streamable is what the streaming test needs from a container
+type streamable interface {
+	serializableCollection
+	EncodeStream(w io.Writer, codec gods.Codec) error
+	DecodeStream(r io.Reader, codec gods.Codec) error
+}
+
+// onlyReader hides the io.ByteReader implementation of a reader
+type onlyReader struct {
+	r io.Reader
+}
+
+func (o onlyReader) Read(p []byte) (int, error) {
+	return o.r.Read(p)
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	src := make([]int64, 2*gods.StreamChunkSize+10)
+	for i := range src {
+		src[i] = int64(i)
+	}
+	for name, newContainer := range containers {
+		for _, codec := range []gods.Codec{gods.JSONCodec{}, gods.GobCodec{}, gods.BinaryCodec{}} {
+			c, ok := newContainer().(streamable)
+			if !ok {
+				t.Fatalf("%s doesn't implement EncodeStream/DecodeStream", name)
+			}
+			var buf bytes.Buffer
+			_ = gods.EncodeSlice(&buf, codec, src)
+			if err := c.Decode(&buf, codec); err != nil {
+				t.Fatalf("%s: unexpected error %v", name, err)
+			}
+			want := c.ToSlice()
+
+			// two streams back to back, read without an io.ByteReader
+			buf.Reset()
+			for i := 0; i < 2; i++ {
+				if err := c.EncodeStream(&buf, codec); err != nil {
+					t.Fatalf("%s: unexpected error %v", name, err)
+				}
+			}
+			r := onlyReader{&buf}
+			for i := 0; i < 2; i++ {
+				d := newContainer().(streamable)
+				if err := d.DecodeStream(r, codec); err != nil {
+					t.Fatalf("%s: unexpected error %v", name, err)
+				}
+				if got := d.ToSlice(); !slices.Equal(got, want) {
+					t.Errorf("%s: "+errExpectedX, name, want, got)
+				}
+			}
+		}
+	}
+}
+
+func TestStreamChunks(t *testing.T) {
+	items := make([]int64, gods.StreamChunkSize+1)
+	var buf bytes.Buffer
+	if err := gods.EncodeStream(&buf, gods.BinaryCodec{}, slices.Values(items)); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	chunks := 0
+	for {
+		n, err := binary.ReadUvarint(&buf)
+		if err != nil {
+			t.Fatalf(errUnexpectedErr, err)
+		}
+		if n == 0 {
+			break
+		}
+		chunks++
+		buf.Next(int(n))
+	}
+	if chunks != 2 {
+		t.Errorf(errExpectedX, 2, chunks)
+	}
+
+	// an empty sequence is just the terminator
+	buf.Reset()
+	_ = gods.EncodeStream(&buf, gods.JSONCodec{}, slices.Values([]int(nil)))
+	if !bytes.Equal(buf.Bytes(), []byte{0}) {
+		t.Errorf(errExpectedX, []byte{0}, buf.Bytes())
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	add := func(int64) error { return nil }
+	if err := gods.EncodeStream[int](io.Discard, nil, nil); err == nil || err.Error() != gods.ErrCodecIsNil {
+		t.Errorf(errExpectedX, gods.ErrCodecIsNil, err)
+	}
+	huge := binary.AppendUvarint(nil, 1<<40)
+	if err := gods.DecodeStream(bytes.NewReader(huge), gods.JSONCodec{}, add); err == nil || err.Error() != gods.ErrStreamChunkTooLarge {
+		t.Errorf(errExpectedX, gods.ErrStreamChunkTooLarge, err)
+	}
+	var buf bytes.Buffer
+	_ = gods.EncodeStream(&buf, gods.JSONCodec{}, slices.Values([]int64{1, 2, 3}))
+	truncated := buf.Bytes()[:buf.Len()-3]
+	if err := gods.DecodeStream(bytes.NewReader(truncated), gods.JSONCodec{}, add); err != io.ErrUnexpectedEOF {
+		t.Errorf(errExpectedX, io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestStreamPriorityQueueAndMap(t *testing.T) {
+	pq := pqueue.New[string]()
+	pq.Enqueue("low", 1)
+	pq.Enqueue("high", 10)
+	var buf bytes.Buffer
+	if err := pq.EncodeStream(&buf, gods.GobCodec{}); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	decoded := pqueue.New[string]()
+	if err := decoded.DecodeStream(&buf, gods.GobCodec{}); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if v, _ := decoded.Dequeue(); v != "high" {
+		t.Errorf(errExpectedX, "high", v)
+	}
+
+	m := phashmap.New[string, int]().Assoc("a", 1).Assoc("b", 2)
+	buf.Reset()
+	if err := m.EncodeStream(&buf, gods.JSONCodec{}); err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	dm, err := phashmap.DecodeStream[string, int](&buf, gods.JSONCodec{})
+	if err != nil {
+		t.Fatalf(errUnexpectedErr, err)
+	}
+	if v, err := dm.Get("b"); err != nil || v != 2 || dm.Size() != 2 {
+		t.Errorf(errExpectedX, 2, v)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// Tracer receives the potentially slow operations of the library (snapshots,
+// restores, drains and the bulk *Ctx methods of the concurrent containers),
+// so they can be recorded as spans of a distributed trace. The library has no
+// dependency on a tracing SDK; for example an OpenTelemetry adapter is:
+//
+//	type otelTracer struct{ t trace.Tracer }
+//
+//	func (o otelTracer) Start(ctx context.Context, op string, attrs ...slog.Attr) (context.Context, func(error)) {
+//		ctx, span := o.t.Start(ctx, op)
+//		for _, a := range attrs {
+//			span.SetAttributes(attribute.String(a.Key, a.Value.String()))
+//		}
+//		return ctx, func(err error) {
+//			if err != nil {
+//				span.RecordError(err)
+//				span.SetStatus(codes.Error, err.Error())
+//			}
+//			span.End()
+//		}
+//	}
+//
+// An adapter can also record an event on the current span instead of
+// starting a new one.
+type Tracer interface {
+	// Start is called when an operation starts, with the context of the
+	// caller (context.Background() for the functions without a context) and
+	// the attributes of the operation. It returns the context used by the
+	// operation (for example to call a SnapshotStore) and a function called
+	// with the result of the operation when it ends.
+	Start(ctx context.Context, op string, attrs ...slog.Attr) (context.Context, func(err error))
+}
+
+// tracer is the Tracer set by SetTracer
+var tracer atomic.Pointer[Tracer]
+
+// SetTracer sets the Tracer of the library (nil disables tracing, which is
+// the default).
+func SetTracer(t Tracer) {
+	if t == nil {
+		tracer.Store(nil)
+		return
+	}
+	tracer.Store(&t)
+}
+
+// Trace starts the operation op on the Tracer set by SetTracer. It is used by
+// the containers to instrument their slow operations:
+//
+//	ctx, end := gods.Trace(ctx, "csstack.FilterCtx", slog.Uint64("size", cs.Size()))
+//	defer func() { end(err) }()
+//
+// Without a Tracer it returns ctx and a function doing nothing.
+func Trace(ctx context.Context, op string, attrs ...slog.Attr) (context.Context, func(err error)) {
+	t := tracer.Load()
+	if t == nil {
+		return ctx, endNothing
+	}
+	return (*t).Start(ctx, op, attrs...)
+}
+
+// endNothing ends an operation that is not traced
+func endNothing(error) {}
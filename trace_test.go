@@ -0,0 +1,75 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods_test
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	gods "github.com/pzaino/gods"
+	csstack "github.com/pzaino/gods/pkg/csstack"
+	queue "github.com/pzaino/gods/pkg/queue"
+)
+
+// recordingTracer records the operations it receives
+type recordingTracer struct {
+	mu     sync.Mutex
+	ops    []string
+	failed []string
+}
+
+func (r *recordingTracer) Start(ctx context.Context, op string, attrs ...slog.Attr) (context.Context, func(error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops = append(r.ops, op)
+	return ctx, func(err error) {
+		if err != nil {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.failed = append(r.failed, op)
+		}
+	}
+}
+
+func TestTracer(t *testing.T) {
+	rec := &recordingTracer{}
+	gods.SetTracer(rec)
+	defer gods.SetTracer(nil)
+
+	s := csstack.NewFromSlice([]int{1, 2, 3})
+	_ = s.FilterCtx(context.Background(), func(v int) bool { return v > 1 })
+	path := filepath.Join(t.TempDir(), "stack.snap")
+	_ = gods.Snapshot(s, path)
+	_ = gods.Restore(filepath.Join(t.TempDir(), "missing"), s)
+	gods.Drain[int](s, queue.New[int]().Enqueue)
+
+	want := []string{"csstack.FilterCtx", "gods.Snapshot", "gods.Restore", "gods.Drain"}
+	if !slices.Equal(rec.ops, want) {
+		t.Errorf(errExpectedX, want, rec.ops)
+	}
+	if !slices.Equal(rec.failed, []string{"gods.Restore"}) {
+		t.Errorf(errExpectedX, []string{"gods.Restore"}, rec.failed)
+	}
+
+	gods.SetTracer(nil)
+	_ = gods.Snapshot(s, path)
+	if len(rec.ops) != len(want) {
+		t.Errorf(errExpectedX, len(want), len(rec.ops))
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2024 Paolo Fabio Zaino
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gods
+
+import "fmt"
+
+const (
+	ErrInvariantViolated = "invariant violated"
+)
+
+// Validator is implemented by the containers that can check their internal
+// invariants (like the heap property of a priority queue or the links of a
+// list). Validate walks the whole container, so it is meant for tests and
+// debug assertions: calling it after the operations under suspicion catches
+// a corruption (for example caused by using a non-concurrent container from
+// more goroutines) near its cause.
+type Validator interface {
+	// Validate returns an error starting with ErrInvariantViolated and
+	// describing the first invariant found violated, or nil
+	Validate() error
+}
+
+// Invariantf returns the error reported by Validate for a violated
+// invariant, formatting its description like fmt.Sprintf.
+func Invariantf(format string, args ...any) error {
+	return fmt.Errorf("%s: %s", ErrInvariantViolated, fmt.Sprintf(format, args...))
+}